@@ -19,14 +19,18 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
 	"github.com/grycap/oscar/v2/pkg/handlers"
 	"github.com/grycap/oscar/v2/pkg/resourcemanager"
 	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
 	"github.com/grycap/oscar/v2/pkg/utils/auth"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -62,12 +66,87 @@ func main() {
 		go ofBack.StartScaler()
 	}
 
+	// Inject faults in the backend operations (only in chaos builds)
+	back = backends.WrapChaosBackend(back)
+
 	// Create the ResourceManager and start it if enabled
 	resMan := resourcemanager.MakeResourceManager(cfg, kubeClientset)
 	if resMan != nil {
 		go resourcemanager.StartResourceManager(resMan, cfg.ResourceManagerInterval)
 	}
 
+	// Create the dispatcher of the services' events (shared by the job and replay handlers to aggregate the same batches)
+	dispatcher := handlers.MakeEventDispatcher(cfg, kubeClientset, resMan)
+
+	// Enable the VOs approved through the VO requests and refresh them (approved in other replicas)
+	if err := utils.LoadApprovedVOs(kubeClientset, cfg.ServicesNamespace); err != nil {
+		log.Println(err.Error())
+	}
+	go utils.StartApprovedVOsRefresher(cfg, kubeClientset)
+
+	// Start the watcher to store failed jobs in the services' dead-letter path
+	go utils.StartDeadLetterWatcher(cfg, back, kubeClientset)
+
+	// Start the router of the staged outputs of the finished jobs to the services' outputs by the tags of the files
+	go utils.StartOutputRouter(cfg, back, kubeClientset, handlers.MakeChainedJobRunner(cfg, kubeClientset, resMan))
+
+	// Start the purger of the soft-deleted services whose retention period has expired
+	go utils.StartDeletedServicesPurger(cfg, back, kubeClientset)
+
+	// Start the watcher to notify the finished jobs to the services' callbacks
+	go utils.StartCallbackWatcher(cfg, back, kubeClientset)
+
+	// Start the email triggers and notifications if enabled
+	if cfg.EmailTriggersEnable {
+		go utils.StartEmailPoller(cfg, back, handlers.MakeJobRunner(cfg, kubeClientset, resMan))
+	}
+	if cfg.EmailNotificationsEnable {
+		go utils.StartNotificationWatcher(cfg, back, kubeClientset)
+	}
+	if cfg.ChatNotificationsEnable {
+		go utils.StartChatNotificationWatcher(cfg, back, kubeClientset)
+	}
+
+	// Start the archiver of the finished jobs' logs if enabled
+	if cfg.LogsArchiveEnable {
+		go utils.StartLogsArchiver(cfg, kubeClientset)
+	}
+
+	// Start the watcher to create the services of the finished image builds if enabled
+	if cfg.BuildsRegistry != "" {
+		go utils.StartBuildWatcher(cfg, kubeClientset, handlers.MakeBuildServiceDeployer(cfg, back))
+	}
+
+	// Start the releaser of the jobs queued by the services' max_concurrent_jobs
+	go utils.StartQueuedJobsReleaser(cfg, kubeClientset)
+
+	// Start the tracker of the storage usage of the VOs and users if quotas are defined
+	if quotas := cfg.GetQuotas(); len(quotas[types.QuotaScopeVO])+len(quotas[types.QuotaScopeUser]) > 0 {
+		go utils.StartStorageUsageTracker(cfg, back)
+	}
+
+	// Start the usage reports scheduler if enabled
+	if cfg.ReportsEnable {
+		go utils.StartReportScheduler(cfg, back, kubeClientset)
+	}
+
+	// Start the releaser of the jobs deferred to low-carbon windows if the carbon intensity API is configured
+	if cfg.CarbonIntensityURL != "" {
+		go utils.StartDeferredJobsReleaser(cfg, kubeClientset)
+	}
+
+	// Start the reconciler of the KEDA ScaledJobs of the services with workers if enabled
+	if cfg.KEDAEnable {
+		dynClient, err := dynamic.NewForConfig(kubeConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go utils.StartScaledJobsReconciler(cfg, back, dynClient)
+	}
+
+	// Start the watcher of the jobs publishing their lifecycle events
+	go utils.StartLifecycleEventsWatcher(cfg, kubeClientset)
+
 	// Start the ReScheduler if enabled
 	if cfg.ReSchedulerEnable {
 		go resourcemanager.StartReScheduler(cfg, back, kubeClientset)
@@ -75,37 +154,225 @@ func main() {
 
 	// Create the router
 	r := gin.Default()
+	// Only the X-Forwarded-For header of the configured proxies is trusted (none by default)
+	if err := r.SetTrustedProxies(cfg.GetTrustedProxies()); err != nil {
+		log.Fatal(err)
+	}
+
+	// Record the management operations and the invocations in the audit log (if enabled)
+	auditor, err := utils.NewAuditor(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Use(handlers.MakeAuditMiddleware(auditor))
+
+	// Limit the requests of each client IP and credential, and block the brute-force attacks (if enabled)
+	r.Use(handlers.MakeRateLimitMiddleware(cfg))
 
-	// Define system group with basic auth middleware
-	system := r.Group("/system", auth.GetAuthMiddleware(cfg))
+	// Reject the requests modifying the cluster while the API is frozen for maintenance
+	maintenance := utils.NewMaintenance(cfg, kubeClientset, r)
+	r.Use(handlers.MakeMaintenanceMiddleware(maintenance))
+
+	// Define system group with basic auth middleware (the paths of a service also accept its named access tokens)
+	// and the roles and services of the users
+	users, err := auth.NewUserDirectory(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authMiddleware, err := auth.GetAuthMiddleware(cfg, users)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API keys of the OSCAR manager, accepted besides the configured auth method
+	apiKeys := utils.NewAPIKeys(cfg, kubeClientset)
+	authMiddleware = handlers.MakeAPIKeyAuthMiddleware(apiKeys, authMiddleware)
+	// Authorization webhook (create, update, delete and run)
+	authzMiddleware := handlers.MakeAuthzWebhookMiddleware(cfg)
+	system := r.Group("/system", handlers.MakeServiceTokenAuthMiddleware(back, authMiddleware), handlers.MakeRBACMiddleware(cfg), handlers.MakeServiceOwnershipMiddleware(cfg, back), authzMiddleware)
 
 	// Config path
 	system.GET("/config", handlers.MakeConfigHandler(cfg))
 
 	// CRUD Services
-	system.POST("/services", handlers.MakeCreateHandler(cfg, back))
-	system.GET("/services", handlers.MakeListHandler(back))
-	system.GET("/services/:serviceName", handlers.MakeReadHandler(back))
+	system.POST("/services", handlers.MakeIdempotencyMiddleware(), handlers.MakeCreateHandler(cfg, back))
+	system.POST("/services/batch", handlers.MakeBulkCreateHandler(cfg, back))
+	system.POST("/services/validate", handlers.MakeValidateHandler(cfg))
+	system.POST("/services/import", handlers.MakeImportHandler(cfg, back))
+	system.GET("/services", handlers.MakeListHandler(cfg, back))
+	system.GET("/services/watch", handlers.MakeServiceWatchHandler(cfg, back))
+	system.GET("/services/:serviceName", handlers.MakeReadHandler(cfg, back))
 	system.PUT("/services", handlers.MakeUpdateHandler(cfg, back))
+	system.PATCH("/services/:serviceName", handlers.MakePatchHandler(cfg, back))
 	system.DELETE("/services/:serviceName", handlers.MakeDeleteHandler(cfg, back))
+	system.POST("/services/:serviceName/restore", handlers.MakeRestoreHandler(cfg, back))
+	system.GET("/deleted-services", handlers.MakeDeletedServicesListHandler(cfg, back))
+	system.POST("/services/:serviceName/replay", handlers.MakeReplayHandler(back, dispatcher))
+	system.POST("/services/:serviceName/simulate-event", handlers.MakeSimulateEventHandler(back, dispatcher))
+	system.PUT("/services/:serviceName/inputs/:index/enabled", handlers.MakeInputToggleHandler(back))
+	system.PATCH("/services/:serviceName/features", handlers.MakeFeaturesHandler(back))
+	system.GET("/services/:serviceName/deadletter", handlers.MakeDeadLetterListHandler(cfg, back))
+	system.POST("/services/:serviceName/deadletter/redrive", handlers.MakeDeadLetterRedriveHandler(cfg, kubeClientset, back, resMan))
+	system.GET("/services/:serviceName/export", handlers.MakeExportHandler(back))
+	system.POST("/services/:serviceName/clone", handlers.MakeCloneHandler(cfg, back))
+	system.GET("/services/:serviceName/revisions", handlers.MakeRevisionListHandler(cfg, back))
+	system.POST("/services/:serviceName/rollback/:revision", handlers.MakeRollbackHandler(cfg, back))
+	system.POST("/services/:serviceName/unlock", handlers.MakeServiceUnlockHandler(back))
+	system.GET("/services/:serviceName/tokens", handlers.MakeServiceTokenListHandler(back))
+	system.POST("/services/:serviceName/tokens", handlers.MakeServiceTokenCreateHandler(back))
+	system.POST("/services/:serviceName/tokens/:tokenName/rotate", handlers.MakeServiceTokenRotateHandler(back))
+	system.DELETE("/services/:serviceName/tokens/:tokenName", handlers.MakeServiceTokenRevokeHandler(back))
+	system.POST("/services/:serviceName/presign", handlers.MakePresignHandler(back))
+	system.GET("/services/:serviceName/callbacks", handlers.MakeCallbackListHandler(back))
+	system.GET("/services/:serviceName/status", handlers.MakeServiceHealthHandler(cfg, back))
+	system.GET("/services/:serviceName/latency", handlers.MakeServiceLatencyHandler(back, kubeClientset, cfg.GetJobsNamespace()))
+	system.GET("/services/:serviceName/schema", handlers.MakeServiceSchemaHandler(back))
+	system.GET("/services/:serviceName/secrets", handlers.MakeServiceSecretsListHandler(cfg, back))
+	system.PUT("/services/:serviceName/secrets", handlers.MakeServiceSecretsUpdateHandler(cfg, back))
+	system.GET("/services/:serviceName/domains", handlers.MakeExposedDomainsListHandler(cfg, back))
+	system.PUT("/services/:serviceName/domains/:domain", handlers.MakeExposedDomainSetHandler(cfg, back))
+	system.DELETE("/services/:serviceName/domains/:domain", handlers.MakeExposedDomainDeleteHandler(cfg, back))
+	system.POST("/services/:serviceName/alias", handlers.MakeAliasCreateHandler(cfg, back))
+	system.GET("/services/:serviceName/alias", handlers.MakeAliasListHandler(cfg, back))
+	system.DELETE("/services/:serviceName/alias/:alias", handlers.MakeAliasDeleteHandler(cfg, back))
+	system.POST("/services/:serviceName/callbacks/:deliveryID/redeliver", handlers.MakeCallbackRedeliverHandler(cfg, kubeClientset, back))
+
+	// Lifecycle events of the services (WebSocket)
+	system.GET("/events/ws", handlers.MakeEventsWebSocketHandler(cfg, back))
+
+	// Asynchronous operations paths
+	system.GET("/operations/:operationID", handlers.MakeOperationReadHandler())
+
+	// Upload sessions paths (two-phase service create)
+	system.GET("/uploads/:uploadID", handlers.MakeUploadReadHandler(cfg))
+	system.PUT("/uploads/:uploadID/assets/:assetName", handlers.MakeUploadAssetHandler(cfg))
+	system.POST("/uploads/:uploadID/activate", handlers.MakeUploadActivateHandler(cfg, back))
+	system.DELETE("/uploads/:uploadID", handlers.MakeUploadDeleteHandler(cfg))
+
+	// Users paths (user store)
+	system.GET("/users", handlers.MakeUserListHandler(cfg, users))
+	system.POST("/users", handlers.MakeUserCreateHandler(cfg, users))
+	system.GET("/users/:username", handlers.MakeUserReadHandler(cfg, users))
+	system.PUT("/users/:username", handlers.MakeUserUpdateHandler(cfg, users))
+	system.DELETE("/users/:username", handlers.MakeUserDeleteHandler(cfg, users))
+
+	// SCIM 2.0 paths to provision the users of the "scim" user store
+	if users != nil && users.SCIM {
+		scim := r.Group("/scim/v2", handlers.MakeSCIMAuthMiddleware(cfg))
+		scim.GET("/Users", handlers.MakeSCIMUserListHandler(users))
+		scim.POST("/Users", handlers.MakeSCIMUserCreateHandler(cfg, users))
+		scim.GET("/Users/:id", handlers.MakeSCIMUserReadHandler(users))
+		scim.PUT("/Users/:id", handlers.MakeSCIMUserReplaceHandler(cfg, users))
+		scim.PATCH("/Users/:id", handlers.MakeSCIMUserPatchHandler(cfg, users))
+		scim.DELETE("/Users/:id", handlers.MakeSCIMUserDeleteHandler(users))
+	}
+
+	// API keys paths
+	system.GET("/apikeys", handlers.MakeAPIKeyListHandler(cfg, apiKeys))
+	system.POST("/apikeys", handlers.MakeAPIKeyCreateHandler(cfg, apiKeys))
+	system.DELETE("/apikeys/:keyName", handlers.MakeAPIKeyRevokeHandler(cfg, apiKeys))
+
+	// Maintenance paths (read-only mode of the API)
+	system.GET("/maintenance/freeze", handlers.MakeMaintenanceStatusHandler(maintenance))
+	system.POST("/maintenance/freeze", handlers.MakeMaintenanceFreezeHandler(cfg, maintenance))
+	system.DELETE("/maintenance/freeze", handlers.MakeMaintenanceUnfreezeHandler(cfg, maintenance))
+
+	// VO requests paths (self-registration of new VOs approved by the admin)
+	system.POST("/vo-requests", handlers.MakeVORequestCreateHandler(cfg, kubeClientset))
+	system.GET("/vo-requests", handlers.MakeVORequestsListHandler(cfg, kubeClientset))
+	system.GET("/vo-requests/:requestID", handlers.MakeVORequestReadHandler(cfg, kubeClientset))
+	system.POST("/vo-requests/:requestID/approve", handlers.MakeVORequestApproveHandler(cfg, kubeClientset))
+	system.POST("/vo-requests/:requestID/reject", handlers.MakeVORequestRejectHandler(cfg, kubeClientset))
+
+	// Reconciliation of the MinIO webhooks and bucket notifications
+	system.POST("/admin/reconcile", handlers.MakeReconcileHandler(cfg, back))
+
+	// Service catalog paths
+	catalog := utils.NewCatalog(cfg)
+	system.GET("/catalog", handlers.MakeCatalogHandler(catalog))
+	system.POST("/catalog/:template/deploy", handlers.MakeIdempotencyMiddleware(), handlers.MakeCatalogDeployHandler(cfg, back, catalog))
+
+	// Image builds paths
+	system.POST("/builds", handlers.MakeBuildCreateHandler(cfg, kubeClientset, back))
+	system.GET("/builds", handlers.MakeBuildListHandler(cfg, kubeClientset))
+	system.GET("/builds/:buildID", handlers.MakeBuildReadHandler(cfg, kubeClientset))
+	system.GET("/builds/:buildID/logs", handlers.MakeBuildLogsHandler(cfg, kubeClientset))
+	system.DELETE("/builds/:buildID", handlers.MakeBuildDeleteHandler(cfg, kubeClientset))
+
+	// Jobs of all the services
+	system.GET("/jobs", handlers.MakeJobListHandler(cfg, back, kubeClientset, cfg.GetJobsNamespace()))
 
 	// Logs paths
-	system.GET("/logs/:serviceName", handlers.MakeJobsInfoHandler(kubeClientset, cfg.ServicesNamespace))
-	system.DELETE("/logs/:serviceName", handlers.MakeDeleteJobsHandler(kubeClientset, cfg.ServicesNamespace))
-	system.GET("/logs/:serviceName/:jobName", handlers.MakeGetLogsHandler(kubeClientset, cfg.ServicesNamespace))
-	system.DELETE("/logs/:serviceName/:jobName", handlers.MakeDeleteJobHandler(kubeClientset, cfg.ServicesNamespace))
+	system.GET("/logs/:serviceName", handlers.MakeJobsInfoHandler(kubeClientset, cfg.GetJobsNamespace()))
+	system.DELETE("/logs/:serviceName", handlers.MakeDeleteJobsHandler(kubeClientset, cfg.GetJobsNamespace()))
+	system.GET("/logs/:serviceName/:jobName", handlers.MakeGetLogsHandler(cfg, kubeClientset))
+	system.GET("/logs/:serviceName/:jobName/stream", handlers.MakeJobLogsStreamHandler(kubeClientset, cfg.GetJobsNamespace()))
+	system.GET("/logs/:serviceName/:jobName/status", handlers.MakeJobStatusHandler(kubeClientset, cfg.GetJobsNamespace()))
+	system.GET("/logs/:serviceName/:jobName/output", handlers.MakeJobOutputsHandler(back, kubeClientset, cfg.GetJobsNamespace()))
+	system.DELETE("/logs/:serviceName/:jobName", handlers.MakeDeleteJobHandler(kubeClientset, cfg.GetJobsNamespace()))
+	system.POST("/logs/:serviceName/:jobName/retry", handlers.MakeJobRetryHandler(cfg, kubeClientset, back, resMan))
 
 	// Job path for async invocations
-	r.POST("/job/:serviceName", handlers.MakeJobHandler(cfg, kubeClientset, back, resMan))
+	r.POST("/job/:serviceName", authzMiddleware, handlers.MakeIdempotencyMiddleware(), handlers.MakeJobHandler(back, dispatcher))
+	r.GET("/job/:serviceName/:jobName", handlers.MakeJobInvocationStatusHandler(back, kubeClientset, cfg.GetJobsNamespace()))
+
+	// Queue of the events of the services with workers, polled by KEDA and consumed by the workers
+	r.GET("/queue/:serviceName", handlers.MakeQueueDepthHandler(back, dispatcher))
+	r.POST("/queue/:serviceName/next", handlers.MakeQueueNextHandler(back, dispatcher))
+	r.POST("/queue/:serviceName/ack", handlers.MakeQueueAckHandler(back, dispatcher))
+
+	// Alias path for invocations through the short aliases of the services
+	r.POST("/i/:alias", authzMiddleware, handlers.MakeIdempotencyMiddleware(), handlers.MakeAliasInvokeHandler(cfg, back, dispatcher))
+
+	// Git path for re-syncing the services' script from their repository
+	r.POST("/git/:serviceName", handlers.MakeGitSyncHandler(cfg, back))
 
 	// Service path for sync invocations (only if ServerlessBackend is enabled)
 	syncBack, ok := back.(types.SyncBackend)
 	if cfg.ServerlessBackend != "" && ok {
-		r.POST("/run/:serviceName", handlers.MakeRunHandler(cfg, syncBack))
+		r.POST("/run/:serviceName", authzMiddleware, handlers.MakeIdempotencyMiddleware(), handlers.MakeRunHandler(cfg, syncBack))
+	}
+
+	// Service paths for async invocations queued in the ServerlessBackend and their results (only if supported)
+	if asyncBack, ok := back.(types.AsyncBackend); ok {
+		r.POST("/run-async/:serviceName", authzMiddleware, handlers.MakeRunAsyncHandler(cfg, asyncBack))
+		r.POST("/async-results/:serviceName/:invocationID", handlers.MakeAsyncResultHandler(back))
+		system.GET("/invocations/:serviceName", handlers.MakeInvocationListHandler(back))
+		system.GET("/invocations/:serviceName/:invocationID", handlers.MakeInvocationReadHandler())
 	}
 
 	// System info path
-	system.GET("/info", handlers.MakeInfoHandler(kubeClientset, back))
+	system.GET("/info", handlers.MakeInfoHandler(cfg, kubeClientset, back))
+
+	// Capacity path (metrics for the delegation policies of the peer clusters)
+	system.GET("/capacity", handlers.MakeCapacityHandler(cfg, kubeClientset, resMan))
+	system.GET("/capabilities", handlers.MakeCapabilitiesHandler(kubeClientset))
+
+	// Metrics path (delivery stats of the MinIO notifications of the services in OpenMetrics format)
+	system.GET("/metrics", handlers.MakeMetricsHandler(cfg, back))
+
+	// Audit log path (management operations and invocations)
+	system.GET("/audit", handlers.MakeAuditHandler(cfg, auditor))
+
+	// GraphQL API (services, jobs, logs and usage)
+	graphQLHandler := handlers.MakeGraphQLHandler(cfg, back, kubeClientset)
+	system.GET("/graphql", graphQLHandler)
+	system.POST("/graphql", graphQLHandler)
+
+	// Usage reports path
+	system.GET("/reports", handlers.MakeReportsHandler(cfg, back, kubeClientset))
+
+	// Usage and quotas path
+	system.GET("/usage", handlers.MakeUsageHandler(cfg, back, kubeClientset))
+
+	// Error catalog path
+	system.GET("/errors", handlers.MakeErrorsHandler())
+
+	// Fault injection paths (only in chaos builds)
+	if chaos.Enabled {
+		system.GET("/chaos/faults", handlers.MakeChaosListHandler())
+		system.POST("/chaos/faults", handlers.MakeChaosAddHandler())
+		system.DELETE("/chaos/faults", handlers.MakeChaosClearHandler())
+	}
 
 	// Serve OSCAR User Interface
 	r.Static("/ui", "./assets")
@@ -118,6 +385,18 @@ func main() {
 	// Health path for k8s health checks
 	r.GET("/health", handlers.HealthHandler)
 
+	// Start the gRPC API (services, jobs, logs and invocations) if enabled
+	if cfg.GRPCPort > 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			log.Fatal(err)
+		}
+		grpcServer := handlers.MakeGRPCServer(cfg, back, kubeClientset, dispatcher)
+		go func() {
+			log.Fatal(grpcServer.Serve(lis))
+		}()
+	}
+
 	// Define and start HTTP server
 	s := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.ServicePort),
@@ -126,5 +405,14 @@ func main() {
 		ReadTimeout:  cfg.ReadTimeout,
 	}
 
+	// Serve the API over HTTPS (requesting the client certificates if enabled)
+	if cfg.TLSCertFile != "" {
+		tlsConfig, err := auth.GetTLSConfig(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.TLSConfig = tlsConfig
+		log.Fatal(s.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile))
+	}
 	log.Fatal(s.ListenAndServe())
 }
@@ -0,0 +1,129 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"net/http"
+
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// ChaosBackend wraps a ServerlessBackend to inject the backend faults in its operations
+type ChaosBackend struct {
+	types.ServerlessBackend
+}
+
+// ChaosSyncBackend wraps a SyncBackend to inject the backend faults in its operations
+type ChaosSyncBackend struct {
+	ChaosBackend
+	sync types.SyncBackend
+}
+
+// ChaosAsyncBackend wraps an AsyncBackend to inject the backend faults in its operations
+type ChaosAsyncBackend struct {
+	ChaosSyncBackend
+	async types.AsyncBackend
+}
+
+// WrapChaosBackend returns the backend wrapped to inject faults in chaos builds, or the same backend otherwise
+func WrapChaosBackend(back types.ServerlessBackend) types.ServerlessBackend {
+	if !chaos.Enabled {
+		return back
+	}
+	if asyncBack, ok := back.(types.AsyncBackend); ok {
+		return &ChaosAsyncBackend{ChaosSyncBackend: ChaosSyncBackend{ChaosBackend: ChaosBackend{back}, sync: asyncBack}, async: asyncBack}
+	}
+	if syncBack, ok := back.(types.SyncBackend); ok {
+		return &ChaosSyncBackend{ChaosBackend: ChaosBackend{back}, sync: syncBack}
+	}
+	return &ChaosBackend{back}
+}
+
+// ListServices injects the faults of the "ListServices" operation
+func (c *ChaosBackend) ListServices() ([]*types.Service, error) {
+	if err := chaos.Inject(chaos.TargetBackend, "ListServices"); err != nil {
+		return nil, err
+	}
+	return c.ServerlessBackend.ListServices()
+}
+
+// CreateService injects the faults of the "CreateService" operation
+func (c *ChaosBackend) CreateService(service types.Service) error {
+	if err := chaos.Inject(chaos.TargetBackend, "CreateService"); err != nil {
+		return err
+	}
+	return c.ServerlessBackend.CreateService(service)
+}
+
+// ReadService injects the faults of the "ReadService" operation
+func (c *ChaosBackend) ReadService(name string) (*types.Service, error) {
+	if err := chaos.Inject(chaos.TargetBackend, "ReadService"); err != nil {
+		return nil, err
+	}
+	return c.ServerlessBackend.ReadService(name)
+}
+
+// UpdateService injects the faults of the "UpdateService" operation
+func (c *ChaosBackend) UpdateService(service types.Service) error {
+	if err := chaos.Inject(chaos.TargetBackend, "UpdateService"); err != nil {
+		return err
+	}
+	return c.ServerlessBackend.UpdateService(service)
+}
+
+// DeleteService injects the faults of the "DeleteService" operation
+func (c *ChaosBackend) DeleteService(name string) error {
+	if err := chaos.Inject(chaos.TargetBackend, "DeleteService"); err != nil {
+		return err
+	}
+	return c.ServerlessBackend.DeleteService(name)
+}
+
+// DryRunService injects the faults of the "DryRunService" operation, checking the service in the wrapped backend
+// if it is a DryRunBackend
+func (c *ChaosBackend) DryRunService(service types.Service, update bool) error {
+	if err := chaos.Inject(chaos.TargetBackend, "DryRunService"); err != nil {
+		return err
+	}
+	if dryRunBack, ok := c.ServerlessBackend.(types.DryRunBackend); ok {
+		return dryRunBack.DryRunService(service, update)
+	}
+	return nil
+}
+
+// UpdateServiceDefinition injects the faults of the "UpdateServiceDefinition" operation, updating the service's
+// definition in the wrapped backend if it is a DefinitionBackend (or the whole service otherwise)
+func (c *ChaosBackend) UpdateServiceDefinition(service types.Service) error {
+	if err := chaos.Inject(chaos.TargetBackend, "UpdateServiceDefinition"); err != nil {
+		return err
+	}
+	if defBack, ok := c.ServerlessBackend.(types.DefinitionBackend); ok {
+		return defBack.UpdateServiceDefinition(service)
+	}
+	return c.ServerlessBackend.UpdateService(service)
+}
+
+// GetProxyDirector returns the proxy director of the wrapped SyncBackend
+func (c *ChaosSyncBackend) GetProxyDirector(serviceName string) func(req *http.Request) {
+	return c.sync.GetProxyDirector(serviceName)
+}
+
+// GetAsyncProxyDirector returns the async proxy director of the wrapped AsyncBackend
+func (c *ChaosAsyncBackend) GetAsyncProxyDirector(serviceName string, callbackURL string) func(req *http.Request) {
+	return c.async.GetAsyncProxyDirector(serviceName, callbackURL)
+}
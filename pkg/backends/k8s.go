@@ -53,22 +53,29 @@ func (k *KubeBackend) GetInfo() *types.ServerlessBackendInfo {
 	return nil
 }
 
-// ListServices returns a slice with all services registered in the provided namespace
+// ListServices returns a slice with all services registered in the services namespace and the VO namespaces
 func (k *KubeBackend) ListServices() ([]*types.Service, error) {
-	// Get the list with all podTemplates
-	podTemplates, err := k.kubeClientset.CoreV1().PodTemplates(k.namespace).List(context.TODO(), metav1.ListOptions{})
+	namespaces, err := listServiceNamespaces(k.config, k.kubeClientset)
 	if err != nil {
 		return nil, err
 	}
 
 	services := []*types.Service{}
-	for _, podTemplate := range podTemplates.Items {
-		// Get service from configMap's FDL
-		svc, err := getServiceFromFDL(podTemplate.Name, k.namespace, k.kubeClientset)
+	for _, namespace := range namespaces {
+		// Get the list with all podTemplates
+		podTemplates, err := k.kubeClientset.CoreV1().PodTemplates(namespace).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			log.Printf("WARNING: %v\n", err)
-		} else {
-			services = append(services, svc)
+			return nil, err
+		}
+
+		for _, podTemplate := range podTemplates.Items {
+			// Get service from configMap's FDL
+			svc, err := getServiceFromFDL(podTemplate.Name, namespace, k.kubeClientset)
+			if err != nil {
+				log.Printf("WARNING: %v\n", err)
+			} else {
+				services = append(services, svc)
+			}
 		}
 	}
 
@@ -79,25 +86,25 @@ func (k *KubeBackend) ListServices() ([]*types.Service, error) {
 func (k *KubeBackend) CreateService(service types.Service) error {
 	// Validate the input variables of the service
 	service = utils.ValidateService(service)
+	namespace, err := prepareServiceNamespace(k.config, k.kubeClientset, &service)
+	if err != nil {
+		return err
+	}
 	// Create the configMap with FDL and user-script
-	err := createServiceConfigMap(&service, k.namespace, k.kubeClientset)
+	err = createServiceConfigMap(&service, namespace, k.kubeClientset)
 	if err != nil {
 		return err
 	}
 
-	if service.VO != "" {
-		for _, vo := range k.config.OIDCGroups {
-			if vo == service.VO {
-				service.Labels["vo"] = service.VO
-			}
-		}
+	if service.VO != "" && k.config.IsAllowedGroup(service.VO) {
+		service.Labels["vo"] = service.VO
 	}
 
 	// Create podSpec from the service
 	podSpec, err := service.ToPodSpec(k.config)
 	if err != nil {
 		// Delete the previously created configMap
-		if delErr := deleteServiceConfigMap(service.Name, k.namespace, k.kubeClientset); delErr != nil {
+		if delErr := deleteServiceConfigMap(service.Name, namespace, k.kubeClientset); delErr != nil {
 			log.Println(delErr.Error())
 		}
 		return err
@@ -107,7 +114,7 @@ func (k *KubeBackend) CreateService(service types.Service) error {
 	podTemplate := &v1.PodTemplate{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        service.Name,
-			Namespace:   k.namespace,
+			Namespace:   namespace,
 			Labels:      service.Labels,
 			Annotations: service.Annotations,
 		},
@@ -115,10 +122,10 @@ func (k *KubeBackend) CreateService(service types.Service) error {
 			Spec: *podSpec,
 		},
 	}
-	_, err = k.kubeClientset.CoreV1().PodTemplates(k.namespace).Create(context.TODO(), podTemplate, metav1.CreateOptions{})
+	_, err = k.kubeClientset.CoreV1().PodTemplates(namespace).Create(context.TODO(), podTemplate, metav1.CreateOptions{})
 	if err != nil {
 		// Delete the previously created configMap
-		if delErr := deleteServiceConfigMap(service.Name, k.namespace, k.kubeClientset); delErr != nil {
+		if delErr := deleteServiceConfigMap(service.Name, namespace, k.kubeClientset); delErr != nil {
 			log.Println(delErr.Error())
 		}
 		return err
@@ -127,15 +134,23 @@ func (k *KubeBackend) CreateService(service types.Service) error {
 	//Create an expose service
 	if service.Expose.Port != 0 {
 		exposeConf := utils.Expose{
-			Name:         service.Name,
-			NameSpace:    k.namespace,
-			Variables:    service.Environment.Vars,
-			Image:        service.Image,
-			Port:         service.Expose.Port,
-			MaxScale:     service.Expose.MaxScale,
-			MinScale:     service.Expose.MinScale,
-			CpuThreshold: service.Expose.CpuThreshold,
-			EnableSGX:    service.EnableSGX,
+			Name:              service.Name,
+			NameSpace:         namespace,
+			Variables:         service.Environment.Vars,
+			Image:             service.Image,
+			Port:              service.Expose.Port,
+			MaxScale:          service.Expose.MaxScale,
+			MinScale:          service.Expose.MinScale,
+			CpuThreshold:      service.Expose.CpuThreshold,
+			EnableSGX:         service.EnableSGX,
+			ExtendedResources: service.GetExtendedResourceLimits(),
+			NodeSelector:      service.NodeSelector,
+			Affinity:          service.Affinity,
+			Tolerations:       service.Tolerations,
+			RuntimeClass:      service.RuntimeClass,
+			SecurityContext:   service.GetSecurityContext(k.config),
+			InitContainers:    service.InitContainers,
+			Sidecars:          service.Sidecars,
 		}
 		utils.CreateExpose(exposeConf, k.kubeClientset, *k.config)
 	}
@@ -152,13 +167,15 @@ func (k *KubeBackend) CreateService(service types.Service) error {
 
 // ReadService returns a Service
 func (k *KubeBackend) ReadService(name string) (*types.Service, error) {
+	namespace := findServiceNamespace(k.config, k.kubeClientset, name)
+
 	// Check if service exists
-	if _, err := k.kubeClientset.CoreV1().PodTemplates(k.namespace).Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+	if _, err := k.kubeClientset.CoreV1().PodTemplates(namespace).Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
 		return nil, err
 	}
 
 	// Get service from configMap's FDL
-	svc, err := getServiceFromFDL(name, k.namespace, k.kubeClientset)
+	svc, err := getServiceFromFDL(name, namespace, k.kubeClientset)
 	if err != nil {
 		return nil, err
 	}
@@ -170,14 +187,15 @@ func (k *KubeBackend) ReadService(name string) (*types.Service, error) {
 func (k *KubeBackend) UpdateService(service types.Service) error {
 	// Validate the input variables of the service
 	service = utils.ValidateService(service)
+	namespace := k.config.GetServiceNamespace(&service)
 	// Get the old service's configMap
-	oldCm, err := k.kubeClientset.CoreV1().ConfigMaps(k.namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+	oldCm, err := k.kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("the service \"%s\" does not have a registered ConfigMap", service.Name)
 	}
 
 	// Update the configMap with FDL and user-script
-	if err := updateServiceConfigMap(&service, k.namespace, k.kubeClientset); err != nil {
+	if err := updateServiceConfigMap(&service, namespace, k.kubeClientset); err != nil {
 		return err
 	}
 
@@ -185,7 +203,7 @@ func (k *KubeBackend) UpdateService(service types.Service) error {
 	podSpec, err := service.ToPodSpec(k.config)
 	if err != nil {
 		// Restore the old configMap
-		_, resErr := k.kubeClientset.CoreV1().ConfigMaps(k.namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
+		_, resErr := k.kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
 		if resErr != nil {
 			log.Println(resErr.Error())
 		}
@@ -196,7 +214,7 @@ func (k *KubeBackend) UpdateService(service types.Service) error {
 	podTemplate := &v1.PodTemplate{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      service.Name,
-			Namespace: k.namespace,
+			Namespace: namespace,
 			Labels: map[string]string{
 				types.ServiceLabel: service.Name,
 			},
@@ -205,10 +223,10 @@ func (k *KubeBackend) UpdateService(service types.Service) error {
 			Spec: *podSpec,
 		},
 	}
-	_, err = k.kubeClientset.CoreV1().PodTemplates(k.namespace).Update(context.TODO(), podTemplate, metav1.UpdateOptions{})
+	_, err = k.kubeClientset.CoreV1().PodTemplates(namespace).Update(context.TODO(), podTemplate, metav1.UpdateOptions{})
 	if err != nil {
 		// Restore the old configMap
-		_, resErr := k.kubeClientset.CoreV1().ConfigMaps(k.namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
+		_, resErr := k.kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
 		if resErr != nil {
 			log.Println(resErr.Error())
 		}
@@ -217,39 +235,90 @@ func (k *KubeBackend) UpdateService(service types.Service) error {
 
 	//Update an expose service
 	exposeConf := utils.Expose{
-		Name:         service.Name,
-		NameSpace:    k.namespace,
-		Variables:    service.Environment.Vars,
-		Image:        service.Image,
-		Port:         service.Expose.Port,
-		MaxScale:     service.Expose.MaxScale,
-		MinScale:     service.Expose.MinScale,
-		CpuThreshold: service.Expose.CpuThreshold,
-		EnableSGX:    service.EnableSGX,
+		Name:              service.Name,
+		NameSpace:         namespace,
+		Variables:         service.Environment.Vars,
+		Image:             service.Image,
+		Port:              service.Expose.Port,
+		MaxScale:          service.Expose.MaxScale,
+		MinScale:          service.Expose.MinScale,
+		CpuThreshold:      service.Expose.CpuThreshold,
+		EnableSGX:         service.EnableSGX,
+		ExtendedResources: service.GetExtendedResourceLimits(),
+		NodeSelector:      service.NodeSelector,
+		Affinity:          service.Affinity,
+		Tolerations:       service.Tolerations,
+		RuntimeClass:      service.RuntimeClass,
+		SecurityContext:   service.GetSecurityContext(k.config),
+		InitContainers:    service.InitContainers,
+		Sidecars:          service.Sidecars,
 	}
 	utils.UpdateExpose(exposeConf, k.kubeClientset, *k.config)
 
 	return nil
 }
 
+// UpdateServiceDefinition updates the service's configMap without updating its podTemplate. The jobs are created
+// from the stored definition, so the new jobs get the updated definition
+func (k *KubeBackend) UpdateServiceDefinition(service types.Service) error {
+	namespace := k.config.GetServiceNamespace(&service)
+	return updateServiceConfigMap(&service, namespace, k.kubeClientset)
+}
+
+// DryRunService checks the creation (or update) of the service's configMap and podTemplate with a server-side dry-run
+func (k *KubeBackend) DryRunService(service types.Service, update bool) error {
+	// Validate the input variables of the service
+	service = utils.ValidateService(service)
+	namespace := getDryRunNamespace(k.config, k.kubeClientset, &service)
+	if err := dryRunServiceConfigMap(&service, namespace, k.kubeClientset, update); err != nil {
+		return err
+	}
+
+	// Create podSpec from the service
+	podSpec, err := service.ToPodSpec(k.config)
+	if err != nil {
+		return err
+	}
+
+	podTemplate := &v1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        service.Name,
+			Namespace:   namespace,
+			Labels:      service.Labels,
+			Annotations: service.Annotations,
+		},
+		Template: v1.PodTemplateSpec{
+			Spec: *podSpec,
+		},
+	}
+	if update {
+		_, err = k.kubeClientset.CoreV1().PodTemplates(namespace).Update(context.TODO(), podTemplate, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	} else {
+		_, err = k.kubeClientset.CoreV1().PodTemplates(namespace).Create(context.TODO(), podTemplate, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	}
+	return err
+}
+
 // DeleteService deletes a service
 func (k *KubeBackend) DeleteService(name string) error {
-	if err := k.kubeClientset.CoreV1().PodTemplates(k.namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
+	namespace := findServiceNamespace(k.config, k.kubeClientset, name)
+
+	if err := k.kubeClientset.CoreV1().PodTemplates(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
 		return err
 	}
 
 	// Delete the service's configMap
-	if delErr := deleteServiceConfigMap(name, k.namespace, k.kubeClientset); delErr != nil {
+	if delErr := deleteServiceConfigMap(name, namespace, k.kubeClientset); delErr != nil {
 		log.Println(delErr.Error())
 	}
 
 	// Delete all the service's jobs
-	if err := deleteServiceJobs(name, k.namespace, k.kubeClientset); err != nil {
+	if err := deleteServiceJobs(name, namespace, k.kubeClientset); err != nil {
 		log.Printf("Error deleting associated jobs for service \"%s\": %v\n", name, err)
 	}
 	exposeConf := utils.Expose{
 		Name:      name,
-		NameSpace: k.namespace,
+		NameSpace: namespace,
 		Port:      80,
 	}
 	if err2 := utils.DeleteExpose(exposeConf, k.kubeClientset); err2 != nil {
@@ -278,32 +347,10 @@ func getServiceFromFDL(name string, namespace string, kubeClientset kubernetes.I
 }
 
 func createServiceConfigMap(service *types.Service, namespace string, kubeClientset kubernetes.Interface) error {
-	// Copy script from service
-	script := service.Script
-
-	// Clear script from YAML
-	service.Script = ""
-
-	// Create FDL YAML
-	fdl, err := service.ToYAML()
+	cm, err := makeServiceConfigMap(service, namespace)
 	if err != nil {
 		return err
 	}
-
-	// Create ConfigMap
-	cm := &v1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      service.Name,
-			Namespace: namespace,
-			Labels: map[string]string{
-				types.ServiceLabel: service.Name,
-			},
-		},
-		Data: map[string]string{
-			types.ScriptFileName: script,
-			types.FDLFileName:    fdl,
-		},
-	}
 	_, err = kubeClientset.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
 	if err != nil {
 		return err
@@ -313,6 +360,34 @@ func createServiceConfigMap(service *types.Service, namespace string, kubeClient
 }
 
 func updateServiceConfigMap(service *types.Service, namespace string, kubeClientset kubernetes.Interface) error {
+	cm, err := makeServiceConfigMap(service, namespace)
+	if err != nil {
+		return err
+	}
+	_, err = kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// dryRunServiceConfigMap checks the creation (or update) of the service's configMap with a server-side dry-run
+func dryRunServiceConfigMap(service *types.Service, namespace string, kubeClientset kubernetes.Interface, update bool) error {
+	cm, err := makeServiceConfigMap(service, namespace)
+	if err != nil {
+		return err
+	}
+	if update {
+		_, err = kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), cm, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	} else {
+		_, err = kubeClientset.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	}
+	return err
+}
+
+// makeServiceConfigMap returns the service's configMap with the FDL, user-script and feature flags
+func makeServiceConfigMap(service *types.Service, namespace string) (*v1.ConfigMap, error) {
 	// Copy script from service
 	script := service.Script
 
@@ -322,11 +397,11 @@ func updateServiceConfigMap(service *types.Service, namespace string, kubeClient
 	// Create FDL YAML
 	fdl, err := service.ToYAML()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Create ConfigMap
-	cm := &v1.ConfigMap{
+	return &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      service.Name,
 			Namespace: namespace,
@@ -335,16 +410,11 @@ func updateServiceConfigMap(service *types.Service, namespace string, kubeClient
 			},
 		},
 		Data: map[string]string{
-			types.ScriptFileName: script,
-			types.FDLFileName:    fdl,
+			types.ScriptFileName:   script,
+			types.FDLFileName:      fdl,
+			types.FeaturesFileName: service.FeaturesEnvFile(),
 		},
-	}
-	_, err = kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
-	if err != nil {
-		return err
-	}
-
-	return nil
+	}, nil
 }
 
 func deleteServiceConfigMap(name string, namespace string, kubeClientset kubernetes.Interface) error {
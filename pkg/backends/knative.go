@@ -21,7 +21,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/grycap/oscar/v2/pkg/imagepuller"
 	"github.com/grycap/oscar/v2/pkg/types"
@@ -70,22 +69,29 @@ func (kn *KnativeBackend) GetInfo() *types.ServerlessBackendInfo {
 	return backInfo
 }
 
-// ListServices returns a slice with all services registered in the provided namespace
+// ListServices returns a slice with all services registered in the services namespace and the VO namespaces
 func (kn *KnativeBackend) ListServices() ([]*types.Service, error) {
-	// Get the list with all Knative services
-	knSvcs, err := kn.knClientset.ServingV1().Services(kn.namespace).List(context.TODO(), metav1.ListOptions{})
+	namespaces, err := listServiceNamespaces(kn.config, kn.kubeClientset)
 	if err != nil {
 		return nil, err
 	}
 
 	services := []*types.Service{}
-	for _, knSvc := range knSvcs.Items {
-		// Get service from configMap's FDL
-		svc, err := getServiceFromFDL(knSvc.Name, kn.namespace, kn.kubeClientset)
+	for _, namespace := range namespaces {
+		// Get the list with all Knative services
+		knSvcs, err := kn.knClientset.ServingV1().Services(namespace).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
-			log.Printf("WARNING: %v\n", err)
-		} else {
-			services = append(services, svc)
+			return nil, err
+		}
+
+		for _, knSvc := range knSvcs.Items {
+			// Get service from configMap's FDL
+			svc, err := getServiceFromFDL(knSvc.Name, namespace, kn.kubeClientset)
+			if err != nil {
+				log.Printf("WARNING: %v\n", err)
+			} else {
+				services = append(services, svc)
+			}
 		}
 	}
 
@@ -96,8 +102,12 @@ func (kn *KnativeBackend) ListServices() ([]*types.Service, error) {
 func (kn *KnativeBackend) CreateService(service types.Service) error {
 	// Validate the input variables of the service
 	service = utils.ValidateService(service)
+	namespace, err := prepareServiceNamespace(kn.config, kn.kubeClientset, &service)
+	if err != nil {
+		return err
+	}
 	// Create the configMap with FDL and user-script
-	err := createServiceConfigMap(&service, kn.namespace, kn.kubeClientset)
+	err = createServiceConfigMap(&service, namespace, kn.kubeClientset)
 	if err != nil {
 		return err
 	}
@@ -106,17 +116,17 @@ func (kn *KnativeBackend) CreateService(service types.Service) error {
 	knSvc, err := kn.createKNServiceDefinition(&service)
 	if err != nil {
 		// Delete the previously created configMap
-		if delErr := deleteServiceConfigMap(service.Name, kn.namespace, kn.kubeClientset); delErr != nil {
+		if delErr := deleteServiceConfigMap(service.Name, namespace, kn.kubeClientset); delErr != nil {
 			log.Println(delErr.Error())
 		}
 		return err
 	}
 
 	// Create the Knative service
-	_, err = kn.knClientset.ServingV1().Services(kn.namespace).Create(context.TODO(), knSvc, metav1.CreateOptions{})
+	_, err = kn.knClientset.ServingV1().Services(namespace).Create(context.TODO(), knSvc, metav1.CreateOptions{})
 	if err != nil {
 		// Delete the previously created configMap
-		if delErr := deleteServiceConfigMap(service.Name, kn.namespace, kn.kubeClientset); delErr != nil {
+		if delErr := deleteServiceConfigMap(service.Name, namespace, kn.kubeClientset); delErr != nil {
 			log.Println(delErr.Error())
 		}
 		return err
@@ -125,15 +135,23 @@ func (kn *KnativeBackend) CreateService(service types.Service) error {
 	//Create an expose service
 	if service.Expose.Port != 0 {
 		exposeConf := utils.Expose{
-			Name:         service.Name,
-			NameSpace:    kn.namespace,
-			Variables:    service.Environment.Vars,
-			Image:        service.Image,
-			Port:         service.Expose.Port,
-			MaxScale:     service.Expose.MaxScale,
-			MinScale:     service.Expose.MinScale,
-			CpuThreshold: service.Expose.CpuThreshold,
-			EnableSGX:    service.EnableSGX,
+			Name:              service.Name,
+			NameSpace:         namespace,
+			Variables:         service.Environment.Vars,
+			Image:             service.Image,
+			Port:              service.Expose.Port,
+			MaxScale:          service.Expose.MaxScale,
+			MinScale:          service.Expose.MinScale,
+			CpuThreshold:      service.Expose.CpuThreshold,
+			EnableSGX:         service.EnableSGX,
+			ExtendedResources: service.GetExtendedResourceLimits(),
+			NodeSelector:      service.NodeSelector,
+			Affinity:          service.Affinity,
+			Tolerations:       service.Tolerations,
+			RuntimeClass:      service.RuntimeClass,
+			SecurityContext:   service.GetSecurityContext(kn.config),
+			InitContainers:    service.InitContainers,
+			Sidecars:          service.Sidecars,
 		}
 		utils.CreateExpose(exposeConf, kn.kubeClientset, *kn.config)
 
@@ -151,13 +169,15 @@ func (kn *KnativeBackend) CreateService(service types.Service) error {
 
 // ReadService returns a Service
 func (kn *KnativeBackend) ReadService(name string) (*types.Service, error) {
+	namespace := findServiceNamespace(kn.config, kn.kubeClientset, name)
+
 	// Check if service exists
-	if _, err := kn.knClientset.ServingV1().Services(kn.namespace).Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+	if _, err := kn.knClientset.ServingV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
 		return nil, err
 	}
 
 	// Get service from configMap's FDL
-	svc, err := getServiceFromFDL(name, kn.namespace, kn.kubeClientset)
+	svc, err := getServiceFromFDL(name, namespace, kn.kubeClientset)
 	if err != nil {
 		return nil, err
 	}
@@ -167,21 +187,23 @@ func (kn *KnativeBackend) ReadService(name string) (*types.Service, error) {
 
 // UpdateService updates an existent service
 func (kn *KnativeBackend) UpdateService(service types.Service) error {
+	namespace := kn.config.GetServiceNamespace(&service)
+
 	// Get the old knative service
-	oldSvc, err := kn.knClientset.ServingV1().Services(kn.namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+	oldSvc, err := kn.knClientset.ServingV1().Services(namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 	// Validate the input variables of the service
 	service = utils.ValidateService(service)
 	// Get the old service's configMap
-	oldCm, err := kn.kubeClientset.CoreV1().ConfigMaps(kn.namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+	oldCm, err := kn.kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("the service \"%s\" does not have a registered ConfigMap", service.Name)
 	}
 
 	// Update the configMap with FDL and user-script
-	if err := updateServiceConfigMap(&service, kn.namespace, kn.kubeClientset); err != nil {
+	if err := updateServiceConfigMap(&service, namespace, kn.kubeClientset); err != nil {
 		return err
 	}
 
@@ -189,7 +211,7 @@ func (kn *KnativeBackend) UpdateService(service types.Service) error {
 	knSvc, err := kn.createKNServiceDefinition(&service)
 	if err != nil {
 		// Restore the old configMap
-		_, resErr := kn.kubeClientset.CoreV1().ConfigMaps(kn.namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
+		_, resErr := kn.kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
 		if resErr != nil {
 			log.Println(resErr.Error())
 		}
@@ -205,10 +227,10 @@ func (kn *KnativeBackend) UpdateService(service types.Service) error {
 	}
 
 	// Update the Knative service
-	_, err = kn.knClientset.ServingV1().Services(kn.namespace).Update(context.TODO(), oldSvc, metav1.UpdateOptions{})
+	_, err = kn.knClientset.ServingV1().Services(namespace).Update(context.TODO(), oldSvc, metav1.UpdateOptions{})
 	if err != nil {
 		// Restore the old configMap
-		_, resErr := kn.kubeClientset.CoreV1().ConfigMaps(kn.namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
+		_, resErr := kn.kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), oldCm, metav1.UpdateOptions{})
 		if resErr != nil {
 			log.Println(resErr.Error())
 		}
@@ -217,39 +239,91 @@ func (kn *KnativeBackend) UpdateService(service types.Service) error {
 
 	//Update an expose service
 	exposeConf := utils.Expose{
-		Name:         service.Name,
-		NameSpace:    kn.namespace,
-		Variables:    service.Environment.Vars,
-		Image:        service.Image,
-		Port:         service.Expose.Port,
-		MaxScale:     service.Expose.MaxScale,
-		MinScale:     service.Expose.MinScale,
-		CpuThreshold: service.Expose.CpuThreshold,
-		EnableSGX:    service.EnableSGX,
+		Name:              service.Name,
+		NameSpace:         namespace,
+		Variables:         service.Environment.Vars,
+		Image:             service.Image,
+		Port:              service.Expose.Port,
+		MaxScale:          service.Expose.MaxScale,
+		MinScale:          service.Expose.MinScale,
+		CpuThreshold:      service.Expose.CpuThreshold,
+		EnableSGX:         service.EnableSGX,
+		ExtendedResources: service.GetExtendedResourceLimits(),
+		NodeSelector:      service.NodeSelector,
+		Affinity:          service.Affinity,
+		Tolerations:       service.Tolerations,
+		RuntimeClass:      service.RuntimeClass,
+		SecurityContext:   service.GetSecurityContext(kn.config),
+		InitContainers:    service.InitContainers,
+		Sidecars:          service.Sidecars,
 	}
 	utils.UpdateExpose(exposeConf, kn.kubeClientset, *kn.config)
 
 	return nil
 }
 
+// UpdateServiceDefinition updates the service's configMap without redeploying the Knative service. The files of the
+// configMap are updated in the running containers, but not their environment variables
+func (kn *KnativeBackend) UpdateServiceDefinition(service types.Service) error {
+	namespace := kn.config.GetServiceNamespace(&service)
+	return updateServiceConfigMap(&service, namespace, kn.kubeClientset)
+}
+
+// DryRunService checks the creation (or update) of the service's configMap and Knative service with a server-side dry-run
+func (kn *KnativeBackend) DryRunService(service types.Service, update bool) error {
+	// Validate the input variables of the service
+	service = utils.ValidateService(service)
+	namespace := getDryRunNamespace(kn.config, kn.kubeClientset, &service)
+	if err := dryRunServiceConfigMap(&service, namespace, kn.kubeClientset, update); err != nil {
+		return err
+	}
+
+	// Create the Knative service definition
+	knSvc, err := kn.createKNServiceDefinition(&service)
+	if err != nil {
+		return err
+	}
+	knSvc.Namespace = namespace
+
+	if !update {
+		_, err = kn.knClientset.ServingV1().Services(namespace).Create(context.TODO(), knSvc, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+		return err
+	}
+
+	// Set the new service's values on the old Knative service (as in UpdateService)
+	oldSvc, err := kn.knClientset.ServingV1().Services(namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	oldSvc.ObjectMeta.Labels = knSvc.ObjectMeta.Labels
+	oldSvc.Spec = knSvc.Spec
+	for k, v := range knSvc.ObjectMeta.Annotations {
+		oldSvc.ObjectMeta.Annotations[k] = v
+	}
+	_, err = kn.knClientset.ServingV1().Services(namespace).Update(context.TODO(), oldSvc, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	return err
+}
+
 // DeleteService deletes a service
 func (kn *KnativeBackend) DeleteService(name string) error {
-	if err := kn.knClientset.ServingV1().Services(kn.namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
+	namespace := findServiceNamespace(kn.config, kn.kubeClientset, name)
+
+	if err := kn.knClientset.ServingV1().Services(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
 		return err
 	}
 
 	// Delete the service's configMap
-	if delErr := deleteServiceConfigMap(name, kn.namespace, kn.kubeClientset); delErr != nil {
+	if delErr := deleteServiceConfigMap(name, namespace, kn.kubeClientset); delErr != nil {
 		log.Println(delErr.Error())
 	}
 
 	// Delete all the service's jobs
-	if err := deleteServiceJobs(name, kn.namespace, kn.kubeClientset); err != nil {
+	if err := deleteServiceJobs(name, namespace, kn.kubeClientset); err != nil {
 		log.Printf("Error deleting associated jobs for service \"%s\": %v\n", name, err)
 	}
 	exposeConf := utils.Expose{
 		Name:      name,
-		NameSpace: kn.namespace,
+		NameSpace: namespace,
 		Port:      80,
 	}
 	if err2 := utils.DeleteExpose(exposeConf, kn.kubeClientset); err2 != nil {
@@ -261,10 +335,11 @@ func (kn *KnativeBackend) DeleteService(name string) error {
 
 // GetProxyDirector returns a director function to use in a httputil.ReverseProxy
 func (kn *KnativeBackend) GetProxyDirector(serviceName string) func(req *http.Request) {
+	namespace := findServiceNamespace(kn.config, kn.kubeClientset, serviceName)
 	return func(req *http.Request) {
 		// Set the request Host parameter to avoid issues in the redirection
 		// related issue: https://github.com/golang/go/issues/7682
-		host := fmt.Sprintf("%s.%s", serviceName, kn.namespace)
+		host := fmt.Sprintf("%s.%s", serviceName, namespace)
 		req.Host = host
 
 		req.URL.Scheme = "http"
@@ -279,12 +354,8 @@ func (kn *KnativeBackend) createKNServiceDefinition(service *types.Service) (*kn
 	service.Labels[types.KnativeVisibilityLabel] = types.KnativeClusterLocalValue
 
 	// Add to the service labels the user VO for accounting on k8s pods
-	if service.VO != "" {
-		for _, vo := range kn.config.OIDCGroups {
-			if vo == service.VO {
-				service.Labels["vo"] = service.VO
-			}
-		}
+	if service.VO != "" && kn.config.IsAllowedGroup(service.VO) {
+		service.Labels["vo"] = service.VO
 	}
 
 	podSpec, err := service.ToPodSpec(kn.config)
@@ -292,13 +363,13 @@ func (kn *KnativeBackend) createKNServiceDefinition(service *types.Service) (*kn
 		return nil, err
 	}
 
-	// fix ContainerConcurrency to 1 to avoid parallel invocations in the same container
-	containerConcurrency := int64(1)
+	// ContainerConcurrency defaults to 1 to avoid parallel invocations in the same container
+	containerConcurrency := int64(service.GetContainerConcurrency())
 
 	knSvc := &knv1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        service.Name,
-			Namespace:   kn.namespace,
+			Namespace:   kn.config.GetServiceNamespace(service),
 			Labels:      service.Labels,
 			Annotations: service.Annotations,
 		},
@@ -306,11 +377,8 @@ func (kn *KnativeBackend) createKNServiceDefinition(service *types.Service) (*kn
 			ConfigurationSpec: knv1.ConfigurationSpec{
 				Template: knv1.RevisionTemplateSpec{
 					ObjectMeta: metav1.ObjectMeta{
-						Annotations: map[string]string{
-							// Set autoscaling bounds (min_scale and max_scale)
-							types.KnativeMinScaleAnnotation: strconv.Itoa(service.Synchronous.MinScale),
-							types.KnativeMaxScaleAnnotation: strconv.Itoa(service.Synchronous.MaxScale),
-						},
+						// Set autoscaling bounds (min_scale and max_scale), target and scale-down delay
+						Annotations: service.GetKnativeAutoscalingAnnotations(),
 						//Empty labels map to avoid nil pointer errors
 						Labels: map[string]string{},
 					},
@@ -712,6 +712,36 @@ func TestKnativeGetProxyDirector(t *testing.T) {
 	}
 }
 
+func TestKnativeCreateServiceAutoscaling(t *testing.T) {
+	back := MakeKnativeBackend(fake.NewSimpleClientset(), fakeConfig, testConfig)
+
+	service := types.Service{Name: "test", Labels: map[string]string{}}
+	service.Synchronous.MaxScale = 3
+	service.Synchronous.TargetConcurrency = 2
+	service.Synchronous.ContainerConcurrency = 4
+	service.Synchronous.ScaleDownDelay = "5m"
+
+	knSvc, err := back.createKNServiceDefinition(&service)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := knSvc.Spec.ConfigurationSpec.Template
+	if cc := *template.Spec.ContainerConcurrency; cc != 4 {
+		t.Errorf("expecting container concurrency 4, got %d", cc)
+	}
+	expected := map[string]string{
+		types.KnativeMinScaleAnnotation:       "0",
+		types.KnativeMaxScaleAnnotation:       "3",
+		types.KnativeTargetAnnotation:         "2",
+		types.KnativeScaleDownDelayAnnotation: "5m",
+	}
+	for k, v := range expected {
+		if template.ObjectMeta.Annotations[k] != v {
+			t.Errorf("expecting annotation %s=%s, got %s", k, v, template.ObjectMeta.Annotations[k])
+		}
+	}
+}
+
 func TestKnativeKubeGetKubeClientset(t *testing.T) {
 	clientset := fake.NewSimpleClientset()
 
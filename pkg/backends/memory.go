@@ -0,0 +1,186 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// servicesResource resource reported in the errors of the MemoryBackend
+var servicesResource = schema.GroupResource{Resource: "services"}
+
+// MemoryBackend in-memory implementation of the ServerlessBackend interface, storing the services
+// in a map and using a fake Kubernetes clientset. The errors added with AddError are returned
+// before performing the operations
+type MemoryBackend struct {
+	*FakeBackend
+	services      map[string]types.Service
+	kubeClientset kubernetes.Interface
+	mutex         sync.Mutex
+}
+
+// MakeMemoryBackend returns the pointer of a new MemoryBackend without services
+func MakeMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		FakeBackend:   MakeFakeBackend(),
+		services:      map[string]types.Service{},
+		kubeClientset: testclient.NewSimpleClientset(),
+	}
+}
+
+// GetInfo returns the ServerlessBackendInfo with the name and version
+func (m *MemoryBackend) GetInfo() *types.ServerlessBackendInfo {
+	return &types.ServerlessBackendInfo{
+		Name:    "memory-backend",
+		Version: "devel",
+	}
+}
+
+// ListServices returns all the stored services sorted by name
+func (m *MemoryBackend) ListServices() ([]*types.Service, error) {
+	if err := m.returnError("ListServices"); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	services := []*types.Service{}
+	for _, service := range m.services {
+		services = append(services, copyService(service))
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	return services, nil
+}
+
+// CreateService stores a new service
+func (m *MemoryBackend) CreateService(service types.Service) error {
+	if err := m.returnError("CreateService"); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.services[service.Name]; ok {
+		return k8serr.NewAlreadyExists(servicesResource, service.Name)
+	}
+	m.services[service.Name] = *copyService(service)
+
+	return nil
+}
+
+// ReadService returns a copy of a stored service
+func (m *MemoryBackend) ReadService(name string) (*types.Service, error) {
+	if err := m.returnError("ReadService"); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	service, ok := m.services[name]
+	if !ok {
+		return nil, k8serr.NewNotFound(servicesResource, name)
+	}
+
+	return copyService(service), nil
+}
+
+// UpdateService replaces a stored service
+func (m *MemoryBackend) UpdateService(service types.Service) error {
+	if err := m.returnError("UpdateService"); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.services[service.Name]; !ok {
+		return k8serr.NewNotFound(servicesResource, service.Name)
+	}
+	m.services[service.Name] = *copyService(service)
+
+	return nil
+}
+
+// UpdateServiceDefinition replaces a stored service, as UpdateService
+func (m *MemoryBackend) UpdateServiceDefinition(service types.Service) error {
+	if err := m.returnError("UpdateServiceDefinition"); err != nil {
+		return err
+	}
+	return m.UpdateService(service)
+}
+
+// DeleteService removes a stored service
+func (m *MemoryBackend) DeleteService(name string) error {
+	if err := m.returnError("DeleteService"); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.services[name]; !ok {
+		return k8serr.NewNotFound(servicesResource, name)
+	}
+	delete(m.services, name)
+
+	return nil
+}
+
+// DryRunService checks if the service can be created (or updated) without storing it
+func (m *MemoryBackend) DryRunService(service types.Service, update bool) error {
+	if err := m.returnError("DryRunService"); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, ok := m.services[service.Name]
+	if update && !ok {
+		return k8serr.NewNotFound(servicesResource, service.Name)
+	}
+	if !update && ok {
+		return k8serr.NewAlreadyExists(servicesResource, service.Name)
+	}
+
+	return nil
+}
+
+// GetKubeClientset returns the fake Kubernetes clientset of the backend
+func (m *MemoryBackend) GetKubeClientset() kubernetes.Interface {
+	return m.kubeClientset
+}
+
+// copyService returns a deep copy of a service, so the stored services can't be modified by the callers
+func copyService(service types.Service) *types.Service {
+	serviceCopy := &types.Service{}
+	serviceBytes, _ := json.Marshal(service)
+	json.Unmarshal(serviceBytes, serviceCopy)
+	return serviceCopy
+}
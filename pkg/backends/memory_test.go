@@ -0,0 +1,75 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	back := MakeMemoryBackend()
+
+	if err := back.CreateService(types.Service{Name: "b", Image: "image"}); err != nil {
+		t.Fatalf("error creating the service: %v", err)
+	}
+	if err := back.CreateService(types.Service{Name: "a"}); err != nil {
+		t.Fatalf("error creating the service: %v", err)
+	}
+	if err := back.CreateService(types.Service{Name: "a"}); !k8serr.IsAlreadyExists(err) {
+		t.Errorf("expecting already exists error, got: %v", err)
+	}
+
+	services, err := back.ListServices()
+	if err != nil {
+		t.Fatalf("error listing the services: %v", err)
+	}
+	if len(services) != 2 || services[0].Name != "a" || services[1].Name != "b" {
+		t.Errorf("expecting services \"a\" and \"b\", got %v", services)
+	}
+
+	// The returned services are copies
+	service, _ := back.ReadService("b")
+	service.Image = "modified"
+	if service, _ := back.ReadService("b"); service.Image != "image" {
+		t.Errorf("expecting the stored service not to be modified, got image \"%s\"", service.Image)
+	}
+
+	service.Image = "updated"
+	if err := back.UpdateService(*service); err != nil {
+		t.Fatalf("error updating the service: %v", err)
+	}
+	if service, _ := back.ReadService("b"); service.Image != "updated" {
+		t.Errorf("expecting the service to be updated, got image \"%s\"", service.Image)
+	}
+	if err := back.UpdateService(types.Service{Name: "c"}); !k8serr.IsNotFound(err) {
+		t.Errorf("expecting not found error, got: %v", err)
+	}
+
+	back.AddError("DeleteService", errFake)
+	if err := back.DeleteService("a"); err != errFake {
+		t.Errorf("expecting the added error, got: %v", err)
+	}
+	if err := back.DeleteService("a"); err != nil {
+		t.Errorf("expecting no error, got: %v", err)
+	}
+	if _, err := back.ReadService("a"); !k8serr.IsNotFound(err) {
+		t.Errorf("expecting not found error, got: %v", err)
+	}
+}
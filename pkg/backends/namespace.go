@@ -0,0 +1,84 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// findServiceNamespace returns the namespace of a service from its configMap, which can be in the namespace of
+// its VO with VONamespaces enabled. The services namespace is returned if the configMap is not found
+func findServiceNamespace(cfg *types.Config, kubeClientset kubernetes.Interface, name string) string {
+	if !cfg.VONamespaces {
+		return cfg.ServicesNamespace
+	}
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", types.ServiceLabel, name),
+	}
+	cms, err := kubeClientset.CoreV1().ConfigMaps(metav1.NamespaceAll).List(context.TODO(), listOpts)
+	if err != nil {
+		return cfg.ServicesNamespace
+	}
+	// Other configMaps of the service (revisions, aliases...) have the same label
+	for _, cm := range cms.Items {
+		if cm.Name == name {
+			return cm.Namespace
+		}
+	}
+	return cfg.ServicesNamespace
+}
+
+// listServiceNamespaces returns the namespaces with services: the services namespace and the VO namespaces
+func listServiceNamespaces(cfg *types.Config, kubeClientset kubernetes.Interface) ([]string, error) {
+	if !cfg.VONamespaces {
+		return []string{cfg.ServicesNamespace}, nil
+	}
+	voNamespaces, err := utils.ListVONamespaces(kubeClientset)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{cfg.ServicesNamespace}, voNamespaces...), nil
+}
+
+// prepareServiceNamespace returns the namespace of a new service, creating the namespace of its VO (with its quota
+// and limits) if needed
+func prepareServiceNamespace(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service) (string, error) {
+	namespace := cfg.GetServiceNamespace(service)
+	if namespace == cfg.ServicesNamespace {
+		return namespace, nil
+	}
+	if err := utils.EnsureVONamespace(cfg, kubeClientset, namespace, service.VO); err != nil {
+		return "", err
+	}
+	return namespace, nil
+}
+
+// getDryRunNamespace returns the namespace to dry-run the creation or update of a service: the services namespace
+// if the namespace of its VO does not exist yet
+func getDryRunNamespace(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service) string {
+	namespace := cfg.GetServiceNamespace(service)
+	if _, err := kubeClientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{}); err != nil {
+		return cfg.ServicesNamespace
+	}
+	return namespace
+}
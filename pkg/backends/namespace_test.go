@@ -0,0 +1,91 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubeVONamespaces(t *testing.T) {
+	cfg := &types.Config{
+		ServicesNamespace: "oscar-svc",
+		VONamespaces:      true,
+		VONamespaceQuota:  "requests.cpu=4,pods=10",
+		VONamespaceLimits: "cpu=500m,memory=512Mi",
+	}
+	clientset := fake.NewSimpleClientset()
+	back := MakeKubeBackend(clientset, cfg)
+
+	voService := types.Service{Name: "vo-svc", VO: "vo.Example.eu", Labels: map[string]string{}}
+	voService.Namespace = cfg.SelectServiceNamespace(&voService)
+	if voService.Namespace != "oscar-svc-vo-example-eu" {
+		t.Fatalf("expecting namespace \"oscar-svc-vo-example-eu\", got \"%s\"", voService.Namespace)
+	}
+	if err := back.CreateService(voService); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := back.CreateService(types.Service{Name: "svc", Labels: map[string]string{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The namespace of the VO is created with its quota and limits
+	ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), voService.Namespace, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expecting the namespace of the VO, got error: %v", err)
+	}
+	if ns.Annotations[types.VOAnnotation] != "vo.Example.eu" {
+		t.Errorf("expecting the VO annotation \"vo.Example.eu\", got \"%s\"", ns.Annotations[types.VOAnnotation])
+	}
+	quota, err := clientset.CoreV1().ResourceQuotas(voService.Namespace).Get(context.TODO(), utils.VOResourceQuotaName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expecting the quota of the VO, got error: %v", err)
+	}
+	if pods := quota.Spec.Hard["pods"]; pods.String() != "10" {
+		t.Errorf("expecting a quota of 10 pods, got %s", pods.String())
+	}
+	if _, err := clientset.CoreV1().LimitRanges(voService.Namespace).Get(context.TODO(), utils.VOLimitRangeName, metav1.GetOptions{}); err != nil {
+		t.Errorf("expecting the limits of the VO, got error: %v", err)
+	}
+	if _, err := clientset.CoreV1().PodTemplates(voService.Namespace).Get(context.TODO(), "vo-svc", metav1.GetOptions{}); err != nil {
+		t.Errorf("expecting the podTemplate in the namespace of the VO, got error: %v", err)
+	}
+
+	// The services are read and listed from all the namespaces
+	if _, err := back.ReadService("vo-svc"); err != nil {
+		t.Errorf("unexpected error reading the service: %v", err)
+	}
+	services, err := back.ListServices()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(services) != 2 {
+		t.Errorf("expecting 2 services, got %d", len(services))
+	}
+
+	if err := back.DeleteService("vo-svc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps(voService.Namespace).Get(context.TODO(), "vo-svc", metav1.GetOptions{}); err == nil {
+		t.Error("expecting the configMap of the service to be deleted")
+	}
+}
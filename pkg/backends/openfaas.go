@@ -291,6 +291,40 @@ func (of *OpenfaasBackend) UpdateService(service types.Service) error {
 	return nil
 }
 
+// UpdateServiceDefinition updates the service's configMap without redeploying the OpenFaaS function. The files of the
+// configMap are updated in the running containers, but not their environment variables
+func (of *OpenfaasBackend) UpdateServiceDefinition(service types.Service) error {
+	return updateServiceConfigMap(&service, of.namespace, of.kubeClientset)
+}
+
+// DryRunService checks the creation of the service's configMap and OpenFaaS function (or the update of the configMap
+// and the function's deployment) with a server-side dry-run
+func (of *OpenfaasBackend) DryRunService(service types.Service, update bool) error {
+	if err := dryRunServiceConfigMap(&service, of.namespace, of.kubeClientset, update); err != nil {
+		return err
+	}
+
+	podSpec, err := service.ToPodSpec(of.config)
+	if err != nil {
+		return err
+	}
+
+	if update {
+		// Only the function's deployment is modified on updates
+		deployment, err := of.kubeClientset.AppsV1().Deployments(of.namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		deployment.Spec.Template.Spec = *podSpec
+		_, err = of.kubeClientset.AppsV1().Deployments(of.namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+		return err
+	}
+
+	function := of.createOFFunctionDefinition(&service)
+	_, err = of.ofClientset.OpenfaasV1().Functions(of.namespace).Create(context.TODO(), function, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	return err
+}
+
 // DeleteService deletes a service
 func (of *OpenfaasBackend) DeleteService(name string) error {
 	if err := of.ofClientset.OpenfaasV1().Functions(of.namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
@@ -319,6 +353,17 @@ func (of *OpenfaasBackend) GetProxyDirector(serviceName string) func(req *http.R
 	}
 }
 
+// GetAsyncProxyDirector returns a director function to use in a httputil.ReverseProxy, queuing the invocation in
+// the gateway. The result of the function is sent by the queue worker to callbackURL
+func (of *OpenfaasBackend) GetAsyncProxyDirector(serviceName string, callbackURL string) func(req *http.Request) {
+	return func(req *http.Request) {
+		req.URL.Scheme = "http"
+		req.URL.Host = of.gatewayEndpoint
+		req.URL.Path = fmt.Sprintf("/async-function/%s", serviceName)
+		req.Header.Set("X-Callback-Url", callbackURL)
+	}
+}
+
 func (of *OpenfaasBackend) createOFFunctionDefinition(service *types.Service) *ofv1.Function {
 	// Add label "com.openfaas.scale.zero=true" for scaling to zero
 	service.Labels[types.OpenfaasZeroScalingLabel] = "true"
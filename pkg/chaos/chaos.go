@@ -0,0 +1,199 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chaos provides fault injection hooks (delays and errors in the storage, backend
+// and OIDC calls) and in-memory fakes to run end-to-end tests without real infrastructure.
+// The hooks are only active in binaries built with the "chaos" build tag
+package chaos
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Targets of the faults
+const (
+	// TargetStorage calls to the S3/MinIO API
+	TargetStorage = "storage"
+	// TargetBackend calls to the ServerlessBackend
+	TargetBackend = "backend"
+	// TargetOIDC validation of OIDC tokens
+	TargetOIDC = "oidc"
+)
+
+// Fault defines a delay and/or error injected in the operations of a target
+type Fault struct {
+	// ID identifier of the fault (assigned when added)
+	ID string `json:"id"`
+	// Target component affected by the fault ("storage", "backend" or "oidc")
+	Target string `json:"target" binding:"required"`
+	// Operation name of the affected operation (e.g. "CreateService" or "PutObject")
+	// Optional. (default: all the operations of the target)
+	Operation string `json:"operation,omitempty"`
+	// Delay duration to wait before the operation (e.g. "2s")
+	// Optional.
+	Delay string `json:"delay,omitempty"`
+	// Error message of the error returned by the operation
+	// Optional. (default: the operation is only delayed)
+	Error string `json:"error,omitempty"`
+	// Count number of times the fault is injected
+	// Optional. (default: 0, unlimited)
+	Count int `json:"count,omitempty"`
+
+	delay time.Duration
+}
+
+// Validate checks the fault definition
+func (f *Fault) Validate() error {
+	switch f.Target {
+	case TargetStorage, TargetBackend, TargetOIDC:
+	default:
+		return fmt.Errorf("invalid target \"%s\", it must be \"%s\", \"%s\" or \"%s\"", f.Target, TargetStorage, TargetBackend, TargetOIDC)
+	}
+	if f.Delay != "" {
+		delay, err := time.ParseDuration(f.Delay)
+		if err != nil || delay < 0 {
+			return fmt.Errorf("invalid delay \"%s\"", f.Delay)
+		}
+		f.delay = delay
+	}
+	if f.delay == 0 && f.Error == "" {
+		return fmt.Errorf("the fault must define a delay or an error")
+	}
+	if f.Count < 0 {
+		return fmt.Errorf("the count must be a non-negative integer")
+	}
+	return nil
+}
+
+// InjectedError error returned by the operations affected by a fault
+type InjectedError struct {
+	FaultID string
+	Message string
+}
+
+// Error returns the message of the injected error
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("injected fault %s: %s", e.FaultID, e.Message)
+}
+
+// Injector stores the active faults
+type Injector struct {
+	faults []*Fault
+	nextID int
+	mutex  sync.Mutex
+}
+
+// NewInjector returns a new Injector without faults
+func NewInjector() *Injector {
+	return &Injector{nextID: 1}
+}
+
+// Add validates and stores a fault, returning it with its assigned ID
+func (in *Injector) Add(f Fault) (Fault, error) {
+	if err := f.Validate(); err != nil {
+		return Fault{}, err
+	}
+
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	f.ID = strconv.Itoa(in.nextID)
+	in.nextID++
+	in.faults = append(in.faults, &f)
+
+	return f, nil
+}
+
+// List returns the active faults
+func (in *Injector) List() []Fault {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	faults := []Fault{}
+	for _, f := range in.faults {
+		faults = append(faults, *f)
+	}
+	return faults
+}
+
+// Clear removes all the faults
+func (in *Injector) Clear() {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	in.faults = nil
+}
+
+// Inject applies the first fault matching the target and operation, waiting its delay and returning its error (if any)
+func (in *Injector) Inject(target string, operation string) error {
+	in.mutex.Lock()
+	var fault *Fault
+	for i, f := range in.faults {
+		if f.Target != target || (f.Operation != "" && f.Operation != operation) {
+			continue
+		}
+		fault = f
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				// Faults with a count are removed once exhausted
+				in.faults = append(in.faults[:i], in.faults[i+1:]...)
+			}
+		}
+		break
+	}
+	in.mutex.Unlock()
+
+	if fault == nil {
+		return nil
+	}
+
+	time.Sleep(fault.delay)
+	if fault.Error != "" {
+		return &InjectedError{FaultID: fault.ID, Message: fault.Error}
+	}
+	return nil
+}
+
+// defaultInjector faults injected by the hooks
+var defaultInjector = NewInjector()
+
+// AddFault adds a fault to the hooks
+func AddFault(f Fault) (Fault, error) {
+	return defaultInjector.Add(f)
+}
+
+// ListFaults returns the faults of the hooks
+func ListFaults() []Fault {
+	return defaultInjector.List()
+}
+
+// ClearFaults removes all the faults of the hooks
+func ClearFaults() {
+	defaultInjector.Clear()
+}
+
+// Inject is the hook called before the operations of the targets.
+// It does nothing in binaries built without the "chaos" build tag
+func Inject(target string, operation string) error {
+	if !Enabled {
+		return nil
+	}
+	return defaultInjector.Inject(target, operation)
+}
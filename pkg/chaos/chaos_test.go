@@ -0,0 +1,181 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestFaultValidate(t *testing.T) {
+	scenarios := []struct {
+		name  string
+		fault Fault
+		valid bool
+	}{
+		{"error", Fault{Target: TargetStorage, Error: "unavailable"}, true},
+		{"delay", Fault{Target: TargetBackend, Operation: "CreateService", Delay: "1s"}, true},
+		{"invalid target", Fault{Target: "network", Error: "unavailable"}, false},
+		{"invalid delay", Fault{Target: TargetOIDC, Delay: "soon"}, false},
+		{"no delay nor error", Fault{Target: TargetOIDC}, false},
+		{"negative count", Fault{Target: TargetOIDC, Error: "unavailable", Count: -1}, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := s.fault.Validate()
+			if s.valid && err != nil {
+				t.Errorf("expecting valid fault, got: %v", err)
+			}
+			if !s.valid && err == nil {
+				t.Error("expecting invalid fault")
+			}
+		})
+	}
+}
+
+func TestInjectorInject(t *testing.T) {
+	in := NewInjector()
+
+	if _, err := in.Add(Fault{Target: TargetBackend, Operation: "CreateService", Error: "create failed", Count: 2}); err != nil {
+		t.Fatalf("error adding the fault: %v", err)
+	}
+	if _, err := in.Add(Fault{Target: TargetStorage, Delay: "10ms"}); err != nil {
+		t.Fatalf("error adding the fault: %v", err)
+	}
+
+	// Other operations and targets are not affected
+	if err := in.Inject(TargetBackend, "ReadService"); err != nil {
+		t.Errorf("expecting no error, got: %v", err)
+	}
+	if err := in.Inject(TargetOIDC, "Verify"); err != nil {
+		t.Errorf("expecting no error, got: %v", err)
+	}
+
+	// The fault is removed once its count is exhausted
+	for i := 0; i < 2; i++ {
+		var injectedErr *InjectedError
+		if err := in.Inject(TargetBackend, "CreateService"); !errors.As(err, &injectedErr) {
+			t.Errorf("expecting injected error, got: %v", err)
+		}
+	}
+	if err := in.Inject(TargetBackend, "CreateService"); err != nil {
+		t.Errorf("expecting no error after exhausting the fault, got: %v", err)
+	}
+	if faults := in.List(); len(faults) != 1 {
+		t.Errorf("expecting 1 fault, got %d", len(faults))
+	}
+
+	// Delays without error
+	start := time.Now()
+	if err := in.Inject(TargetStorage, "PutObject"); err != nil {
+		t.Errorf("expecting no error, got: %v", err)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Error("expecting the operation to be delayed")
+	}
+
+	in.Clear()
+	if faults := in.List(); len(faults) != 0 {
+		t.Errorf("expecting no faults, got %d", len(faults))
+	}
+}
+
+func TestInjectDisabled(t *testing.T) {
+	if Enabled {
+		t.Skip("the hooks are enabled in chaos builds")
+	}
+
+	if _, err := AddFault(Fault{Target: TargetOIDC, Error: "unavailable"}); err != nil {
+		t.Fatalf("error adding the fault: %v", err)
+	}
+	defer ClearFaults()
+
+	if err := Inject(TargetOIDC, "Verify"); err != nil {
+		t.Errorf("expecting no error without the chaos build tag, got: %v", err)
+	}
+}
+
+func TestS3Server(t *testing.T) {
+	s3Server := NewS3Server()
+	defer s3Server.Close()
+
+	sess, _ := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials("minioadmin", "minioadmin", ""),
+		Endpoint:         aws.String(s3Server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	client := s3.New(sess)
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("test")}); err != nil {
+		t.Fatalf("error creating the bucket: %v", err)
+	}
+	if _, err := client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String("test"),
+		Key:         aws.String("in/file.txt"),
+		Body:        bytes.NewReader([]byte("content")),
+		ContentType: aws.String("text/plain"),
+	}); err != nil {
+		t.Fatalf("error putting the object: %v", err)
+	}
+	if _, err := client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String("test"),
+		Key:        aws.String("out/file.txt"),
+		CopySource: aws.String("test/in/file.txt"),
+	}); err != nil {
+		t.Fatalf("error copying the object: %v", err)
+	}
+
+	list, err := client.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String("test"), Prefix: aws.String("out/")})
+	if err != nil {
+		t.Fatalf("error listing the objects: %v", err)
+	}
+	if len(list.Contents) != 1 || aws.StringValue(list.Contents[0].Key) != "out/file.txt" {
+		t.Errorf("expecting the copied object, got %v", list.Contents)
+	}
+
+	obj, err := client.GetObject(&s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("out/file.txt")})
+	if err != nil {
+		t.Fatalf("error getting the object: %v", err)
+	}
+	data, _ := io.ReadAll(obj.Body)
+	if string(data) != "content" || aws.StringValue(obj.ContentType) != "text/plain" {
+		t.Errorf("unexpected object \"%s\" (%s)", data, aws.StringValue(obj.ContentType))
+	}
+
+	if _, err := client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String("test"), Key: aws.String("missing")}); err == nil {
+		t.Error("expecting error getting a missing object")
+	}
+
+	s3Server.SetFailure(func(r *http.Request) bool { return r.Method == http.MethodDelete })
+	if _, err := client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String("test"), Key: aws.String("in/file.txt")}); err == nil {
+		t.Error("expecting error deleting the object")
+	}
+	if _, ok := s3Server.GetObject("test", "in/file.txt"); !ok {
+		t.Error("expecting the object not to be deleted")
+	}
+}
@@ -0,0 +1,35 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// AddS3Hooks injects the storage faults in the requests of an S3 client.
+// The requests affected by an error are not sent
+func AddS3Hooks(client *s3.S3) {
+	if !Enabled {
+		return
+	}
+	client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if err := Inject(TargetStorage, r.Operation.Name); err != nil {
+			r.Error = err
+		}
+	})
+}
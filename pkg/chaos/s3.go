@@ -0,0 +1,394 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Object object stored in the S3Server
+type S3Object struct {
+	Data         []byte
+	ContentType  string
+	LastModified time.Time
+}
+
+// S3Server in-memory implementation of the S3 API calls used by OSCAR (buckets, objects, multipart
+// uploads and bucket notifications), served over HTTP to be used as the endpoint of a MinIO provider
+type S3Server struct {
+	*httptest.Server
+	mutex         sync.Mutex
+	buckets       map[string]map[string]S3Object
+	notifications map[string]string
+	uploads       map[string]*s3Upload
+	nextUpload    int
+	failure       func(r *http.Request) bool
+}
+
+// s3Upload pending multipart upload
+type s3Upload struct {
+	bucket string
+	key    string
+	parts  map[int][]byte
+}
+
+type s3ListResult struct {
+	XMLName     xml.Name `xml:"ListBucketResult"`
+	Name        string
+	Prefix      string
+	KeyCount    int
+	IsTruncated bool
+	Contents    []s3ListObject
+}
+
+type s3ListObject struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+type s3InitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string
+	Key      string
+	UploadId string
+}
+
+type s3CompleteMultipartUpload struct {
+	Parts []struct {
+		PartNumber int
+		ETag       string
+	} `xml:"Part"`
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string
+	Message string
+}
+
+// NewS3Server starts a new S3Server without buckets. It must be closed when no longer needed
+func NewS3Server() *S3Server {
+	s := &S3Server{
+		buckets:       map[string]map[string]S3Object{},
+		notifications: map[string]string{},
+		uploads:       map[string]*s3Upload{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// CreateBucket creates an empty bucket (if it doesn't exist)
+func (s *S3Server) CreateBucket(bucket string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = map[string]S3Object{}
+	}
+}
+
+// PutObject stores an object, creating its bucket if it doesn't exist
+func (s *S3Server) PutObject(bucket string, key string, object S3Object) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.putObject(bucket, key, object)
+}
+
+// GetObject returns a stored object
+func (s *S3Server) GetObject(bucket string, key string) (S3Object, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	object, ok := s.buckets[bucket][key]
+	return object, ok
+}
+
+// HasBucket checks if a bucket exists
+func (s *S3Server) HasBucket(bucket string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.buckets[bucket]
+	return ok
+}
+
+// PendingUploads returns the number of multipart uploads neither completed nor aborted
+func (s *S3Server) PendingUploads() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.uploads)
+}
+
+// SetFailure makes the server return an internal error for the requests matching fail (nil to disable it)
+func (s *S3Server) SetFailure(fail func(r *http.Request) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failure = fail
+}
+
+func (s *S3Server) putObject(bucket string, key string, object S3Object) {
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = map[string]S3Object{}
+	}
+	if object.LastModified.IsZero() {
+		object.LastModified = time.Now()
+	}
+	s.buckets[bucket][key] = object
+}
+
+func s3ETag(object S3Object) string {
+	return fmt.Sprintf("\"%x\"", md5.Sum(object.Data))
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string, message string) {
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(s3Error{Code: code, Message: message})
+}
+
+func (s *S3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failure != nil && s.failure(r) {
+		writeS3Error(w, http.StatusInternalServerError, "InternalError", "injected failure")
+		return
+	}
+
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	bucket := path[0]
+	key := ""
+	if len(path) == 2 {
+		key = path[1]
+	}
+	objects, bucketExists := s.buckets[bucket]
+
+	if r.URL.Query().Has("notification") {
+		s.serveNotification(w, r, bucket)
+		return
+	}
+	if r.URL.Query().Has("lifecycle") {
+		// Lifecycle rules are accepted but not applied
+		return
+	}
+
+	if key == "" {
+		switch r.Method {
+		case http.MethodPut:
+			if bucketExists {
+				writeS3Error(w, http.StatusConflict, "BucketAlreadyOwnedByYou", "the bucket already exists")
+				return
+			}
+			s.buckets[bucket] = map[string]S3Object{}
+		case http.MethodHead:
+			if !bucketExists {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodGet:
+			if !bucketExists {
+				writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "the bucket does not exist")
+				return
+			}
+			s.listObjects(w, r, bucket)
+		case http.MethodDelete:
+			delete(s.buckets, bucket)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+
+	if !bucketExists {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "the bucket does not exist")
+		return
+	}
+	if r.URL.Query().Has("uploads") || r.URL.Query().Has("uploadId") {
+		s.serveMultipartUpload(w, r, bucket, key)
+		return
+	}
+	object, objectExists := objects[key]
+
+	switch r.Method {
+	case http.MethodPut:
+		if copySource := r.Header.Get("X-Amz-Copy-Source"); copySource != "" {
+			source, _ := url.PathUnescape(strings.TrimPrefix(copySource, "/"))
+			split := strings.SplitN(source, "/", 2)
+			if len(split) != 2 {
+				writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source")
+				return
+			}
+			src, ok := s.buckets[split[0]][split[1]]
+			if !ok {
+				writeS3Error(w, http.StatusNotFound, "NoSuchKey", "the source object does not exist")
+				return
+			}
+			src.LastModified = time.Now()
+			s.putObject(bucket, key, src)
+			fmt.Fprintf(w, "<CopyObjectResult><ETag>%s</ETag></CopyObjectResult>", s3ETag(src))
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		object = S3Object{Data: data, ContentType: r.Header.Get("Content-Type")}
+		s.putObject(bucket, key, object)
+		w.Header().Set("ETag", s3ETag(object))
+	case http.MethodHead, http.MethodGet:
+		if !objectExists {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				writeS3Error(w, http.StatusNotFound, "NoSuchKey", "the object does not exist")
+			}
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(object.Data)))
+		w.Header().Set("Content-Type", object.ContentType)
+		w.Header().Set("ETag", s3ETag(object))
+		w.Header().Set("Last-Modified", object.LastModified.UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(object.Data)
+		}
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (s *S3Server) serveMultipartUpload(w http.ResponseWriter, r *http.Request, bucket string, key string) {
+	query := r.URL.Query()
+	if query.Has("uploads") {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		s.nextUpload++
+		id := fmt.Sprintf("upload-%d", s.nextUpload)
+		s.uploads[id] = &s3Upload{bucket: bucket, key: key, parts: map[int][]byte{}}
+		xml.NewEncoder(w).Encode(s3InitiateMultipartUploadResult{Bucket: bucket, Key: key, UploadId: id})
+		return
+	}
+
+	id := query.Get("uploadId")
+	upload, ok := s.uploads[id]
+	if !ok || upload.bucket != bucket || upload.key != key {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "the multipart upload does not exist")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		partNumber, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil || partNumber < 1 {
+			writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+			return
+		}
+		var data []byte
+		if copySource := r.Header.Get("X-Amz-Copy-Source"); copySource != "" {
+			source, _ := url.PathUnescape(strings.TrimPrefix(copySource, "/"))
+			split := strings.SplitN(source, "/", 2)
+			src, ok := s.buckets[split[0]][split[len(split)-1]]
+			if len(split) != 2 || !ok {
+				writeS3Error(w, http.StatusNotFound, "NoSuchKey", "the source object does not exist")
+				return
+			}
+			var start, end int
+			if _, err := fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &start, &end); err != nil || start > end || end >= len(src.Data) {
+				writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source range")
+				return
+			}
+			data = append([]byte{}, src.Data[start:end+1]...)
+			upload.parts[partNumber] = data
+			fmt.Fprintf(w, "<CopyPartResult><ETag>%s</ETag></CopyPartResult>", s3ETag(S3Object{Data: data}))
+			return
+		}
+		data, _ = io.ReadAll(r.Body)
+		upload.parts[partNumber] = data
+		w.Header().Set("ETag", s3ETag(S3Object{Data: data}))
+	case http.MethodPost:
+		var complete s3CompleteMultipartUpload
+		if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		object := S3Object{}
+		for i, part := range complete.Parts {
+			data, ok := upload.parts[part.PartNumber]
+			if !ok || part.PartNumber != i+1 || part.ETag != s3ETag(S3Object{Data: data}) {
+				writeS3Error(w, http.StatusBadRequest, "InvalidPart", "the part is not valid")
+				return
+			}
+			object.Data = append(object.Data, data...)
+		}
+		delete(s.uploads, id)
+		s.putObject(bucket, key, object)
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>", bucket, key, s3ETag(object))
+	case http.MethodDelete:
+		delete(s.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (s *S3Server) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	prefix := r.URL.Query().Get("prefix")
+	res := s3ListResult{Name: bucket, Prefix: prefix}
+	for key, object := range s.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			res.Contents = append(res.Contents, s3ListObject{
+				Key:          key,
+				LastModified: object.LastModified.UTC().Format(time.RFC3339),
+				ETag:         s3ETag(object),
+				Size:         int64(len(object.Data)),
+			})
+		}
+	}
+	sort.Slice(res.Contents, func(i, j int) bool { return res.Contents[i].Key < res.Contents[j].Key })
+	res.KeyCount = len(res.Contents)
+	xml.NewEncoder(w).Encode(res)
+}
+
+func (s *S3Server) serveNotification(w http.ResponseWriter, r *http.Request, bucket string) {
+	if _, ok := s.buckets[bucket]; !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "the bucket does not exist")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		s.notifications[bucket] = string(body)
+	case http.MethodGet:
+		if cfg, ok := s.notifications[bucket]; ok {
+			fmt.Fprint(w, cfg)
+			return
+		}
+		fmt.Fprint(w, "<NotificationConfiguration></NotificationConfiguration>")
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// CreateBuild starts the build of an image (and the creation of its service, if defined in the request)
+func (c *Client) CreateBuild(ctx context.Context, build *types.BuildRequest) (*types.Build, error) {
+	req, err := jsonRequest("CreateBuild", build)
+	if err != nil {
+		return nil, err
+	}
+	res := &types.Build{}
+	if _, err := c.do(ctx, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListBuilds returns the image builds, newest first
+func (c *Client) ListBuilds(ctx context.Context) ([]types.Build, error) {
+	builds := []types.Build{}
+	if _, err := c.do(ctx, request{operation: "ListBuilds"}, &builds); err != nil {
+		return nil, err
+	}
+	return builds, nil
+}
+
+// ReadBuild returns the status of an image build
+func (c *Client) ReadBuild(ctx context.Context, buildID string) (*types.Build, error) {
+	build := &types.Build{}
+	if _, err := c.do(ctx, request{operation: "ReadBuild", params: []string{buildID}}, build); err != nil {
+		return nil, err
+	}
+	return build, nil
+}
+
+// GetBuildLogs returns the logs of an image build
+func (c *Client) GetBuildLogs(ctx context.Context, buildID string) (string, error) {
+	req := request{operation: "GetBuildLogs", params: []string{buildID}}
+	req.header = map[string][]string{"Accept": {"text/plain"}}
+	var logs []byte
+	if _, err := c.do(ctx, req, &logs); err != nil {
+		return "", err
+	}
+	return string(logs), nil
+}
+
+// DeleteBuild deletes an image build
+func (c *Client) DeleteBuild(ctx context.Context, buildID string) error {
+	_, err := c.do(ctx, request{operation: "DeleteBuild", params: []string{buildID}}, nil)
+	return err
+}
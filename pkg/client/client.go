@@ -0,0 +1,328 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is a typed Go client of the OSCAR API. The endpoints (endpoints_gen.go) are generated
+// from the OpenAPI specification (docs/api.yaml) and each one has a method of Client named after its
+// operation ID
+package client
+
+//go:generate go run ./gen -spec ../../docs/api.yaml -out endpoints_gen.go
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 5 * time.Minute
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+
+	errorCodeHeader      = "X-Oscar-Error-Code"
+	totalCountHeader     = "X-Total-Count"
+	continueHeader       = "X-Continue"
+	invocationIDHeader   = "X-Oscar-Invocation-Id"
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// Client client of the OSCAR API of a cluster
+type Client struct {
+	endpoint   string
+	username   string
+	password   string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBasicAuth sets the credentials of the OSCAR manager (used by the /system endpoints)
+func WithBasicAuth(username string, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send the requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets the maximum number of retries of the idempotent requests (GET, PUT, DELETE and the requests
+// with an idempotency key) failed by connection errors or by a 429, 502, 503 or 504 status code, and the backoff
+// before the first retry (doubled on each retry). Zero maxRetries disables the retries
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// idempotencyKeyContext context key of the idempotency key of the requests
+type idempotencyKeyContext struct{}
+
+// WithIdempotencyKey returns a copy of ctx whose requests are sent with the Idempotency-Key header, so the service
+// creation and the invocations (CreateService, InvokeAsync, InvokeSync and InvokeAlias) are processed only once
+// and can be retried safely. The key (e.g. a UUID) must be unique for each operation
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContext{}, key)
+}
+
+// New returns a client of the OSCAR API served at endpoint (e.g. "https://oscar.example.com")
+func New(endpoint string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint \"%s\": %v", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint \"%s\": the scheme must be http or https", endpoint)
+	}
+
+	c := &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// request request to an endpoint of the OSCAR API
+type request struct {
+	// operation operation ID of the endpoint
+	operation string
+	// params values of the path parameters, in order
+	params      []string
+	query       url.Values
+	header      http.Header
+	body        []byte
+	contentType string
+	// token service's token sent as bearer token instead of the basic auth credentials
+	token string
+}
+
+// jsonRequest returns a request with the JSON encoding of body
+func jsonRequest(operation string, body interface{}, params ...string) (request, error) {
+	req := request{operation: operation, params: params}
+	if body == nil {
+		return req, nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return req, err
+	}
+	req.body = data
+	req.contentType = "application/json"
+	return req, nil
+}
+
+// do sends the request (retrying it if possible) and decodes the JSON response body in out (if not nil).
+// A *[]byte out gets the raw response body. The response is returned (with its body closed) to read its headers
+func (c *Client) do(ctx context.Context, req request, out interface{}) (*http.Response, error) {
+	ep, reqURL, err := c.resolve(req)
+	if err != nil {
+		return nil, err
+	}
+
+	_, hasKey := ctx.Value(idempotencyKeyContext{}).(string)
+	retryable := ep.method == http.MethodGet || ep.method == http.MethodPut || ep.method == http.MethodDelete || hasKey
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		res, body, err := c.send(ctx, ep.method, reqURL, req)
+		if retryable && attempt < c.maxRetries && ctx.Err() == nil && isRetryable(res, err) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode >= http.StatusBadRequest {
+			return res, newError(res, body)
+		}
+		switch o := out.(type) {
+		case nil:
+		case *[]byte:
+			*o = body
+		default:
+			if err := json.Unmarshal(body, out); err != nil {
+				return res, fmt.Errorf("error decoding the response of %s: %v", req.operation, err)
+			}
+		}
+		return res, nil
+	}
+}
+
+// stream sends the request (without retries) and returns the response with its body open, to read it as it is
+// received. The caller must close the body
+func (c *Client) stream(ctx context.Context, req request) (*http.Response, error) {
+	ep, reqURL, err := c.resolve(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := c.newHTTPRequest(ctx, ep.method, reqURL, req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newError(res, body)
+	}
+	return res, nil
+}
+
+// errEndOfStream returned by the onEvent functions of readServerSentEvents to stop reading the stream
+var errEndOfStream = errors.New("end of stream")
+
+// readServerSentEvents reads the Server-Sent Events of a stream, calling onEvent with the name and the data of each
+// one until it returns an error. io.ErrUnexpectedEOF is returned if the stream is closed before (e.g. by the
+// timeout of the HTTP client or the write timeout of the OSCAR manager)
+func readServerSentEvents(body io.Reader, onEvent func(event string, data string) error) error {
+	// The lines starting with ":" are heartbeats
+	var event string
+	var data []string
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(line, "data:"))
+		case line == "" && event != "":
+			if err := onEvent(event, strings.Join(data, "\n")); err != nil {
+				return err
+			}
+			event = ""
+			data = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// resolve returns the endpoint of the request's operation and the URL of the request
+func (c *Client) resolve(req request) (endpoint, string, error) {
+	ep, ok := endpoints[req.operation]
+	if !ok {
+		return ep, "", fmt.Errorf("unknown operation \"%s\"", req.operation)
+	}
+	path, err := ep.expand(req.params)
+	if err != nil {
+		return ep, "", err
+	}
+	reqURL := c.endpoint + path
+	if len(req.query) > 0 {
+		reqURL += "?" + req.query.Encode()
+	}
+	return ep, reqURL, nil
+}
+
+// send sends a single attempt of the request, returning the response and its body
+func (c *Client) send(ctx context.Context, method string, reqURL string, req request) (*http.Response, []byte, error) {
+	httpReq, err := c.newHTTPRequest(ctx, method, reqURL, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, resBody, nil
+}
+
+// newHTTPRequest returns the HTTP request of a request, with its headers and credentials
+func (c *Client) newHTTPRequest(ctx context.Context, method string, reqURL string, req request) (*http.Request, error) {
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if key, ok := ctx.Value(idempotencyKeyContext{}).(string); ok {
+		httpReq.Header.Set(idempotencyKeyHeader, key)
+	}
+	// Get the structured errors
+	if httpReq.Header.Get("Accept") == "" {
+		httpReq.Header.Set("Accept", "application/json")
+	}
+	if req.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+req.token)
+	} else if c.username != "" {
+		httpReq.SetBasicAuth(c.username, c.password)
+	}
+	return httpReq, nil
+}
+
+// isRetryable checks if a failed attempt can be retried
+func isRetryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
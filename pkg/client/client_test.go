@@ -0,0 +1,334 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestEndpointsCoverage(t *testing.T) {
+	clientType := reflect.TypeOf(&Client{})
+	for operation := range endpoints {
+		if _, ok := clientType.MethodByName(operation); !ok {
+			t.Errorf("the operation %s has not a method in the client", operation)
+		}
+	}
+}
+
+func TestEndpointExpand(t *testing.T) {
+	ep := endpoint{http.MethodGet, "/system/logs/{serviceName}/{jobName}"}
+
+	path, err := ep.expand([]string{"my service", "job-1"})
+	if err != nil || path != "/system/logs/my%20service/job-1" {
+		t.Errorf("unexpected path %s (%v)", path, err)
+	}
+	for _, params := range [][]string{{"service"}, {"service", "job", "other"}, {"", "job"}} {
+		if _, err := ep.expand(params); err == nil {
+			t.Errorf("%v: expecting error", params)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, endpoint := range []string{"oscar.example.com", "ftp://oscar.example.com", "://"} {
+		if _, err := New(endpoint); err == nil {
+			t.Errorf("%s: expecting error", endpoint)
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	attempts := 0
+	key := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		key = r.Header.Get("Idempotency-Key")
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"name": "oscar"}`))
+	}))
+	defer server.Close()
+
+	c, _ := New(server.URL, WithRetries(3, time.Millisecond))
+	if _, err := c.GetInfo(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expecting 3 attempts, got %d", attempts)
+	}
+
+	// The non-idempotent requests are not retried
+	attempts = 0
+	if _, err := c.InvokeAsync(context.Background(), "test", "token", []byte("{}")); err == nil {
+		t.Error("expecting error")
+	}
+	if attempts != 1 {
+		t.Errorf("expecting 1 attempt, got %d", attempts)
+	}
+
+	// The requests with an idempotency key are retried
+	attempts = 0
+	ctx := WithIdempotencyKey(context.Background(), "key")
+	if _, err := c.InvokeAsync(ctx, "test", "token", []byte("{}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expecting 3 attempts, got %d", attempts)
+	}
+	if key != "key" {
+		t.Errorf("expecting the Idempotency-Key header \"key\", got \"%s\"", key)
+	}
+
+	// The retries stop when the context is done
+	attempts = -10
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c, _ = New(server.URL, WithRetries(10, time.Second))
+	if _, err := c.GetInfo(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expecting context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/system/services/missing":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(types.NewAPIError(types.ErrServiceNotFound, "not found"))
+		case "/system/logs/test/job":
+			w.Header().Set(errorCodeHeader, types.ErrJobNotFound.Code)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("job not found\n"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	c, _ := New(server.URL, WithRetries(0, 0))
+
+	_, err := c.ReadService(context.Background(), "missing", nil)
+	if !IsErrorCode(err, types.ErrServiceNotFound) || !IsNotFound(err) {
+		t.Errorf("expecting ErrServiceNotFound, got %v", err)
+	}
+
+	_, err = c.GetJobLogs(context.Background(), "test", "job", false)
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.Code != types.ErrJobNotFound.Code || apiErr.Name != types.ErrJobNotFound.Name || apiErr.Message != "job not found" {
+		t.Errorf("expecting ErrJobNotFound, got %v", err)
+	}
+
+	err = c.HealthCheck(context.Background())
+	if apiErr, ok := err.(*Error); !ok || apiErr.StatusCode != http.StatusBadGateway || apiErr.Code != "" {
+		t.Errorf("expecting a 502 error without code, got %v", err)
+	}
+}
+
+func TestListServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "oscar" || pass != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("vo") != "vo" || len(query["label"]) != 2 || query.Get("limit") != "1" || query.Has("offset") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(totalCountHeader, "2")
+		w.Header().Set(continueHeader, types.MakeServiceListContinue(1))
+		w.Write([]byte(`[{"name": "first"}]`))
+	}))
+	defer server.Close()
+
+	c, _ := New(server.URL, WithBasicAuth("oscar", "password"))
+	list, err := c.ListServices(context.Background(), types.ServiceListOptions{Limit: 1, VO: "vo", Labels: []string{"a=b", "c"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Services) != 1 || list.Services[0].Name != "first" || list.Total != 2 || list.NextOffset != 1 {
+		t.Errorf("unexpected list: %+v", list)
+	}
+}
+
+func TestListAllServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if r.URL.Query().Get("limit") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		names := []string{"a", "b", "c"}
+		page := []*types.Service{}
+		for i := offset; i < len(names) && i < offset+2; i++ {
+			page = append(page, &types.Service{Name: names[i]})
+		}
+		if offset+2 < len(names) {
+			w.Header().Set(continueHeader, types.MakeServiceListContinue(offset+2))
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	c, _ := New(server.URL)
+	services, err := c.ListAllServices(context.Background(), types.ServiceListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(services) != 3 || services[2].Name != "c" {
+		t.Errorf("unexpected services %v", services)
+	}
+}
+
+func TestStreamJobLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/system/logs/test/job/stream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event:log\ndata:line 1\n\n: heartbeat\n\nevent:log\ndata:line 2\n\nevent:end\ndata:job\n\n"))
+	}))
+	defer server.Close()
+
+	c, _ := New(server.URL)
+	lines := []string{}
+	err := c.StreamJobLogs(context.Background(), "test", "job", false, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"line 1", "line 2"}) {
+		t.Errorf("unexpected lines %v", lines)
+	}
+
+	if err := c.StreamJobLogs(context.Background(), "other", "job", false, func(string) error { return nil }); err == nil {
+		t.Error("expecting error")
+	}
+}
+
+func TestWatchServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("initial") != "false" || r.URL.Query().Get("label") != "a=b" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event:created\ndata:{\"type\":\"created\",\"name\":\"a\",\"service\":{\"name\":\"a\"}}\n\n: heartbeat\n\n"))
+		w.Write([]byte("event:deleted\ndata:{\"type\":\"deleted\",\"name\":\"a\"}\n\n"))
+	}))
+	defer server.Close()
+
+	c, _ := New(server.URL)
+	events := []string{}
+	err := c.WatchServices(context.Background(), ServiceWatchOptions{Labels: []string{"a=b"}, SkipInitial: true}, func(event types.ServiceWatchEvent) error {
+		events = append(events, event.Type+" "+event.Name)
+		return nil
+	})
+	// The stream is closed by the server
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expecting io.ErrUnexpectedEOF, got %v", err)
+	}
+	if !reflect.DeepEqual(events, []string{"created a", "deleted a"}) {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	if err := c.WatchServices(context.Background(), ServiceWatchOptions{}, func(types.ServiceWatchEvent) error { return nil }); err == nil {
+		t.Error("expecting error")
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "oscar" || r.URL.Query().Get("service") != "a,b" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteJSON(types.LifecycleEvent{Type: types.JobCreatedEvent, Service: "a", Job: "job"})
+		conn.WriteJSON(types.LifecycleEvent{Type: types.JobSucceededEvent, Service: "a", Job: "job"})
+	}))
+	defer server.Close()
+
+	c, _ := New(server.URL, WithBasicAuth("oscar", "pass"))
+	events := []string{}
+	stop := errors.New("stop")
+	err := c.WatchEvents(context.Background(), []string{"a", "b"}, func(event types.LifecycleEvent) error {
+		events = append(events, event.Type)
+		if len(events) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(events, []string{types.JobCreatedEvent, types.JobSucceededEvent}) {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	c, _ = New(server.URL)
+	err = c.WatchEvents(context.Background(), []string{"a", "b"}, func(types.LifecycleEvent) error { return nil })
+	if e, ok := err.(*Error); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expecting an unauthorized error, got %v", err)
+	}
+}
+
+func TestSearchJobLogs(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		json.NewEncoder(w).Encode([]types.JobLogMatches{{Job: "job", Lines: []string{"error"}}})
+	}))
+	defer server.Close()
+
+	c, _ := New(server.URL)
+	since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	matches, err := c.SearchJobLogs(context.Background(), "test", LogFilter{Since: since, Level: "ERROR", Pattern: "err"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Job != "job" {
+		t.Errorf("unexpected matches %v", matches)
+	}
+	expected := url.Values{"search": {"err"}, "since": {"2023-01-01T00:00:00Z"}, "level": {"ERROR"}}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("expecting query %v, got %v", expected, query)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// endpoint method and path template (with "{param}" placeholders) of an endpoint of the OSCAR API
+type endpoint struct {
+	method string
+	path   string
+}
+
+// expand returns the path of the endpoint with the placeholders replaced, in order, by the escaped params
+func (ep endpoint) expand(params []string) (string, error) {
+	var b strings.Builder
+	path := ep.path
+	for _, param := range params {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			return "", fmt.Errorf("too many parameters for the path \"%s\"", ep.path)
+		}
+		if param == "" {
+			return "", fmt.Errorf("the parameter %s of the path \"%s\" is empty", path[start:end+1], ep.path)
+		}
+		b.WriteString(path[:start])
+		b.WriteString(url.PathEscape(param))
+		path = path[end+1:]
+	}
+	if strings.Contains(path, "{") {
+		return "", fmt.Errorf("missing parameters for the path \"%s\"", ep.path)
+	}
+	b.WriteString(path)
+	return b.String(), nil
+}
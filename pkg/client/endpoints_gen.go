@@ -0,0 +1,124 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by gen from docs/api.yaml. DO NOT EDIT.
+
+package client
+
+import "net/http"
+
+// endpoints endpoints of the OSCAR API by operation ID
+var endpoints = map[string]endpoint{
+	"ActivateUploadSession":  {http.MethodPost, "/system/uploads/{uploadID}/activate"},
+	"ApproveVORequest":       {http.MethodPost, "/system/vo-requests/{requestID}/approve"},
+	"CloneService":           {http.MethodPost, "/system/services/{serviceName}/clone"},
+	"CreateAPIKey":           {http.MethodPost, "/system/apikeys"},
+	"CreateBuild":            {http.MethodPost, "/system/builds"},
+	"CreateService":          {http.MethodPost, "/system/services"},
+	"CreateServiceAlias":     {http.MethodPost, "/system/services/{serviceName}/alias"},
+	"CreateServiceToken":     {http.MethodPost, "/system/services/{serviceName}/tokens"},
+	"CreateServicesBulk":     {http.MethodPost, "/system/services/batch"},
+	"CreateUser":             {http.MethodPost, "/system/users"},
+	"CreateVORequest":        {http.MethodPost, "/system/vo-requests"},
+	"DeleteBuild":            {http.MethodDelete, "/system/builds/{buildID}"},
+	"DeleteExposedDomain":    {http.MethodDelete, "/system/services/{serviceName}/domains/{domain}"},
+	"DeleteJob":              {http.MethodDelete, "/system/logs/{serviceName}/{jobName}"},
+	"DeleteJobs":             {http.MethodDelete, "/system/logs/{serviceName}"},
+	"DeleteService":          {http.MethodDelete, "/system/services/{serviceName}"},
+	"DeleteServiceAlias":     {http.MethodDelete, "/system/services/{serviceName}/alias/{alias}"},
+	"DeleteUploadSession":    {http.MethodDelete, "/system/uploads/{uploadID}"},
+	"DeleteUser":             {http.MethodDelete, "/system/users/{username}"},
+	"DeployCatalogTemplate":  {http.MethodPost, "/system/catalog/{template}/deploy"},
+	"ExportService":          {http.MethodGet, "/system/services/{serviceName}/export"},
+	"FreezeAPI":              {http.MethodPost, "/system/maintenance/freeze"},
+	"GetAsyncJobStatus":      {http.MethodGet, "/job/{serviceName}/{jobName}"},
+	"GetBuildLogs":           {http.MethodGet, "/system/builds/{buildID}/logs"},
+	"GetCapacity":            {http.MethodGet, "/system/capacity"},
+	"GetConfig":              {http.MethodGet, "/system/config"},
+	"GetInfo":                {http.MethodGet, "/system/info"},
+	"GetJobLogs":             {http.MethodGet, "/system/logs/{serviceName}/{jobName}"},
+	"GetJobOutputs":          {http.MethodGet, "/system/logs/{serviceName}/{jobName}/output"},
+	"GetJobStatus":           {http.MethodGet, "/system/logs/{serviceName}/{jobName}/status"},
+	"GetMaintenanceStatus":   {http.MethodGet, "/system/maintenance/freeze"},
+	"GetMetrics":             {http.MethodGet, "/system/metrics"},
+	"GetServiceHealth":       {http.MethodGet, "/system/services/{serviceName}/status"},
+	"GetServiceLatency":      {http.MethodGet, "/system/services/{serviceName}/latency"},
+	"GetServiceSchema":       {http.MethodGet, "/system/services/{serviceName}/schema"},
+	"GetUsage":               {http.MethodGet, "/system/usage"},
+	"GetUsageReport":         {http.MethodGet, "/system/reports"},
+	"HealthCheck":            {http.MethodGet, "/health"},
+	"ImportServices":         {http.MethodPost, "/system/services/import"},
+	"InvokeAlias":            {http.MethodPost, "/i/{alias}"},
+	"InvokeAsync":            {http.MethodPost, "/job/{serviceName}"},
+	"InvokeQueued":           {http.MethodPost, "/run-async/{serviceName}"},
+	"InvokeSync":             {http.MethodPost, "/run/{serviceName}"},
+	"ListAPIKeys":            {http.MethodGet, "/system/apikeys"},
+	"ListAuditEntries":       {http.MethodGet, "/system/audit"},
+	"ListBuilds":             {http.MethodGet, "/system/builds"},
+	"ListCallbackDeliveries": {http.MethodGet, "/system/services/{serviceName}/callbacks"},
+	"ListCapabilities":       {http.MethodGet, "/system/capabilities"},
+	"ListCatalogTemplates":   {http.MethodGet, "/system/catalog"},
+	"ListClusterJobs":        {http.MethodGet, "/system/jobs"},
+	"ListDeadLetter":         {http.MethodGet, "/system/services/{serviceName}/deadletter"},
+	"ListDeletedServices":    {http.MethodGet, "/system/deleted-services"},
+	"ListErrors":             {http.MethodGet, "/system/errors"},
+	"ListExposedDomains":     {http.MethodGet, "/system/services/{serviceName}/domains"},
+	"ListInvocations":        {http.MethodGet, "/system/invocations/{serviceName}"},
+	"ListJobs":               {http.MethodGet, "/system/logs/{serviceName}"},
+	"ListServiceAliases":     {http.MethodGet, "/system/services/{serviceName}/alias"},
+	"ListServiceRevisions":   {http.MethodGet, "/system/services/{serviceName}/revisions"},
+	"ListServiceSecrets":     {http.MethodGet, "/system/services/{serviceName}/secrets"},
+	"ListServiceTokens":      {http.MethodGet, "/system/services/{serviceName}/tokens"},
+	"ListServices":           {http.MethodGet, "/system/services"},
+	"ListUsers":              {http.MethodGet, "/system/users"},
+	"ListVORequests":         {http.MethodGet, "/system/vo-requests"},
+	"PatchService":           {http.MethodPatch, "/system/services/{serviceName}"},
+	"PresignInvocation":      {http.MethodPost, "/system/services/{serviceName}/presign"},
+	"QueryGraphQL":           {http.MethodPost, "/system/graphql"},
+	"ReadBuild":              {http.MethodGet, "/system/builds/{buildID}"},
+	"ReadInvocation":         {http.MethodGet, "/system/invocations/{serviceName}/{invocationID}"},
+	"ReadOperation":          {http.MethodGet, "/system/operations/{operationID}"},
+	"ReadService":            {http.MethodGet, "/system/services/{serviceName}"},
+	"ReadUploadSession":      {http.MethodGet, "/system/uploads/{uploadID}"},
+	"ReadUser":               {http.MethodGet, "/system/users/{username}"},
+	"ReadVORequest":          {http.MethodGet, "/system/vo-requests/{requestID}"},
+	"ReconcileWebhooks":      {http.MethodPost, "/system/admin/reconcile"},
+	"RedeliverCallback":      {http.MethodPost, "/system/services/{serviceName}/callbacks/{deliveryID}/redeliver"},
+	"RedriveDeadLetter":      {http.MethodPost, "/system/services/{serviceName}/deadletter/redrive"},
+	"RejectVORequest":        {http.MethodPost, "/system/vo-requests/{requestID}/reject"},
+	"ReplayService":          {http.MethodPost, "/system/services/{serviceName}/replay"},
+	"RestoreService":         {http.MethodPost, "/system/services/{serviceName}/restore"},
+	"RetryJob":               {http.MethodPost, "/system/logs/{serviceName}/{jobName}/retry"},
+	"RevokeAPIKey":           {http.MethodDelete, "/system/apikeys/{keyName}"},
+	"RevokeServiceToken":     {http.MethodDelete, "/system/services/{serviceName}/tokens/{tokenName}"},
+	"RollbackService":        {http.MethodPost, "/system/services/{serviceName}/rollback/{revision}"},
+	"RotateServiceToken":     {http.MethodPost, "/system/services/{serviceName}/tokens/{tokenName}/rotate"},
+	"SetExposedDomain":       {http.MethodPut, "/system/services/{serviceName}/domains/{domain}"},
+	"SimulateServiceEvent":   {http.MethodPost, "/system/services/{serviceName}/simulate-event"},
+	"StreamJobLogs":          {http.MethodGet, "/system/logs/{serviceName}/{jobName}/stream"},
+	"SyncGitScript":          {http.MethodPost, "/git/{serviceName}"},
+	"ToggleServiceInput":     {http.MethodPut, "/system/services/{serviceName}/inputs/{index}/enabled"},
+	"UnfreezeAPI":            {http.MethodDelete, "/system/maintenance/freeze"},
+	"UnlockService":          {http.MethodPost, "/system/services/{serviceName}/unlock"},
+	"UpdateService":          {http.MethodPut, "/system/services"},
+	"UpdateServiceFeatures":  {http.MethodPatch, "/system/services/{serviceName}/features"},
+	"UpdateServiceSecrets":   {http.MethodPut, "/system/services/{serviceName}/secrets"},
+	"UpdateUser":             {http.MethodPut, "/system/users/{username}"},
+	"UploadAsset":            {http.MethodPut, "/system/uploads/{uploadID}/assets/{assetName}"},
+	"ValidateService":        {http.MethodPost, "/system/services/validate"},
+	"WatchEvents":            {http.MethodGet, "/system/events/ws"},
+	"WatchServices":          {http.MethodGet, "/system/services/watch"},
+}
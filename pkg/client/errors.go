@@ -0,0 +1,83 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// Error error returned by the OSCAR API
+type Error struct {
+	// StatusCode HTTP status code of the response
+	StatusCode int
+	// Code code of the error (OSCAR-NNNN), see types.GetErrorCatalog
+	Code string
+	// Name short kebab-case name of the error
+	Name string
+	// Message detail of the error
+	Message string
+	// Details issues found in the request (e.g. the invalid fields of the body), if any
+	Details []string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Code, e.Name, e.Message)
+}
+
+// IsErrorCode checks if err is an error returned by the OSCAR API with the code of the catalog's error
+func IsErrorCode(err error, code types.ErrorCode) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code.Code
+}
+
+// IsNotFound checks if err is an error returned by the OSCAR API with the status 404
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// newError returns the Error of a failed response. The endpoints proxied to the services
+// may not return the structured error body
+func newError(res *http.Response, body []byte) *Error {
+	e := &Error{StatusCode: res.StatusCode}
+	var apiErr types.APIError
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Code != "" {
+		e.Code = apiErr.Code
+		e.Name = apiErr.Name
+		e.Message = apiErr.Message
+		e.Details = apiErr.Details
+		return e
+	}
+
+	e.Code = res.Header.Get(errorCodeHeader)
+	for _, code := range types.GetErrorCatalog() {
+		if code.Code == e.Code {
+			e.Name = code.Name
+		}
+	}
+	e.Message = strings.TrimSpace(string(body))
+	return e
+}
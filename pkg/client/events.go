@@ -0,0 +1,131 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// WatchEvents receives the lifecycle events of the services over a WebSocket connection, calling onEvent for
+// each one until ctx is done (returning nil) or onEvent returns an error. Empty services receives the events
+// of all the services
+func (c *Client) WatchEvents(ctx context.Context, services []string, onEvent func(event types.LifecycleEvent) error) error {
+	req := request{operation: "WatchEvents"}
+	if len(services) > 0 {
+		req.query = url.Values{"service": {strings.Join(services, ",")}}
+	}
+	_, reqURL, err := c.resolve(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := c.newHTTPRequest(ctx, http.MethodGet, reqURL, req)
+	if err != nil {
+		return err
+	}
+
+	dialer := &websocket.Dialer{Proxy: http.ProxyFromEnvironment}
+	if transport, ok := c.httpClient.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	wsURL := "ws" + strings.TrimPrefix(reqURL, "http")
+	conn, res, err := dialer.DialContext(ctx, wsURL, httpReq.Header)
+	if err != nil {
+		if res != nil && res.StatusCode >= http.StatusBadRequest {
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			return newError(res, body)
+		}
+		return err
+	}
+	defer conn.Close()
+
+	// Close the connection when the context is done to stop reading
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		var event types.LifecycleEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := onEvent(event); err != nil {
+			return err
+		}
+	}
+}
+
+// ServiceWatchOptions filters of the service watch
+type ServiceWatchOptions struct {
+	// Labels label requirements ("key=value" or "key") the services must satisfy
+	Labels []string
+	// VO only watch the services of this VO
+	VO string
+	// SkipInitial don't receive the services existing when the watch starts ("added" and "synced" events)
+	SkipInitial bool
+}
+
+// WatchServices receives the changes of the service definitions (Server-Sent Events), calling onEvent for each one
+// until ctx is done (returning nil) or onEvent returns an error. Unless opts.SkipInitial, the services existing when
+// the watch starts are received first as "added" events, followed by a "synced" event, so the watch can be started
+// again to resync after an error (e.g. io.ErrUnexpectedEOF if the stream is closed by a timeout)
+func (c *Client) WatchServices(ctx context.Context, opts ServiceWatchOptions, onEvent func(event types.ServiceWatchEvent) error) error {
+	query := url.Values{}
+	for _, label := range opts.Labels {
+		query.Add("label", label)
+	}
+	setQuery(query, "vo", opts.VO)
+	if opts.SkipInitial {
+		query.Set("initial", "false")
+	}
+	req := request{operation: "WatchServices", query: query}
+	req.header = map[string][]string{"Accept": {"text/event-stream"}}
+	res, err := c.stream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	err = readServerSentEvents(res.Body, func(_ string, data string) error {
+		var event types.ServiceWatchEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return err
+		}
+		return onEvent(event)
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
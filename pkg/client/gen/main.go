@@ -0,0 +1,124 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command gen generates the endpoints of the OSCAR API client from its OpenAPI specification
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const header = `/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by gen from docs/api.yaml. DO NOT EDIT.
+
+package client
+
+import "net/http"
+
+// endpoints endpoints of the OSCAR API by operation ID
+var endpoints = map[string]endpoint{
+`
+
+// methods constants of the HTTP methods of the OpenAPI operations
+var methods = map[string]string{
+	"get":    "http.MethodGet",
+	"put":    "http.MethodPut",
+	"post":   "http.MethodPost",
+	"patch":  "http.MethodPatch",
+	"delete": "http.MethodDelete",
+}
+
+// spec paths of an OpenAPI specification
+type spec struct {
+	Paths map[string]map[string]interface{} `json:"paths"`
+}
+
+func main() {
+	specPath := flag.String("spec", "docs/api.yaml", "OpenAPI specification of the OSCAR API")
+	outPath := flag.String("out", "endpoints_gen.go", "output file")
+	flag.Parse()
+
+	data, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var s spec
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		log.Fatalf("error reading the specification: %v", err)
+	}
+
+	lines := []string{}
+	operations := map[string]string{}
+	for path, item := range s.Paths {
+		for method, op := range item {
+			goMethod, ok := methods[strings.ToLower(method)]
+			if !ok {
+				// "parameters" and other path item fields
+				continue
+			}
+			opMap, _ := op.(map[string]interface{})
+			id, _ := opMap["operationId"].(string)
+			if id == "" {
+				log.Fatalf("the operation %s %s has not an operationId", strings.ToUpper(method), path)
+			}
+			if other, ok := operations[id]; ok {
+				log.Fatalf("the operationId %s is duplicated (%s and %s)", id, other, path)
+			}
+			operations[id] = path
+			lines = append(lines, fmt.Sprintf("\t%q: {%s, %q},\n", id, goMethod, path))
+		}
+	}
+	sort.Strings(lines)
+
+	var b bytes.Buffer
+	b.WriteString(header)
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalf("error formatting the generated code: %v", err)
+	}
+	if err := os.WriteFile(*outPath, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// JobList page of the cluster-wide job list
+type JobList struct {
+	Jobs []types.ClusterJob
+	// Total number of jobs matching the filters
+	Total int
+	// NextOffset offset of the next page (0 on the last page)
+	NextOffset int
+}
+
+// LogFilter filter of the lines of the jobs' logs. The zero values do not filter
+type LogFilter struct {
+	// Since only the lines logged from this time
+	Since time.Time
+	// Until only the lines logged up to this time
+	Until time.Time
+	// Level only the faas-supervisor's lines with this level or higher (DEBUG, INFO, WARNING, ERROR or CRITICAL)
+	Level string
+	// Pattern only the lines matching this regular expression
+	Pattern string
+}
+
+// setQuery sets the querystring parameters of the filter, with the pattern in patternKey
+func (f LogFilter) setQuery(query url.Values, patternKey string) {
+	if !f.Since.IsZero() {
+		query.Set("since", f.Since.Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() {
+		query.Set("until", f.Until.Format(time.RFC3339))
+	}
+	setQuery(query, "level", f.Level)
+	setQuery(query, patternKey, f.Pattern)
+}
+
+// ListClusterJobs lists the jobs of all the services with the filtering, sorting and pagination options
+func (c *Client) ListClusterJobs(ctx context.Context, opts types.JobListOptions) (*JobList, error) {
+	query := url.Values{}
+	setQuery(query, "status", opts.Status)
+	setQuery(query, "vo", opts.VO)
+	setQuery(query, "service", opts.Service)
+	setQuery(query, "sort", opts.Sort)
+	setQueryInt(query, "limit", opts.Limit)
+	setQueryInt(query, "offset", opts.Offset)
+	if opts.OlderThan > 0 {
+		query.Set("olderThan", opts.OlderThan.String())
+	}
+
+	list := &JobList{}
+	res, err := c.do(ctx, request{operation: "ListClusterJobs", query: query}, &list.Jobs)
+	if err != nil {
+		return nil, err
+	}
+	list.Total, list.NextOffset = readPageHeaders(res.Header.Get(totalCountHeader), res.Header.Get(continueHeader))
+	return list, nil
+}
+
+// ListJobs lists the jobs of a service by name
+func (c *Client) ListJobs(ctx context.Context, serviceName string) (map[string]*types.JobInfo, error) {
+	jobs := map[string]*types.JobInfo{}
+	if _, err := c.do(ctx, request{operation: "ListJobs", params: []string{serviceName}}, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// SearchJobLogs returns the lines of the logs of all the jobs from a service passing the filter, newest jobs
+// first. The jobs without matching lines are omitted
+func (c *Client) SearchJobLogs(ctx context.Context, serviceName string, filter LogFilter) ([]types.JobLogMatches, error) {
+	query := url.Values{"search": {filter.Pattern}}
+	filter.setQuery(query, "search")
+	matches := []types.JobLogMatches{}
+	if _, err := c.do(ctx, request{operation: "ListJobs", params: []string{serviceName}, query: query}, &matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// DeleteJobs deletes the completed jobs of a service (all of them, including the running ones, if all is set)
+func (c *Client) DeleteJobs(ctx context.Context, serviceName string, all bool) error {
+	req := request{operation: "DeleteJobs", params: []string{serviceName}}
+	if all {
+		req.query = url.Values{"all": {"true"}}
+	}
+	_, err := c.do(ctx, req, nil)
+	return err
+}
+
+// GetJobLogs returns the logs of a job (with the timestamp of each line if timestamps is set)
+func (c *Client) GetJobLogs(ctx context.Context, serviceName string, jobName string, timestamps bool) (string, error) {
+	return c.GetFilteredJobLogs(ctx, serviceName, jobName, timestamps, LogFilter{})
+}
+
+// GetFilteredJobLogs returns the lines of the logs of a job passing the filter (with the timestamp of each line if
+// timestamps is set)
+func (c *Client) GetFilteredJobLogs(ctx context.Context, serviceName string, jobName string, timestamps bool, filter LogFilter) (string, error) {
+	query := url.Values{"timestamps": {strconv.FormatBool(timestamps)}}
+	filter.setQuery(query, "grep")
+	req := request{operation: "GetJobLogs", params: []string{serviceName, jobName}, query: query}
+	req.header = map[string][]string{"Accept": {"text/plain"}}
+	var logs []byte
+	if _, err := c.do(ctx, req, &logs); err != nil {
+		return "", err
+	}
+	return string(logs), nil
+}
+
+// StreamJobLogs follows the logs of a job (with the timestamp of each line if timestamps is set), calling onLine
+// with each line until the job finishes, ctx is cancelled or onLine returns an error. io.ErrUnexpectedEOF is
+// returned if the stream is closed before (e.g. by the timeout of the HTTP client or the write timeout of the
+// OSCAR manager)
+func (c *Client) StreamJobLogs(ctx context.Context, serviceName string, jobName string, timestamps bool, onLine func(line string) error) error {
+	req := request{operation: "StreamJobLogs", params: []string{serviceName, jobName}, query: url.Values{"timestamps": {strconv.FormatBool(timestamps)}}}
+	req.header = map[string][]string{"Accept": {"text/event-stream"}}
+	res, err := c.stream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	err = readServerSentEvents(res.Body, func(event string, data string) error {
+		switch event {
+		case "log":
+			return onLine(data)
+		case "error":
+			return fmt.Errorf("error streaming the logs of job \"%s\": %s", jobName, data)
+		case "end":
+			return errEndOfStream
+		}
+		return nil
+	})
+	if err == errEndOfStream {
+		return nil
+	}
+	return err
+}
+
+// GetJobStatus returns the detailed status of a job (timing, exit details, resource requests and triggering event)
+func (c *Client) GetJobStatus(ctx context.Context, serviceName string, jobName string) (*types.JobStatus, error) {
+	status := &types.JobStatus{}
+	if _, err := c.do(ctx, request{operation: "GetJobStatus", params: []string{serviceName, jobName}}, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// GetJobOutputs returns the files stored by a job in the MinIO outputs of its service, with presigned download URLs
+// valid for expires (the server's default if 0)
+func (c *Client) GetJobOutputs(ctx context.Context, serviceName string, jobName string, expires time.Duration) (*types.JobOutputs, error) {
+	query := url.Values{}
+	if expires > 0 {
+		query.Set("expires", strconv.Itoa(int(expires.Seconds())))
+	}
+	outputs := &types.JobOutputs{}
+	if _, err := c.do(ctx, request{operation: "GetJobOutputs", params: []string{serviceName, jobName}, query: query}, outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// DeleteJob deletes a job of a service
+func (c *Client) DeleteJob(ctx context.Context, serviceName string, jobName string) error {
+	_, err := c.do(ctx, request{operation: "DeleteJob", params: []string{serviceName, jobName}}, nil)
+	return err
+}
+
+// RetryJob re-submits the event of a job as a new job with the current definition of the service
+func (c *Client) RetryJob(ctx context.Context, serviceName string, jobName string) (*types.JobRetry, error) {
+	retry := &types.JobRetry{}
+	if _, err := c.do(ctx, request{operation: "RetryJob", params: []string{serviceName, jobName}}, retry); err != nil {
+		return nil, err
+	}
+	return retry, nil
+}
+
+// InvokeAsync invokes a service asynchronously (creating a job) with the service's token. The returned invocation
+// has an empty JobID if no job has been created yet (e.g. the event has been discarded, batched or delegated)
+func (c *Client) InvokeAsync(ctx context.Context, serviceName string, token string, event []byte) (*types.JobInvocation, error) {
+	var body []byte
+	res, err := c.do(ctx, request{operation: "InvokeAsync", params: []string{serviceName}, body: event, contentType: "application/json", token: token}, &body)
+	if err != nil {
+		return nil, err
+	}
+	invocation := &types.JobInvocation{}
+	if res.StatusCode == http.StatusCreated {
+		if err := json.Unmarshal(body, invocation); err != nil {
+			return nil, fmt.Errorf("error decoding the response of InvokeAsync: %v", err)
+		}
+	}
+	return invocation, nil
+}
+
+// GetAsyncJobStatus returns the detailed status of a job created by an async invocation with the service's token
+func (c *Client) GetAsyncJobStatus(ctx context.Context, serviceName string, token string, jobName string) (*types.JobStatus, error) {
+	status := &types.JobStatus{}
+	if _, err := c.do(ctx, request{operation: "GetAsyncJobStatus", params: []string{serviceName, jobName}, token: token}, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// InvokeAlias invokes the service of an alias with the service's token (empty for public aliases), returning the
+// output of the sync aliases or the JSON-encoded JobInvocation of the async ones
+func (c *Client) InvokeAlias(ctx context.Context, alias string, token string, input []byte) ([]byte, error) {
+	req := request{operation: "InvokeAlias", params: []string{alias}, body: input, contentType: "application/json", token: token}
+	req.header = map[string][]string{"Accept": {"*/*"}}
+	var output []byte
+	if _, err := c.do(ctx, req, &output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// InvokeSync invokes a service synchronously with the service's token, returning its output
+func (c *Client) InvokeSync(ctx context.Context, serviceName string, token string, input []byte) ([]byte, error) {
+	req := request{operation: "InvokeSync", params: []string{serviceName}, body: input, contentType: "application/json", token: token}
+	req.header = map[string][]string{"Accept": {"*/*"}}
+	var output []byte
+	if _, err := c.do(ctx, req, &output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// InvokeSyncFile invokes a service synchronously with the service's token uploading a file, returning the output
+// stored by the service (or its response if it doesn't store any output)
+func (c *Client) InvokeSyncFile(ctx context.Context, serviceName string, token string, fileName string, file io.Reader) ([]byte, error) {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(fw, file); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	req := request{operation: "InvokeSync", params: []string{serviceName}, body: body.Bytes(), contentType: mw.FormDataContentType(), token: token}
+	req.header = map[string][]string{"Accept": {"*/*"}}
+	var output []byte
+	if _, err := c.do(ctx, req, &output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// InvokeQueued invokes a service asynchronously through the queue of the ServerlessBackend with the service's
+// token, returning the ID of the invocation
+func (c *Client) InvokeQueued(ctx context.Context, serviceName string, token string, event []byte) (string, error) {
+	res, err := c.do(ctx, request{operation: "InvokeQueued", params: []string{serviceName}, body: event, contentType: "application/json", token: token}, nil)
+	if err != nil {
+		return "", err
+	}
+	return res.Header.Get(invocationIDHeader), nil
+}
+
+// ListInvocations lists the queued invocations of a service, newest first
+func (c *Client) ListInvocations(ctx context.Context, serviceName string) ([]types.AsyncInvocation, error) {
+	invocations := []types.AsyncInvocation{}
+	if _, err := c.do(ctx, request{operation: "ListInvocations", params: []string{serviceName}}, &invocations); err != nil {
+		return nil, err
+	}
+	return invocations, nil
+}
+
+// ReadInvocation reads the status and the result location of a queued invocation
+func (c *Client) ReadInvocation(ctx context.Context, serviceName string, invocationID string) (*types.AsyncInvocation, error) {
+	invocation := &types.AsyncInvocation{}
+	if _, err := c.do(ctx, request{operation: "ReadInvocation", params: []string{serviceName, invocationID}}, invocation); err != nil {
+		return nil, err
+	}
+	return invocation, nil
+}
+
+// SyncGitScript re-syncs the script of a service from its Git repository, authenticated with the service's token
+func (c *Client) SyncGitScript(ctx context.Context, serviceName string, token string) error {
+	_, err := c.do(ctx, request{operation: "SyncGitScript", params: []string{serviceName}, token: token}, nil)
+	return err
+}
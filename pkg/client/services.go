@@ -0,0 +1,634 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// ServiceList page of the service list
+type ServiceList struct {
+	Services []*types.Service
+	// Total number of services matching the filters
+	Total int
+	// NextOffset offset of the next page (0 on the last page)
+	NextOffset int
+}
+
+// ServiceDetails service with the runtime info requested in ReadServiceOptions
+type ServiceDetails struct {
+	types.Service
+	Status     *types.ServiceStatus `json:"status,omitempty"`
+	Executions []types.JobSummary   `json:"executions,omitempty"`
+}
+
+// ReadServiceOptions runtime info to include when reading a service
+type ReadServiceOptions struct {
+	// Status include the status of the service
+	Status bool
+	// Executions include the last executions of the service
+	Executions bool
+	// Last number of executions to include (0 uses the server's default)
+	Last int
+}
+
+// ListServices lists the services with the filtering, sorting and pagination options
+func (c *Client) ListServices(ctx context.Context, opts types.ServiceListOptions) (*ServiceList, error) {
+	query := url.Values{}
+	for _, label := range opts.Labels {
+		query.Add("label", label)
+	}
+	setQuery(query, "vo", opts.VO)
+	setQuery(query, "sort", opts.Sort)
+	setQueryInt(query, "limit", opts.Limit)
+	setQueryInt(query, "offset", opts.Offset)
+
+	list := &ServiceList{}
+	res, err := c.do(ctx, request{operation: "ListServices", query: query}, &list.Services)
+	if err != nil {
+		return nil, err
+	}
+	list.Total, list.NextOffset = readPageHeaders(res.Header.Get(totalCountHeader), res.Header.Get(continueHeader))
+	return list, nil
+}
+
+// ListAllServices lists all the services satisfying the filters of opts, requesting them in pages of opts.Limit
+// services (MaxServiceListLimit by default) from opts.Offset
+func (c *Client) ListAllServices(ctx context.Context, opts types.ServiceListOptions) ([]*types.Service, error) {
+	if opts.Limit == 0 {
+		opts.Limit = types.MaxServiceListLimit
+	}
+	services := []*types.Service{}
+	for {
+		list, err := c.ListServices(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, list.Services...)
+		if list.NextOffset == 0 {
+			return services, nil
+		}
+		opts.Offset = list.NextOffset
+	}
+}
+
+// CreateService creates a service, returning its definition with the resolved defaults (e.g. the paths of the
+// outputs without provider). With dryRun the service is only validated
+func (c *Client) CreateService(ctx context.Context, service *types.Service, dryRun bool) (*types.Service, error) {
+	req, err := jsonRequest("CreateService", service)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		req.query = url.Values{"dry_run": {"true"}}
+	}
+	created := &types.Service{}
+	if _, err := c.do(ctx, req, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// CreateUploadSession returns an upload session to upload the script and assets of a service before creating it
+func (c *Client) CreateUploadSession(ctx context.Context, service *types.Service) (*types.UploadSession, error) {
+	req, err := jsonRequest("CreateService", service)
+	if err != nil {
+		return nil, err
+	}
+	req.query = url.Values{"upload": {"true"}}
+	session := &types.UploadSession{}
+	if _, err := c.do(ctx, req, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CreateServiceAsync starts the creation of a service in background, returning the operation reporting its progress
+func (c *Client) CreateServiceAsync(ctx context.Context, service *types.Service) (*types.Operation, error) {
+	req, err := jsonRequest("CreateService", service)
+	if err != nil {
+		return nil, err
+	}
+	req.query = url.Values{"async": {"true"}}
+	op := &types.Operation{}
+	if _, err := c.do(ctx, req, op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// ReadOperation returns the progress of an asynchronous operation
+func (c *Client) ReadOperation(ctx context.Context, operationID string) (*types.Operation, error) {
+	op := &types.Operation{}
+	if _, err := c.do(ctx, request{operation: "ReadOperation", params: []string{operationID}}, op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// UpdateService updates a service. With dryRun the service is only validated, returning its fully-defaulted definition
+func (c *Client) UpdateService(ctx context.Context, service *types.Service, dryRun bool) (*types.Service, error) {
+	return c.writeService(ctx, "UpdateService", service, dryRun)
+}
+
+// PatchService partially updates a service with a JSON merge patch (RFC 7386), returning the updated service.
+// With dryRun the service is only validated
+func (c *Client) PatchService(ctx context.Context, name string, patch []byte, dryRun bool) (*types.Service, error) {
+	req := request{operation: "PatchService", params: []string{name}, body: patch, contentType: "application/merge-patch+json"}
+	if dryRun {
+		req.query = url.Values{"dry_run": {"true"}}
+	}
+	service := &types.Service{}
+	if _, err := c.do(ctx, req, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// ReadService reads a service, including the runtime info of opts (if not nil)
+func (c *Client) ReadService(ctx context.Context, name string, opts *ReadServiceOptions) (*ServiceDetails, error) {
+	query := url.Values{}
+	if opts != nil {
+		include := []string{}
+		if opts.Status {
+			include = append(include, "status")
+		}
+		if opts.Executions {
+			include = append(include, "executions")
+		}
+		setQuery(query, "include", strings.Join(include, ","))
+		setQueryInt(query, "last", opts.Last)
+	}
+
+	service := &ServiceDetails{}
+	if _, err := c.do(ctx, request{operation: "ReadService", params: []string{name}, query: query}, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// DeleteService deletes a service
+func (c *Client) DeleteService(ctx context.Context, name string) error {
+	_, err := c.do(ctx, request{operation: "DeleteService", params: []string{name}}, nil)
+	return err
+}
+
+// DeleteServiceWithBuckets deletes a service setting the fate of its buckets: "true" (removed with their data),
+// "false" (kept untouched) or "orphan" (kept, removing their notifications)
+func (c *Client) DeleteServiceWithBuckets(ctx context.Context, name string, deleteBuckets string) error {
+	req := request{operation: "DeleteService", params: []string{name}}
+	req.query = url.Values{"delete_buckets": {deleteBuckets}}
+	_, err := c.do(ctx, req, nil)
+	return err
+}
+
+// SoftDeleteService deletes a service keeping its definition to be restored within the retention period
+func (c *Client) SoftDeleteService(ctx context.Context, name string) error {
+	req := request{operation: "DeleteService", params: []string{name}}
+	req.query = url.Values{"purge": {"false"}}
+	_, err := c.do(ctx, req, nil)
+	return err
+}
+
+// CreateServicesBulk creates several services at once. If any of them fails none is created
+func (c *Client) CreateServicesBulk(ctx context.Context, services []*types.Service) error {
+	req, err := jsonRequest("CreateServicesBulk", services)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, req, nil)
+	return err
+}
+
+// ListCatalogTemplates returns the templates of the service catalog
+func (c *Client) ListCatalogTemplates(ctx context.Context) ([]types.CatalogTemplate, error) {
+	templates := []types.CatalogTemplate{}
+	if _, err := c.do(ctx, request{operation: "ListCatalogTemplates"}, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// DeployCatalogTemplate creates the services of a catalog template with the values of its parameters. With dryRun
+// the services are only validated and returned (nil otherwise)
+func (c *Client) DeployCatalogTemplate(ctx context.Context, template string, params map[string]string, dryRun bool) ([]types.Service, error) {
+	req, err := jsonRequest("DeployCatalogTemplate", types.CatalogDeployRequest{Parameters: params}, template)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		_, err := c.do(ctx, req, nil)
+		return nil, err
+	}
+	req.query = url.Values{"dry_run": {"true"}}
+	services := []types.Service{}
+	if _, err := c.do(ctx, req, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// ImportServices creates the services of an FDL document (as returned by ExportService),
+// resolving its secret references. If any of them fails none is created
+func (c *Client) ImportServices(ctx context.Context, fdl []byte) error {
+	_, err := c.do(ctx, request{operation: "ImportServices", body: fdl, contentType: "application/yaml"}, nil)
+	return err
+}
+
+// ExportService returns a service as a portable FDL document, with its credentials replaced by secret references
+func (c *Client) ExportService(ctx context.Context, name string) ([]byte, error) {
+	req := request{operation: "ExportService", params: []string{name}, header: map[string][]string{"Accept": {"application/yaml"}}}
+	var fdl []byte
+	if _, err := c.do(ctx, req, &fdl); err != nil {
+		return nil, err
+	}
+	return fdl, nil
+}
+
+// ValidateService validates a service definition without creating it
+func (c *Client) ValidateService(ctx context.Context, service *types.Service) (*types.ValidationResult, error) {
+	req, err := jsonRequest("ValidateService", service)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.ValidationResult{}
+	if _, err := c.do(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CloneService creates a new service from the definition of an existing one, returning the new service.
+// With dryRun the new service is only validated
+func (c *Client) CloneService(ctx context.Context, name string, clone types.CloneRequest, dryRun bool) (*types.Service, error) {
+	req, err := jsonRequest("CloneService", clone, name)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		req.query = url.Values{"dry_run": {"true"}}
+	}
+	service := &types.Service{}
+	if _, err := c.do(ctx, req, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// ListServiceRevisions lists the stored revisions of a service definition, newest first
+func (c *Client) ListServiceRevisions(ctx context.Context, name string) ([]types.ServiceRevision, error) {
+	revisions := []types.ServiceRevision{}
+	if _, err := c.do(ctx, request{operation: "ListServiceRevisions", params: []string{name}}, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// RollbackService restores a revision of a service definition, returning the restored service
+func (c *Client) RollbackService(ctx context.Context, name string, revision int) (*types.Service, error) {
+	service := &types.Service{}
+	if _, err := c.do(ctx, request{operation: "RollbackService", params: []string{name, strconv.Itoa(revision)}}, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// UnlockService unlocks a protected service, so it can be updated, rolled back or deleted once within the
+// next minutes
+func (c *Client) UnlockService(ctx context.Context, name string) (*types.ServiceUnlock, error) {
+	unlock := &types.ServiceUnlock{}
+	if _, err := c.do(ctx, request{operation: "UnlockService", params: []string{name}}, unlock); err != nil {
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// RestoreService deploys again a soft-deleted service, returning the restored service
+func (c *Client) RestoreService(ctx context.Context, name string) (*types.Service, error) {
+	service := &types.Service{}
+	if _, err := c.do(ctx, request{operation: "RestoreService", params: []string{name}}, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// ListDeletedServices returns the soft-deleted services that can be restored, most recently deleted first
+func (c *Client) ListDeletedServices(ctx context.Context) ([]types.DeletedService, error) {
+	deleted := []types.DeletedService{}
+	if _, err := c.do(ctx, request{operation: "ListDeletedServices"}, &deleted); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// ReplayService re-enqueues the events of the objects stored in the service's MinIO inputs
+func (c *Client) ReplayService(ctx context.Context, name string, replay types.ReplayRequest) (*types.ReplayResult, error) {
+	req, err := jsonRequest("ReplayService", replay, name)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.ReplayResult{}
+	if _, err := c.do(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SimulateServiceEvent simulates the trigger of a service with a synthetic storage event,
+// returning the job that would be created
+func (c *Client) SimulateServiceEvent(ctx context.Context, name string, simulation types.EventSimulationRequest) (*types.EventSimulation, error) {
+	req, err := jsonRequest("SimulateServiceEvent", simulation, name)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.EventSimulation{}
+	if _, err := c.do(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListDeadLetter lists the failed jobs stored in the service's dead-letter path
+func (c *Client) ListDeadLetter(ctx context.Context, name string) ([]types.DeadLetterRecord, error) {
+	records := []types.DeadLetterRecord{}
+	if _, err := c.do(ctx, request{operation: "ListDeadLetter", params: []string{name}}, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RedriveDeadLetter creates new jobs for the dead-letter records (all of them if redrive.IDs is empty)
+func (c *Client) RedriveDeadLetter(ctx context.Context, name string, redrive types.RedriveRequest) (*types.RedriveResult, error) {
+	req, err := jsonRequest("RedriveDeadLetter", redrive, name)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.RedriveResult{}
+	if _, err := c.do(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateServiceAlias registers a short invocation alias (/i/{alias}) of a service
+func (c *Client) CreateServiceAlias(ctx context.Context, name string, alias *types.InvocationAlias) (*types.InvocationAlias, error) {
+	req, err := jsonRequest("CreateServiceAlias", alias, name)
+	if err != nil {
+		return nil, err
+	}
+	created := &types.InvocationAlias{}
+	if _, err := c.do(ctx, req, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ListServiceAliases lists the invocation aliases of a service
+func (c *Client) ListServiceAliases(ctx context.Context, name string) ([]types.InvocationAlias, error) {
+	aliases := []types.InvocationAlias{}
+	if _, err := c.do(ctx, request{operation: "ListServiceAliases", params: []string{name}}, &aliases); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// DeleteServiceAlias removes an invocation alias of a service
+func (c *Client) DeleteServiceAlias(ctx context.Context, name string, alias string) error {
+	_, err := c.do(ctx, request{operation: "DeleteServiceAlias", params: []string{name, alias}}, nil)
+	return err
+}
+
+// CreateServiceToken creates a named access token of a service with the scopes granted, returning its value
+func (c *Client) CreateServiceToken(ctx context.Context, name string, tokenName string, scopes []string) (*types.ServiceAccessToken, error) {
+	req, err := jsonRequest("CreateServiceToken", types.ServiceAccessTokenRequest{Name: tokenName, Scopes: scopes}, name)
+	if err != nil {
+		return nil, err
+	}
+	token := &types.ServiceAccessToken{}
+	if _, err := c.do(ctx, req, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ListServiceTokens lists the named access tokens of a service, without their values
+func (c *Client) ListServiceTokens(ctx context.Context, name string) ([]types.ServiceAccessToken, error) {
+	tokens := []types.ServiceAccessToken{}
+	if _, err := c.do(ctx, request{operation: "ListServiceTokens", params: []string{name}}, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RotateServiceToken replaces the value of a named access token of a service, returning the new value
+func (c *Client) RotateServiceToken(ctx context.Context, name string, tokenName string) (*types.ServiceAccessToken, error) {
+	token := &types.ServiceAccessToken{}
+	if _, err := c.do(ctx, request{operation: "RotateServiceToken", params: []string{name, tokenName}}, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeServiceToken revokes a named access token of a service
+func (c *Client) RevokeServiceToken(ctx context.Context, name string, tokenName string) error {
+	_, err := c.do(ctx, request{operation: "RevokeServiceToken", params: []string{name, tokenName}}, nil)
+	return err
+}
+
+// PresignInvocation generates a presigned URL to invoke a service synchronously without credentials, with the
+// validity and the constraints of the body of the request
+func (c *Client) PresignInvocation(ctx context.Context, name string, presign types.PresignRequest) (*types.PresignedURL, error) {
+	req, err := jsonRequest("PresignInvocation", presign, name)
+	if err != nil {
+		return nil, err
+	}
+	presigned := &types.PresignedURL{}
+	if _, err := c.do(ctx, req, presigned); err != nil {
+		return nil, err
+	}
+	return presigned, nil
+}
+
+// GetServiceLatency returns the latency report of the last limit jobs of a service (0 for the default)
+func (c *Client) GetServiceLatency(ctx context.Context, name string, limit int) (*types.ServiceLatencyReport, error) {
+	req := request{operation: "GetServiceLatency", params: []string{name}}
+	if limit > 0 {
+		req.query = url.Values{"limit": {strconv.Itoa(limit)}}
+	}
+	report := &types.ServiceLatencyReport{}
+	if _, err := c.do(ctx, req, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetServiceHealth returns the health summary of a service, including the outcome of its last jobs (0 for the default)
+func (c *Client) GetServiceHealth(ctx context.Context, name string, last int) (*types.ServiceHealth, error) {
+	req := request{operation: "GetServiceHealth", params: []string{name}}
+	if last > 0 {
+		req.query = url.Values{"last": {strconv.Itoa(last)}}
+	}
+	health := &types.ServiceHealth{}
+	if _, err := c.do(ctx, req, health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// GetServiceSchema returns the JSON Schemas of the input and output of a service's synchronous invocations
+func (c *Client) GetServiceSchema(ctx context.Context, name string) (*types.ServiceSchema, error) {
+	schema := &types.ServiceSchema{}
+	if _, err := c.do(ctx, request{operation: "GetServiceSchema", params: []string{name}}, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// ListServiceSecrets returns the names of the secrets of a service
+func (c *Client) ListServiceSecrets(ctx context.Context, name string) ([]string, error) {
+	secrets := &types.ServiceSecrets{}
+	if _, err := c.do(ctx, request{operation: "ListServiceSecrets", params: []string{name}}, secrets); err != nil {
+		return nil, err
+	}
+	return secrets.Names, nil
+}
+
+// UpdateServiceSecrets replaces the secrets of a service, set as environment variables of its containers
+func (c *Client) UpdateServiceSecrets(ctx context.Context, name string, secrets map[string]string) error {
+	req, err := jsonRequest("UpdateServiceSecrets", types.ServiceSecrets{Secrets: secrets}, name)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, req, nil)
+	return err
+}
+
+// ListExposedDomains returns the custom domains of an exposed service
+func (c *Client) ListExposedDomains(ctx context.Context, name string) ([]types.ExposedDomain, error) {
+	domains := []types.ExposedDomain{}
+	if _, err := c.do(ctx, request{operation: "ListExposedDomains", params: []string{name}}, &domains); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// SetExposedDomain attaches a custom domain to an exposed service (or updates it)
+func (c *Client) SetExposedDomain(ctx context.Context, name string, domain types.ExposedDomain) (*types.ExposedDomain, error) {
+	req, err := jsonRequest("SetExposedDomain", domain, name, domain.Host)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.ExposedDomain{}
+	if _, err := c.do(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteExposedDomain detaches a custom domain from an exposed service
+func (c *Client) DeleteExposedDomain(ctx context.Context, name string, host string) error {
+	_, err := c.do(ctx, request{operation: "DeleteExposedDomain", params: []string{name, host}}, nil)
+	return err
+}
+
+// ListCallbackDeliveries lists the last deliveries of the service's callback, newest first
+func (c *Client) ListCallbackDeliveries(ctx context.Context, name string) ([]types.CallbackDelivery, error) {
+	deliveries := []types.CallbackDelivery{}
+	if _, err := c.do(ctx, request{operation: "ListCallbackDeliveries", params: []string{name}}, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RedeliverCallback sends again a delivery of the service's callback, returning the result of the attempt
+func (c *Client) RedeliverCallback(ctx context.Context, name string, deliveryID string) (*types.CallbackDelivery, error) {
+	delivery := &types.CallbackDelivery{}
+	if _, err := c.do(ctx, request{operation: "RedeliverCallback", params: []string{name, deliveryID}}, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ToggleServiceInput enables or disables the trigger of an input of a service
+func (c *Client) ToggleServiceInput(ctx context.Context, name string, index int, enabled bool) error {
+	req, err := jsonRequest("ToggleServiceInput", map[string]bool{"enabled": enabled}, name, strconv.Itoa(index))
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, req, nil)
+	return err
+}
+
+// UpdateServiceFeatures sets (or removes, with a nil value) feature flags of a service, returning its updated flags
+func (c *Client) UpdateServiceFeatures(ctx context.Context, name string, features map[string]*string) (map[string]string, error) {
+	req, err := jsonRequest("UpdateServiceFeatures", features, name)
+	if err != nil {
+		return nil, err
+	}
+	updated := map[string]string{}
+	if _, err := c.do(ctx, req, &updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// writeService creates or updates a service
+func (c *Client) writeService(ctx context.Context, operation string, service *types.Service, dryRun bool) (*types.Service, error) {
+	req, err := jsonRequest(operation, service)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		_, err := c.do(ctx, req, nil)
+		return nil, err
+	}
+
+	req.query = url.Values{"dry_run": {"true"}}
+	defaulted := &types.Service{}
+	if _, err := c.do(ctx, req, defaulted); err != nil {
+		return nil, err
+	}
+	return defaulted, nil
+}
+
+// setQuery sets a querystring parameter if value is not empty
+func setQuery(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+// setQueryInt sets a querystring parameter if value is not zero
+func setQueryInt(query url.Values, key string, value int) {
+	if value != 0 {
+		query.Set(key, strconv.Itoa(value))
+	}
+}
+
+// readPageHeaders returns the total count and the offset of the next page from the pagination headers
+func readPageHeaders(totalCount string, continueToken string) (total int, nextOffset int) {
+	total, _ = strconv.Atoi(totalCount)
+	if continueToken != "" {
+		nextOffset, _ = types.ParseServiceListContinue(continueToken)
+	}
+	return total, nextOffset
+}
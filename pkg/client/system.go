@@ -0,0 +1,363 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// GetInfo returns the info of the OSCAR cluster
+func (c *Client) GetInfo(ctx context.Context) (*types.Info, error) {
+	info := &types.Info{}
+	if _, err := c.do(ctx, request{operation: "GetInfo"}, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// GetCapacity returns the capacity of the cluster
+func (c *Client) GetCapacity(ctx context.Context) (*types.ClusterCapacity, error) {
+	capacity := &types.ClusterCapacity{}
+	if _, err := c.do(ctx, request{operation: "GetCapacity"}, capacity); err != nil {
+		return nil, err
+	}
+	return capacity, nil
+}
+
+// GetMetrics returns the delivery stats of the MinIO notifications of the services in OpenMetrics format (only for
+// the admin user)
+func (c *Client) GetMetrics(ctx context.Context) (string, error) {
+	req := request{operation: "GetMetrics"}
+	req.header = map[string][]string{"Accept": {"application/openmetrics-text"}}
+	var metrics []byte
+	if _, err := c.do(ctx, req, &metrics); err != nil {
+		return "", err
+	}
+	return string(metrics), nil
+}
+
+// ListAuditEntries returns the most recent entries of the audit log passing the filter, newest first (only for the
+// admins). The default limit is applied if not set
+func (c *Client) ListAuditEntries(ctx context.Context, filter types.AuditFilter) ([]types.AuditEntry, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{"subject": filter.Subject, "service": filter.Service, "operation": filter.Operation, "result": filter.Result} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		query.Set("until", filter.Until.Format(time.RFC3339))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", fmt.Sprint(filter.Limit))
+	}
+
+	entries := []types.AuditEntry{}
+	if _, err := c.do(ctx, request{operation: "ListAuditEntries", query: query}, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CreateUser creates a user of the user store (only for the admins)
+func (c *Client) CreateUser(ctx context.Context, user types.UserRequest) (*types.User, error) {
+	req, err := jsonRequest("CreateUser", user)
+	if err != nil {
+		return nil, err
+	}
+	created := &types.User{}
+	if _, err := c.do(ctx, req, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ListUsers lists the users of the user store, without their passwords (only for the admins)
+func (c *Client) ListUsers(ctx context.Context) ([]types.User, error) {
+	users := []types.User{}
+	if _, err := c.do(ctx, request{operation: "ListUsers"}, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ReadUser returns a user of the user store, without its password (only for the admins)
+func (c *Client) ReadUser(ctx context.Context, username string) (*types.User, error) {
+	user := &types.User{}
+	if _, err := c.do(ctx, request{operation: "ReadUser", params: []string{username}}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUser updates the password, the role, the groups or the status of a user of the user store, keeping the
+// fields not set (only for the admins)
+func (c *Client) UpdateUser(ctx context.Context, user types.UserRequest) (*types.User, error) {
+	req, err := jsonRequest("UpdateUser", user, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	updated := &types.User{}
+	if _, err := c.do(ctx, req, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteUser deletes a user of the user store (only for the admins)
+func (c *Client) DeleteUser(ctx context.Context, username string) error {
+	_, err := c.do(ctx, request{operation: "DeleteUser", params: []string{username}}, nil)
+	return err
+}
+
+// CreateAPIKey creates an API key of the OSCAR manager with the role granted until its expiry, returning its value
+// (only for the admins)
+func (c *Client) CreateAPIKey(ctx context.Context, name string, role string, expiresAt time.Time) (*types.APIKey, error) {
+	req, err := jsonRequest("CreateAPIKey", types.APIKeyRequest{Name: name, Role: role, ExpiresAt: expiresAt})
+	if err != nil {
+		return nil, err
+	}
+	key := &types.APIKey{}
+	if _, err := c.do(ctx, req, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys lists the API keys of the OSCAR manager, without their values (only for the admins)
+func (c *Client) ListAPIKeys(ctx context.Context) ([]types.APIKey, error) {
+	keys := []types.APIKey{}
+	if _, err := c.do(ctx, request{operation: "ListAPIKeys"}, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key of the OSCAR manager (only for the admins)
+func (c *Client) RevokeAPIKey(ctx context.Context, name string) error {
+	_, err := c.do(ctx, request{operation: "RevokeAPIKey", params: []string{name}}, nil)
+	return err
+}
+
+// QueryGraphQL runs a query of the GraphQL API, decoding its data in out. The errors of the query are returned as a
+// single error
+func (c *Client) QueryGraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	req, err := jsonRequest("QueryGraphQL", map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	if _, err := c.do(ctx, req, &result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		messages := []string{}
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("GraphQL query failed: %s", strings.Join(messages, "; "))
+	}
+	if out == nil || len(result.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+// ListCapabilities returns the hardware capabilities provided by the nodes of the cluster
+func (c *Client) ListCapabilities(ctx context.Context) ([]types.Capability, error) {
+	capabilities := []types.Capability{}
+	if _, err := c.do(ctx, request{operation: "ListCapabilities"}, &capabilities); err != nil {
+		return nil, err
+	}
+	return capabilities, nil
+}
+
+// GetConfig returns the configuration of the OSCAR manager (only the fields exposed by the API are set)
+func (c *Client) GetConfig(ctx context.Context) (*types.Config, error) {
+	cfg := &types.Config{}
+	if _, err := c.do(ctx, request{operation: "GetConfig"}, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ListErrors returns the catalog of the errors returned by the OSCAR API
+func (c *Client) ListErrors(ctx context.Context) ([]types.ErrorCode, error) {
+	codes := []types.ErrorCode{}
+	if _, err := c.do(ctx, request{operation: "ListErrors"}, &codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// GetUsageReport returns the usage report of the cluster per VO between from and to
+// (zero values use the server's defaults)
+func (c *Client) GetUsageReport(ctx context.Context, from time.Time, to time.Time) (*types.UsageReport, error) {
+	query := url.Values{}
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339))
+	}
+	report := &types.UsageReport{}
+	if _, err := c.do(ctx, request{operation: "GetUsageReport", query: query}, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetUsage returns the resources consumed by each VO and user in the quotas period, along with their quotas.
+// If vo or user are not empty only their usage is returned
+func (c *Client) GetUsage(ctx context.Context, vo string, user string) (*types.UsageSummary, error) {
+	query := url.Values{}
+	if vo != "" {
+		query.Set("vo", vo)
+	}
+	if user != "" {
+		query.Set("user", user)
+	}
+	summary := &types.UsageSummary{}
+	if _, err := c.do(ctx, request{operation: "GetUsage", query: query}, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// GetMaintenanceStatus returns the status of the read-only mode of the API
+func (c *Client) GetMaintenanceStatus(ctx context.Context) (*types.MaintenanceStatus, error) {
+	status := &types.MaintenanceStatus{}
+	if _, err := c.do(ctx, request{operation: "GetMaintenanceStatus"}, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// FreezeAPI puts the API in read-only mode for maintenance
+func (c *Client) FreezeAPI(ctx context.Context, freeze types.MaintenanceFreezeRequest) (*types.MaintenanceStatus, error) {
+	req, err := jsonRequest("FreezeAPI", freeze)
+	if err != nil {
+		return nil, err
+	}
+	status := &types.MaintenanceStatus{}
+	if _, err := c.do(ctx, req, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// UnfreezeAPI ends the read-only mode of the API
+func (c *Client) UnfreezeAPI(ctx context.Context) (*types.MaintenanceStatus, error) {
+	status := &types.MaintenanceStatus{}
+	if _, err := c.do(ctx, request{operation: "UnfreezeAPI"}, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ReconcileWebhooks restores the MinIO webhooks and bucket notifications missing for the stored services.
+// With dryRun the drift is only reported
+func (c *Client) ReconcileWebhooks(ctx context.Context, dryRun bool) (*types.ReconcileReport, error) {
+	query := url.Values{}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	report := &types.ReconcileReport{}
+	if _, err := c.do(ctx, request{operation: "ReconcileWebhooks", query: query}, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// CreateVORequest requests enabling a new VO in the cluster, pending on the approval of the admin
+func (c *Client) CreateVORequest(ctx context.Context, voRequest types.VORequest) (*types.VORequest, error) {
+	req, err := jsonRequest("CreateVORequest", voRequest)
+	if err != nil {
+		return nil, err
+	}
+	created := &types.VORequest{}
+	if _, err := c.do(ctx, req, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ListVORequests lists the VO requests (of the user, or all of them for the admin) with the status (all if empty)
+func (c *Client) ListVORequests(ctx context.Context, status string) ([]types.VORequest, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	requests := []types.VORequest{}
+	if _, err := c.do(ctx, request{operation: "ListVORequests", query: query}, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ReadVORequest returns a VO request
+func (c *Client) ReadVORequest(ctx context.Context, id string) (*types.VORequest, error) {
+	voRequest := &types.VORequest{}
+	if _, err := c.do(ctx, request{operation: "ReadVORequest", params: []string{id}}, voRequest); err != nil {
+		return nil, err
+	}
+	return voRequest, nil
+}
+
+// ApproveVORequest approves a pending VO request, enabling the VO in the cluster (admin only)
+func (c *Client) ApproveVORequest(ctx context.Context, id string, decision types.VORequestDecision) (*types.VORequest, error) {
+	return c.decideVORequest(ctx, "ApproveVORequest", id, decision)
+}
+
+// RejectVORequest rejects a pending VO request (admin only)
+func (c *Client) RejectVORequest(ctx context.Context, id string, decision types.VORequestDecision) (*types.VORequest, error) {
+	return c.decideVORequest(ctx, "RejectVORequest", id, decision)
+}
+
+// decideVORequest sends the decision on a VO request to the operation
+func (c *Client) decideVORequest(ctx context.Context, operation string, id string, decision types.VORequestDecision) (*types.VORequest, error) {
+	req, err := jsonRequest(operation, decision, id)
+	if err != nil {
+		return nil, err
+	}
+	voRequest := &types.VORequest{}
+	if _, err := c.do(ctx, req, voRequest); err != nil {
+		return nil, err
+	}
+	return voRequest, nil
+}
+
+// HealthCheck checks the health of the OSCAR manager
+func (c *Client) HealthCheck(ctx context.Context) error {
+	_, err := c.do(ctx, request{operation: "HealthCheck"}, nil)
+	return err
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// ReadUploadSession reads an upload session
+func (c *Client) ReadUploadSession(ctx context.Context, uploadID string) (*types.UploadSession, error) {
+	session := &types.UploadSession{}
+	if _, err := c.do(ctx, request{operation: "ReadUploadSession", params: []string{uploadID}}, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// DeleteUploadSession deletes an upload session and its staged assets
+func (c *Client) DeleteUploadSession(ctx context.Context, uploadID string) error {
+	_, err := c.do(ctx, request{operation: "DeleteUploadSession", params: []string{uploadID}}, nil)
+	return err
+}
+
+// UploadAsset uploads an asset of an upload session, in chunks of types.UploadMaxChunkSize bytes at most.
+// Returns the asset after uploading its last chunk
+func (c *Client) UploadAsset(ctx context.Context, uploadID string, assetName string, data []byte) (*types.UploadAsset, error) {
+	total := len(data)
+	asset := &types.UploadAsset{}
+	for offset := 0; offset == 0 || offset < total; offset += types.UploadMaxChunkSize {
+		end := offset + types.UploadMaxChunkSize
+		if end > total {
+			end = total
+		}
+		req := request{operation: "UploadAsset", params: []string{uploadID, assetName}, body: data[offset:end], contentType: "application/octet-stream"}
+		if total > 0 {
+			req.header = map[string][]string{"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", offset, end-1, total)}}
+		}
+		if _, err := c.do(ctx, req, asset); err != nil {
+			return nil, err
+		}
+	}
+	return asset, nil
+}
+
+// ActivateUploadSession creates the service of a completed upload session
+func (c *Client) ActivateUploadSession(ctx context.Context, uploadID string) error {
+	_, err := c.do(ctx, request{operation: "ActivateUploadSession", params: []string{uploadID}}, nil)
+	return err
+}
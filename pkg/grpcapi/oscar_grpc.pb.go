@@ -0,0 +1,464 @@
+// Copyright (C) GRyCAP - I3M - UPV
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: oscar.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Oscar_ListServices_FullMethodName = "/oscar.v1.Oscar/ListServices"
+	Oscar_GetService_FullMethodName   = "/oscar.v1.Oscar/GetService"
+	Oscar_ListJobs_FullMethodName     = "/oscar.v1.Oscar/ListJobs"
+	Oscar_GetJob_FullMethodName       = "/oscar.v1.Oscar/GetJob"
+	Oscar_DeleteJob_FullMethodName    = "/oscar.v1.Oscar/DeleteJob"
+	Oscar_StreamLogs_FullMethodName   = "/oscar.v1.Oscar/StreamLogs"
+	Oscar_InvokeAsync_FullMethodName  = "/oscar.v1.Oscar/InvokeAsync"
+	Oscar_Invoke_FullMethodName       = "/oscar.v1.Oscar/Invoke"
+)
+
+// OscarClient is the client API for Oscar service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OscarClient interface {
+	// ListServices returns the services of the cluster.
+	ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ListServicesResponse, error)
+	// GetService returns a service.
+	GetService(ctx context.Context, in *GetServiceRequest, opts ...grpc.CallOption) (*Service, error)
+	// ListJobs returns the jobs of a service, newest first.
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	// GetJob returns the detailed status of a job.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// DeleteJob removes a job and its pods.
+	DeleteJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*DeleteJobResponse, error)
+	// StreamLogs streams the logs of a job, line by line, until the job finishes (or until the current logs are sent
+	// if follow is false).
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Oscar_StreamLogsClient, error)
+	// InvokeAsync creates a job of a service processing the event.
+	InvokeAsync(ctx context.Context, in *InvokeAsyncRequest, opts ...grpc.CallOption) (*InvokeAsyncResponse, error)
+	// Invoke invokes a service synchronously. The first message must set the service (and optionally the content
+	// type) and the body is sent in the data of the messages. The response of the service is streamed back as it is
+	// received, with its status code and content type in the first message.
+	Invoke(ctx context.Context, opts ...grpc.CallOption) (Oscar_InvokeClient, error)
+}
+
+type oscarClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOscarClient(cc grpc.ClientConnInterface) OscarClient {
+	return &oscarClient{cc}
+}
+
+func (c *oscarClient) ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ListServicesResponse, error) {
+	out := new(ListServicesResponse)
+	err := c.cc.Invoke(ctx, Oscar_ListServices_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oscarClient) GetService(ctx context.Context, in *GetServiceRequest, opts ...grpc.CallOption) (*Service, error) {
+	out := new(Service)
+	err := c.cc.Invoke(ctx, Oscar_GetService_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oscarClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, Oscar_ListJobs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oscarClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Oscar_GetJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oscarClient) DeleteJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*DeleteJobResponse, error) {
+	out := new(DeleteJobResponse)
+	err := c.cc.Invoke(ctx, Oscar_DeleteJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oscarClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Oscar_StreamLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Oscar_ServiceDesc.Streams[0], Oscar_StreamLogs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &oscarStreamLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Oscar_StreamLogsClient interface {
+	Recv() (*LogChunk, error)
+	grpc.ClientStream
+}
+
+type oscarStreamLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *oscarStreamLogsClient) Recv() (*LogChunk, error) {
+	m := new(LogChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *oscarClient) InvokeAsync(ctx context.Context, in *InvokeAsyncRequest, opts ...grpc.CallOption) (*InvokeAsyncResponse, error) {
+	out := new(InvokeAsyncResponse)
+	err := c.cc.Invoke(ctx, Oscar_InvokeAsync_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oscarClient) Invoke(ctx context.Context, opts ...grpc.CallOption) (Oscar_InvokeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Oscar_ServiceDesc.Streams[1], Oscar_Invoke_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &oscarInvokeClient{stream}
+	return x, nil
+}
+
+type Oscar_InvokeClient interface {
+	Send(*InvokeRequest) error
+	Recv() (*InvokeResponse, error)
+	grpc.ClientStream
+}
+
+type oscarInvokeClient struct {
+	grpc.ClientStream
+}
+
+func (x *oscarInvokeClient) Send(m *InvokeRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *oscarInvokeClient) Recv() (*InvokeResponse, error) {
+	m := new(InvokeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OscarServer is the server API for Oscar service.
+// All implementations must embed UnimplementedOscarServer
+// for forward compatibility
+type OscarServer interface {
+	// ListServices returns the services of the cluster.
+	ListServices(context.Context, *ListServicesRequest) (*ListServicesResponse, error)
+	// GetService returns a service.
+	GetService(context.Context, *GetServiceRequest) (*Service, error)
+	// ListJobs returns the jobs of a service, newest first.
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	// GetJob returns the detailed status of a job.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// DeleteJob removes a job and its pods.
+	DeleteJob(context.Context, *GetJobRequest) (*DeleteJobResponse, error)
+	// StreamLogs streams the logs of a job, line by line, until the job finishes (or until the current logs are sent
+	// if follow is false).
+	StreamLogs(*StreamLogsRequest, Oscar_StreamLogsServer) error
+	// InvokeAsync creates a job of a service processing the event.
+	InvokeAsync(context.Context, *InvokeAsyncRequest) (*InvokeAsyncResponse, error)
+	// Invoke invokes a service synchronously. The first message must set the service (and optionally the content
+	// type) and the body is sent in the data of the messages. The response of the service is streamed back as it is
+	// received, with its status code and content type in the first message.
+	Invoke(Oscar_InvokeServer) error
+	mustEmbedUnimplementedOscarServer()
+}
+
+// UnimplementedOscarServer must be embedded to have forward compatible implementations.
+type UnimplementedOscarServer struct {
+}
+
+func (UnimplementedOscarServer) ListServices(context.Context, *ListServicesRequest) (*ListServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServices not implemented")
+}
+func (UnimplementedOscarServer) GetService(context.Context, *GetServiceRequest) (*Service, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetService not implemented")
+}
+func (UnimplementedOscarServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedOscarServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedOscarServer) DeleteJob(context.Context, *GetJobRequest) (*DeleteJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteJob not implemented")
+}
+func (UnimplementedOscarServer) StreamLogs(*StreamLogsRequest, Oscar_StreamLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedOscarServer) InvokeAsync(context.Context, *InvokeAsyncRequest) (*InvokeAsyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InvokeAsync not implemented")
+}
+func (UnimplementedOscarServer) Invoke(Oscar_InvokeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Invoke not implemented")
+}
+func (UnimplementedOscarServer) mustEmbedUnimplementedOscarServer() {}
+
+// UnsafeOscarServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OscarServer will
+// result in compilation errors.
+type UnsafeOscarServer interface {
+	mustEmbedUnimplementedOscarServer()
+}
+
+func RegisterOscarServer(s grpc.ServiceRegistrar, srv OscarServer) {
+	s.RegisterService(&Oscar_ServiceDesc, srv)
+}
+
+func _Oscar_ListServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OscarServer).ListServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Oscar_ListServices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OscarServer).ListServices(ctx, req.(*ListServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Oscar_GetService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OscarServer).GetService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Oscar_GetService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OscarServer).GetService(ctx, req.(*GetServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Oscar_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OscarServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Oscar_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OscarServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Oscar_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OscarServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Oscar_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OscarServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Oscar_DeleteJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OscarServer).DeleteJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Oscar_DeleteJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OscarServer).DeleteJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Oscar_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OscarServer).StreamLogs(m, &oscarStreamLogsServer{stream})
+}
+
+type Oscar_StreamLogsServer interface {
+	Send(*LogChunk) error
+	grpc.ServerStream
+}
+
+type oscarStreamLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *oscarStreamLogsServer) Send(m *LogChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Oscar_InvokeAsync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvokeAsyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OscarServer).InvokeAsync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Oscar_InvokeAsync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OscarServer).InvokeAsync(ctx, req.(*InvokeAsyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Oscar_Invoke_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OscarServer).Invoke(&oscarInvokeServer{stream})
+}
+
+type Oscar_InvokeServer interface {
+	Send(*InvokeResponse) error
+	Recv() (*InvokeRequest, error)
+	grpc.ServerStream
+}
+
+type oscarInvokeServer struct {
+	grpc.ServerStream
+}
+
+func (x *oscarInvokeServer) Send(m *InvokeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *oscarInvokeServer) Recv() (*InvokeRequest, error) {
+	m := new(InvokeRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Oscar_ServiceDesc is the grpc.ServiceDesc for Oscar service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Oscar_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "oscar.v1.Oscar",
+	HandlerType: (*OscarServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListServices",
+			Handler:    _Oscar_ListServices_Handler,
+		},
+		{
+			MethodName: "GetService",
+			Handler:    _Oscar_GetService_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _Oscar_ListJobs_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _Oscar_GetJob_Handler,
+		},
+		{
+			MethodName: "DeleteJob",
+			Handler:    _Oscar_DeleteJob_Handler,
+		},
+		{
+			MethodName: "InvokeAsync",
+			Handler:    _Oscar_InvokeAsync_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _Oscar_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Invoke",
+			Handler:       _Oscar_Invoke_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "oscar.proto",
+}
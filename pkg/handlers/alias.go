@@ -0,0 +1,216 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// aliasRateLimiter counts the invocations through each alias in fixed windows of one minute
+type aliasRateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*aliasWindow
+}
+
+// aliasWindow invocations through an alias in the current window
+type aliasWindow struct {
+	start time.Time
+	count int
+}
+
+// aliasLimiter rate limiter shared by all the aliases
+var aliasLimiter = &aliasRateLimiter{windows: map[string]*aliasWindow{}}
+
+// allow registers an invocation through the alias if its limit (invocations per minute) has not been reached.
+// Returns the time to wait until the next window otherwise
+func (l *aliasRateLimiter) allow(alias string, limit int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[alias]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &aliasWindow{start: now}
+		l.windows[alias] = w
+	}
+	if w.count >= limit {
+		return false, w.start.Add(time.Minute).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// reset removes the window of an alias
+func (l *aliasRateLimiter) reset(alias string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.windows, alias)
+}
+
+// MakeAliasCreateHandler makes a handler to register a short invocation alias (/i/{alias}) of a service
+func MakeAliasCreateHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias := &types.InvocationAlias{}
+		if err := c.ShouldBindJSON(alias); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The alias specification is not valid: %v", err))
+			return
+		}
+		if err := alias.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		if _, ok := back.(types.SyncBackend); alias.Sync && (!ok || cfg.ServerlessBackend == "") {
+			sendError(c, types.ErrBadRequest, "The synchronous invocations are not enabled in the cluster")
+			return
+		}
+
+		alias.Service = service.Name
+		alias.URL = types.AliasPathPrefix + alias.Alias
+		alias.CreatedAt = time.Now().UTC()
+		if err := utils.CreateAlias(back.GetKubeClientset(), cfg.ServicesNamespace, alias); err != nil {
+			if errors.IsAlreadyExists(err) {
+				sendError(c, types.ErrAliasAlreadyExists, fmt.Sprintf("The alias \"%s\" is already registered", alias.Alias))
+			} else {
+				sendError(c, types.ErrInternal, fmt.Sprintf("Error registering the alias: %v", err))
+			}
+			return
+		}
+		aliasLimiter.reset(alias.Alias)
+
+		c.JSON(http.StatusCreated, alias)
+	}
+}
+
+// MakeAliasListHandler makes a handler to list the invocation aliases of a service
+func MakeAliasListHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		aliases, err := utils.ListServiceAliases(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, aliases)
+	}
+}
+
+// MakeAliasDeleteHandler makes a handler to remove an invocation alias of a service
+func MakeAliasDeleteHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias, err := utils.GetAlias(back.GetKubeClientset(), cfg.ServicesNamespace, c.Param("alias"))
+		if err != nil && !errors.IsNotFound(err) {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+		if err != nil || alias.Service != c.Param("serviceName") {
+			sendError(c, types.ErrAliasNotFound, "")
+			return
+		}
+
+		if err := utils.DeleteAlias(back.GetKubeClientset(), cfg.ServicesNamespace, alias.Alias); err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error removing the alias: %v", err))
+			return
+		}
+		aliasLimiter.reset(alias.Alias)
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// MakeAliasInvokeHandler makes a handler to invoke a service through an alias, synchronously or creating a job.
+// The public aliases don't require the service's token
+func MakeAliasInvokeHandler(cfg *types.Config, back types.ServerlessBackend, dispatcher *EventDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alias, err := utils.GetAlias(back.GetKubeClientset(), cfg.ServicesNamespace, c.Param("alias"))
+		if err != nil {
+			if errors.IsNotFound(err) {
+				sendError(c, types.ErrAliasNotFound, "")
+			} else {
+				sendError(c, types.ErrInternal, err.Error())
+			}
+			return
+		}
+
+		service, err := back.ReadService(alias.Service)
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		// Check auth token (unless the alias is public)
+		if !alias.Public && !checkServiceToken(c, service) {
+			sendError(c, types.ErrUnauthorized, "")
+			return
+		}
+
+		if alias.RateLimit > 0 {
+			if ok, wait := aliasLimiter.allow(alias.Alias, alias.RateLimit, time.Now()); !ok {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				sendError(c, types.ErrRateLimited, fmt.Sprintf("The alias \"%s\" has reached its rate limit (%d invocations per minute)", alias.Alias, alias.RateLimit))
+				return
+			}
+		}
+
+		if !alias.Sync {
+			invokeAsync(c, dispatcher, service)
+			return
+		}
+		syncBack, ok := back.(types.SyncBackend)
+		if !ok || cfg.ServerlessBackend == "" {
+			sendError(c, types.ErrBadRequest, "The synchronous invocations are not enabled in the cluster")
+			return
+		}
+		invokeSync(c, cfg, syncBack, service)
+	}
+}
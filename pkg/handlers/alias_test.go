@@ -0,0 +1,95 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestAliasHandlers(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Token: "token", Labels: map[string]string{types.ServiceLabel: "test"}})
+	dispatcher := MakeEventDispatcher(&testConfigValidRun, back.GetKubeClientset(), nil)
+
+	r := gin.Default()
+	r.POST("/system/services/:serviceName/alias", MakeAliasCreateHandler(&testConfigValidRun, back))
+	r.GET("/system/services/:serviceName/alias", MakeAliasListHandler(&testConfigValidRun, back))
+	r.DELETE("/system/services/:serviceName/alias/:alias", MakeAliasDeleteHandler(&testConfigValidRun, back))
+	r.POST("/i/:alias", MakeAliasInvokeHandler(&testConfigValidRun, back, dispatcher))
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		token        string
+		expectedCode int
+	}{
+		{"create private alias", "POST", "/system/services/test/alias", `{"alias": "hook"}`, "", http.StatusCreated},
+		{"create public alias", "POST", "/system/services/test/alias", `{"alias": "public-hook", "public": true, "rate_limit": 1}`, "", http.StatusCreated},
+		{"duplicated alias", "POST", "/system/services/test/alias", `{"alias": "hook"}`, "", http.StatusConflict},
+		{"invalid alias", "POST", "/system/services/test/alias", `{"alias": "Hook_1"}`, "", http.StatusBadRequest},
+		{"public alias without rate limit", "POST", "/system/services/test/alias", `{"alias": "open", "public": true}`, "", http.StatusBadRequest},
+		{"sync alias without serverless backend", "POST", "/system/services/test/alias", `{"alias": "sync", "sync": true}`, "", http.StatusBadRequest},
+		{"service not found", "POST", "/system/services/other/alias", `{"alias": "other"}`, "", http.StatusNotFound},
+		{"private alias without token", "POST", "/i/hook", `{}`, "", http.StatusUnauthorized},
+		{"private alias", "POST", "/i/hook", `{}`, "token", http.StatusCreated},
+		{"public alias", "POST", "/i/public-hook", `{}`, "", http.StatusCreated},
+		{"public alias rate limited", "POST", "/i/public-hook", `{}`, "", http.StatusTooManyRequests},
+		{"delete alias of other service", "DELETE", "/system/services/other/alias/hook", "", "", http.StatusNotFound},
+		{"delete alias", "DELETE", "/system/services/test/alias/hook", "", "", http.StatusNoContent},
+		{"deleted alias", "POST", "/i/hook", `{}`, "token", http.StatusNotFound},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, strings.NewReader(s.body))
+			if s.token != "" {
+				req.Header.Set("Authorization", "Bearer "+s.token)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("expecting the Retry-After header")
+			}
+		})
+	}
+
+	// Only the public alias remains
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/services/test/alias", nil)
+	r.ServeHTTP(w, req)
+	var aliases []types.InvocationAlias
+	if err := json.Unmarshal(w.Body.Bytes(), &aliases); err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 1 || aliases[0].Alias != "public-hook" || aliases[0].URL != "/i/public-hook" || aliases[0].Service != "test" {
+		t.Errorf("unexpected aliases: %+v", aliases)
+	}
+}
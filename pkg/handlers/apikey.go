@@ -0,0 +1,123 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// MakeAPIKeyListHandler makes a handler to list the API keys of the OSCAR manager (without their values)
+func MakeAPIKeyListHandler(cfg *types.Config, apiKeys *utils.APIKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		keys, err := apiKeys.List()
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error reading the API keys: %v", err))
+			return
+		}
+		for i := range keys {
+			keys[i].Hash = ""
+		}
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// MakeAPIKeyCreateHandler makes a handler to create an API key of the OSCAR manager. The value of the key is only
+// returned in the response
+func MakeAPIKeyCreateHandler(cfg *types.Config, apiKeys *utils.APIKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		req := types.APIKeyRequest{}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The API key specification is not valid: %v", err))
+			return
+		}
+		if err := req.Validate(time.Now()); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		key, err := apiKeys.Create(req, c.GetString(gin.AuthUserKey))
+		if errors.Is(err, utils.ErrAPIKeyAlreadyExists) {
+			sendError(c, types.ErrAPIKeyAlreadyExists, fmt.Sprintf("The API key \"%s\" already exists", req.Name))
+			return
+		}
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error storing the API key: %v", err))
+			return
+		}
+		c.JSON(http.StatusCreated, key)
+	}
+}
+
+// MakeAPIKeyRevokeHandler makes a handler to revoke (remove) an API key of the OSCAR manager
+func MakeAPIKeyRevokeHandler(cfg *types.Config, apiKeys *utils.APIKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		err := apiKeys.Revoke(c.Param("keyName"))
+		if errors.Is(err, utils.ErrAPIKeyNotFound) {
+			sendError(c, types.ErrAPIKeyNotFound, fmt.Sprintf("The API key \"%s\" does not exist", c.Param("keyName")))
+			return
+		}
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error revoking the API key: %v", err))
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// MakeAPIKeyAuthMiddleware makes a middleware authenticating the requests with the API keys of the OSCAR manager
+// (as the "apikey:<name>" user with the role of the key). The rest of the requests are authenticated by
+// authMiddleware (basic auth, OIDC or client certificates)
+func MakeAPIKeyAuthMiddleware(apiKeys *utils.APIKeys, authMiddleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if !strings.HasPrefix(value, types.APIKeyPrefix) {
+			authMiddleware(c)
+			return
+		}
+
+		key, ok := apiKeys.Authenticate(value)
+		if !ok {
+			sendError(c, types.ErrUnauthorized, "The API key is not valid or has expired")
+			return
+		}
+		c.Set(gin.AuthUserKey, key.Subject())
+		c.Set(types.RoleKey, key.Role)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAPIKeys(t *testing.T) {
+	cfg := &types.Config{Username: "oscar", ServicesNamespace: "oscar-svc"}
+	apiKeys := utils.NewAPIKeys(cfg, testclient.NewSimpleClientset())
+	basicAuth := func(c *gin.Context) {
+		if _, _, ok := c.Request.BasicAuth(); !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(gin.AuthUserKey, cfg.Username)
+	}
+
+	r := gin.New()
+	system := r.Group("/system", MakeAPIKeyAuthMiddleware(apiKeys, basicAuth), MakeRBACMiddleware(cfg))
+	system.GET("/apikeys", MakeAPIKeyListHandler(cfg, apiKeys))
+	system.POST("/apikeys", MakeAPIKeyCreateHandler(cfg, apiKeys))
+	system.DELETE("/apikeys/:keyName", MakeAPIKeyRevokeHandler(cfg, apiKeys))
+	system.GET("/services", func(c *gin.Context) { c.String(http.StatusOK, c.GetString(gin.AuthUserKey)) })
+	system.POST("/services", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	request := func(method string, path string, body string, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		if key == "" {
+			req.SetBasicAuth("oscar", "password")
+		} else {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	w := request(http.MethodPost, "/system/apikeys", `{"name": "ci", "role": "viewer", "expires_at": "`+expiresAt+`"}`, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	key := types.APIKey{}
+	json.Unmarshal(w.Body.Bytes(), &key)
+	if !strings.HasPrefix(key.Key, types.APIKeyPrefix) || key.Hash != "" || key.CreatedBy != "oscar" {
+		t.Errorf("unexpected key %+v", key)
+	}
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		key          string
+		expectedCode int
+	}{
+		{"duplicated key", http.MethodPost, "/system/apikeys", `{"name": "ci", "role": "viewer", "expires_at": "` + expiresAt + `"}`, "", http.StatusConflict},
+		{"invalid role", http.MethodPost, "/system/apikeys", `{"name": "other", "role": "root", "expires_at": "` + expiresAt + `"}`, "", http.StatusBadRequest},
+		{"expired key", http.MethodPost, "/system/apikeys", `{"name": "other", "role": "viewer", "expires_at": "2020-01-01T00:00:00Z"}`, "", http.StatusBadRequest},
+		{"read with the key", http.MethodGet, "/system/services", "", key.Key, http.StatusOK},
+		{"write above the role of the key", http.MethodPost, "/system/services", "", key.Key, http.StatusForbidden},
+		{"keys management with the key", http.MethodGet, "/system/apikeys", "", key.Key, http.StatusForbidden},
+		{"invalid key", http.MethodGet, "/system/services", "", types.APIKeyPrefix + "invalid", http.StatusUnauthorized},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if w := request(s.method, s.path, s.body, s.key); w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := request(http.MethodGet, "/system/services", "", key.Key); w.Body.String() != "apikey:ci" {
+		t.Errorf("expecting the user of the key, got %s", w.Body.String())
+	}
+
+	w = request(http.MethodGet, "/system/apikeys", "", "")
+	keys := []types.APIKey{}
+	json.Unmarshal(w.Body.Bytes(), &keys)
+	if len(keys) != 1 || keys[0].Name != "ci" || keys[0].Hash != "" || keys[0].Key != "" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+
+	if w := request(http.MethodDelete, "/system/apikeys/ci", "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expecting code %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w := request(http.MethodGet, "/system/services", "", key.Key); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting the revoked key to be rejected, got %d", w.Code)
+	}
+	if w := request(http.MethodDelete, "/system/apikeys/ci", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expecting code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// auditServiceKey key set in the context of the requests whose service is not a path parameter (e.g. the creation)
+const auditServiceKey = "oscarAuditService"
+
+// callbackPaths prefixes of the paths of the internal callbacks, not recorded in the audit log nor rate limited
+var callbackPaths = []string{"/async-results/"}
+
+// MakeAuditMiddleware makes a middleware recording in the audit log the management operations (the requests
+// modifying the cluster) and the invocations, with their subject, client IP, request hash and result
+func MakeAuditMiddleware(auditor *utils.Auditor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auditor == nil || !isOperation(c) {
+			return
+		}
+
+		received := time.Now().UTC()
+		hash := sha256.New()
+		var body io.Reader
+		if c.Request.Body != nil {
+			body = io.TeeReader(c.Request.Body, hash)
+			c.Request.Body = struct {
+				io.Reader
+				io.Closer
+			}{body, c.Request.Body}
+		}
+
+		c.Next()
+
+		// Hash the rest of the body not read by the handler
+		if body != nil {
+			io.Copy(io.Discard, body)
+		}
+
+		route := c.FullPath()
+		entry := &types.AuditEntry{
+			Time:        received,
+			Subject:     getRequestSubject(c),
+			IP:          c.ClientIP(),
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			Operation:   getAuditOperation(c.Request.Method, route),
+			Service:     c.Param("serviceName"),
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+			Status:      c.Writer.Status(),
+			Result:      types.AuditResultSuccess,
+		}
+		if entry.Service == "" {
+			entry.Service = c.GetString(auditServiceKey)
+		}
+		if entry.Status >= http.StatusBadRequest {
+			entry.Result = types.AuditResultFailure
+			entry.ErrorCode = c.Writer.Header().Get(errorCodeHeader)
+		}
+		auditor.Record(entry)
+	}
+}
+
+// isOperation checks if a request is a management operation or an invocation (the requests modifying the cluster
+// of the known routes), recorded in the audit log and authorized by the authorization webhook
+func isOperation(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	if c.FullPath() == "" {
+		return false
+	}
+	for _, prefix := range callbackPaths {
+		if strings.HasPrefix(c.Request.URL.Path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// getRequestSubject returns the authenticated user of a request, or AuditServiceTokenSubject for the requests with
+// the token of a service
+func getRequestSubject(c *gin.Context) string {
+	if c.GetBool(serviceTokenAuthKey) || (c.GetString(gin.AuthUserKey) == "" && isInvocationPath(c.Request.URL.Path) && c.GetHeader("Authorization") != "") {
+		return types.AuditServiceTokenSubject
+	}
+	return c.GetString(gin.AuthUserKey)
+}
+
+// isInvocationPath checks if a path invokes a service
+func isInvocationPath(path string) bool {
+	for _, prefix := range invocationPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAuditOperation returns the operation of a request: the invocations and the requests requiring the invoker role
+// (e.g. replaying events) run the services, the rest create, update or delete by their method
+func getAuditOperation(method string, route string) string {
+	if isInvocationPath(route) || getRequiredRole(method, route) == types.RoleInvoker {
+		return types.AuditOperationRun
+	}
+	switch method {
+	case http.MethodPut, http.MethodPatch:
+		return types.AuditOperationUpdate
+	case http.MethodDelete:
+		return types.AuditOperationDelete
+	}
+	return types.AuditOperationCreate
+}
+
+// MakeAuditHandler makes a handler to query the audit log (only the admins). The querystrings "subject", "service",
+// "operation", "result", "since" and "until" (RFC 3339) filter the entries, returning the "limit" most recent ones
+func MakeAuditHandler(cfg *types.Config, auditor *utils.Auditor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		filter, err := getAuditFilter(c)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		entries, err := auditor.List(filter)
+		if err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+// getAuditFilter parses the querystrings of an audit log query
+func getAuditFilter(c *gin.Context) (types.AuditFilter, error) {
+	filter := types.AuditFilter{
+		Subject:   c.Query("subject"),
+		Service:   c.Query("service"),
+		Operation: c.Query("operation"),
+		Result:    c.Query("result"),
+		Limit:     types.DefaultAuditLimit,
+	}
+
+	var err error
+	if limit := c.Query("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return filter, fmt.Errorf("invalid limit \"%s\"", limit)
+		}
+	}
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, fmt.Errorf("invalid since \"%s\", it must be a RFC 3339 time", since)
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, fmt.Errorf("invalid until \"%s\", it must be a RFC 3339 time", until)
+		}
+	}
+	return filter, filter.Validate()
+}
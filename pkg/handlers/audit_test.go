@@ -0,0 +1,152 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+func TestAudit(t *testing.T) {
+	cfg := &types.Config{Username: "oscar", AuditSink: types.AuditSinkFile, AuditFile: filepath.Join(t.TempDir(), "audit.log")}
+	auditor, err := utils.NewAuditor(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(MakeAuditMiddleware(auditor))
+	system := r.Group("/system", func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	system.POST("/services", func(c *gin.Context) {
+		var service types.Service
+		c.ShouldBindJSON(&service)
+		c.Set(auditServiceKey, service.Name)
+		c.Status(http.StatusCreated)
+	})
+	system.DELETE("/services/:serviceName", func(c *gin.Context) {
+		sendError(c, types.ErrServiceNotFound, "")
+	})
+	system.GET("/services", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	system.GET("/audit", MakeAuditHandler(cfg, auditor))
+	r.POST("/job/:serviceName", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	request := func(method string, path string, user string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		if strings.HasPrefix(path, "/job/") {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	request("POST", "/system/services", "alice", `{"name":"test"}`)
+	request("GET", "/system/services", "alice", "")
+	request("DELETE", "/system/services/other", "bob", "")
+	request("POST", "/job/test", "", "event")
+
+	// Wait for the entries to be written
+	var entries []*types.AuditEntry
+	for i := 0; i < 50; i++ {
+		if entries, err = auditor.List(types.AuditFilter{Limit: 10}); err == nil && len(entries) == 3 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expecting 3 entries, got %d (%v)", len(entries), err)
+	}
+
+	// Newest first
+	run, deleted, created := entries[0], entries[1], entries[2]
+	bodyHash := sha256.Sum256([]byte(`{"name":"test"}`))
+	if created.Subject != "alice" || created.Operation != types.AuditOperationCreate || created.Service != "test" ||
+		created.Result != types.AuditResultSuccess || created.RequestHash != hex.EncodeToString(bodyHash[:]) {
+		t.Errorf("unexpected entry of the creation: %+v", created)
+	}
+	if deleted.Subject != "bob" || deleted.Operation != types.AuditOperationDelete || deleted.Service != "other" ||
+		deleted.Status != http.StatusNotFound || deleted.Result != types.AuditResultFailure || deleted.ErrorCode != types.ErrServiceNotFound.Code {
+		t.Errorf("unexpected entry of the deletion: %+v", deleted)
+	}
+	if run.Subject != types.AuditServiceTokenSubject || run.Operation != types.AuditOperationRun || run.Service != "test" {
+		t.Errorf("unexpected entry of the invocation: %+v", run)
+	}
+
+	scenarios := []struct {
+		name         string
+		user         string
+		query        string
+		expectedCode int
+		expected     int
+	}{
+		{"admin", "oscar", "", http.StatusOK, 3},
+		{"filtered by subject", "oscar", "?subject=alice", http.StatusOK, 1},
+		{"filtered by result", "oscar", "?result=failure&operation=delete", http.StatusOK, 1},
+		{"limited", "oscar", "?limit=2", http.StatusOK, 2},
+		{"invalid operation", "oscar", "?operation=read", http.StatusBadRequest, 0},
+		{"invalid since", "oscar", "?since=yesterday", http.StatusBadRequest, 0},
+		{"not admin", "alice", "", http.StatusForbidden, 0},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := request("GET", "/system/audit"+s.query, s.user, "")
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode != http.StatusOK {
+				return
+			}
+			var res []types.AuditEntry
+			json.Unmarshal(w.Body.Bytes(), &res)
+			if len(res) != s.expected {
+				t.Errorf("expecting %d entries, got %d", s.expected, len(res))
+			}
+		})
+	}
+}
+
+func TestAuditDisabled(t *testing.T) {
+	r := gin.New()
+	r.GET("/system/audit", func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, "oscar")
+	}, MakeAuditHandler(&types.Config{Username: "oscar"}, nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/audit", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expecting code %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// MakeAuthzWebhookMiddleware makes a middleware authorizing the management operations and the invocations with the
+// external policy service of AUTHZ_WEBHOOK, which receives the subject, the action and the resource of each request
+// and allows or denies it. The requests are denied if the webhook fails (unless AUTHZ_WEBHOOK_FAIL_OPEN is set)
+func MakeAuthzWebhookMiddleware(cfg *types.Config) gin.HandlerFunc {
+	client := &http.Client{Timeout: cfg.AuthzWebhookTimeout}
+
+	return func(c *gin.Context) {
+		if cfg.AuthzWebhook == "" || !isOperation(c) {
+			return
+		}
+
+		req, err := getAuthzRequest(c)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Error reading the request body: %v", err))
+			return
+		}
+
+		allowed, reason, err := requestAuthz(client, cfg.AuthzWebhook, req)
+		if err != nil {
+			if cfg.AuthzWebhookFailOpen {
+				log.Printf("Error authorizing %s %s with the webhook (allowed): %v\n", req.Resource.Method, req.Resource.Path, err)
+				return
+			}
+			sendError(c, types.ErrAuthzFailed, err.Error())
+			return
+		}
+		if !allowed {
+			sendError(c, types.ErrAuthzDenied, reason)
+		}
+	}
+}
+
+// getAuthzRequest returns the request to authorize, with the definition of the service for the creations, updates
+// and patches of the services
+func getAuthzRequest(c *gin.Context) (*types.AuthzRequest, error) {
+	route := c.FullPath()
+	req := &types.AuthzRequest{
+		Subject: getRequestSubject(c),
+		Groups:  c.GetStringSlice(types.UserGroupsKey),
+		Role:    c.GetString(types.RoleKey),
+		Action:  getAuditOperation(c.Request.Method, route),
+		Resource: types.AuthzResource{
+			Service: c.Param("serviceName"),
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+		},
+	}
+	if req.Resource.Service == "" {
+		req.Resource.Service = c.Param("alias")
+	}
+
+	if c.Request.Body != nil && (route == "/system/services" || (route == "/system/services/:serviceName" && c.Request.Method == http.MethodPatch)) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if json.Valid(body) {
+			req.Resource.Definition = body
+			if req.Resource.Service == "" {
+				service := struct {
+					Name string `json:"name"`
+				}{}
+				json.Unmarshal(body, &service)
+				req.Resource.Service = service.Name
+			}
+		}
+	}
+	return req, nil
+}
+
+// requestAuthz asks the webhook for the decision on a request (sent as the "input" of the decision)
+func requestAuthz(client *http.Client, webhook string, req *types.AuthzRequest) (bool, string, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": req})
+	if err != nil {
+		return false, "", err
+	}
+	res, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, "", fmt.Errorf("error requesting the authorization webhook: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("the authorization webhook returned status code %d", res.StatusCode)
+	}
+
+	decision := &types.AuthzDecision{}
+	if err := json.NewDecoder(res.Body).Decode(decision); err != nil {
+		return false, "", fmt.Errorf("error decoding the response of the authorization webhook: %v", err)
+	}
+	return decision.GetDecision()
+}
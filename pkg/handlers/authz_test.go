@@ -0,0 +1,106 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestAuthzWebhookMiddleware(t *testing.T) {
+	var input types.AuthzRequest
+	response := `{"result": {"allow": false, "reason": "not allowed"}}`
+	status := http.StatusOK
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Input types.AuthzRequest `json:"input"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		input = body.Input
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	defer webhook.Close()
+
+	cfg := &types.Config{AuthzWebhook: webhook.URL}
+	var received string
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(gin.AuthUserKey, "user") }, MakeAuthzWebhookMiddleware(cfg))
+	r.POST("/system/services", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		received = string(body)
+		c.Status(http.StatusCreated)
+	})
+	r.DELETE("/system/services/:serviceName", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/system/services", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Denied creation, with the definition of the service
+	w := send(http.MethodPost, "/system/services", `{"name": "test"}`)
+	if w.Code != http.StatusForbidden || w.Header().Get(errorCodeHeader) != types.ErrAuthzDenied.Code {
+		t.Errorf("expecting the request to be denied, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "not allowed") {
+		t.Errorf("expecting the reason of the decision, got %s", w.Body.String())
+	}
+	if input.Subject != "user" || input.Action != types.AuditOperationCreate || input.Resource.Service != "test" || string(input.Resource.Definition) != `{"name":"test"}` {
+		t.Errorf("unexpected input %+v", input)
+	}
+
+	// Allowed creation and deletion, with the body of the request preserved
+	response = `{"allow": true}`
+	if w := send(http.MethodPost, "/system/services", `{"name": "test"}`); w.Code != http.StatusCreated || received != `{"name": "test"}` {
+		t.Errorf("expecting the request to be allowed with its body, got %d (%s)", w.Code, received)
+	}
+	response = `{"result": true}`
+	if w := send(http.MethodDelete, "/system/services/test", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expecting the request to be allowed, got %d", w.Code)
+	}
+	if input.Action != types.AuditOperationDelete || input.Resource.Service != "test" {
+		t.Errorf("unexpected input %+v", input)
+	}
+
+	// The requests not modifying the cluster are not authorized
+	response = `{"allow": false}`
+	input = types.AuthzRequest{}
+	if w := send(http.MethodGet, "/system/services", ""); w.Code != http.StatusOK || input.Action != "" {
+		t.Errorf("expecting the request not to be authorized, got %d", w.Code)
+	}
+
+	// Failing webhook (closed and open)
+	status = http.StatusInternalServerError
+	if w := send(http.MethodDelete, "/system/services/test", ""); w.Code != http.StatusServiceUnavailable || w.Header().Get(errorCodeHeader) != types.ErrAuthzFailed.Code {
+		t.Errorf("expecting the request to fail, got %d", w.Code)
+	}
+	cfg.AuthzWebhookFailOpen = true
+	if w := send(http.MethodDelete, "/system/services/test", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expecting the request to be allowed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// Custom logger
+var batchLogger = log.New(os.Stdout, "[BATCH] ", log.Flags())
+
+// eventBatch buffered events of a service
+type eventBatch struct {
+	service *types.Service
+	events  []string
+	timer   *time.Timer
+}
+
+// eventBatcher aggregates the events of services with batch processing enabled,
+// delivering them once the batch size is reached or the time window expires
+type eventBatcher struct {
+	batches map[string]*eventBatch
+	deliver func(service *types.Service, event string)
+	mutex   sync.Mutex
+}
+
+// newEventBatcher returns a new eventBatcher calling deliver for every completed batch
+func newEventBatcher(deliver func(service *types.Service, event string)) *eventBatcher {
+	return &eventBatcher{
+		batches: map[string]*eventBatch{},
+		deliver: deliver,
+	}
+}
+
+// add appends an event to the service's batch
+func (eb *eventBatcher) add(service *types.Service, event string) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	batch, ok := eb.batches[service.Name]
+	if !ok {
+		batch = &eventBatch{}
+		eb.batches[service.Name] = batch
+		// Start the time window with the first event of the batch
+		if service.Batch.Window > 0 {
+			name := service.Name
+			batch.timer = time.AfterFunc(time.Duration(service.Batch.Window)*time.Second, func() {
+				eb.flush(name, batch)
+			})
+		}
+	}
+
+	// Always keep the latest service definition
+	batch.service = service
+	batch.events = append(batch.events, event)
+
+	if service.Batch.Size > 0 && len(batch.events) >= service.Batch.Size {
+		if batch.timer != nil {
+			batch.timer.Stop()
+		}
+		delete(eb.batches, service.Name)
+		go eb.deliver(batch.service, joinEvents(batch.events))
+	}
+}
+
+// flush delivers the pending events of a service's batch (if it has not been delivered yet)
+func (eb *eventBatcher) flush(name string, batch *eventBatch) {
+	eb.mutex.Lock()
+	current, ok := eb.batches[name]
+	if !ok || current != batch {
+		eb.mutex.Unlock()
+		return
+	}
+	delete(eb.batches, name)
+	eb.mutex.Unlock()
+
+	if len(batch.events) > 0 {
+		eb.deliver(batch.service, joinEvents(batch.events))
+	}
+}
+
+// joinEvents returns a JSON array with the provided events.
+// Events that are not valid JSON are included as strings
+func joinEvents(events []string) string {
+	values := make([]interface{}, len(events))
+	for i, e := range events {
+		if json.Valid([]byte(e)) {
+			values[i] = json.RawMessage(e)
+		} else {
+			values[i] = e
+		}
+	}
+
+	bytes, _ := json.Marshal(values)
+	return string(bytes)
+}
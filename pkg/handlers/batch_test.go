@@ -0,0 +1,60 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestEventBatcher(t *testing.T) {
+	delivered := make(chan string, 2)
+	batcher := newEventBatcher(func(service *types.Service, event string) {
+		delivered <- event
+	})
+
+	svc := &types.Service{Name: "test"}
+	svc.Batch.Size = 2
+	svc.Batch.Window = 1
+
+	// Batch completed by size
+	batcher.add(svc, `{"a":1}`)
+	batcher.add(svc, "plain")
+	select {
+	case event := <-delivered:
+		expected := `[{"a":1},"plain"]`
+		if event != expected {
+			t.Errorf("expected event %s, got %s", expected, event)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("the batch was not delivered after reaching its size")
+	}
+
+	// Batch completed by time window
+	batcher.add(svc, `{"b":2}`)
+	select {
+	case event := <-delivered:
+		expected := `[{"b":2}]`
+		if event != expected {
+			t.Errorf("expected event %s, got %s", expected, event)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the batch was not delivered after the time window")
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeBuildCreateHandler makes a handler for building an image from a Dockerfile (or Git repository) and pushing it
+// to the cluster's registry. If the request includes a service definition, it is checked and created with the built
+// image when the build succeeds
+func MakeBuildCreateHandler(cfg *types.Config, kubeClientset kubernetes.Interface, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.BuildsRegistry == "" {
+			sendError(c, types.ErrBuildsDisabled, "")
+			return
+		}
+
+		var req types.BuildRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The build request is not valid: %v", err))
+			return
+		}
+		if err := req.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The build request is not valid: %v", err))
+			return
+		}
+
+		if req.Service != nil {
+			req.Service.Image = req.GetImage(cfg.BuildsRegistry, c.GetString(gin.AuthUserKey))
+			if err := binding.Validator.ValidateStruct(req.Service); err != nil {
+				sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: %v", err))
+				return
+			}
+			if err := prepareService(req.Service, cfg); err != nil {
+				sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+				return
+			}
+			if err := checkProtectedFlag(c, cfg, req.Service, nil); err != nil {
+				sendCodedError(c, err, types.ErrAdminRequired)
+				return
+			}
+			if len(req.Service.Assets) > 0 {
+				sendError(c, types.ErrInvalidServiceDefinition, "The service specification is not valid: the assets can only be uploaded through an upload session (upload=true)")
+				return
+			}
+			setServiceOwner(c, req.Service)
+			if err := checkServiceVO(c, cfg, req.Service); err != nil {
+				sendCodedError(c, err, types.ErrVOCheckFailed)
+				return
+			}
+			// Check that the service can be created before building the image
+			if err := dryRunService(back, req.Service, false); err != nil {
+				sendCodedError(c, err, types.ErrServiceCreateFailed)
+				return
+			}
+		}
+
+		build, err := utils.CreateBuild(cfg, kubeClientset, &req, c.GetString(gin.AuthUserKey))
+		if err != nil {
+			sendCodedError(c, err, types.ErrBuildFailed)
+			return
+		}
+
+		c.JSON(http.StatusCreated, build)
+	}
+}
+
+// MakeBuildListHandler makes a handler for listing the builds of the user (all of them for the admins)
+func MakeBuildListHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		builds, err := utils.ListBuilds(cfg, kubeClientset)
+		if err != nil {
+			sendCodedError(c, err, types.ErrBuildFailed)
+			return
+		}
+
+		accessible := []types.Build{}
+		for i := range builds {
+			if canAccessBuild(c, cfg, &builds[i]) {
+				accessible = append(accessible, builds[i])
+			}
+		}
+		c.JSON(http.StatusOK, accessible)
+	}
+}
+
+// MakeBuildReadHandler makes a handler for reading the status of a build
+func MakeBuildReadHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		build, ok := readAccessibleBuild(c, cfg, kubeClientset)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, build)
+	}
+}
+
+// MakeBuildLogsHandler makes a handler for getting the logs of a build
+func MakeBuildLogsHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := readAccessibleBuild(c, cfg, kubeClientset); !ok {
+			return
+		}
+
+		logs, err := utils.GetBuildLogs(cfg, kubeClientset, c.Param("buildID"))
+		if err != nil {
+			sendCodedError(c, err, types.ErrBuildFailed)
+			return
+		}
+
+		c.String(http.StatusOK, logs)
+	}
+}
+
+// MakeBuildDeleteHandler makes a handler for removing a build
+func MakeBuildDeleteHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := readAccessibleBuild(c, cfg, kubeClientset); !ok {
+			return
+		}
+
+		if err := utils.DeleteBuild(cfg, kubeClientset, c.Param("buildID")); err != nil {
+			sendCodedError(c, err, types.ErrBuildFailed)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// canAccessBuild checks if the user of the request can access a build: the user that requested it and the admins
+func canAccessBuild(c *gin.Context, cfg *types.Config, build *types.Build) bool {
+	return isAdmin(c, cfg) || (build.Owner != "" && build.Owner == c.GetString(gin.AuthUserKey))
+}
+
+// readAccessibleBuild reads the build of the request, sending the error if it does not exist or the user can not
+// access it (reported as not found, so the builds of other users are not disclosed)
+func readAccessibleBuild(c *gin.Context, cfg *types.Config, kubeClientset kubernetes.Interface) (*types.Build, bool) {
+	build, err := utils.GetBuild(cfg, kubeClientset, c.Param("buildID"))
+	if err != nil {
+		sendCodedError(c, err, types.ErrBuildFailed)
+		return nil, false
+	}
+	if !canAccessBuild(c, cfg, build) {
+		sendError(c, types.ErrBuildNotFound, fmt.Sprintf("The build \"%s\" does not exist", build.ID))
+		return nil, false
+	}
+	return build, true
+}
+
+// MakeBuildServiceDeployer makes a function to create the services of the succeeded builds
+func MakeBuildServiceDeployer(cfg *types.Config, back types.ServerlessBackend) func(service *types.Service) error {
+	return func(service *types.Service) error {
+		// Get the script (from its Git repository if defined)
+		if err := setServiceScript(service, cfg, back); err != nil {
+			return err
+		}
+		return deployService(cfg, back, service)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeBuildHandlers(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.BuildsRegistry = "registry.example.com/oscar"
+	cfg.BuildsImage = "kaniko"
+	cfg.Username = "admin"
+	back := backends.MakeMemoryBackend()
+	if err := back.CreateService(types.Service{Name: "existing"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeClientset := testclient.NewSimpleClientset()
+
+	disabledCfg := testConfigValidRun
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.POST("/disabled/builds", MakeBuildCreateHandler(&disabledCfg, kubeClientset, back))
+	r.POST("/system/builds", MakeBuildCreateHandler(&cfg, kubeClientset, back))
+	r.GET("/system/builds", MakeBuildListHandler(&cfg, kubeClientset))
+	r.GET("/system/builds/:buildID", MakeBuildReadHandler(&cfg, kubeClientset))
+	r.GET("/system/builds/:buildID/logs", MakeBuildLogsHandler(&cfg, kubeClientset))
+	r.DELETE("/system/builds/:buildID", MakeBuildDeleteHandler(&cfg, kubeClientset))
+
+	var build types.Build
+
+	steps := []struct {
+		name              string
+		method            string
+		path              string
+		body              string
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"builds disabled", "POST", "/disabled/builds", `{"name": "test", "dockerfile": "FROM busybox"}`, http.StatusNotImplemented, types.ErrBuildsDisabled.Code},
+		{"without dockerfile", "POST", "/system/builds", `{"name": "test"}`, http.StatusBadRequest, types.ErrBadRequest.Code},
+		{"invalid service", "POST", "/system/builds", `{"name": "test", "dockerfile": "FROM busybox", "service": {"name": "test", "script": "ls", "delegation_policy": "random"}}`, http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code},
+		{"existing service", "POST", "/system/builds", `{"name": "test", "dockerfile": "FROM busybox", "service": {"name": "existing", "script": "ls"}}`, http.StatusConflict, types.ErrServiceAlreadyExists.Code},
+		{"create", "POST", "/system/builds", `{"name": "test", "tag": "v1", "dockerfile": "FROM busybox", "service": {"name": "test", "script": "ls"}}`, http.StatusCreated, ""},
+		{"list", "GET", "/system/builds", "", http.StatusOK, ""},
+		{"list other user", "GET", "/system/builds", "", http.StatusOK, ""},
+		{"read other user", "GET", "/system/builds/{id}", "", http.StatusNotFound, types.ErrBuildNotFound.Code},
+		{"logs other user", "GET", "/system/builds/{id}/logs", "", http.StatusNotFound, types.ErrBuildNotFound.Code},
+		{"delete other user", "DELETE", "/system/builds/{id}", "", http.StatusNotFound, types.ErrBuildNotFound.Code},
+		{"read admin", "GET", "/system/builds/{id}", "", http.StatusOK, ""},
+		{"read", "GET", "/system/builds/{id}", "", http.StatusOK, ""},
+		{"read missing", "GET", "/system/builds/missing", "", http.StatusNotFound, types.ErrBuildNotFound.Code},
+		{"logs", "GET", "/system/builds/{id}/logs", "", http.StatusOK, ""},
+		{"delete", "DELETE", "/system/builds/{id}", "", http.StatusNoContent, ""},
+		{"read deleted", "GET", "/system/builds/{id}", "", http.StatusNotFound, types.ErrBuildNotFound.Code},
+	}
+
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, strings.Replace(s.path, "{id}", build.ID, 1), strings.NewReader(s.body))
+			switch {
+			case strings.HasSuffix(s.name, "other user"):
+				req.Header.Set("X-User", "other")
+			case strings.HasSuffix(s.name, "admin"):
+				req.Header.Set("X-User", "admin")
+			default:
+				req.Header.Set("X-User", "user")
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+
+			switch s.name {
+			case "create":
+				if err := json.Unmarshal(w.Body.Bytes(), &build); err != nil {
+					t.Fatalf("error decoding the build: %v", err)
+				}
+				if build.Image != "registry.example.com/oscar/user/test:v1" || build.Owner != "user" || build.Service != "test" || build.ServiceStatus != types.BuildPending {
+					t.Errorf("unexpected build %+v", build)
+				}
+				secret, err := kubeClientset.CoreV1().Secrets(cfg.ServicesNamespace).Get(context.TODO(), "oscar-build-"+build.ID, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("the build secret has not been created: %v", err)
+				}
+				var service types.Service
+				if err := json.Unmarshal(secret.Data[types.BuildServiceKey], &service); err != nil || service.Image != build.Image {
+					t.Errorf("expecting the service with the built image, got %+v (%v)", service, err)
+				}
+				if len(secret.Data[types.BuildContextKey]) == 0 {
+					t.Error("expecting the build context in the secret")
+				}
+			case "list":
+				var builds []types.Build
+				if err := json.Unmarshal(w.Body.Bytes(), &builds); err != nil || len(builds) != 1 || builds[0].ID != build.ID {
+					t.Errorf("expecting the created build, got %s", w.Body.String())
+				}
+			case "list other user":
+				if w.Body.String() != "[]" {
+					t.Errorf("expecting no builds for another user, got %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// fdlFunctions "functions" block of an FDL file, with the services to be deployed in each OSCAR cluster
+type fdlFunctions struct {
+	Functions struct {
+		OSCAR []map[string]json.RawMessage `json:"oscar"`
+	} `json:"functions"`
+}
+
+// serviceBucket bucket used by a service in a MinIO or S3 storage provider
+type serviceBucket struct {
+	client *s3.S3
+	name   string
+}
+
+// id returns the identifier of the bucket across storage providers
+func (bucket serviceBucket) id() string {
+	return bucket.client.ClientInfo.Endpoint + "/" + bucket.name
+}
+
+// MakeBulkCreateHandler makes a handler for creating several services at once. The services are created
+// transactionally: if any of them fails, the already created services (and their new buckets) are removed
+func MakeBulkCreateHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Error reading the request body: %v", err))
+			return
+		}
+		services, err := readBulkServices(body)
+		if err != nil {
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: %v", err))
+			return
+		}
+
+		createServices(c, cfg, back, services)
+	}
+}
+
+// createServices validates and creates the services, removing the already created services (and their new buckets)
+// if any of them fails
+func createServices(c *gin.Context, cfg *types.Config, back types.ServerlessBackend, services []*types.Service) {
+	// Validate all the services before creating any of them
+	names := map[string]bool{}
+	for _, service := range services {
+		if names[service.Name] {
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: the service \"%s\" is duplicated", service.Name))
+			return
+		}
+		names[service.Name] = true
+
+		if err := prepareBulkService(c, cfg, back, service); err != nil {
+			sendCodedError(c, fmt.Errorf("Service \"%s\": %w", service.Name, err), types.ErrInvalidServiceDefinition)
+			return
+		}
+	}
+
+	// Only the buckets created by the request are removed on rollback
+	buckets := getNewBuckets(cfg, services)
+
+	for i, service := range services {
+		if err := deployService(cfg, back, service); err != nil {
+			rollbackServices(cfg, back, services[:i+1], buckets)
+			sendCodedError(c, fmt.Errorf("Service \"%s\": %w", service.Name, err), types.ErrServiceCreateFailed)
+			return
+		}
+	}
+
+	c.Status(http.StatusCreated)
+}
+
+// readBulkServices decodes a list of services or the "functions" block of an FDL file
+func readBulkServices(body []byte) ([]*types.Service, error) {
+	raws := []json.RawMessage{}
+	if strings.HasPrefix(string(bytes.TrimSpace(body)), "[") {
+		if err := json.Unmarshal(body, &raws); err != nil {
+			return nil, err
+		}
+	} else {
+		var fdl fdlFunctions
+		if err := json.Unmarshal(body, &fdl); err != nil {
+			return nil, err
+		}
+		for _, clusters := range fdl.Functions.OSCAR {
+			for _, raw := range clusters {
+				raws = append(raws, raw)
+			}
+		}
+	}
+	if len(raws) == 0 {
+		return nil, errors.New("no services provided")
+	}
+
+	services := []*types.Service{}
+	for i, raw := range raws {
+		service := &types.Service{}
+		if err := json.Unmarshal(raw, service); err != nil {
+			return nil, fmt.Errorf("service %d: %v", i, err)
+		}
+		if err := binding.Validator.ValidateStruct(service); err != nil {
+			return nil, fmt.Errorf("service %d: %v", i, err)
+		}
+		services = append(services, service)
+	}
+
+	return services, nil
+}
+
+// prepareBulkService sets the default values and the script of a service and checks its definition and VO
+func prepareBulkService(c *gin.Context, cfg *types.Config, back types.ServerlessBackend, service *types.Service) error {
+	if err := prepareService(service, cfg); err != nil {
+		return err
+	}
+	if err := checkProtectedFlag(c, cfg, service, nil); err != nil {
+		return err
+	}
+	if err := checkServiceRequirements(service, back); err != nil {
+		return err
+	}
+	if len(service.Assets) > 0 {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, errors.New("the assets can only be uploaded through an upload session"))
+	}
+	if err := setServiceScript(service, cfg, back); err != nil {
+		return err
+	}
+	setServiceOwner(c, service)
+	return checkServiceVO(c, cfg, service)
+}
+
+// getNewBuckets returns the MinIO and S3 buckets of the services that don't exist yet
+func getNewBuckets(cfg *types.Config, services []*types.Service) []serviceBucket {
+	buckets := []serviceBucket{}
+	checked := map[string]bool{}
+
+	for _, service := range services {
+		for _, bucket := range getServiceBuckets(cfg, service) {
+			if checked[bucket.id()] {
+				continue
+			}
+			checked[bucket.id()] = true
+
+			_, err := bucket.client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket.name)})
+			// Any other error is considered as an existing bucket to avoid deleting it
+			if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusNotFound {
+				buckets = append(buckets, bucket)
+			}
+		}
+	}
+
+	return buckets
+}
+
+// getServiceBuckets returns the MinIO and S3 buckets used by a service
+func getServiceBuckets(cfg *types.Config, service *types.Service) []serviceBucket {
+	buckets := []serviceBucket{}
+	addBucket := func(client *s3.S3, path string) {
+		if bucket, _ := (types.StorageIOConfig{Path: path}).SplitPath(); bucket != "" {
+			buckets = append(buckets, serviceBucket{client: client, name: bucket})
+		}
+	}
+
+	providers := service.StorageProviders
+	if providers == nil {
+		providers = &types.StorageProviders{}
+	}
+	for _, in := range service.Input {
+		if provName, provID := in.GetProvider(); provName == types.MinIOName && providers.MinIO[provID] != nil {
+			addBucket(providers.MinIO[provID].GetS3Client(), in.Path)
+		}
+	}
+	for _, out := range service.Output {
+		provName, provID := out.GetProvider()
+		if provName == types.MinIOName && providers.MinIO[provID] != nil {
+			addBucket(providers.MinIO[provID].GetS3Client(), out.Path)
+		} else if provName == types.S3Name && providers.S3[provID] != nil {
+			addBucket(providers.S3[provID].GetS3Client(), out.Path)
+		}
+	}
+	if service.DeadLetterPath != "" && cfg.MinIOProvider != nil {
+		addBucket(cfg.MinIOProvider.GetS3Client(), service.DeadLetterPath)
+	}
+
+	return buckets
+}
+
+// recordCreatedBuckets records the buckets created by a service, the only ones removed with it (delete_buckets=true)
+func recordCreatedBuckets(cfg *types.Config, back types.ServerlessBackend, serviceName string, buckets []serviceBucket) {
+	if len(buckets) == 0 {
+		return
+	}
+	ids := []string{}
+	for _, bucket := range buckets {
+		ids = append(ids, bucket.id())
+	}
+	if err := utils.AddCreatedBuckets(back.GetKubeClientset(), cfg.ServicesNamespace, serviceName, ids); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// rollbackServices deletes the provided services and buckets
+func rollbackServices(cfg *types.Config, back types.ServerlessBackend, services []*types.Service, buckets []serviceBucket) {
+	for _, service := range services {
+		// The service may have already been deleted if its deployment failed
+		if err := back.DeleteService(service.Name); err != nil {
+			log.Printf("Error deleting service \"%s\" on rollback: %v\n", service.Name, err)
+		}
+		cleanupService(cfg, back, service, true)
+		purgeService(cfg, back, service)
+	}
+
+	for _, bucket := range buckets {
+		if err := deleteBucket(bucket); err != nil {
+			log.Printf("Error deleting bucket \"%s\" on rollback: %v\n", bucket.name, err)
+		}
+	}
+}
+
+// deleteBucket deletes a bucket and its objects
+func deleteBucket(bucket serviceBucket) error {
+	keys := []string{}
+	err := bucket.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket.name),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+			return nil
+		}
+		return err
+	}
+
+	for _, key := range keys {
+		if _, err := bucket.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(bucket.name), Key: aws.String(key)}); err != nil {
+			return err
+		}
+	}
+
+	_, err = bucket.client.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String(bucket.name)})
+	return err
+}
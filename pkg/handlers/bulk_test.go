@@ -0,0 +1,163 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestReadBulkServices(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		body          string
+		expectedNames []string
+		returnError   bool
+	}{
+		{"list", `[{"name": "a", "image": "test"}, {"name": "b", "image": "test"}]`, []string{"a", "b"}, false},
+		{"FDL", `{"functions": {"oscar": [{"cluster-1": {"name": "a", "image": "test"}}, {"cluster-2": {"name": "b", "image": "test"}}]}}`, []string{"a", "b"}, false},
+		{"empty", `[]`, nil, true},
+		{"missing image", `[{"name": "a"}]`, nil, true},
+		{"not JSON", `functions:`, nil, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			services, err := readBulkServices([]byte(s.body))
+			if s.returnError {
+				if err == nil {
+					t.Error("expecting error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(services) != len(s.expectedNames) {
+				t.Fatalf("expecting %d services, got %d", len(s.expectedNames), len(services))
+			}
+			for i, service := range services {
+				if service.Name != s.expectedNames[i] {
+					t.Errorf("expecting service %s, got %s", s.expectedNames[i], service.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeBulkCreateHandler(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+
+	scenarios := []struct {
+		name         string
+		body         string
+		backendErr   error
+		expectedCode int
+		expectedErr  types.ErrorCode
+	}{
+		{"duplicated service", `[{"name": "a", "image": "test", "script": "echo"}, {"name": "a", "image": "test", "script": "echo"}]`, nil, http.StatusBadRequest, types.ErrInvalidServiceDefinition},
+		{"invalid service", `[{"name": "a", "image": "test", "script": "echo"}, {"name": "b", "image": "test", "script": "echo", "delegation_policy": "random"}]`, nil, http.StatusBadRequest, types.ErrInvalidServiceDefinition},
+		{"missing script", `[{"name": "a", "image": "test"}]`, nil, http.StatusBadRequest, types.ErrInvalidServiceDefinition},
+		{"create error", `[{"name": "a", "image": "test", "script": "echo"}, {"name": "b", "image": "test", "script": "echo"}]`, errors.New("create error"), http.StatusInternalServerError, types.ErrServiceCreateFailed},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			back := backends.MakeMemoryBackend()
+			if s.backendErr != nil {
+				back.AddError("CreateService", s.backendErr)
+			}
+
+			r := gin.Default()
+			r.POST("/system/services/batch", MakeBulkCreateHandler(&cfg, back))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/services/batch", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Header().Get(errorCodeHeader) != s.expectedErr.Code {
+				t.Errorf("expecting error code %s, got %s", s.expectedErr.Code, w.Header().Get(errorCodeHeader))
+			}
+
+			// No service must be left after a failure
+			services, _ := back.ListServices()
+			if len(services) != 0 {
+				t.Errorf("expecting no services, got %d", len(services))
+			}
+		})
+	}
+}
+
+func TestRollbackServices(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("existing")
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+
+	back := backends.MakeMemoryBackend()
+	services := []*types.Service{}
+	for _, bucket := range []string{"existing", "new"} {
+		service := &types.Service{
+			Name:  "rollback-" + bucket,
+			Image: "test",
+			Input: []types.StorageIOConfig{{Provider: types.MinIOName, Path: bucket + "/in"}},
+			StorageProviders: &types.StorageProviders{
+				MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: cfg.MinIOProvider},
+			},
+		}
+		services = append(services, service)
+	}
+
+	buckets := getNewBuckets(&cfg, services)
+	if len(buckets) != 1 || buckets[0].name != "new" {
+		t.Fatalf("expecting only the bucket \"new\" to be created, got %v", buckets)
+	}
+
+	// Simulate the deployment of the services
+	for _, service := range services {
+		back.CreateService(*service)
+	}
+	s3Server.CreateBucket("new")
+	s3Server.PutObject("new", "in/", chaos.S3Object{})
+
+	rollbackServices(&cfg, back, services, buckets)
+
+	if list, _ := back.ListServices(); len(list) != 0 {
+		t.Errorf("expecting no services, got %d", len(list))
+	}
+	if s3Server.HasBucket("new") {
+		t.Error("expecting the bucket \"new\" to be deleted")
+	}
+	if !s3Server.HasBucket("existing") {
+		t.Error("expecting the bucket \"existing\" to be kept")
+	}
+}
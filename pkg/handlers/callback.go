@@ -0,0 +1,90 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeCallbackListHandler makes a handler for listing the deliveries of the service's callback
+func MakeCallbackListHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readCallbackService(c, back)
+		if service == nil {
+			return
+		}
+
+		c.JSON(http.StatusOK, utils.ListCallbackDeliveries(service.Name))
+	}
+}
+
+// MakeCallbackRedeliverHandler makes a handler for sending again (a single attempt) a delivery of the service's callback
+func MakeCallbackRedeliverHandler(cfg *types.Config, kubeClientset kubernetes.Interface, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readCallbackService(c, back)
+		if service == nil {
+			return
+		}
+
+		id := c.Param("deliveryID")
+		delivery, ok := utils.GetCallbackDelivery(service.Name, id)
+		if !ok {
+			sendError(c, types.ErrJobNotFound, fmt.Sprintf("The callback delivery \"%s\" does not exist", id))
+			return
+		}
+		if delivery.Status == types.CallbackPending {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The callback delivery \"%s\" is still being retried", id))
+			return
+		}
+
+		delivery, err := utils.DeliverCallback(cfg, kubeClientset, service, id, 1)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// readCallbackService returns the service of the request if it has a callback defined (sending the error response otherwise)
+func readCallbackService(c *gin.Context, back types.ServerlessBackend) *types.Service {
+	service, err := back.ReadService(c.Param("serviceName"))
+	if err != nil {
+		// Check if error is caused because the service is not found
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			sendError(c, types.ErrServiceNotFound, "")
+		} else {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+		}
+		return nil
+	}
+
+	if service.Callback == nil {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("The service \"%s\" has not a callback defined", service.Name))
+		return nil
+	}
+
+	return service
+}
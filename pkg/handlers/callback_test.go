@@ -0,0 +1,70 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeCallbackHandlers(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "callback", Callback: &types.Callback{URL: "http://example.com/callback", OnSuccess: true}})
+	back.CreateService(types.Service{Name: "no-callback"})
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/callbacks", MakeCallbackListHandler(back))
+	r.POST("/system/services/:serviceName/callbacks/:deliveryID/redeliver", MakeCallbackRedeliverHandler(&testConfigValidRun, testclient.NewSimpleClientset(), back))
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedErr  types.ErrorCode
+	}{
+		{"list", "GET", "/system/services/callback/callbacks", http.StatusOK, types.ErrorCode{}},
+		{"list without callback", "GET", "/system/services/no-callback/callbacks", http.StatusBadRequest, types.ErrBadRequest},
+		{"list missing service", "GET", "/system/services/missing/callbacks", http.StatusNotFound, types.ErrServiceNotFound},
+		{"redeliver missing delivery", "POST", "/system/services/callback/callbacks/job/redeliver", http.StatusNotFound, types.ErrJobNotFound},
+		{"redeliver without callback", "POST", "/system/services/no-callback/callbacks/job/redeliver", http.StatusBadRequest, types.ErrBadRequest},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Header().Get(errorCodeHeader) != s.expectedErr.Code {
+				t.Errorf("expecting error code %s, got %s", s.expectedErr.Code, w.Header().Get(errorCodeHeader))
+			}
+			if s.expectedCode == http.StatusOK && w.Body.String() != "[]" {
+				t.Errorf("expecting no deliveries, got %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/resourcemanager"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeCapabilitiesHandler makes a handler to list the hardware capabilities (GPU models, CPU flags, hugepages and
+// architectures) provided by the nodes of the cluster, which can be required by the services
+func MakeCapabilitiesHandler(kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		capabilities, err := resourcemanager.ListCapabilities(kubeClientset)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, capabilities)
+	}
+}
+
+// checkServiceRequirements checks that the capabilities required by the service are provided by a node of the cluster
+func checkServiceRequirements(service *types.Service, back types.ServerlessBackend) error {
+	if err := resourcemanager.CheckRequirements(back.GetKubeClientset(), service.Requires); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/resourcemanager"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeCapacityHandler makes a handler to retrieve the capacity of the cluster,
+// used by the peer clusters to apply the delegation policies of their services
+func MakeCapacityHandler(cfg *types.Config, kubeClientset kubernetes.Interface, rm resourcemanager.ResourceManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		capacity, err := resourcemanager.GetLocalCapacity(cfg, kubeClientset, rm)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, capacity)
+	}
+}
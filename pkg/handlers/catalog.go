@@ -0,0 +1,87 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// MakeCatalogHandler makes a handler to list the templates of the service catalog
+func MakeCatalogHandler(catalog *utils.Catalog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		templates, err := catalog.ListTemplates()
+		if err != nil {
+			sendCodedError(c, err, types.ErrCatalogUnavailable)
+			return
+		}
+		c.JSON(http.StatusOK, templates)
+	}
+}
+
+// MakeCatalogDeployHandler makes a handler to deploy the services of a catalog template, replacing its parameters by
+// the provided values. The services are created transactionally, as in the bulk creations. The "dry_run" querystring
+// returns the services of the template without creating them
+func MakeCatalogDeployHandler(cfg *types.Config, back types.ServerlessBackend, catalog *utils.Catalog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.CatalogDeployRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("The deploy request is not valid: %v", err))
+				return
+			}
+		}
+
+		fdl, err := catalog.RenderTemplate(c.Param("template"), req.Parameters)
+		if err != nil {
+			if errors.Is(err, utils.ErrTemplateNotFound) {
+				sendError(c, types.ErrTemplateNotFound, "")
+			} else {
+				sendCodedError(c, err, types.ErrCatalogUnavailable)
+			}
+			return
+		}
+		services, err := readBulkServices(fdl)
+		if err != nil {
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification of the template is not valid: %v", err))
+			return
+		}
+
+		// Return the services of the template (checked and with their default values) without creating them
+		if isDryRun(c) {
+			for _, service := range services {
+				if err := prepareBulkService(c, cfg, back, service); err != nil {
+					sendCodedError(c, fmt.Errorf("Service \"%s\": %w", service.Name, err), types.ErrInvalidServiceDefinition)
+					return
+				}
+				if err := dryRunService(back, service, false); err != nil {
+					sendCodedError(c, fmt.Errorf("Service \"%s\": %w", service.Name, err), types.ErrServiceCreateFailed)
+					return
+				}
+			}
+			c.JSON(http.StatusOK, services)
+			return
+		}
+
+		createServices(c, cfg, back, services)
+	}
+}
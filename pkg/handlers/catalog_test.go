@@ -0,0 +1,150 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// newTestCatalogRegistry starts a registry serving the files as the tar layer of the "catalog:latest" artifact
+func newTestCatalogRegistry(t *testing.T, files map[string]string) *httptest.Server {
+	var layer bytes.Buffer
+	tw := tar.NewWriter(&layer)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	sum := sha256.Sum256(layer.Bytes())
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/catalog/manifests/latest":
+			fmt.Fprintf(w, `{"layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar", "digest": "%s", "size": %d}]}`, digest, layer.Len())
+		case "/v2/catalog/blobs/" + digest:
+			w.Write(layer.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCatalogHandlers(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	registry := newTestCatalogRegistry(t, map[string]string{
+		"hello/hello.yaml": "description: Hello world\nparameters:\n  NAME:\n    default: hello\n  MESSAGE: {}\n" +
+			"functions:\n  oscar:\n  - cluster:\n      name: ${NAME}\n      image: busybox\n      script: script.sh\n      environment:\n        variables:\n          MESSAGE: ${MESSAGE}\n",
+		"hello/script.sh": "echo $MESSAGE",
+	})
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	cfg.CatalogURL = "oci://" + strings.TrimPrefix(registry.URL, "http://") + "/catalog"
+	cfg.CatalogInsecure = true
+	cfg.CatalogRefreshInterval = time.Minute
+
+	back := backends.MakeMemoryBackend()
+	catalog := utils.NewCatalog(&cfg)
+	r := gin.Default()
+	r.GET("/system/catalog", MakeCatalogHandler(catalog))
+	r.POST("/system/catalog/:template/deploy", MakeCatalogDeployHandler(&cfg, back, catalog))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/catalog", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var templates []types.CatalogTemplate
+	if err := json.Unmarshal(w.Body.Bytes(), &templates); err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates[0].Name != "hello" || len(templates[0].Parameters) != 2 {
+		t.Fatalf("unexpected templates: %+v", templates)
+	}
+
+	scenarios := []struct {
+		name         string
+		path         string
+		body         string
+		expectedCode int
+		expectedErr  types.ErrorCode
+	}{
+		{"template not found", "other/deploy", `{}`, http.StatusNotFound, types.ErrTemplateNotFound},
+		{"missing parameter", "hello/deploy", `{}`, http.StatusBadRequest, types.ErrBadRequest},
+		{"unknown parameter", "hello/deploy", `{"parameters": {"MESSAGE": "hi", "OTHER": "x"}}`, http.StatusBadRequest, types.ErrBadRequest},
+		{"create error", "hello/deploy", `{"parameters": {"MESSAGE": "hi"}}`, http.StatusInternalServerError, types.ErrWebhookRegisterFailed},
+		{"dry run", "hello/deploy?dry_run=true", `{"parameters": {"MESSAGE": "hi", "NAME": "hello-alice"}}`, http.StatusOK, types.ErrorCode{}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/catalog/"+s.path, strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Header().Get(errorCodeHeader) != s.expectedErr.Code {
+				t.Errorf("expecting error code %s, got %s", s.expectedErr.Code, w.Header().Get(errorCodeHeader))
+			}
+			if s.expectedCode == http.StatusOK {
+				var services []types.Service
+				if err := json.Unmarshal(w.Body.Bytes(), &services); err != nil {
+					t.Fatal(err)
+				}
+				if len(services) != 1 || services[0].Name != "hello-alice" || services[0].Script != "echo $MESSAGE" || services[0].Environment.Vars["MESSAGE"] != "hi" {
+					t.Errorf("unexpected services: %+v", services)
+				}
+			}
+		})
+	}
+
+	// The failed deployments are rolled back
+	if services, _ := back.ListServices(); len(services) != 0 {
+		t.Errorf("expecting no services, got %d", len(services))
+	}
+
+	// The catalog is not available if not configured
+	notConfigured := gin.Default()
+	notConfigured.GET("/system/catalog", MakeCatalogHandler(utils.NewCatalog(&testConfigValidRun)))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/system/catalog", nil)
+	notConfigured.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expecting code %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
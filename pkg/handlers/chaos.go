@@ -0,0 +1,60 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// MakeChaosListHandler makes a handler for listing the injected faults
+func MakeChaosListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, chaos.ListFaults())
+	}
+}
+
+// MakeChaosAddHandler makes a handler for injecting a fault in the storage, backend or OIDC operations
+func MakeChaosAddHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var fault chaos.Fault
+		if err := c.ShouldBindJSON(&fault); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The fault specification is not valid: %v", err))
+			return
+		}
+
+		fault, err := chaos.AddFault(fault)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The fault specification is not valid: %v", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, fault)
+	}
+}
+
+// MakeChaosClearHandler makes a handler for removing all the injected faults
+func MakeChaosClearHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chaos.ClearFaults()
+		c.Status(http.StatusNoContent)
+	}
+}
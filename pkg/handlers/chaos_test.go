@@ -0,0 +1,66 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+)
+
+func TestMakeChaosHandlers(t *testing.T) {
+	defer chaos.ClearFaults()
+
+	r := gin.Default()
+	r.GET("/system/chaos/faults", MakeChaosListHandler())
+	r.POST("/system/chaos/faults", MakeChaosAddHandler())
+	r.DELETE("/system/chaos/faults", MakeChaosClearHandler())
+
+	scenarios := []struct {
+		name         string
+		method       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"add fault", "POST", `{"target": "backend", "operation": "UpdateService", "error": "unavailable"}`, http.StatusCreated, `"id":"1"`},
+		{"invalid target", "POST", `{"target": "network", "error": "unavailable"}`, http.StatusBadRequest, ""},
+		{"missing target", "POST", `{"error": "unavailable"}`, http.StatusBadRequest, ""},
+		{"list faults", "GET", "", http.StatusOK, `"operation":"UpdateService"`},
+		{"clear faults", "DELETE", "", http.StatusNoContent, ""},
+		{"list without faults", "GET", "", http.StatusOK, "[]"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, "/system/chaos/faults", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), s.expectedBody) {
+				t.Errorf("expecting body to contain %s, got %s", s.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
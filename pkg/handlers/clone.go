@@ -0,0 +1,106 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// MakeCloneHandler makes a handler for cloning services. The new service gets the definition of the cloned one
+// (with the optional overrides applied) and its own token, webhook and notifications. The buckets are shared
+// with the cloned service unless "copy_buckets" is set, creating new (empty) buckets named after the new service.
+// The "dry_run" querystring is also supported
+func MakeCloneHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.CloneRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The clone request is not valid: %v", err))
+			return
+		}
+
+		// Read the cloned service
+		oldService, err := readUpdatedService(back, c.Param("serviceName"))
+		if err != nil {
+			sendCodedError(c, err, types.ErrServiceReadFailed)
+			return
+		}
+
+		// Copy the definition applying the overrides. The clones are not protected unless set in the overrides
+		overrides := req.Overrides
+		if len(overrides) == 0 {
+			overrides = []byte("{}")
+		}
+		source := *oldService
+		source.Protected = false
+		newService, err := patchService(&source, overrides)
+		if err != nil {
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: %v", err))
+			return
+		}
+		if err := checkProtectedFlag(c, cfg, newService, nil); err != nil {
+			sendCodedError(c, err, types.ErrAdminRequired)
+			return
+		}
+		newService.Name = req.Name
+		newService.Revision = 0
+		if req.CopyBuckets {
+			newService.RenameBuckets(oldService.Name)
+		}
+
+		// Check service values, set defaults (including a new token) and validate the definition
+		if err := prepareService(newService, cfg); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
+
+		// Get the script from its Git repository only if overridden, the deploy key secrets are
+		// scoped to the cloned service
+		if !reflect.DeepEqual(newService.ScriptGit, oldService.ScriptGit) {
+			if err := setServiceScript(newService, cfg, back); err != nil {
+				sendCodedError(c, err, types.ErrScriptFetchFailed)
+				return
+			}
+		}
+
+		setServiceOwner(c, newService)
+		if err := checkServiceVO(c, cfg, newService); err != nil {
+			sendCodedError(c, err, types.ErrVOCheckFailed)
+			return
+		}
+
+		if isDryRun(c) {
+			if err := dryRunService(back, newService, false); err != nil {
+				sendCodedError(c, err, types.ErrServiceCreateFailed)
+				return
+			}
+			c.JSON(http.StatusOK, newService)
+			return
+		}
+
+		if err := deployService(cfg, back, newService); err != nil {
+			sendCodedError(c, err, types.ErrServiceCreateFailed)
+			return
+		}
+
+		c.JSON(http.StatusCreated, newService)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeCloneHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{
+		Name:   "original",
+		Image:  "busybox",
+		Script: "ls",
+		Memory: "1Gi",
+		Token:  "token",
+		Input:  []types.StorageIOConfig{{Provider: "minio", Path: "original/in"}},
+		Output: []types.StorageIOConfig{{Provider: "minio", Path: "original/out"}},
+	})
+
+	r := gin.Default()
+	r.POST("/system/services/:serviceName/clone", MakeCloneHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name              string
+		path              string
+		body              string
+		expectedCode      int
+		expectedErrorCode string
+		expectedMemory    string
+		expectedInput     string
+	}{
+		{"clone", "/system/services/original/clone?dry_run=true", `{"name": "variant"}`, http.StatusOK, "", "1Gi", "original/in"},
+		{"clone with overrides", "/system/services/original/clone?dry_run=true", `{"name": "variant", "overrides": {"memory": "2Gi"}}`, http.StatusOK, "", "2Gi", "original/in"},
+		{"clone copying buckets", "/system/services/original/clone?dry_run=true", `{"name": "variant", "copy_buckets": true}`, http.StatusOK, "", "1Gi", "variant/in"},
+		{"clone existing", "/system/services/original/clone?dry_run=true", `{"name": "original"}`, http.StatusConflict, types.ErrServiceAlreadyExists.Code, "", ""},
+		{"clone missing service", "/system/services/missing/clone", `{"name": "variant"}`, http.StatusNotFound, types.ErrServiceNotFound.Code, "", ""},
+		{"clone without name", "/system/services/original/clone", `{}`, http.StatusBadRequest, types.ErrBadRequest.Code, "", ""},
+		{"clone with invalid overrides", "/system/services/original/clone", `{"name": "variant", "overrides": {"input": "in"}}`, http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code, "", ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", s.path, strings.NewReader(s.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+
+			if s.expectedCode == http.StatusOK {
+				var service types.Service
+				if err := json.Unmarshal(w.Body.Bytes(), &service); err != nil {
+					t.Fatalf("error decoding the service: %v", err)
+				}
+				if service.Name != "variant" || service.Labels[types.ServiceLabel] != "variant" {
+					t.Errorf("the service has not been renamed: %+v", service)
+				}
+				if service.Token == "" || service.Token == "token" {
+					t.Errorf("expecting a new token, got \"%s\"", service.Token)
+				}
+				if service.Memory != s.expectedMemory {
+					t.Errorf("expecting memory %s, got %s", s.expectedMemory, service.Memory)
+				}
+				if service.Input[0].Path != s.expectedInput {
+					t.Errorf("expecting input path %s, got %s", s.expectedInput, service.Input[0].Path)
+				}
+			}
+
+			// The original service is not modified
+			if original, _ := back.ReadService("original"); original.Memory != "1Gi" || original.Token != "token" {
+				t.Errorf("the original service has been modified: %+v", original)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"log"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -33,6 +34,7 @@ import (
 	"github.com/grycap/oscar/v2/pkg/utils"
 	"github.com/grycap/oscar/v2/pkg/utils/auth"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const (
@@ -41,79 +43,371 @@ const (
 	defaultLogLevel = "INFO"
 )
 
-var errInput = errors.New("unrecognized input (valid inputs are MinIO and dCache)")
+var errInput = types.NewCodedError(types.ErrInvalidInputProvider, errors.New("unrecognized input (valid inputs are MinIO and dCache)"))
 
-// MakeCreateHandler makes a handler for creating services
+// MakeCreateHandler makes a handler for creating services.
+// With the "upload" querystring set to true the service is not created, an upload session is returned
+// instead to upload its script and assets before activating it (see MakeUploadActivateHandler).
+// With the "dry_run" querystring set to true the service is validated (also by the backend) but not created,
+// returning its fully-defaulted definition
 func MakeCreateHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var service types.Service
 
 		if err := c.ShouldBindJSON(&service); err != nil {
-			c.String(http.StatusBadRequest, fmt.Sprintf("The service specification is not valid: %v", err))
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: %v", err))
 			return
 		}
+		c.Set(auditServiceKey, service.Name)
 
-		// Check service values and set defaults
-		checkValues(&service, cfg)
+		// Check service values, set defaults and validate the definition
+		if err := prepareService(&service, cfg); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
+		if err := checkProtectedFlag(c, cfg, &service, nil); err != nil {
+			sendCodedError(c, err, types.ErrAdminRequired)
+			return
+		}
 
-		if service.VO != "" {
-			oidcManager, _ := auth.NewOIDCManager(cfg.OIDCIssuer, cfg.OIDCSubject, cfg.OIDCGroups)
+		// Check the required capabilities against the nodes of the cluster
+		if err := checkServiceRequirements(&service, back); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
 
-			authHeader := c.GetHeader("Authorization")
-			rawToken := strings.TrimPrefix(authHeader, "Bearer ")
-			hasVO, err2 := oidcManager.UserHasVO(rawToken, service.VO)
+		// Two-phase create: return an upload session for the script and assets
+		if upload, _ := strconv.ParseBool(c.Query("upload")); upload {
+			if isDryRun(c) {
+				sendError(c, types.ErrBadRequest, "The dry_run option can not be used to create upload sessions")
+				return
+			}
 
-			if err2 != nil {
-				c.String(http.StatusInternalServerError, err2.Error())
+			// Check the VO before staging the assets (it is checked again on activation)
+			setServiceOwner(c, &service)
+			if err := checkServiceVO(c, cfg, &service); err != nil {
+				sendCodedError(c, err, types.ErrVOCheckFailed)
 				return
 			}
 
-			if !hasVO {
-				c.String(http.StatusBadRequest, fmt.Sprintf("This user isn't enrrolled on the vo: %v", service.VO))
+			session, err := utils.CreateUploadSession(cfg, &service)
+			if err != nil {
+				sendCodedError(c, err, types.ErrUploadFailed)
 				return
 			}
+			c.JSON(http.StatusAccepted, session)
+			return
 		}
 
-		// Create the service
-		if err := back.CreateService(service); err != nil {
-			// Check if error is caused because the service name provided already exists
-			if k8sErrors.IsAlreadyExists(err) {
-				c.String(http.StatusConflict, "A service with the provided name already exists")
-			} else {
-				c.String(http.StatusInternalServerError, fmt.Sprintf("Error creating the service: %v", err))
-			}
+		if len(service.Assets) > 0 {
+			sendError(c, types.ErrInvalidServiceDefinition, "The service specification is not valid: the assets can only be uploaded through an upload session (upload=true)")
 			return
 		}
 
-		// Register minio webhook and restart the server
-		if err := registerMinIOWebhook(service.Name, service.Token, service.StorageProviders.MinIO[types.DefaultProvider], cfg); err != nil {
-			back.DeleteService(service.Name)
-			c.String(http.StatusInternalServerError, err.Error())
+		// Get the script (from its Git repository if defined)
+		if err := setServiceScript(&service, cfg, back); err != nil {
+			sendCodedError(c, err, types.ErrScriptFetchFailed)
 			return
 		}
 
-		// Create buckets/folders based on the Input and Output and enable notifications
-		if err := createBuckets(&service, cfg); err != nil {
-			if err == errInput {
-				c.String(http.StatusBadRequest, err.Error())
-			} else {
-				c.String(http.StatusInternalServerError, err.Error())
-			}
-			back.DeleteService(service.Name)
+		setServiceOwner(c, &service)
+		if err := checkServiceVO(c, cfg, &service); err != nil {
+			sendCodedError(c, err, types.ErrVOCheckFailed)
 			return
 		}
 
-		// Add Yunikorn queue if enabled
-		if cfg.YunikornEnable {
-			if err := utils.AddYunikornQueue(cfg, back.GetKubeClientset(), &service); err != nil {
-				log.Println(err.Error())
+		// Return the fully-defaulted service without persisting it
+		if isDryRun(c) {
+			if err := dryRunService(back, &service, false); err != nil {
+				sendCodedError(c, err, types.ErrServiceCreateFailed)
+				return
 			}
+			c.JSON(http.StatusOK, service)
+			return
+		}
+
+		// Asynchronous create: deploy the service in background, reporting its progress in an operation
+		if async, _ := strconv.ParseBool(c.Query("async")); async {
+			op := utils.NewOperation("create", service.Name, types.CreateOperationSteps)
+			go func() {
+				err := deployServiceWithProgress(cfg, back, &service, func(step string, err error) {
+					utils.UpdateOperationStep(op.ID, step, err)
+				})
+				utils.FinishOperation(op.ID, err, types.ErrServiceCreateFailed)
+			}()
+			c.Header("Location", "/system/operations/"+op.ID)
+			c.JSON(http.StatusAccepted, op)
+			return
 		}
 
-		c.Status(http.StatusCreated)
+		if err := deployService(cfg, back, &service); err != nil {
+			sendCodedError(c, err, types.ErrServiceCreateFailed)
+			return
+		}
+
+		// Return the created service, with the resolved defaults (e.g. the paths of the outputs without provider)
+		c.JSON(http.StatusCreated, service)
 	}
 }
 
+// prepareService sets the default values of a service and validates its definition
+func prepareService(service *types.Service, cfg *types.Config) error {
+	checkValues(service, cfg)
+
+	// Check the YuniKorn settings
+	if err := service.ValidateYunikorn(cfg); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the placement policy
+	if service.Placement != nil {
+		if err := service.Placement.Validate(service.Replicas); err != nil {
+			return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+		}
+	}
+
+	// Check the delegation policy
+	if err := service.ValidateDelegationPolicy(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the deferral settings
+	if err := service.ValidateDeferral(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the GPUs and extended resources
+	if err := service.ValidateExtendedResources(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the node selector and tolerations
+	if err := service.ValidateScheduling(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the RuntimeClass
+	if err := service.ValidateRuntimeClass(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+	if !service.IsRuntimeClassAllowed(cfg) {
+		return types.NewCodedError(types.ErrRuntimeClassNotAllowed, fmt.Errorf("the runtime class \"%s\" is not allowed in the cluster", service.RuntimeClass))
+	}
+
+	// Check the security context
+	if err := service.ValidateSecurityContext(cfg); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the init containers and the sidecars
+	if err := service.ValidateContainers(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the autoscaling settings of the synchronous invocations
+	if err := service.ValidateSynchronous(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the batch settings
+	if err := service.ValidateBatch(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the workers consuming the events with KEDA
+	if err := service.ValidateWorkers(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+	if service.HasWorkers() && !cfg.KEDAEnable {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, errors.New("the service specification is not valid: the workers consuming the events with KEDA (KEDA_ENABLE) are not enabled in the cluster"))
+	}
+
+	// Check the concurrency limit and priority
+	if err := service.ValidateConcurrency(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the callback
+	if err := service.ValidateCallback(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the chat notification
+	if err := service.ValidateChatNotification(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the dependencies
+	if err := service.ValidateDependencies(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the custom FaaS Supervisor
+	if err := service.ValidateSupervisor(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+	if service.Supervisor != nil && !service.Supervisor.IsAllowed(cfg.SupervisorImages) {
+		return types.NewCodedError(types.ErrSupervisorNotAllowed, fmt.Errorf("the supervisor image \"%s\" is not allowed in the cluster", service.Supervisor.GetImage()))
+	}
+
+	// Check the dead-letter path
+	if err := service.ValidateDeadLetterPath(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the quarantine path
+	if err := service.ValidateQuarantinePath(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+	if service.QuarantinePath != "" && cfg.MalwareScannerURL == "" {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, errors.New("the service specification is not valid: the malware scanning of the inputs (quarantine_path) is not enabled in the cluster"))
+	}
+
+	// Check the credentials referencing Vault secrets
+	if err := service.ValidateVaultReferences(cfg.GetVaultPathPrefixes()); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+	if service.HasVaultReferences() && cfg.VaultAddress == "" {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, errors.New("the service specification is not valid: the Vault integration (VAULT_ADDR) is not enabled in the cluster"))
+	}
+
+	// Check the output destinations
+	if err := service.ValidateOutputDestinations(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the output routes
+	if err := service.ValidateOutputRoutes(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the inline outputs
+	if err := service.ValidateInlineOutputs(cfg); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the assets
+	if err := service.ValidateAssets(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the feature flags
+	if err := service.ValidateFeatures(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the input/output schemas
+	if err := service.ValidateSchema(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	}
+
+	// Check the storage providers of the inputs and outputs
+	return validateStorageIO(service, cfg)
+}
+
+// setServiceOwner sets the authenticated user (basic auth username or OIDC subject) as the owner of a new service
+func setServiceOwner(c *gin.Context, service *types.Service) {
+	service.Owner = c.GetString(gin.AuthUserKey)
+}
+
+// checkServiceVO checks that the user creating the service (OIDC token) is enrolled in the service's VO
+func checkServiceVO(c *gin.Context, cfg *types.Config, service *types.Service) error {
+	if service.VO == "" {
+		return nil
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	rawToken := strings.TrimPrefix(authHeader, "Bearer ")
+	hasVO, err := auth.UserHasVO(cfg, rawToken, service.VO)
+	if err != nil {
+		return types.NewCodedError(types.ErrVOCheckFailed, err)
+	}
+
+	if !hasVO {
+		return types.NewCodedError(types.ErrVONotEnrolled, fmt.Errorf("This user isn't enrrolled on the vo: %v", service.VO))
+	}
+
+	return nil
+}
+
+// deployService creates the service in the backend and its MinIO resources (webhook, buckets and notifications),
+// deleting the service if any of them fails
+func deployService(cfg *types.Config, back types.ServerlessBackend, service *types.Service) error {
+	return deployServiceWithProgress(cfg, back, service, func(step string, err error) {})
+}
+
+// deployServiceWithProgress deploys the service as deployService, reporting the result of each step
+// (types.CreateOperationSteps) to progress
+func deployServiceWithProgress(cfg *types.Config, back types.ServerlessBackend, service *types.Service, progress func(step string, err error)) error {
+	setServiceRevision(cfg, back, service)
+
+	// Create the service
+	if err := back.CreateService(*service); err != nil {
+		// Check if error is caused because the service name provided already exists
+		if k8sErrors.IsAlreadyExists(err) {
+			err = types.NewCodedError(types.ErrServiceAlreadyExists, errors.New("A service with the provided name already exists"))
+		} else {
+			err = types.NewCodedError(types.ErrServiceCreateFailed, fmt.Errorf("Error creating the service: %v", err))
+		}
+		progress(types.StepBackendCreated, err)
+		return err
+	}
+	progress(types.StepBackendCreated, nil)
+
+	// Register minio webhook and restart the server
+	if err := registerMinIOWebhook(service.Name, service.Token, service.StorageProviders.MinIO[types.DefaultProvider], cfg); err != nil {
+		back.DeleteService(service.Name)
+		err = types.NewCodedError(types.GetErrorCode(err, types.ErrWebhookRegisterFailed), err)
+		progress(types.StepWebhookRegistered, err)
+		return err
+	}
+	progress(types.StepWebhookRegistered, nil)
+
+	// Create buckets/folders based on the Input and Output
+	newBuckets := getNewBuckets(cfg, []*types.Service{service})
+	if err := createBuckets(service, cfg); err != nil {
+		back.DeleteService(service.Name)
+		err = types.NewCodedError(types.GetErrorCode(err, types.ErrInternal), err)
+		progress(types.StepBucketsCreated, err)
+		return err
+	}
+
+	// Create the dead-letter bucket
+	if service.DeadLetterPath != "" {
+		if err := utils.CreateDeadLetterBucket(cfg, service); err != nil {
+			back.DeleteService(service.Name)
+			err = types.NewCodedError(types.ErrBucketCreateFailed, err)
+			progress(types.StepBucketsCreated, err)
+			return err
+		}
+	}
+	progress(types.StepBucketsCreated, nil)
+	recordCreatedBuckets(cfg, back, service.Name, newBuckets)
+
+	// Enable the MinIO notifications of the inputs
+	if err := enableInputNotifications(service); err != nil {
+		disableInputNotifications(service.GetMinIOWebhookARN(), service.Input, cfg.MinIOProvider)
+		back.DeleteService(service.Name)
+		err = types.NewCodedError(types.GetErrorCode(err, types.ErrNotificationFailed), err)
+		progress(types.StepNotificationsEnabled, err)
+		return err
+	}
+	progress(types.StepNotificationsEnabled, nil)
+
+	// Add Yunikorn queue if enabled
+	if cfg.YunikornEnable {
+		if err := utils.AddYunikornQueue(cfg, back.GetKubeClientset(), service); err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	saveServiceRevision(cfg, back, service)
+
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceCreatedEvent, Service: service.Name})
+
+	return nil
+}
+
 func checkValues(service *types.Service, cfg *types.Config) {
 	// Add default values for Memory and CPU if they are not set
 	// Do not validate, Kubernetes client throws an error if they are not correct
@@ -124,6 +418,17 @@ func checkValues(service *types.Service, cfg *types.Config) {
 		service.CPU = defaultCPU
 	}
 
+	// Request a GPU if enabled without its number, and enable the GPU if requested
+	if service.EnableGPU && service.GPU == 0 {
+		service.GPU = 1
+	}
+	service.EnableGPU = service.GPU > 0
+
+	// Set the default RuntimeClass
+	if service.RuntimeClass == "" {
+		service.RuntimeClass = cfg.DefaultRuntimeClass
+	}
+
 	// Validate logLevel (Python logging levels for faas-supervisor)
 	service.LogLevel = strings.ToUpper(service.LogLevel)
 	switch service.LogLevel {
@@ -132,84 +437,186 @@ func checkValues(service *types.Service, cfg *types.Config) {
 		service.LogLevel = defaultLogLevel
 	}
 
+	// Use the default image for the custom FaaS Supervisor
+	if service.Supervisor != nil && service.Supervisor.Image == "" {
+		service.Supervisor.Image = cfg.SupervisorImage
+	}
+
 	// Add default Labels
 	if service.Labels == nil {
 		service.Labels = make(map[string]string)
 	}
 	service.Labels[types.ServiceLabel] = service.Name
-	service.Labels[types.YunikornApplicationIDLabel] = service.Name
-	service.Labels[types.YunikornQueueLabel] = fmt.Sprintf("%s.%s.%s", types.YunikornRootQueue, types.YunikornOscarQueue, service.Name)
+	yunikorn := service.GetYunikornSettings(cfg)
+	service.Labels[types.YunikornApplicationIDLabel] = yunikorn.GetApplicationID(service)
+	if yunikorn.Placement == types.YunikornPlacementRules {
+		delete(service.Labels, types.YunikornQueueLabel)
+	} else {
+		service.Labels[types.YunikornQueueLabel] = yunikorn.GetQueue(service)
+	}
 
 	if service.VO != "" {
 		service.Labels["vo"] = service.VO
 	}
 
+	// Select the namespace of the service's resources (the updates keep the current one)
+	service.Namespace = cfg.SelectServiceNamespace(service)
+
 	// Create default annotations map
 	if service.Annotations == nil {
 		service.Annotations = make(map[string]string)
 	}
 
-	// Add the default MinIO provider
+	// Add the default MinIO provider (the secondary one while the OSCAR's MinIO is unreachable)
+	defaultMinIO := utils.GetDefaultMinIOProvider(cfg)
 	if service.StorageProviders != nil {
 		if service.StorageProviders.MinIO != nil {
-			service.StorageProviders.MinIO[types.DefaultProvider] = cfg.MinIOProvider
+			service.StorageProviders.MinIO[types.DefaultProvider] = defaultMinIO
 		} else {
 			service.StorageProviders.MinIO = map[string]*types.MinIOProvider{
-				types.DefaultProvider: cfg.MinIOProvider,
+				types.DefaultProvider: defaultMinIO,
 			}
 
 		}
 	} else {
 		service.StorageProviders = &types.StorageProviders{
 			MinIO: map[string]*types.MinIOProvider{
-				types.DefaultProvider: cfg.MinIOProvider,
+				types.DefaultProvider: defaultMinIO,
 			},
 		}
 	}
 
-	// Generate a new access token
+	// Fall back to the default provider for the outputs declared without any
+	setDefaultOutputs(service, cfg)
+
+	// Generate a new access token. The named access tokens are only managed through their endpoints
 	service.Token = utils.GenerateToken()
+	service.AccessTokens = nil
 }
 
-func createBuckets(service *types.Service, cfg *types.Config) error {
-	var s3Client *s3.S3
-	var cdmiClient *cdmi.Client
-	var provName, provID string
+// setDefaultOutputs sets cfg.DefaultOutputProvider as the provider of the outputs declared without any, placing
+// them in cfg.DefaultOutputPath if they have no path either
+func setDefaultOutputs(service *types.Service, cfg *types.Config) {
+	if cfg.DefaultOutputProvider == "" {
+		return
+	}
+	for i, out := range service.Output {
+		if strings.TrimSpace(out.Provider) != "" {
+			continue
+		}
+		service.Output[i].Provider = cfg.DefaultOutputProvider
+		if strings.Trim(out.Path, " /") == "" {
+			service.Output[i].Path = strings.ReplaceAll(cfg.DefaultOutputPath, types.ServicePlaceholder, service.Name)
+		}
+	}
+}
 
-	// Create input buckets
+// validateStorageIO checks the providers, filters and paths of the service's inputs and outputs
+func validateStorageIO(service *types.Service, cfg *types.Config) error {
 	for _, in := range service.Input {
-		// Split input provider
-		provSlice := strings.SplitN(strings.TrimSpace(in.Provider), types.ProviderSeparator, 2)
-		if len(provSlice) == 1 {
-			provName = strings.ToLower(provSlice[0])
-			// Set "default" provider ID
-			provID = types.DefaultProvider
-		} else {
-			provName = strings.ToLower(provSlice[0])
-			provID = provSlice[1]
+		if err := validateInput(service, cfg, in); err != nil {
+			return err
 		}
+	}
 
-		// Only allow input from MinIO and dCache
-		if provName != types.MinIOName && provName != types.WebDavName {
-			return errInput
+	for _, out := range service.Output {
+		if err := validateOutput(service, out); err != nil {
+			return err
 		}
+	}
 
-		// If the provider is WebDav (dCache) skip bucket creation
-		if provName == types.WebDavName {
-			continue
+	return nil
+}
+
+// validateInput checks the storage provider and the filters of a service input
+func validateInput(service *types.Service, cfg *types.Config, in types.StorageIOConfig) error {
+	provName, provID := in.GetProvider()
+
+	// Only allow input from MinIO and dCache
+	if provName != types.MinIOName && provName != types.WebDavName {
+		return errInput
+	}
+
+	// The dCache inputs have no buckets to check
+	if provName == types.WebDavName {
+		return nil
+	}
+
+	// Check if the provider identifier is defined in StorageProviders
+	if !isStorageProviderDefined(provName, provID, service.StorageProviders) {
+		return types.NewCodedError(types.ErrStorageProviderNotDefined, fmt.Errorf("the StorageProvider \"%s.%s\" is not defined", provName, provID))
+	}
+
+	// Check if the input provider is the defined in the server config
+	if provID != types.DefaultProvider {
+		if !reflect.DeepEqual(*cfg.MinIOProvider, *service.StorageProviders.MinIO[provID]) {
+			return types.NewCodedError(types.ErrStorageProviderNotDefined, fmt.Errorf("the provided MinIO server \"%s\" is not the configured in OSCAR", service.StorageProviders.MinIO[provID].Endpoint))
 		}
+	}
 
-		// Check if the provider identifier is defined in StorageProviders
-		if !isStorageProviderDefined(provName, provID, service.StorageProviders) {
-			return fmt.Errorf("the StorageProvider \"%s.%s\" is not defined", provName, provID)
+	// Check the input filters
+	if in.MaxSize != "" {
+		if _, err := resource.ParseQuantity(in.MaxSize); err != nil {
+			return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the max_size \"%s\" of the input \"%s\" is not valid: %v", in.MaxSize, in.Path, err))
 		}
+	}
+
+	if err := in.ValidateFileSet(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, err)
+	}
 
-		// Check if the input provider is the defined in the server config
-		if provID != types.DefaultProvider {
-			if !reflect.DeepEqual(*cfg.MinIOProvider, *service.StorageProviders.MinIO[provID]) {
-				return fmt.Errorf("the provided MinIO server \"%s\" is not the configured in OSCAR", service.StorageProviders.MinIO[provID].Endpoint)
+	if err := in.ValidateLimits(); err != nil {
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, err)
+	}
+
+	// Avoid the objects copied to the error path triggering the service again
+	if in.ErrorPath != "" {
+		for _, other := range service.Input {
+			otherProvName, otherProvID := other.GetProvider()
+			if otherProvName == provName && otherProvID == provID && other.ContainsPath(in.ErrorPath) {
+				return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the error_path \"%s\" of the input \"%s\" can not be placed in the input \"%s\"", in.ErrorPath, in.Path, other.Path))
 			}
 		}
+	}
+
+	return nil
+}
+
+// validateOutput checks the storage provider of a service output
+func validateOutput(service *types.Service, out types.StorageIOConfig) error {
+	provName, provID := out.GetProvider()
+
+	// Check if the provider identifier is defined in StorageProviders
+	if !isStorageProviderDefined(provName, provID, service.StorageProviders) {
+		return types.NewCodedError(types.ErrStorageProviderNotDefined, fmt.Errorf("the StorageProvider \"%s.%s\" is not defined", provName, provID))
+	}
+
+	return nil
+}
+
+func createBuckets(service *types.Service, cfg *types.Config) error {
+	var s3Client *s3.S3
+	var cdmiClient *cdmi.Client
+	var provName, provID string
+
+	if err := validateStorageIO(service, cfg); err != nil {
+		return err
+	}
+
+	// Resolve the credentials of the storage providers referencing Vault secrets
+	service, err := utils.ResolveVaultCredentials(cfg, service)
+	if err != nil {
+		return err
+	}
+
+	// Create input buckets
+	for _, in := range service.Input {
+		provName, provID = in.GetProvider()
+
+		// If the provider is WebDav (dCache) skip bucket creation
+		if provName == types.WebDavName {
+			continue
+		}
 
 		// Get client for the provider
 		s3Client = service.StorageProviders.MinIO[provID].GetS3Client()
@@ -227,10 +634,10 @@ func createBuckets(service *types.Service, cfg *types.Config) error {
 				if aerr.Code() == s3.ErrCodeBucketAlreadyExists || aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
 					log.Printf("The bucket \"%s\" already exists\n", splitPath[0])
 				} else {
-					return fmt.Errorf("error creating bucket %s: %v", splitPath[0], err)
+					return types.NewCodedError(types.ErrBucketCreateFailed, fmt.Errorf("error creating bucket %s: %v", splitPath[0], err))
 				}
 			} else {
-				return fmt.Errorf("error creating bucket %s: %v", splitPath[0], err)
+				return types.NewCodedError(types.ErrBucketCreateFailed, fmt.Errorf("error creating bucket %s: %v", splitPath[0], err))
 			}
 		}
 		// Create folder(s)
@@ -242,13 +649,15 @@ func createBuckets(service *types.Service, cfg *types.Config) error {
 				Key:    aws.String(folderKey),
 			})
 			if err != nil {
-				return fmt.Errorf("error creating folder \"%s\" in bucket \"%s\": %v", folderKey, splitPath[0], err)
+				return types.NewCodedError(types.ErrFolderCreateFailed, fmt.Errorf("error creating folder \"%s\" in bucket \"%s\": %v", folderKey, splitPath[0], err))
 			}
 		}
 
-		// Enable MinIO notifications based on the Input []StorageIOConfig
-		if err := enableInputNotification(s3Client, service.GetMinIOWebhookARN(), in); err != nil {
-			return err
+		// Create the error path for the objects discarded by the filters
+		if in.ErrorPath != "" {
+			if err := createErrorPath(s3Client, in.ErrorPath); err != nil {
+				return err
+			}
 		}
 
 	}
@@ -266,12 +675,6 @@ func createBuckets(service *types.Service, cfg *types.Config) error {
 			provID = provSlice[1]
 		}
 
-		// Check if the provider identifier is defined in StorageProviders
-		if !isStorageProviderDefined(provName, provID, service.StorageProviders) {
-			disableInputNotifications(service.GetMinIOWebhookARN(), service.Input, cfg.MinIOProvider)
-			return fmt.Errorf("the StorageProvider \"%s.%s\" is not defined", provName, provID)
-		}
-
 		path := strings.Trim(out.Path, " /")
 		// Split buckets and folders from path
 		splitPath := strings.SplitN(path, "/", 2)
@@ -294,12 +697,10 @@ func createBuckets(service *types.Service, cfg *types.Config) error {
 					if aerr.Code() == s3.ErrCodeBucketAlreadyExists || aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
 						log.Printf("The bucket \"%s\" already exists\n", splitPath[0])
 					} else {
-						disableInputNotifications(service.GetMinIOWebhookARN(), service.Input, cfg.MinIOProvider)
-						return fmt.Errorf("error creating bucket %s: %v", splitPath[0], err)
+						return types.NewCodedError(types.ErrBucketCreateFailed, fmt.Errorf("error creating bucket %s: %v", splitPath[0], err))
 					}
 				} else {
-					disableInputNotifications(service.GetMinIOWebhookARN(), service.Input, cfg.MinIOProvider)
-					return fmt.Errorf("error creating bucket %s: %v", splitPath[0], err)
+					return types.NewCodedError(types.ErrBucketCreateFailed, fmt.Errorf("error creating bucket %s: %v", splitPath[0], err))
 				}
 			}
 			// Create folder(s)
@@ -311,8 +712,7 @@ func createBuckets(service *types.Service, cfg *types.Config) error {
 					Key:    aws.String(folderKey),
 				})
 				if err != nil {
-					disableInputNotifications(service.GetMinIOWebhookARN(), service.Input, cfg.MinIOProvider)
-					return fmt.Errorf("error creating folder \"%s\" in bucket \"%s\": %v", folderKey, splitPath[0], err)
+					return types.NewCodedError(types.ErrFolderCreateFailed, fmt.Errorf("error creating folder \"%s\" in bucket \"%s\": %v", folderKey, splitPath[0], err))
 				}
 			}
 		case types.OnedataName:
@@ -322,8 +722,7 @@ func createBuckets(service *types.Service, cfg *types.Config) error {
 				if err == cdmi.ErrBadRequest {
 					log.Printf("Error creating \"%s\" folder in Onedata. Error: %v\n", path, err)
 				} else {
-					disableInputNotifications(service.GetMinIOWebhookARN(), service.Input, cfg.MinIOProvider)
-					return fmt.Errorf("error connecting to Onedata's Oneprovider \"%s\". Error: %v", service.StorageProviders.Onedata[provID].OneproviderHost, err)
+					return types.NewCodedError(types.ErrStorageConnectionFailed, fmt.Errorf("error connecting to Onedata's Oneprovider \"%s\". Error: %v", service.StorageProviders.Onedata[provID].OneproviderHost, err))
 				}
 			}
 		}
@@ -348,18 +747,32 @@ func isStorageProviderDefined(storageName string, storageID string, providers *t
 }
 
 func registerMinIOWebhook(name string, token string, minIO *types.MinIOProvider, cfg *types.Config) error {
-	minIOAdminClient, err := utils.MakeMinIOAdminClient(cfg)
+	minIOAdminClient, err := utils.MakeProviderMinIOAdminClient(cfg, minIO)
 	if err != nil {
 		return fmt.Errorf("the provided MinIO configuration is not valid: %v", err)
 	}
 
 	if err := minIOAdminClient.RegisterWebhook(name, token); err != nil {
-		return fmt.Errorf("error registering the service's webhook: %v", err)
+		return types.NewCodedError(types.ErrWebhookRegisterFailed, fmt.Errorf("error registering the service's webhook: %v", err))
 	}
 
 	return minIOAdminClient.RestartServer()
 }
 
+// enableInputNotifications enables the MinIO notifications of the service's inputs (unless they are disabled)
+func enableInputNotifications(service *types.Service) error {
+	for _, in := range service.Input {
+		provName, provID := in.GetProvider()
+		if provName == types.WebDavName || in.Disabled {
+			continue
+		}
+		if err := enableInputNotification(service.StorageProviders.MinIO[provID].GetS3Client(), service.GetMinIOWebhookARN(), in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func enableInputNotification(minIOClient *s3.S3, arnStr string, input types.StorageIOConfig) error {
 	path := strings.Trim(input.Path, " /")
 	// Split buckets and folders from path
@@ -371,7 +784,7 @@ func enableInputNotification(minIOClient *s3.S3, arnStr string, input types.Stor
 	}
 	nCfg, err := minIOClient.GetBucketNotificationConfiguration(gbncRequest)
 	if err != nil {
-		return fmt.Errorf("error getting bucket \"%s\" notifications: %v", splitPath[0], err)
+		return types.NewCodedError(types.ErrNotificationFailed, fmt.Errorf("error getting bucket \"%s\" notifications: %v", splitPath[0], err))
 	}
 	queueConfiguration := s3.QueueConfiguration{
 		QueueArn: aws.String(arnStr),
@@ -402,8 +815,33 @@ func enableInputNotification(minIOClient *s3.S3, arnStr string, input types.Stor
 	// Enable the notification
 	_, err = minIOClient.PutBucketNotificationConfiguration(pbncInput)
 	if err != nil {
-		return fmt.Errorf("error enabling bucket notification: %v", err)
+		return types.NewCodedError(types.ErrNotificationFailed, fmt.Errorf("error enabling bucket notification: %v", err))
 	}
 
 	return nil
 }
+
+// createErrorPath creates the bucket and folder (if any) of an input's error path
+func createErrorPath(s3Client *s3.S3, errorPath string) error {
+	bucket, folder := types.StorageIOConfig{Path: errorPath}.SplitPath()
+	_, err := s3Client.CreateBucket(&s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		// Check if the error is caused because the bucket already exists
+		if aerr, ok := err.(awserr.Error); !ok || (aerr.Code() != s3.ErrCodeBucketAlreadyExists && aerr.Code() != s3.ErrCodeBucketAlreadyOwnedByYou) {
+			return types.NewCodedError(types.ErrBucketCreateFailed, fmt.Errorf("error creating bucket %s: %v", bucket, err))
+		}
+	}
+	if folder != "" {
+		folderKey := fmt.Sprintf("%s/", folder)
+		_, err := s3Client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(folderKey),
+		})
+		if err != nil {
+			return types.NewCodedError(types.ErrFolderCreateFailed, fmt.Errorf("error creating folder \"%s\" in bucket \"%s\": %v", folderKey, bucket, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/resourcemanager"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeDeadLetterListHandler makes a handler for listing the failed jobs stored in the service's dead-letter path
+func MakeDeadLetterListHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readDeadLetterService(c, back)
+		if service == nil {
+			return
+		}
+
+		records, err := utils.ListDeadLetterRecords(cfg, service)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, records)
+	}
+}
+
+// MakeDeadLetterRedriveHandler makes a handler for re-driving (creating new jobs) the failed jobs stored in the service's dead-letter path
+func MakeDeadLetterRedriveHandler(cfg *types.Config, kubeClientset kubernetes.Interface, back types.ServerlessBackend, rm resourcemanager.ResourceManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.RedriveRequest
+		// The request body is optional (re-drive all the records)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("The redrive request is not valid: %v", err))
+				return
+			}
+		}
+
+		service := readDeadLetterService(c, back)
+		if service == nil {
+			return
+		}
+
+		records := []types.DeadLetterRecord{}
+		if len(req.IDs) == 0 {
+			var err error
+			records, err = utils.ListDeadLetterRecords(cfg, service)
+			if err != nil {
+				sendError(c, types.ErrInternal, err.Error())
+				return
+			}
+		} else {
+			for _, id := range req.IDs {
+				record, err := utils.GetDeadLetterRecord(cfg, service, id)
+				if err != nil {
+					sendError(c, types.ErrBadRequest, fmt.Sprintf("Error getting the dead-letter record \"%s\": %v", id, err))
+					return
+				}
+				records = append(records, *record)
+			}
+		}
+
+		res := types.RedriveResult{Jobs: map[string]string{}}
+		for _, record := range records {
+			jobName, err := runJob(cfg, kubeClientset, service, record.Event, rm)
+			if err != nil {
+				sendError(c, types.ErrInternal, fmt.Sprintf("Error re-driving the dead-letter record \"%s\": %v", record.ID, err))
+				return
+			}
+			res.Jobs[record.ID] = jobName
+			if err := utils.DeleteDeadLetterRecord(cfg, service, record.ID); err != nil {
+				sendError(c, types.ErrInternal, fmt.Sprintf("Error deleting the dead-letter record \"%s\": %v", record.ID, err))
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// readDeadLetterService returns the service of the request if it has a dead-letter path (sending the error response otherwise)
+func readDeadLetterService(c *gin.Context, back types.ServerlessBackend) *types.Service {
+	service, err := back.ReadService(c.Param("serviceName"))
+	if err != nil {
+		// Check if error is caused because the service is not found
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			sendError(c, types.ErrServiceNotFound, "")
+		} else {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+		}
+		return nil
+	}
+
+	if service.DeadLetterPath == "" {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("The service \"%s\" has not a dead-letter path defined", service.Name))
+		return nil
+	}
+
+	return service
+}
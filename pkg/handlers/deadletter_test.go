@@ -0,0 +1,66 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeDeadLetterHandlers(t *testing.T) {
+	back := backends.MakeFakeBackend()
+	kubeClientset := testclient.NewSimpleClientset()
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/deadletter", MakeDeadLetterListHandler(&testConfigValidRun, back))
+	r.POST("/system/services/:serviceName/deadletter/redrive", MakeDeadLetterRedriveHandler(&testConfigValidRun, kubeClientset, back, nil))
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		notFound     bool
+		expectedCode int
+	}{
+		// The fake backend returns services without dead-letter path
+		{"list without dead-letter path", "GET", "/system/services/test/deadletter", false, http.StatusBadRequest},
+		{"redrive without dead-letter path", "POST", "/system/services/test/deadletter/redrive", false, http.StatusBadRequest},
+		{"list service not found", "GET", "/system/services/test/deadletter", true, http.StatusNotFound},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if s.notFound {
+				back.AddError("ReadService", k8serr.NewGone("Not Found"))
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -31,45 +32,251 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 )
 
-// MakeDeleteHandler makes a handler for deleting services
+// Values of the "delete_buckets" parameter of the delete requests
+const (
+	// deleteBucketsRemove removes the buckets of the service along with their data
+	deleteBucketsRemove = "true"
+	// deleteBucketsRetain keeps the buckets of the service untouched, including their notifications
+	deleteBucketsRetain = "false"
+	// deleteBucketsOrphan keeps the buckets of the service and their data, but removes their notifications
+	deleteBucketsOrphan = "orphan"
+)
+
+// MakeDeleteHandler makes a handler for deleting services. With "purge=false" the definition of the service, its
+// revision history and its invocation aliases are kept to be restored within the DeletedServicesRetention period.
+// The "delete_buckets" parameter sets the fate of the service's buckets ("orphan" by default)
 func MakeDeleteHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		purge, err := strconv.ParseBool(c.DefaultQuery("purge", "true"))
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid purge value: %v", err))
+			return
+		}
+		deleteBuckets := c.DefaultQuery("delete_buckets", deleteBucketsOrphan)
+		if deleteBuckets != deleteBucketsRemove && deleteBuckets != deleteBucketsRetain && deleteBuckets != deleteBucketsOrphan {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid delete_buckets value \"%s\", it must be \"true\", \"false\" or \"orphan\"", deleteBuckets))
+			return
+		}
+
 		// First get the Service
-		service, _ := back.ReadService(c.Param("serviceName"))
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil && !purge {
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		if !confirmProtectedChange(c, service) {
+			return
+		}
+
+		// Keep the definition before deleting the service
+		if !purge {
+			if err := utils.SaveDeletedService(cfg, back.GetKubeClientset(), service); err != nil {
+				sendError(c, types.ErrServiceDeleteFailed, err.Error())
+				return
+			}
+		}
 
 		if err := back.DeleteService(c.Param("serviceName")); err != nil {
+			if !purge {
+				utils.RemoveDeletedService(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name)
+			}
 			// Check if error is caused because the service is not found
 			if errors.IsNotFound(err) || errors.IsGone(err) {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrServiceNotFound, "")
 			} else {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrServiceDeleteFailed, err.Error())
 			}
 			return
 		}
 
+		cleanupService(cfg, back, service, deleteBuckets != deleteBucketsRetain)
+		if deleteBuckets == deleteBucketsRemove {
+			deleteServiceBuckets(cfg, back, service)
+		}
+		if purge {
+			purgeService(cfg, back, service)
+			// Remove the definition kept by a previous soft deletion
+			if err := utils.RemoveDeletedService(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name); err != nil {
+				log.Println(err.Error())
+			}
+		}
+
+		utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceDeletedEvent, Service: service.Name})
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// cleanupService removes the Yunikorn queue and the queued events (of its workers) of a deleted service and, if
+// unlinkBuckets, its MinIO notifications and webhook
+func cleanupService(cfg *types.Config, back types.ServerlessBackend, service *types.Service, unlinkBuckets bool) {
+	if unlinkBuckets {
 		// Disable input notifications
 		if err := disableInputNotifications(service.GetMinIOWebhookARN(), service.Input, service.StorageProviders.MinIO[types.DefaultProvider]); err != nil {
 			log.Printf("Error disabling MinIO input notifications for service \"%s\": %v\n", service.Name, err)
 		}
 
 		// Remove the service's webhook in MinIO config and restart the server
-		if err := removeMinIOWebhook(service.Name, cfg); err != nil {
+		if err := removeMinIOWebhook(service.Name, service.StorageProviders.MinIO[types.DefaultProvider], cfg); err != nil {
 			log.Printf("Error removing MinIO webhook for service \"%s\": %v\n", service.Name, err)
 		}
+	}
 
-		// Add Yunikorn queue if enabled
-		if cfg.YunikornEnable {
-			if err := utils.DeleteYunikornQueue(cfg, back.GetKubeClientset(), service); err != nil {
-				log.Println(err.Error())
+	// Add Yunikorn queue if enabled
+	if cfg.YunikornEnable {
+		if err := utils.DeleteYunikornQueue(cfg, back.GetKubeClientset(), service); err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	// Remove the queued events, so they are not consumed by a recreated service
+	if err := utils.DeleteEventQueue(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// deleteServiceBuckets removes the MinIO and S3 buckets created by a deleted service along with their data, except the
+// ones used by other services (including the soft-deleted ones that can be restored)
+func deleteServiceBuckets(cfg *types.Config, back types.ServerlessBackend, service *types.Service) {
+	kubeClientset := back.GetKubeClientset()
+	created, err := utils.GetCreatedBuckets(kubeClientset, cfg.ServicesNamespace, service.Name)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	services, err := back.ListServices()
+	if err != nil {
+		log.Printf("Error listing the services to delete the buckets of service \"%s\": %v\n", service.Name, err)
+		return
+	}
+	retained, err := utils.ListDeletedServices(cfg, kubeClientset)
+	if err != nil {
+		log.Printf("Error listing the deleted services to delete the buckets of service \"%s\": %v\n", service.Name, err)
+		return
+	}
+	for _, deleted := range retained {
+		if deleted.Service != nil {
+			services = append(services, deleted.Service)
+		}
+	}
+	shared := map[string]bool{}
+	for _, svc := range services {
+		if svc.Name == service.Name {
+			continue
+		}
+		for _, bucket := range getServiceBuckets(cfg, svc) {
+			shared[bucket.id()] = true
+		}
+	}
+
+	deleted := []string{}
+	for _, bucket := range getServiceBuckets(cfg, service) {
+		if !created[bucket.id()] {
+			log.Printf("The bucket \"%s\" of service \"%s\" is kept as it was not created by the service\n", bucket.name, service.Name)
+			continue
+		}
+		if shared[bucket.id()] {
+			log.Printf("The bucket \"%s\" of service \"%s\" is kept as it is used by other services\n", bucket.name, service.Name)
+			continue
+		}
+		// The buckets used by several inputs and outputs are deleted once
+		created[bucket.id()] = false
+		if err := deleteBucket(bucket); err != nil {
+			log.Printf("Error deleting bucket \"%s\" of service \"%s\": %v\n", bucket.name, service.Name, err)
+			continue
+		}
+		deleted = append(deleted, bucket.id())
+	}
+	if err := utils.RemoveCreatedBuckets(kubeClientset, cfg.ServicesNamespace, service.Name, deleted); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// purgeService removes the revision history, the invocation aliases, the secrets, the custom domains and the record of
+// the created buckets of a deleted service
+func purgeService(cfg *types.Config, back types.ServerlessBackend, service *types.Service) {
+	// Remove the revision history
+	if err := utils.DeleteServiceRevisions(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name); err != nil {
+		log.Println(err.Error())
+	}
+
+	// Remove the invocation aliases
+	if err := utils.DeleteServiceAliases(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name); err != nil {
+		log.Println(err.Error())
+	}
+
+	// Remove the secrets
+	if err := utils.DeleteServiceSecrets(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name); err != nil {
+		log.Println(err.Error())
+	}
+
+	// Remove the custom domains
+	if err := utils.DeleteServiceDomains(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name); err != nil {
+		log.Println(err.Error())
+	}
+
+	// Remove the record of the created buckets
+	if err := utils.DeleteCreatedBuckets(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// MakeDeletedServicesListHandler makes a handler to list the services deleted with "purge=false" that can be restored
+func MakeDeletedServicesListHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deleted, err := utils.ListDeletedServices(cfg, back.GetKubeClientset())
+		if err != nil {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+			return
+		}
+		// Only the services that the user could restore are listed (the unreadable ones to the admins)
+		accessible := []types.DeletedService{}
+		for _, service := range deleted {
+			if (service.Service != nil && canAccessService(c, cfg, service.Service)) || (service.Service == nil && isAdmin(c, cfg)) {
+				accessible = append(accessible, service)
 			}
 		}
+		c.JSON(http.StatusOK, accessible)
+	}
+}
 
-		c.Status(http.StatusNoContent)
+// MakeRestoreHandler makes a handler to restore a service deleted with "purge=false" within its retention period,
+// deploying it again with its last definition (including its token)
+func MakeRestoreHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := utils.GetDeletedService(back.GetKubeClientset(), cfg.ServicesNamespace, c.Param("serviceName"))
+		if err != nil {
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrDeletedServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+		if !checkServiceAccess(c, cfg, service) {
+			return
+		}
+
+		if err := deployService(cfg, back, service); err != nil {
+			sendCodedError(c, err, types.ErrServiceCreateFailed)
+			return
+		}
+
+		if err := utils.RemoveDeletedService(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name); err != nil {
+			log.Println(err.Error())
+		}
+
+		c.JSON(http.StatusCreated, service)
 	}
 }
 
-func removeMinIOWebhook(name string, cfg *types.Config) error {
-	minIOAdminClient, err := utils.MakeMinIOAdminClient(cfg)
+func removeMinIOWebhook(name string, minIO *types.MinIOProvider, cfg *types.Config) error {
+	minIOAdminClient, err := utils.MakeProviderMinIOAdminClient(cfg, minIO)
 	if err != nil {
 		return fmt.Errorf("the provided MinIO configuration is not valid: %v", err)
 	}
@@ -0,0 +1,136 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+func TestMakeDeleteHandlerBuckets(t *testing.T) {
+	scenarios := []struct {
+		name                  string
+		deleteBuckets         string
+		expectedCode          int
+		expectedInput         bool
+		expectedNotifications int
+	}{
+		{"invalid", "?delete_buckets=all", http.StatusBadRequest, true, 1},
+		{"default", "", http.StatusNoContent, true, 0},
+		{"orphan", "?delete_buckets=orphan", http.StatusNoContent, true, 0},
+		{"retain", "?delete_buckets=false", http.StatusNoContent, true, 1},
+		{"remove", "?delete_buckets=true", http.StatusNoContent, false, 0},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			s3Server := chaos.NewS3Server()
+			defer s3Server.Close()
+			cfg := testConfigValidRun
+			cfg.MinIOProvider = testS3Provider(s3Server)
+			providers := &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: cfg.MinIOProvider}}
+
+			service := types.Service{
+				Name:             "test",
+				Input:            []types.StorageIOConfig{{Provider: "minio.default", Path: "input/in"}},
+				Output:           []types.StorageIOConfig{{Provider: "minio.default", Path: "shared/out"}, {Provider: "minio.default", Path: "foreign/out"}, {Provider: "minio.default", Path: "restorable/out"}},
+				StorageProviders: providers,
+				Workers:          &types.WorkerPool{},
+			}
+			other := types.Service{
+				Name:             "other",
+				Input:            []types.StorageIOConfig{{Provider: "minio.default", Path: "shared/out"}},
+				StorageProviders: providers,
+			}
+			back := backends.MakeMemoryBackend()
+			back.CreateService(service)
+			back.CreateService(other)
+
+			// The "foreign" bucket was not created by the service, and the "restorable" one is used by a
+			// soft-deleted service
+			minIOClient := cfg.MinIOProvider.GetS3Client()
+			created := []string{}
+			for _, bucket := range []string{"input", "shared", "restorable"} {
+				created = append(created, serviceBucket{client: minIOClient, name: bucket}.id())
+			}
+			if err := utils.AddCreatedBuckets(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name, created); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := utils.PushQueuedEvent(back.GetKubeClientset(), cfg.ServicesNamespace, &service, `{"a":1}`); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			deleted := &types.Service{
+				Name:             "deleted",
+				Input:            []types.StorageIOConfig{{Provider: "minio.default", Path: "restorable/in"}},
+				StorageProviders: providers,
+			}
+			if err := utils.SaveDeletedService(&cfg, back.GetKubeClientset(), deleted); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, bucket := range []string{"input", "shared", "foreign", "restorable"} {
+				s3Server.PutObject(bucket, "out/file", chaos.S3Object{Data: []byte("data")})
+			}
+			s3Server.PutObject("input", "in/file", chaos.S3Object{Data: []byte("data")})
+			if err := enableInputNotification(minIOClient, service.GetMinIOWebhookARN(), service.Input[0]); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			r := gin.Default()
+			r.DELETE("/system/services/:serviceName", MakeDeleteHandler(&cfg, back))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/system/services/test"+s.deleteBuckets, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			// The queued events are not kept for a recreated service
+			if depth, _ := utils.GetQueueDepth(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name); s.expectedCode == http.StatusNoContent && depth != 0 {
+				t.Errorf("expecting the queue removed, got %d events", depth)
+			}
+			if s3Server.HasBucket("input") != s.expectedInput {
+				t.Errorf("expecting input bucket existence %v", s.expectedInput)
+			}
+			// The buckets used by other services (or not created by the service) are always kept
+			for _, bucket := range []string{"shared", "foreign", "restorable"} {
+				if _, ok := s3Server.GetObject(bucket, "out/file"); !ok {
+					t.Errorf("expecting the bucket \"%s\" to be kept", bucket)
+				}
+			}
+			if s.expectedInput {
+				nCfg, err := minIOClient.GetBucketNotificationConfiguration(&s3.GetBucketNotificationConfigurationRequest{Bucket: aws.String("input")})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(nCfg.QueueConfigurations) != s.expectedNotifications {
+					t.Errorf("expecting %d notifications, got %d", s.expectedNotifications, len(nCfg.QueueConfigurations))
+				}
+			}
+		})
+	}
+}
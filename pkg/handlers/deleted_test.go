@@ -0,0 +1,160 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+func TestSoftDeleteService(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+
+	cfg.Username = "admin"
+
+	service := types.Service{
+		Name:             "test",
+		Image:            "test",
+		Script:           "echo",
+		Token:            "token",
+		Owner:            "user",
+		Revision:         1,
+		StorageProviders: &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: cfg.MinIOProvider}},
+	}
+	back := backends.MakeMemoryBackend()
+	back.CreateService(service)
+	kubeClientset := back.GetKubeClientset()
+	if err := utils.SaveServiceRevision(&cfg, kubeClientset, &service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.DELETE("/system/services/:serviceName", MakeDeleteHandler(&cfg, back))
+	r.GET("/system/deleted-services", MakeDeletedServicesListHandler(&cfg, back))
+	r.POST("/system/services/:serviceName/restore", MakeRestoreHandler(&cfg, back))
+
+	serveAs := func(user, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		return serveAs("user", method, path)
+	}
+
+	// Invalid purge value
+	w := serve("DELETE", "/system/services/test?purge=maybe")
+	if w.Code != http.StatusBadRequest || w.Header().Get(errorCodeHeader) != types.ErrBadRequest.Code {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	// Soft delete of a missing service
+	w = serve("DELETE", "/system/services/missing?purge=false")
+	if w.Code != http.StatusNotFound || w.Header().Get(errorCodeHeader) != types.ErrServiceNotFound.Code {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	// Soft delete keeping the definition and the revision history
+	w = serve("DELETE", "/system/services/test?purge=false")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if _, err := back.ReadService("test"); err == nil {
+		t.Error("expecting the service to be deleted")
+	}
+	deleted, err := utils.GetDeletedService(kubeClientset, cfg.ServicesNamespace, "test")
+	if err != nil {
+		t.Fatalf("expecting the definition to be kept, got: %v", err)
+	}
+	if deleted.Token != "token" || deleted.Script != "echo" {
+		t.Errorf("unexpected kept definition: %+v", deleted)
+	}
+	if revisions, _ := utils.ListServiceRevisions(kubeClientset, cfg.ServicesNamespace, "test"); len(revisions) != 1 {
+		t.Errorf("expecting the revision history to be kept, got %d revisions", len(revisions))
+	}
+
+	// List the deleted services
+	w = serve("GET", "/system/deleted-services")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var list []types.DeletedService
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "test" || !list[0].PurgeAt.Equal(list[0].DeletedAt.Add(cfg.DeletedServicesRetention)) {
+		t.Errorf("unexpected deleted services: %+v", list)
+	}
+
+	// The services of other owners are neither listed nor restored
+	w = serveAs("other", "GET", "/system/deleted-services")
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Errorf("expecting no deleted services for another user, got %d: %s", w.Code, w.Body.String())
+	}
+	w = serveAs("admin", "GET", "/system/deleted-services")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 {
+		t.Errorf("expecting the deleted services for the admin, got %d: %s", w.Code, w.Body.String())
+	}
+	w = serveAs("other", "POST", "/system/services/test/restore")
+	if w.Code != http.StatusForbidden || w.Header().Get(errorCodeHeader) != types.ErrServiceAccessDenied.Code {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+
+	// Restore a service that has not been deleted
+	w = serve("POST", "/system/services/missing/restore")
+	if w.Code != http.StatusNotFound || w.Header().Get(errorCodeHeader) != types.ErrDeletedServiceNotFound.Code {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	// Restore when a service with the same name has been created since
+	back.CreateService(service)
+	w = serve("POST", "/system/services/test/restore")
+	if w.Code != http.StatusConflict || w.Header().Get(errorCodeHeader) != types.ErrServiceAlreadyExists.Code {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if _, err := utils.GetDeletedService(kubeClientset, cfg.ServicesNamespace, "test"); err != nil {
+		t.Errorf("expecting the definition to be kept after a failed restore, got: %v", err)
+	}
+
+	// A purging delete removes the kept definition and the revision history
+	w = serve("DELETE", "/system/services/test")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if _, err := utils.GetDeletedService(kubeClientset, cfg.ServicesNamespace, "test"); err == nil {
+		t.Error("expecting the kept definition to be removed")
+	}
+	if revisions, _ := utils.ListServiceRevisions(kubeClientset, cfg.ServicesNamespace, "test"); len(revisions) != 0 {
+		t.Errorf("expecting the revision history to be removed, got %d revisions", len(revisions))
+	}
+}
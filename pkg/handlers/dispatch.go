@@ -0,0 +1,221 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/grycap/oscar/v2/pkg/resourcemanager"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/client-go/kubernetes"
+)
+
+// dispatchStatus result of dispatching an event
+type dispatchStatus int
+
+const (
+	// eventDiscarded the event does not satisfy the input filters
+	eventDiscarded dispatchStatus = iota
+	// eventWaiting the event is waiting for the rest of its file set
+	eventWaiting
+	// eventBatched the event has been added to the service's batch
+	eventBatched
+	// eventDelivered the event has been delivered to a job (or delegated to a replica)
+	eventDelivered
+	// eventQueued the event has been queued for the workers of the service
+	eventQueued
+)
+
+// EventDispatcher processes the events of the services applying the input filters,
+// file sets and batches before creating the jobs. The same dispatcher must be shared
+// by all the handlers receiving events in order to aggregate them in the same batches
+type EventDispatcher struct {
+	cfg           *types.Config
+	kubeClientset kubernetes.Interface
+	rm            resourcemanager.ResourceManager
+	batcher       *eventBatcher
+	scanner       utils.MalwareScanner
+}
+
+// MakeEventDispatcher returns a new EventDispatcher
+func MakeEventDispatcher(cfg *types.Config, kubeClientset kubernetes.Interface, rm resourcemanager.ResourceManager) *EventDispatcher {
+	d := &EventDispatcher{
+		cfg:           cfg,
+		kubeClientset: kubeClientset,
+		rm:            rm,
+	}
+	// Scanner of the input objects of the services with a quarantine path
+	scanner, err := utils.NewMalwareScanner(cfg)
+	if err != nil {
+		malwareLogger.Printf("The input objects can not be scanned: %v\n", err)
+	}
+	d.scanner = scanner
+
+	// Aggregator of events for services with batch processing enabled
+	d.batcher = newEventBatcher(func(service *types.Service, event string) {
+		if _, err := runJob(cfg, kubeClientset, service, event, rm); err != nil {
+			batchLogger.Printf("Error creating batch job for service \"%s\": %v\n", service.Name, err)
+		}
+	})
+
+	return d
+}
+
+// dispatch processes an event of the service. Returns the resulting status and
+// the name of the created job (or the reason why the event has been discarded)
+func (d *EventDispatcher) dispatch(service *types.Service, eventBytes []byte) (dispatchStatus, string, error) {
+	// Check the input filters (size and content type) to avoid creating jobs for unsupported objects
+	if err := filterEvent(service, eventBytes); err != nil {
+		log.Printf("Event discarded for service \"%s\": %v\n", service.Name, err)
+		return eventDiscarded, err.Error(), nil
+	}
+
+	// Skip (and quarantine) the infected input objects
+	reason, err := d.scanEvent(service, eventBytes)
+	if err != nil {
+		return eventDiscarded, "", err
+	}
+	if reason != "" {
+		return eventDiscarded, reason, nil
+	}
+
+	// Wait until all the members of the input's file set (if defined) exist
+	eventBytes, err = checkFileSet(service, eventBytes)
+	if err != nil {
+		return eventWaiting, "", types.NewCodedError(types.ErrStorageConnectionFailed, err)
+	}
+	if eventBytes == nil {
+		return eventWaiting, "", nil
+	}
+
+	// Discard the events exceeding the limits of their input (unless its jobs are queued)
+	if reason := d.checkInputLimits(service, eventBytes); reason != "" {
+		log.Printf("Event discarded for service \"%s\": %s\n", service.Name, reason)
+		return eventDiscarded, reason, nil
+	}
+
+	// Aggregate the event if the service processes events in batches (the jobs of the batches, with the events of
+	// several invocations, don't use the OIDC token of the invoking user)
+	if service.HasBatch() {
+		d.batcher.add(service.WithIdentityToken(""), string(eventBytes))
+		return eventBatched, "", nil
+	}
+
+	// Queue the event for the workers of the service (if enabled)
+	if service.HasWorkers() {
+		if err := utils.PushQueuedEvent(d.kubeClientset, d.cfg.ServicesNamespace, service, string(eventBytes)); err != nil {
+			return eventQueued, "", types.NewCodedError(types.GetErrorCode(err, types.ErrJobCreateFailed), err)
+		}
+		return eventQueued, "", nil
+	}
+
+	jobName, err := runJob(d.cfg, d.kubeClientset, service, string(eventBytes), d.rm)
+	if err != nil {
+		return eventDelivered, "", types.NewCodedError(types.GetErrorCode(err, types.ErrJobCreateFailed), err)
+	}
+
+	return eventDelivered, jobName, nil
+}
+
+// checkInputLimits returns the description of the max_in_flight or max_events_per_minute limit reached by the input
+// of the event (MinIO notification) if its limit_policy is "drop", empty otherwise
+func (d *EventDispatcher) checkInputLimits(service *types.Service, eventBytes []byte) string {
+	minIOEvent, err := types.ParseMinIOEvent(eventBytes)
+	if err != nil {
+		return ""
+	}
+	input := service.GetInputIndexForObject(minIOEvent.GetBucket(), minIOEvent.GetObjectKey())
+	if input < 0 || service.Input[input].GetLimitPolicy() != types.LimitPolicyDrop {
+		return ""
+	}
+
+	exceeded, err := utils.CheckInputLimits(d.cfg, d.kubeClientset, service, input)
+	if err != nil {
+		log.Printf("Unable to check the limits of the input \"%s\" of service \"%s\": %v\n", service.Input[input].Path, service.Name, err)
+		return ""
+	}
+	if exceeded == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s", service.Input[input].Path, exceeded)
+}
+
+// simulate runs the dispatch pipeline for an event without side effects (the discarded objects are not copied to
+// the error path, file sets and batches are not registered and the job is not created). Returns the job that would
+// be created to process the event
+func (d *EventDispatcher) simulate(service *types.Service, eventBytes []byte) (*types.EventSimulation, error) {
+	res := &types.EventSimulation{}
+
+	// Check the input filters
+	var bucket, key string
+	if minIOEvent, err := types.ParseMinIOEvent(eventBytes); err == nil {
+		bucket = minIOEvent.GetBucket()
+		key = minIOEvent.GetObjectKey()
+		res.Input = service.GetInputForObject(bucket, key)
+		if res.Input != nil {
+			if res.Input.Disabled {
+				res.Status = types.SimulationDiscarded
+				res.Reason = fmt.Sprintf("the input \"%s\" is disabled", res.Input.Path)
+				return res, nil
+			}
+			object := minIOEvent.GetObject()
+			if err := res.Input.CheckObjectFilters(object.Size, object.ContentType); err != nil {
+				res.Status = types.SimulationDiscarded
+				res.Reason = err.Error()
+				if res.Input.ErrorPath != "" {
+					res.Reason += fmt.Sprintf(" (the object would be copied to \"%s\")", res.Input.ErrorPath)
+				}
+				return res, nil
+			}
+		}
+	}
+
+	// Check the file set
+	eventBytes, err := getFileSetEvent(service, eventBytes, false)
+	if err != nil {
+		return nil, types.NewCodedError(types.ErrStorageConnectionFailed, err)
+	}
+	if eventBytes == nil {
+		res.Status = types.SimulationWaiting
+		res.Reason = "waiting for the rest of the file set"
+		return res, nil
+	}
+
+	// Render the job (with a batch of a single event if the service processes events in batches)
+	res.Status = types.SimulationDelivered
+	res.Event = string(eventBytes)
+	if service.HasBatch() {
+		res.Status = types.SimulationBatched
+		res.Reason = fmt.Sprintf("the event would be aggregated in a batch (size: %d, window: %ds)", service.Batch.Size, service.Batch.Window)
+		res.Event = joinEvents([]string{res.Event})
+	}
+	res.Job, err = makeJob(d.cfg, service, res.Event)
+	if err != nil {
+		return nil, types.NewCodedError(types.ErrJobCreateFailed, err)
+	}
+
+	// Routing of the job following the service's placement policy
+	if service.Placement != nil {
+		for _, tier := range service.Placement.SelectTiers(bucket, key) {
+			res.Tiers = append(res.Tiers, tier.Name)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDispatchInputLimits(t *testing.T) {
+	service := &types.Service{
+		Name:   "test",
+		Image:  "busybox",
+		Labels: map[string]string{types.ServiceLabel: "test"},
+		Input: []types.StorageIOConfig{
+			{Provider: "minio.default", Path: "limited", MaxInFlight: 1},
+			{Provider: "minio.default", Path: "queued", MaxInFlight: 1, LimitPolicy: types.LimitPolicyQueue},
+			{Provider: "minio.default", Path: "other"},
+		},
+	}
+	// A running job of each limited input
+	kubeClientset := testclient.NewSimpleClientset()
+	for _, input := range []string{"0", "1"} {
+		kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).Create(context.TODO(), &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "running-" + input,
+				Namespace:         testConfigValidRun.ServicesNamespace,
+				Labels:            map[string]string{types.ServiceLabel: "test", types.InputLabelKey: input},
+				CreationTimestamp: metav1.Now(),
+			},
+		}, metav1.CreateOptions{})
+	}
+	d := MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)
+
+	scenarios := []struct {
+		name           string
+		bucket         string
+		expectedStatus dispatchStatus
+	}{
+		{"limit reached (drop)", "limited", eventDiscarded},
+		{"limit reached (queue)", "queued", eventDelivered},
+		{"input without limits", "other", eventDelivered},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			event, _ := json.Marshal(types.NewMinIOEvent(s.bucket, types.MinIOEventObject{Key: "file.txt"}, time.Now()))
+			status, detail, err := d.dispatch(service, event)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status != s.expectedStatus {
+				t.Fatalf("expecting status %d, got %d (%s)", s.expectedStatus, status, detail)
+			}
+			if status == eventDelivered {
+				job, _ := kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).Get(context.TODO(), detail, metav1.GetOptions{})
+				queued := job.Spec.Suspend != nil && *job.Spec.Suspend
+				if queued != (s.bucket == "queued") {
+					t.Errorf("expecting the job queued %v, got %v", s.bucket == "queued", queued)
+				}
+			}
+		})
+	}
+}
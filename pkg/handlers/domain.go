@@ -0,0 +1,99 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// MakeExposedDomainsListHandler makes a handler to list the custom domains of an exposed service
+func MakeExposedDomainsListHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+
+		domains, err := utils.ListExposedDomains(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name)
+		if err != nil {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, domains)
+	}
+}
+
+// MakeExposedDomainSetHandler makes a handler to attach a custom domain to an exposed service (or update its
+// annotations and TLS), served by its own Ingress with the certificate requested to cert-manager
+func MakeExposedDomainSetHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domain := types.ExposedDomain{}
+		if err := c.ShouldBindJSON(&domain); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The domain specification is not valid: %v", err))
+			return
+		}
+		domain.Host = c.Param("domain")
+		domain.TLSSecret = ""
+		if err := domain.Validate(cfg); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+		if service.Expose.Port == 0 {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The service \"%s\" is not exposed", service.Name))
+			return
+		}
+
+		if err := utils.SetExposedDomain(cfg, back.GetKubeClientset(), service, &domain); err != nil {
+			sendCodedError(c, err, types.ErrServiceUpdateFailed)
+			return
+		}
+
+		if domain.TLS {
+			domain.Issuer = domain.GetIssuer(cfg)
+			domain.TLSSecret = types.DomainTLSSecretName(service.Name, domain.Host)
+		}
+		c.JSON(http.StatusOK, domain)
+	}
+}
+
+// MakeExposedDomainDeleteHandler makes a handler to detach a custom domain from an exposed service
+func MakeExposedDomainDeleteHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+
+		if err := utils.DeleteExposedDomain(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name, c.Param("domain")); err != nil {
+			sendCodedError(c, err, types.ErrServiceUpdateFailed)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExposedDomainHandlers(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.CertManagerIssuer = "letsencrypt"
+	back := backends.MakeMemoryBackend()
+	exposed := types.Service{Name: "test"}
+	exposed.Expose.Port = 8080
+	back.CreateService(exposed)
+	other := types.Service{Name: "other"}
+	other.Expose.Port = 8080
+	back.CreateService(other)
+	back.CreateService(types.Service{Name: "notexposed"})
+	kubeClientset := back.GetKubeClientset()
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/domains", MakeExposedDomainsListHandler(&cfg, back))
+	r.PUT("/system/services/:serviceName/domains/:domain", MakeExposedDomainSetHandler(&cfg, back))
+	r.DELETE("/system/services/:serviceName/domains/:domain", MakeExposedDomainDeleteHandler(&cfg, back))
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"invalid body", "PUT", "/system/services/test/domains/app.example.org", `{"tls": "yes"}`, http.StatusBadRequest},
+		{"invalid domain", "PUT", "/system/services/test/domains/app", `{}`, http.StatusBadRequest},
+		{"service not found", "PUT", "/system/services/missing/domains/app.example.org", `{}`, http.StatusNotFound},
+		{"service not exposed", "PUT", "/system/services/notexposed/domains/app.example.org", `{}`, http.StatusBadRequest},
+		{"domain not found", "DELETE", "/system/services/test/domains/app.example.org", "", http.StatusNotFound},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if w := request(s.method, s.path, s.body); w.Code != s.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The domain is attached with TLS
+	w := request("PUT", "/system/services/test/domains/app.example.org", `{"tls": true, "annotations": {"nginx.ingress.kubernetes.io/proxy-body-size": "8m"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	ingress, err := kubeClientset.NetworkingV1().Ingresses(cfg.ServicesNamespace).Get(context.TODO(), types.DomainIngressName("test", "app.example.org"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ingress.Spec.Rules[0].Host != "app.example.org" || ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name != "test-svc" {
+		t.Errorf("unexpected ingress rules: %+v", ingress.Spec.Rules)
+	}
+	if ingress.Annotations["cert-manager.io/cluster-issuer"] != "letsencrypt" || ingress.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"] != "8m" {
+		t.Errorf("unexpected ingress annotations: %v", ingress.Annotations)
+	}
+	if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != types.DomainTLSSecretName("test", "app.example.org") {
+		t.Errorf("unexpected ingress TLS: %+v", ingress.Spec.TLS)
+	}
+
+	// The domain can't be used by other services
+	if w := request("PUT", "/system/services/other/domains/app.example.org", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	// The domain is updated without TLS
+	if w := request("PUT", "/system/services/test/domains/app.example.org", `{}`); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request("GET", "/system/services/test/domains", "")
+	domains := []types.ExposedDomain{}
+	if err := json.Unmarshal(w.Body.Bytes(), &domains); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(domains) != 1 || domains[0].Host != "app.example.org" || domains[0].TLS || len(domains[0].Annotations) != 0 {
+		t.Errorf("unexpected domains: %s", w.Body.String())
+	}
+
+	// The domain is detached
+	if w := request("DELETE", "/system/services/test/domains/app.example.org", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if w := request("GET", "/system/services/test/domains", ""); w.Body.String() != "[]" {
+		t.Errorf("expected no domains, got %s", w.Body.String())
+	}
+}
+
+func TestExposedDomainServiceNamespace(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.VONamespaces = true
+	cfg.CertManagerIssuer = "letsencrypt"
+	back := backends.MakeMemoryBackend()
+	exposed := types.Service{Name: "test", Namespace: "oscar-svc-vo"}
+	exposed.Expose.Port = 8080
+	back.CreateService(exposed)
+	other := types.Service{Name: "other"}
+	other.Expose.Port = 8080
+	back.CreateService(other)
+	kubeClientset := back.GetKubeClientset()
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/domains", MakeExposedDomainsListHandler(&cfg, back))
+	r.PUT("/system/services/:serviceName/domains/:domain", MakeExposedDomainSetHandler(&cfg, back))
+	r.DELETE("/system/services/:serviceName/domains/:domain", MakeExposedDomainDeleteHandler(&cfg, back))
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The Ingress is created in the namespace of the service, where its Kubernetes service lives
+	if w := request("PUT", "/system/services/test/domains/app.example.org", `{"tls": true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	name := types.DomainIngressName("test", "app.example.org")
+	if _, err := kubeClientset.NetworkingV1().Ingresses("oscar-svc-vo").Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected the ingress in the namespace of the service: %v", err)
+	}
+	if _, err := kubeClientset.NetworkingV1().Ingresses(cfg.ServicesNamespace).Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+		t.Errorf("unexpected ingress in the services namespace")
+	}
+
+	// The domain can't be used by the services of other namespaces
+	if w := request("PUT", "/system/services/other/domains/app.example.org", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	if w := request("GET", "/system/services/test/domains", ""); !strings.Contains(w.Body.String(), "app.example.org") {
+		t.Errorf("expected the domain to be listed, got %s", w.Body.String())
+	}
+	if w := request("DELETE", "/system/services/test/domains/app.example.org", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// isDryRun checks if the request has the "dry_run" querystring set to true
+func isDryRun(c *gin.Context) bool {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	return dryRun
+}
+
+// dryRunService checks the creation (or update) of the service in the backend without persisting it.
+// The backends not implementing the DryRunBackend interface are not checked
+func dryRunService(back types.ServerlessBackend, service *types.Service, update bool) error {
+	dryRunBack, ok := back.(types.DryRunBackend)
+	if !ok {
+		return nil
+	}
+
+	err := dryRunBack.DryRunService(*service, update)
+	switch {
+	case err == nil:
+		return nil
+	case k8sErrors.IsAlreadyExists(err):
+		return types.NewCodedError(types.ErrServiceAlreadyExists, errors.New("A service with the provided name already exists"))
+	case k8sErrors.IsNotFound(err) || k8sErrors.IsGone(err):
+		return types.NewCodedError(types.ErrServiceNotFound, fmt.Errorf("the service \"%s\" does not exist", service.Name))
+	case k8sErrors.IsInvalid(err) || k8sErrors.IsBadRequest(err):
+		return types.NewCodedError(types.ErrInvalidServiceDefinition, fmt.Errorf("the service specification is not valid: %v", err))
+	case update:
+		return types.NewCodedError(types.ErrServiceUpdateFailed, fmt.Errorf("Error updating the service: %v", err))
+	default:
+		return types.NewCodedError(types.ErrServiceCreateFailed, fmt.Errorf("Error creating the service: %v", err))
+	}
+}
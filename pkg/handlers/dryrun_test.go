@@ -0,0 +1,144 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDryRun(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "existing", Image: "busybox", Script: "ls", Memory: "1Gi", Token: "token"})
+
+	r := gin.Default()
+	r.POST("/system/services", MakeCreateHandler(&testConfigValidRun, back))
+	r.PUT("/system/services", MakeUpdateHandler(&testConfigValidRun, back))
+	r.PATCH("/system/services/:serviceName", MakePatchHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name              string
+		method            string
+		path              string
+		body              string
+		backendErr        error
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"create", "POST", "/system/services?dry_run=true", `{"name": "new", "image": "busybox", "script": "ls"}`, nil, http.StatusOK, ""},
+		{"create existing", "POST", "/system/services?dry_run=true", `{"name": "existing", "image": "busybox", "script": "ls"}`, nil, http.StatusConflict, types.ErrServiceAlreadyExists.Code},
+		{"create invalid storage provider", "POST", "/system/services?dry_run=true", `{"name": "new", "image": "busybox", "script": "ls", "output": [{"storage_provider": "s3.undefined", "path": "out"}]}`, nil, http.StatusBadRequest, types.ErrStorageProviderNotDefined.Code},
+		{"create rejected by the backend", "POST", "/system/services?dry_run=true", `{"name": "new", "image": "busybox", "script": "ls"}`, k8serr.NewInvalid(schema.GroupKind{Kind: "PodTemplate"}, "new", nil), http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code},
+		{"create upload session", "POST", "/system/services?dry_run=true&upload=true", `{"name": "new", "image": "busybox"}`, nil, http.StatusBadRequest, types.ErrBadRequest.Code},
+		{"update", "PUT", "/system/services?dry_run=true", `{"name": "existing", "image": "busybox", "script": "ls"}`, nil, http.StatusOK, ""},
+		{"update not found", "PUT", "/system/services?dry_run=true", `{"name": "new", "image": "busybox", "script": "ls"}`, nil, http.StatusNotFound, types.ErrServiceNotFound.Code},
+		{"patch", "PATCH", "/system/services/existing?dry_run=true", `{"memory": "2Gi"}`, nil, http.StatusOK, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if s.backendErr != nil {
+				back.AddError("DryRunService", s.backendErr)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, strings.NewReader(s.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+
+			if s.expectedCode == http.StatusOK {
+				var service types.Service
+				if err := json.Unmarshal(w.Body.Bytes(), &service); err != nil {
+					t.Fatalf("error decoding the service: %v", err)
+				}
+				// The service is returned with its default values
+				if service.LogLevel != defaultLogLevel || service.Labels[types.ServiceLabel] != service.Name {
+					t.Errorf("the service has not been defaulted: %+v", service)
+				}
+			}
+
+			// Nothing is persisted
+			if _, err := back.ReadService("new"); err == nil {
+				t.Error("the service has been created")
+			}
+			if existing, _ := back.ReadService("existing"); existing.Memory != "1Gi" || existing.Token != "token" {
+				t.Errorf("the service has been updated: %+v", existing)
+			}
+		})
+	}
+}
+
+func TestDefaultOutputProvider(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.DefaultOutputProvider = "minio.default"
+	cfg.DefaultOutputPath = "outputs/{service}"
+	back := backends.MakeMemoryBackend()
+
+	r := gin.Default()
+	r.POST("/system/services", MakeCreateHandler(&cfg, back))
+
+	body := `{"name": "new", "image": "busybox", "script": "ls", "output": [{}, {"path": "results/"}, {"storage_provider": "minio.default", "path": "out"}]}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/system/services?dry_run=true", strings.NewReader(body))
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var service types.Service
+	if err := json.Unmarshal(w.Body.Bytes(), &service); err != nil {
+		t.Fatalf("error decoding the service: %v", err)
+	}
+	expected := []types.StorageIOConfig{
+		{Provider: "minio.default", Path: "outputs/new"},
+		{Provider: "minio.default", Path: "results/"},
+		{Provider: "minio.default", Path: "out"},
+	}
+	if len(service.Output) != len(expected) {
+		t.Fatalf("expecting the outputs %+v, got %+v", expected, service.Output)
+	}
+	for i, out := range service.Output {
+		if out.Provider != expected[i].Provider || out.Path != expected[i].Path {
+			t.Errorf("expecting the output %+v, got %+v", expected[i], out)
+		}
+	}
+
+	// Without default provider the outputs must declare their provider
+	cfg.DefaultOutputProvider = ""
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/system/services?dry_run=true", strings.NewReader(body))
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || w.Header().Get(errorCodeHeader) != types.ErrStorageProviderNotDefined.Code {
+		t.Errorf("expecting the error %s, got %d: %s", types.ErrStorageProviderNotDefined.Code, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// MakeErrorsHandler makes a handler for listing the API error catalog
+func MakeErrorsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, types.GetErrorCatalog())
+	}
+}
+
+// errorCodeHeader header with the error code, set in all the error responses
+const errorCodeHeader = "X-Oscar-Error-Code"
+
+// sendError aborts the request returning the error of code. The structured error body is only returned
+// to the clients accepting JSON ("Accept: application/json"), the rest receive the message as plain text
+func sendError(c *gin.Context, code types.ErrorCode, message string) {
+	c.Header(errorCodeHeader, code.Code)
+	if c.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON) == gin.MIMEJSON {
+		c.AbortWithStatusJSON(code.Status, types.NewAPIError(code, message))
+		return
+	}
+
+	c.Abort()
+	if message == "" {
+		c.Status(code.Status)
+		return
+	}
+	c.String(code.Status, message)
+}
+
+// sendErrorDetails aborts the request as sendError, adding the list of issues found (e.g. the fields of an
+// invalid body) to the structured error body or, in plain text, one per line after the message
+func sendErrorDetails(c *gin.Context, code types.ErrorCode, message string, details []string) {
+	c.Header(errorCodeHeader, code.Code)
+	if c.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON) == gin.MIMEJSON {
+		apiErr := types.NewAPIError(code, message)
+		apiErr.Details = details
+		c.AbortWithStatusJSON(code.Status, apiErr)
+		return
+	}
+
+	c.Abort()
+	if message == "" {
+		message = code.Description
+	}
+	c.String(code.Status, strings.Join(append([]string{message}, details...), "\n"))
+}
+
+// sendCodedError aborts the request returning the structured error body of
+// err, using defaultCode if err has not been annotated with an ErrorCode
+func sendCodedError(c *gin.Context, err error, defaultCode types.ErrorCode) {
+	sendError(c, types.GetErrorCode(err, defaultCode), err.Error())
+}
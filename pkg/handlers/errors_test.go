@@ -0,0 +1,103 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeErrorsHandler(t *testing.T) {
+	r := gin.Default()
+	r.GET("/system/errors", MakeErrorsHandler())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/errors", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expecting code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	catalog := []types.ErrorCode{}
+	if err := json.Unmarshal(w.Body.Bytes(), &catalog); err != nil {
+		t.Fatalf("unable to parse the error catalog: %v", err)
+	}
+
+	codes := map[string]bool{}
+	for _, code := range catalog {
+		if codes[code.Code] {
+			t.Errorf("duplicated error code %s", code.Code)
+		}
+		codes[code.Code] = true
+	}
+}
+
+func TestStructuredErrorBody(t *testing.T) {
+	back := backends.MakeFakeBackend()
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName", MakeReadHandler(&testConfigValidRun, back))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/services/test?include=foo", nil)
+	req.Header.Set("Accept", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != types.ErrBadRequest.Status {
+		t.Errorf("expecting code %d, got %d", types.ErrBadRequest.Status, w.Code)
+	}
+
+	apiErr := types.APIError{}
+	if err := json.Unmarshal(w.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("unable to parse the error body: %v", err)
+	}
+	if apiErr.Code != types.ErrBadRequest.Code {
+		t.Errorf("expecting error code %s, got %s", types.ErrBadRequest.Code, apiErr.Code)
+	}
+}
+
+func TestPlainTextErrorBody(t *testing.T) {
+	back := backends.MakeFakeBackend()
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName", MakeReadHandler(&testConfigValidRun, back))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/services/test?include=foo", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != types.ErrBadRequest.Status {
+		t.Errorf("expecting code %d, got %d", types.ErrBadRequest.Status, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("expecting plain text body, got %s", contentType)
+	}
+	if code := w.Header().Get(errorCodeHeader); code != types.ErrBadRequest.Code {
+		t.Errorf("expecting error code header %s, got %s", types.ErrBadRequest.Code, code)
+	}
+	if w.Body.Len() == 0 {
+		t.Error("expecting the error message in the body")
+	}
+}
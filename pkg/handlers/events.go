@@ -0,0 +1,131 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+const (
+	// eventsPingInterval interval of the pings that keep alive the WebSocket connections
+	eventsPingInterval = 30 * time.Second
+	// eventsWriteTimeout time to write a message to a WebSocket connection
+	eventsWriteTimeout = 10 * time.Second
+)
+
+var eventsUpgrader = websocket.Upgrader{}
+
+// MakeEventsWebSocketHandler makes a handler for pushing the lifecycle events of the services (job created, succeeded
+// or failed and service created, updated or deleted) over WebSocket. The events can be filtered by service with the
+// 'service' querystring (comma-separated) and updated with subscription messages sent by the client. Only the events
+// of the services accessible by the user are sent
+func MakeEventsWebSocketHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		access := newServiceAccessCache(c, cfg, back)
+		filter := serviceFilter{}
+		filter.update(types.LifecycleSubscription{Subscribe: strings.Split(c.Query("service"), ",")})
+
+		conn, err := eventsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// The upgrader replies with the error
+			return
+		}
+		defer conn.Close()
+
+		events, unsubscribe := utils.SubscribeLifecycleEvents()
+		defer unsubscribe()
+
+		// Read the subscription updates until the connection is closed
+		updates := make(chan types.LifecycleSubscription)
+		closed := make(chan struct{})
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			defer close(closed)
+			for {
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				var sub types.LifecycleSubscription
+				if err := json.Unmarshal(msg, &sub); err != nil {
+					continue
+				}
+				select {
+				case updates <- sub:
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		ping := time.NewTicker(eventsPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case event := <-events:
+				if !filter.matches(event.Service) {
+					continue
+				}
+				// The access is read again when the service is created or updated (e.g. its owner changes)
+				refresh := event.Type == types.ServiceCreatedEvent || event.Type == types.ServiceUpdatedEvent
+				if !access.canAccess(event.Service, refresh) {
+					continue
+				}
+				conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case sub := <-updates:
+				filter.update(sub)
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventsWriteTimeout)); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}
+}
+
+// serviceFilter services whose events are pushed to a subscriber (all of them if empty)
+type serviceFilter map[string]bool
+
+// update adds and removes services from the filter
+func (filter serviceFilter) update(sub types.LifecycleSubscription) {
+	for _, name := range sub.Subscribe {
+		if name = strings.TrimSpace(name); name != "" {
+			filter[name] = true
+		}
+	}
+	for _, name := range sub.Unsubscribe {
+		delete(filter, strings.TrimSpace(name))
+	}
+}
+
+// matches checks if the events of a service pass the filter
+func (filter serviceFilter) matches(serviceName string) bool {
+	return len(filter) == 0 || filter[serviceName]
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+func TestMakeEventsWebSocketHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Owner: "user"})
+	back.CreateService(types.Service{Name: "new"})
+	back.CreateService(types.Service{Name: "other", Owner: "other"})
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, "user")
+	})
+	r.GET("/system/events/ws", MakeEventsWebSocketHandler(&testConfigValidRun, back))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/system/events/ws?service=test", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	// Publish the events until the subscription is active
+	read := func(expected types.LifecycleEvent) {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		received := make(chan types.LifecycleEvent)
+		go func() {
+			var event types.LifecycleEvent
+			if err := conn.ReadJSON(&event); err == nil {
+				received <- event
+			}
+			close(received)
+		}()
+		for {
+			utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.JobCreatedEvent, Service: "other", Job: "job"})
+			utils.PublishLifecycleEvent(expected)
+			select {
+			case event, ok := <-received:
+				if !ok {
+					t.Fatalf("expecting the event %+v", expected)
+				}
+				if event.Type != expected.Type || event.Service != expected.Service || event.Job != expected.Job {
+					t.Fatalf("expecting the event %+v, got %+v", expected, event)
+				}
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	read(types.LifecycleEvent{Type: types.JobSucceededEvent, Service: "test", Job: "job"})
+
+	// Subscribe to the events of other services, unsubscribing from the current one. The events of the services of
+	// other owners are not sent
+	if err := conn.WriteJSON(types.LifecycleSubscription{Subscribe: []string{"new", "other"}, Unsubscribe: []string{"test"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	read(types.LifecycleEvent{Type: types.ServiceUpdatedEvent, Service: "new"})
+}
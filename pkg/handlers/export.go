@@ -0,0 +1,121 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// defaultExportClusterID identifier of the cluster in the exported FDL if the service has not a ClusterID
+const defaultExportClusterID = "oscar"
+
+// MakeExportHandler makes a handler for exporting a service as a portable FDL document, with its credentials
+// replaced by references to a secret that must be created in the target cluster
+func MakeExportHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		exported, keys, err := service.Export()
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error exporting the service: %v", err))
+			return
+		}
+
+		clusterID := service.ClusterID
+		if clusterID == "" {
+			clusterID = defaultExportClusterID
+		}
+		fdl := types.FDL{}
+		fdl.Functions.OSCAR = []map[string]*types.Service{{clusterID: exported}}
+		fdlBytes, err := types.YAMLMarshal(fdl)
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error exporting the service: %v", err))
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.yaml\"", service.Name))
+		c.Data(http.StatusOK, "application/yaml", append([]byte(exportHeader(service.Name, keys)), fdlBytes...))
+	}
+}
+
+// exportHeader returns the comment of the exported FDL describing the secret referenced by the credentials
+func exportHeader(serviceName string, keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# The credentials of the service reference the secret \"%s\", which must be created in the services\n", types.ExportSecretName(serviceName))
+	fmt.Fprintf(&sb, "# namespace of the target cluster with the label \"%s=%s\" and the keys:\n", types.ServiceLabel, serviceName)
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "#   - %s\n", key)
+	}
+	return sb.String()
+}
+
+// MakeImportHandler makes a handler for creating the services of an FDL document (YAML or JSON), such as the ones
+// produced by the export handler. The credentials referencing a secret ("secret://<name>/<key>") are resolved and
+// the services are created transactionally
+func MakeImportHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Error reading the request body: %v", err))
+			return
+		}
+		services, err := readImportServices(body)
+		if err != nil {
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: %v", err))
+			return
+		}
+
+		for _, service := range services {
+			if err := utils.ResolveSecretReferences(back.GetKubeClientset(), cfg.ServicesNamespace, service); err != nil {
+				sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("Service \"%s\": %v", service.Name, err))
+				return
+			}
+		}
+
+		createServices(c, cfg, back, services)
+	}
+}
+
+// readImportServices decodes the services of an FDL document (YAML or JSON)
+func readImportServices(body []byte) ([]*types.Service, error) {
+	jsonBody, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	return readBulkServices(jsonBody)
+}
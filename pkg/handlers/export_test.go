@@ -0,0 +1,110 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeExportHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{
+		Name:      "test",
+		ClusterID: "cluster",
+		Image:     "test-image",
+		Token:     "service-token",
+		StorageProviders: &types.StorageProviders{
+			MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: {Endpoint: "http://minio", SecretKey: "minio-secret"}},
+			S3:    map[string]*types.S3Provider{"aws": {AccessKey: "access", SecretKey: "s3-secret"}},
+		},
+	})
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/export", MakeExportHandler(back))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/services/test/export", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"# The credentials of the service reference the secret \"test-credentials\"", "#   - s3.aws.secret_key", "cluster:", "secret://test-credentials/s3.aws.secret_key", "test-image"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expecting \"%s\" in the exported FDL: %s", expected, body)
+		}
+	}
+	for _, unexpected := range []string{"s3-secret", "minio-secret", "service-token"} {
+		if strings.Contains(body, unexpected) {
+			t.Errorf("unexpected \"%s\" in the exported FDL: %s", unexpected, body)
+		}
+	}
+
+	// The exported document must be readable by the import handler
+	services, err := readImportServices([]byte(body))
+	if err != nil || len(services) != 1 || services[0].Name != "test" {
+		t.Errorf("unexpected imported services: %v (%v)", services, err)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/system/services/missing/export", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expecting code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestMakeImportHandler(t *testing.T) {
+	scenarios := []struct {
+		name string
+		body string
+	}{
+		{"invalid document", "functions: ["},
+		{"no services", "functions:\n  oscar: []\n"},
+		{"missing secret", "functions:\n  oscar:\n  - cluster:\n      name: test\n      image: test\n      script: test\n      storage_providers:\n        s3:\n          aws:\n            access_key: access\n            secret_key: secret://test-credentials/s3.aws.secret_key\n"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			back := backends.MakeMemoryBackend()
+			r := gin.Default()
+			r.POST("/system/services/import", MakeImportHandler(&testConfigValidRun, back))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/services/import", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expecting code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			if w.Header().Get(errorCodeHeader) != types.ErrInvalidServiceDefinition.Code {
+				t.Errorf("expecting error code %s, got %s", types.ErrInvalidServiceDefinition.Code, w.Header().Get(errorCodeHeader))
+			}
+			if services, _ := back.ListServices(); len(services) != 0 {
+				t.Errorf("expecting no services, got %d", len(services))
+			}
+		})
+	}
+}
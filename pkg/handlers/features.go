@@ -0,0 +1,82 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MakeFeaturesHandler makes a handler to set (or remove, with a null value) feature flags of a service at runtime.
+// Only the stored definition of the service (and its features.env file) is updated if the backend implements
+// the DefinitionBackend interface, so the service is not redeployed
+func MakeFeaturesHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var changes map[string]*string
+		if err := c.ShouldBindJSON(&changes); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The request is not valid: %v", err))
+			return
+		}
+		for name := range changes {
+			if err := types.ValidateFeatureName(name); err != nil {
+				sendError(c, types.ErrBadRequest, err.Error())
+				return
+			}
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		if service.Features == nil {
+			service.Features = map[string]string{}
+		}
+		for name, value := range changes {
+			if value == nil {
+				delete(service.Features, name)
+			} else {
+				service.Features[name] = *value
+			}
+		}
+
+		if defBack, ok := back.(types.DefinitionBackend); ok {
+			err = defBack.UpdateServiceDefinition(*service)
+		} else {
+			err = back.UpdateService(*service)
+		}
+		if err != nil {
+			sendError(c, types.ErrServiceUpdateFailed, fmt.Sprintf("Error updating the service: %v", err))
+			return
+		}
+
+		utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceUpdatedEvent, Service: service.Name})
+
+		c.JSON(http.StatusOK, service.Features)
+	}
+}
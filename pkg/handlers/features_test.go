@@ -0,0 +1,83 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeFeaturesHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	if err := back.CreateService(types.Service{Name: "test", Features: map[string]string{"old_path": "true", "beta": "false"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.PATCH("/system/services/:serviceName/features", MakeFeaturesHandler(back))
+
+	scenarios := []struct {
+		name         string
+		service      string
+		body         string
+		expectedCode int
+		expected     map[string]string
+	}{
+		{"invalid body", "test", `["beta"]`, http.StatusBadRequest, nil},
+		{"invalid name", "test", `{"new-model": "true"}`, http.StatusBadRequest, nil},
+		{"service not found", "other", `{"beta": "true"}`, http.StatusNotFound, nil},
+		{"set and remove", "test", `{"beta": "true", "new_model": "on", "old_path": null}`, http.StatusOK, map[string]string{"beta": "true", "new_model": "on"}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PATCH", "/system/services/"+s.service+"/features", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+			if s.expected == nil {
+				return
+			}
+
+			var features map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &features); err != nil {
+				t.Fatal(err)
+			}
+			service, _ := back.ReadService(s.service)
+			for _, f := range []map[string]string{features, service.Features} {
+				if len(f) != len(s.expected) {
+					t.Errorf("expecting features %v, got %v", s.expected, f)
+				}
+				for k, v := range s.expected {
+					if f[k] != v {
+						t.Errorf("expecting features %v, got %v", s.expected, f)
+					}
+				}
+			}
+		})
+	}
+}
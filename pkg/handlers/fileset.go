@@ -0,0 +1,135 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// fileSetTTL time during which a completed file set (with the same objects) is not triggered again,
+// avoiding duplicated jobs when the last members of the set are uploaded simultaneously
+const fileSetTTL = 10 * time.Minute
+
+var completedFileSets = struct {
+	sync.Mutex
+	sets map[string]time.Time
+}{sets: map[string]time.Time{}}
+
+// checkFileSet returns the event to be processed if the MinIO event belongs to an input with a file set.
+// The returned event contains a record for each member of the set, and is nil if the set is not complete yet
+func checkFileSet(service *types.Service, eventBytes []byte) ([]byte, error) {
+	return getFileSetEvent(service, eventBytes, true)
+}
+
+// getFileSetEvent returns the event of the file set of the MinIO event (see checkFileSet). If register is false
+// the completed set is not registered, so it can be triggered again (e.g. to simulate the event)
+func getFileSetEvent(service *types.Service, eventBytes []byte, register bool) ([]byte, error) {
+	minIOEvent, err := types.ParseMinIOEvent(eventBytes)
+	if err != nil {
+		// Not a MinIO event
+		return eventBytes, nil
+	}
+
+	bucket := minIOEvent.GetBucket()
+	in := service.GetInputForObject(bucket, minIOEvent.GetObjectKey())
+	if in == nil || len(in.FileSet) == 0 {
+		return eventBytes, nil
+	}
+
+	name, keys, ok := in.MatchFileSet(minIOEvent.GetObjectKey())
+	if !ok {
+		// The object is not member of any file set
+		return nil, nil
+	}
+
+	_, provID := in.GetProvider()
+	if service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+		return nil, fmt.Errorf("the StorageProvider \"%s\" is not defined", in.Provider)
+	}
+	s3Client := service.StorageProviders.MinIO[provID].GetS3Client()
+
+	// Check that all the members of the set exist
+	objects := []types.MinIOEventObject{}
+	etags := []string{}
+	for _, key := range keys {
+		head, err := s3Client.HeadObject(&s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("error getting the object \"%s/%s\": %v", bucket, key, err)
+		}
+		etag := strings.Trim(aws.StringValue(head.ETag), "\"")
+		objects = append(objects, types.MinIOEventObject{
+			Key:         key,
+			Size:        aws.Int64Value(head.ContentLength),
+			ETag:        etag,
+			ContentType: aws.StringValue(head.ContentType),
+		})
+		etags = append(etags, etag)
+	}
+
+	if register && !markFileSetCompleted(fmt.Sprintf("%s/%s/%s/%s", service.Name, bucket, name, strings.Join(etags, ","))) {
+		return nil, nil
+	}
+
+	// Make an event with a record for each member of the set
+	event := &types.MinIOEvent{}
+	now := time.Now()
+	for _, object := range objects {
+		objectEvent := types.NewMinIOEvent(bucket, object, now)
+		if event.EventName == "" {
+			event.EventName = objectEvent.EventName
+			event.Key = objectEvent.Key
+		}
+		event.Records = append(event.Records, objectEvent.Records...)
+	}
+
+	return json.Marshal(event)
+}
+
+// markFileSetCompleted registers a completed file set, returning false if it was already registered
+func markFileSetCompleted(id string) bool {
+	completedFileSets.Lock()
+	defer completedFileSets.Unlock()
+
+	now := time.Now()
+	for setID, t := range completedFileSets.sets {
+		if now.Sub(t) > fileSetTTL {
+			delete(completedFileSets.sets, setID)
+		}
+	}
+
+	if _, ok := completedFileSets.sets[id]; ok {
+		return false
+	}
+	completedFileSets.sets[id] = now
+
+	return true
+}
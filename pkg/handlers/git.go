@@ -0,0 +1,129 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MakeGitSyncHandler makes a handler to re-sync the script of a service from its Git repository.
+// Intended to be configured as a push webhook in the Git provider, it accepts the service's token as bearer token,
+// GitLab's "X-Gitlab-Token" header or GitHub's "X-Hub-Signature-256" header (using the token as webhook secret)
+func MakeGitSyncHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if k8sErrors.IsNotFound(err) || k8sErrors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		if !isGitWebhookAuthorized(c.Request, body, service.Token) {
+			sendError(c, types.ErrUnauthorized, "")
+			return
+		}
+
+		if service.ScriptGit == nil || !service.ScriptGit.SyncOnWebhook {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The service \"%s\" has not enabled the script sync on webhook", service.Name))
+			return
+		}
+
+		script, err := utils.FetchGitScript(service.Name, service.ScriptGit, back.GetKubeClientset(), cfg.GetServiceNamespace(service))
+		if err != nil {
+			sendError(c, types.ErrScriptFetchFailed, err.Error())
+			return
+		}
+
+		if script == service.Script {
+			c.String(http.StatusOK, "The script is up to date")
+			return
+		}
+
+		service.Script = script
+		if err := back.UpdateService(*service); err != nil {
+			sendError(c, types.ErrServiceUpdateFailed, fmt.Sprintf("Error updating the service: %v", err))
+			return
+		}
+		log.Printf("Script of service \"%s\" synced from \"%s\"\n", service.Name, service.ScriptGit.URL)
+
+		c.String(http.StatusOK, "The script has been updated")
+	}
+}
+
+// setServiceScript fetches the script of the service from its Git repository (if defined)
+func setServiceScript(service *types.Service, cfg *types.Config, back types.ServerlessBackend) error {
+	if service.ScriptGit == nil {
+		if service.Script == "" {
+			return types.NewCodedError(types.ErrInvalidServiceDefinition, errors.New("the service specification is not valid: the script or script_git field is required"))
+		}
+		return nil
+	}
+
+	script, err := utils.FetchGitScript(service.Name, service.ScriptGit, back.GetKubeClientset(), cfg.GetServiceNamespace(service))
+	if err != nil {
+		return types.NewCodedError(types.ErrScriptFetchFailed, err)
+	}
+	service.Script = script
+
+	return nil
+}
+
+// isGitWebhookAuthorized checks if the webhook request has been signed with the service's token
+func isGitWebhookAuthorized(req *http.Request, body []byte, token string) bool {
+	// Bearer token
+	if splitToken := strings.Split(req.Header.Get("Authorization"), "Bearer "); len(splitToken) == 2 {
+		return hmac.Equal([]byte(strings.TrimSpace(splitToken[1])), []byte(token))
+	}
+
+	// GitLab secret token
+	if gitlabToken := req.Header.Get("X-Gitlab-Token"); gitlabToken != "" {
+		return hmac.Equal([]byte(gitlabToken), []byte(token))
+	}
+
+	// GitHub signature
+	if signature := req.Header.Get("X-Hub-Signature-256"); strings.HasPrefix(signature, "sha256=") {
+		mac := hmac.New(sha256.New, []byte(token))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(strings.TrimPrefix(signature, "sha256=")), []byte(expected))
+	}
+
+	return false
+}
@@ -0,0 +1,62 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestIsGitWebhookAuthorized(t *testing.T) {
+	token := "AbCdEf123456"
+	body := []byte(`{"ref": "refs/heads/main"}`)
+
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	scenarios := []struct {
+		name       string
+		header     string
+		value      string
+		authorized bool
+	}{
+		{"bearer token", "Authorization", "Bearer " + token, true},
+		{"invalid bearer token", "Authorization", "Bearer invalid", false},
+		{"gitlab token", "X-Gitlab-Token", token, true},
+		{"invalid gitlab token", "X-Gitlab-Token", "invalid", false},
+		{"github signature", "X-Hub-Signature-256", signature, true},
+		{"invalid github signature", "X-Hub-Signature-256", "sha256=0123", false},
+		{"no credentials", "", "", false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/git/test", nil)
+			if s.header != "" {
+				req.Header.Set(s.header, s.value)
+			}
+
+			if authorized := isGitWebhookAuthorized(req, body, token); authorized != s.authorized {
+				t.Errorf("expecting authorized %v, got %v", s.authorized, authorized)
+			}
+		})
+	}
+}
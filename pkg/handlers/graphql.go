@@ -0,0 +1,410 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// graphQLRequest body of the GraphQL requests
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLAccessKey key of the context of the GraphQL queries with the function checking if the user of the request
+// can access a service
+type graphQLAccessKey struct{}
+
+// canAccessGraphQLService checks if the user of a GraphQL query can access a service
+func canAccessGraphQLService(ctx context.Context, service *types.Service) bool {
+	canAccess, ok := ctx.Value(graphQLAccessKey{}).(func(*types.Service) bool)
+	return ok && canAccess(service)
+}
+
+// graphQLJob job of a service resolved by the GraphQL API
+type graphQLJob struct {
+	service string
+	types.JobSummary
+}
+
+// MakeGraphQLHandler makes a handler to query the services, their jobs (with their logs) and the usage of the
+// cluster through a GraphQL API, so the clients can fetch the fields they need in a single request. The queries
+// are sent in the body of POST requests or in the query parameter of GET requests
+func MakeGraphQLHandler(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	schema, schemaErr := makeGraphQLSchema(cfg, back, kubeClientset)
+	return func(c *gin.Context) {
+		if schemaErr != nil {
+			sendError(c, types.ErrInternal, schemaErr.Error())
+			return
+		}
+
+		req := graphQLRequest{}
+		if c.Request.Method == http.MethodGet {
+			req.Query = c.Query("query")
+			req.OperationName = c.Query("operationName")
+			if variables := c.Query("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+					sendError(c, types.ErrBadRequest, fmt.Sprintf("invalid variables: %v", err))
+					return
+				}
+			}
+		} else if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("invalid GraphQL request: %v", err))
+			return
+		}
+		if strings.TrimSpace(req.Query) == "" {
+			sendError(c, types.ErrBadRequest, "the query is required")
+			return
+		}
+
+		// The resolvers only return the services accessible by the user (and their jobs)
+		ctx := context.WithValue(c.Request.Context(), graphQLAccessKey{}, func(service *types.Service) bool {
+			return canAccessService(c, cfg, service)
+		})
+
+		// The errors of the query are returned in the result, as usual in GraphQL
+		c.JSON(http.StatusOK, graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        ctx,
+		}))
+	}
+}
+
+// makeGraphQLSchema returns the (read-only) schema of the GraphQL API
+func makeGraphQLSchema(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) (graphql.Schema, error) {
+	namespace := cfg.GetJobsNamespace()
+
+	labelType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Label",
+		Fields: graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	jobType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Job",
+		Description: "Job of a service",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveJob(func(job *graphQLJob) interface{} {
+				return job.Name
+			})},
+			"service": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveJob(func(job *graphQLJob) interface{} {
+				return job.service
+			})},
+			"status": &graphql.Field{Type: graphql.String, Description: "Phase of the job's pod (Pending, Running, Succeeded or Failed)",
+				Resolve: resolveJob(func(job *graphQLJob) interface{} {
+					return job.Status
+				})},
+			"creation_time": &graphql.Field{Type: graphql.String, Resolve: resolveJob(func(job *graphQLJob) interface{} {
+				return formatGraphQLTime(job.CreationTime)
+			})},
+			"start_time": &graphql.Field{Type: graphql.String, Resolve: resolveJob(func(job *graphQLJob) interface{} {
+				return formatGraphQLTime(job.StartTime)
+			})},
+			"finish_time": &graphql.Field{Type: graphql.String, Resolve: resolveJob(func(job *graphQLJob) interface{} {
+				return formatGraphQLTime(job.FinishTime)
+			})},
+			"revision": &graphql.Field{Type: graphql.Int, Resolve: resolveJob(func(job *graphQLJob) interface{} {
+				return job.Revision
+			})},
+			"logs": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Logs of the job (the archived ones once the job is removed, if enabled)",
+				Args: graphql.FieldConfigArgument{
+					"tail": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Number of lines from the end of the logs"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					job := p.Source.(*graphQLJob)
+					tail, _ := p.Args["tail"].(int)
+					return getGraphQLJobLogs(p.Context, cfg, kubeClientset, job.service, job.Name, tail)
+				},
+			},
+		},
+	})
+
+	serviceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Service",
+		Description: "OSCAR service",
+		Fields: graphql.Fields{
+			"name":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"image":    &graphql.Field{Type: graphql.String},
+			"memory":   &graphql.Field{Type: graphql.String},
+			"cpu":      &graphql.Field{Type: graphql.String},
+			"vo":       &graphql.Field{Type: graphql.String},
+			"owner":    &graphql.Field{Type: graphql.String},
+			"revision": &graphql.Field{Type: graphql.Int},
+			"labels": &graphql.Field{
+				Type: graphql.NewList(labelType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					labels := []map[string]string{}
+					for key, value := range p.Source.(*types.Service).Labels {
+						labels = append(labels, map[string]string{"key": key, "value": value})
+					}
+					sort.Slice(labels, func(i, j int) bool { return labels[i]["key"] < labels[j]["key"] })
+					return labels, nil
+				},
+			},
+			"jobs": &graphql.Field{
+				Type:        graphql.NewList(jobType),
+				Description: "Jobs of the service, newest first",
+				Args: graphql.FieldConfigArgument{
+					"status": &graphql.ArgumentConfig{Type: graphql.String, Description: "Only the jobs with this status"},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, Description: "Maximum number of jobs"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					service := p.Source.(*types.Service)
+					if !canAccessGraphQLService(p.Context, service) {
+						return nil, fmt.Errorf("the service \"%s\" is owned by another user", service.Name)
+					}
+					status, _ := p.Args["status"].(string)
+					limit, _ := p.Args["limit"].(int)
+					return listGraphQLJobs(kubeClientset, namespace, service.Name, status, limit)
+				},
+			},
+		},
+	})
+
+	accountUsageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AccountUsage",
+		Description: "Usage of a VO or a user in the quotas period",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"jobs": &graphql.Field{Type: graphql.Int, Resolve: resolveUsage(func(usage *types.AccountUsage) interface{} {
+				return usage.Jobs
+			})},
+			"cpu_seconds": &graphql.Field{Type: graphql.Float, Resolve: resolveUsage(func(usage *types.AccountUsage) interface{} {
+				return usage.CPUSeconds
+			})},
+			"memory_seconds": &graphql.Field{Type: graphql.Float, Resolve: resolveUsage(func(usage *types.AccountUsage) interface{} {
+				return usage.MemorySeconds
+			})},
+			"storage_bytes": &graphql.Field{Type: graphql.Float, Resolve: resolveUsage(func(usage *types.AccountUsage) interface{} {
+				return float64(usage.StorageBytes)
+			})},
+			"exceeded": &graphql.Field{Type: graphql.NewList(graphql.String)},
+		},
+	})
+
+	usageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Usage",
+		Fields: graphql.Fields{
+			"from": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*types.UsageSummary).From.Format(time.RFC3339), nil
+			}},
+			"to": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*types.UsageSummary).To.Format(time.RFC3339), nil
+			}},
+			"vos": &graphql.Field{Type: graphql.NewList(accountUsageType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return accountUsagePointers(p.Source.(*types.UsageSummary).VOs), nil
+			}},
+			"users": &graphql.Field{Type: graphql.NewList(accountUsageType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return accountUsagePointers(p.Source.(*types.UsageSummary).Users), nil
+			}},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"services": &graphql.Field{
+				Type:        graphql.NewList(serviceType),
+				Description: "Services of the cluster, sorted by name",
+				Args: graphql.FieldConfigArgument{
+					"vo":    &graphql.ArgumentConfig{Type: graphql.String, Description: "Only the services of this VO"},
+					"label": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String), Description: "Only the services with these labels (key=value or key)"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					opts := types.ServiceListOptions{}
+					opts.VO, _ = p.Args["vo"].(string)
+					if labels, ok := p.Args["label"].([]interface{}); ok {
+						for _, label := range labels {
+							if s, ok := label.(string); ok {
+								opts.Labels = append(opts.Labels, s)
+							}
+						}
+					}
+					services, err := back.ListServices()
+					if err != nil {
+						return nil, err
+					}
+					accessible := []*types.Service{}
+					for _, service := range services {
+						if canAccessGraphQLService(p.Context, service) {
+							accessible = append(accessible, service)
+						}
+					}
+					page, _, _ := opts.Apply(accessible)
+					return page, nil
+				},
+			},
+			"service": &graphql.Field{
+				Type: serviceType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					service, err := back.ReadService(p.Args["name"].(string))
+					if err != nil {
+						return nil, fmt.Errorf("the service \"%s\" cannot be read: %v", p.Args["name"], err)
+					}
+					if !canAccessGraphQLService(p.Context, service) {
+						return nil, fmt.Errorf("the service \"%s\" is owned by another user", service.Name)
+					}
+					return service, nil
+				},
+			},
+			"usage": &graphql.Field{
+				Type:        usageType,
+				Description: "Usage of the cluster's resources per VO and per user in the quotas period",
+				Args: graphql.FieldConfigArgument{
+					"vo":   &graphql.ArgumentConfig{Type: graphql.String},
+					"user": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					vo, _ := p.Args["vo"].(string)
+					user, _ := p.Args["user"].(string)
+					return utils.GetUsageSummary(cfg, back, kubeClientset, vo, user)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// resolveJob returns a resolver of a field of the jobs
+func resolveJob(value func(job *graphQLJob) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return value(p.Source.(*graphQLJob)), nil
+	}
+}
+
+// resolveUsage returns a resolver of a field of the account usages
+func resolveUsage(value func(usage *types.AccountUsage) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return value(p.Source.(*types.AccountUsage)), nil
+	}
+}
+
+// accountUsagePointers returns the pointers to the account usages to resolve their fields
+func accountUsagePointers(usages []types.AccountUsage) []*types.AccountUsage {
+	pointers := []*types.AccountUsage{}
+	for i := range usages {
+		pointers = append(pointers, &usages[i])
+	}
+	return pointers
+}
+
+// formatGraphQLTime returns the time in RFC 3339 format, or nil if not set
+func formatGraphQLTime(t *metav1.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// listGraphQLJobs returns the jobs of a service with the status (all if empty), newest first
+func listGraphQLJobs(kubeClientset kubernetes.Interface, namespace string, serviceName string, status string, limit int) ([]*graphQLJob, error) {
+	jobsInfo, err := getJobsInfo(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*graphQLJob{}
+	for name, info := range jobsInfo {
+		if status == "" || strings.EqualFold(info.Status, status) {
+			jobs = append(jobs, &graphQLJob{service: serviceName, JobSummary: types.JobSummary{Name: name, JobInfo: *info}})
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i].CreationTime, jobs[j].CreationTime
+		if !a.Equal(b) {
+			return b.Before(a)
+		}
+		return jobs[i].Name < jobs[j].Name
+	})
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+// getGraphQLJobLogs returns the logs of a job, or its archived logs if its pod no longer exists
+func getGraphQLJobLogs(ctx context.Context, cfg *types.Config, kubeClientset kubernetes.Interface, serviceName string, jobName string, tail int) (interface{}, error) {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,job-name=%s", types.ServiceLabel, serviceName, jobName),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(cfg.GetJobsNamespace()).List(ctx, listOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pods.Items) == 0 {
+		if !cfg.LogsArchiveEnable {
+			return nil, nil
+		}
+		logs, err := utils.GetArchivedJobLogs(cfg, serviceName, jobName)
+		if err != nil {
+			return nil, err
+		}
+		return tailLines(string(logs), tail), nil
+	}
+
+	podLogOpts := &v1.PodLogOptions{Container: types.ContainerName}
+	if tail > 0 {
+		lines := int64(tail)
+		podLogOpts.TailLines = &lines
+	}
+	logs, err := kubeClientset.CoreV1().Pods(pods.Items[0].Namespace).GetLogs(pods.Items[0].Name, podLogOpts).DoRaw(ctx)
+	if err != nil {
+		// The logs are not available (e.g. the container is not started)
+		return nil, nil
+	}
+	return string(logs), nil
+}
+
+// tailLines returns the last n lines of the logs (all of them if n is 0)
+func tailLines(logs string, n int) string {
+	lines := strings.SplitAfter(logs, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if n <= 0 || len(lines) <= n {
+		return logs
+	}
+	return strings.Join(lines[len(lines)-n:], "")
+}
@@ -0,0 +1,134 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeGraphQLHandler(t *testing.T) {
+	cfg := testConfigValidRun
+	namespace := cfg.ServicesNamespace
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "grayify", Image: "grycap/imagemagick", VO: "vo.example.eu", Labels: map[string]string{"team": "a"}})
+	back.CreateService(types.Service{Name: "plants", Image: "grycap/plants", VO: "other"})
+	back.CreateService(types.Service{Name: "private", Image: "grycap/private", Owner: "other"})
+
+	now := time.Now()
+	newJob := func(name string, created time.Time, phase v1.PodPhase) []runtime.Object {
+		start := metav1.NewTime(created)
+		return []runtime.Object{
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{types.ServiceLabel: "grayify"}},
+				Status:     batchv1.JobStatus{StartTime: &start},
+			},
+			&v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name + "-pod", Namespace: namespace, Labels: map[string]string{types.ServiceLabel: "grayify", "job-name": name}},
+				Status:     v1.PodStatus{Phase: phase},
+			},
+		}
+	}
+	objects := []runtime.Object{}
+	objects = append(objects, newJob("old-failed", now.Add(-time.Hour), v1.PodFailed)...)
+	objects = append(objects, newJob("last-failed", now.Add(-time.Minute), v1.PodFailed)...)
+	objects = append(objects, newJob("succeeded", now, v1.PodSucceeded)...)
+	kubeClientset := testclient.NewSimpleClientset(objects...)
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, "user")
+	})
+	handler := MakeGraphQLHandler(&cfg, back, kubeClientset)
+	r.GET("/system/graphql", handler)
+	r.POST("/system/graphql", handler)
+
+	query := `query($vo: String) {
+		services(vo: $vo) {
+			name
+			image
+			labels { key value }
+			jobs(status: "failed", limit: 1) { name status logs }
+		}
+	}`
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": map[string]interface{}{"vo": "vo.example.eu"}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/system/graphql", strings.NewReader(string(body)))
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	// The fake clientset returns "fake logs" as the logs of any pod
+	expected := `{"data":{"services":[{"image":"grycap/imagemagick","jobs":[{"logs":"fake logs","name":"last-failed","status":"Failed"}],"labels":[{"key":"team","value":"a"}],"name":"grayify"}]}}`
+	if w.Body.String() != expected {
+		t.Errorf("expected the result %s, got %s", expected, w.Body.String())
+	}
+
+	// Queries in GET requests and errors in the result
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/system/graphql?query="+url.QueryEscape(`{ service(name: "plants") { name vo jobs { name } } missing: service(name: "missing") { name } }`), nil)
+	r.ServeHTTP(w, req)
+	result := struct {
+		Data   map[string]interface{}   `json:"data"`
+		Errors []map[string]interface{} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	if service, _ := result.Data["service"].(map[string]interface{}); service == nil || service["vo"] != "other" {
+		t.Errorf("unexpected service: %v", result.Data["service"])
+	}
+	if result.Data["missing"] != nil || len(result.Errors) != 1 {
+		t.Errorf("expected an error reading the missing service, got %s", w.Body.String())
+	}
+
+	// The services of other owners are not returned
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/system/graphql?query="+url.QueryEscape(`{ services { name } private: service(name: "private") { name } }`), nil)
+	r.ServeHTTP(w, req)
+	result.Data, result.Errors = nil, nil
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	if services, _ := result.Data["services"].([]interface{}); len(services) != 2 || result.Data["private"] != nil || len(result.Errors) != 1 {
+		t.Errorf("expected only the accessible services, got %s", w.Body.String())
+	}
+
+	for _, body := range []string{"{", `{"query": ""}`} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/system/graphql", strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for the body %s, got %d", body, w.Code)
+		}
+	}
+}
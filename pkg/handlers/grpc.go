@@ -0,0 +1,468 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/grpcapi"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// grpcChunkSize maximum size of the data of the messages streaming the responses of the services
+const grpcChunkSize = 32 * 1024
+
+// grpcServer implementation of the gRPC API (see pkg/grpcapi/oscar.proto)
+type grpcServer struct {
+	grpcapi.UnimplementedOscarServer
+	cfg           *types.Config
+	back          types.ServerlessBackend
+	kubeClientset kubernetes.Interface
+	dispatcher    *EventDispatcher
+}
+
+// MakeGRPCServer makes the server of the gRPC API, served alongside the REST API for the integrators requiring typed
+// clients. The management RPCs are authenticated with the OSCAR credentials (basic auth) and the invocation RPCs
+// with the token of the invoked service, as the /job and /run paths
+func MakeGRPCServer(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface, dispatcher *EventDispatcher) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := checkGRPCBasicAuth(ctx, cfg, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := checkGRPCBasicAuth(ss.Context(), cfg, info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	grpcapi.RegisterOscarServer(s, &grpcServer{
+		cfg:           cfg,
+		back:          back,
+		kubeClientset: kubeClientset,
+		dispatcher:    dispatcher,
+	})
+	return s
+}
+
+// ListServices returns the services of the cluster
+func (s *grpcServer) ListServices(ctx context.Context, req *grpcapi.ListServicesRequest) (*grpcapi.ListServicesResponse, error) {
+	opts := types.ServiceListOptions{VO: req.Vo, Labels: req.Labels}
+	if err := opts.Validate(); err != nil {
+		return nil, grpcError(types.ErrBadRequest, fmt.Sprintf("Invalid list options: %v", err))
+	}
+
+	services, err := s.back.ListServices()
+	if err != nil {
+		return nil, grpcError(types.ErrServiceReadFailed, err.Error())
+	}
+
+	page, _, _ := opts.Apply(services)
+	res := &grpcapi.ListServicesResponse{}
+	for _, service := range page {
+		res.Services = append(res.Services, s.toGRPCService(service))
+	}
+	return res, nil
+}
+
+// GetService returns a service
+func (s *grpcServer) GetService(ctx context.Context, req *grpcapi.GetServiceRequest) (*grpcapi.Service, error) {
+	service, err := s.readService(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.toGRPCService(service), nil
+}
+
+// ListJobs returns the jobs of a service, newest first
+func (s *grpcServer) ListJobs(ctx context.Context, req *grpcapi.ListJobsRequest) (*grpcapi.ListJobsResponse, error) {
+	if _, err := s.readService(req.Service); err != nil {
+		return nil, err
+	}
+
+	jobs, err := listGraphQLJobs(s.kubeClientset, s.cfg.GetJobsNamespace(), req.Service, req.Status, int(req.Limit))
+	if err != nil {
+		return nil, grpcError(types.ErrJobReadFailed, err.Error())
+	}
+
+	res := &grpcapi.ListJobsResponse{}
+	for _, job := range jobs {
+		res.Jobs = append(res.Jobs, toGRPCJob(&types.JobStatus{Name: job.Name, Service: job.service, JobInfo: job.JobInfo}))
+	}
+	return res, nil
+}
+
+// GetJob returns the detailed status of a job
+func (s *grpcServer) GetJob(ctx context.Context, req *grpcapi.GetJobRequest) (*grpcapi.Job, error) {
+	job, err := getJob(s.kubeClientset, s.cfg.GetJobsNamespace(), req.Job)
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			return nil, grpcError(types.ErrJobNotFound, "")
+		}
+		return nil, grpcError(types.ErrJobReadFailed, err.Error())
+	}
+	// The job must be associated with the provided service
+	if job.Labels[types.ServiceLabel] != req.Service {
+		return nil, grpcError(types.ErrJobNotFound, "")
+	}
+
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", req.Job),
+	}
+	pods, err := s.kubeClientset.CoreV1().Pods(job.Namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, grpcError(types.ErrJobReadFailed, err.Error())
+	}
+
+	return toGRPCJob(getJobDetails(job, pods.Items)), nil
+}
+
+// DeleteJob removes a job and its pods
+func (s *grpcServer) DeleteJob(ctx context.Context, req *grpcapi.GetJobRequest) (*grpcapi.DeleteJobResponse, error) {
+	job, err := getJob(s.kubeClientset, s.cfg.GetJobsNamespace(), req.Job)
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			return nil, grpcError(types.ErrJobNotFound, "")
+		}
+		return nil, grpcError(types.ErrJobReadFailed, err.Error())
+	}
+	if job.Labels[types.ServiceLabel] != req.Service {
+		return nil, grpcError(types.ErrJobNotFound, "")
+	}
+
+	background := metav1.DeletePropagationBackground
+	if err := s.kubeClientset.BatchV1().Jobs(job.Namespace).Delete(ctx, req.Job, metav1.DeleteOptions{PropagationPolicy: &background}); err != nil {
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			return nil, grpcError(types.ErrJobNotFound, "")
+		}
+		return nil, grpcError(types.ErrJobDeleteFailed, err.Error())
+	}
+	return &grpcapi.DeleteJobResponse{}, nil
+}
+
+// StreamLogs streams the logs of the 'oscar-container' of a job line by line
+func (s *grpcServer) StreamLogs(req *grpcapi.StreamLogsRequest, stream grpcapi.Oscar_StreamLogsServer) error {
+	ctx := stream.Context()
+	namespace := s.cfg.GetJobsNamespace()
+
+	// Get job's pod (assuming there's only one pod per job)
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,job-name=%s", types.ServiceLabel, req.Service, req.Job),
+	}
+	pods, err := s.kubeClientset.CoreV1().Pods(namespace).List(ctx, listOpts)
+	if err != nil {
+		return grpcError(types.ErrJobReadFailed, err.Error())
+	}
+	if len(pods.Items) < 1 {
+		return grpcError(types.ErrJobNotFound, "")
+	}
+
+	// The logs are not available until the container starts
+	podLogOpts := &v1.PodLogOptions{
+		Container:  types.ContainerName,
+		Follow:     req.Follow,
+		Timestamps: req.Timestamps,
+	}
+	var logs io.ReadCloser
+	for {
+		logs, err = s.kubeClientset.CoreV1().Pods(pods.Items[0].Namespace).GetLogs(pods.Items[0].Name, podLogOpts).Stream(ctx)
+		if err == nil {
+			break
+		}
+		if !req.Follow || errors.IsNotFound(err) || errors.IsGone(err) {
+			return grpcError(types.ErrJobReadFailed, err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(logsHeartbeatInterval):
+		}
+	}
+	defer logs.Close()
+
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := stream.Send(&grpcapi.LogChunk{Job: req.Job, Line: scanner.Text()}); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return grpcError(types.ErrJobReadFailed, err.Error())
+	}
+	return nil
+}
+
+// InvokeAsync dispatches the event of an async invocation, as the /job path
+func (s *grpcServer) InvokeAsync(ctx context.Context, req *grpcapi.InvokeAsyncRequest) (*grpcapi.InvokeAsyncResponse, error) {
+	service, err := s.readService(req.Service)
+	if err != nil {
+		return nil, err
+	}
+	if !checkGRPCServiceToken(ctx, service) {
+		return nil, grpcError(types.ErrUnauthorized, "")
+	}
+
+	dispatchStatus, detail, err := s.dispatcher.dispatch(service, req.Event)
+	if err != nil {
+		return nil, grpcError(types.GetErrorCode(err, types.ErrInternal), err.Error())
+	}
+
+	switch dispatchStatus {
+	case eventDiscarded:
+		return &grpcapi.InvokeAsyncResponse{Result: "discarded", Detail: detail}, nil
+	case eventWaiting:
+		return &grpcapi.InvokeAsyncResponse{Result: "waiting"}, nil
+	case eventBatched:
+		return &grpcapi.InvokeAsyncResponse{Result: "batched"}, nil
+	case eventQueued:
+		return &grpcapi.InvokeAsyncResponse{Result: "queued"}, nil
+	default:
+		return &grpcapi.InvokeAsyncResponse{Result: "created", Job: detail}, nil
+	}
+}
+
+// Invoke sends the body streamed by the client to the service through the gateway of the ServerlessBackend and
+// streams back its response, as the /run path
+func (s *grpcServer) Invoke(stream grpcapi.Oscar_InvokeServer) error {
+	syncBack, ok := s.back.(types.SyncBackend)
+	if s.cfg.ServerlessBackend == "" || !ok {
+		return status.Error(codes.Unimplemented, "the synchronous invocations require a ServerlessBackend")
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	service, err := s.readService(first.Service)
+	if err != nil {
+		return err
+	}
+	if !checkGRPCServiceToken(stream.Context(), service) {
+		return grpcError(types.ErrUnauthorized, "")
+	}
+
+	// Read the body from the rest of the messages while it is sent to the service
+	pr, pw := io.Pipe()
+	go func() {
+		if _, err := pw.Write(first.Data); err != nil {
+			return
+		}
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(msg.Data); err != nil {
+				return
+			}
+		}
+	}()
+	var body io.Reader = pr
+
+	// Check the body against the input schema of the service
+	if service.Schema != nil && service.Schema.Input != nil {
+		data, err := io.ReadAll(pr)
+		if err != nil {
+			return grpcError(types.ErrBadRequest, fmt.Sprintf("The request body cannot be read: %v", err))
+		}
+		if issues := service.Schema.ValidateInput(data); len(issues) > 0 {
+			return grpcError(types.ErrInvalidPayload, strings.Join(issues, "; "))
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(stream.Context(), http.MethodPost, "/", body)
+	if err != nil {
+		pr.Close()
+		return grpcError(types.ErrInternal, err.Error())
+	}
+	if first.ContentType != "" {
+		req.Header.Set("Content-Type", first.ContentType)
+	}
+	syncBack.GetProxyDirector(service.Name)(req)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		pr.Close()
+		return grpcError(types.ErrInternal, fmt.Sprintf("Error invoking the service: %v", err))
+	}
+	defer res.Body.Close()
+
+	msg := &grpcapi.InvokeResponse{StatusCode: int32(res.StatusCode), ContentType: res.Header.Get("Content-Type")}
+	buf := make([]byte, grpcChunkSize)
+	for {
+		n, err := res.Body.Read(buf)
+		if n > 0 || msg.StatusCode != 0 {
+			msg.Data = buf[:n]
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			msg = &grpcapi.InvokeResponse{}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return grpcError(types.ErrInternal, fmt.Sprintf("Error reading the response of the service: %v", err))
+		}
+	}
+}
+
+// readService returns the service or the gRPC error of its read
+func (s *grpcServer) readService(name string) (*types.Service, error) {
+	service, err := s.back.ReadService(name)
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			return nil, grpcError(types.ErrServiceNotFound, "")
+		}
+		return nil, grpcError(types.ErrServiceReadFailed, err.Error())
+	}
+	return service, nil
+}
+
+// toGRPCService returns the gRPC message of a service
+func (s *grpcServer) toGRPCService(service *types.Service) *grpcapi.Service {
+	definition, _ := json.Marshal(service)
+	_, sync := s.back.(types.SyncBackend)
+	return &grpcapi.Service{
+		Name:       service.Name,
+		Image:      service.Image,
+		Memory:     service.Memory,
+		Cpu:        service.CPU,
+		LogLevel:   service.LogLevel,
+		Vo:         service.VO,
+		Labels:     service.Labels,
+		Revision:   int32(service.Revision),
+		Sync:       sync && s.cfg.ServerlessBackend != "",
+		Definition: definition,
+	}
+}
+
+// toGRPCJob returns the gRPC message of a job
+func toGRPCJob(status *types.JobStatus) *grpcapi.Job {
+	return &grpcapi.Job{
+		Name:         status.Name,
+		Service:      status.Service,
+		Status:       status.Status,
+		CreationTime: toGRPCTimestamp(status.CreationTime),
+		StartTime:    toGRPCTimestamp(status.StartTime),
+		FinishTime:   toGRPCTimestamp(status.FinishTime),
+		Revision:     int32(status.Revision),
+		NodeName:     status.NodeName,
+		ExitCode:     status.ExitCode,
+		Reason:       status.Reason,
+		RetryOf:      status.RetryOf,
+	}
+}
+
+// toGRPCTimestamp returns the protobuf timestamp of t (nil if not set)
+func toGRPCTimestamp(t *metav1.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(t.Time)
+}
+
+// grpcError returns the gRPC status of an API error, with the error code in its message
+func grpcError(code types.ErrorCode, message string) error {
+	if message == "" {
+		message = code.Description
+	}
+
+	grpcCode := codes.Internal
+	switch code.Status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		grpcCode = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		grpcCode = codes.Unauthenticated
+	case http.StatusForbidden:
+		grpcCode = codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		grpcCode = codes.NotFound
+	case http.StatusConflict:
+		grpcCode = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		grpcCode = codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		grpcCode = codes.Unavailable
+	}
+	return status.Error(grpcCode, fmt.Sprintf("%s: %s", code.Code, message))
+}
+
+// getGRPCAuthorization returns the authorization metadata of a gRPC request
+func getGRPCAuthorization(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// checkGRPCBasicAuth checks the OSCAR credentials of the management RPCs (the invocation RPCs check the token of
+// the service)
+func checkGRPCBasicAuth(ctx context.Context, cfg *types.Config, method string) error {
+	if method == grpcapi.Oscar_Invoke_FullMethodName || method == grpcapi.Oscar_InvokeAsync_FullMethodName {
+		return nil
+	}
+	expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+cfg.Password))
+	if subtle.ConstantTimeCompare([]byte(getGRPCAuthorization(ctx)), []byte(expected)) != 1 {
+		return grpcError(types.ErrUnauthorized, "")
+	}
+	return nil
+}
+
+// checkGRPCServiceToken checks if the gRPC request is authenticated with the service's token (or a named access
+// token with the "invoke" scope) as bearer token
+func checkGRPCServiceToken(ctx context.Context, service *types.Service) bool {
+	splitToken := strings.Split(getGRPCAuthorization(ctx), "Bearer ")
+	if len(splitToken) != 2 {
+		return false
+	}
+	return service.CheckToken(strings.TrimSpace(splitToken[1]), types.ServiceTokenScopeInvoke)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/grpcapi"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeGRPCServer(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.Username = "oscar"
+	cfg.Password = "secret"
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Token: "token", VO: "vo.example.eu", Labels: map[string]string{types.ServiceLabel: "test", "team": "a"}})
+	back.CreateService(types.Service{Name: "other", Image: "busybox", Token: "other"})
+	kubeClientset := testclient.NewSimpleClientset()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := MakeGRPCServer(&cfg, back, kubeClientset, MakeEventDispatcher(&cfg, kubeClientset, nil))
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := grpcapi.NewOscarClient(conn)
+
+	basic := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("oscar:secret")))
+	bearer := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	expectCode := func(t *testing.T, err error, code codes.Code) {
+		t.Helper()
+		if status.Code(err) != code {
+			t.Fatalf("expecting code %s, got: %v", code, err)
+		}
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := client.ListServices(context.Background(), &grpcapi.ListServicesRequest{})
+		expectCode(t, err, codes.Unauthenticated)
+		_, err = client.ListServices(bearer("token"), &grpcapi.ListServicesRequest{})
+		expectCode(t, err, codes.Unauthenticated)
+	})
+
+	t.Run("list services", func(t *testing.T) {
+		res, err := client.ListServices(basic, &grpcapi.ListServicesRequest{Vo: "vo.example.eu"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Services) != 1 || res.Services[0].Name != "test" || res.Services[0].Labels["team"] != "a" {
+			t.Fatalf("unexpected services: %v", res.Services)
+		}
+		if res.Services[0].Sync {
+			t.Error("expecting the services not to be invocable synchronously without a ServerlessBackend")
+		}
+	})
+
+	t.Run("service not found", func(t *testing.T) {
+		_, err := client.GetService(basic, &grpcapi.GetServiceRequest{Name: "missing"})
+		expectCode(t, err, codes.NotFound)
+	})
+
+	var jobName string
+	t.Run("invoke async", func(t *testing.T) {
+		_, err := client.InvokeAsync(bearer("other"), &grpcapi.InvokeAsyncRequest{Service: "test", Event: []byte(`{"message": "hello"}`)})
+		expectCode(t, err, codes.Unauthenticated)
+
+		res, err := client.InvokeAsync(bearer("token"), &grpcapi.InvokeAsyncRequest{Service: "test", Event: []byte(`{"message": "hello"}`)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Result != "created" || res.Job == "" {
+			t.Fatalf("unexpected invocation result: %v", res)
+		}
+		jobName = res.Job
+	})
+
+	t.Run("jobs", func(t *testing.T) {
+		// The jobs are listed once started
+		job, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), jobName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := metav1.Now()
+		job.Status.StartTime = &start
+		kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).UpdateStatus(context.TODO(), job, metav1.UpdateOptions{})
+
+		jobs, err := client.ListJobs(basic, &grpcapi.ListJobsRequest{Service: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs.Jobs) != 1 || jobs.Jobs[0].Name != jobName || jobs.Jobs[0].CreationTime == nil {
+			t.Fatalf("unexpected jobs: %v", jobs.Jobs)
+		}
+
+		details, err := client.GetJob(basic, &grpcapi.GetJobRequest{Service: "test", Job: jobName})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details.Service != "test" || details.Status != types.JobPending {
+			t.Fatalf("unexpected job: %v", details)
+		}
+
+		_, err = client.GetJob(basic, &grpcapi.GetJobRequest{Service: "other", Job: jobName})
+		expectCode(t, err, codes.NotFound)
+	})
+
+	t.Run("stream logs", func(t *testing.T) {
+		kubeClientset.CoreV1().Pods(cfg.ServicesNamespace).Create(context.TODO(), &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: jobName + "-pod", Labels: map[string]string{types.ServiceLabel: "test", "job-name": jobName}},
+		}, metav1.CreateOptions{})
+
+		stream, err := client.StreamLogs(basic, &grpcapi.StreamLogsRequest{Service: "test", Job: jobName})
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunk, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Job != jobName || chunk.Line != "fake logs" {
+			t.Errorf("unexpected log chunk: %v", chunk)
+		}
+		if _, err := stream.Recv(); err != io.EOF {
+			t.Errorf("expecting the end of the stream, got: %v", err)
+		}
+
+		stream, err = client.StreamLogs(basic, &grpcapi.StreamLogsRequest{Service: "test", Job: "missing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = stream.Recv()
+		expectCode(t, err, codes.NotFound)
+	})
+
+	t.Run("delete job", func(t *testing.T) {
+		if _, err := client.DeleteJob(basic, &grpcapi.GetJobRequest{Service: "test", Job: jobName}); err != nil {
+			t.Fatal(err)
+		}
+		_, err := client.GetJob(basic, &grpcapi.GetJobRequest{Service: "test", Job: jobName})
+		expectCode(t, err, codes.NotFound)
+	})
+
+	t.Run("invoke without ServerlessBackend", func(t *testing.T) {
+		stream, err := client.Invoke(bearer("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream.Send(&grpcapi.InvokeRequest{Service: "test", Data: []byte("hello")})
+		stream.CloseSend()
+		_, err = stream.Recv()
+		expectCode(t, err, codes.Unimplemented)
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+const (
+	// maxIdempotencyKeyLength maximum length of the Idempotency-Key header
+	maxIdempotencyKeyLength = 255
+	// idempotencyFingerprintBytes bytes of the request body hashed (along with its length) in the fingerprint
+	idempotencyFingerprintBytes = 1024 * 1024
+	// maxIdempotentResponseBytes maximum size of the stored responses. The keys of larger responses are released
+	maxIdempotentResponseBytes = 1024 * 1024
+)
+
+// idempotencyWriter response writer keeping a copy of the response body (until it exceeds the limit)
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idempotencyWriter) record(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > maxIdempotentResponseBytes {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
+
+// MakeIdempotencyMiddleware makes a middleware to process only once the requests sent with the same
+// Idempotency-Key header (and credentials) to a path, replaying the stored response to the retried ones.
+// Server errors (and rate limited requests) are not stored, so they can be retried with the same key
+func MakeIdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(types.IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			sendError(c, types.ErrBadRequest, "The Idempotency-Key header cannot exceed 255 characters")
+			return
+		}
+
+		// The key is scoped to the method, path and credentials of the request
+		scope := sha256.Sum256([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n" + c.GetHeader("Authorization") + "\n" + key))
+		scopedKey := hex.EncodeToString(scope[:])
+
+		// Fingerprint of the request (the body is restored to be read by the handler)
+		prefix, err := io.ReadAll(io.LimitReader(c.Request.Body, idempotencyFingerprintBytes))
+		if err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(prefix), c.Request.Body), c.Request.Body}
+		hash := sha256.Sum256(prefix)
+		fingerprint := hex.EncodeToString(hash[:]) + "-" + strconv.FormatInt(c.Request.ContentLength, 10) + "-" + c.ContentType()
+
+		stored, err := utils.BeginIdempotentRequest(scopedKey, fingerprint)
+		if err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+		if stored != nil {
+			for name, values := range stored.Header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header(types.IdempotentReplayedHeader, "true")
+			c.Data(stored.Status, stored.Header.Get("Content-Type"), stored.Body)
+			c.Abort()
+			return
+		}
+
+		w := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		status := w.Status()
+		if w.overflow || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			utils.FinishIdempotentRequest(scopedKey, nil)
+			return
+		}
+		header := w.Header().Clone()
+		header.Del("Date")
+		header.Del("Content-Length")
+		utils.FinishIdempotentRequest(scopedKey, &types.IdempotentRequest{Status: status, Header: header, Body: w.body.Bytes()})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	calls := 0
+	failing := true
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := gin.Default()
+	r.POST("/echo", MakeIdempotencyMiddleware(), func(c *gin.Context) {
+		calls++
+		body, _ := c.GetRawData()
+		c.Header("Location", "/echo/"+string(body))
+		c.String(http.StatusCreated, "%s-%d", body, calls)
+	})
+	r.POST("/flaky", MakeIdempotencyMiddleware(), func(c *gin.Context) {
+		if failing {
+			failing = false
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+	r.POST("/slow", MakeIdempotencyMiddleware(), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	send := func(path string, key string, auth string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(types.IdempotencyKeyHeader, key)
+		}
+		req.Header.Set("Authorization", auth)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	scenarios := []struct {
+		name             string
+		path             string
+		key              string
+		auth             string
+		body             string
+		expectedCode     int
+		expectedBody     string
+		expectedReplayed bool
+	}{
+		{"first request", "/echo", "key-1", "Bearer a", "hello", http.StatusCreated, "hello-1", false},
+		{"retried request", "/echo", "key-1", "Bearer a", "hello", http.StatusCreated, "hello-1", true},
+		{"different body", "/echo", "key-1", "Bearer a", "bye", http.StatusUnprocessableEntity, "", false},
+		{"different credentials", "/echo", "key-1", "Bearer b", "hello", http.StatusCreated, "hello-2", false},
+		{"without key", "/echo", "", "Bearer a", "hello", http.StatusCreated, "hello-3", false},
+		{"key too long", "/echo", strings.Repeat("k", 256), "Bearer a", "hello", http.StatusBadRequest, "", false},
+		{"server error", "/flaky", "key-2", "Bearer a", "", http.StatusServiceUnavailable, "", false},
+		{"retried server error", "/flaky", "key-2", "Bearer a", "", http.StatusCreated, "", false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := send(s.path, s.key, s.auth, s.body)
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedBody != "" && w.Body.String() != s.expectedBody {
+				t.Errorf("expecting body \"%s\", got \"%s\"", s.expectedBody, w.Body.String())
+			}
+			if replayed := w.Header().Get(types.IdempotentReplayedHeader) == "true"; replayed != s.expectedReplayed {
+				t.Errorf("expecting replayed %v, got %v", s.expectedReplayed, replayed)
+			}
+			if s.expectedReplayed && w.Header().Get("Location") != "/echo/"+s.body {
+				t.Errorf("expecting the stored Location header, got \"%s\"", w.Header().Get("Location"))
+			}
+		})
+	}
+
+	// The requests with a key in use by a request in progress are rejected
+	done := make(chan int)
+	go func() {
+		done <- send("/slow", "key-3", "Bearer a", "").Code
+	}()
+	<-started
+	if w := send("/slow", "key-3", "Bearer a", ""); w.Code != http.StatusConflict {
+		t.Errorf("expecting code %d, got %d", http.StatusConflict, w.Code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("expecting code %d, got %d", http.StatusCreated, code)
+	}
+}
+
+func TestIdempotentJobInvocation(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Token: "token", Labels: map[string]string{types.ServiceLabel: "test"}})
+	kubeClientset := testclient.NewSimpleClientset()
+	dispatcher := MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)
+
+	r := gin.Default()
+	r.POST("/job/:serviceName", MakeIdempotencyMiddleware(), MakeJobHandler(back, dispatcher))
+
+	var first string
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/job/test", strings.NewReader(`{"message": "hello"}`))
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set(types.IdempotencyKeyHeader, "invocation-1")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expecting code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if i == 0 {
+			first = w.Body.String()
+		} else if w.Body.String() != first {
+			t.Errorf("expecting the same job \"%s\", got \"%s\"", first, w.Body.String())
+		}
+	}
+
+	jobs, _ := kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).List(context.TODO(), metav1.ListOptions{})
+	if len(jobs.Items) != 1 {
+		t.Errorf("expecting 1 job, got %d", len(jobs.Items))
+	}
+}
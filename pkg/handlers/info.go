@@ -21,14 +21,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
 	"github.com/grycap/oscar/v2/pkg/version"
 	"k8s.io/client-go/kubernetes"
 )
 
 // MakeInfoHandler makes a handler to retrieve system info
-func MakeInfoHandler(kubeClientset kubernetes.Interface, back types.ServerlessBackend) gin.HandlerFunc {
+func MakeInfoHandler(cfg *types.Config, kubeClientset kubernetes.Interface, back types.ServerlessBackend) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		info := version.GetInfo(kubeClientset, back)
+		info.StorageFailover = utils.GetStorageFailoverStatus(cfg)
 
 		c.JSON(http.StatusOK, info)
 	}
@@ -23,13 +23,14 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
 )
 
 func TestMakeInfoHandler(t *testing.T) {
 	back := backends.MakeFakeBackend()
 
 	r := gin.Default()
-	r.GET("/system/info", MakeInfoHandler(back.GetKubeClientset(), back))
+	r.GET("/system/info", MakeInfoHandler(&types.Config{}, back.GetKubeClientset(), back))
 
 	w := httptest.NewRecorder()
 
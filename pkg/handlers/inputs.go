@@ -0,0 +1,144 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// inputToggle body of the requests to enable/disable an input
+type inputToggle struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// MakeInputToggleHandler makes a handler to enable/disable the trigger (bucket notification) of a service's input
+func MakeInputToggleHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var toggle inputToggle
+		if err := c.ShouldBindJSON(&toggle); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The request is not valid: %v", err))
+			return
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		index, err := strconv.Atoi(c.Param("index"))
+		if err != nil || index < 0 || index >= len(service.Input) {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid input index \"%s\"", c.Param("index")))
+			return
+		}
+		in := service.Input[index]
+
+		provName, provID := in.GetProvider()
+		if provName != types.MinIOName {
+			sendError(c, types.ErrInvalidInputProvider, "Only the MinIO inputs can be enabled/disabled")
+			return
+		}
+		if service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+			sendError(c, types.ErrStorageProviderNotDefined, fmt.Sprintf("The StorageProvider \"%s.%s\" is not defined", provName, provID))
+			return
+		}
+
+		enabled := *toggle.Enabled
+		if enabled == !in.Disabled {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		s3Client := service.StorageProviders.MinIO[provID].GetS3Client()
+		arnStr := service.GetMinIOWebhookARN()
+		toggleNotification := func(enable bool) error {
+			if enable {
+				return enableInputNotification(s3Client, arnStr, in)
+			}
+			return disableInputNotification(s3Client, arnStr, in)
+		}
+
+		if err := toggleNotification(enabled); err != nil {
+			sendCodedError(c, err, types.ErrNotificationFailed)
+			return
+		}
+
+		// Store the state of the input in the service's definition
+		service.Input[index].Disabled = !enabled
+		if err := back.UpdateService(*service); err != nil {
+			// Restore the previous notification
+			if err := toggleNotification(!enabled); err != nil {
+				log.Printf("Error restoring the notification of input \"%s\" of service \"%s\": %v\n", in.Path, service.Name, err)
+			}
+			sendError(c, types.ErrServiceUpdateFailed, fmt.Sprintf("Error updating the service: %v", err))
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// disableInputNotification removes the bucket notification of a single input
+func disableInputNotification(minIOClient *s3.S3, arnStr string, input types.StorageIOConfig) error {
+	parsedARN, _ := arn.Parse(arnStr)
+	bucket, folder := input.SplitPath()
+	prefix := ""
+	if folder != "" {
+		prefix = fmt.Sprintf("%s/", folder)
+	}
+
+	nCfg, err := minIOClient.GetBucketNotificationConfiguration(&s3.GetBucketNotificationConfigurationRequest{Bucket: aws.String(bucket)})
+	if err != nil {
+		return types.NewCodedError(types.ErrNotificationFailed, fmt.Errorf("error getting bucket \"%s\" notifications: %v", bucket, err))
+	}
+
+	// Keep the queue configurations of other services and inputs
+	updatedQueueConfigurations := []*s3.QueueConfiguration{}
+	for _, q := range nCfg.QueueConfigurations {
+		queueARN, _ := arn.Parse(aws.StringValue(q.QueueArn))
+		if queueARN.Resource == parsedARN.Resource && queueARN.AccountID == parsedARN.AccountID && getFilterPrefix(q) == prefix {
+			continue
+		}
+		updatedQueueConfigurations = append(updatedQueueConfigurations, q)
+	}
+
+	nCfg.QueueConfigurations = updatedQueueConfigurations
+	_, err = minIOClient.PutBucketNotificationConfiguration(&s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucket),
+		NotificationConfiguration: nCfg,
+	})
+	if err != nil {
+		return types.NewCodedError(types.ErrNotificationFailed, fmt.Errorf("error disabling bucket notification: %v", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestMakeInputToggleHandler(t *testing.T) {
+	back := backends.MakeFakeBackend()
+
+	r := gin.Default()
+	r.PUT("/system/services/:serviceName/inputs/:index/enabled", MakeInputToggleHandler(back))
+
+	scenarios := []struct {
+		name         string
+		index        string
+		body         string
+		notFound     bool
+		expectedCode int
+	}{
+		{"missing enabled field", "0", `{}`, false, http.StatusBadRequest},
+		// The fake backend returns services without inputs (see TestMakeInputToggleHandlerNotifications)
+		{"invalid index", "0", `{"enabled": false}`, false, http.StatusBadRequest},
+		{"non numeric index", "first", `{"enabled": false}`, false, http.StatusBadRequest},
+		{"service not found", "0", `{"enabled": false}`, true, http.StatusNotFound},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if s.notFound {
+				back.AddError("ReadService", k8serr.NewGone("Not Found"))
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/system/services/test/inputs/"+s.index+"/enabled", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestMakeInputToggleHandlerNotifications(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("input")
+
+	provider := types.MinIOName + types.ProviderSeparator + types.DefaultProvider
+	imagesInput := types.StorageIOConfig{Provider: provider, Path: "input/images"}
+	videosInput := types.StorageIOConfig{Provider: provider, Path: "input/videos"}
+	service := &types.Service{
+		Name:  "test",
+		Input: []types.StorageIOConfig{imagesInput, videosInput},
+		StorageProviders: &types.StorageProviders{
+			MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: testS3Provider(s3Server)},
+		},
+	}
+	otherService := &types.Service{Name: "other", StorageProviders: service.StorageProviders}
+	back := backends.MakeMemoryBackend()
+	back.CreateService(*service)
+
+	// Enable the notifications of both inputs and of another service using the same bucket
+	s3Client := testS3Provider(s3Server).GetS3Client()
+	arnStr := service.GetMinIOWebhookARN()
+	for _, err := range []error{
+		enableInputNotification(s3Client, arnStr, imagesInput),
+		enableInputNotification(s3Client, arnStr, videosInput),
+		enableInputNotification(s3Client, otherService.GetMinIOWebhookARN(), imagesInput),
+	} {
+		if err != nil {
+			t.Fatalf("error enabling the notifications: %v", err)
+		}
+	}
+
+	r := gin.Default()
+	r.PUT("/system/services/:serviceName/inputs/:index/enabled", MakeInputToggleHandler(back))
+
+	scenarios := []struct {
+		name              string
+		body              string
+		updateErr         error
+		failNotifications bool
+		expectedCode      int
+		expectedErrorCode string
+		// expected state after the request
+		expectedEnabled bool
+	}{
+		{"disable", `{"enabled": false}`, nil, false, http.StatusNoContent, "", false},
+		{"disable already disabled", `{"enabled": false}`, nil, false, http.StatusNoContent, "", false},
+		{"enable", `{"enabled": true}`, nil, false, http.StatusNoContent, "", true},
+		// The notification is restored if the service can't be updated
+		{"rollback on update error", `{"enabled": false}`, errors.New("update error"), false, http.StatusInternalServerError, types.ErrServiceUpdateFailed.Code, true},
+		{"notification error", `{"enabled": false}`, nil, true, http.StatusInternalServerError, types.ErrNotificationFailed.Code, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if s.updateErr != nil {
+				back.AddError("UpdateService", s.updateErr)
+			}
+			if s.failNotifications {
+				s3Server.SetFailure(func(r *http.Request) bool { return r.URL.Query().Has("notification") })
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/system/services/test/inputs/0/enabled", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+			s3Server.SetFailure(nil)
+
+			// Check the stored state of the input
+			stored, _ := back.ReadService("test")
+			if stored.Input[0].Disabled == s.expectedEnabled {
+				t.Errorf("expecting the stored input enabled %v, got %v", s.expectedEnabled, !stored.Input[0].Disabled)
+			}
+
+			// Check the notification of the toggled input
+			err := checkInputNotification(s3Client, arnStr, imagesInput)
+			if s.expectedEnabled && err != nil {
+				t.Errorf("expecting the notification enabled, got: %v", err)
+			}
+			if !s.expectedEnabled && err == nil {
+				t.Errorf("expecting the notification disabled")
+			}
+
+			// The notifications of other inputs and services must be kept
+			if err := checkInputNotification(s3Client, arnStr, videosInput); err != nil {
+				t.Errorf("expecting the notification of the other input enabled, got: %v", err)
+			}
+			if err := checkInputNotification(s3Client, otherService.GetMinIOWebhookARN(), imagesInput); err != nil {
+				t.Errorf("expecting the notification of the other service enabled, got: %v", err)
+			}
+			nCfg, err := s3Client.GetBucketNotificationConfiguration(&s3.GetBucketNotificationConfigurationRequest{Bucket: aws.String("input")})
+			if err != nil {
+				t.Fatalf("error getting the bucket notifications: %v", err)
+			}
+			expectedQueues := 2
+			if s.expectedEnabled {
+				expectedQueues = 3
+			}
+			if len(nCfg.QueueConfigurations) != expectedQueues {
+				t.Errorf("expecting %d queue configurations, got %d", expectedQueues, len(nCfg.QueueConfigurations))
+			}
+		})
+	}
+}
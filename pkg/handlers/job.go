@@ -22,13 +22,18 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/grycap/oscar/v2/pkg/resourcemanager"
 	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -47,15 +52,15 @@ var (
 )
 
 // MakeJobHandler makes a handler to manage async invocations
-func MakeJobHandler(cfg *types.Config, kubeClientset *kubernetes.Clientset, back types.ServerlessBackend, rm resourcemanager.ResourceManager) gin.HandlerFunc {
+func MakeJobHandler(back types.ServerlessBackend, dispatcher *EventDispatcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		service, err := back.ReadService(c.Param("serviceName"))
 		if err != nil {
 			// Check if error is caused because the service is not found
 			if errors.IsNotFound(err) || errors.IsGone(err) {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrServiceNotFound, "")
 			} else {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrServiceReadFailed, err.Error())
 			}
 			return
 		}
@@ -64,113 +69,380 @@ func MakeJobHandler(cfg *types.Config, kubeClientset *kubernetes.Clientset, back
 		authHeader := c.GetHeader("Authorization")
 		splitToken := strings.Split(authHeader, "Bearer ")
 		if len(splitToken) != 2 {
-			c.Status(http.StatusUnauthorized)
+			sendError(c, types.ErrUnauthorized, "")
 			return
 		}
 		reqToken := strings.TrimSpace(splitToken[1])
-		if reqToken != service.Token {
-			c.Status(http.StatusUnauthorized)
+		if !service.CheckToken(reqToken, types.ServiceTokenScopeInvoke) {
+			sendError(c, types.ErrUnauthorized, "")
 			return
 		}
 
-		// Get the event from request body
-		eventBytes, err := io.ReadAll(c.Request.Body)
+		invokeAsync(c, dispatcher, service)
+	}
+}
+
+// invokeAsync dispatches the request body of an async invocation as an event of an (authorized) service
+func invokeAsync(c *gin.Context, dispatcher *EventDispatcher, service *types.Service) {
+	// Redirect the outputs of the job to the caller's destination (if requested)
+	if c.Query(types.OutputOverrideParam) != "" && service.HasBatch() {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("The %s parameter is not supported by the services processing events in batches", types.OutputOverrideParam))
+		return
+	}
+	_, service, ok := getOutputOverride(c, service)
+	if !ok {
+		return
+	}
+
+	// Pass the OIDC token of the invoking user (if any) to obtain the temporary credentials of the job
+	if token := strings.TrimSpace(c.GetHeader(types.IdentityTokenHeader)); token != "" {
+		service = service.WithIdentityToken(token)
+	}
+
+	// Get the event from request body
+	eventBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		sendError(c, types.ErrInternal, err.Error())
+		return
+	}
+
+	status, detail, err := dispatcher.dispatch(service, eventBytes)
+	if err != nil {
+		sendCodedError(c, err, types.ErrInternal)
+		return
+	}
+
+	switch status {
+	case eventDiscarded:
+		c.String(http.StatusOK, fmt.Sprintf("Event discarded: %s", detail))
+	case eventWaiting:
+		c.String(http.StatusOK, "Waiting for the rest of the file set")
+	case eventBatched, eventQueued:
+		c.Status(http.StatusAccepted)
+	default:
+		res := types.JobInvocation{JobID: detail}
+		if detail != "" {
+			res.StatusURL = fmt.Sprintf("/job/%s/%s", service.Name, detail)
+			c.Header("Location", res.StatusURL)
+		}
+		c.JSON(http.StatusCreated, res)
+	}
+}
+
+// MakeJobInvocationStatusHandler makes a handler for getting the status of a job created by an async invocation,
+// authenticated with the service's token
+func MakeJobInvocationStatusHandler(back types.ServerlessBackend, kubeClientset kubernetes.Interface, namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := back.ReadService(c.Param("serviceName"))
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		// Check auth token
+		if !checkServiceToken(c, service) {
+			sendError(c, types.ErrUnauthorized, "")
 			return
 		}
-		// Make event envVar
-		event := v1.EnvVar{
-			Name:  types.EventVariable,
-			Value: string(eventBytes),
+
+		sendJobStatus(c, kubeClientset, namespace, service.Name, c.Param("jobName"))
+	}
+}
+
+// MakeJobRunner makes a function to create jobs for the events generated by OSCAR's internal triggers (e.g. emails)
+func MakeJobRunner(cfg *types.Config, kubeClientset kubernetes.Interface, rm resourcemanager.ResourceManager) func(service *types.Service, event string) {
+	return func(service *types.Service, event string) {
+		if _, err := runJob(cfg, kubeClientset, service, event, rm); err != nil {
+			log.Printf("Error creating job for service \"%s\": %v\n", service.Name, err)
+		}
+	}
+}
+
+// MakeChainedJobRunner makes a function to create the jobs of the services chained to a job's outputs that are
+// passed inline (see utils.StartOutputRouter)
+func MakeChainedJobRunner(cfg *types.Config, kubeClientset kubernetes.Interface, rm resourcemanager.ResourceManager) func(service *types.Service, event string) error {
+	return func(service *types.Service, event string) error {
+		_, err := runJob(cfg, kubeClientset, service, event, rm)
+		return err
+	}
+}
+
+// makeJob returns the job definition to process the provided event with a service
+func makeJob(cfg *types.Config, service *types.Service, eventValue string) (*batchv1.Job, error) {
+	// Make event envVar
+	event := v1.EnvVar{
+		Name:  types.EventVariable,
+		Value: eventValue,
+	}
+
+	// Make JOB_UUID envVar
+	jobUUID := uuid.New().String()
+	jobUUIDVar := v1.EnvVar{
+		Name:  types.JobUUIDVariable,
+		Value: jobUUID,
+	}
+
+	// Make RESOURCE_ID envVar
+	resourceIDVar := v1.EnvVar{
+		Name: "RESOURCE_ID",
+		ValueFrom: &v1.EnvVarSource{
+			FieldRef: &v1.ObjectFieldSelector{
+				FieldPath: "spec.nodeName",
+			},
+		},
+	}
+
+	// Resolve the credentials of the storage providers referencing Vault secrets (passed to the pod in its FDL)
+	service, err := utils.ResolveVaultCredentials(cfg, service)
+	if err != nil {
+		return nil, err
+	}
+
+	// Switch the default MinIO provider of the job to the secondary one while the OSCAR's MinIO is unreachable
+	failover := utils.GetFailoverProvider(cfg, service)
+
+	// Stage the outputs to route them by the tags of the files (or pass them inline to the chained services) once
+	// the job finishes (unless overridden or stored in the secondary provider)
+	routed := service.HasStagedOutputs() && service.OutputOverride == nil && failover == nil
+	if routed {
+		var err error
+		if service, err = utils.StageJobOutputs(cfg, service, jobUUID); err != nil {
+			return nil, err
 		}
+	}
+	if failover != nil {
+		service = service.WithFailoverStorage(failover)
+	}
+
+	// Replace the long-lived credentials of the OSCAR's MinIO by temporary ones scoped to the job's inputs and outputs
+	// (derived from the invoking user's OIDC token, if passed)
+	if cfg.JobCredentialsEnable || (cfg.JobCredentialsWebIdentity && service.IdentityToken != "") {
+		var err error
+		if service, err = utils.ScopeJobCredentials(cfg, service, jobUUID); err != nil {
+			return nil, err
+		}
+	}
 
-		// Make JOB_UUID envVar
-		jobUUID := uuid.New().String()
-		jobUUIDVar := v1.EnvVar{
-			Name:  types.JobUUIDVariable,
-			Value: jobUUID,
+	// Get podSpec from the service
+	podSpec, err := service.ToPodSpec(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Add podSpec variables
+	podSpec.RestartPolicy = restartPolicy
+	for i, c := range podSpec.Containers {
+		if c.Name == types.ContainerName {
+			podSpec.Containers[i].Command = command
+			podSpec.Containers[i].Args = []string{"-c", fmt.Sprintf("echo $%s | %s", types.EventVariable, service.GetSupervisorPath())}
+			podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, event)
+			podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, jobUUIDVar)
+			podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, resourceIDVar)
 		}
+	}
+	types.TerminateSidecars(podSpec)
 
-		// Make RESOURCE_ID envVar
-		resourceIDVar := v1.EnvVar{
-			Name: "RESOURCE_ID",
-			ValueFrom: &v1.EnvVarSource{
-				FieldRef: &v1.ObjectFieldSelector{
-					FieldPath: "spec.nodeName",
+	// Create job definition
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			// UUID used as a name for jobs
+			// To filter jobs by service name use the label "oscar_service"
+			Name:        jobUUID,
+			Namespace:   cfg.GetServiceNamespace(service),
+			Labels:      service.Labels,
+			Annotations: service.Annotations,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      service.Labels,
+					Annotations: service.Annotations,
 				},
+				Spec: *podSpec,
 			},
+		},
+	}
+
+	// Record the revision of the service definition that handles the job
+	if service.Revision > 0 {
+		if job.Labels == nil {
+			job.Labels = map[string]string{}
 		}
+		job.Labels[types.RevisionLabel] = strconv.Itoa(service.Revision)
+	}
+
+	// Label the job to route its staged outputs (the service's labels are shared with other jobs)
+	if routed {
+		labels := map[string]string{types.OutputRoutingLabel: types.OutputRoutingPending}
+		for key, value := range job.Labels {
+			labels[key] = value
+		}
+		job.Labels = labels
+	}
+
+	// Record the time when the event was received (the service's annotations are shared with other jobs)
+	job.Annotations = map[string]string{}
+	for key, value := range service.Annotations {
+		job.Annotations[key] = value
+	}
+	job.Annotations[types.EventReceivedAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+	// Record the owner of the service to account the job's usage
+	if service.Owner != "" {
+		job.Annotations[types.OwnerAnnotation] = service.Owner
+	}
 
-		// Get podSpec from the service
-		podSpec, err := service.ToPodSpec(cfg)
+	// Pass the FDL with the overridden outputs, the temporary credentials or the failover storage of the job to the pod
+	// (mounted by the downward API)
+	if service.HasJobConfig() {
+		fdl, err := service.GetOutputConfig()
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
-			return
+			return nil, err
 		}
-		// Add podSpec variables
-		podSpec.RestartPolicy = restartPolicy
-		for i, c := range podSpec.Containers {
-			if c.Name == types.ContainerName {
-				podSpec.Containers[i].Command = command
-				podSpec.Containers[i].Args = []string{"-c", fmt.Sprintf("echo $%s | %s", types.EventVariable, service.GetSupervisorPath())}
-				podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, event)
-				podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, jobUUIDVar)
-				podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, resourceIDVar)
-			}
+		podAnnotations := map[string]string{}
+		for key, value := range service.Annotations {
+			podAnnotations[key] = value
 		}
+		podAnnotations[types.OutputConfigAnnotation] = fdl
+		job.Spec.Template.Annotations = podAnnotations
+	}
 
-		// Delegate job if can't be scheduled and has defined replicas
-		if rm != nil && service.HasReplicas() {
-			if !rm.IsSchedulable(podSpec.Containers[0].Resources) {
-				err := resourcemanager.DelegateJob(service, event.Value, resourcemanager.ResourceManagerLogger)
-				if err == nil {
-					// TODO: check if another status code suits better
-					c.Status(http.StatusCreated)
-					return
-				}
-				log.Printf("unable to delegate job. Error: %v\n", err)
-			}
+	// Add ReScheduler label if there are replicas defined and the cfg.ReSchedulerEnable is true
+	if service.HasReplicas() && cfg.ReSchedulerEnable {
+		if service.ReSchedulerThreshold != 0 {
+			job.Labels[types.ReSchedulerLabelKey] = strconv.Itoa(service.ReSchedulerThreshold)
+		} else {
+			job.Labels[types.ReSchedulerLabelKey] = strconv.Itoa(cfg.ReSchedulerThreshold)
 		}
+	}
 
-		// Create job definition
-		job := &batchv1.Job{
-			ObjectMeta: metav1.ObjectMeta{
-				// UUID used as a name for jobs
-				// To filter jobs by service name use the label "oscar_service"
-				Name:        jobUUID,
-				Namespace:   cfg.ServicesNamespace,
-				Labels:      service.Labels,
-				Annotations: service.Annotations,
-			},
-			Spec: batchv1.JobSpec{
-				BackoffLimit: &backoffLimit,
-				Template: v1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels:      service.Labels,
-						Annotations: service.Annotations,
-					},
-					Spec: *podSpec,
-				},
-			},
+	return job, nil
+}
+
+// runJob delegates the event to a replica if the job can't be scheduled in the cluster or creates the job otherwise.
+// Returns the name of the created job (empty if the event has been delegated)
+func runJob(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, eventValue string, rm resourcemanager.ResourceManager) (string, error) {
+	job, err := makeJob(cfg, service, eventValue)
+	if err != nil {
+		return "", err
+	}
+	return submitJob(cfg, kubeClientset, service, job, eventValue, rm)
+}
+
+// submitJob checks the quotas and defers, queues, places, delegates or creates a job of the service. Returns the
+// name of the job (empty if delegated)
+func submitJob(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, job *batchv1.Job, eventValue string, rm resourcemanager.ResourceManager) (string, error) {
+	// Reject the job if the VO or the owner of the service have reached any of their quotas
+	if err := utils.CheckQuotas(cfg, kubeClientset, service); err != nil {
+		return "", err
+	}
+
+	// Delay the job to a low-carbon window if the service is deferrable (it is released by the deferred jobs releaser)
+	if utils.DeferJob(cfg, service, job) {
+		if _, err := kubeClientset.BatchV1().Jobs(cfg.GetServiceNamespace(service)).Create(context.TODO(), job, metav1.CreateOptions{}); err != nil {
+			return "", err
 		}
+		return job.Name, nil
+	}
 
-		// Add ReScheduler label if there are replicas defined and the cfg.ReSchedulerEnable is true
-		if service.HasReplicas() && cfg.ReSchedulerEnable {
-			if service.ReSchedulerThreshold != 0 {
-				job.Labels[types.ReSchedulerLabelKey] = strconv.Itoa(service.ReSchedulerThreshold)
-			} else {
-				job.Labels[types.ReSchedulerLabelKey] = strconv.Itoa(cfg.ReSchedulerThreshold)
+	// Queue the job if the service has reached its max_concurrent_jobs (it is released by the queued jobs releaser)
+	utils.QueueJob(cfg, kubeClientset, service, job)
+
+	// Place the job following the service's placement policy (if defined)
+	if service.Placement != nil && len(service.Placement.Tiers) > 0 {
+		return placeJob(cfg, kubeClientset, service, job, eventValue, rm)
+	}
+
+	// Run the job in the target selected by the service's delegation policy
+	if service.HasReplicas() && service.GetDelegationPolicy() != types.StaticDelegationPolicy {
+		targets := resourcemanager.RankTargets(cfg, kubeClientset, service, job.Spec.Template.Spec.Containers[0].Resources, rm, resourcemanager.ResourceManagerLogger)
+		for _, target := range targets {
+			if target.IsLocal() {
+				break
+			}
+			// Delegate only to the target's replica
+			targetService := *service
+			targetService.Replicas = types.ReplicaList{*target.Replica}
+			if err := resourcemanager.DelegateJob(&targetService, eventValue, resourcemanager.ResourceManagerLogger); err == nil {
+				return "", nil
+			}
+		}
+	} else if rm != nil && service.HasReplicas() {
+		// Delegate job if can't be scheduled and has defined replicas
+		if !rm.IsSchedulableOnNodes(job.Spec.Template.Spec.Containers[0].Resources, job.Spec.Template.Spec.NodeSelector) {
+			err := resourcemanager.DelegateJob(service, eventValue, resourcemanager.ResourceManagerLogger)
+			if err == nil {
+				return "", nil
 			}
+			log.Printf("unable to delegate job. Error: %v\n", err)
 		}
+	}
 
-		// Create job
-		_, err = kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Create(context.TODO(), job, metav1.CreateOptions{})
-		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
-			return
+	// Create job
+	_, err := kubeClientset.BatchV1().Jobs(cfg.GetServiceNamespace(service)).Create(context.TODO(), job, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	return job.Name, nil
+}
+
+// filterEvent checks if a MinIO event satisfies the filters of the service input that triggered it.
+// Objects not satisfying the filters are copied to the input's ErrorPath (if defined)
+func filterEvent(service *types.Service, eventBytes []byte) error {
+	minIOEvent, err := types.ParseMinIOEvent(eventBytes)
+	if err != nil {
+		// Not a MinIO event, nothing to filter
+		return nil
+	}
+
+	bucket := minIOEvent.GetBucket()
+	key := minIOEvent.GetObjectKey()
+	in := service.GetInputForObject(bucket, key)
+	if in == nil {
+		return nil
+	}
+
+	object := minIOEvent.GetObject()
+	filterErr := in.CheckObjectFilters(object.Size, object.ContentType)
+	if filterErr == nil {
+		return nil
+	}
+
+	if in.ErrorPath != "" {
+		if err := copyToErrorPath(service, *in, bucket, key); err != nil {
+			log.Printf("Error copying discarded object \"%s/%s\" of service \"%s\": %v\n", bucket, key, service.Name, err)
 		}
+	}
+
+	return filterErr
+}
 
-		c.Status(http.StatusCreated)
+// copyToErrorPath copies an object to the ErrorPath of the provided input, keeping its relative key
+func copyToErrorPath(service *types.Service, in types.StorageIOConfig, bucket string, key string) error {
+	_, provID := in.GetProvider()
+	if service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+		return fmt.Errorf("the StorageProvider \"%s\" is not defined", in.Provider)
 	}
+	s3Client := service.StorageProviders.MinIO[provID].GetS3Client()
+
+	_, inFolder := in.SplitPath()
+	relKey := strings.TrimPrefix(strings.TrimPrefix(key, inFolder), "/")
+	errBucket, errFolder := types.StorageIOConfig{Path: in.ErrorPath}.SplitPath()
+	errKey := relKey
+	if errFolder != "" {
+		errKey = fmt.Sprintf("%s/%s", errFolder, relKey)
+	}
+
+	copySource := (&url.URL{Path: fmt.Sprintf("%s/%s", bucket, key)}).EscapedPath()
+	_, err := s3Client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(errBucket),
+		Key:        aws.String(errKey),
+		CopySource: aws.String(copySource),
+	})
+	return err
 }
@@ -0,0 +1,94 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeJobHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Token: "token", Labels: map[string]string{types.ServiceLabel: "test"}})
+	kubeClientset := testclient.NewSimpleClientset()
+	dispatcher := MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)
+
+	r := gin.Default()
+	r.POST("/job/:serviceName", MakeJobHandler(back, dispatcher))
+	r.GET("/job/:serviceName/:jobName", MakeJobInvocationStatusHandler(back, kubeClientset, testConfigValidRun.ServicesNamespace))
+
+	// Invoke the service with an arbitrary event
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/job/test", strings.NewReader(`{"message": "hello"}`))
+	req.Header.Set("Authorization", "Bearer token")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var res types.JobInvocation
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.JobID == "" || res.StatusURL != "/job/test/"+res.JobID {
+		t.Fatalf("unexpected invocation result: %+v", res)
+	}
+	if location := w.Header().Get("Location"); location != res.StatusURL {
+		t.Errorf("expecting Location \"%s\", got \"%s\"", res.StatusURL, location)
+	}
+
+	scenarios := []struct {
+		name         string
+		path         string
+		token        string
+		expectedCode int
+	}{
+		{"status", res.StatusURL, "token", http.StatusOK},
+		{"invalid token", res.StatusURL, "other", http.StatusUnauthorized},
+		{"job not found", "/job/test/other", "token", http.StatusNotFound},
+		{"service not found", "/job/other/" + res.JobID, "token", http.StatusNotFound},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", s.path, nil)
+			req.Header.Set("Authorization", "Bearer "+s.token)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode == http.StatusOK {
+				var status types.JobStatus
+				if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+					t.Fatal(err)
+				}
+				if status.Name != res.JobID || status.Service != "test" {
+					t.Errorf("unexpected job status: %+v", status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeJobListHandler makes a handler for listing the jobs of all the services.
+// The querystrings "status", "vo", "service", "olderThan", "sort", "limit", "offset" and "continue"
+// filter, sort and paginate the list
+func MakeJobListHandler(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface, namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, err := parseJobListOptions(c)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid list options: %v", err))
+			return
+		}
+
+		jobs, err := listClusterJobs(kubeClientset, namespace, opts)
+		if err != nil {
+			sendError(c, types.ErrJobReadFailed, err.Error())
+			return
+		}
+
+		// Only the jobs of the services accessible by the user (the ones of the deleted services for the admins)
+		services, err := back.ListServices()
+		if err != nil {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+			return
+		}
+		accessible := map[string]bool{}
+		for _, service := range filterAccessibleServices(c, cfg, services) {
+			accessible[service.Name] = true
+		}
+		admin := isAdmin(c, cfg)
+		visible := []types.ClusterJob{}
+		for _, job := range jobs {
+			if admin || accessible[job.Service] {
+				visible = append(visible, job)
+			}
+		}
+
+		page, total, next := opts.Apply(visible, time.Now())
+		c.Header(totalCountHeader, strconv.Itoa(total))
+		if next != "" {
+			c.Header(continueHeader, next)
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// parseJobListOptions returns the options of the job list from the querystring
+func parseJobListOptions(c *gin.Context) (types.JobListOptions, error) {
+	opts := types.JobListOptions{
+		Status:  c.Query("status"),
+		VO:      c.Query("vo"),
+		Service: c.Query("service"),
+		Sort:    c.Query("sort"),
+	}
+
+	var err error
+	if limit := c.Query("limit"); limit != "" {
+		if opts.Limit, err = strconv.Atoi(limit); err != nil || opts.Limit < 1 {
+			return opts, fmt.Errorf("invalid limit \"%s\", it must be between 1 and %d", limit, types.MaxJobListLimit)
+		}
+	}
+
+	if olderThan := c.Query("olderThan"); olderThan != "" {
+		if opts.OlderThan, err = time.ParseDuration(olderThan); err != nil {
+			return opts, fmt.Errorf("invalid olderThan \"%s\", it must be a duration (e.g. \"24h\")", olderThan)
+		}
+	}
+
+	// Same pagination as the service list
+	offset, hasOffset := c.GetQuery("offset")
+	token, hasContinue := c.GetQuery("continue")
+	switch {
+	case hasOffset && hasContinue:
+		return opts, fmt.Errorf("the offset and continue querystrings can't be used together")
+	case hasOffset:
+		if opts.Offset, err = strconv.Atoi(offset); err != nil {
+			return opts, fmt.Errorf("invalid offset \"%s\", it must be a non-negative integer", offset)
+		}
+	case hasContinue:
+		if opts.Offset, err = types.ParseServiceListContinue(token); err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, opts.Validate()
+}
+
+// listClusterJobs returns the jobs of the services, using the VO and service filters as label selectors
+func listClusterJobs(kubeClientset kubernetes.Interface, namespace string, opts types.JobListOptions) ([]types.ClusterJob, error) {
+	selector := types.ServiceLabel
+	if opts.Service != "" {
+		selector = fmt.Sprintf("%s=%s", types.ServiceLabel, opts.Service)
+	}
+	// The pods are only filtered by service, the VO of the jobs is checked instead
+	podListOpts := metav1.ListOptions{
+		LabelSelector: selector,
+	}
+	if opts.VO != "" {
+		selector = fmt.Sprintf("%s,vo=%s", selector, opts.VO)
+	}
+	listOpts := metav1.ListOptions{
+		LabelSelector: selector,
+	}
+
+	jobs, err := kubeClientset.BatchV1().Jobs(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), podListOpts)
+	if err != nil {
+		return nil, err
+	}
+	jobPods := map[string]v1.Pod{}
+	for _, pod := range pods.Items {
+		if jobName, ok := pod.Labels["job-name"]; ok {
+			jobPods[jobName] = pod
+		}
+	}
+
+	clusterJobs := []types.ClusterJob{}
+	for _, job := range jobs.Items {
+		creation := job.CreationTimestamp
+		revision, _ := strconv.Atoi(job.Labels[types.RevisionLabel])
+		clusterJob := types.ClusterJob{
+			Name:    job.Name,
+			Service: job.Labels[types.ServiceLabel],
+			VO:      job.Labels["vo"],
+			JobInfo: types.JobInfo{
+				Status:       types.JobPending,
+				CreationTime: &creation,
+				Revision:     revision,
+			},
+		}
+		if pod, ok := jobPods[job.Name]; ok {
+			setPodInfo(&clusterJob.JobInfo, pod)
+		}
+		if status := getJobStatus(&job); status != "" {
+			clusterJob.Status = status
+		}
+		clusterJobs = append(clusterJobs, clusterJob)
+	}
+
+	return clusterJobs, nil
+}
+
+// getJobStatus returns the status of a suspended or finished job (empty otherwise, as the status is given by its pod)
+func getJobStatus(job *batchv1.Job) string {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != v1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return types.JobSucceeded
+		case batchv1.JobFailed:
+			return types.JobFailed
+		}
+	}
+	if job.Spec.Suspend != nil && *job.Spec.Suspend {
+		return types.JobSuspended
+	}
+	return ""
+}
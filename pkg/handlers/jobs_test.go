@@ -0,0 +1,150 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeJobListHandler(t *testing.T) {
+	namespace := "oscar-svc"
+	suspend := true
+	makeJob := func(name string, service string, vo string, age time.Duration) *batchv1.Job {
+		labels := map[string]string{types.ServiceLabel: service}
+		if vo != "" {
+			labels["vo"] = vo
+		}
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				Labels:            labels,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+		}
+	}
+	makePod := func(job string, service string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      job + "-pod",
+				Namespace: namespace,
+				Labels:    map[string]string{types.ServiceLabel: service, "job-name": job},
+			},
+			Status: v1.PodStatus{Phase: phase},
+		}
+	}
+
+	running := makeJob("running", "a", "vo1", 48*time.Hour)
+	completed := makeJob("completed", "a", "vo1", time.Hour)
+	completed.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	suspended := makeJob("suspended", "b", "", 2*time.Hour)
+	suspended.Spec.Suspend = &suspend
+	pending := makeJob("pending", "b", "vo2", 30*time.Minute)
+	private := makeJob("private", "c", "", time.Minute)
+
+	kubeClientset := testclient.NewSimpleClientset(
+		running, completed, suspended, pending, private,
+		makePod("running", "a", v1.PodRunning),
+		makePod("completed", "a", v1.PodSucceeded),
+	)
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "a"})
+	back.CreateService(types.Service{Name: "b", Owner: "user"})
+	back.CreateService(types.Service{Name: "c", Owner: "other"})
+	cfg := testConfigValidRun
+	cfg.Username = "admin"
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.GET("/system/jobs", MakeJobListHandler(&cfg, back, kubeClientset, namespace))
+
+	scenarios := []struct {
+		name             string
+		query            string
+		expectedCode     int
+		expectedJobs     []string
+		expectedStatuses []string
+		expectedTotal    string
+	}{
+		{"all", "", http.StatusOK, []string{"pending", "completed", "suspended", "running"}, []string{"Pending", "Succeeded", "Suspended", "Running"}, "4"},
+		{"stuck running jobs", "?status=running&olderThan=24h", http.StatusOK, []string{"running"}, []string{"Running"}, "1"},
+		{"vo", "?vo=vo1&sort=name", http.StatusOK, []string{"completed", "running"}, []string{"Succeeded", "Running"}, "2"},
+		{"service", "?service=b&limit=1", http.StatusOK, []string{"pending"}, []string{"Pending"}, "2"},
+		{"invalid status", "?status=unknown", http.StatusBadRequest, nil, nil, ""},
+		{"invalid olderThan", "?olderThan=1week", http.StatusBadRequest, nil, nil, ""},
+		{"offset and continue", "?offset=1&continue=abc", http.StatusBadRequest, nil, nil, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/system/jobs"+s.query, nil)
+			req.Header.Set("X-User", "user")
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode != http.StatusOK {
+				if w.Header().Get(errorCodeHeader) != types.ErrBadRequest.Code {
+					t.Errorf("expecting error code %s, got %s", types.ErrBadRequest.Code, w.Header().Get(errorCodeHeader))
+				}
+				return
+			}
+
+			var jobs []types.ClusterJob
+			if err := json.Unmarshal(w.Body.Bytes(), &jobs); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(jobs) != len(s.expectedJobs) {
+				t.Fatalf("expecting jobs %v, got %v", s.expectedJobs, jobs)
+			}
+			for i, job := range jobs {
+				if job.Name != s.expectedJobs[i] || job.Status != s.expectedStatuses[i] {
+					t.Errorf("expecting job %s (%s), got %s (%s)", s.expectedJobs[i], s.expectedStatuses[i], job.Name, job.Status)
+				}
+			}
+			if total := w.Header().Get(totalCountHeader); total != s.expectedTotal {
+				t.Errorf("expecting total %s, got %s", s.expectedTotal, total)
+			}
+		})
+	}
+
+	// The admins get the jobs of all the services
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/jobs", nil)
+	req.Header.Set("X-User", "admin")
+	r.ServeHTTP(w, req)
+	if total := w.Header().Get(totalCountHeader); w.Code != http.StatusOK || total != "5" {
+		t.Errorf("expecting the 5 jobs for the admin, got %d (total %s)", w.Code, total)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// defaultLatencyJobs default number of jobs of the latency reports
+	defaultLatencyJobs = 20
+	// maxLatencyJobs maximum number of jobs of the latency reports
+	maxLatencyJobs = 100
+	// scriptStartLogBytes bytes of the logs of the jobs read to find the start of the script
+	scriptStartLogBytes int64 = 64 * 1024
+)
+
+// scriptStartRegexp log line of the faas-supervisor when it starts executing the user script
+var scriptStartRegexp = regexp.MustCompile(`(?i)executing user (defined )?script`)
+
+// MakeServiceLatencyHandler makes a handler to report the latency of the phases of the last jobs of a service
+// (dispatch, scheduling, container startup, initialization and execution), aggregated per phase
+func MakeServiceLatencyHandler(back types.ServerlessBackend, kubeClientset kubernetes.Interface, namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultLatencyJobs
+		if value := c.Query("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxLatencyJobs {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid limit \"%s\", it must be between 1 and %d", value, maxLatencyJobs))
+				return
+			}
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		listOpts := metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", types.ServiceLabel, service.Name),
+		}
+		jobs, err := kubeClientset.BatchV1().Jobs(namespace).List(context.TODO(), listOpts)
+		if err != nil {
+			sendError(c, types.ErrJobReadFailed, err.Error())
+			return
+		}
+		pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+		if err != nil {
+			sendError(c, types.ErrJobReadFailed, err.Error())
+			return
+		}
+		jobPods := map[string][]v1.Pod{}
+		for _, pod := range pods.Items {
+			jobName := pod.Labels["job-name"]
+			jobPods[jobName] = append(jobPods[jobName], pod)
+		}
+
+		// Newest jobs first
+		sort.Slice(jobs.Items, func(i, j int) bool {
+			return jobs.Items[j].CreationTimestamp.Before(&jobs.Items[i].CreationTimestamp)
+		})
+		if len(jobs.Items) > limit {
+			jobs.Items = jobs.Items[:limit]
+		}
+
+		latencies := []types.JobLatency{}
+		for i := range jobs.Items {
+			job := &jobs.Items[i]
+			details := getJobDetails(job, jobPods[job.Name])
+			latencies = append(latencies, types.JobLatency{
+				Name:    job.Name,
+				Status:  details.Status,
+				Timings: *readJobTimings(kubeClientset, job, jobPods[job.Name]),
+			})
+		}
+
+		c.JSON(http.StatusOK, types.NewServiceLatencyReport(service.Name, service.LatencyBudget, latencies))
+	}
+}
+
+// readJobTimings returns the timings of a job, reading the logs of its newest pod to find the start of the script
+func readJobTimings(kubeClientset kubernetes.Interface, job *batchv1.Job, pods []v1.Pod) *types.JobTimings {
+	var pod *v1.Pod
+	for i := range pods {
+		if pod == nil || pod.CreationTimestamp.Before(&pods[i].CreationTimestamp) {
+			pod = &pods[i]
+		}
+	}
+
+	logs := ""
+	if pod != nil && getContainerStartTime(pod) != nil {
+		limitBytes := scriptStartLogBytes
+		podLogOpts := &v1.PodLogOptions{Container: types.ContainerName, Timestamps: true, LimitBytes: &limitBytes}
+		if raw, err := kubeClientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOpts).DoRaw(context.TODO()); err == nil {
+			logs = string(raw)
+		}
+	}
+
+	return getJobTimings(job, pod, logs)
+}
+
+// getJobTimings returns the timings of a job from its definition, its newest pod (nil if not created) and the
+// logs of the pod (with timestamps)
+func getJobTimings(job *batchv1.Job, pod *v1.Pod, logs string) *types.JobTimings {
+	timings := &types.JobTimings{}
+	if t, err := time.Parse(time.RFC3339Nano, job.Annotations[types.EventReceivedAnnotation]); err == nil {
+		timings.EventReceived = &t
+	}
+	if !job.CreationTimestamp.IsZero() {
+		t := job.CreationTimestamp.Time
+		timings.JobCreated = &t
+	}
+
+	if pod != nil {
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionTrue {
+				t := cond.LastTransitionTime.Time
+				timings.PodScheduled = &t
+			}
+		}
+		timings.ContainerStarted = getContainerStartTime(pod)
+		for _, contStatus := range pod.Status.ContainerStatuses {
+			if contStatus.Name == types.ContainerName && contStatus.State.Terminated != nil {
+				t := contStatus.State.Terminated.FinishedAt.Time
+				timings.Finished = &t
+			}
+		}
+	}
+
+	// The script starts with the first log line of the supervisor executing it
+	for _, line := range strings.Split(logs, "\n") {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 && scriptStartRegexp.MatchString(parts[1]) {
+			if t, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+				timings.ScriptStarted = &t
+				break
+			}
+		}
+	}
+
+	timings.SetPhases()
+	return timings
+}
+
+// getContainerStartTime returns the time when the service container of a pod started (nil if not started)
+func getContainerStartTime(pod *v1.Pod) *time.Time {
+	for _, contStatus := range pod.Status.ContainerStatuses {
+		if contStatus.Name != types.ContainerName {
+			continue
+		}
+		if contStatus.State.Running != nil {
+			t := contStatus.State.Running.StartedAt.Time
+			return &t
+		}
+		if contStatus.State.Terminated != nil && !contStatus.State.Terminated.StartedAt.IsZero() {
+			t := contStatus.State.Terminated.StartedAt.Time
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetJobTimings(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "job",
+			CreationTimestamp: metav1.NewTime(start.Add(time.Second)),
+			Annotations:       map[string]string{types.EventReceivedAnnotation: start.Format(time.RFC3339Nano)},
+		},
+	}
+	pod := &v1.Pod{
+		Status: v1.PodStatus{
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(start.Add(3 * time.Second))},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: types.ContainerName, State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+					StartedAt:  metav1.NewTime(start.Add(13 * time.Second)),
+					FinishedAt: metav1.NewTime(start.Add(60 * time.Second)),
+				}}},
+			},
+		},
+	}
+	logs := start.Add(14*time.Second).Format(time.RFC3339Nano) + " 2024-01-01 00:00:14,000 - supervisor - INFO - Downloading input\n" +
+		start.Add(15*time.Second).Format(time.RFC3339Nano) + " 2024-01-01 00:00:15,000 - supervisor - INFO - Executing user defined script\n"
+
+	timings := getJobTimings(job, pod, logs)
+	expected := map[string]float64{
+		types.LatencyPhaseDispatch:         1,
+		types.LatencyPhaseScheduling:       2,
+		types.LatencyPhaseContainerStartup: 10,
+		types.LatencyPhaseInitialization:   2,
+		types.LatencyPhaseExecution:        45,
+	}
+	for phase, d := range expected {
+		if timings.Phases[phase] != d {
+			t.Errorf("expecting %s %v, got %v", phase, d, timings.Phases[phase])
+		}
+	}
+	if timings.Startup == nil || *timings.Startup != 15 {
+		t.Errorf("expecting startup 15, got %v", timings.Startup)
+	}
+
+	// Pending job without pod
+	timings = getJobTimings(job, nil, "")
+	if len(timings.Phases) != 1 || timings.PodScheduled != nil {
+		t.Errorf("expecting only the dispatch phase, got %v", timings.Phases)
+	}
+}
+
+func TestMakeServiceLatencyHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", LatencyBudget: 30})
+	kubeClientset := testclient.NewSimpleClientset()
+	now := time.Now()
+	for i, name := range []string{"old", "new"} {
+		created := now.Add(time.Duration(i) * time.Minute)
+		kubeClientset.BatchV1().Jobs("").Create(context.TODO(), &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Labels:            map[string]string{types.ServiceLabel: "test"},
+				Annotations:       map[string]string{types.EventReceivedAnnotation: created.Add(-time.Second).Format(time.RFC3339Nano)},
+				CreationTimestamp: metav1.NewTime(created),
+			},
+		}, metav1.CreateOptions{})
+	}
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/latency", MakeServiceLatencyHandler(back, kubeClientset, ""))
+
+	scenarios := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expectedJobs []string
+	}{
+		{"report", "/system/services/test/latency", http.StatusOK, []string{"new", "old"}},
+		{"limit", "/system/services/test/latency?limit=1", http.StatusOK, []string{"new"}},
+		{"invalid limit", "/system/services/test/latency?limit=0", http.StatusBadRequest, nil},
+		{"service not found", "/system/services/other/latency", http.StatusNotFound, nil},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode != http.StatusOK {
+				return
+			}
+			var report types.ServiceLatencyReport
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Jobs) != len(s.expectedJobs) {
+				t.Fatalf("expecting %d jobs, got %d", len(s.expectedJobs), len(report.Jobs))
+			}
+			for i, name := range s.expectedJobs {
+				if report.Jobs[i].Name != name {
+					t.Errorf("expecting job \"%s\" in position %d, got \"%s\"", name, i, report.Jobs[i].Name)
+				}
+			}
+			if report.Budget != 30 || report.Phases[types.LatencyPhaseDispatch].Count != len(s.expectedJobs) || report.Bottleneck != types.LatencyPhaseDispatch {
+				t.Errorf("unexpected report: %+v", report)
+			}
+		})
+	}
+}
@@ -17,21 +17,74 @@ limitations under the License.
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/types"
 )
 
-// MakeListHandler makes a handler for listing services
-func MakeListHandler(back types.ServerlessBackend) gin.HandlerFunc {
+// Headers of the paginated service list
+const (
+	totalCountHeader = "X-Total-Count"
+	continueHeader   = "X-Continue"
+)
+
+// MakeListHandler makes a handler for listing the services accessible by the user (all of them for the admins).
+// The querystrings "label", "vo", "sort", "limit", "offset" and "continue" filter, sort and paginate the list
+func MakeListHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		opts, err := parseServiceListOptions(c)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid list options: %v", err))
+			return
+		}
+
 		services, err := back.ListServices()
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			sendError(c, types.ErrServiceReadFailed, err.Error())
 			return
 		}
+		page, total, next := opts.Apply(filterAccessibleServices(c, cfg, services))
+		c.Header(totalCountHeader, strconv.Itoa(total))
+		if next != "" {
+			c.Header(continueHeader, next)
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// parseServiceListOptions returns the options of the service list from the querystring
+func parseServiceListOptions(c *gin.Context) (types.ServiceListOptions, error) {
+	opts := types.ServiceListOptions{
+		Labels: c.QueryArray("label"),
+		VO:     c.Query("vo"),
+		Sort:   c.Query("sort"),
+	}
+
+	var err error
+	if limit := c.Query("limit"); limit != "" {
+		if opts.Limit, err = strconv.Atoi(limit); err != nil || opts.Limit < 1 {
+			return opts, fmt.Errorf("invalid limit \"%s\", it must be between 1 and %d", limit, types.MaxServiceListLimit)
+		}
+	}
 
-		c.JSON(http.StatusOK, services)
+	offset, hasOffset := c.GetQuery("offset")
+	token, hasContinue := c.GetQuery("continue")
+	switch {
+	case hasOffset && hasContinue:
+		return opts, fmt.Errorf("the offset and continue querystrings can't be used together")
+	case hasOffset:
+		if opts.Offset, err = strconv.Atoi(offset); err != nil {
+			return opts, fmt.Errorf("invalid offset \"%s\", it must be a non-negative integer", offset)
+		}
+	case hasContinue:
+		if opts.Offset, err = types.ParseServiceListContinue(token); err != nil {
+			return opts, err
+		}
 	}
+
+	return opts, opts.Validate()
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -24,13 +25,14 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
 )
 
 func TestMakeListHandler(t *testing.T) {
 	back := backends.MakeFakeBackend()
 
 	r := gin.Default()
-	r.GET("/system/services", MakeListHandler(back))
+	r.GET("/system/services", MakeListHandler(&testConfigValidRun, back))
 
 	scenarios := []struct {
 		name        string
@@ -64,3 +66,63 @@ func TestMakeListHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeListHandlerOptions(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	for _, name := range []string{"c", "a", "b"} {
+		back.CreateService(types.Service{Name: name, VO: "vo1"})
+	}
+	back.CreateService(types.Service{Name: "d", VO: "vo2"})
+
+	r := gin.Default()
+	r.GET("/system/services", MakeListHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name             string
+		query            string
+		expectedCode     int
+		expectedNames    []string
+		expectedTotal    string
+		expectedContinue string
+	}{
+		{"first page", "limit=2", http.StatusOK, []string{"a", "b"}, "4", types.MakeServiceListContinue(2)},
+		{"continue", "limit=2&continue=" + types.MakeServiceListContinue(2), http.StatusOK, []string{"c", "d"}, "4", ""},
+		{"vo sorted", "vo=vo1&sort=-name", http.StatusOK, []string{"c", "b", "a"}, "3", ""},
+		{"invalid limit", "limit=abc", http.StatusBadRequest, nil, "", ""},
+		{"invalid sort", "sort=memory", http.StatusBadRequest, nil, "", ""},
+		{"offset and continue", "offset=1&continue=" + types.MakeServiceListContinue(2), http.StatusBadRequest, nil, "", ""},
+		{"invalid continue", "continue=abc", http.StatusBadRequest, nil, "", ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/system/services?"+s.query, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+			if s.expectedCode != http.StatusOK {
+				return
+			}
+
+			var services []types.Service
+			json.Unmarshal(w.Body.Bytes(), &services)
+			if len(services) != len(s.expectedNames) {
+				t.Fatalf("expecting %d services, got %d", len(s.expectedNames), len(services))
+			}
+			for i, name := range s.expectedNames {
+				if services[i].Name != name {
+					t.Errorf("expecting service %d to be \"%s\", got \"%s\"", i, name, services[i].Name)
+				}
+			}
+			if total := w.Header().Get(totalCountHeader); total != s.expectedTotal {
+				t.Errorf("expecting total \"%s\", got \"%s\"", s.expectedTotal, total)
+			}
+			if next := w.Header().Get(continueHeader); next != s.expectedContinue {
+				t.Errorf("expecting continue token \"%s\", got \"%s\"", s.expectedContinue, next)
+			}
+		})
+	}
+}
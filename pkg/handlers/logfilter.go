@@ -0,0 +1,144 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// logLevels levels of the faas-supervisor's log lines, by severity
+var logLevels = map[string]int{"DEBUG": 0, "INFO": 1, "WARNING": 2, "ERROR": 3, "CRITICAL": 4}
+
+// supervisorLogRegexp format of the faas-supervisor's log lines ("<DATE> <TIME> - <LOGGER> - <LEVEL> - <MESSAGE>")
+var supervisorLogRegexp = regexp.MustCompile(`^\S+ \S+ - \S+ - ([A-Z]+) - `)
+
+// logFilter filter of the log lines of the jobs, set by the 'since', 'until', 'level' and 'grep' querystrings
+type logFilter struct {
+	since   *time.Time
+	until   *time.Time
+	pattern *regexp.Regexp
+	// level minimum level of the lines (-1 to not filter by level)
+	level int
+	// timestamps keep the timestamps of the lines
+	timestamps bool
+	// timestamped the lines to filter are prefixed by their timestamps
+	timestamped bool
+}
+
+// parseLogFilter returns the filter of the request's querystrings. patternQuery is the name of the querystring
+// with the regular expression to match
+func parseLogFilter(c *gin.Context, patternQuery string, timestamps bool) (*logFilter, error) {
+	f := &logFilter{level: -1, timestamps: timestamps, timestamped: timestamps}
+	for query, t := range map[string]**time.Time{"since": &f.since, "until": &f.until} {
+		if value := c.Query(query); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s \"%s\", it must be a RFC 3339 time", query, value)
+			}
+			*t = &parsed
+		}
+	}
+	if f.since != nil && f.until != nil && f.until.Before(*f.since) {
+		return nil, fmt.Errorf("the until time must be after the since time")
+	}
+	// The timestamps are needed to filter by time
+	if f.since != nil || f.until != nil {
+		f.timestamped = true
+	}
+	if value := c.Query(patternQuery); value != "" {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern \"%s\": %v", patternQuery, value, err)
+		}
+		f.pattern = pattern
+	}
+	if value := c.Query("level"); value != "" {
+		level, ok := logLevels[strings.ToUpper(value)]
+		if !ok {
+			return nil, fmt.Errorf("invalid level \"%s\" (valid levels are DEBUG, INFO, WARNING, ERROR and CRITICAL)", value)
+		}
+		f.level = level
+	}
+	return f, nil
+}
+
+// active checks if the filter discards or modifies any line
+func (f *logFilter) active() bool {
+	return f.since != nil || f.until != nil || f.pattern != nil || f.level >= 0 || f.timestamped != f.timestamps
+}
+
+// setPodLogOptions sets the options to read the logs to filter
+func (f *logFilter) setPodLogOptions(opts *v1.PodLogOptions) {
+	opts.Timestamps = f.timestamped
+	if f.since != nil {
+		opts.SinceTime = &metav1.Time{Time: *f.since}
+	}
+}
+
+// apply returns the line to send (removing the timestamp if not requested) and whether it passes the filter
+func (f *logFilter) apply(line string) (string, bool) {
+	msg := line
+	if f.timestamped {
+		// Lines prefixed by the Kubernetes timestamp ("<RFC3339Nano> <LINE>")
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 {
+			msg = parts[1]
+			if t, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+				if (f.since != nil && t.Before(*f.since)) || (f.until != nil && t.After(*f.until)) {
+					return "", false
+				}
+			}
+		}
+		if !f.timestamps {
+			line = msg
+		}
+	}
+	if f.level >= 0 {
+		match := supervisorLogRegexp.FindStringSubmatch(msg)
+		if match == nil {
+			return "", false
+		}
+		if level, ok := logLevels[match[1]]; !ok || level < f.level {
+			return "", false
+		}
+	}
+	if f.pattern != nil && !f.pattern.MatchString(msg) {
+		return "", false
+	}
+	return line, true
+}
+
+// filterLogs returns the lines of the logs passing the filter
+func (f *logFilter) filterLogs(logs string) []string {
+	lines := []string{}
+	if logs == "" {
+		return lines
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(logs, "\n"), "\n") {
+		if line, ok := f.apply(line); ok {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
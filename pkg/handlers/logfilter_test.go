@@ -0,0 +1,148 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLogFilter(t *testing.T) {
+	logs := "2023-01-01 10:00:00,000 - supervisor - INFO - Starting\n" +
+		"2023-01-01 10:00:01,000 - supervisor - DEBUG - Downloading input\n" +
+		"script output\n" +
+		"2023-01-01 10:00:03,000 - supervisor - ERROR - Error uploading output\n"
+	timestampedLogs := "2023-01-01T10:00:00Z 2023-01-01 10:00:00,000 - supervisor - INFO - Starting\n" +
+		"2023-01-01T10:00:01Z 2023-01-01 10:00:01,000 - supervisor - DEBUG - Downloading input\n" +
+		"2023-01-01T10:00:02Z script output\n" +
+		"2023-01-01T10:00:03Z 2023-01-01 10:00:03,000 - supervisor - ERROR - Error uploading output\n"
+
+	scenarios := []struct {
+		name  string
+		query string
+		// readTimestamps the logs must be read with timestamps
+		readTimestamps bool
+		expected       []string
+	}{
+		{"no filter", "", false, []string{
+			"2023-01-01 10:00:00,000 - supervisor - INFO - Starting",
+			"2023-01-01 10:00:01,000 - supervisor - DEBUG - Downloading input",
+			"script output",
+			"2023-01-01 10:00:03,000 - supervisor - ERROR - Error uploading output",
+		}},
+		{"time range", "?since=2023-01-01T10:00:01Z&until=2023-01-01T10:00:02Z", true, []string{
+			"2023-01-01 10:00:01,000 - supervisor - DEBUG - Downloading input",
+			"script output",
+		}},
+		{"level", "?level=info", false, []string{
+			"2023-01-01 10:00:00,000 - supervisor - INFO - Starting",
+			"2023-01-01 10:00:03,000 - supervisor - ERROR - Error uploading output",
+		}},
+		{"grep with timestamps", "?grep=put&timestamps=true", true, []string{
+			"2023-01-01T10:00:01Z 2023-01-01 10:00:01,000 - supervisor - DEBUG - Downloading input",
+			"2023-01-01T10:00:02Z script output",
+			"2023-01-01T10:00:03Z 2023-01-01 10:00:03,000 - supervisor - ERROR - Error uploading output",
+		}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest("GET", "/"+s.query, nil)
+			filter, err := parseLogFilter(c, "grep", c.Query("timestamps") == "true")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			opts := &v1.PodLogOptions{}
+			filter.setPodLogOptions(opts)
+			if opts.Timestamps != s.readTimestamps {
+				t.Fatalf("expecting timestamps %t in the log options", s.readTimestamps)
+			}
+			input := logs
+			if opts.Timestamps {
+				input = timestampedLogs
+			}
+			if lines := filter.filterLogs(input); !reflect.DeepEqual(lines, s.expected) {
+				t.Errorf("expecting lines %v, got %v", s.expected, lines)
+			}
+		})
+	}
+
+	for _, query := range []string{"?since=yesterday", "?grep=(", "?level=verbose", "?since=2023-01-02T00:00:00Z&until=2023-01-01T00:00:00Z"} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/"+query, nil)
+		if _, err := parseLogFilter(c, "grep", false); err == nil {
+			t.Errorf("expecting error for %s", query)
+		}
+	}
+}
+
+func TestSearchJobsLogs(t *testing.T) {
+	namespace := testConfigValidRun.ServicesNamespace
+	newPod := func(name string, job string, created time.Time) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(created),
+			Labels:            map[string]string{types.ServiceLabel: "test", "job-name": job},
+		}}
+	}
+	now := time.Now()
+	// The fake clientset returns "fake logs" as logs of every pod
+	kubeClientset := testclient.NewSimpleClientset(newPod("job-1-pod", "job-1", now.Add(-time.Hour)), newPod("job-2-pod", "job-2", now))
+
+	r := gin.Default()
+	r.GET("/system/logs/:serviceName", func(c *gin.Context) {
+		searchJobsLogs(c, kubeClientset, namespace, c.Param("serviceName"))
+	})
+
+	scenarios := []struct {
+		query        string
+		expectedCode int
+		expected     []types.JobLogMatches
+	}{
+		{"?search=fake", http.StatusOK, []types.JobLogMatches{{Job: "job-2", Lines: []string{"fake logs"}}, {Job: "job-1", Lines: []string{"fake logs"}}}},
+		{"?search=missing", http.StatusOK, []types.JobLogMatches{}},
+		{"?search=(", http.StatusBadRequest, nil},
+	}
+	for _, s := range scenarios {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/system/logs/test"+s.query, nil)
+		r.ServeHTTP(w, req)
+
+		if w.Code != s.expectedCode {
+			t.Fatalf("expecting code %d, got %d", s.expectedCode, w.Code)
+		}
+		if s.expected == nil {
+			continue
+		}
+		matches := []types.JobLogMatches{}
+		if err := json.Unmarshal(w.Body.Bytes(), &matches); err != nil || !reflect.DeepEqual(matches, s.expected) {
+			t.Errorf("expecting matches %v, got %s", s.expected, w.Body.String())
+		}
+	}
+}
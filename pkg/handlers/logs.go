@@ -17,84 +17,351 @@ limitations under the License.
 package handlers
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-// MakeJobsInfoHandler makes a handler for listing all existing jobs from a service and show their JobInfo
+const (
+	// defaultOutputURLsExpiration default expiration (in seconds) of the presigned URLs of the jobs' output files
+	defaultOutputURLsExpiration = 3600
+	// maxOutputURLsExpiration maximum expiration (in seconds) of the presigned URLs (7 days, the S3 limit)
+	maxOutputURLsExpiration = 7 * 24 * 3600
+)
+
+// MakeJobsInfoHandler makes a handler for listing all existing jobs from a service and show their JobInfo.
+// If the 'search' querystring is set, the lines of the jobs' logs matching it are returned instead
 func MakeJobsInfoHandler(kubeClientset *kubernetes.Clientset, namespace string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		jobsInfo := make(map[string]*types.JobInfo)
-
 		// Get serviceName
 		serviceName := c.Param("serviceName")
 
-		// List jobs
-		listOpts := metav1.ListOptions{
-			LabelSelector: fmt.Sprintf("%s=%s", types.ServiceLabel, serviceName),
+		if _, ok := c.GetQuery("search"); ok {
+			searchJobsLogs(c, kubeClientset, namespace, serviceName)
+			return
 		}
 
-		jobs, err := kubeClientset.BatchV1().Jobs(namespace).List(context.TODO(), listOpts)
+		jobsInfo, err := getJobsInfo(kubeClientset, namespace, serviceName)
 		if err != nil {
 			// Check if error is caused because the service is not found
 			if errors.IsNotFound(err) || errors.IsGone(err) {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrServiceNotFound, "")
 			} else {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrJobReadFailed, err.Error())
 			}
 			return
 		}
 
-		// Populate jobsInfo with keys (job names) and creation time
-		for _, job := range jobs.Items {
-			if job.Status.StartTime != nil {
-				jobsInfo[job.Name] = &types.JobInfo{
-					CreationTime: job.Status.StartTime,
-				}
+		c.JSON(http.StatusOK, jobsInfo)
+	}
+}
+
+// getJobsInfo returns the JobInfo of all existing jobs from a service
+func getJobsInfo(kubeClientset kubernetes.Interface, namespace string, serviceName string) (map[string]*types.JobInfo, error) {
+	jobsInfo := make(map[string]*types.JobInfo)
+
+	// List jobs
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", types.ServiceLabel, serviceName),
+	}
+
+	jobs, err := kubeClientset.BatchV1().Jobs(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Populate jobsInfo with keys (job names) and creation time
+	for _, job := range jobs.Items {
+		if job.Status.StartTime != nil {
+			revision, _ := strconv.Atoi(job.Labels[types.RevisionLabel])
+			jobsInfo[job.Name] = &types.JobInfo{
+				CreationTime: job.Status.StartTime,
+				Revision:     revision,
 			}
 		}
+	}
 
-		// List jobs' pods
-		pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+	// List jobs' pods
+	pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Populate jobsInfo with status, start and finish times (from pods)
+	for _, pod := range pods.Items {
+		if jobName, ok := pod.Labels["job-name"]; ok {
+			jobInfo, ok := jobsInfo[jobName]
+			if !ok {
+				continue
+			}
+			setPodInfo(jobInfo, pod)
+		}
+	}
+
+	return jobsInfo, nil
+}
+
+// searchJobsLogs sends the lines of the logs of all the jobs from a service matching the 'search' pattern (and
+// the rest of the log filters), newest jobs first. Jobs without matching lines are omitted
+func searchJobsLogs(c *gin.Context, kubeClientset kubernetes.Interface, namespace string, serviceName string) {
+	timestamps, err := strconv.ParseBool(c.DefaultQuery("timestamps", "false"))
+	if err != nil {
+		timestamps = false
+	}
+	filter, err := parseLogFilter(c, "search", timestamps)
+	if err != nil {
+		sendError(c, types.ErrBadRequest, err.Error())
+		return
+	}
+
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", types.ServiceLabel, serviceName),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		sendError(c, types.ErrJobReadFailed, err.Error())
+		return
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+
+	matches := []types.JobLogMatches{}
+	for _, pod := range pods.Items {
+		jobName, ok := pod.Labels["job-name"]
+		if !ok {
+			continue
+		}
+		podLogOpts := &v1.PodLogOptions{
+			Container: types.ContainerName,
+		}
+		filter.setPodLogOptions(podLogOpts)
+		logs, err := kubeClientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOpts).DoRaw(context.TODO())
+		if err != nil {
+			// The logs are not available (e.g. the container is not started)
+			continue
+		}
+		if lines := filter.filterLogs(string(logs)); len(lines) > 0 {
+			matches = append(matches, types.JobLogMatches{Job: jobName, Lines: lines})
+		}
+	}
+
+	c.JSON(http.StatusOK, matches)
+}
+
+// setPodInfo sets the status, start and finish times of a job from its pod
+func setPodInfo(jobInfo *types.JobInfo, pod v1.Pod) {
+	jobInfo.Status = string(pod.Status.Phase)
+	// Loop through job.Status.ContainerStatuses to find oscar-container
+	for _, contStatus := range pod.Status.ContainerStatuses {
+		if contStatus.Name == types.ContainerName {
+			if contStatus.State.Running != nil {
+				jobInfo.StartTime = &contStatus.State.Running.StartedAt
+			} else if contStatus.State.Terminated != nil {
+				jobInfo.StartTime = &contStatus.State.Terminated.StartedAt
+				jobInfo.FinishTime = &contStatus.State.Terminated.FinishedAt
+			}
+		}
+	}
+}
+
+// MakeJobStatusHandler makes a handler for getting the detailed status of a job (timing, exit details, resource
+// requests and triggering event)
+func MakeJobStatusHandler(kubeClientset kubernetes.Interface, namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sendJobStatus(c, kubeClientset, namespace, c.Param("serviceName"), c.Param("jobName"))
+	}
+}
+
+// MakeJobOutputsHandler makes a handler for getting the output files stored by a job in the MinIO outputs of its
+// service, correlated by the name of its input file, with presigned download URLs
+func MakeJobOutputsHandler(back types.ServerlessBackend, kubeClientset kubernetes.Interface, namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expires, err := strconv.Atoi(c.DefaultQuery("expires", strconv.Itoa(defaultOutputURLsExpiration)))
+		if err != nil || expires <= 0 || expires > maxOutputURLsExpiration {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("the expires parameter must be a number of seconds between 1 and %d", maxOutputURLsExpiration))
+			return
+		}
+
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+
+		job, err := getJob(kubeClientset, namespace, c.Param("jobName"))
 		if err != nil {
-			// Check if error is caused because the service is not found
 			if errors.IsNotFound(err) || errors.IsGone(err) {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrJobNotFound, "")
 			} else {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrJobReadFailed, err.Error())
 			}
 			return
 		}
+		// Return StatusNotFound if job exists but is not associated with the provided serviceName
+		if job.Labels[types.ServiceLabel] != service.Name {
+			sendError(c, types.ErrJobNotFound, "")
+			return
+		}
 
-		// Populate jobsInfo with status, start and finish times (from pods)
-		for _, pod := range pods.Items {
-			if jobName, ok := pod.Labels["job-name"]; ok {
-				jobsInfo[jobName].Status = string(pod.Status.Phase)
-				// Loop through job.Status.ContainerStatuses to find oscar-container
-				for _, contStatus := range pod.Status.ContainerStatuses {
-					if contStatus.Name == types.ContainerName {
-						if contStatus.State.Running != nil {
-							jobsInfo[jobName].StartTime = &contStatus.State.Running.StartedAt
-						} else if contStatus.State.Terminated != nil {
-							jobsInfo[jobName].StartTime = &contStatus.State.Terminated.StartedAt
-							jobsInfo[jobName].FinishTime = &contStatus.State.Terminated.FinishedAt
-						}
-					}
-				}
+		c.JSON(http.StatusOK, utils.ListJobOutputs(service, job, time.Duration(expires)*time.Second))
+	}
+}
+
+// sendJobStatus sends the detailed status of a service's job
+func sendJobStatus(c *gin.Context, kubeClientset kubernetes.Interface, namespace string, serviceName string, jobName string) {
+	job, err := getJob(kubeClientset, namespace, jobName)
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			sendError(c, types.ErrJobNotFound, "")
+		} else {
+			sendError(c, types.ErrJobReadFailed, err.Error())
+		}
+		return
+	}
+	// Return StatusNotFound if job exists but is not associated with the provided serviceName
+	if job.Labels[types.ServiceLabel] != serviceName {
+		sendError(c, types.ErrJobNotFound, "")
+		return
+	}
+
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", jobName),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(job.Namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		sendError(c, types.ErrJobReadFailed, err.Error())
+		return
+	}
+
+	status := getJobDetails(job, pods.Items)
+	status.Timings = readJobTimings(kubeClientset, job, pods.Items)
+	c.JSON(http.StatusOK, status)
+}
+
+// getJobDetails returns the detailed status of a job from its definition and its pods
+func getJobDetails(job *batchv1.Job, pods []v1.Pod) *types.JobStatus {
+	revision, _ := strconv.Atoi(job.Labels[types.RevisionLabel])
+	status := &types.JobStatus{
+		Name:    job.Name,
+		Service: job.Labels[types.ServiceLabel],
+		JobInfo: types.JobInfo{
+			Status:       types.JobPending,
+			CreationTime: &job.CreationTimestamp,
+			Revision:     revision,
+		},
+		RetryOf: job.Annotations[types.RetryOfAnnotation],
+		Event:   types.NewJobEventInfo(utils.GetJobEvent(job)),
+	}
+
+	for _, cont := range job.Spec.Template.Spec.Containers {
+		if cont.Name == types.ContainerName && len(cont.Resources.Requests) > 0 {
+			status.Requests = map[string]string{}
+			for name, quantity := range cont.Resources.Requests {
+				status.Requests[string(name)] = quantity.String()
 			}
 		}
+	}
 
-		c.JSON(http.StatusOK, jobsInfo)
+	// Take the details from the newest pod of the job
+	if len(pods) > 0 {
+		sort.Slice(pods, func(i, j int) bool {
+			return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+		})
+		pod := pods[0]
+		setPodInfo(&status.JobInfo, pod)
+		status.NodeName = pod.Spec.NodeName
+		for _, contStatus := range pod.Status.ContainerStatuses {
+			if contStatus.Name == types.ContainerName && contStatus.State.Terminated != nil {
+				exitCode := contStatus.State.Terminated.ExitCode
+				status.ExitCode = &exitCode
+				status.Reason = contStatus.State.Terminated.Reason
+				status.OOMKilled = status.Reason == "OOMKilled"
+			}
+		}
 	}
+	if jobStatus := getJobStatus(job); jobStatus != "" {
+		status.Status = jobStatus
+	}
+
+	return status
+}
+
+// getJob returns a job by its name. With the jobs of all the namespaces (VO namespaces enabled), the job is
+// searched by its name among the jobs of the services
+func getJob(kubeClientset kubernetes.Interface, namespace string, jobName string) (*batchv1.Job, error) {
+	if namespace != metav1.NamespaceAll {
+		return kubeClientset.BatchV1().Jobs(namespace).Get(context.TODO(), jobName, metav1.GetOptions{})
+	}
+	listOpts := metav1.ListOptions{
+		LabelSelector: types.ServiceLabel,
+		FieldSelector: fmt.Sprintf("metadata.name=%s", jobName),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		if jobs.Items[i].Name == jobName {
+			return &jobs.Items[i], nil
+		}
+	}
+	return nil, errors.NewNotFound(batchv1.Resource("jobs"), jobName)
+}
+
+// deleteJobs deletes the jobs selected by listOpts. The collections can only be deleted by namespace, so the jobs
+// of all the namespaces (VO namespaces enabled) are deleted one by one
+func deleteJobs(kubeClientset kubernetes.Interface, namespace string, delOpts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	if namespace != metav1.NamespaceAll {
+		return kubeClientset.BatchV1().Jobs(namespace).DeleteCollection(context.TODO(), delOpts, listOpts)
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs.Items {
+		if err := kubeClientset.BatchV1().Jobs(job.Namespace).Delete(context.TODO(), job.Name, delOpts); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLastJobs returns the summary of the last n jobs from a service, sorted from newest to oldest
+func getLastJobs(kubeClientset kubernetes.Interface, namespace string, serviceName string, n int) ([]types.JobSummary, error) {
+	jobsInfo, err := getJobsInfo(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []types.JobSummary{}
+	for name, info := range jobsInfo {
+		summaries = append(summaries, types.JobSummary{Name: name, JobInfo: *info})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[j].CreationTime.Before(summaries[i].CreationTime)
+	})
+
+	if n >= 0 && len(summaries) > n {
+		summaries = summaries[:n]
+	}
+
+	return summaries, nil
 }
 
 // MakeDeleteJobsHandler makes a handler for deleting all jobs created by the provided service.
@@ -125,13 +392,13 @@ func MakeDeleteJobsHandler(kubeClientset *kubernetes.Clientset, namespace string
 			PropagationPolicy: &background,
 		}
 
-		err = kubeClientset.BatchV1().Jobs(namespace).DeleteCollection(context.TODO(), delOpts, listOpts)
+		err = deleteJobs(kubeClientset, namespace, delOpts, listOpts)
 		if err != nil {
 			// Check if error is caused because the service is not found
 			if !errors.IsNotFound(err) && !errors.IsGone(err) {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrJobDeleteFailed, err.Error())
 			} else {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrServiceNotFound, "")
 			}
 			return
 		}
@@ -140,8 +407,10 @@ func MakeDeleteJobsHandler(kubeClientset *kubernetes.Clientset, namespace string
 	}
 }
 
-// MakeGetLogsHandler makes a handler for getting logs from the 'oscar-container' inside the pod created by the specified job
-func MakeGetLogsHandler(kubeClientset *kubernetes.Clientset, namespace string) gin.HandlerFunc {
+// MakeGetLogsHandler makes a handler for getting logs from the 'oscar-container' inside the pod created by the specified job.
+// If the job has no pods and the logs archive is enabled, the archived logs are returned
+func MakeGetLogsHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	namespace := cfg.GetJobsNamespace()
 	return func(c *gin.Context) {
 		// Get serviceName and jobName
 		serviceName := c.Param("serviceName")
@@ -151,45 +420,200 @@ func MakeGetLogsHandler(kubeClientset *kubernetes.Clientset, namespace string) g
 		if err != nil {
 			timestamps = false
 		}
+		filter, err := parseLogFilter(c, "grep", timestamps)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
 
 		// Get job's pod (assuming there's only one pod per job)
 		listOpts := metav1.ListOptions{
 			LabelSelector: fmt.Sprintf("%s=%s,job-name=%s", types.ServiceLabel, serviceName, jobName),
 		}
 		pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
-		if err != nil || len(pods.Items) < 1 {
+		if err != nil {
 			// Check if error is caused because the service is not found
 			if !errors.IsNotFound(err) && !errors.IsGone(err) {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrJobReadFailed, err.Error())
 			} else {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrJobNotFound, "")
 			}
 			return
 		}
+		if len(pods.Items) < 1 {
+			if !cfg.LogsArchiveEnable {
+				sendError(c, types.ErrJobNotFound, "")
+				return
+			}
+			logs, err := utils.GetArchivedJobLogs(cfg, serviceName, jobName)
+			if err != nil {
+				sendCodedError(c, err, types.ErrJobReadFailed)
+				return
+			}
+			filter.timestamped = true
+			sendFilteredLogs(c, filter, logs)
+			return
+		}
 
 		// Get logs
 		podLogOpts := &v1.PodLogOptions{
-			Timestamps: timestamps,
-			Container:  types.ContainerName,
+			Container: types.ContainerName,
 		}
-		req := kubeClientset.CoreV1().Pods(namespace).GetLogs(pods.Items[0].Name, podLogOpts)
+		filter.setPodLogOptions(podLogOpts)
+		req := kubeClientset.CoreV1().Pods(pods.Items[0].Namespace).GetLogs(pods.Items[0].Name, podLogOpts)
 		result := req.Do(context.TODO())
 
 		// Check result status code
 		statusCode := new(int)
 		if result.StatusCode(statusCode); *statusCode != 200 {
-			c.Status(http.StatusNotFound)
+			sendError(c, types.ErrJobNotFound, "")
 			return
 		}
 
 		logs, err := result.Raw()
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			sendError(c, types.ErrJobReadFailed, err.Error())
 			return
 		}
 
+		sendFilteredLogs(c, filter, logs)
+	}
+}
+
+// sendFilteredLogs sends the lines of the logs passing the filter
+func sendFilteredLogs(c *gin.Context, filter *logFilter, logs []byte) {
+	if !filter.active() {
 		c.String(http.StatusOK, string(logs))
+		return
+	}
+	lines := filter.filterLogs(string(logs))
+	if len(lines) == 0 {
+		c.String(http.StatusOK, "")
+		return
 	}
+	c.String(http.StatusOK, strings.Join(lines, "\n")+"\n")
+}
+
+// logsHeartbeatInterval interval of the heartbeats sent while streaming logs with no new lines
+var logsHeartbeatInterval = 15 * time.Second
+
+// MakeJobLogsStreamHandler makes a handler for streaming the logs of the 'oscar-container' of a job as Server-Sent
+// Events. Each line is sent as a "log" event and, once the container finishes (or if 'follow' is set to 'false'),
+// an "end" event closes the stream. Heartbeat comments keep the connection alive while there are no new lines
+func MakeJobLogsStreamHandler(kubeClientset kubernetes.Interface, namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get serviceName and jobName
+		serviceName := c.Param("serviceName")
+		jobName := c.Param("jobName")
+		// Get timestamps and follow querystrings (default to false and true)
+		timestamps, err := strconv.ParseBool(c.DefaultQuery("timestamps", "false"))
+		if err != nil {
+			timestamps = false
+		}
+		follow, err := strconv.ParseBool(c.DefaultQuery("follow", "true"))
+		if err != nil {
+			follow = true
+		}
+		filter, err := parseLogFilter(c, "grep", timestamps)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		// Get job's pod (assuming there's only one pod per job)
+		listOpts := metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s,job-name=%s", types.ServiceLabel, serviceName, jobName),
+		}
+		pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+		if err != nil {
+			sendError(c, types.ErrJobReadFailed, err.Error())
+			return
+		}
+		if len(pods.Items) < 1 {
+			sendError(c, types.ErrJobNotFound, "")
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// Disable the buffering of the ingress (NGINX)
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		ctx := c.Request.Context()
+		heartbeat := time.NewTicker(logsHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		// The logs are not available until the container starts
+		podLogOpts := &v1.PodLogOptions{
+			Container: types.ContainerName,
+			Follow:    follow,
+		}
+		filter.setPodLogOptions(podLogOpts)
+		var logs io.ReadCloser
+		for {
+			logs, err = kubeClientset.CoreV1().Pods(pods.Items[0].Namespace).GetLogs(pods.Items[0].Name, podLogOpts).Stream(ctx)
+			if err == nil {
+				break
+			}
+			if !follow || errors.IsNotFound(err) || errors.IsGone(err) {
+				sendEvent(c, "error", err.Error())
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				sendHeartbeat(c)
+			}
+		}
+		defer logs.Close()
+
+		lines := make(chan string)
+		go func() {
+			defer close(lines)
+			scanner := bufio.NewScanner(logs)
+			scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				select {
+				case lines <- scanner.Text():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					sendEvent(c, "end", jobName)
+					return
+				}
+				if line, ok := filter.apply(line); ok {
+					sendEvent(c, "log", line)
+				}
+			case <-heartbeat.C:
+				sendHeartbeat(c)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// sendEvent sends a Server-Sent Event
+func sendEvent(c *gin.Context, event string, data string) {
+	c.SSEvent(event, data)
+	c.Writer.Flush()
+}
+
+// sendHeartbeat sends a Server-Sent Events comment, ignored by the clients
+func sendHeartbeat(c *gin.Context) {
+	fmt.Fprint(c.Writer, ": heartbeat\n\n")
+	c.Writer.Flush()
 }
 
 // MakeDeleteJobHandler makes a handler for removing a job
@@ -200,20 +624,20 @@ func MakeDeleteJobHandler(kubeClientset *kubernetes.Clientset, namespace string)
 		jobName := c.Param("jobName")
 
 		// Get job in order to check if it is associated with the provided serviceName
-		job, err := kubeClientset.BatchV1().Jobs(namespace).Get(context.TODO(), jobName, metav1.GetOptions{})
+		job, err := getJob(kubeClientset, namespace, jobName)
 		if err != nil {
 			// Check if error is caused because the service is not found
 			if !errors.IsNotFound(err) && !errors.IsGone(err) {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrJobReadFailed, err.Error())
 			} else {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrJobNotFound, "")
 			}
 			return
 		}
 
 		// Return StatusNotFound if job exists but is not associated with the provided serviceName
 		if job.Labels[types.ServiceLabel] != serviceName {
-			c.Status(http.StatusNotFound)
+			sendError(c, types.ErrJobNotFound, "")
 			return
 		}
 
@@ -224,13 +648,13 @@ func MakeDeleteJobHandler(kubeClientset *kubernetes.Clientset, namespace string)
 		}
 
 		// Delete the job
-		err = kubeClientset.BatchV1().Jobs(namespace).Delete(context.TODO(), jobName, delOpts)
+		err = kubeClientset.BatchV1().Jobs(job.Namespace).Delete(context.TODO(), jobName, delOpts)
 		if err != nil {
 			// Check if error is caused because the service is not found
 			if !errors.IsNotFound(err) && !errors.IsGone(err) {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrJobDeleteFailed, err.Error())
 			} else {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrJobNotFound, "")
 			}
 			return
 		}
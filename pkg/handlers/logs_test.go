@@ -0,0 +1,334 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeJobStatusHandler(t *testing.T) {
+	namespace := testConfigValidRun.ServicesNamespace
+	event := types.NewMinIOEvent("in", types.MinIOEventObject{Key: "dir/my file.jpg", Size: 1024}, metav1.Now().Time)
+	eventJSON, _ := json.Marshal(event)
+
+	kubeClientset := testclient.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "job",
+				Namespace:   namespace,
+				Labels:      map[string]string{types.ServiceLabel: "test", types.RevisionLabel: "2"},
+				Annotations: map[string]string{types.RetryOfAnnotation: "original"},
+			},
+			Spec: batchv1.JobSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{
+							Name: types.ContainerName,
+							Env:  []v1.EnvVar{{Name: types.EventVariable, Value: string(eventJSON)}},
+							Resources: v1.ResourceRequirements{
+								Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")},
+							},
+						}},
+					},
+				},
+			},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue}},
+			},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "job-pod",
+				Namespace: namespace,
+				Labels:    map[string]string{"job-name": "job"},
+			},
+			Spec: v1.PodSpec{NodeName: "node-1"},
+			Status: v1.PodStatus{
+				Phase: v1.PodFailed,
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name: types.ContainerName,
+					State: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+					},
+				}},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "queued",
+				Namespace: namespace,
+				Labels:    map[string]string{types.ServiceLabel: "test"},
+			},
+			Spec: batchv1.JobSpec{
+				Suspend: func() *bool { b := true; return &b }(),
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{Name: types.ContainerName, Env: []v1.EnvVar{{Name: types.EventVariable, Value: "hello"}}}},
+					},
+				},
+			},
+		},
+	)
+
+	r := gin.Default()
+	r.GET("/system/logs/:serviceName/:jobName/status", MakeJobStatusHandler(kubeClientset, namespace))
+
+	scenarios := []struct {
+		name              string
+		path              string
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"finished job", "/system/logs/test/job/status", http.StatusOK, ""},
+		{"queued job", "/system/logs/test/queued/status", http.StatusOK, ""},
+		{"missing job", "/system/logs/test/missing/status", http.StatusNotFound, types.ErrJobNotFound.Code},
+		{"job of other service", "/system/logs/other/job/status", http.StatusNotFound, types.ErrJobNotFound.Code},
+	}
+
+	statuses := map[string]types.JobStatus{}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+			if s.expectedCode == http.StatusOK {
+				status := types.JobStatus{}
+				if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+					t.Fatalf("error decoding the status: %v", err)
+				}
+				statuses[status.Name] = status
+			}
+		})
+	}
+
+	status := statuses["job"]
+	if status.Status != types.JobFailed || status.NodeName != "node-1" || status.Revision != 2 || status.RetryOf != "original" {
+		t.Errorf("unexpected status of the finished job: %+v", status)
+	}
+	if status.ExitCode == nil || *status.ExitCode != 137 || !status.OOMKilled {
+		t.Errorf("unexpected exit details of the finished job: %+v", status)
+	}
+	if status.Requests["memory"] != "256Mi" {
+		t.Errorf("unexpected requests of the finished job: %v", status.Requests)
+	}
+	expectedEvent := types.JobEventInfo{Source: types.JobEventMinIO, EventName: "s3:ObjectCreated:Put", EventTime: event.Records[0].EventTime, Bucket: "in", Key: "dir/my file.jpg", Size: 1024}
+	if status.Event == nil || *status.Event != expectedEvent {
+		t.Errorf("expecting the event %+v, got %+v", expectedEvent, status.Event)
+	}
+
+	status = statuses["queued"]
+	if status.Status != types.JobSuspended || status.ExitCode != nil || status.NodeName != "" {
+		t.Errorf("unexpected status of the queued job: %+v", status)
+	}
+	if status.Event == nil || *status.Event != (types.JobEventInfo{Source: types.JobEventCustom, Size: 5}) {
+		t.Errorf("unexpected event of the queued job: %+v", status.Event)
+	}
+}
+
+func TestMakeJobLogsStreamHandler(t *testing.T) {
+	namespace := testConfigValidRun.ServicesNamespace
+	kubeClientset := testclient.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "job-pod",
+			Namespace: namespace,
+			Labels:    map[string]string{types.ServiceLabel: "test", "job-name": "job"},
+		},
+	})
+
+	r := gin.Default()
+	r.GET("/system/logs/:serviceName/:jobName/stream", MakeJobLogsStreamHandler(kubeClientset, namespace))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/logs/test/job/stream", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("unexpected content type \"%s\"", contentType)
+	}
+	// The fake clientset returns "fake logs" as the logs of any pod
+	if expected := "event:log\ndata:fake logs\n\nevent:end\ndata:job\n\n"; w.Body.String() != expected {
+		t.Errorf("expecting the events %q, got %q", expected, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/system/logs/other/job/stream", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get(errorCodeHeader) != types.ErrJobNotFound.Code {
+		t.Errorf("expecting the error %s, got %d", types.ErrJobNotFound.Code, w.Code)
+	}
+}
+
+func TestMakeGetLogsHandlerArchived(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.PutObject("oscar-logs", "test/job.log", chaos.S3Object{Data: []byte("2023-01-01T10:00:00Z line 1\n2023-01-01T10:00:01Z line 2\n"), ContentType: "text/plain"})
+
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	cfg.LogsArchiveBucket = "oscar-logs"
+	kubeClientset := testclient.NewSimpleClientset()
+
+	scenarios := []struct {
+		name         string
+		enable       bool
+		path         string
+		expectedCode int
+		expected     string
+	}{
+		{"archive disabled", false, "/system/logs/test/job", http.StatusNotFound, ""},
+		{"archived logs", true, "/system/logs/test/job", http.StatusOK, "line 1\nline 2\n"},
+		{"archived logs with filter", true, "/system/logs/test/job?timestamps=true&since=2023-01-01T10:00:01Z", http.StatusOK, "2023-01-01T10:00:01Z line 2\n"},
+		{"not archived", true, "/system/logs/test/other", http.StatusNotFound, ""},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			cfg.LogsArchiveEnable = s.enable
+			r := gin.Default()
+			r.GET("/system/logs/:serviceName/:jobName", MakeGetLogsHandler(&cfg, kubeClientset))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+			if s.expectedCode == http.StatusOK && w.Body.String() != s.expected {
+				t.Errorf("expecting logs %q, got %q", s.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMakeJobOutputsHandler(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("out")
+	minIOProvider := testS3Provider(s3Server)
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{
+		Name:             "test",
+		Output:           []types.StorageIOConfig{{Provider: "minio", Path: "out/results"}},
+		StorageProviders: &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: minIOProvider}},
+	})
+	namespace := testConfigValidRun.ServicesNamespace
+
+	created := time.Now().Add(-time.Minute)
+	event := types.NewMinIOEvent("in", types.MinIOEventObject{Key: "dir/image.jpg", Size: 1024}, created)
+	eventJSON, _ := json.Marshal(event)
+	newJob := func(name string, service string, event string) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				Labels:            map[string]string{types.ServiceLabel: service},
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: batchv1.JobSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{
+							Name: types.ContainerName,
+							Env:  []v1.EnvVar{{Name: types.EventVariable, Value: event}},
+						}},
+					},
+				},
+			},
+			Status: batchv1.JobStatus{Succeeded: 1},
+		}
+	}
+	kubeClientset := testclient.NewSimpleClientset(
+		newJob("job", "test", string(eventJSON)),
+		newJob("custom", "test", "custom event"),
+		newJob("other", "other", string(eventJSON)),
+	)
+
+	s3Server.PutObject("out", "results/image.png", chaos.S3Object{Data: []byte("image")})
+	s3Server.PutObject("out", "results/image-thumb.png", chaos.S3Object{Data: []byte("thumb")})
+	s3Server.PutObject("out", "results/other.png", chaos.S3Object{Data: []byte("other")})
+	s3Server.PutObject("out", "results/image-old.png", chaos.S3Object{Data: []byte("old"), LastModified: created.Add(-time.Hour)})
+
+	r := gin.Default()
+	r.GET("/system/logs/:serviceName/:jobName/output", MakeJobOutputsHandler(back, kubeClientset, namespace))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/logs/test/job/output?expires=600", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	outputs := &types.JobOutputs{}
+	if err := json.Unmarshal(w.Body.Bytes(), outputs); err != nil {
+		t.Fatalf("unexpected error decoding the response: %v", err)
+	}
+	if !outputs.Correlated || !outputs.Finished || len(outputs.Files) != 2 {
+		t.Fatalf("unexpected outputs: %+v", outputs)
+	}
+	for _, file := range outputs.Files {
+		if file.Bucket != "out" || !strings.HasPrefix(file.Key, "results/image") || !strings.Contains(file.URL, "X-Amz-Expires=600") {
+			t.Errorf("unexpected output file: %+v", file)
+		}
+	}
+
+	// The outputs of the jobs without MinIO events cannot be correlated
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/system/logs/test/custom/output", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"correlated":false`) {
+		t.Errorf("unexpected response for the job without MinIO event: %d %s", w.Code, w.Body.String())
+	}
+
+	for path, code := range map[string]int{
+		"/system/logs/test/other/output":            http.StatusNotFound,
+		"/system/logs/test/missing/output":          http.StatusNotFound,
+		"/system/logs/missing/job/output":           http.StatusNotFound,
+		"/system/logs/test/job/output?expires=0":    http.StatusBadRequest,
+		"/system/logs/test/job/output?expires=week": http.StatusBadRequest,
+	} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("%s: expected status %d, got %d", path, code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// maintenancePath prefix of the paths to manage the maintenance mode, allowed during the freezes
+const maintenancePath = "/system/maintenance"
+
+// invocationPaths prefixes of the invocation paths, which are not affected by the freezes (unless the async
+// invocations are queued)
+var invocationPaths = []string{"/job/", "/run/", "/i/", "/run-async/", "/async-results/"}
+
+// MakeMaintenanceMiddleware makes a middleware rejecting the requests that modify the cluster (all but the reads and
+// the invocations) while the API is frozen for maintenance. The async invocations are queued if the freeze requires it
+func MakeMaintenanceMiddleware(maintenance *utils.Maintenance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := maintenance.Status()
+		if !status.Frozen {
+			return
+		}
+
+		path := c.Request.URL.Path
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if strings.HasPrefix(path, maintenancePath) {
+			return
+		}
+		for _, prefix := range invocationPaths {
+			if !strings.HasPrefix(path, prefix) {
+				continue
+			}
+			if status.QueueInvocations && prefix == "/job/" {
+				queueInvocation(c, maintenance, status)
+			}
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(status.RetryAfter))
+		sendError(c, types.ErrMaintenanceMode, status.Reason)
+	}
+}
+
+// queueInvocation queues an async invocation until the end of the freeze
+func queueInvocation(c *gin.Context, maintenance *utils.Maintenance, status types.MaintenanceStatus) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, utils.MaxQueuedInvocationBytes+1))
+	if err != nil {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("Error reading the request body: %v", err))
+		return
+	}
+	if len(body) > utils.MaxQueuedInvocationBytes {
+		c.Header("Retry-After", strconv.Itoa(status.RetryAfter))
+		sendError(c, types.ErrMaintenanceMode, "The event is too large to be queued during the maintenance")
+		return
+	}
+	if err := maintenance.Queue(c.Request, body); err != nil {
+		c.Header("Retry-After", strconv.Itoa(status.RetryAfter))
+		sendError(c, types.ErrMaintenanceMode, err.Error())
+		return
+	}
+	c.AbortWithStatus(http.StatusAccepted)
+}
+
+// MakeMaintenanceStatusHandler makes a handler to get the maintenance status of the API
+func MakeMaintenanceStatusHandler(maintenance *utils.Maintenance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, maintenance.Status())
+	}
+}
+
+// MakeMaintenanceFreezeHandler makes a handler to put the API in read-only mode (admin only)
+func MakeMaintenanceFreezeHandler(cfg *types.Config, maintenance *utils.Maintenance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		var req types.MaintenanceFreezeRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("The freeze request is not valid: %v", err))
+				return
+			}
+		}
+
+		status, err := maintenance.Freeze(req)
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error storing the maintenance status: %v", err))
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// MakeMaintenanceUnfreezeHandler makes a handler to end the read-only mode of the API, replaying the queued
+// invocations (admin only)
+func MakeMaintenanceUnfreezeHandler(cfg *types.Config, maintenance *utils.Maintenance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		status, err := maintenance.Unfreeze()
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error removing the maintenance status: %v", err))
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMaintenance(t *testing.T) {
+	kubeClientset := testclient.NewSimpleClientset()
+	invocations := make(chan string, 10)
+
+	cfg := testConfigValidRun
+	cfg.Username = "admin"
+
+	r := gin.New()
+	maintenance := utils.NewMaintenance(&cfg, kubeClientset, r)
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.Use(MakeMaintenanceMiddleware(maintenance))
+	r.GET("/system/services", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/system/services", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	r.POST("/job/:serviceName", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		invocations <- string(body)
+		c.Status(http.StatusCreated)
+	})
+	r.GET("/system/maintenance/freeze", MakeMaintenanceStatusHandler(maintenance))
+	r.POST("/system/maintenance/freeze", MakeMaintenanceFreezeHandler(&cfg, maintenance))
+	r.DELETE("/system/maintenance/freeze", MakeMaintenanceUnfreezeHandler(&cfg, maintenance))
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User", "admin")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("POST", "/system/services", "{}"); w.Code != http.StatusCreated {
+		t.Fatalf("expecting code %d before the freeze, got %d", http.StatusCreated, w.Code)
+	}
+	for _, method := range []string{"POST", "DELETE"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/system/maintenance/freeze", nil)
+		req.Header.Set("X-User", "user")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden || w.Header().Get(errorCodeHeader) != types.ErrAdminRequired.Code {
+			t.Errorf("expecting code %d for the %s of a non-admin user, got %d", http.StatusForbidden, method, w.Code)
+		}
+	}
+	if w := request("POST", "/system/maintenance/freeze", `{"retry_after": -1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expecting code %d for an invalid freeze, got %d", http.StatusBadRequest, w.Code)
+	}
+	w := request("POST", "/system/maintenance/freeze", `{"reason": "MinIO upgrade", "queue_invocations": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{"management write", "POST", "/system/services", http.StatusServiceUnavailable},
+		{"read", "GET", "/system/services", http.StatusOK},
+		{"queued invocation", "POST", "/job/test", http.StatusAccepted},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := request(s.method, s.path, `{"message": "hello"}`)
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode == http.StatusServiceUnavailable {
+				if w.Header().Get("Retry-After") != "300" || w.Header().Get(errorCodeHeader) != types.ErrMaintenanceMode.Code {
+					t.Errorf("unexpected headers: %v", w.Header())
+				}
+				if !strings.Contains(w.Body.String(), "MinIO upgrade") {
+					t.Errorf("expecting the reason in the error, got %s", w.Body.String())
+				}
+			}
+		})
+	}
+
+	// Other replicas get the status from the configMap
+	other := utils.NewMaintenance(&testConfigValidRun, kubeClientset, r)
+	if status := other.Status(); !status.Frozen || status.Reason != "MinIO upgrade" {
+		t.Errorf("unexpected status in other replica: %+v", status)
+	}
+
+	w = request("GET", "/system/maintenance/freeze", "")
+	var status types.MaintenanceStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Frozen || status.QueuedInvocations != 1 || status.Since == nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	select {
+	case <-invocations:
+		t.Fatal("the invocation must not be processed during the freeze")
+	default:
+	}
+
+	// The queued invocations are replayed after the freeze
+	if w := request("DELETE", "/system/maintenance/freeze", ""); w.Code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	select {
+	case body := <-invocations:
+		if body != `{"message": "hello"}` {
+			t.Errorf("unexpected replayed invocation: %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued invocation has not been replayed")
+	}
+	if w := request("POST", "/system/services", "{}"); w.Code != http.StatusCreated {
+		t.Errorf("expecting code %d after the freeze, got %d", http.StatusCreated, w.Code)
+	}
+}
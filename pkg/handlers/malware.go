@@ -0,0 +1,81 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// Logger for the malware scanning of the input objects
+var malwareLogger = log.New(os.Stdout, "[MALWARE-SCAN] ", log.Flags())
+
+// scanEvent scans the input object of the event (MinIO notification) if the service has a quarantine path, moving
+// the infected objects to it. Returns the reason to discard the event if the object has been quarantined
+func (d *EventDispatcher) scanEvent(service *types.Service, eventBytes []byte) (string, error) {
+	if service.QuarantinePath == "" {
+		return "", nil
+	}
+	minIOEvent, err := types.ParseMinIOEvent(eventBytes)
+	if err != nil {
+		// Not a MinIO event, nothing to scan
+		return "", nil
+	}
+	bucket := minIOEvent.GetBucket()
+	key := minIOEvent.GetObjectKey()
+	in := service.GetInputForObject(bucket, key)
+	if in == nil {
+		return "", nil
+	}
+	if provName, provID := in.GetProvider(); provName != types.MinIOName || provID != types.DefaultProvider {
+		return "", nil
+	}
+
+	// The events are not dispatched if the object can't be scanned
+	if d.scanner == nil {
+		return "", types.NewCodedError(types.ErrMalwareScanFailed, fmt.Errorf("the malware scanner is not configured in the cluster"))
+	}
+	threat, err := utils.ScanObject(d.cfg, d.scanner, bucket, key)
+	if err != nil {
+		return "", types.NewCodedError(types.ErrMalwareScanFailed, err)
+	}
+	if threat == "" {
+		return "", nil
+	}
+
+	record, err := utils.QuarantineObject(d.cfg, service, *in, bucket, key, threat, d.scanner.Type())
+	if err != nil && record == nil {
+		return "", types.NewCodedError(types.ErrMalwareScanFailed, err)
+	}
+	if err != nil {
+		malwareLogger.Println(err.Error())
+	}
+	malwareLogger.Printf("Object \"%s/%s\" of service \"%s\" infected by \"%s\", moved to the quarantine path \"%s\"\n", bucket, key, service.Name, threat, service.QuarantinePath)
+	utils.PublishLifecycleEvent(types.LifecycleEvent{
+		Type:    types.ObjectQuarantinedEvent,
+		Service: service.Name,
+		Message: fmt.Sprintf("the object \"%s/%s\" is infected by \"%s\"", bucket, key, threat),
+		Time:    time.Now(),
+	})
+
+	return fmt.Sprintf("the object \"%s/%s\" is infected by \"%s\" and has been quarantined", bucket, key, threat), nil
+}
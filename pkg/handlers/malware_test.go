@@ -0,0 +1,119 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDispatchMalwareScan(t *testing.T) {
+	// ClamAV daemon reporting the objects with "EICAR" as infected
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			r.ReadString(0)
+			var content strings.Builder
+			for {
+				size := make([]byte, 4)
+				if _, err := io.ReadFull(r, size); err != nil || binary.BigEndian.Uint32(size) == 0 {
+					break
+				}
+				io.CopyN(&content, r, int64(binary.BigEndian.Uint32(size)))
+			}
+			if strings.Contains(content.String(), "EICAR") {
+				conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+			} else {
+				conn.Write([]byte("stream: OK\x00"))
+			}
+			conn.Close()
+		}
+	}()
+
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("uploads")
+	s3Server.PutObject("uploads", "in/clean.txt", chaos.S3Object{Data: []byte("hello")})
+	s3Server.PutObject("uploads", "in/infected.txt", chaos.S3Object{Data: []byte("X5O!P%@AP-EICAR-TEST")})
+
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	cfg.MalwareScannerURL = "clamav://" + listener.Addr().String()
+	cfg.MalwareScanTimeout = 5 * time.Second
+	service := &types.Service{
+		Name:           "test",
+		Image:          "busybox",
+		Input:          []types.StorageIOConfig{{Provider: "minio.default", Path: "uploads/in"}},
+		QuarantinePath: "uploads/quarantine",
+	}
+	d := MakeEventDispatcher(&cfg, testclient.NewSimpleClientset(), nil)
+
+	dispatch := func(key string) (dispatchStatus, string) {
+		event, _ := json.Marshal(types.NewMinIOEvent("uploads", types.MinIOEventObject{Key: key}, time.Now()))
+		status, detail, err := d.dispatch(service, event)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", key, err)
+		}
+		return status, detail
+	}
+
+	if status, detail := dispatch("in/clean.txt"); status != eventDelivered {
+		t.Errorf("expecting the clean object to be delivered, got %d (%s)", status, detail)
+	}
+
+	status, detail := dispatch("in/infected.txt")
+	if status != eventDiscarded || !strings.Contains(detail, "Eicar-Signature") {
+		t.Errorf("expecting the infected object to be discarded, got %d (%s)", status, detail)
+	}
+	if _, ok := s3Server.GetObject("uploads", "in/infected.txt"); ok {
+		t.Error("expecting the infected object to be removed from the input")
+	}
+	if obj, ok := s3Server.GetObject("uploads", "quarantine/infected.txt"); !ok || !strings.Contains(string(obj.Data), "EICAR") {
+		t.Error("expecting the infected object in the quarantine path")
+	}
+	obj, ok := s3Server.GetObject("uploads", "quarantine/infected.txt"+types.QuarantineRecordSuffix)
+	var record types.QuarantineRecord
+	if !ok || json.Unmarshal(obj.Data, &record) != nil || record.Threat != "Eicar-Signature" || record.Key != "in/infected.txt" {
+		t.Errorf("expecting the audit record of the quarantined object, got %s", string(obj.Data))
+	}
+
+	// The events are not dispatched if the scanner is not available
+	listener.Close()
+	event, _ := json.Marshal(types.NewMinIOEvent("uploads", types.MinIOEventObject{Key: "in/clean.txt"}, time.Now()))
+	if _, _, err := d.dispatch(service, event); types.GetErrorCode(err, types.ErrInternal) != types.ErrMalwareScanFailed {
+		t.Errorf("expecting the %s error, got %v", types.ErrMalwareScanFailed.Code, err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// openMetricsContentType content type of the OpenMetrics text format
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// MakeMetricsHandler makes a handler exposing (in OpenMetrics format) the delivery stats of the MinIO notifications
+// of the services triggered by the OSCAR's MinIO, so the trigger delivery problems can be monitored
+func MakeMetricsHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		services, err := back.ListServices()
+		if err != nil {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+			return
+		}
+		stats, err := utils.GetTriggerDeliveryStats(cfg)
+		if err != nil {
+			sendError(c, types.ErrStorageConnectionFailed, err.Error())
+			return
+		}
+
+		// The services without stats (e.g. not triggered yet) are reported with zeros
+		reported := []*types.TriggerDeliveryStats{}
+		for _, service := range services {
+			if !hasMinIOTrigger(service) {
+				continue
+			}
+			s := stats[service.Name]
+			if s == nil {
+				s = &types.TriggerDeliveryStats{Service: service.Name}
+			}
+			reported = append(reported, s)
+		}
+		sort.Slice(reported, func(i, j int) bool { return reported[i].Service < reported[j].Service })
+
+		c.Data(http.StatusOK, openMetricsContentType, []byte(formatTriggerMetrics(reported)))
+	}
+}
+
+// hasMinIOTrigger checks if the service has an input in the OSCAR's MinIO (triggered by its webhook)
+func hasMinIOTrigger(service *types.Service) bool {
+	for _, in := range service.Input {
+		if provName, provID := in.GetProvider(); provName == types.MinIOName && provID == types.DefaultProvider {
+			return true
+		}
+	}
+	return false
+}
+
+// formatTriggerMetrics returns the OpenMetrics exposition of the trigger delivery stats
+func formatTriggerMetrics(stats []*types.TriggerDeliveryStats) string {
+	families := []struct {
+		name   string
+		kind   string
+		help   string
+		suffix string
+		value  func(s *types.TriggerDeliveryStats) float64
+	}{
+		{"oscar_trigger_events_delivered", "counter", "MinIO events delivered to the service", "_total",
+			func(s *types.TriggerDeliveryStats) float64 { return s.Delivered }},
+		{"oscar_trigger_events_failed", "counter", "MinIO events whose delivery to the service failed", "_total",
+			func(s *types.TriggerDeliveryStats) float64 { return s.Failed }},
+		{"oscar_trigger_events_pending", "gauge", "MinIO events queued or being delivered to the service", "",
+			func(s *types.TriggerDeliveryStats) float64 { return s.Pending }},
+	}
+
+	var sb strings.Builder
+	for _, family := range families {
+		fmt.Fprintf(&sb, "# TYPE %s %s\n# HELP %s %s.\n", family.name, family.kind, family.name, family.help)
+		for _, s := range stats {
+			fmt.Fprintf(&sb, "%s%s{service=%s} %s\n", family.name, family.suffix, strconv.Quote(s.Service),
+				strconv.FormatFloat(family.value(s), 'g', -1, 64))
+		}
+	}
+	sb.WriteString("# EOF\n")
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeMetricsHandler(t *testing.T) {
+	minIOServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`minio_notify_target_total_events{target_id="grayify",target_name="webhook"} 10
+minio_notify_target_failed_events{target_id="grayify",target_name="webhook"} 1
+minio_notify_target_queue_length{target_id="grayify",target_name="webhook"} 2
+minio_notify_target_total_events{target_id="removed",target_name="webhook"} 4
+`))
+	}))
+	defer minIOServer.Close()
+
+	cfg := testConfigValidRun
+	cfg.Username = "oscar"
+	cfg.MinIOProvider = &types.MinIOProvider{Endpoint: minIOServer.URL}
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "grayify", Input: []types.StorageIOConfig{{Provider: "minio", Path: "grayify/in"}}})
+	back.CreateService(types.Service{Name: "plants", Input: []types.StorageIOConfig{{Provider: "minio.default", Path: "plants/in"}}})
+	back.CreateService(types.Service{Name: "external", Input: []types.StorageIOConfig{{Provider: "minio.other", Path: "external/in"}}})
+	back.CreateService(types.Service{Name: "sync"})
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.GET("/system/metrics", MakeMetricsHandler(&cfg, back))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/metrics", nil)
+	req.Header.Set("X-User", "oscar")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/openmetrics-text") {
+		t.Errorf("unexpected content type %s", w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE oscar_trigger_events_delivered counter",
+		`oscar_trigger_events_delivered_total{service="grayify"} 9`,
+		`oscar_trigger_events_failed_total{service="grayify"} 1`,
+		`oscar_trigger_events_pending{service="grayify"} 2`,
+		`oscar_trigger_events_delivered_total{service="plants"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in the metrics:\n%s", line, body)
+		}
+	}
+	for _, service := range []string{"removed", "external", "sync"} {
+		if strings.Contains(body, `"`+service+`"`) {
+			t.Errorf("unexpected metrics of service %s", service)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("expected the metrics to end with # EOF")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/system/metrics", nil)
+	req.Header.Set("X-User", "user")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a non-admin user, got %d", w.Code)
+	}
+}
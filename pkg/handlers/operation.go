@@ -0,0 +1,38 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// MakeOperationReadHandler makes a handler for reading the progress of an asynchronous operation
+func MakeOperationReadHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op, ok := utils.GetOperation(c.Param("operationID"))
+		if !ok {
+			sendError(c, types.ErrOperationNotFound, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, op)
+	}
+}
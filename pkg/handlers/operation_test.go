@@ -0,0 +1,111 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestAsyncCreate(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	back := backends.MakeMemoryBackend()
+
+	r := gin.Default()
+	r.POST("/system/services", MakeCreateHandler(&cfg, back))
+	r.GET("/system/operations/:operationID", MakeOperationReadHandler())
+
+	scenarios := []struct {
+		name          string
+		createErr     error
+		expectedSteps []string
+		expectedError string
+	}{
+		{"backend error", errors.New("create error"), []string{types.OperationFailed, types.OperationSkipped, types.OperationSkipped, types.OperationSkipped}, types.ErrServiceCreateFailed.Code},
+		// The fake S3 server does not implement the MinIO admin API
+		{"webhook error", nil, []string{types.OperationSucceeded, types.OperationFailed, types.OperationSkipped, types.OperationSkipped}, types.ErrWebhookRegisterFailed.Code},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if s.createErr != nil {
+				back.AddError("CreateService", s.createErr)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/services?async=true", strings.NewReader(`{"name": "test", "image": "busybox", "script": "ls"}`))
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expecting code %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+			}
+			var op types.Operation
+			if err := json.Unmarshal(w.Body.Bytes(), &op); err != nil {
+				t.Fatalf("error decoding the operation: %v", err)
+			}
+			if location := w.Header().Get("Location"); location != "/system/operations/"+op.ID {
+				t.Errorf("unexpected location \"%s\"", location)
+			}
+
+			// Wait for the operation to finish
+			for i := 0; op.Status == types.OperationRunning && i < 50; i++ {
+				time.Sleep(100 * time.Millisecond)
+				w = httptest.NewRecorder()
+				req, _ = http.NewRequest("GET", "/system/operations/"+op.ID, nil)
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+				}
+				op = types.Operation{}
+				json.Unmarshal(w.Body.Bytes(), &op)
+			}
+
+			if op.Status != types.OperationFailed || op.Error == nil || op.Error.Code != s.expectedError {
+				t.Fatalf("expecting the operation failed with %s, got %+v", s.expectedError, op)
+			}
+			for i, step := range op.Steps {
+				if step.Status != s.expectedSteps[i] {
+					t.Errorf("expecting the step %s %s, got %s", step.Name, s.expectedSteps[i], step.Status)
+				}
+			}
+			// The service is removed when a step fails
+			if _, err := back.ReadService("test"); err == nil {
+				t.Error("the service has not been removed")
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/operations/missing", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get(errorCodeHeader) != types.ErrOperationNotFound.Code {
+		t.Errorf("expecting the error %s, got %d", types.ErrOperationNotFound.Code, w.Code)
+	}
+}
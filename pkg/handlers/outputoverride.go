@@ -0,0 +1,44 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// getOutputOverride returns the destination of the invocation's output_override parameter (nil if not set) and the
+// service with its outputs redirected to it, aborting the request if the destination is not valid or not allowed
+func getOutputOverride(c *gin.Context, service *types.Service) (*types.StorageIOConfig, *types.Service, bool) {
+	value := c.Query(types.OutputOverrideParam)
+	if value == "" {
+		return nil, service, true
+	}
+	dest, err := types.ParseOutputDestination(value)
+	if err != nil {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid %s: %v", types.OutputOverrideParam, err))
+		return nil, nil, false
+	}
+	overridden, err := service.WithOutputOverride(dest)
+	if err != nil {
+		sendError(c, types.ErrOutputOverrideNotAllowed, err.Error())
+		return nil, nil, false
+	}
+	return &dest, overridden, true
+}
@@ -0,0 +1,96 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOutputOverride(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{
+		Name:               "test",
+		Image:              "busybox",
+		Token:              "token",
+		Labels:             map[string]string{types.ServiceLabel: "test"},
+		Output:             []types.StorageIOConfig{{Provider: "minio.default", Path: "bucket/out"}},
+		OutputDestinations: []types.StorageIOConfig{{Provider: "minio.default", Path: "results"}},
+		StorageProviders:   &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: {}}},
+	})
+	kubeClientset := testclient.NewSimpleClientset()
+	dispatcher := MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)
+
+	r := gin.Default()
+	r.POST("/job/:serviceName", MakeJobHandler(back, dispatcher))
+	r.POST("/run/:serviceName", MakeRunHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name         string
+		path         string
+		expectedCode int
+	}{
+		{"not allowed", "/job/test?output_override=minio.default:other", http.StatusForbidden},
+		{"invalid", "/job/test?output_override=results/../other", http.StatusBadRequest},
+		{"sync without multipart", "/run/test?output_override=results/alice", http.StatusBadRequest},
+		{"allowed", "/job/test?output_override=results/alice", http.StatusCreated},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", s.path, strings.NewReader(`{"message": "hello"}`))
+			req.Header.Set("Authorization", "Bearer token")
+			r.ServeHTTP(w, req)
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode == http.StatusForbidden {
+				if code := w.Header().Get(errorCodeHeader); code != types.ErrOutputOverrideNotAllowed.Code {
+					t.Errorf("expecting the %s error, got %s", types.ErrOutputOverrideNotAllowed.Code, code)
+				}
+			}
+		})
+	}
+
+	// The job must mount the FDL with the overridden output from its pod annotation
+	jobs, err := kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 1 {
+		t.Fatalf("expecting 1 job, got %d", len(jobs.Items))
+	}
+	fdl := jobs.Items[0].Spec.Template.Annotations[types.OutputConfigAnnotation]
+	if !strings.Contains(fdl, "results/alice") || strings.Contains(fdl, "bucket/out") {
+		t.Errorf("unexpected output config:\n%s", fdl)
+	}
+	for _, volume := range jobs.Items[0].Spec.Template.Spec.Volumes {
+		if volume.Name == types.ConfigVolumeName && volume.Projected == nil {
+			t.Error("the config volume of the job is not projected")
+		}
+	}
+}
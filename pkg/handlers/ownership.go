@@ -0,0 +1,99 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// MakeServiceOwnershipMiddleware makes a middleware restricting the /system paths of a service (definition, jobs,
+// logs...) to the users that can access it: its owners, the members of its VO and the admins
+func MakeServiceOwnershipMiddleware(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Param("serviceName") == "" {
+			return
+		}
+		// The handlers report the services not found
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			return
+		}
+		checkServiceAccess(c, cfg, service)
+	}
+}
+
+// canAccessService checks if the user of the request can access a service: the admins, the users of
+// service.IsAccessibleBy and the named access tokens of the service (limited by their scopes)
+func canAccessService(c *gin.Context, cfg *types.Config, service *types.Service) bool {
+	return c.GetBool(serviceTokenAuthKey) || isAdmin(c, cfg) ||
+		service.IsAccessibleBy(c.GetString(gin.AuthUserKey), c.GetStringSlice(types.UserGroupsKey))
+}
+
+// checkServiceAccess checks if the user of the request can access a service, sending the error otherwise
+func checkServiceAccess(c *gin.Context, cfg *types.Config, service *types.Service) bool {
+	if canAccessService(c, cfg, service) {
+		return true
+	}
+	sendError(c, types.ErrServiceAccessDenied, fmt.Sprintf("The service \"%s\" is owned by another user", service.Name))
+	return false
+}
+
+// filterAccessibleServices returns the services that the user of the request can access
+func filterAccessibleServices(c *gin.Context, cfg *types.Config, services []*types.Service) []*types.Service {
+	accessible := []*types.Service{}
+	for _, service := range services {
+		if canAccessService(c, cfg, service) {
+			accessible = append(accessible, service)
+		}
+	}
+	return accessible
+}
+
+// serviceAccessCache access of the user of a long-lived request (e.g. an event stream) to the services, read again
+// on their creation and update events. The last known access is kept for the deleted services
+type serviceAccessCache struct {
+	c       *gin.Context
+	cfg     *types.Config
+	back    types.ServerlessBackend
+	allowed map[string]bool
+}
+
+func newServiceAccessCache(c *gin.Context, cfg *types.Config, back types.ServerlessBackend) *serviceAccessCache {
+	return &serviceAccessCache{c: c, cfg: cfg, back: back, allowed: map[string]bool{}}
+}
+
+// set stores the access to a service already read
+func (cache *serviceAccessCache) set(service *types.Service) bool {
+	allowed := canAccessService(cache.c, cache.cfg, service)
+	cache.allowed[service.Name] = allowed
+	return allowed
+}
+
+// canAccess checks if the user can access a service, reading it if it is not cached or refresh is set
+func (cache *serviceAccessCache) canAccess(name string, refresh bool) bool {
+	if allowed, ok := cache.allowed[name]; ok && !refresh {
+		return allowed
+	}
+	service, err := cache.back.ReadService(name)
+	if err != nil {
+		return cache.allowed[name]
+	}
+	return cache.set(service)
+}
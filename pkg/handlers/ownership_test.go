@@ -0,0 +1,115 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestServiceOwnership(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.Username = "oscar"
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "alice-svc", Owner: "alice", Owners: []string{"bob"}})
+	back.CreateService(types.Service{Name: "vo-svc", Owner: "carol", VO: "vo.example.eu"})
+	back.CreateService(types.Service{Name: "shared-svc"})
+
+	r := gin.Default()
+	system := r.Group("/system", func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+		if groups := c.GetHeader("X-Groups"); groups != "" {
+			c.Set(types.UserGroupsKey, strings.Split(groups, ","))
+		}
+	}, MakeServiceOwnershipMiddleware(&cfg, back))
+	system.GET("/services", MakeListHandler(&cfg, back))
+	system.DELETE("/services/:serviceName", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	request := func(method string, path string, user string, groups string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Groups", groups)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	listScenarios := []struct {
+		name     string
+		user     string
+		groups   string
+		expected []string
+	}{
+		{"owner", "alice", "", []string{"alice-svc", "shared-svc"}},
+		{"additional owner", "bob", "", []string{"alice-svc", "shared-svc"}},
+		{"VO member", "dave", "vo.example.eu", []string{"shared-svc", "vo-svc"}},
+		{"other user", "dave", "", []string{"shared-svc"}},
+		{"admin", "oscar", "", []string{"alice-svc", "shared-svc", "vo-svc"}},
+	}
+	for _, s := range listScenarios {
+		t.Run("list by "+s.name, func(t *testing.T) {
+			w := request("GET", "/system/services?sort=name", s.user, s.groups)
+			var services []types.Service
+			json.Unmarshal(w.Body.Bytes(), &services)
+			names := []string{}
+			for _, service := range services {
+				names = append(names, service.Name)
+			}
+			if !reflect.DeepEqual(names, s.expected) {
+				t.Errorf("expecting services %v, got %v", s.expected, names)
+			}
+		})
+	}
+
+	deleteScenarios := []struct {
+		name         string
+		path         string
+		user         string
+		groups       string
+		expectedCode int
+	}{
+		{"owner", "/system/services/alice-svc", "alice", "", http.StatusNoContent},
+		{"additional owner", "/system/services/alice-svc", "bob", "", http.StatusNoContent},
+		{"other user", "/system/services/alice-svc", "dave", "", http.StatusForbidden},
+		{"VO member", "/system/services/vo-svc", "dave", "vo.example.eu", http.StatusNoContent},
+		{"not VO member", "/system/services/vo-svc", "dave", "vo.other.eu", http.StatusForbidden},
+		{"admin", "/system/services/vo-svc", "oscar", "", http.StatusNoContent},
+		{"not found", "/system/services/other", "dave", "", http.StatusNoContent},
+	}
+	for _, s := range deleteScenarios {
+		t.Run("delete by "+s.name, func(t *testing.T) {
+			w := request("DELETE", s.path, s.user, s.groups)
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+			if s.expectedCode == http.StatusForbidden && w.Header().Get(errorCodeHeader) != types.ErrServiceAccessDenied.Code {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", types.ErrServiceAccessDenied.Code, w.Header().Get(errorCodeHeader))
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/grycap/oscar/v2/pkg/resourcemanager"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var placementLogger = log.New(os.Stdout, "[PLACEMENT] ", log.Flags())
+
+// placeJob places the job in the tier closest to the object that triggered it, following the service's placement policy.
+// Returns the name of the created job (empty if the event has been delegated to a replica)
+func placeJob(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, job *batchv1.Job, eventValue string, rm resourcemanager.ResourceManager) (string, error) {
+	var bucket, key string
+	if minIOEvent, err := types.ParseMinIOEvent([]byte(eventValue)); err == nil {
+		bucket = minIOEvent.GetBucket()
+		key = minIOEvent.GetObjectKey()
+	}
+
+	for _, tier := range service.Placement.SelectTiers(bucket, key) {
+		if tier.IsRemote() {
+			// Delegate only to the tier's replica
+			tierService := *service
+			tierService.Replicas = service.Replicas.FilterByCluster(tier.ClusterID)
+			if err := resourcemanager.DelegateJob(&tierService, eventValue, placementLogger); err != nil {
+				placementLogger.Printf("Unable to place job of service \"%s\" in tier \"%s\": %v\n", service.Name, tier.Name, err)
+				continue
+			}
+			return "", nil
+		}
+
+		tierJob := job.DeepCopy()
+		podSpec := &tierJob.Spec.Template.Spec
+		if len(tier.NodeSelector) > 0 {
+			if podSpec.NodeSelector == nil {
+				podSpec.NodeSelector = map[string]string{}
+			}
+			for k, v := range tier.NodeSelector {
+				podSpec.NodeSelector[k] = v
+			}
+		}
+
+		// Only check the resources of the tier's nodes (matching the service's node selector too)
+		if rm != nil && !rm.IsSchedulableOnNodes(podSpec.Containers[0].Resources, podSpec.NodeSelector) {
+			placementLogger.Printf("Unable to place job of service \"%s\" in tier \"%s\": not enough resources\n", service.Name, tier.Name)
+			continue
+		}
+
+		if _, err := kubeClientset.BatchV1().Jobs(cfg.GetServiceNamespace(service)).Create(context.TODO(), tierJob, metav1.CreateOptions{}); err != nil {
+			placementLogger.Printf("Unable to place job of service \"%s\" in tier \"%s\": %v\n", service.Name, tier.Name, err)
+			continue
+		}
+		return tierJob.Name, nil
+	}
+
+	return "", fmt.Errorf("unable to place the job of service \"%s\" in any tier", service.Name)
+}
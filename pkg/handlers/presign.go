@@ -0,0 +1,127 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// presignParams query parameters of the presigned URLs, removed before forwarding the invocations to the services
+var presignParams = []string{types.PresignExpiresParam, types.PresignMaxSizeParam, types.PresignContentTypeParam, types.PresignSHA256Param, types.PresignSignatureParam}
+
+// MakePresignHandler makes a handler to generate presigned URLs to invoke a service synchronously (/run) without
+// credentials until their expiry, e.g. handed out by a portal to its users
+func MakePresignHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := types.PresignRequest{}
+		if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The presigned URL specification is not valid: %v", err))
+			return
+		}
+		if err := req.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second).UTC().Truncate(time.Second)
+		u := url.URL{
+			Scheme:   "http",
+			Host:     c.Request.Host,
+			Path:     "/run/" + service.Name,
+			RawQuery: req.Query(service, expiresAt).Encode(),
+		}
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			u.Scheme = "https"
+		}
+		c.JSON(http.StatusCreated, types.PresignedURL{URL: u.String(), ExpiresAt: expiresAt})
+	}
+}
+
+// isPresignedInvocation checks if the invocation is made through a presigned URL
+func isPresignedInvocation(c *gin.Context) bool {
+	return c.Query(types.PresignSignatureParam) != ""
+}
+
+// checkPresignedInvocation checks the signature, the expiry and the body constraints of an invocation made through a
+// presigned URL, sending the error otherwise. The parameters of the URL are removed from the request
+func checkPresignedInvocation(c *gin.Context, service *types.Service) bool {
+	query := c.Request.URL.Query()
+	if !service.CheckPresignedQuery(query, time.Now()) {
+		sendError(c, types.ErrPresignedURLInvalid, "")
+		return false
+	}
+
+	if contentType := query.Get(types.PresignContentTypeParam); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !strings.EqualFold(c.ContentType(), mediaType) {
+			sendError(c, types.ErrPresignedPayloadRejected, fmt.Sprintf("The content type of the body must be \"%s\"", contentType))
+			return false
+		}
+	}
+
+	maxSize, _ := strconv.ParseInt(query.Get(types.PresignMaxSizeParam), 10, 64)
+	hash := query.Get(types.PresignSHA256Param)
+	if maxSize > 0 || hash != "" {
+		if maxSize > 0 && c.Request.ContentLength > maxSize {
+			sendError(c, types.ErrPresignedPayloadRejected, fmt.Sprintf("The body can't be larger than %d bytes", maxSize))
+			return false
+		}
+		reader := io.Reader(c.Request.Body)
+		if maxSize > 0 {
+			reader = io.LimitReader(c.Request.Body, maxSize+1)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The request body cannot be read: %v", err))
+			return false
+		}
+		if maxSize > 0 && int64(len(body)) > maxSize {
+			sendError(c, types.ErrPresignedPayloadRejected, fmt.Sprintf("The body can't be larger than %d bytes", maxSize))
+			return false
+		}
+		if sum := sha256.Sum256(body); hash != "" && hex.EncodeToString(sum[:]) != hash {
+			sendError(c, types.ErrPresignedPayloadRejected, "The SHA-256 hash of the body does not match the one of the URL")
+			return false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+	}
+
+	for _, param := range presignParams {
+		query.Del(param)
+	}
+	c.Request.URL.RawQuery = query.Encode()
+	return true
+}
@@ -0,0 +1,114 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestPresignedInvocations(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Token: "token"})
+
+	r := gin.New()
+	r.POST("/system/services/:serviceName/presign", MakePresignHandler(back))
+	r.POST("/run/:serviceName", func(c *gin.Context) {
+		service, _ := back.ReadService(c.Param("serviceName"))
+		if !checkPresignedInvocation(c, service) {
+			return
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, c.Request.URL.RawQuery+"|"+string(body))
+	})
+
+	presign := func(body string) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/system/services/test/presign", strings.NewReader(body))
+		req.Host = "oscar.example.org"
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expecting code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		presigned := types.PresignedURL{}
+		json.Unmarshal(w.Body.Bytes(), &presigned)
+		u, _ := url.Parse(presigned.URL)
+		if u.Host != "oscar.example.org" || u.Path != "/run/test" || presigned.ExpiresAt.IsZero() {
+			t.Fatalf("unexpected presigned URL %+v", presigned)
+		}
+		return u.RequestURI()
+	}
+	invoke := func(uri string, contentType string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, uri, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without constraints, the parameters of the URL are removed
+	uri := presign("")
+	if w := invoke(uri+"&key=value", "text/plain", "data"); w.Code != http.StatusOK || w.Body.String() != "key=value|data" {
+		t.Errorf("expecting the invocation to be allowed, got %d: %s", w.Code, w.Body.String())
+	}
+	tampered := strings.Replace(uri, "oscar_expires=", "oscar_expires=9", 1)
+	if w := invoke(tampered, "text/plain", "data"); w.Code != http.StatusForbidden || w.Header().Get(errorCodeHeader) != types.ErrPresignedURLInvalid.Code {
+		t.Errorf("expecting the tampered URL to be rejected, got %d", w.Code)
+	}
+	if w := invoke("/run/test?oscar_expires=1&oscar_signature=abc", "text/plain", "data"); w.Code != http.StatusForbidden {
+		t.Errorf("expecting the expired URL to be rejected, got %d", w.Code)
+	}
+
+	// With constraints of the body
+	sum := sha256.Sum256([]byte(`{"a":1}`))
+	uri = presign(`{"expires_in": 60, "max_size": 10, "content_type": "application/json", "sha256": "` + hex.EncodeToString(sum[:]) + `"}`)
+	scenarios := []struct {
+		name         string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{"valid body", "application/json; charset=utf-8", `{"a":1}`, http.StatusOK},
+		{"wrong content type", "text/plain", `{"a":1}`, http.StatusUnprocessableEntity},
+		{"too large body", "application/json", `{"a":1000000}`, http.StatusUnprocessableEntity},
+		{"wrong hash", "application/json", `{"a":2}`, http.StatusUnprocessableEntity},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if w := invoke(uri, s.contentType, s.body); w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The URLs are invalidated with the token of the service
+	back.UpdateService(types.Service{Name: "test", Image: "busybox", Token: "new-token"})
+	if w := invoke(uri, "application/json", `{"a":1}`); w.Code != http.StatusForbidden {
+		t.Errorf("expecting the URL to be invalidated, got %d", w.Code)
+	}
+}
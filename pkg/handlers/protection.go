@@ -0,0 +1,66 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// MakeServiceUnlockHandler makes a handler for unlocking a protected service, so it can be updated or deleted once
+// within the next minutes without the X-Oscar-Confirm header
+func MakeServiceUnlockHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+		if !service.Protected {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The service \"%s\" is not protected", service.Name))
+			return
+		}
+
+		c.JSON(http.StatusOK, utils.UnlockService(service.Name, c.GetString(gin.AuthUserKey)))
+	}
+}
+
+// checkProtectedFlag returns an error if the protected flag of a service is set (or unset) by a user other than the
+// admin of the cluster. oldService is nil for the new services
+func checkProtectedFlag(c *gin.Context, cfg *types.Config, newService *types.Service, oldService *types.Service) error {
+	wasProtected := oldService != nil && oldService.Protected
+	if newService.Protected == wasProtected || isAdmin(c, cfg) {
+		return nil
+	}
+	return types.NewCodedError(types.ErrAdminRequired, fmt.Errorf("the protected flag of the services can only be changed by the admin of the cluster"))
+}
+
+// confirmProtectedChange checks that the update or deletion of a protected service has been confirmed with the
+// X-Oscar-Confirm header or a previous unlock (which is consumed), sending the error otherwise
+func confirmProtectedChange(c *gin.Context, service *types.Service) bool {
+	if service == nil || !service.Protected {
+		return true
+	}
+	if c.GetHeader(types.ConfirmHeader) == service.Name || utils.ConsumeServiceUnlock(service.Name) {
+		return true
+	}
+	sendError(c, types.ErrServiceProtected, fmt.Sprintf("The service \"%s\" is protected, set the %s header to its name or unlock it first", service.Name, types.ConfirmHeader))
+	return false
+}
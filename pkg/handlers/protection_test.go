@@ -0,0 +1,92 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestServiceProtection(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.Username = "admin"
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Memory: "256Mi", Script: "ls", Token: "token", Protected: true})
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.PATCH("/system/services/:serviceName", MakePatchHandler(&cfg, back))
+	r.DELETE("/system/services/:serviceName", MakeDeleteHandler(&cfg, back))
+	r.POST("/system/services/:serviceName/unlock", MakeServiceUnlockHandler(back))
+
+	scenarios := []struct {
+		name              string
+		method            string
+		path              string
+		body              string
+		user              string
+		confirm           string
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"update without confirmation", "PATCH", "/system/services/test", `{"memory": "1Gi"}`, "user", "", http.StatusPreconditionRequired, types.ErrServiceProtected.Code},
+		{"update with wrong confirmation", "PATCH", "/system/services/test", `{"memory": "1Gi"}`, "user", "other", http.StatusPreconditionRequired, types.ErrServiceProtected.Code},
+		{"update with confirmation", "PATCH", "/system/services/test", `{"memory": "1Gi"}`, "user", "test", http.StatusOK, ""},
+		{"delete without confirmation", "DELETE", "/system/services/test", "", "user", "", http.StatusPreconditionRequired, types.ErrServiceProtected.Code},
+		{"unprotect by user", "PATCH", "/system/services/test", `{"protected": false}`, "user", "test", http.StatusForbidden, types.ErrAdminRequired.Code},
+		{"unlock", "POST", "/system/services/test/unlock", "", "user", "", http.StatusOK, ""},
+		{"update after unlock", "PATCH", "/system/services/test", `{"memory": "2Gi"}`, "user", "", http.StatusOK, ""},
+		{"update after consumed unlock", "PATCH", "/system/services/test", `{"memory": "3Gi"}`, "user", "", http.StatusPreconditionRequired, types.ErrServiceProtected.Code},
+		{"unprotect by admin", "PATCH", "/system/services/test", `{"protected": false}`, "admin", "test", http.StatusOK, ""},
+		{"unlock unprotected", "POST", "/system/services/test/unlock", "", "user", "", http.StatusBadRequest, types.ErrBadRequest.Code},
+		{"update unprotected", "PATCH", "/system/services/test", `{"memory": "4Gi"}`, "user", "", http.StatusOK, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, strings.NewReader(s.body))
+			req.Header.Set("Content-Type", mergePatchContentType)
+			req.Header.Set("X-User", s.user)
+			if s.confirm != "" {
+				req.Header.Set(types.ConfirmHeader, s.confirm)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+		})
+	}
+
+	stored, _ := back.ReadService("test")
+	if stored.Memory != "4Gi" || stored.Protected {
+		t.Errorf("unexpected stored service: %+v", stored)
+	}
+}
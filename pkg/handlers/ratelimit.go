@@ -0,0 +1,214 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// maxRateLimitKeys number of keys (client IPs or credentials) tracked before removing the idle ones
+const maxRateLimitKeys = 10000
+
+// tokenBucket requests available to a key
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenBucketLimiter limits the requests of each key with a token bucket, refilled at rate tokens per second up to
+// burst tokens
+type tokenBucketLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+// newTokenBucketLimiter creates a limiter of perMinute requests per minute (nil if disabled), allowing burst
+// requests at once (perMinute if not positive)
+func newTokenBucketLimiter(perMinute int, burst int) *tokenBucketLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = perMinute
+	}
+	return &tokenBucketLimiter{rate: float64(perMinute) / 60, burst: float64(burst), buckets: map[string]*tokenBucket{}}
+}
+
+// allow takes a token of the key's bucket if available. Returns the time to wait until the next token otherwise
+func (l *tokenBucketLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitKeys {
+			l.prune(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune removes the buckets refilled since their last request (equivalent to new ones)
+func (l *tokenBucketLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// authFailureWindow failed authentications of a client IP in the current window
+type authFailureWindow struct {
+	start time.Time
+	count int
+}
+
+// authFailureTracker blocks the client IPs reaching the limit of failed authentications in fixed windows
+type authFailureTracker struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*authFailureWindow
+}
+
+// blocked checks if a client IP has reached the limit in its window, returning the time until the window ends
+func (t *authFailureTracker) blocked(ip string, now time.Time) (bool, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[ip]
+	if !ok {
+		return false, 0
+	}
+	if now.Sub(w.start) >= t.window {
+		delete(t.windows, ip)
+		return false, 0
+	}
+	if w.count >= t.limit {
+		return true, w.start.Add(t.window).Sub(now)
+	}
+	return false, 0
+}
+
+// fail registers a failed authentication of a client IP
+func (t *authFailureTracker) fail(ip string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[ip]
+	if !ok || now.Sub(w.start) >= t.window {
+		if !ok && len(t.windows) >= maxRateLimitKeys {
+			for key, other := range t.windows {
+				if now.Sub(other.start) >= t.window {
+					delete(t.windows, key)
+				}
+			}
+		}
+		w = &authFailureWindow{start: now}
+		t.windows[ip] = w
+	}
+	w.count++
+}
+
+// MakeRateLimitMiddleware makes a middleware limiting the requests to the /system and invocation paths of each
+// client IP and credential (token buckets of RATE_LIMIT_IP and RATE_LIMIT_TOKEN requests per minute), and
+// rejecting the client IPs that fail to authenticate AUTH_FAILURE_LIMIT times during AUTH_FAILURE_WINDOW, so the
+// basic auth credential and the OIDC issuers are protected from brute-force attacks and abuse
+func MakeRateLimitMiddleware(cfg *types.Config) gin.HandlerFunc {
+	ipLimiter := newTokenBucketLimiter(cfg.RateLimitIP, cfg.RateLimitBurst)
+	tokenLimiter := newTokenBucketLimiter(cfg.RateLimitToken, cfg.RateLimitBurst)
+	var failures *authFailureTracker
+	if cfg.AuthFailureLimit > 0 && cfg.AuthFailureWindow > 0 {
+		failures = &authFailureTracker{limit: cfg.AuthFailureLimit, window: cfg.AuthFailureWindow, windows: map[string]*authFailureWindow{}}
+	}
+
+	return func(c *gin.Context) {
+		if !isRateLimited(c.Request.URL.Path) {
+			return
+		}
+		now := time.Now()
+		ip := c.ClientIP()
+
+		if failures != nil {
+			if blocked, wait := failures.blocked(ip, now); blocked {
+				sendRateLimited(c, wait, "Too many failed authentications from the client IP")
+				return
+			}
+		}
+		if ipLimiter != nil {
+			if ok, wait := ipLimiter.allow(ip, now); !ok {
+				sendRateLimited(c, wait, fmt.Sprintf("The client IP has reached its rate limit (%d requests per minute)", cfg.RateLimitIP))
+				return
+			}
+		}
+		if credential := c.GetHeader("Authorization"); tokenLimiter != nil && credential != "" {
+			// Key the credentials by their hash so they are not kept in memory
+			hash := sha256.Sum256([]byte(credential))
+			if ok, wait := tokenLimiter.allow(hex.EncodeToString(hash[:]), now); !ok {
+				sendRateLimited(c, wait, fmt.Sprintf("The credential has reached its rate limit (%d requests per minute)", cfg.RateLimitToken))
+				return
+			}
+		}
+
+		c.Next()
+
+		if failures != nil && c.Writer.Status() == http.StatusUnauthorized {
+			failures.fail(ip, now)
+		}
+	}
+}
+
+// isRateLimited checks if a path is protected by the rate limits (the /system and invocation paths)
+func isRateLimited(path string) bool {
+	if strings.HasPrefix(path, "/system/") {
+		return true
+	}
+	for _, prefix := range callbackPaths {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return isInvocationPath(path)
+}
+
+// sendRateLimited rejects a request with the time to wait in the Retry-After header
+func sendRateLimited(c *gin.Context, wait time.Duration, msg string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	sendError(c, types.ErrRateLimited, msg)
+}
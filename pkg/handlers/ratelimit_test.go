@@ -0,0 +1,140 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestTokenBucketLimiter(t *testing.T) {
+	if newTokenBucketLimiter(0, 10) != nil {
+		t.Error("expecting the limiter to be disabled")
+	}
+
+	// 60 requests per minute (one per second), 2 at once
+	limiter := newTokenBucketLimiter(60, 2)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("key", now); !ok {
+			t.Fatalf("expecting request %d to be allowed", i)
+		}
+	}
+	ok, wait := limiter.allow("key", now)
+	if ok || wait != time.Second {
+		t.Errorf("expecting the request to wait 1s, got %v (%v)", ok, wait)
+	}
+	if ok, _ := limiter.allow("other", now); !ok {
+		t.Error("expecting the requests of other keys to be allowed")
+	}
+	if ok, _ := limiter.allow("key", now.Add(time.Second)); !ok {
+		t.Error("expecting the request to be allowed after refilling a token")
+	}
+
+	// The refilled buckets are pruned
+	limiter.prune(now.Add(time.Minute))
+	if len(limiter.buckets) != 0 {
+		t.Errorf("expecting the buckets to be pruned, got %d", len(limiter.buckets))
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	cfg := &types.Config{RateLimitIP: 60, RateLimitToken: 60, RateLimitBurst: 3, AuthFailureLimit: 2, AuthFailureWindow: time.Minute}
+
+	r := gin.New()
+	r.Use(MakeRateLimitMiddleware(cfg))
+	r.GET("/system/info", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Basic valid" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.POST("/run/:serviceName", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(method string, path string, ip string, auth string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":12345"
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Per-IP limit (the burst of 3 requests)
+	for i := 0; i < 3; i++ {
+		if w := request("POST", "/run/test", "10.0.0.1", ""); w.Code != http.StatusOK {
+			t.Fatalf("expecting request %d to be allowed, got %d", i, w.Code)
+		}
+	}
+	w := request("POST", "/run/test", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expecting code 429 with Retry-After 1, got %d (%s)", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := request("GET", "/health", "10.0.0.1", ""); w.Code != http.StatusOK {
+		t.Errorf("expecting the health path not to be limited, got %d", w.Code)
+	}
+
+	// Per-credential limit (from several IPs)
+	for i := 0; i < 3; i++ {
+		request("POST", "/run/test", fmt.Sprintf("10.0.1.%d", i), "Bearer token")
+	}
+	if w := request("POST", "/run/test", "10.0.1.9", "Bearer token"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expecting the credential to be limited, got %d", w.Code)
+	}
+
+	// Brute-force protection
+	for i := 0; i < 2; i++ {
+		if w := request("GET", "/system/info", "10.0.2.1", "Basic wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expecting code 401, got %d", w.Code)
+		}
+	}
+	w = request("GET", "/system/info", "10.0.2.1", "Basic valid")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("X-Oscar-Error-Code") != types.ErrRateLimited.Code {
+		t.Errorf("expecting the client IP to be blocked, got %d", w.Code)
+	}
+	if w := request("GET", "/system/info", "10.0.2.2", "Basic valid"); w.Code != http.StatusOK {
+		t.Errorf("expecting other client IPs not to be blocked, got %d", w.Code)
+	}
+}
+
+func TestAuthFailureTracker(t *testing.T) {
+	tracker := &authFailureTracker{limit: 1, window: time.Minute, windows: map[string]*authFailureWindow{}}
+	now := time.Now()
+	tracker.fail("10.0.0.1", now)
+
+	blocked, wait := tracker.blocked("10.0.0.1", now.Add(20*time.Second))
+	if !blocked || wait != 40*time.Second {
+		t.Errorf("expecting the IP to be blocked for 40s, got %v (%v)", blocked, wait)
+	}
+	if blocked, _ := tracker.blocked("10.0.0.1", now.Add(time.Minute)); blocked {
+		t.Error("expecting the IP to be unblocked after the window")
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// rbacRule role required by a route of the /system group (any method if method is empty)
+type rbacRule struct {
+	method string
+	route  string
+	role   string
+}
+
+// rbacRules roles required by the routes not following the default rule: the GET requests require the viewer
+// role and the rest the operator role
+var rbacRules = []rbacRule{
+	{http.MethodPost, "/system/services/validate", types.RoleViewer},
+	{http.MethodPost, "/system/vo-requests", types.RoleViewer},
+	{http.MethodPost, "/system/graphql", types.RoleViewer},
+	{http.MethodPost, "/system/services/:serviceName/replay", types.RoleInvoker},
+	{http.MethodPost, "/system/services/:serviceName/simulate-event", types.RoleInvoker},
+	{http.MethodPost, "/system/services/:serviceName/presign", types.RoleInvoker},
+	{http.MethodPost, "/system/logs/:serviceName/:jobName/retry", types.RoleInvoker},
+	{http.MethodPost, "/system/vo-requests/:requestID/approve", types.RoleAdmin},
+	{http.MethodPost, "/system/vo-requests/:requestID/reject", types.RoleAdmin},
+	{http.MethodPost, "/system/admin/reconcile", types.RoleAdmin},
+	{http.MethodPost, "/system/maintenance/freeze", types.RoleAdmin},
+	{http.MethodDelete, "/system/maintenance/freeze", types.RoleAdmin},
+	{"", "/system/chaos/faults", types.RoleAdmin},
+	{"", "/system/apikeys", types.RoleAdmin},
+	{"", "/system/users", types.RoleAdmin},
+	{"", "/system/users/:username", types.RoleAdmin},
+	{"", "/system/apikeys/:keyName", types.RoleAdmin},
+}
+
+// MakeRBACMiddleware makes a middleware enforcing the role of the authenticated users (mapped from their names and
+// OIDC groups) in the /system group. The requests authorised with the named access tokens of a service are only
+// limited by their scopes, and the roles assigned by the authentication (API keys and users of the user store) are
+// always enforced
+func MakeRBACMiddleware(cfg *types.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(serviceTokenAuthKey) {
+			return
+		}
+
+		role := c.GetString(types.RoleKey)
+		if role == "" {
+			if !cfg.RBACEnable {
+				return
+			}
+			role = cfg.GetUserRole(c.GetString(gin.AuthUserKey), c.GetStringSlice(types.UserGroupsKey))
+			c.Set(types.RoleKey, role)
+		}
+
+		required := getRequiredRole(c.Request.Method, c.FullPath())
+		if !types.HasRole(role, required) {
+			sendError(c, types.ErrRoleRequired, fmt.Sprintf("The request requires the role \"%s\" (the role of the user is \"%s\")", required, role))
+		}
+	}
+}
+
+// getRequiredRole returns the role required by a route
+func getRequiredRole(method string, route string) string {
+	for _, rule := range rbacRules {
+		if rule.route == route && (rule.method == "" || rule.method == method) {
+			return rule.role
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return types.RoleViewer
+	}
+	return types.RoleOperator
+}
@@ -0,0 +1,105 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestRBACMiddleware(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.Username = "oscar"
+	cfg.RBACEnable = true
+	cfg.RBACDefaultRole = types.RoleViewer
+	cfg.RBACRoles = []string{"user:alice=operator", "user:bob=operator", "group:vo.example.eu=invoker", "user:carol=admin"}
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Owner: "alice", VO: "vo.example.eu"})
+
+	ok := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	r := gin.Default()
+	system := r.Group("/system", func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+		if groups := c.GetHeader("X-Groups"); groups != "" {
+			c.Set(types.UserGroupsKey, strings.Split(groups, ","))
+		}
+	}, MakeRBACMiddleware(&cfg), MakeServiceOwnershipMiddleware(&cfg, back))
+	system.GET("/services", ok)
+	system.POST("/services", ok)
+	system.DELETE("/services/:serviceName", ok)
+	system.POST("/services/:serviceName/replay", ok)
+	system.POST("/admin/reconcile", ok)
+
+	scenarios := []struct {
+		name              string
+		method            string
+		path              string
+		user              string
+		groups            string
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"viewer lists", "GET", "/system/services", "dave", "", http.StatusOK, ""},
+		{"viewer creates", "POST", "/system/services", "dave", "", http.StatusForbidden, types.ErrRoleRequired.Code},
+		{"viewer replays", "POST", "/system/services/test/replay", "dave", "vo.other.eu", http.StatusForbidden, types.ErrRoleRequired.Code},
+		{"invoker replays", "POST", "/system/services/test/replay", "dave", "vo.example.eu", http.StatusOK, ""},
+		{"invoker creates", "POST", "/system/services", "dave", "vo.example.eu", http.StatusForbidden, types.ErrRoleRequired.Code},
+		{"operator creates", "POST", "/system/services", "bob", "", http.StatusOK, ""},
+		{"operator deletes other's service", "DELETE", "/system/services/test", "bob", "", http.StatusForbidden, types.ErrServiceAccessDenied.Code},
+		{"owner deletes", "DELETE", "/system/services/test", "alice", "", http.StatusOK, ""},
+		{"operator reconciles", "POST", "/system/admin/reconcile", "alice", "", http.StatusForbidden, types.ErrRoleRequired.Code},
+		{"admin deletes other's service", "DELETE", "/system/services/test", "carol", "", http.StatusOK, ""},
+		{"admin reconciles", "POST", "/system/admin/reconcile", "carol", "", http.StatusOK, ""},
+		{"cluster admin reconciles", "POST", "/system/admin/reconcile", "oscar", "", http.StatusOK, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, nil)
+			req.Header.Set("X-User", s.user)
+			req.Header.Set("X-Groups", s.groups)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+		})
+	}
+
+	// Without RBAC all the users are allowed
+	cfg.RBACEnable = false
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/system/admin/reconcile", nil)
+	req.Header.Set("X-User", "dave")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expecting code %d without RBAC, got %d", http.StatusOK, w.Code)
+	}
+}
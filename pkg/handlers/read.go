@@ -17,27 +17,91 @@ limitations under the License.
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/types"
 	"k8s.io/apimachinery/pkg/api/errors"
 )
 
-// MakeReadHandler makes a handler for reading a service
-func MakeReadHandler(back types.ServerlessBackend) gin.HandlerFunc {
+const (
+	includeStatus     = "status"
+	includeExecutions = "executions"
+
+	defaultLastExecutions = 10
+)
+
+// serviceWithRuntime service definition extended with its runtime info
+type serviceWithRuntime struct {
+	*types.Service
+	Status     *types.ServiceStatus `json:"status,omitempty"`
+	Executions []types.JobSummary   `json:"executions,omitempty"`
+}
+
+// MakeReadHandler makes a handler for reading a service.
+// The "include" querystring accepts a comma-separated list ("status", "executions") to embed runtime info
+func MakeReadHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Parse the include querystring
+		var withStatus, withExecutions bool
+		if include := c.Query("include"); include != "" {
+			for _, opt := range strings.Split(include, ",") {
+				switch strings.TrimSpace(opt) {
+				case includeStatus:
+					withStatus = true
+				case includeExecutions:
+					withExecutions = true
+				default:
+					sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid include option \"%s\"", opt))
+					return
+				}
+			}
+		}
+
+		// Get the number of executions to include (default: 10)
+		last, err := strconv.Atoi(c.DefaultQuery("last", strconv.Itoa(defaultLastExecutions)))
+		if err != nil || last < 0 {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid last value \"%s\", it must be a non-negative integer", c.Query("last")))
+			return
+		}
+
 		service, err := back.ReadService(c.Param("serviceName"))
 		if err != nil {
 			// Check if error is caused because the service is not found
 			if errors.IsNotFound(err) || errors.IsGone(err) {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrServiceNotFound, "")
 			} else {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrServiceReadFailed, err.Error())
 			}
 			return
 		}
 
-		c.JSON(http.StatusOK, service)
+		if !withStatus && !withExecutions {
+			c.JSON(http.StatusOK, service)
+			return
+		}
+
+		res := serviceWithRuntime{Service: service}
+
+		if withStatus {
+			res.Status, err = getServiceStatus(cfg, back.GetKubeClientset(), service)
+			if err != nil {
+				sendError(c, types.ErrInternal, fmt.Sprintf("Error getting the service status: %v", err))
+				return
+			}
+		}
+
+		if withExecutions {
+			res.Executions, err = getLastJobs(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name, last)
+			if err != nil {
+				sendError(c, types.ErrInternal, fmt.Sprintf("Error getting the service executions: %v", err))
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, res)
 	}
 }
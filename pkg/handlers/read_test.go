@@ -1,21 +1,30 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
 )
 
 func TestMakeReadHandler(t *testing.T) {
 	back := backends.MakeFakeBackend()
 
 	r := gin.Default()
-	r.GET("/system/services/:serviceName", MakeReadHandler(back))
+	r.GET("/system/services/:serviceName", MakeReadHandler(&testConfigValidRun, back))
 
 	scenarios := []struct {
 		name        string
@@ -61,3 +70,136 @@ func TestMakeReadHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeReadHandlerInclude(t *testing.T) {
+	back := backends.MakeFakeBackend()
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName", MakeReadHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name         string
+		include      string
+		expectedCode int
+	}{
+		{"executions", "executions", http.StatusOK},
+		{"status", "status", http.StatusOK},
+		{"invalid option", "status,foo", http.StatusBadRequest},
+		{"invalid last", "executions&last=abc", http.StatusBadRequest},
+		{"negative last", "executions&last=-1", http.StatusBadRequest},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/system/services/testName?include="+s.include, nil)
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestMakeReadHandlerRuntime(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	phases := []v1.PodPhase{v1.PodSucceeded, v1.PodFailed, v1.PodPending, v1.PodRunning, v1.PodPending}
+
+	objects := []runtime.Object{}
+	for i, phase := range phases {
+		name := fmt.Sprintf("job-%d", i)
+		startTime := metav1.NewTime(start.Add(time.Duration(i) * time.Minute))
+		labels := map[string]string{types.ServiceLabel: "testName"}
+		objects = append(objects, &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testConfigValidRun.ServicesNamespace, Labels: labels},
+			Status:     batchv1.JobStatus{StartTime: &startTime},
+		})
+		objects = append(objects, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-pod",
+				Namespace: testConfigValidRun.ServicesNamespace,
+				Labels:    map[string]string{types.ServiceLabel: "testName", "job-name": name},
+			},
+			Status: v1.PodStatus{Phase: phase},
+		})
+	}
+	// Pods of other services and not created by jobs (e.g. exposed services) are not taken into account
+	objects = append(objects,
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "exposed", Namespace: testConfigValidRun.ServicesNamespace, Labels: map[string]string{types.ServiceLabel: "testName"}},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testConfigValidRun.ServicesNamespace, Labels: map[string]string{types.ServiceLabel: "other", "job-name": "other"}},
+			Status:     v1.PodStatus{Phase: v1.PodPending},
+		},
+	)
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "testName"})
+	tracker := back.GetKubeClientset().(*testclient.Clientset).Tracker()
+	for _, obj := range objects {
+		if err := tracker.Add(obj); err != nil {
+			t.Fatalf("error adding the object: %v", err)
+		}
+	}
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName", MakeReadHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name               string
+		query              string
+		expectedStatus     *types.ServiceStatus
+		expectedExecutions []string
+	}{
+		{"status", "include=status", &types.ServiceStatus{PendingJobs: 2, RunningJobs: 1}, nil},
+		{"default last", "include=executions", nil, []string{"job-4", "job-3", "job-2", "job-1", "job-0"}},
+		{"last 2", "include=executions&last=2", nil, []string{"job-4", "job-3"}},
+		{"last 0", "include=executions&last=0", nil, nil},
+		{"status and executions", "include=status,executions&last=1", &types.ServiceStatus{PendingJobs: 2, RunningJobs: 1}, []string{"job-4"}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/system/services/testName?"+s.query, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			var res struct {
+				Status     *types.ServiceStatus `json:"status"`
+				Executions []types.JobSummary   `json:"executions"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error decoding the response: %v", err)
+			}
+
+			if s.expectedStatus == nil && res.Status != nil {
+				t.Errorf("expecting no status, got %+v", res.Status)
+			}
+			if s.expectedStatus != nil {
+				if res.Status == nil {
+					t.Fatalf("expecting status, got none")
+				}
+				if res.Status.PendingJobs != s.expectedStatus.PendingJobs || res.Status.RunningJobs != s.expectedStatus.RunningJobs {
+					t.Errorf("expecting %d pending and %d running jobs, got %d and %d", s.expectedStatus.PendingJobs, s.expectedStatus.RunningJobs, res.Status.PendingJobs, res.Status.RunningJobs)
+				}
+			}
+
+			if len(res.Executions) != len(s.expectedExecutions) {
+				t.Fatalf("expecting %d executions, got %d", len(s.expectedExecutions), len(res.Executions))
+			}
+			for i, name := range s.expectedExecutions {
+				if res.Executions[i].Name != name {
+					t.Errorf("expecting execution %d to be \"%s\", got \"%s\"", i, name, res.Executions[i].Name)
+				}
+			}
+		})
+	}
+}
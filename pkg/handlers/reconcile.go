@@ -0,0 +1,154 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// MakeReconcileHandler makes a handler to re-derive the MinIO webhooks and bucket notifications expected by the
+// stored services, restoring the missing ones (e.g. after a redeployment of MinIO). With the "dry_run" querystring
+// set to true the drift is only reported (admin only)
+func MakeReconcileHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		services, err := back.ListServices()
+		if err != nil {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+			return
+		}
+		sort.Slice(services, func(i, j int) bool {
+			return services[i].Name < services[j].Name
+		})
+
+		report := types.ReconcileReport{
+			DryRun:   isDryRun(c),
+			Services: []types.ServiceReconciliation{},
+		}
+
+		// The webhooks are restored first, as MinIO rejects the notifications targeting unknown webhooks
+		registered := reconcileWebhooks(cfg, services, &report)
+		if registered {
+			if err := restartMinIO(cfg); err != nil {
+				report.Message = err.Error()
+			} else {
+				report.MinIORestarted = true
+			}
+		}
+
+		for i := range services {
+			report.Services[i].Notifications = reconcileNotifications(services[i], report.DryRun)
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// reconcileWebhooks checks the webhook of each service, registering the missing ones (unless in dry-run mode).
+// Returns true if any webhook has been registered
+func reconcileWebhooks(cfg *types.Config, services []*types.Service, report *types.ReconcileReport) bool {
+	minIOAdminClient, adminErr := utils.MakeMinIOAdminClient(cfg)
+	registered := false
+
+	for _, service := range services {
+		item := types.ReconcileItem{Resource: service.Name}
+
+		if adminErr != nil {
+			item.Status = types.ReconcileFailed
+			item.Message = fmt.Sprintf("the provided MinIO configuration is not valid: %v", adminErr)
+		} else if ok, err := minIOAdminClient.IsWebhookRegistered(service.Name); err != nil {
+			item.Status = types.ReconcileFailed
+			item.Message = fmt.Sprintf("error checking the service's webhook: %v", err)
+		} else if ok {
+			item.Status = types.ReconcileOK
+		} else if report.DryRun {
+			item.Status = types.ReconcileMissing
+			item.Message = "the service's webhook is not registered in MinIO"
+		} else if err := minIOAdminClient.RegisterWebhook(service.Name, service.Token); err != nil {
+			item.Status = types.ReconcileFailed
+			item.Message = fmt.Sprintf("error registering the service's webhook: %v", err)
+		} else {
+			log.Printf("Restored the webhook of service \"%s\"\n", service.Name)
+			item.Status = types.ReconcileFixed
+			registered = true
+		}
+
+		report.Services = append(report.Services, types.ServiceReconciliation{Service: service.Name, Webhook: item})
+	}
+
+	return registered
+}
+
+// restartMinIO restarts the MinIO server to apply the restored webhooks
+func restartMinIO(cfg *types.Config) error {
+	minIOAdminClient, err := utils.MakeMinIOAdminClient(cfg)
+	if err != nil {
+		return fmt.Errorf("the provided MinIO configuration is not valid: %v", err)
+	}
+	return minIOAdminClient.RestartServer()
+}
+
+// reconcileNotifications checks the bucket notifications of the enabled MinIO inputs of a service, enabling the
+// missing ones (unless in dry-run mode)
+func reconcileNotifications(service *types.Service, dryRun bool) []types.ReconcileItem {
+	items := []types.ReconcileItem{}
+
+	for _, in := range service.Input {
+		provName, provID := in.GetProvider()
+		if provName != types.MinIOName || in.Disabled {
+			continue
+		}
+		item := types.ReconcileItem{Resource: fmt.Sprintf("%s/%s", in.Provider, in.Path)}
+
+		if service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil || service.StorageProviders.MinIO[types.DefaultProvider] == nil {
+			item.Status = types.ReconcileFailed
+			item.Message = fmt.Sprintf("the StorageProvider \"%s.%s\" is not defined", provName, provID)
+			items = append(items, item)
+			continue
+		}
+
+		minIOClient := service.StorageProviders.MinIO[provID].GetS3Client()
+		arnStr := service.GetMinIOWebhookARN()
+		if err := checkInputNotification(minIOClient, arnStr, in); err == nil {
+			item.Status = types.ReconcileOK
+		} else if dryRun {
+			item.Status = types.ReconcileMissing
+			item.Message = err.Error()
+		} else if err := enableInputNotification(minIOClient, arnStr, in); err != nil {
+			item.Status = types.ReconcileFailed
+			item.Message = err.Error()
+		} else {
+			log.Printf("Restored the notification of input \"%s\" of service \"%s\"\n", in.Path, service.Name)
+			item.Status = types.ReconcileFixed
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
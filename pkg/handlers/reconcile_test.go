@@ -0,0 +1,123 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeReconcileHandler(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("input")
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	cfg.Username = "admin"
+	providers := &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: cfg.MinIOProvider}}
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{
+		Name: "test",
+		Input: []types.StorageIOConfig{
+			{Provider: "minio.default", Path: "input/in"},
+			{Provider: "minio.default", Path: "input/disabled", Disabled: true},
+		},
+		StorageProviders: providers,
+	})
+	back.CreateService(types.Service{
+		Name:             "missing",
+		Input:            []types.StorageIOConfig{{Provider: "minio.default", Path: "missing/in"}},
+		StorageProviders: providers,
+	})
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.POST("/system/admin/reconcile", MakeReconcileHandler(&cfg, back))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/system/admin/reconcile", nil)
+	req.Header.Set("X-User", "user")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for a non-admin user, got %d", http.StatusForbidden, w.Code)
+	}
+
+	reconcile := func(query string) types.ReconcileReport {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/system/admin/reconcile"+query, nil)
+		req.Header.Set("X-User", "admin")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		report := types.ReconcileReport{}
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(report.Services) != 2 || report.Services[0].Service != "missing" || report.Services[1].Service != "test" {
+			t.Fatalf("unexpected services: %+v", report.Services)
+		}
+		return report
+	}
+
+	expectNotification := func(sr types.ServiceReconciliation, status string) {
+		if len(sr.Notifications) != 1 {
+			t.Fatalf("expected 1 notification, got %+v", sr.Notifications)
+		}
+		if sr.Notifications[0].Status != status {
+			t.Errorf("expected notification status \"%s\", got %+v", status, sr.Notifications[0])
+		}
+	}
+
+	// The drift is only reported in dry-run mode
+	report := reconcile("?dry_run=true")
+	if !report.DryRun || report.MinIORestarted {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	// The webhook can't be checked without the MinIO admin API
+	if report.Services[1].Webhook.Status != types.ReconcileFailed {
+		t.Errorf("expected a failed webhook, got %+v", report.Services[1].Webhook)
+	}
+	expectNotification(report.Services[1], types.ReconcileMissing)
+	expectNotification(report.Services[0], types.ReconcileMissing)
+
+	// The missing notification is restored
+	report = reconcile("")
+	if report.DryRun {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	expectNotification(report.Services[1], types.ReconcileFixed)
+	// The bucket of the other service doesn't exist
+	expectNotification(report.Services[0], types.ReconcileFailed)
+
+	report = reconcile("")
+	expectNotification(report.Services[1], types.ReconcileOK)
+	if !strings.Contains(report.Services[0].Notifications[0].Message, "missing") {
+		t.Errorf("unexpected message: %s", report.Services[0].Notifications[0].Message)
+	}
+}
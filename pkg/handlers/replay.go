@@ -0,0 +1,158 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// maxReplayEvents maximum number of objects replayed in a single request
+const maxReplayEvents = 1000
+
+// MakeReplayHandler makes a handler for re-enqueuing events of the objects stored in the service's MinIO inputs.
+// The events are processed by the dispatcher like the ones received by the job handler (filters, file sets and batches)
+func MakeReplayHandler(back types.ServerlessBackend, dispatcher *EventDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.ReplayRequest
+		// The request body is optional (replay all the objects)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("The replay request is not valid: %v", err))
+				return
+			}
+		}
+		if req.Limit < 0 || req.Limit > maxReplayEvents {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The limit must be between 1 and %d", maxReplayEvents))
+			return
+		}
+		if req.Limit == 0 {
+			req.Limit = maxReplayEvents
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		res := types.ReplayResult{Jobs: []string{}}
+		for _, in := range service.Input {
+			if provName, _ := in.GetProvider(); provName != types.MinIOName {
+				continue
+			}
+			if err := replayInput(dispatcher, service, in, req, &res); err != nil {
+				sendCodedError(c, err, types.ErrInternal)
+				return
+			}
+			if res.Truncated {
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// replayInput dispatches an event for each object of the input satisfying the replay request,
+// stopping (and marking the result as truncated) when the request's limit is reached
+func replayInput(dispatcher *EventDispatcher, service *types.Service, in types.StorageIOConfig, req types.ReplayRequest, res *types.ReplayResult) error {
+	_, provID := in.GetProvider()
+	if service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+		return types.NewCodedError(types.ErrStorageProviderNotDefined, fmt.Errorf("the StorageProvider \"%s\" is not defined", in.Provider))
+	}
+	s3Client := service.StorageProviders.MinIO[provID].GetS3Client()
+
+	bucket, folder := in.SplitPath()
+	prefix := strings.TrimPrefix(req.Prefix, "/")
+	if folder != "" {
+		prefix = fmt.Sprintf("%s/%s", folder, prefix)
+	}
+
+	var runErr error
+	err := s3Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			// Skip folders
+			if strings.HasSuffix(key, "/") || !req.InTimeRange(aws.TimeValue(obj.LastModified)) {
+				continue
+			}
+			if res.Events+res.Skipped >= req.Limit {
+				res.Truncated = true
+				return false
+			}
+
+			object := types.MinIOEventObject{
+				Key:  key,
+				Size: aws.Int64Value(obj.Size),
+				ETag: strings.Trim(aws.StringValue(obj.ETag), "\""),
+			}
+			// The content type is not returned when listing objects
+			if len(in.ContentTypes) > 0 {
+				head, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(bucket), Key: obj.Key})
+				if err != nil {
+					runErr = types.NewCodedError(types.ErrStorageConnectionFailed, fmt.Errorf("error getting the object \"%s/%s\": %v", bucket, key, err))
+					return false
+				}
+				object.ContentType = aws.StringValue(head.ContentType)
+			}
+
+			eventBytes, _ := json.Marshal(types.NewMinIOEvent(bucket, object, time.Now()))
+			status, jobName, err := dispatcher.dispatch(service, eventBytes)
+			if err != nil {
+				runErr = types.NewCodedError(types.GetErrorCode(err, types.ErrInternal), fmt.Errorf("error replaying object \"%s/%s\": %v", bucket, key, err))
+				return false
+			}
+			if status == eventDiscarded {
+				res.Skipped++
+				continue
+			}
+			res.Events++
+			if status == eventDelivered && jobName != "" {
+				res.Jobs = append(res.Jobs, jobName)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return types.NewCodedError(types.ErrStorageConnectionFailed, fmt.Errorf("error listing the objects of bucket \"%s\": %v", bucket, err))
+	}
+	if runErr != nil {
+		log.Printf("Error replaying events for service \"%s\": %v\n", service.Name, runErr)
+		return runErr
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// testS3Provider returns a MinIO provider using the in-memory S3 server
+func testS3Provider(s3Server *chaos.S3Server) *types.MinIOProvider {
+	return &types.MinIOProvider{
+		Endpoint:  s3Server.URL,
+		Region:    "us-east-1",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Verify:    true,
+	}
+}
+
+func TestMakeReplayHandler(t *testing.T) {
+	back := backends.MakeFakeBackend()
+	kubeClientset := testclient.NewSimpleClientset()
+
+	r := gin.Default()
+	r.POST("/system/services/:serviceName/replay", MakeReplayHandler(back, MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)))
+
+	scenarios := []struct {
+		name         string
+		body         string
+		notFound     bool
+		expectedCode int
+	}{
+		{"valid without body", "", false, http.StatusOK},
+		{"valid with time range", `{"prefix": "images/", "from": "2023-01-01T00:00:00Z"}`, false, http.StatusOK},
+		{"invalid body", `{"from": "yesterday"}`, false, http.StatusBadRequest},
+		{"limit too high", `{"limit": 5000}`, false, http.StatusBadRequest},
+		{"service not found", "", true, http.StatusNotFound},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if s.notFound {
+				back.AddError("ReadService", k8serr.NewGone("Not Found"))
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/services/test/replay", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestMakeReplayHandlerInputs(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+
+	recent := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	old := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s3Server.PutObject("input", "images/a.jpg", chaos.S3Object{Data: make([]byte, 100), ContentType: "image/jpeg", LastModified: recent})
+	s3Server.PutObject("input", "images/b.txt", chaos.S3Object{Data: make([]byte, 100), ContentType: "text/plain", LastModified: recent})
+	s3Server.PutObject("input", "images/c.jpg", chaos.S3Object{Data: make([]byte, 4096), ContentType: "image/jpeg", LastModified: recent})
+	s3Server.PutObject("input", "images/old.jpg", chaos.S3Object{Data: make([]byte, 100), ContentType: "image/jpeg", LastModified: old})
+	s3Server.PutObject("input", "images/sub/", chaos.S3Object{Data: make([]byte, 0), ContentType: "", LastModified: recent})
+	s3Server.PutObject("input", "other/d.jpg", chaos.S3Object{Data: make([]byte, 100), ContentType: "image/jpeg", LastModified: recent})
+	s3Server.PutObject("sets", "a.jpg", chaos.S3Object{Data: make([]byte, 100), ContentType: "image/jpeg", LastModified: recent})
+	s3Server.PutObject("sets", "a.txt", chaos.S3Object{Data: make([]byte, 100), ContentType: "text/plain", LastModified: recent})
+	s3Server.PutObject("sets", "b.jpg", chaos.S3Object{Data: make([]byte, 100), ContentType: "image/jpeg", LastModified: recent})
+
+	filtersInput := types.StorageIOConfig{
+		Provider:     types.MinIOName + types.ProviderSeparator + types.DefaultProvider,
+		Path:         "input/images",
+		MaxSize:      "1Ki",
+		ContentTypes: []string{"image/*"},
+	}
+	fileSetInput := types.StorageIOConfig{
+		Provider: types.MinIOName + types.ProviderSeparator + types.DefaultProvider,
+		Path:     "sets",
+		FileSet:  []string{"{name}.jpg", "{name}.txt"},
+	}
+
+	scenarios := []struct {
+		name              string
+		input             types.StorageIOConfig
+		batch             bool
+		body              string
+		expectedEvents    int
+		expectedSkipped   int
+		expectedJobs      int
+		expectedTruncated bool
+	}{
+		// a.jpg and old.jpg satisfy the filters, b.txt (content type) and c.jpg (size) are skipped
+		{"filters", filtersInput, false, "", 2, 2, 2, false},
+		{"time range", filtersInput, false, `{"from": "2023-01-01T00:00:00Z"}`, 1, 2, 1, false},
+		{"prefix", filtersInput, false, `{"prefix": "a"}`, 1, 0, 1, false},
+		{"limit", filtersInput, false, `{"limit": 1}`, 1, 0, 1, true},
+		// The events are aggregated in the service's batch instead of creating a job for each one
+		{"batch", filtersInput, true, "", 2, 2, 0, false},
+		// Only the complete file set "a" creates a job
+		{"file set", fileSetInput, false, "", 3, 0, 1, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &types.Service{
+				Name:  "replay-" + strings.ReplaceAll(s.name, " ", "-"),
+				Image: "test",
+				Input: []types.StorageIOConfig{s.input},
+				StorageProviders: &types.StorageProviders{
+					MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: testS3Provider(s3Server)},
+				},
+			}
+			if s.batch {
+				service.Batch.Window = 3600
+			}
+			back := backends.MakeMemoryBackend()
+			back.CreateService(*service)
+			kubeClientset := testclient.NewSimpleClientset()
+
+			r := gin.Default()
+			r.POST("/system/services/:serviceName/replay", MakeReplayHandler(back, MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/services/"+service.Name+"/replay", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expecting code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			var res types.ReplayResult
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error decoding the result: %v", err)
+			}
+			if res.Events != s.expectedEvents || res.Skipped != s.expectedSkipped || res.Truncated != s.expectedTruncated {
+				t.Errorf("expecting %d events, %d skipped and truncated %v, got %+v", s.expectedEvents, s.expectedSkipped, s.expectedTruncated, res)
+			}
+			if len(res.Jobs) != s.expectedJobs {
+				t.Errorf("expecting %d jobs in the result, got %d", s.expectedJobs, len(res.Jobs))
+			}
+
+			jobs, _ := kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).List(context.TODO(), metav1.ListOptions{})
+			if len(jobs.Items) != s.expectedJobs {
+				t.Errorf("expecting %d created jobs, got %d", s.expectedJobs, len(jobs.Items))
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeReportsHandler makes a handler for getting the usage report of the cluster per VO
+func MakeReportsHandler(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" && format != "html" {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid format \"%s\", valid formats are \"json\", \"csv\" and \"html\"", format))
+			return
+		}
+
+		// By default the report covers the last reports interval
+		to := time.Now().UTC()
+		if toStr := c.Query("to"); toStr != "" {
+			var err error
+			if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid \"to\" date: %v", err))
+				return
+			}
+		}
+		from := to.Add(-cfg.ReportsInterval)
+		if fromStr := c.Query("from"); fromStr != "" {
+			var err error
+			if from, err = time.Parse(time.RFC3339, fromStr); err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid \"from\" date: %v", err))
+				return
+			}
+		}
+		if !from.Before(to) {
+			sendError(c, types.ErrBadRequest, "The \"from\" date must be before the \"to\" date")
+			return
+		}
+
+		report, err := utils.GenerateUsageReport(cfg, back, kubeClientset, from, to)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		switch format {
+		case "csv":
+			csvReport, err := utils.UsageReportToCSV(report)
+			if err != nil {
+				sendError(c, types.ErrInternal, err.Error())
+				return
+			}
+			c.Data(http.StatusOK, "text/csv; charset=utf-8", csvReport)
+		case "html":
+			html, err := utils.UsageReportToHTML(report)
+			if err != nil {
+				sendError(c, types.ErrInternal, err.Error())
+				return
+			}
+			c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+		default:
+			c.JSON(http.StatusOK, report)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeReportsHandler(t *testing.T) {
+	back := backends.MakeFakeBackend()
+	kubeClientset := testclient.NewSimpleClientset()
+	cfg := testConfigValidRun
+	cfg.ReportsInterval = 7 * 24 * time.Hour
+
+	r := gin.Default()
+	r.GET("/system/reports", MakeReportsHandler(&cfg, back, kubeClientset))
+
+	scenarios := []struct {
+		name                string
+		query               string
+		expectedCode        int
+		expectedContentType string
+	}{
+		{"default format", "", http.StatusOK, "application/json"},
+		{"csv format", "?format=csv", http.StatusOK, "text/csv"},
+		{"html format", "?format=html", http.StatusOK, "text/html"},
+		{"period", "?from=2023-01-01T00:00:00Z&to=2023-02-01T00:00:00Z", http.StatusOK, "application/json"},
+		{"invalid format", "?format=xml", http.StatusBadRequest, "application/json"},
+		{"invalid from", "?from=yesterday", http.StatusBadRequest, "application/json"},
+		{"from after to", "?from=2023-02-01T00:00:00Z&to=2023-01-01T00:00:00Z", http.StatusBadRequest, "application/json"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/system/reports"+s.query, nil)
+			// Get the JSON body of the errors
+			req.Header.Set("Accept", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, s.expectedContentType) {
+				t.Errorf("expecting content type %s, got %s", s.expectedContentType, contentType)
+			}
+		})
+	}
+}
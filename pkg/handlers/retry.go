@@ -0,0 +1,86 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/resourcemanager"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeJobRetryHandler makes a handler for re-submitting the event of a job as a new job with the current service
+// definition. The new job is annotated with the name of the retried one
+func MakeJobRetryHandler(cfg *types.Config, kubeClientset kubernetes.Interface, back types.ServerlessBackend, rm resourcemanager.ResourceManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceName := c.Param("serviceName")
+		jobName := c.Param("jobName")
+
+		original, err := getJob(kubeClientset, cfg.GetJobsNamespace(), jobName)
+		if err != nil {
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrJobNotFound, "")
+			} else {
+				sendError(c, types.ErrJobReadFailed, err.Error())
+			}
+			return
+		}
+		if original.Labels[types.ServiceLabel] != serviceName {
+			sendError(c, types.ErrJobNotFound, "")
+			return
+		}
+
+		service, err := back.ReadService(serviceName)
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		event := utils.GetJobEvent(original)
+		job, err := makeJob(cfg, service, event)
+		if err != nil {
+			sendError(c, types.ErrJobCreateFailed, err.Error())
+			return
+		}
+
+		// Copy the annotations, they are shared with the service definition
+		annotations := map[string]string{}
+		for key, value := range job.Annotations {
+			annotations[key] = value
+		}
+		annotations[types.RetryOfAnnotation] = original.Name
+		job.Annotations = annotations
+
+		name, err := submitJob(cfg, kubeClientset, service, job, event, rm)
+		if err != nil {
+			sendCodedError(c, fmt.Errorf("Error retrying the job \"%s\": %w", original.Name, err), types.ErrJobCreateFailed)
+			return
+		}
+
+		c.JSON(http.StatusCreated, types.JobRetry{Job: name, RetryOf: original.Name})
+	}
+}
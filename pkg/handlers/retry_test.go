@@ -0,0 +1,102 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeJobRetryHandler(t *testing.T) {
+	back := backends.MakeFakeBackend()
+	kubeClientset := testclient.NewSimpleClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "original",
+			Namespace: testConfigValidRun.ServicesNamespace,
+			Labels:    map[string]string{types.ServiceLabel: "test"},
+		},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: types.ContainerName, Env: []v1.EnvVar{{Name: types.EventVariable, Value: "{\"key\": \"in/file\"}"}}}},
+				},
+			},
+		},
+	})
+
+	r := gin.Default()
+	r.POST("/system/logs/:serviceName/:jobName/retry", MakeJobRetryHandler(&testConfigValidRun, kubeClientset, back, nil))
+
+	scenarios := []struct {
+		name              string
+		path              string
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"retry", "/system/logs/test/original/retry", http.StatusCreated, ""},
+		{"retry missing job", "/system/logs/test/missing/retry", http.StatusNotFound, types.ErrJobNotFound.Code},
+		{"retry job of other service", "/system/logs/other/original/retry", http.StatusNotFound, types.ErrJobNotFound.Code},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+			if s.expectedCode != http.StatusCreated {
+				return
+			}
+
+			var res types.JobRetry
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error decoding the result: %v", err)
+			}
+			if res.RetryOf != "original" {
+				t.Errorf("expecting retry of \"original\", got \"%s\"", res.RetryOf)
+			}
+			job, err := kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).Get(context.TODO(), res.Job, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("the job has not been created: %v", err)
+			}
+			if job.Annotations[types.RetryOfAnnotation] != "original" {
+				t.Errorf("expecting the annotation %s, got %v", types.RetryOfAnnotation, job.Annotations)
+			}
+			if event := utils.GetJobEvent(job); event != "{\"key\": \"in/file\"}" {
+				t.Errorf("expecting the event of the original job, got %s", event)
+			}
+		})
+	}
+}
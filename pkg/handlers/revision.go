@@ -0,0 +1,115 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MakeRevisionListHandler makes a handler for listing the stored revisions of a service definition, newest first
+func MakeRevisionListHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := readUpdatedService(back, c.Param("serviceName"))
+		if err != nil {
+			sendCodedError(c, err, types.ErrServiceReadFailed)
+			return
+		}
+
+		revisions, err := utils.ListServiceRevisions(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+// MakeRollbackHandler makes a handler for restoring a revision of a service definition. The restored definition
+// (keeping the current token) is stored as a new revision
+func MakeRollbackHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revision, err := strconv.Atoi(c.Param("revision"))
+		if err != nil || revision < 1 {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid revision \"%s\", it must be a positive integer", c.Param("revision")))
+			return
+		}
+
+		// Read the current service
+		oldService, err := readUpdatedService(back, c.Param("serviceName"))
+		if err != nil {
+			sendCodedError(c, err, types.ErrServiceReadFailed)
+			return
+		}
+
+		newService, err := utils.GetServiceRevision(back.GetKubeClientset(), cfg.ServicesNamespace, oldService.Name, revision)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				sendError(c, types.ErrRevisionNotFound, fmt.Sprintf("The revision %d of service \"%s\" does not exist", revision, oldService.Name))
+			} else {
+				sendError(c, types.ErrInternal, err.Error())
+			}
+			return
+		}
+
+		// Check the restored definition against the current cluster. Its script is kept (not fetched again from Git)
+		if err := prepareService(newService, cfg); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
+		newService.Token = oldService.Token
+		// The protection of the service is not rolled back
+		newService.Protected = oldService.Protected
+
+		if !confirmProtectedChange(c, oldService) {
+			return
+		}
+		if err := updateService(cfg, back, newService, oldService); err != nil {
+			sendCodedError(c, err, types.ErrServiceUpdateFailed)
+			return
+		}
+
+		c.JSON(http.StatusOK, newService)
+	}
+}
+
+// setServiceRevision sets the number of the new revision of a service definition before creating or updating it
+func setServiceRevision(cfg *types.Config, back types.ServerlessBackend, service *types.Service) {
+	revision, err := utils.NextServiceRevision(back.GetKubeClientset(), cfg.ServicesNamespace, service.Name)
+	if err != nil {
+		log.Println(err.Error())
+	}
+	service.Revision = revision
+}
+
+// saveServiceRevision stores a created or updated service definition in its revision history
+func saveServiceRevision(cfg *types.Config, back types.ServerlessBackend, service *types.Service) {
+	if service.Revision == 0 {
+		return
+	}
+	if err := utils.SaveServiceRevision(cfg, back.GetKubeClientset(), service); err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+func TestMakeRevisionHandlers(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Revision: 1})
+	if err := utils.SaveServiceRevision(&testConfigValidRun, back.GetKubeClientset(), &types.Service{Name: "test", Revision: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/revisions", MakeRevisionListHandler(&testConfigValidRun, back))
+	r.POST("/system/services/:serviceName/rollback/:revision", MakeRollbackHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedErr  types.ErrorCode
+	}{
+		{"list", "GET", "/system/services/test/revisions", http.StatusOK, types.ErrorCode{}},
+		{"list missing service", "GET", "/system/services/missing/revisions", http.StatusNotFound, types.ErrServiceNotFound},
+		{"rollback invalid revision", "POST", "/system/services/test/rollback/first", http.StatusBadRequest, types.ErrBadRequest},
+		{"rollback missing revision", "POST", "/system/services/test/rollback/5", http.StatusNotFound, types.ErrRevisionNotFound},
+		{"rollback missing service", "POST", "/system/services/missing/rollback/1", http.StatusNotFound, types.ErrServiceNotFound},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Header().Get(errorCodeHeader) != s.expectedErr.Code {
+				t.Errorf("expecting error code %s, got %s", s.expectedErr.Code, w.Header().Get(errorCodeHeader))
+			}
+		})
+	}
+}
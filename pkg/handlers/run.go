@@ -17,15 +17,32 @@ limitations under the License.
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
 	"k8s.io/apimachinery/pkg/api/errors"
 )
 
+const (
+	// runFileField name of the form field with the file uploaded in the multipart sync invocations
+	runFileField = "file"
+	// outputLocationHeader header with the location ("provider:path") where the output has been copied in the
+	// sync invocations with an output_override
+	outputLocationHeader = "X-Oscar-Output-Location"
+)
+
 // MakeRunHandler makes a handler to manage sync invocations sending them to the gateway of the ServerlessBackend
 func MakeRunHandler(cfg *types.Config, back types.SyncBackend) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -33,29 +50,157 @@ func MakeRunHandler(cfg *types.Config, back types.SyncBackend) gin.HandlerFunc {
 		if err != nil {
 			// Check if error is caused because the service is not found
 			if errors.IsNotFound(err) || errors.IsGone(err) {
-				c.Status(http.StatusNotFound)
+				sendError(c, types.ErrServiceNotFound, "")
 			} else {
-				c.String(http.StatusInternalServerError, err.Error())
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		// Check auth token (or the signature of the presigned URL)
+		if isPresignedInvocation(c) {
+			if !checkPresignedInvocation(c, service) {
+				return
 			}
+		} else if !checkServiceToken(c, service) {
+			sendError(c, types.ErrUnauthorized, "")
 			return
 		}
 
-		// Check auth token
-		authHeader := c.GetHeader("Authorization")
-		splitToken := strings.Split(authHeader, "Bearer ")
-		if len(splitToken) != 2 {
-			c.Status(http.StatusUnauthorized)
+		invokeSync(c, cfg, back, service)
+	}
+}
+
+// invokeSync sends the sync invocation of an (authorized) service to the gateway of the ServerlessBackend
+func invokeSync(c *gin.Context, cfg *types.Config, back types.SyncBackend, service *types.Service) {
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		runMultipart(c, cfg, back, service)
+		return
+	}
+	if c.Query(types.OutputOverrideParam) != "" {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("The %s parameter of the synchronous invocations requires a multipart/form-data upload", types.OutputOverrideParam))
+		return
+	}
+
+	// Check the body against the input schema of the service
+	if service.Schema != nil && service.Schema.Input != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The request body cannot be read: %v", err))
 			return
 		}
-		reqToken := strings.TrimSpace(splitToken[1])
-		if reqToken != service.Token {
-			c.Status(http.StatusUnauthorized)
+		if issues := service.Schema.ValidateInput(body); len(issues) > 0 {
+			sendErrorDetails(c, types.ErrInvalidPayload, "", issues)
 			return
 		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: back.GetProxyDirector(service.Name),
+	}
+	if service.Streaming {
+		// Flush the output of streaming services to the client as it is received, also disabling the
+		// buffering of the response in the NGINX ingress
+		proxy.FlushInterval = -1
+		c.Header("X-Accel-Buffering", "no")
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// runMultipart invokes the service with the file uploaded in a multipart/form-data request, staging it in the
+// OSCAR's MinIO and passing its MinIO event. The output stored by the service is sent in the response (or the
+// response of the service if it doesn't store any output)
+func runMultipart(c *gin.Context, cfg *types.Config, back types.SyncBackend, service *types.Service) {
+	dest, _, ok := getOutputOverride(c, service)
+	if !ok {
+		return
+	}
+
+	file, header, err := c.Request.FormFile(runFileField)
+	if err != nil {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("The \"%s\" field is required: %v", runFileField, err))
+		return
+	}
+	defer file.Close()
 
-		proxy := &httputil.ReverseProxy{
-			Director: back.GetProxyDirector(service.Name),
+	start := time.Now()
+	object, err := utils.StageRunInput(cfg, service.Name, header.Filename, header.Size, header.Header.Get("Content-Type"), file)
+	if err != nil {
+		sendError(c, types.ErrUploadFailed, err.Error())
+		return
+	}
+	defer func() {
+		if err := utils.DeleteRunInput(cfg, object.Key); err != nil {
+			log.Println(err.Error())
+		}
+	}()
+
+	event, _ := json.Marshal(types.NewMinIOEvent(cfg.UploadStagingBucket, object, start))
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, "/", bytes.NewReader(event))
+	if err != nil {
+		sendError(c, types.ErrInternal, err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	back.GetProxyDirector(service.Name)(req)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		sendError(c, types.ErrInternal, fmt.Sprintf("Error invoking the service: %v", err))
+		return
+	}
+	defer res.Body.Close()
+
+	// Send the response of the service if the invocation failed
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		c.DataFromReader(res.StatusCode, res.ContentLength, res.Header.Get("Content-Type"), res.Body, nil)
+		return
+	}
+
+	// Copy the output to the caller's destination (if requested)
+	if dest != nil {
+		destKey, err := utils.CopyRunOutput(service, *dest, object.Key, start)
+		if err != nil {
+			sendError(c, types.ErrStorageConnectionFailed, err.Error())
+			return
 		}
-		proxy.ServeHTTP(c.Writer, c.Request)
+		if destKey != "" {
+			destBucket, _ := dest.SplitPath()
+			c.Header(outputLocationHeader, fmt.Sprintf("%s:%s/%s", dest.Provider, destBucket, destKey))
+		}
+	}
+
+	output, key, err := utils.GetRunOutput(service, object.Key, start)
+	if err != nil {
+		sendError(c, types.ErrStorageConnectionFailed, err.Error())
+		return
+	}
+	if output == nil {
+		c.DataFromReader(res.StatusCode, res.ContentLength, res.Header.Get("Content-Type"), res.Body, nil)
+		return
+	}
+	defer output.Body.Close()
+
+	contentType := aws.StringValue(output.ContentType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	size := int64(-1)
+	if output.ContentLength != nil {
+		size = *output.ContentLength
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, output.Body, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", strconv.Quote(utils.RunOutputName(key))),
+	})
+}
+
+// checkServiceToken checks if the request is authenticated with the service's token (or a named access token with
+// the "invoke" scope) as bearer token
+func checkServiceToken(c *gin.Context, service *types.Service) bool {
+	splitToken := strings.Split(c.GetHeader("Authorization"), "Bearer ")
+	if len(splitToken) != 2 {
+		return false
 	}
+	return service.CheckToken(strings.TrimSpace(splitToken[1]), types.ServiceTokenScopeInvoke)
 }
@@ -1,14 +1,26 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
 	"github.com/grycap/oscar/v2/pkg/types"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
 )
@@ -107,3 +119,180 @@ func TestMakeRunHandler(t *testing.T) {
 		})
 	}
 }
+
+// testSyncBackend memory backend sending the sync invocations to a test gateway
+type testSyncBackend struct {
+	*backends.MemoryBackend
+	gateway *httptest.Server
+}
+
+func (b *testSyncBackend) GetProxyDirector(serviceName string) func(req *http.Request) {
+	return func(req *http.Request) {
+		u, _ := url.Parse(b.gateway.URL)
+		req.URL.Scheme = u.Scheme
+		req.URL.Host = u.Host
+		req.URL.Path = fmt.Sprintf("/function/%s", serviceName)
+	}
+}
+
+func TestMakeRunHandlerMultipart(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("results")
+
+	// The "upper" service stores the uppercased input in its output (followed by the output of a concurrent
+	// invocation of a file with the same name), the "echo" service only responds and the "fail" service fails
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event types.MinIOEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil || len(event.Records) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		input, ok := s3Server.GetObject(event.GetBucket(), event.GetObjectKey())
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch path.Base(r.URL.Path) {
+		case "upper":
+			name := strings.TrimSuffix(path.Base(event.GetObjectKey()), ".txt")
+			s3Server.PutObject("results", "out/"+name+".out", chaos.S3Object{Data: bytes.ToUpper(input.Data), ContentType: "text/plain"})
+			for _, other := range []string{"upper.out", strings.Repeat("0", 32) + "-upper.out"} {
+				s3Server.PutObject("results", "out/"+other, chaos.S3Object{Data: []byte("OTHER"), ContentType: "text/plain"})
+			}
+			w.Write([]byte("stored"))
+		case "echo":
+			w.Write(input.Data)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("error"))
+		}
+	}))
+	defer gateway.Close()
+
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	cfg.UploadStagingBucket = "oscar-uploads"
+
+	back := &testSyncBackend{MemoryBackend: backends.MakeMemoryBackend(), gateway: gateway}
+	for _, name := range []string{"upper", "echo", "fail"} {
+		back.CreateService(types.Service{
+			Name:  name,
+			Token: "token",
+			Output: []types.StorageIOConfig{
+				{Provider: types.MinIOName + types.ProviderSeparator + types.DefaultProvider, Path: "results/out"},
+			},
+			StorageProviders: &types.StorageProviders{
+				MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: cfg.MinIOProvider},
+			},
+		})
+	}
+
+	r := gin.Default()
+	r.POST("/run/:serviceName", MakeRunHandler(&cfg, back))
+
+	scenarios := []struct {
+		name                string
+		service             string
+		field               string
+		expectedCode        int
+		expectedBody        string
+		expectedDisposition string
+	}{
+		{"output", "upper", "file", http.StatusOK, "HELLO", `attachment; filename="upper.out"`},
+		{"no output", "echo", "file", http.StatusOK, "hello", ""},
+		{"service failure", "fail", "file", http.StatusInternalServerError, "error", ""},
+		{"missing file", "upper", "other", http.StatusBadRequest, "", ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			fw, _ := mw.CreateFormFile(s.field, s.service+".txt")
+			fw.Write([]byte("hello"))
+			mw.Close()
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/run/"+s.service, body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer token")
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedBody != "" && w.Body.String() != s.expectedBody {
+				t.Errorf("expecting body \"%s\", got \"%s\"", s.expectedBody, w.Body.String())
+			}
+			if disposition := w.Header().Get("Content-Disposition"); disposition != s.expectedDisposition {
+				t.Errorf("expecting Content-Disposition \"%s\", got \"%s\"", s.expectedDisposition, disposition)
+			}
+		})
+	}
+
+	// The staged files are removed after the invocations
+	res, err := cfg.MinIOProvider.GetS3Client().ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(cfg.UploadStagingBucket)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Contents) != 0 {
+		t.Errorf("expecting the staged files removed, got %d objects", len(res.Contents))
+	}
+}
+
+func TestMakeRunHandlerStreaming(t *testing.T) {
+	// The gateway writes a chunk and waits for the client to receive it before finishing
+	received := make(chan struct{})
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte("second\n"))
+	}))
+	defer gateway.Close()
+
+	back := &testSyncBackend{MemoryBackend: backends.MakeMemoryBackend(), gateway: gateway}
+	back.CreateService(types.Service{Name: "stream", Token: "token", Streaming: true})
+
+	r := gin.Default()
+	r.POST("/run/:serviceName", MakeRunHandler(&testConfigValidRun, back))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/run/stream", strings.NewReader("input"))
+	req.Header.Set("Authorization", "Bearer token")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("X-Accel-Buffering") != "no" {
+		t.Errorf("expecting the X-Accel-Buffering header disabling the buffering, got \"%s\"", res.Header.Get("X-Accel-Buffering"))
+	}
+
+	reader := bufio.NewReader(res.Body)
+	done := make(chan string)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		done <- line
+	}()
+	select {
+	case line := <-done:
+		if line != "first\n" {
+			t.Fatalf("expecting the first chunk, got \"%s\"", line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the first chunk has not been flushed to the client")
+	}
+	close(received)
+
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "second\n" {
+		t.Errorf("expecting the second chunk, got \"%s\"", string(rest))
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// invocationIDHeader header of the queued invocations with their ID
+const invocationIDHeader = "X-Oscar-Invocation-Id"
+
+// functionStatusHeader header set by the OpenFaaS queue worker with the status code returned by the function
+const functionStatusHeader = "X-Function-Status"
+
+// maxInvocationErrorLength maximum length of the response body kept as error of a failed invocation
+const maxInvocationErrorLength = 1024
+
+// MakeRunAsyncHandler makes a handler to manage async invocations queuing them in the gateway of the
+// ServerlessBackend. The result is sent back to OSCAR (MakeAsyncResultHandler) to store it in the invocation
+// history and in the first output of the service
+func MakeRunAsyncHandler(cfg *types.Config, back types.AsyncBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if k8serrors.IsNotFound(err) || k8serrors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		if !checkServiceToken(c, service) {
+			sendError(c, types.ErrUnauthorized, "")
+			return
+		}
+
+		inv := utils.NewAsyncInvocation(service.Name)
+		callbackURL := fmt.Sprintf("http://%s.%s:%d/async-results/%s/%s", cfg.Name, cfg.Namespace, cfg.ServicePort, service.Name, inv.ID)
+		proxy := &httputil.ReverseProxy{
+			Director: back.GetAsyncProxyDirector(service.Name, callbackURL),
+			ModifyResponse: func(res *http.Response) error {
+				if res.StatusCode != http.StatusAccepted {
+					utils.FinishAsyncInvocation(inv.ID, res.StatusCode, "", fmt.Errorf("the invocation was not queued (status code %d)", res.StatusCode))
+					return nil
+				}
+				res.Header.Set("Location", fmt.Sprintf("/system/invocations/%s/%s", service.Name, inv.ID))
+				res.Header.Set(invocationIDHeader, inv.ID)
+				return nil
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				utils.FinishAsyncInvocation(inv.ID, 0, "", fmt.Errorf("the invocation was not queued: %v", err))
+				log.Printf("Error queuing the invocation of service \"%s\": %v\n", service.Name, err)
+				w.WriteHeader(http.StatusBadGateway)
+			},
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// MakeAsyncResultHandler makes a handler to receive the results of the async invocations from the queue worker of
+// the ServerlessBackend. The invocation ID (only known by the queue worker) authenticates the request
+func MakeAsyncResultHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inv, ok := utils.GetAsyncInvocation(c.Param("invocationID"))
+		if !ok || inv.Service != c.Param("serviceName") {
+			sendError(c, types.ErrInvocationNotFound, "")
+			return
+		}
+		if inv.Status != types.InvocationPending {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The result of the invocation \"%s\" has already been received", inv.ID))
+			return
+		}
+
+		statusCode := http.StatusOK
+		if status := c.GetHeader(functionStatusHeader); status != "" {
+			code, err := strconv.Atoi(status)
+			if err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid %s header \"%s\"", functionStatusHeader, status))
+				return
+			}
+			statusCode = code
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Error reading the result: %v", err))
+			return
+		}
+
+		if statusCode >= http.StatusBadRequest {
+			msg := strings.TrimSpace(string(body))
+			if len(msg) > maxInvocationErrorLength {
+				msg = msg[:maxInvocationErrorLength]
+			}
+			utils.FinishAsyncInvocation(inv.ID, statusCode, "", fmt.Errorf("the service returned the status code %d: %s", statusCode, msg))
+			c.Status(http.StatusOK)
+			return
+		}
+
+		service, err := back.ReadService(inv.Service)
+		// Keep the invocation pending on errors to accept the retries of the queue worker
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if k8serrors.IsNotFound(err) || k8serrors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+		output, err := storeAsyncResult(service, inv.ID, body, c.ContentType())
+		if err != nil {
+			utils.FinishAsyncInvocation(inv.ID, statusCode, "", fmt.Errorf("error storing the result: %v", err))
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+		utils.FinishAsyncInvocation(inv.ID, statusCode, output, nil)
+		c.Status(http.StatusOK)
+	}
+}
+
+// MakeInvocationListHandler makes a handler for listing the async invocations of a service, newest first
+func MakeInvocationListHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if k8serrors.IsNotFound(err) || k8serrors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, utils.ListAsyncInvocations(service.Name))
+	}
+}
+
+// MakeInvocationReadHandler makes a handler for reading an async invocation of a service
+func MakeInvocationReadHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inv, ok := utils.GetAsyncInvocation(c.Param("invocationID"))
+		if !ok || inv.Service != c.Param("serviceName") {
+			sendError(c, types.ErrInvocationNotFound, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, inv)
+	}
+}
+
+// storeAsyncResult uploads the result of an async invocation to the first output of the service (MinIO or S3),
+// returning its path. No result is stored if the service has not outputs
+func storeAsyncResult(service *types.Service, id string, result []byte, contentType string) (string, error) {
+	if len(service.Output) == 0 {
+		return "", nil
+	}
+	out := service.Output[0]
+	provName, provID := out.GetProvider()
+
+	var s3Client *s3.S3
+	switch {
+	case provName == types.MinIOName && service.StorageProviders != nil && service.StorageProviders.MinIO[provID] != nil:
+		s3Client = service.StorageProviders.MinIO[provID].GetS3Client()
+	case provName == types.S3Name && service.StorageProviders != nil && service.StorageProviders.S3[provID] != nil:
+		s3Client = service.StorageProviders.S3[provID].GetS3Client()
+	default:
+		return "", errors.New("the results can only be stored in MinIO or S3 outputs")
+	}
+
+	bucket, folder := out.SplitPath()
+	key := id
+	if folder != "" {
+		key = fmt.Sprintf("%s/%s", folder, id)
+	}
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(result),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if _, err := s3Client.PutObject(input); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s/%s", out.Provider, bucket, key), nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// testAsyncBackend memory backend queuing the async invocations in a test gateway
+type testAsyncBackend struct {
+	*backends.MemoryBackend
+	gateway *httptest.Server
+}
+
+func (b *testAsyncBackend) GetProxyDirector(serviceName string) func(req *http.Request) {
+	return b.GetAsyncProxyDirector(serviceName, "")
+}
+
+func (b *testAsyncBackend) GetAsyncProxyDirector(serviceName string, callbackURL string) func(req *http.Request) {
+	return func(req *http.Request) {
+		u, _ := url.Parse(b.gateway.URL)
+		req.URL.Scheme = u.Scheme
+		req.URL.Host = u.Host
+		req.URL.Path = fmt.Sprintf("/async-function/%s", serviceName)
+		req.Header.Set("X-Callback-Url", callbackURL)
+	}
+}
+
+func TestMakeRunAsyncHandler(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("results")
+
+	callbacks := []string{}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/async-function/test" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		callbacks = append(callbacks, r.Header.Get("X-Callback-Url"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer gateway.Close()
+
+	back := &testAsyncBackend{MemoryBackend: backends.MakeMemoryBackend(), gateway: gateway}
+	back.CreateService(types.Service{
+		Name:  "test",
+		Token: "token",
+		Output: []types.StorageIOConfig{
+			{Provider: types.MinIOName + types.ProviderSeparator + types.DefaultProvider, Path: "results/out"},
+		},
+		StorageProviders: &types.StorageProviders{
+			MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: testS3Provider(s3Server)},
+		},
+	})
+
+	r := gin.Default()
+	r.POST("/run/:serviceName", MakeRunHandler(&testConfigValidRun, back))
+	r.POST("/run-async/:serviceName", MakeRunAsyncHandler(&testConfigValidRun, back))
+	r.POST("/async-results/:serviceName/:invocationID", MakeAsyncResultHandler(back))
+	r.GET("/system/invocations/:serviceName", MakeInvocationListHandler(back))
+	r.GET("/system/invocations/:serviceName/:invocationID", MakeInvocationReadHandler())
+
+	// The proxied requests need a server (the reverse proxy requires a http.CloseNotifier)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	invoke := func(token string) *http.Response {
+		req, _ := http.NewRequest("POST", server.URL+"/run-async/test", strings.NewReader("input"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+		return res
+	}
+	sendResult := func(callbackURL string, status string, body string) int {
+		u, _ := url.Parse(callbackURL)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", u.Path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set(functionStatusHeader, status)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	readInvocation := func(id string) types.AsyncInvocation {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/system/invocations/test/"+id, nil)
+		r.ServeHTTP(w, req)
+		var inv types.AsyncInvocation
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &inv) != nil {
+			t.Fatalf("unexpected response reading the invocation: %d %s", w.Code, w.Body.String())
+		}
+		return inv
+	}
+
+	if res := invoke("invalid"); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expecting code %d, got %d", http.StatusUnauthorized, res.StatusCode)
+	}
+
+	// Successful invocation
+	res := invoke("token")
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("expecting code %d, got %d", http.StatusAccepted, res.StatusCode)
+	}
+	id := res.Header.Get(invocationIDHeader)
+	if res.Header.Get("Location") != "/system/invocations/test/"+id || len(callbacks) != 1 || !strings.HasSuffix(callbacks[0], "/async-results/test/"+id) {
+		t.Fatalf("unexpected location \"%s\" or callbacks %v", res.Header.Get("Location"), callbacks)
+	}
+	if inv := readInvocation(id); inv.Status != types.InvocationPending {
+		t.Errorf("expecting a pending invocation, got %+v", inv)
+	}
+	if code := sendResult(callbacks[0], "200", "result"); code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d", http.StatusOK, code)
+	}
+	inv := readInvocation(id)
+	if inv.Status != types.InvocationSucceeded || inv.StatusCode != http.StatusOK || inv.Output != "minio.default:results/out/"+id || inv.FinishedAt == nil {
+		t.Errorf("unexpected invocation %+v", inv)
+	}
+	if obj, ok := s3Server.GetObject("results", "out/"+id); !ok || string(obj.Data) != "result" {
+		t.Errorf("the result has not been stored")
+	}
+	if code := sendResult(callbacks[0], "200", "again"); code != http.StatusBadRequest {
+		t.Errorf("expecting code %d for a repeated result, got %d", http.StatusBadRequest, code)
+	}
+
+	// Failed invocation
+	id = invoke("token").Header.Get(invocationIDHeader)
+	if code := sendResult(callbacks[1], "500", "exit status 1"); code != http.StatusOK {
+		t.Fatalf("expecting code %d, got %d", http.StatusOK, code)
+	}
+	inv = readInvocation(id)
+	if inv.Status != types.InvocationFailed || inv.StatusCode != http.StatusInternalServerError || inv.Output != "" || !strings.Contains(inv.Error, "exit status 1") {
+		t.Errorf("unexpected invocation %+v", inv)
+	}
+
+	// Invocation history
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/system/invocations/test", nil)
+	r.ServeHTTP(w, req)
+	var list []types.AsyncInvocation
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 2 || list[0].ID != id {
+		t.Errorf("unexpected invocation history %s", w.Body.String())
+	}
+
+	if code := sendResult("http://oscar/async-results/test/unknown", "200", ""); code != http.StatusNotFound {
+		t.Errorf("expecting code %d for an unknown invocation, got %d", http.StatusNotFound, code)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestServiceSchema(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer gateway.Close()
+
+	schema := &types.ServiceSchema{
+		Input: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"text"},
+			"properties": map[string]interface{}{
+				"text": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+	back := &testSyncBackend{MemoryBackend: backends.MakeMemoryBackend(), gateway: gateway}
+	back.CreateService(types.Service{Name: "schema", Token: "token", Schema: schema})
+	back.CreateService(types.Service{Name: "noschema", Token: "token"})
+
+	r := gin.Default()
+	r.POST("/run/:serviceName", MakeRunHandler(&testConfigValidRun, back))
+	r.GET("/system/services/:serviceName/schema", MakeServiceSchemaHandler(back))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	run := func(service string, body string) (*http.Response, []byte) {
+		req, _ := http.NewRequest("POST", server.URL+"/run/"+service, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("Accept", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		resBody, _ := io.ReadAll(res.Body)
+		return res, resBody
+	}
+
+	// Valid bodies are sent to the service
+	res, body := run("schema", `{"text": "hello"}`)
+	if res.StatusCode != http.StatusOK || string(body) != `{"text": "hello"}` {
+		t.Errorf("expecting the body to be sent to the service, got %d \"%s\"", res.StatusCode, string(body))
+	}
+
+	// Invalid bodies are rejected with the issues found
+	for _, invalid := range []string{`{"text": 1}`, `{}`, `not json`} {
+		res, body = run("schema", invalid)
+		if res.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expecting status %d, got %d", invalid, http.StatusUnprocessableEntity, res.StatusCode)
+		}
+		var apiErr types.APIError
+		json.Unmarshal(body, &apiErr)
+		if apiErr.Code != types.ErrInvalidPayload.Code || len(apiErr.Details) == 0 {
+			t.Errorf("%s: expecting the %s error with details, got %s", invalid, types.ErrInvalidPayload.Code, string(body))
+		}
+	}
+
+	// The services without schema are not checked
+	if res, _ := run("noschema", "not json"); res.StatusCode != http.StatusOK {
+		t.Errorf("expecting status %d, got %d", http.StatusOK, res.StatusCode)
+	}
+
+	// Schema endpoint
+	res, err := http.Get(server.URL + "/system/services/schema/schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got types.ServiceSchema
+	json.NewDecoder(res.Body).Decode(&got)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || got.Input["type"] != "object" {
+		t.Errorf("expecting the input schema, got %d %v", res.StatusCode, got)
+	}
+	for service, status := range map[string]int{"noschema": http.StatusNotFound, "missing": http.StatusNotFound} {
+		res, err := http.Get(server.URL + "/system/services/" + service + "/schema")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != status {
+			t.Errorf("%s: expecting status %d, got %d", service, status, res.StatusCode)
+		}
+	}
+}
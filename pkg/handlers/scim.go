@@ -0,0 +1,271 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils/auth"
+)
+
+// scimFilterRegex filter of the SCIM user lists supported (the users with a userName)
+var scimFilterRegex = regexp.MustCompile(`^userName eq "([^"]*)"$`)
+
+// MakeSCIMAuthMiddleware makes a middleware authenticating the identity provider provisioning the users of the
+// "scim" store with the SCIM_TOKEN bearer token
+func MakeSCIMAuthMiddleware(cfg *types.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if cfg.SCIMToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.SCIMToken)) != 1 {
+			sendSCIMError(c, http.StatusUnauthorized, "", "The SCIM token is not valid")
+		}
+	}
+}
+
+// MakeSCIMUserListHandler makes a handler to list the users of the user store through SCIM (filtered by userName)
+func MakeSCIMUserListHandler(users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := users.List()
+		if err != nil {
+			sendSCIMStoreError(c, err)
+			return
+		}
+
+		if filter := c.Query("filter"); filter != "" {
+			match := scimFilterRegex.FindStringSubmatch(filter)
+			if match == nil {
+				sendSCIMError(c, http.StatusBadRequest, "invalidFilter", "Only the userName eq \"<username>\" filters are supported")
+				return
+			}
+			filtered := []types.User{}
+			for _, user := range list {
+				if user.Username == match[1] {
+					filtered = append(filtered, user)
+				}
+			}
+			list = filtered
+		}
+
+		startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+		if err != nil || startIndex < 1 {
+			startIndex = 1
+		}
+		count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(len(list))))
+		if err != nil || count < 0 {
+			count = len(list)
+		}
+		res := types.SCIMListResponse{
+			Schemas:      []string{types.SCIMListResponseSchema},
+			TotalResults: len(list),
+			StartIndex:   startIndex,
+			Resources:    []types.SCIMUser{},
+		}
+		for i := startIndex - 1; i < len(list) && len(res.Resources) < count; i++ {
+			res.Resources = append(res.Resources, types.NewSCIMUser(list[i]))
+		}
+		res.ItemsPerPage = len(res.Resources)
+		sendSCIM(c, http.StatusOK, res)
+	}
+}
+
+// MakeSCIMUserReadHandler makes a handler to read a user of the user store through SCIM
+func MakeSCIMUserReadHandler(users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := users.Get(c.Param("id"))
+		if err != nil {
+			sendSCIMStoreError(c, err)
+			return
+		}
+		sendSCIM(c, http.StatusOK, types.NewSCIMUser(*user))
+	}
+}
+
+// MakeSCIMUserCreateHandler makes a handler to provision a user of the user store through SCIM
+func MakeSCIMUserCreateHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scimUser := types.SCIMUser{}
+		if err := c.ShouldBindJSON(&scimUser); err != nil {
+			sendSCIMError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+		req := scimUser.UserRequest()
+		if err := validateUserRequest(cfg, &req, false); err != nil {
+			sendSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+
+		user, err := users.Create(req)
+		if err != nil {
+			sendSCIMStoreError(c, err)
+			return
+		}
+		sendSCIM(c, http.StatusCreated, types.NewSCIMUser(*user))
+	}
+}
+
+// MakeSCIMUserReplaceHandler makes a handler to replace a user of the user store through SCIM (its username can't
+// be changed)
+func MakeSCIMUserReplaceHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scimUser := types.SCIMUser{}
+		if err := c.ShouldBindJSON(&scimUser); err != nil {
+			sendSCIMError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+		if scimUser.UserName != "" && scimUser.UserName != c.Param("id") {
+			sendSCIMError(c, http.StatusBadRequest, "mutability", "The userName can't be changed")
+			return
+		}
+		scimUser.UserName = c.Param("id")
+		updateSCIMUser(c, cfg, users, scimUser.UserRequest())
+	}
+}
+
+// MakeSCIMUserPatchHandler makes a handler to patch the status, the password or the role of a user of the user
+// store through SCIM. The rest of the attributes are ignored
+func MakeSCIMUserPatchHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		patch := types.SCIMPatchRequest{}
+		if err := c.ShouldBindJSON(&patch); err != nil {
+			sendSCIMError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+			return
+		}
+
+		req := types.UserRequest{Username: c.Param("id")}
+		for _, op := range patch.Operations {
+			if !strings.EqualFold(op.Op, "add") && !strings.EqualFold(op.Op, "replace") {
+				continue
+			}
+			// The operations without path set the attributes of the value
+			values := map[string]interface{}{op.Path: op.Value}
+			if op.Path == "" {
+				values, _ = op.Value.(map[string]interface{})
+			}
+			if err := applySCIMPatch(&req, values); err != nil {
+				sendSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+				return
+			}
+		}
+		updateSCIMUser(c, cfg, users, req)
+	}
+}
+
+// MakeSCIMUserDeleteHandler makes a handler to deprovision a user of the user store through SCIM
+func MakeSCIMUserDeleteHandler(users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := users.Delete(c.Param("id")); err != nil {
+			sendSCIMStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// applySCIMPatch applies the values of the active, password and roles attributes of a SCIM patch to the request
+func applySCIMPatch(req *types.UserRequest, values map[string]interface{}) error {
+	for attribute, value := range values {
+		switch attribute {
+		case "active":
+			// Some identity providers send the booleans as strings
+			active, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(toString(value))))
+			if err != nil {
+				return errors.New("the active attribute must be a boolean")
+			}
+			disabled := !active
+			req.Disabled = &disabled
+		case "password":
+			password, ok := value.(string)
+			if !ok {
+				return errors.New("the password attribute must be a string")
+			}
+			req.Password = password
+		case "roles":
+			data, _ := json.Marshal(value)
+			roles := []types.SCIMValue{}
+			if err := json.Unmarshal(data, &roles); err != nil {
+				return errors.New("the roles attribute must be a list of values")
+			}
+			req.Role = types.SCIMUser{Roles: roles}.UserRequest().Role
+		}
+	}
+	return nil
+}
+
+// toString returns the string of a scalar value
+func toString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// updateSCIMUser updates a user of the user store with the request of a SCIM replacement or patch
+func updateSCIMUser(c *gin.Context, cfg *types.Config, users *auth.UserDirectory, req types.UserRequest) {
+	if err := validateUserRequest(cfg, &req, false); err != nil {
+		sendSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	user, err := users.Update(req)
+	if err != nil {
+		sendSCIMStoreError(c, err)
+		return
+	}
+	sendSCIM(c, http.StatusOK, types.NewSCIMUser(*user))
+}
+
+// sendSCIM sends a SCIM response
+func sendSCIM(c *gin.Context, status int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		sendSCIMError(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	c.Data(status, types.SCIMContentType, data)
+}
+
+// sendSCIMError sends a SCIM error, aborting the request
+func sendSCIMError(c *gin.Context, status int, scimType string, detail string) {
+	data, _ := json.Marshal(types.SCIMError{
+		Schemas:  []string{types.SCIMErrorSchema},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+	c.Data(status, types.SCIMContentType, data)
+	c.Abort()
+}
+
+// sendSCIMStoreError sends the SCIM error of an operation of the user store
+func sendSCIMStoreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		sendSCIMError(c, http.StatusNotFound, "", err.Error())
+	case errors.Is(err, auth.ErrUserAlreadyExists):
+		sendSCIMError(c, http.StatusConflict, "uniqueness", err.Error())
+	default:
+		sendSCIMError(c, http.StatusInternalServerError, "", err.Error())
+	}
+}
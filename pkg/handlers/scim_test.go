@@ -0,0 +1,95 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils/auth"
+)
+
+func TestSCIMUsers(t *testing.T) {
+	cfg := &types.Config{Username: "oscar", UserStore: types.UserStoreSCIM, UserStoreFile: filepath.Join(t.TempDir(), "users.json"), SCIMToken: "scim-token"}
+	users, err := auth.NewUserDirectory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	scim := r.Group("/scim/v2", MakeSCIMAuthMiddleware(cfg))
+	scim.GET("/Users", MakeSCIMUserListHandler(users))
+	scim.POST("/Users", MakeSCIMUserCreateHandler(cfg, users))
+	scim.GET("/Users/:id", MakeSCIMUserReadHandler(users))
+	scim.PUT("/Users/:id", MakeSCIMUserReplaceHandler(cfg, users))
+	scim.PATCH("/Users/:id", MakeSCIMUserPatchHandler(cfg, users))
+	scim.DELETE("/Users/:id", MakeSCIMUserDeleteHandler(users))
+
+	request := func(method string, path string, body string, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("GET", "/scim/v2/Users", "", "wrong-token"); w.Code != http.StatusUnauthorized || w.Header().Get("Content-Type") != types.SCIMContentType {
+		t.Errorf("expecting a SCIM error %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"provision", "POST", "/scim/v2/Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "alice@example.org", "password": "password1", "active": true, "roles": [{"value": "operator", "primary": true}], "name": {"givenName": "Alice"}}`, http.StatusCreated},
+		{"duplicated user", "POST", "/scim/v2/Users", `{"userName": "alice@example.org"}`, http.StatusConflict},
+		{"provision without password", "POST", "/scim/v2/Users", `{"userName": "bob@example.org"}`, http.StatusCreated},
+		{"unsupported filter", "GET", "/scim/v2/Users?filter=emails+co+%22example%22", "", http.StatusBadRequest},
+		{"rename", "PUT", "/scim/v2/Users/bob@example.org", `{"userName": "robert@example.org"}`, http.StatusBadRequest},
+		{"deactivate", "PATCH", "/scim/v2/Users/bob@example.org", `{"Operations": [{"op": "Replace", "path": "active", "value": "False"}]}`, http.StatusOK},
+		{"patch without path", "PATCH", "/scim/v2/Users/alice@example.org", `{"Operations": [{"op": "replace", "value": {"roles": [{"value": "viewer"}], "displayName": "Alice"}}]}`, http.StatusOK},
+		{"patch not found", "PATCH", "/scim/v2/Users/carol@example.org", `{"Operations": [{"op": "replace", "path": "active", "value": false}]}`, http.StatusNotFound},
+		{"deprovision", "DELETE", "/scim/v2/Users/bob@example.org", "", http.StatusNoContent},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if w := request(s.method, s.path, s.body, "scim-token"); w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := request("GET", "/scim/v2/Users?filter=userName+eq+%22alice@example.org%22", "", "scim-token")
+	res := types.SCIMListResponse{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.TotalResults != 1 || len(res.Resources) != 1 || res.Resources[0].ID != "alice@example.org" || res.Resources[0].Password != "" ||
+		len(res.Resources[0].Roles) != 1 || res.Resources[0].Roles[0].Value != types.RoleViewer {
+		t.Errorf("unexpected list response %+v", res)
+	}
+	if user, ok := users.Authenticate("alice@example.org", "password1"); !ok || user.Role != types.RoleViewer {
+		t.Errorf("expecting the provisioned user to be authenticated, got %+v", user)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MakeServiceSecretsUpdateHandler makes a handler to replace the secrets of a service, set as environment variables
+// of its jobs and never returned in the service definition
+func MakeServiceSecretsUpdateHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secrets := types.ServiceSecrets{}
+		if err := c.ShouldBindJSON(&secrets); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The secrets specification is not valid: %v", err))
+			return
+		}
+		if err := secrets.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+
+		if err := utils.SetServiceSecrets(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name, secrets.Secrets); err != nil {
+			sendError(c, types.ErrServiceUpdateFailed, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, types.ServiceSecrets{Names: secrets.GetNames()})
+	}
+}
+
+// MakeServiceSecretsListHandler makes a handler to list the names of the secrets of a service
+func MakeServiceSecretsListHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+
+		names, err := utils.GetServiceSecretNames(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name)
+		if err != nil {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, types.ServiceSecrets{Names: names})
+	}
+}
+
+// readPathService returns the service of the request path (sending the error response if it can't be read)
+func readPathService(c *gin.Context, back types.ServerlessBackend) *types.Service {
+	service, err := back.ReadService(c.Param("serviceName"))
+	if err != nil {
+		// Check if error is caused because the service is not found
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			sendError(c, types.ErrServiceNotFound, "")
+		} else {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+		}
+		return nil
+	}
+	return service
+}
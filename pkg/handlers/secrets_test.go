@@ -0,0 +1,101 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceSecretsHandlers(t *testing.T) {
+	cfg := testConfigValidRun
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test"})
+	kubeClientset := back.GetKubeClientset()
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/secrets", MakeServiceSecretsListHandler(&cfg, back))
+	r.PUT("/system/services/:serviceName/secrets", MakeServiceSecretsUpdateHandler(&cfg, back))
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+	expectNames := func(w *httptest.ResponseRecorder, expected []string) {
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		secrets := types.ServiceSecrets{}
+		if err := json.Unmarshal(w.Body.Bytes(), &secrets); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(secrets.Secrets) != 0 || !reflect.DeepEqual(secrets.Names, expected) {
+			t.Errorf("expected names %v, got %s", expected, w.Body.String())
+		}
+	}
+
+	scenarios := []struct {
+		name         string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"invalid body", "/system/services/test/secrets", `{"secrets": []}`, http.StatusBadRequest},
+		{"invalid name", "/system/services/test/secrets", `{"secrets": {"MY-TOKEN": "x"}}`, http.StatusBadRequest},
+		{"service not found", "/system/services/other/secrets", `{"secrets": {"TOKEN": "x"}}`, http.StatusNotFound},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if w := request("PUT", s.path, s.body); w.Code != s.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	expectNames(request("GET", "/system/services/test/secrets", ""), []string{})
+
+	// The secrets are replaced
+	expectNames(request("PUT", "/system/services/test/secrets", `{"secrets": {"TOKEN": "old", "OTHER": "x"}}`), []string{"OTHER", "TOKEN"})
+	expectNames(request("PUT", "/system/services/test/secrets", `{"secrets": {"TOKEN": "secret"}}`), []string{"TOKEN"})
+	expectNames(request("GET", "/system/services/test/secrets", ""), []string{"TOKEN"})
+
+	secret, err := kubeClientset.CoreV1().Secrets(cfg.ServicesNamespace).Get(context.TODO(), types.ServiceSecretsName("test"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(secret.Data["TOKEN"]) != "secret" || len(secret.Data) != 1 || secret.Labels[types.ServiceLabel] != "test" {
+		t.Errorf("unexpected secret: %+v", secret)
+	}
+
+	// The secret is removed without secrets
+	expectNames(request("PUT", "/system/services/test/secrets", `{}`), []string{})
+	if _, err := kubeClientset.CoreV1().Secrets(cfg.ServicesNamespace).Get(context.TODO(), types.ServiceSecretsName("test"), metav1.GetOptions{}); err == nil {
+		t.Error("expected the secret to be removed")
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// storageCheckTimeout timeout of the requests checking the reachability of the WebDAV storage providers
+const storageCheckTimeout = 10 * time.Second
+
+// MakeServiceHealthHandler makes a handler returning the health summary of a service: registration of its webhook,
+// bucket notifications, readiness of its deployments, reachability of its storage providers and outcome of its last
+// jobs (the "last" querystring, 10 by default), so a broken service can be debugged in one call
+func MakeServiceHealthHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		last, err := strconv.Atoi(c.DefaultQuery("last", strconv.Itoa(defaultLastExecutions)))
+		if err != nil || last < 0 {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid last value \"%s\", it must be a non-negative integer", c.Query("last")))
+			return
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		status, err := getServiceStatus(cfg, back.GetKubeClientset(), service)
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error getting the service status: %v", err))
+			return
+		}
+		lastJobs, err := getLastJobs(back.GetKubeClientset(), cfg.GetServiceNamespace(service), service.Name, last)
+		if err != nil {
+			sendError(c, types.ErrInternal, fmt.Sprintf("Error getting the service jobs: %v", err))
+			return
+		}
+
+		health := &types.ServiceHealth{
+			Service:          service.Name,
+			ServiceStatus:    *status,
+			StorageProviders: getStorageProvidersStatus(service),
+			LastJobs:         lastJobs,
+			JobOutcomes:      map[string]int{},
+		}
+		for _, job := range lastJobs {
+			health.JobOutcomes[job.Status]++
+		}
+		health.Issues = getHealthIssues(health)
+		health.Healthy = len(health.Issues) == 0
+
+		c.JSON(http.StatusOK, health)
+	}
+}
+
+// getHealthIssues returns the problems found in the health summary of a service
+func getHealthIssues(health *types.ServiceHealth) []string {
+	issues := []string{}
+	if health.Webhook != nil && !health.Webhook.Registered {
+		issues = append(issues, health.Webhook.Message)
+	}
+	for _, trigger := range health.Triggers {
+		if trigger.Enabled && !trigger.Healthy {
+			issues = append(issues, fmt.Sprintf("the trigger of input \"%s\" is not healthy: %s", trigger.Path, trigger.Message))
+		}
+	}
+	for _, deployment := range health.Deployments {
+		if !deployment.Ready {
+			issues = append(issues, fmt.Sprintf("the deployment \"%s\" is not ready (%d/%d replicas)", deployment.Name, deployment.ReadyReplicas, deployment.Replicas))
+		}
+	}
+	for _, provider := range health.StorageProviders {
+		if !provider.Reachable {
+			issues = append(issues, fmt.Sprintf("the storage provider \"%s\" is not reachable: %s", provider.Provider, provider.Message))
+		}
+	}
+	// The last finished job (newest first) failed
+	for _, job := range health.LastJobs {
+		if job.Status == string(v1.PodSucceeded) {
+			break
+		}
+		if job.Status == string(v1.PodFailed) {
+			issues = append(issues, fmt.Sprintf("the last finished job \"%s\" failed", job.Name))
+			break
+		}
+	}
+	return issues
+}
+
+// getStorageProvidersStatus checks the reachability of the storage providers of the service's inputs and outputs,
+// accessing their paths (the buckets in MinIO and S3)
+func getStorageProvidersStatus(service *types.Service) []types.StorageProviderStatus {
+	statuses := []types.StorageProviderStatus{}
+	index := map[string]int{}
+
+	paths := append(append([]types.StorageIOConfig{}, service.Input...), service.Output...)
+	for _, storageIO := range paths {
+		provName, provID := storageIO.GetProvider()
+		provider := provName + types.ProviderSeparator + provID
+		i, ok := index[provider]
+		if !ok {
+			i = len(statuses)
+			index[provider] = i
+			statuses = append(statuses, types.StorageProviderStatus{Provider: provider, Reachable: true})
+		}
+		// Only the first error of each provider is reported
+		if !statuses[i].Reachable {
+			continue
+		}
+		if err := checkStoragePath(service.StorageProviders, provName, provID, storageIO.Path); err != nil {
+			statuses[i].Reachable = false
+			statuses[i].Message = err.Error()
+		}
+	}
+
+	return statuses
+}
+
+// checkStoragePath checks that a path of a storage provider can be accessed
+func checkStoragePath(providers *types.StorageProviders, provName string, provID string, path string) error {
+	if providers == nil {
+		providers = &types.StorageProviders{}
+	}
+	bucket, _ := types.StorageIOConfig{Path: path}.SplitPath()
+
+	var s3Client *s3.S3
+	switch provName {
+	case types.MinIOName:
+		if providers.MinIO[provID] == nil {
+			return fmt.Errorf("the storage provider is not defined")
+		}
+		s3Client = providers.MinIO[provID].GetS3Client()
+	case types.S3Name:
+		if providers.S3[provID] == nil {
+			return fmt.Errorf("the storage provider is not defined")
+		}
+		s3Client = providers.S3[provID].GetS3Client()
+	case types.OnedataName:
+		if providers.Onedata[provID] == nil {
+			return fmt.Errorf("the storage provider is not defined")
+		}
+		onedata := providers.Onedata[provID]
+		if _, err := onedata.GetCDMIClient().ReadContainer(fmt.Sprintf("%s/%s", onedata.Space, strings.Trim(path, " /"))); err != nil {
+			return fmt.Errorf("error reading the path \"%s\": %v", path, err)
+		}
+		return nil
+	case types.WebDavName:
+		if providers.WebDav[provID] == nil {
+			return fmt.Errorf("the storage provider is not defined")
+		}
+		return checkWebDavPath(providers.WebDav[provID], path)
+	default:
+		return fmt.Errorf("the storage provider type \"%s\" is not supported", provName)
+	}
+
+	if _, err := s3Client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return fmt.Errorf("error accessing the bucket \"%s\": %v", bucket, err)
+	}
+	return nil
+}
+
+// checkWebDavPath checks that a path of a WebDAV storage provider can be accessed
+func checkWebDavPath(webDav *types.WebDavProvider, path string) error {
+	endpoint := strings.TrimRight(webDav.Hostname, "/")
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "https://" + endpoint
+	}
+	req, err := http.NewRequest("PROPFIND", endpoint+"/"+strings.Trim(path, " /"), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Depth", "0")
+	req.SetBasicAuth(webDav.Login, webDav.Password)
+
+	res, err := (&http.Client{Timeout: storageCheckTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("error reading the path \"%s\": %v", path, err)
+	}
+	res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("error reading the path \"%s\": %s", path, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMakeServiceHealthHandler(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	s3Server.CreateBucket("input")
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	providers := &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: cfg.MinIOProvider}}
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{
+		Name:             "test",
+		Input:            []types.StorageIOConfig{{Provider: "minio.default", Path: "input/in"}},
+		StorageProviders: providers,
+	})
+	back.CreateService(types.Service{
+		Name:             "missing",
+		Output:           []types.StorageIOConfig{{Provider: "minio.default", Path: "missing/out"}},
+		StorageProviders: providers,
+	})
+
+	// Two jobs, the newest one failed
+	kubeClientset := back.GetKubeClientset()
+	for i, phase := range []v1.PodPhase{v1.PodSucceeded, v1.PodFailed} {
+		name := []string{"job-1", "job-2"}[i]
+		labels := map[string]string{types.ServiceLabel: "test"}
+		start := metav1.NewTime(time.Now().Add(time.Duration(i) * time.Minute))
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: cfg.ServicesNamespace, Labels: labels},
+			Status:     batchv1.JobStatus{StartTime: &start},
+		}
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-pod", Namespace: cfg.ServicesNamespace, Labels: map[string]string{types.ServiceLabel: "test", "job-name": name}},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+		kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Create(context.TODO(), job, metav1.CreateOptions{})
+		kubeClientset.CoreV1().Pods(cfg.ServicesNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	}
+
+	r := gin.Default()
+	r.GET("/system/services/:serviceName/status", MakeServiceHealthHandler(&cfg, back))
+
+	scenarios := []struct {
+		name              string
+		path              string
+		expectedCode      int
+		expectedReachable bool
+		expectedJobs      int
+	}{
+		{"not found", "/system/services/other/status", http.StatusNotFound, false, 0},
+		{"invalid last", "/system/services/test/status?last=x", http.StatusBadRequest, false, 0},
+		{"reachable", "/system/services/test/status", http.StatusOK, true, 2},
+		{"last", "/system/services/test/status?last=1", http.StatusOK, true, 1},
+		{"unreachable", "/system/services/missing/status", http.StatusOK, false, 0},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", s.path, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			health := types.ServiceHealth{}
+			if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The webhook can't be checked without the MinIO admin API
+			if health.Healthy || len(health.Issues) == 0 {
+				t.Errorf("expected an unhealthy service, got %+v", health)
+			}
+			if len(health.StorageProviders) != 1 || health.StorageProviders[0].Provider != "minio.default" {
+				t.Fatalf("unexpected storage providers: %+v", health.StorageProviders)
+			}
+			if health.StorageProviders[0].Reachable != s.expectedReachable {
+				t.Errorf("expected reachable %v, got %+v", s.expectedReachable, health.StorageProviders[0])
+			}
+			if len(health.LastJobs) != s.expectedJobs {
+				t.Errorf("expected %d jobs, got %d", s.expectedJobs, len(health.LastJobs))
+			}
+			if s.expectedJobs > 0 && health.JobOutcomes[string(v1.PodFailed)] != 1 {
+				t.Errorf("expected 1 failed job, got %v", health.JobOutcomes)
+			}
+		})
+	}
+}
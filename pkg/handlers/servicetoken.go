@@ -0,0 +1,184 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// serviceTokenAuthKey key set in the context of the requests authorised with a named access token
+const serviceTokenAuthKey = "oscarServiceToken"
+
+// MakeServiceTokenListHandler makes a handler to list the named access tokens of a service (without their values)
+func MakeServiceTokenListHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+
+		tokens := []types.ServiceAccessToken{}
+		for _, t := range service.AccessTokens {
+			t.Hash = ""
+			tokens = append(tokens, t)
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// MakeServiceTokenCreateHandler makes a handler to create a named access token of a service. The value of the token
+// is only returned in the response
+func MakeServiceTokenCreateHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := types.ServiceAccessTokenRequest{}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The token specification is not valid: %v", err))
+			return
+		}
+		if err := req.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+		if service.GetAccessToken(req.Name) >= 0 {
+			sendError(c, types.ErrServiceTokenAlreadyExists, fmt.Sprintf("The token \"%s\" already exists in the service \"%s\"", req.Name, service.Name))
+			return
+		}
+
+		value := types.ServiceTokenPrefix + utils.GenerateToken()
+		token := types.ServiceAccessToken{
+			Name:      req.Name,
+			Scopes:    req.Scopes,
+			Hash:      types.HashServiceToken(value),
+			CreatedBy: c.GetString(gin.AuthUserKey),
+			CreatedAt: time.Now().UTC(),
+		}
+		service.AccessTokens = append(service.AccessTokens, token)
+		if err := back.UpdateService(*service); err != nil {
+			sendError(c, types.ErrServiceUpdateFailed, fmt.Sprintf("Error updating the service: %v", err))
+			return
+		}
+
+		token.Hash = ""
+		token.Token = value
+		c.JSON(http.StatusCreated, token)
+	}
+}
+
+// MakeServiceTokenRotateHandler makes a handler to replace the value of a named access token of a service, keeping
+// its scopes. The previous value is no longer accepted
+func MakeServiceTokenRotateHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+		index := service.GetAccessToken(c.Param("tokenName"))
+		if index < 0 {
+			sendError(c, types.ErrServiceTokenNotFound, fmt.Sprintf("The token \"%s\" does not exist in the service \"%s\"", c.Param("tokenName"), service.Name))
+			return
+		}
+
+		value := types.ServiceTokenPrefix + utils.GenerateToken()
+		rotatedAt := time.Now().UTC()
+		service.AccessTokens[index].Hash = types.HashServiceToken(value)
+		service.AccessTokens[index].RotatedAt = &rotatedAt
+		if err := back.UpdateService(*service); err != nil {
+			sendError(c, types.ErrServiceUpdateFailed, fmt.Sprintf("Error updating the service: %v", err))
+			return
+		}
+
+		token := service.AccessTokens[index]
+		token.Hash = ""
+		token.Token = value
+		c.JSON(http.StatusOK, token)
+	}
+}
+
+// MakeServiceTokenRevokeHandler makes a handler to revoke (remove) a named access token of a service
+func MakeServiceTokenRevokeHandler(back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := readPathService(c, back)
+		if service == nil {
+			return
+		}
+		index := service.GetAccessToken(c.Param("tokenName"))
+		if index < 0 {
+			sendError(c, types.ErrServiceTokenNotFound, fmt.Sprintf("The token \"%s\" does not exist in the service \"%s\"", c.Param("tokenName"), service.Name))
+			return
+		}
+
+		service.AccessTokens = append(service.AccessTokens[:index], service.AccessTokens[index+1:]...)
+		if err := back.UpdateService(*service); err != nil {
+			sendError(c, types.ErrServiceUpdateFailed, fmt.Sprintf("Error updating the service: %v", err))
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// MakeServiceTokenAuthMiddleware makes a middleware authorising the requests of the /system paths of a service
+// with its named access tokens, acting as the owner of the service. The rest of the requests are authorised by
+// authMiddleware (basic auth or OIDC)
+func MakeServiceTokenAuthMiddleware(back types.ServerlessBackend, authMiddleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		scope := getServiceTokenScope(c)
+		if !strings.HasPrefix(token, types.ServiceTokenPrefix) || scope == "" {
+			authMiddleware(c)
+			return
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil || !service.CheckToken(token, scope) {
+			sendError(c, types.ErrUnauthorized, "")
+			return
+		}
+		c.Set(gin.AuthUserKey, service.Owner)
+		c.Set(serviceTokenAuthKey, true)
+	}
+}
+
+// getServiceTokenScope returns the scope of the access tokens required by the route of the request, empty if the
+// route can't be accessed with them (including the management of the tokens)
+func getServiceTokenScope(c *gin.Context) string {
+	route := c.FullPath()
+	switch {
+	case strings.HasPrefix(route, "/system/services/:serviceName/tokens"):
+		return ""
+	case route == "/system/services/:serviceName" || strings.HasPrefix(route, "/system/services/:serviceName/"):
+		return types.ServiceTokenScopeManage
+	case strings.HasPrefix(route, "/system/logs/:serviceName"), strings.HasPrefix(route, "/system/invocations/:serviceName"):
+		if c.Request.Method == http.MethodGet {
+			return types.ServiceTokenScopeLogs
+		}
+		return types.ServiceTokenScopeManage
+	}
+	return ""
+}
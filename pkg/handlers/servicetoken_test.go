@@ -0,0 +1,145 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestServiceTokens(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", Image: "busybox", Token: "token", Owner: "owner"})
+
+	r := gin.Default()
+	system := r.Group("/system", MakeServiceTokenAuthMiddleware(back, gin.BasicAuth(gin.Accounts{"user": "pass"})))
+	system.GET("/services/:serviceName/tokens", MakeServiceTokenListHandler(back))
+	system.POST("/services/:serviceName/tokens", MakeServiceTokenCreateHandler(back))
+	system.POST("/services/:serviceName/tokens/:tokenName/rotate", MakeServiceTokenRotateHandler(back))
+	system.DELETE("/services/:serviceName/tokens/:tokenName", MakeServiceTokenRevokeHandler(back))
+	system.GET("/logs/:serviceName", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(gin.AuthUserKey))
+	})
+	system.GET("/services/:serviceName", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(gin.AuthUserKey))
+	})
+
+	request := func(method string, path string, body string, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else {
+			req.SetBasicAuth("user", "pass")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	createToken := func(body string) types.ServiceAccessToken {
+		w := request("POST", "/system/services/test/tokens", body, "")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expecting code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		token := types.ServiceAccessToken{}
+		json.Unmarshal(w.Body.Bytes(), &token)
+		if !strings.HasPrefix(token.Token, types.ServiceTokenPrefix) || token.Hash != "" || token.CreatedBy != "user" {
+			t.Fatalf("unexpected created token: %+v", token)
+		}
+		return token
+	}
+
+	logs := createToken(`{"name": "ci-logs", "scopes": ["logs"]}`)
+	invoke := createToken(`{"name": "webhook", "scopes": ["invoke"]}`)
+
+	if w := request("POST", "/system/services/test/tokens", `{"name": "webhook", "scopes": ["invoke"]}`, ""); w.Code != http.StatusConflict {
+		t.Errorf("expecting code %d creating a duplicated token, got %d", http.StatusConflict, w.Code)
+	}
+	if w := request("POST", "/system/services/test/tokens", `{"name": "other", "scopes": ["admin"]}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expecting code %d creating a token with an invalid scope, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// The tokens are listed without their values nor hashes
+	w := request("GET", "/system/services/test/tokens", "", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), logs.Token) || strings.Contains(w.Body.String(), `"hash"`) {
+		t.Errorf("unexpected list of tokens: %s", w.Body.String())
+	}
+
+	// Scopes of the tokens
+	service, _ := back.ReadService("test")
+	if !service.CheckToken(invoke.Token, types.ServiceTokenScopeInvoke) || service.CheckToken(logs.Token, types.ServiceTokenScopeInvoke) {
+		t.Error("expecting only the \"webhook\" token to invoke the service")
+	}
+	if w := request("GET", "/system/logs/test", "", logs.Token); w.Code != http.StatusOK || w.Body.String() != "owner" {
+		t.Errorf("expecting the logs to be read as the owner, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, s := range []struct{ path, token string }{
+		{"/system/logs/test", invoke.Token},
+		{"/system/services/test", logs.Token},
+		{"/system/services/test/tokens", logs.Token},
+	} {
+		if w := request("GET", s.path, "", s.token); w.Code != http.StatusUnauthorized {
+			t.Errorf("expecting code %d reading %s, got %d", http.StatusUnauthorized, s.path, w.Code)
+		}
+	}
+
+	// Rotation
+	w = request("POST", "/system/services/test/tokens/ci-logs/rotate", "", "")
+	rotated := types.ServiceAccessToken{}
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	if w.Code != http.StatusOK || rotated.Token == logs.Token || rotated.RotatedAt == nil {
+		t.Fatalf("unexpected rotated token (%d): %s", w.Code, w.Body.String())
+	}
+	if w := request("GET", "/system/logs/test", "", logs.Token); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting the previous value to be rejected, got %d", w.Code)
+	}
+	if w := request("GET", "/system/logs/test", "", rotated.Token); w.Code != http.StatusOK {
+		t.Errorf("expecting the rotated value to be accepted, got %d", w.Code)
+	}
+
+	// Revocation
+	if w := request("DELETE", "/system/services/test/tokens/ci-logs", "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expecting code %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w := request("GET", "/system/logs/test", "", rotated.Token); w.Code != http.StatusUnauthorized {
+		t.Errorf("expecting the revoked token to be rejected, got %d", w.Code)
+	}
+	if w := request("DELETE", "/system/services/test/tokens/ci-logs", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expecting code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestServiceTokenIsNotAdmin(t *testing.T) {
+	cfg := &types.Config{Username: "oscar"}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(gin.AuthUserKey, "oscar")
+	if !isAdmin(c, cfg) {
+		t.Fatal("expecting the basic auth user to be the admin")
+	}
+
+	c.Set(serviceTokenAuthKey, true)
+	if isAdmin(c, cfg) {
+		t.Error("expecting a request authorised with a service token not to act as the admin")
+	}
+}
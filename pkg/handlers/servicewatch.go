@@ -0,0 +1,148 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// serviceWatchHeartbeatInterval interval of the heartbeats that keep alive the service watch streams
+var serviceWatchHeartbeatInterval = 15 * time.Second
+
+// MakeServiceWatchHandler makes a handler for streaming (Server-Sent Events) the creation, update and deletion of the
+// service definitions satisfying the 'label' and 'vo' filters of the service list. Unless 'initial=false', the
+// services existing when the watch starts are sent first as "added" events, followed by a "synced" event. Only the
+// services accessible by the user are sent
+func MakeServiceWatchHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := types.ServiceListOptions{
+			Labels: c.QueryArray("label"),
+			VO:     c.Query("vo"),
+		}
+		if err := opts.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid watch options: %v", err))
+			return
+		}
+		initial, err := strconv.ParseBool(c.DefaultQuery("initial", "true"))
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid initial value: %v", err))
+			return
+		}
+
+		// Subscribe before listing the services to not miss any change
+		events, unsubscribe := utils.SubscribeLifecycleEvents()
+		defer unsubscribe()
+
+		// Services satisfying the filters sent to the client, to send their deletion
+		watched := map[string]bool{}
+		// The services are listed to know which ones are accessible by the user
+		access := newServiceAccessCache(c, cfg, back)
+		services, err := back.ListServices()
+		if err != nil {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+			return
+		}
+		for _, service := range services {
+			access.set(service)
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// Disable the buffering of the ingress (NGINX)
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		if initial {
+			for _, service := range services {
+				if access.canAccess(service.Name, false) && opts.Matches(service) {
+					watched[service.Name] = true
+					sendServiceWatchEvent(c, types.ServiceAddedWatchEvent, service.Name, service)
+				}
+			}
+			sendServiceWatchEvent(c, types.ServiceSyncedWatchEvent, "", nil)
+		}
+
+		ctx := c.Request.Context()
+		heartbeat := time.NewTicker(serviceWatchHeartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event := <-events:
+				var eventType string
+				switch event.Type {
+				case types.ServiceCreatedEvent:
+					eventType = types.ServiceCreatedWatchEvent
+				case types.ServiceUpdatedEvent:
+					eventType = types.ServiceUpdatedWatchEvent
+				case types.ServiceDeletedEvent:
+					if watched[event.Service] || (!initial && access.canAccess(event.Service, false)) {
+						delete(watched, event.Service)
+						sendServiceWatchEvent(c, types.ServiceDeletedWatchEvent, event.Service, nil)
+					}
+					continue
+				default:
+					continue
+				}
+
+				service, err := back.ReadService(event.Service)
+				if err != nil {
+					// The service may have been deleted since, which is sent in its own event
+					if !errors.IsNotFound(err) && !errors.IsGone(err) {
+						log.Printf("Error reading service \"%s\" to send its watch event: %v\n", event.Service, err)
+					}
+					continue
+				}
+				switch {
+				case access.set(service) && opts.Matches(service):
+					watched[service.Name] = true
+					sendServiceWatchEvent(c, eventType, service.Name, service)
+				case watched[service.Name]:
+					// The service no longer satisfies the filters (or is no longer accessible)
+					delete(watched, service.Name)
+					sendServiceWatchEvent(c, types.ServiceDeletedWatchEvent, service.Name, nil)
+				}
+			case <-heartbeat.C:
+				sendHeartbeat(c)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// sendServiceWatchEvent sends an event of the service watch stream
+func sendServiceWatchEvent(c *gin.Context, eventType string, name string, service *types.Service) {
+	data, _ := json.Marshal(types.ServiceWatchEvent{
+		Type:    eventType,
+		Name:    name,
+		Service: service,
+		Time:    time.Now().UTC(),
+	})
+	sendEvent(c, eventType, string(data))
+}
@@ -0,0 +1,118 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+func TestMakeServiceWatchHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "a", Labels: map[string]string{"team": "x"}})
+	back.CreateService(types.Service{Name: "b"})
+	back.CreateService(types.Service{Name: "other", Owner: "other", Labels: map[string]string{"team": "x"}})
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, "user")
+	})
+	r.GET("/system/services/watch", MakeServiceWatchHandler(&testConfigValidRun, back))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/system/services/watch?initial=maybe")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expecting code %d, got %d", http.StatusBadRequest, res.StatusCode)
+	}
+
+	res, err = http.Get(server.URL + "/system/services/watch?label=team=x")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	if contentType := res.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("expecting Content-Type text/event-stream, got %s", contentType)
+	}
+
+	events := make(chan types.ServiceWatchEvent)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			if data := strings.TrimPrefix(scanner.Text(), "data:"); data != scanner.Text() {
+				var event types.ServiceWatchEvent
+				json.Unmarshal([]byte(data), &event)
+				events <- event
+			}
+		}
+	}()
+	read := func(expectedType string, expectedName string) {
+		select {
+		case event := <-events:
+			if event.Type != expectedType || event.Name != expectedName {
+				t.Fatalf("expecting the event %s of \"%s\", got %+v", expectedType, expectedName, event)
+			}
+			if (event.Service != nil) != (expectedType != types.ServiceDeletedWatchEvent && expectedType != types.ServiceSyncedWatchEvent) {
+				t.Errorf("unexpected service definition in the event %+v", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expecting the event %s of \"%s\"", expectedType, expectedName)
+		}
+	}
+
+	// Services existing when the watch starts
+	read(types.ServiceAddedWatchEvent, "a")
+	read(types.ServiceSyncedWatchEvent, "")
+
+	// A service not satisfying the filters is skipped
+	back.CreateService(types.Service{Name: "c"})
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceCreatedEvent, Service: "c"})
+
+	// The services of other owners are skipped
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceUpdatedEvent, Service: "other"})
+	back.DeleteService("other")
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceDeletedEvent, Service: "other"})
+
+	// A service starting to satisfy the filters
+	back.UpdateService(types.Service{Name: "b", Labels: map[string]string{"team": "x"}})
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceUpdatedEvent, Service: "b"})
+	read(types.ServiceUpdatedWatchEvent, "b")
+
+	// A service no longer satisfying the filters is sent as deleted
+	back.UpdateService(types.Service{Name: "a"})
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceUpdatedEvent, Service: "a"})
+	read(types.ServiceDeletedWatchEvent, "a")
+
+	back.DeleteService("b")
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceDeletedEvent, Service: "b"})
+	read(types.ServiceDeletedWatchEvent, "b")
+}
@@ -0,0 +1,63 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MakeSimulateEventHandler makes a handler for simulating the trigger of a service with a synthetic storage event.
+// The event runs through the dispatcher pipeline (filters, file sets, batches and placement) without side effects,
+// returning the job that would be created
+func MakeSimulateEventHandler(back types.ServerlessBackend, dispatcher *EventDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.EventSimulationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The simulation request is not valid: %v", err))
+			return
+		}
+		eventBytes, err := req.GetEvent()
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The simulation request is not valid: %v", err))
+			return
+		}
+
+		service, err := back.ReadService(c.Param("serviceName"))
+		if err != nil {
+			// Check if error is caused because the service is not found
+			if errors.IsNotFound(err) || errors.IsGone(err) {
+				sendError(c, types.ErrServiceNotFound, "")
+			} else {
+				sendError(c, types.ErrServiceReadFailed, err.Error())
+			}
+			return
+		}
+
+		res, err := dispatcher.simulate(service, eventBytes)
+		if err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
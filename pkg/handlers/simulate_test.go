@@ -0,0 +1,117 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMakeSimulateEventHandler(t *testing.T) {
+	input := types.StorageIOConfig{
+		Provider:     types.MinIOName + types.ProviderSeparator + types.DefaultProvider,
+		Path:         "input/images",
+		ContentTypes: []string{"image/*"},
+	}
+
+	scenarios := []struct {
+		name           string
+		service        string
+		batch          bool
+		body           string
+		expectedCode   int
+		expectedStatus string
+	}{
+		{"delivered", "simulate", false, `{"bucket": "input", "key": "images/a.jpg", "content_type": "image/jpeg"}`, http.StatusOK, types.SimulationDelivered},
+		{"discarded", "simulate", false, `{"bucket": "input", "key": "images/a.txt", "content_type": "text/plain"}`, http.StatusOK, types.SimulationDiscarded},
+		{"batched", "simulate", true, `{"bucket": "input", "key": "images/a.jpg", "content_type": "image/jpeg"}`, http.StatusOK, types.SimulationBatched},
+		{"missing object", "simulate", false, `{"bucket": "input"}`, http.StatusBadRequest, ""},
+		{"service not found", "nonexistent", false, `{"bucket": "input", "key": "images/a.jpg"}`, http.StatusNotFound, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := types.Service{
+				Name:  "simulate",
+				Image: "test",
+				Input: []types.StorageIOConfig{input},
+			}
+			if s.batch {
+				service.Batch.Window = 3600
+			}
+			back := backends.MakeMemoryBackend()
+			back.CreateService(service)
+			kubeClientset := testclient.NewSimpleClientset()
+
+			r := gin.Default()
+			r.POST("/system/services/:serviceName/simulate-event", MakeSimulateEventHandler(back, MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/services/"+s.service+"/simulate-event", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode != http.StatusOK {
+				return
+			}
+
+			var res types.EventSimulation
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error decoding the result: %v", err)
+			}
+			if res.Status != s.expectedStatus {
+				t.Errorf("expecting status %s, got %s (%s)", s.expectedStatus, res.Status, res.Reason)
+			}
+			if s.expectedStatus == types.SimulationDiscarded {
+				if res.Job != nil {
+					t.Error("expecting no job for a discarded event")
+				}
+			} else {
+				if res.Job == nil {
+					t.Fatal("expecting the job definition in the result")
+				}
+				found := false
+				for _, env := range res.Job.Spec.Template.Spec.Containers[0].Env {
+					if env.Name == types.EventVariable && env.Value == res.Event && strings.Contains(env.Value, "images/a.jpg") {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expecting the %s variable with the event in the job", types.EventVariable)
+				}
+			}
+
+			// The simulation must not create any job
+			jobs, _ := kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).List(context.TODO(), metav1.ListOptions{})
+			if len(jobs.Items) != 0 {
+				t.Errorf("expecting no created jobs, got %d", len(jobs.Items))
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// getServiceStatus returns the runtime status of a service
+func getServiceStatus(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service) (*types.ServiceStatus, error) {
+	status := &types.ServiceStatus{
+		Deployments: []types.DeploymentStatus{},
+		Triggers:    []types.TriggerStatus{},
+	}
+
+	// Deployments readiness
+	deployments, err := getServiceDeployments(cfg, kubeClientset, service)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments {
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		status.Deployments = append(status.Deployments, types.DeploymentStatus{
+			Name:          d.Name,
+			Ready:         d.Status.ReadyReplicas >= replicas && d.Status.UnavailableReplicas == 0,
+			Replicas:      replicas,
+			ReadyReplicas: d.Status.ReadyReplicas,
+		})
+	}
+
+	// Queue depth (only jobs' pods are taken into account)
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,job-name", types.ServiceLabel, service.Name),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(cfg.GetServiceNamespace(service)).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		switch pod.Status.Phase {
+		case v1.PodPending:
+			status.PendingJobs++
+		case v1.PodRunning:
+			status.RunningJobs++
+		}
+	}
+
+	// Trigger path health
+	status.Triggers, status.Webhook = getTriggersStatus(cfg, service)
+
+	// Effective FaaS Supervisor
+	status.SupervisorVersion = service.GetSupervisorVersion(cfg)
+	if service.Supervisor != nil {
+		status.SupervisorImage = service.Supervisor.GetImage()
+	}
+
+	return status, nil
+}
+
+// getServiceDeployments returns the deployments backing the synchronous and exposed parts of a service
+func getServiceDeployments(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service) ([]appsv1.Deployment, error) {
+	deployments := []appsv1.Deployment{}
+	names := []string{}
+
+	switch cfg.ServerlessBackend {
+	case types.OpenFaaSBackend:
+		names = append(names, service.Name)
+	case types.KnativeBackend:
+		listOpts := metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", types.KnativeServiceLabel, service.Name),
+		}
+		knDeployments, err := kubeClientset.AppsV1().Deployments(cfg.GetServiceNamespace(service)).List(context.TODO(), listOpts)
+		if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, knDeployments.Items...)
+	}
+
+	if service.Expose.Port != 0 {
+		names = append(names, utils.GetExposeDeploymentName(service.Name))
+	}
+
+	for _, name := range names {
+		d, err := kubeClientset.AppsV1().Deployments(cfg.GetServiceNamespace(service)).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			// Report missing deployments as not ready
+			deployments = append(deployments, appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			continue
+		}
+		deployments = append(deployments, *d)
+	}
+
+	return deployments, nil
+}
+
+// getTriggersStatus checks the webhook and the bucket notifications of the service's MinIO inputs. The status of
+// the webhook is nil if the service has no MinIO inputs
+func getTriggersStatus(cfg *types.Config, service *types.Service) ([]types.TriggerStatus, *types.WebhookStatus) {
+	triggers := []types.TriggerStatus{}
+
+	// The service's webhook is only checked if it has MinIO inputs
+	var webhookErr error
+	var webhook *types.WebhookStatus
+
+	for _, in := range service.Input {
+		provName, provID := in.GetProvider()
+		if provName != types.MinIOName {
+			continue
+		}
+		if webhook == nil {
+			webhookErr = checkMinIOWebhook(cfg, service.Name)
+			webhook = &types.WebhookStatus{Registered: webhookErr == nil}
+			if webhookErr != nil {
+				webhook.Message = webhookErr.Error()
+			}
+		}
+
+		trigger := types.TriggerStatus{
+			Provider: in.Provider,
+			Path:     in.Path,
+			Enabled:  !in.Disabled,
+		}
+
+		if in.Disabled {
+			trigger.Message = "the trigger is disabled"
+		} else if webhookErr != nil {
+			trigger.Message = webhookErr.Error()
+		} else if service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+			trigger.Message = fmt.Sprintf("the StorageProvider \"%s.%s\" is not defined", provName, provID)
+		} else if err := checkInputNotification(service.StorageProviders.MinIO[provID].GetS3Client(), service.GetMinIOWebhookARN(), in); err != nil {
+			trigger.Message = err.Error()
+		} else {
+			trigger.Healthy = true
+		}
+
+		triggers = append(triggers, trigger)
+	}
+
+	return triggers, webhook
+}
+
+// checkMinIOWebhook checks if the service's webhook is registered in MinIO
+func checkMinIOWebhook(cfg *types.Config, name string) error {
+	minIOAdminClient, err := utils.MakeMinIOAdminClient(cfg)
+	if err != nil {
+		return fmt.Errorf("the provided MinIO configuration is not valid: %v", err)
+	}
+
+	registered, err := minIOAdminClient.IsWebhookRegistered(name)
+	if err != nil {
+		return fmt.Errorf("error checking the service's webhook: %v", err)
+	}
+	if !registered {
+		return fmt.Errorf("the service's webhook is not registered in MinIO")
+	}
+
+	return nil
+}
+
+// checkInputNotification checks if the bucket notification of an input is enabled
+func checkInputNotification(minIOClient *s3.S3, arnStr string, input types.StorageIOConfig) error {
+	parsedARN, _ := arn.Parse(arnStr)
+	bucket, folder := input.SplitPath()
+
+	nCfg, err := minIOClient.GetBucketNotificationConfiguration(&s3.GetBucketNotificationConfigurationRequest{Bucket: aws.String(bucket)})
+	if err != nil {
+		return fmt.Errorf("error getting bucket \"%s\" notifications: %v", bucket, err)
+	}
+
+	for _, q := range nCfg.QueueConfigurations {
+		queueARN, _ := arn.Parse(aws.StringValue(q.QueueArn))
+		if queueARN.Resource != parsedARN.Resource || queueARN.AccountID != parsedARN.AccountID {
+			continue
+		}
+		if folder == "" || getFilterPrefix(q) == fmt.Sprintf("%s/", folder) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the notification of bucket \"%s\" is not enabled", bucket)
+}
+
+// getFilterPrefix returns the prefix filter rule of a QueueConfiguration (if any)
+func getFilterPrefix(q *s3.QueueConfiguration) string {
+	if q.Filter == nil || q.Filter.Key == nil {
+		return ""
+	}
+	for _, rule := range q.Filter.Key.FilterRules {
+		if aws.StringValue(rule.Name) == s3.FilterRuleNamePrefix {
+			return aws.StringValue(rule.Value)
+		}
+	}
+	return ""
+}
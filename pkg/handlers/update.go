@@ -17,95 +17,298 @@ limitations under the License.
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/grycap/oscar/v2/pkg/types"
 	"github.com/grycap/oscar/v2/pkg/utils"
 	"k8s.io/apimachinery/pkg/api/errors"
 )
 
-// MakeUpdateHandler makes a handler for updating services
+// mergePatchContentType content type of the JSON merge patches (RFC 7386)
+const mergePatchContentType = "application/merge-patch+json"
+
+// MakeUpdateHandler makes a handler for updating services.
+// With the "dry_run" querystring set to true the service is validated but not updated, returning its fully-defaulted definition
 func MakeUpdateHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var provName string
 		var newService types.Service
 		if err := c.ShouldBindJSON(&newService); err != nil {
-			c.String(http.StatusBadRequest, fmt.Sprintf("The service specification is not valid: %v", err))
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: %v", err))
 			return
 		}
+		c.Set(auditServiceKey, newService.Name)
 
-		// Check service values and set defaults
-		checkValues(&newService, cfg)
+		// Check service values, set defaults and validate the definition
+		if err := prepareService(&newService, cfg); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
+
+		// Check the required capabilities against the nodes of the cluster
+		if err := checkServiceRequirements(&newService, back); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
+
+		// Get the script (from its Git repository if defined)
+		if err := setServiceScript(&newService, cfg, back); err != nil {
+			sendCodedError(c, err, types.ErrScriptFetchFailed)
+			return
+		}
 
 		// Read the current service
-		oldService, err := back.ReadService(newService.Name)
+		oldService, err := readUpdatedService(back, newService.Name)
 		if err != nil {
-			// Check if error is caused because the service is not found
-			if errors.IsNotFound(err) || errors.IsGone(err) {
-				c.Status(http.StatusNotFound)
-			} else {
-				c.String(http.StatusInternalServerError, fmt.Sprintf("Error updating the service: %v", err))
+			sendCodedError(c, err, types.ErrServiceReadFailed)
+			return
+		}
+		if !checkServiceAccess(c, cfg, oldService) {
+			return
+		}
+		if err := checkProtectedFlag(c, cfg, &newService, oldService); err != nil {
+			sendCodedError(c, err, types.ErrAdminRequired)
+			return
+		}
+		if err := checkUpdatedServiceVO(c, cfg, &newService, oldService); err != nil {
+			sendCodedError(c, err, types.ErrVOCheckFailed)
+			return
+		}
+
+		// Return the fully-defaulted service without persisting it
+		if isDryRun(c) {
+			if err := dryRunService(back, &newService, true); err != nil {
+				sendCodedError(c, err, types.ErrServiceUpdateFailed)
+				return
 			}
+			c.JSON(http.StatusOK, newService)
 			return
 		}
 
-		// Update the service
-		if err := back.UpdateService(newService); err != nil {
-			c.String(http.StatusInternalServerError, fmt.Sprintf("Error updating the service: %v", err))
+		if !confirmProtectedChange(c, oldService) {
+			return
+		}
+		if err := updateService(cfg, back, &newService, oldService); err != nil {
+			sendCodedError(c, err, types.ErrServiceUpdateFailed)
 			return
 		}
 
-		for _, in := range oldService.Input {
-			// Split input provider
-			provSlice := strings.SplitN(strings.TrimSpace(in.Provider), types.ProviderSeparator, 2)
-			if len(provSlice) == 1 {
-				provName = strings.ToLower(provSlice[0])
-			} else {
-				provName = strings.ToLower(provSlice[0])
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// MakePatchHandler makes a handler for partially updating services. The body is a JSON merge patch (RFC 7386)
+// applied to the stored service definition, so only the fields to change have to be sent (null removes a field).
+// Unlike full updates, the service's token is kept. The "dry_run" querystring is also supported
+func MakePatchHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if contentType := c.ContentType(); contentType != mergePatchContentType && contentType != gin.MIMEJSON {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The Content-Type must be \"%s\"", mergePatchContentType))
+			return
+		}
+
+		patch, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Error reading the patch: %v", err))
+			return
+		}
+
+		// Read the current service
+		name := c.Param("serviceName")
+		oldService, err := readUpdatedService(back, name)
+		if err != nil {
+			sendCodedError(c, err, types.ErrServiceReadFailed)
+			return
+		}
+
+		// Merge the patch with the stored definition
+		newService, err := patchService(oldService, patch)
+		if err != nil {
+			sendError(c, types.ErrInvalidServiceDefinition, fmt.Sprintf("The service specification is not valid: %v", err))
+			return
+		}
+		if newService.Name != name {
+			sendError(c, types.ErrInvalidServiceDefinition, "The service specification is not valid: the name of the service can not be changed")
+			return
+		}
+		if err := checkProtectedFlag(c, cfg, newService, oldService); err != nil {
+			sendCodedError(c, err, types.ErrAdminRequired)
+			return
+		}
+		if err := checkUpdatedServiceVO(c, cfg, newService, oldService); err != nil {
+			sendCodedError(c, err, types.ErrVOCheckFailed)
+			return
+		}
+
+		// Check service values, set defaults and validate the definition
+		if err := prepareService(newService, cfg); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
+		newService.Token = oldService.Token
+
+		// Check the required capabilities against the nodes of the cluster
+		if err := checkServiceRequirements(newService, back); err != nil {
+			sendCodedError(c, err, types.ErrInvalidServiceDefinition)
+			return
+		}
+
+		// Get the script (from its Git repository if defined)
+		if err := setServiceScript(newService, cfg, back); err != nil {
+			sendCodedError(c, err, types.ErrScriptFetchFailed)
+			return
+		}
+
+		if isDryRun(c) {
+			if err := dryRunService(back, newService, true); err != nil {
+				sendCodedError(c, err, types.ErrServiceUpdateFailed)
+				return
+			}
+		} else {
+			if !confirmProtectedChange(c, oldService) {
+				return
 			}
-			if provName == types.MinIOName {
-				// Register minio webhook and restart the server
-				if err := registerMinIOWebhook(newService.Name, newService.Token, newService.StorageProviders.MinIO[types.DefaultProvider], cfg); err != nil {
-					back.UpdateService(*oldService)
-					c.String(http.StatusInternalServerError, err.Error())
-					return
-				}
-
-				// Update buckets
-				if err := updateBuckets(&newService, oldService, cfg); err != nil {
-					if err == errInput {
-						c.String(http.StatusBadRequest, err.Error())
-					} else {
-						c.String(http.StatusInternalServerError, err.Error())
-					}
-					// If updateBuckets fails restore the oldService
-					back.UpdateService(*oldService)
-					return
-				}
+			if err := updateService(cfg, back, newService, oldService); err != nil {
+				sendCodedError(c, err, types.ErrServiceUpdateFailed)
+				return
 			}
 		}
 
-		// Add Yunikorn queue if enabled
-		if cfg.YunikornEnable {
-			if err := utils.AddYunikornQueue(cfg, back.GetKubeClientset(), &newService); err != nil {
-				log.Println(err.Error())
+		c.JSON(http.StatusOK, newService)
+	}
+}
+
+// checkUpdatedServiceVO checks that the user is enrolled in the new VO of an updated service, as on creation, so
+// that the VO (and the policies depending on it) can not be switched to another one
+func checkUpdatedServiceVO(c *gin.Context, cfg *types.Config, newService, oldService *types.Service) error {
+	if newService.VO == oldService.VO {
+		return nil
+	}
+	return checkServiceVO(c, cfg, newService)
+}
+
+// patchService returns the service resulting of applying a JSON merge patch to the service's definition
+func patchService(service *types.Service, patch []byte) (*types.Service, error) {
+	original, err := json.Marshal(service)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return nil, fmt.Errorf("error applying the patch: %v", err)
+	}
+
+	newService := &types.Service{}
+	if err := json.Unmarshal(patched, newService); err != nil {
+		return nil, err
+	}
+	if err := binding.Validator.ValidateStruct(newService); err != nil {
+		return nil, err
+	}
+
+	return newService, nil
+}
+
+// readUpdatedService reads the current definition of a service to be updated
+func readUpdatedService(back types.ServerlessBackend, name string) (*types.Service, error) {
+	service, err := back.ReadService(name)
+	if err != nil {
+		// Check if error is caused because the service is not found
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			return nil, types.NewCodedError(types.ErrServiceNotFound, fmt.Errorf("the service \"%s\" does not exist", name))
+		}
+		return nil, types.NewCodedError(types.ErrServiceReadFailed, fmt.Errorf("Error updating the service: %v", err))
+	}
+	return service, nil
+}
+
+// updateService updates the service in the backend and its MinIO resources (webhook, buckets and notifications),
+// restoring the old service if any of them fails
+func updateService(cfg *types.Config, back types.ServerlessBackend, newService *types.Service, oldService *types.Service) error {
+	var provName string
+
+	// The owner, the named access tokens and the namespace of the service can't be changed
+	newService.Owner = oldService.Owner
+	newService.AccessTokens = oldService.AccessTokens
+	newService.Namespace = oldService.Namespace
+	setServiceRevision(cfg, back, newService)
+
+	// Update the service
+	if err := back.UpdateService(*newService); err != nil {
+		return types.NewCodedError(types.ErrServiceUpdateFailed, fmt.Errorf("Error updating the service: %v", err))
+	}
+	newBuckets := getNewBuckets(cfg, []*types.Service{newService})
+
+	for _, in := range oldService.Input {
+		// Split input provider
+		provSlice := strings.SplitN(strings.TrimSpace(in.Provider), types.ProviderSeparator, 2)
+		if len(provSlice) == 1 {
+			provName = strings.ToLower(provSlice[0])
+		} else {
+			provName = strings.ToLower(provSlice[0])
+		}
+		if provName == types.MinIOName {
+			// Register minio webhook and restart the server
+			if err := registerMinIOWebhook(newService.Name, newService.Token, newService.StorageProviders.MinIO[types.DefaultProvider], cfg); err != nil {
+				back.UpdateService(*oldService)
+				return types.NewCodedError(types.GetErrorCode(err, types.ErrWebhookRegisterFailed), err)
 			}
+
+			// Update buckets
+			if err := updateBuckets(newService, oldService, cfg); err != nil {
+				// If updateBuckets fails restore the oldService
+				back.UpdateService(*oldService)
+				return types.NewCodedError(types.GetErrorCode(err, types.ErrInternal), err)
+			}
+		}
+	}
+
+	// Create the dead-letter bucket
+	if newService.DeadLetterPath != "" {
+		if err := utils.CreateDeadLetterBucket(cfg, newService); err != nil {
+			back.UpdateService(*oldService)
+			return types.NewCodedError(types.ErrBucketCreateFailed, err)
 		}
+	}
+	recordCreatedBuckets(cfg, back, newService.Name, newBuckets)
 
-		c.Status(http.StatusNoContent)
+	// Add Yunikorn queue if enabled
+	if cfg.YunikornEnable {
+		if err := utils.AddYunikornQueue(cfg, back.GetKubeClientset(), newService); err != nil {
+			log.Println(err.Error())
+		}
+		// Remove the old queue if the service has been moved
+		if oldQueue := oldService.Labels[types.YunikornQueueLabel]; oldQueue != "" && oldQueue != newService.Labels[types.YunikornQueueLabel] {
+			if err := utils.DeleteYunikornQueue(cfg, back.GetKubeClientset(), oldService); err != nil {
+				log.Println(err.Error())
+			}
+		}
 	}
+
+	saveServiceRevision(cfg, back, newService)
+
+	utils.PublishLifecycleEvent(types.LifecycleEvent{Type: types.ServiceUpdatedEvent, Service: newService.Name})
+
+	return nil
 }
 
 func updateBuckets(newService, oldService *types.Service, cfg *types.Config) error {
 	// Disable notifications from oldService.Input
 	if err := disableInputNotifications(oldService.GetMinIOWebhookARN(), oldService.Input, oldService.StorageProviders.MinIO[types.DefaultProvider]); err != nil {
-		return fmt.Errorf("error disabling MinIO input notifications: %v", err)
+		return types.NewCodedError(types.ErrNotificationFailed, fmt.Errorf("error disabling MinIO input notifications: %v", err))
 	}
 
-	// Create the input and output buckets/folders from newService
-	return createBuckets(newService, cfg)
+	// Create the input and output buckets/folders from newService and enable its notifications
+	if err := createBuckets(newService, cfg); err != nil {
+		return err
+	}
+	return enableInputNotifications(newService)
 }
@@ -0,0 +1,94 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakePatchHandler(t *testing.T) {
+	back := backends.MakeMemoryBackend()
+	service := types.Service{
+		Name:   "test",
+		Image:  "busybox",
+		Memory: "256Mi",
+		Script: "ls",
+		Token:  "token",
+	}
+	service.Environment.Vars = map[string]string{"A": "1", "B": "2"}
+	back.CreateService(service)
+
+	r := gin.Default()
+	r.PATCH("/system/services/:serviceName", MakePatchHandler(&testConfigValidRun, back))
+
+	scenarios := []struct {
+		name              string
+		path              string
+		contentType       string
+		body              string
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"change memory and env var", "/system/services/test", mergePatchContentType, `{"memory": "1Gi", "environment": {"Variables": {"A": "3", "B": null}}}`, http.StatusOK, ""},
+		{"rename", "/system/services/test", mergePatchContentType, `{"name": "other"}`, http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code},
+		{"change vo without enrollment", "/system/services/test", mergePatchContentType, `{"vo": "other"}`, http.StatusInternalServerError, types.ErrVOCheckFailed.Code},
+		{"remove required field", "/system/services/test", mergePatchContentType, `{"image": null}`, http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code},
+		{"invalid patch", "/system/services/test", mergePatchContentType, `{"memory": `, http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code},
+		{"invalid content type", "/system/services/test", "text/plain", `{"memory": "2Gi"}`, http.StatusBadRequest, types.ErrBadRequest.Code},
+		{"service not found", "/system/services/other", mergePatchContentType, `{"memory": "2Gi"}`, http.StatusNotFound, types.ErrServiceNotFound.Code},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PATCH", s.path, strings.NewReader(s.body))
+			req.Header.Set("Content-Type", s.contentType)
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+
+			// Check the stored service (only the first patch is applied)
+			stored, _ := back.ReadService("test")
+			if stored.Memory != "1Gi" || stored.Image != "busybox" || stored.Script != "ls" || stored.Token != "token" {
+				t.Errorf("unexpected stored service: %+v", stored)
+			}
+			if vars := stored.Environment.Vars; len(vars) != 1 || vars["A"] != "3" {
+				t.Errorf("unexpected environment variables: %v", vars)
+			}
+
+			if s.expectedCode == http.StatusOK {
+				var patched types.Service
+				if err := json.Unmarshal(w.Body.Bytes(), &patched); err != nil || patched.Memory != "1Gi" {
+					t.Errorf("unexpected response: %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
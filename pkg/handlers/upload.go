@@ -0,0 +1,149 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+)
+
+// contentRangeRegexp format of the Content-Range header of the chunks ("bytes start-end/total")
+var contentRangeRegexp = regexp.MustCompile(`^bytes (\d+)-(\d+)/(\d+)$`)
+
+// MakeUploadReadHandler makes a handler for reading the status of an upload session
+func MakeUploadReadHandler(cfg *types.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, err := utils.GetUploadSession(cfg, c.Param("uploadID"))
+		if err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+// MakeUploadAssetHandler makes a handler for uploading a chunk of an asset of an upload session.
+// The chunk range is set in the Content-Range header, if not provided the body is the whole asset
+func MakeUploadAssetHandler(cfg *types.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		size := c.Request.ContentLength
+		if size < 0 {
+			sendError(c, types.ErrBadRequest, "The Content-Length header is required")
+			return
+		}
+		if size > types.UploadMaxChunkSize {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The chunks can not exceed %d bytes", types.UploadMaxChunkSize))
+			return
+		}
+
+		offset, total := int64(0), size
+		if contentRange := c.GetHeader("Content-Range"); contentRange != "" {
+			var err error
+			offset, total, err = parseContentRange(contentRange, size)
+			if err != nil {
+				sendError(c, types.ErrBadRequest, err.Error())
+				return
+			}
+		}
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, size)
+		asset, err := utils.UploadAssetChunk(cfg, c.Param("uploadID"), c.Param("assetName"), offset, size, total, body)
+		if err != nil {
+			sendCodedError(c, err, types.ErrUploadFailed)
+			return
+		}
+
+		c.JSON(http.StatusOK, asset)
+	}
+}
+
+// parseContentRange returns the offset and the total size of the asset from the Content-Range of a chunk of size bytes
+func parseContentRange(contentRange string, size int64) (offset int64, total int64, err error) {
+	match := contentRangeRegexp.FindStringSubmatch(contentRange)
+	if match == nil {
+		return 0, 0, errors.New("The Content-Range header must have the format \"bytes start-end/total\"")
+	}
+	offset, _ = strconv.ParseInt(match[1], 10, 64)
+	end, _ := strconv.ParseInt(match[2], 10, 64)
+	total, _ = strconv.ParseInt(match[3], 10, 64)
+	if end-offset+1 != size || end >= total {
+		return 0, 0, fmt.Errorf("The Content-Range \"%s\" does not match the %d bytes of the chunk", contentRange, size)
+	}
+	return offset, total, nil
+}
+
+// MakeUploadActivateHandler makes a handler for creating the service of a completed upload session
+func MakeUploadActivateHandler(cfg *types.Config, back types.ServerlessBackend) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("uploadID")
+		service, err := utils.ReadUploadedService(cfg, id)
+		if err != nil {
+			sendCodedError(c, err, types.ErrUploadFailed)
+			return
+		}
+
+		// Get the script (from its Git repository if defined)
+		if err := setServiceScript(service, cfg, back); err != nil {
+			sendCodedError(c, err, types.ErrScriptFetchFailed)
+			return
+		}
+
+		if err := checkServiceVO(c, cfg, service); err != nil {
+			sendCodedError(c, err, types.ErrVOCheckFailed)
+			return
+		}
+
+		// The assets are copied before creating the service, so they are available in its first jobs
+		if err := utils.CopyUploadedAssets(cfg, id); err != nil {
+			sendCodedError(c, err, types.ErrUploadFailed)
+			return
+		}
+
+		if err := deployService(cfg, back, service); err != nil {
+			sendCodedError(c, err, types.ErrServiceCreateFailed)
+			return
+		}
+
+		// The service is already created, so a failure removing the staged assets is only logged
+		if err := utils.DeleteUploadSession(cfg, id); err != nil {
+			log.Printf("Error deleting the upload session \"%s\": %v\n", id, err)
+		}
+
+		c.Status(http.StatusCreated)
+	}
+}
+
+// MakeUploadDeleteHandler makes a handler for discarding an upload session and its staged assets
+func MakeUploadDeleteHandler(cfg *types.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := utils.DeleteUploadSession(cfg, c.Param("uploadID")); err != nil {
+			sendCodedError(c, err, types.ErrUploadFailed)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeUploadHandlers(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+
+	cfg := testConfigValidRun
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	cfg.UploadStagingBucket = "oscar-uploads"
+	cfg.UploadSessionTTL = time.Hour
+	back := backends.MakeMemoryBackend()
+
+	r := gin.Default()
+	r.POST("/system/services", MakeCreateHandler(&cfg, back))
+	r.GET("/system/uploads/:uploadID", MakeUploadReadHandler(&cfg))
+	r.PUT("/system/uploads/:uploadID/assets/:assetName", MakeUploadAssetHandler(&cfg))
+	r.POST("/system/uploads/:uploadID/activate", MakeUploadActivateHandler(&cfg, back))
+	r.DELETE("/system/uploads/:uploadID", MakeUploadDeleteHandler(&cfg))
+
+	model := bytes.Repeat([]byte("m"), types.UploadMinChunkSize+10)
+	script := []byte("#!/bin/sh\necho $INPUT_FILE_PATH")
+	var session types.UploadSession
+
+	steps := []struct {
+		name              string
+		method            string
+		path              string
+		contentRange      string
+		body              []byte
+		createErr         error
+		expectedCode      int
+		expectedErrorCode string
+	}{
+		{"assets without upload session", "POST", "/system/services", "", []byte(`{"name": "test", "image": "busybox", "script": "ls", "input": [{"storage_provider": "minio", "path": "data/in"}], "assets": [{"name": "model", "path": "data/models/model.bin"}]}`), nil, http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code},
+		{"asset outside the service's buckets", "POST", "/system/services?upload=true", "", []byte(`{"name": "test", "image": "busybox", "input": [{"storage_provider": "minio", "path": "data/in"}], "assets": [{"name": "model", "path": "other/model.bin"}]}`), nil, http.StatusBadRequest, types.ErrInvalidServiceDefinition.Code},
+		{"create session", "POST", "/system/services?upload=true", "", []byte(`{"name": "test", "image": "busybox", "input": [{"storage_provider": "minio", "path": "data/in"}], "assets": [{"name": "model", "path": "data/models/model.bin"}]}`), nil, http.StatusAccepted, ""},
+		{"read session", "GET", "/system/uploads/{id}", "", nil, nil, http.StatusOK, ""},
+		{"small intermediate chunk", "PUT", "/system/uploads/{id}/assets/model", fmt.Sprintf("bytes 0-9/%d", len(model)), model[:10], nil, http.StatusBadRequest, types.ErrBadRequest.Code},
+		{"first chunk", "PUT", "/system/uploads/{id}/assets/model", fmt.Sprintf("bytes 0-%d/%d", types.UploadMinChunkSize-1, len(model)), model[:types.UploadMinChunkSize], nil, http.StatusOK, ""},
+		{"repeated chunk", "PUT", "/system/uploads/{id}/assets/model", fmt.Sprintf("bytes 0-%d/%d", types.UploadMinChunkSize-1, len(model)), model[:types.UploadMinChunkSize], nil, http.StatusConflict, types.ErrUploadOffsetMismatch.Code},
+		{"invalid range", "PUT", "/system/uploads/{id}/assets/model", fmt.Sprintf("bytes %d-%d/%d", types.UploadMinChunkSize, len(model), len(model)), model[types.UploadMinChunkSize:], nil, http.StatusBadRequest, types.ErrBadRequest.Code},
+		{"last chunk", "PUT", "/system/uploads/{id}/assets/model", fmt.Sprintf("bytes %d-%d/%d", types.UploadMinChunkSize, len(model)-1, len(model)), model[types.UploadMinChunkSize:], nil, http.StatusOK, ""},
+		{"unknown asset", "PUT", "/system/uploads/{id}/assets/other", "", script, nil, http.StatusNotFound, types.ErrUploadNotFound.Code},
+		{"activate with pending script", "POST", "/system/uploads/{id}/activate", "", nil, nil, http.StatusConflict, types.ErrUploadIncomplete.Code},
+		{"whole script", "PUT", "/system/uploads/{id}/assets/script", "", script, nil, http.StatusOK, ""},
+		// The session is kept to retry the activation
+		{"activate with backend error", "POST", "/system/uploads/{id}/activate", "", nil, errors.New("create error"), http.StatusInternalServerError, types.ErrServiceCreateFailed.Code},
+		{"read session after failed activation", "GET", "/system/uploads/{id}", "", nil, nil, http.StatusOK, ""},
+		{"delete session", "DELETE", "/system/uploads/{id}", "", nil, nil, http.StatusNoContent, ""},
+		{"read deleted session", "GET", "/system/uploads/{id}", "", nil, nil, http.StatusNotFound, types.ErrUploadNotFound.Code},
+	}
+
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			if s.createErr != nil {
+				back.AddError("CreateService", s.createErr)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, strings.ReplaceAll(s.path, "{id}", session.ID), bytes.NewReader(s.body))
+			if s.contentRange != "" {
+				req.Header.Set("Content-Range", s.contentRange)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if code := w.Header().Get(errorCodeHeader); code != s.expectedErrorCode {
+				t.Errorf("expecting error code \"%s\", got \"%s\"", s.expectedErrorCode, code)
+			}
+
+			switch s.name {
+			case "create session":
+				if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+					t.Fatalf("error decoding the session: %v", err)
+				}
+				if strings.Contains(w.Body.String(), "minioadmin") {
+					t.Error("the session must not include the service's credentials")
+				}
+				if len(session.Assets) != 2 || session.Assets[0].Name != types.ScriptAssetName || session.Assets[1].Path != "data/models/model.bin" {
+					t.Errorf("unexpected session assets: %+v", session.Assets)
+				}
+				if _, err := back.ReadService("test"); err == nil {
+					t.Error("the service must not be created before the activation")
+				}
+			case "first chunk", "last chunk":
+				var asset types.UploadAsset
+				json.Unmarshal(w.Body.Bytes(), &asset)
+				if asset.Complete != (s.name == "last chunk") || asset.Size != int64(len(model)) {
+					t.Errorf("unexpected asset status: %+v", asset)
+				}
+			case "activate with backend error":
+				object, ok := s3Server.GetObject("data", "models/model.bin")
+				if !ok || !bytes.Equal(object.Data, model) {
+					t.Error("the model has not been copied to its path")
+				}
+			case "delete session":
+				if s3Server.PendingUploads() != 0 {
+					t.Errorf("expecting no pending uploads, got %d", s3Server.PendingUploads())
+				}
+				if _, ok := s3Server.GetObject(cfg.UploadStagingBucket, session.ID+"/assets/model"); ok {
+					t.Error("the staged model has not been deleted")
+				}
+			}
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	scenarios := []struct {
+		contentRange   string
+		size           int64
+		expectedOffset int64
+		expectedTotal  int64
+		returnError    bool
+	}{
+		{"bytes 0-9/20", 10, 0, 20, false},
+		{"bytes 10-19/20", 10, 10, 20, false},
+		{"bytes 10-19/20", 5, 0, 0, true},
+		{"bytes 10-20/20", 11, 0, 0, true},
+		{"bytes */20", 0, 0, 0, true},
+		{"10-19/20", 10, 0, 0, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.contentRange, func(t *testing.T) {
+			offset, total, err := parseContentRange(s.contentRange, s.size)
+			if (err != nil) != s.returnError {
+				t.Fatalf("expecting error %v, got %v", s.returnError, err)
+			}
+			if offset != s.expectedOffset || total != s.expectedTotal {
+				t.Errorf("expecting %d/%d, got %d/%d", s.expectedOffset, s.expectedTotal, offset, total)
+			}
+		})
+	}
+}
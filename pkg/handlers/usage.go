@@ -0,0 +1,40 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeUsageHandler makes a handler for getting the resources consumed by each VO and user in the quotas period,
+// along with their quotas. The "vo" and "user" querystrings filter the accounts returned
+func MakeUsageHandler(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		summary, err := utils.GetUsageSummary(cfg, back, kubeClientset, c.Query("vo"), c.Query("user"))
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, summary)
+	}
+}
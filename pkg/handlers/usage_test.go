@@ -0,0 +1,75 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeUsageHandler(t *testing.T) {
+	cfg := testConfigValidRun
+	cfg.QuotasPeriod = 24 * time.Hour
+	cfg.Quotas = []string{"vo:vo.example.eu:jobs=10", "user:bob:jobs=0"}
+
+	back := backends.MakeMemoryBackend()
+	back.CreateService(types.Service{Name: "test", VO: "vo.example.eu", Owner: "alice"})
+
+	r := gin.Default()
+	r.GET("/system/usage", MakeUsageHandler(&cfg, back, back.GetKubeClientset()))
+
+	scenarios := []struct {
+		name          string
+		query         string
+		expectedVOs   int
+		expectedUsers int
+	}{
+		{"all", "", 1, 2},
+		{"vo", "?vo=vo.example.eu", 1, 0},
+		{"other vo", "?vo=other.eu", 0, 0},
+		{"user", "?user=bob", 0, 1},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/system/usage"+s.query, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			summary := types.UsageSummary{}
+			if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(summary.VOs) != s.expectedVOs || len(summary.Users) != s.expectedUsers {
+				t.Errorf("expected %d VOs and %d users, got %+v", s.expectedVOs, s.expectedUsers, summary)
+			}
+			if s.expectedVOs > 0 && (summary.VOs[0].Quota == nil || summary.VOs[0].Quota.Jobs != 10) {
+				t.Errorf("expected the quota of the VO, got %+v", summary.VOs[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils/auth"
+)
+
+// MakeUserListHandler makes a handler to list the users of the user store (without their passwords)
+func MakeUserListHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkUserStore(c, cfg, users) {
+			return
+		}
+		list, err := users.List()
+		if err != nil {
+			sendUserStoreError(c, err, "")
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// MakeUserReadHandler makes a handler to read a user of the user store (without its password)
+func MakeUserReadHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkUserStore(c, cfg, users) {
+			return
+		}
+		user, err := users.Get(c.Param("username"))
+		if err != nil {
+			sendUserStoreError(c, err, c.Param("username"))
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// MakeUserCreateHandler makes a handler to create a user of the user store, with its password, role and groups
+func MakeUserCreateHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkUserStore(c, cfg, users) {
+			return
+		}
+		req := types.UserRequest{}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The user specification is not valid: %v", err))
+			return
+		}
+		if err := validateUserRequest(cfg, &req, true); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		user, err := users.Create(req)
+		if err != nil {
+			sendUserStoreError(c, err, req.Username)
+			return
+		}
+		c.JSON(http.StatusCreated, user)
+	}
+}
+
+// MakeUserUpdateHandler makes a handler to update the password, the role, the groups or the status of a user of the
+// user store (the fields not set are kept)
+func MakeUserUpdateHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkUserStore(c, cfg, users) {
+			return
+		}
+		req := types.UserRequest{}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The user specification is not valid: %v", err))
+			return
+		}
+		req.Username = c.Param("username")
+		if err := validateUserRequest(cfg, &req, false); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		user, err := users.Update(req)
+		if err != nil {
+			sendUserStoreError(c, err, req.Username)
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// MakeUserDeleteHandler makes a handler to delete a user of the user store
+func MakeUserDeleteHandler(cfg *types.Config, users *auth.UserDirectory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkUserStore(c, cfg, users) {
+			return
+		}
+		if err := users.Delete(c.Param("username")); err != nil {
+			sendUserStoreError(c, err, c.Param("username"))
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// checkUserStore checks if the user of the request is an admin and the user store is enabled, sending the error
+// otherwise
+func checkUserStore(c *gin.Context, cfg *types.Config, users *auth.UserDirectory) bool {
+	if !isAdmin(c, cfg) {
+		sendError(c, types.ErrAdminRequired, "")
+		return false
+	}
+	if users == nil || !users.Managed {
+		sendError(c, types.ErrUserStoreUnavailable, "")
+		return false
+	}
+	return true
+}
+
+// validateUserRequest validates a request to create or update a user, whose username can't be the admin's one
+func validateUserRequest(cfg *types.Config, req *types.UserRequest, create bool) error {
+	if err := req.Validate(create); err != nil {
+		return err
+	}
+	if req.Username == cfg.Username {
+		return fmt.Errorf("the username \"%s\" is reserved for the admin of the cluster", req.Username)
+	}
+	return nil
+}
+
+// sendUserStoreError sends the error of an operation of the user store
+func sendUserStoreError(c *gin.Context, err error, username string) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		sendError(c, types.ErrUserNotFound, fmt.Sprintf("The user \"%s\" does not exist", username))
+	case errors.Is(err, auth.ErrUserAlreadyExists):
+		sendError(c, types.ErrUserAlreadyExists, fmt.Sprintf("The user \"%s\" already exists", username))
+	case errors.Is(err, auth.ErrUserStoreReadOnly):
+		sendError(c, types.ErrUserStoreUnavailable, "")
+	default:
+		sendError(c, types.ErrInternal, fmt.Sprintf("Error accessing the user store: %v", err))
+	}
+}
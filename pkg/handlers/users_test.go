@@ -0,0 +1,103 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils/auth"
+)
+
+func TestUsers(t *testing.T) {
+	cfg := &types.Config{Username: "oscar", UserStore: types.UserStoreFile, UserStoreFile: filepath.Join(t.TempDir(), "users.json")}
+	users, err := auth.NewUserDirectory(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := "oscar"
+	r := gin.New()
+	system := r.Group("/system", func(c *gin.Context) { c.Set(gin.AuthUserKey, user) })
+	system.GET("/users", MakeUserListHandler(cfg, users))
+	system.POST("/users", MakeUserCreateHandler(cfg, users))
+	system.GET("/users/:username", MakeUserReadHandler(cfg, users))
+	system.PUT("/users/:username", MakeUserUpdateHandler(cfg, users))
+	system.DELETE("/users/:username", MakeUserDeleteHandler(cfg, users))
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"create", "POST", "/system/users", `{"username": "alice", "password": "password1", "role": "operator"}`, http.StatusCreated},
+		{"duplicated user", "POST", "/system/users", `{"username": "alice", "password": "password1"}`, http.StatusConflict},
+		{"short password", "POST", "/system/users", `{"username": "bob", "password": "short"}`, http.StatusBadRequest},
+		{"admin's username", "POST", "/system/users", `{"username": "oscar", "password": "password1"}`, http.StatusBadRequest},
+		{"invalid role", "PUT", "/system/users/alice", `{"role": "root"}`, http.StatusBadRequest},
+		{"update", "PUT", "/system/users/alice", `{"role": "viewer", "groups": ["vo.example.eu"]}`, http.StatusOK},
+		{"update not found", "PUT", "/system/users/bob", `{"role": "viewer"}`, http.StatusNotFound},
+		{"read", "GET", "/system/users/alice", "", http.StatusOK},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if w := request(s.method, s.path, s.body); w.Code != s.expectedCode {
+				t.Errorf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := request("GET", "/system/users", "")
+	list := []types.User{}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list) != 1 || list[0].Role != types.RoleViewer || list[0].PasswordHash != "" || len(list[0].Groups) != 1 {
+		t.Errorf("unexpected users %+v", list)
+	}
+
+	// Only for the admins
+	user = "alice"
+	if w := request("GET", "/system/users", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expecting code %d, got %d", http.StatusForbidden, w.Code)
+	}
+	user = "oscar"
+
+	if w := request("DELETE", "/system/users/alice", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expecting code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	// Without a managed user store
+	r = gin.New()
+	r.GET("/system/users", func(c *gin.Context) { c.Set(gin.AuthUserKey, "oscar") }, MakeUserListHandler(cfg, nil))
+	if w := request("GET", "/system/users", ""); w.Code != http.StatusNotImplemented || w.Header().Get(errorCodeHeader) != types.ErrUserStoreUnavailable.Code {
+		t.Errorf("expecting code %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// MakeValidateHandler makes a handler for validating a service definition without creating it.
+// All the issues found are returned with the path of the field, so clients can show field-level feedback
+func MakeValidateHandler(cfg *types.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var service types.Service
+		res := types.ValidationResult{
+			Valid:    true,
+			Errors:   []types.ValidationIssue{},
+			Warnings: []types.ValidationIssue{},
+		}
+
+		err := c.ShouldBindJSON(&service)
+		// The fields with a wrong type are skipped, keep validating the rest of the definition
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			res.AddError(typeErr.Field, types.ErrInvalidServiceDefinition, fmt.Sprintf("the value must be of type %s", typeErr.Type))
+			err = binding.Validator.ValidateStruct(&service)
+		}
+		var fieldErrs validator.ValidationErrors
+		if errors.As(err, &fieldErrs) {
+			for _, fieldErr := range fieldErrs {
+				res.AddError(jsonFieldPath(fieldErr.Namespace()), types.ErrInvalidServiceDefinition, validationMessage(fieldErr))
+			}
+		} else if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The service specification can not be decoded: %v", err))
+			return
+		}
+
+		validateService(&service, cfg, &res)
+
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// validateService checks all the fields of a service definition, adding the issues found to the validation result
+func validateService(service *types.Service, cfg *types.Config, res *types.ValidationResult) {
+	// Values replaced by the defaults
+	if service.LogLevel != "" {
+		switch strings.ToUpper(service.LogLevel) {
+		case "NOTSET", "DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL":
+		default:
+			res.AddWarning("log_level", fmt.Sprintf("the log level \"%s\" is not valid, \"%s\" will be used", service.LogLevel, defaultLogLevel))
+		}
+	}
+
+	// Replicas that can't be used to delegate jobs
+	for i, replica := range service.Replicas {
+		if strings.ToLower(replica.Type) == "oscar" {
+			if _, ok := service.Clusters[replica.ClusterID]; !ok {
+				res.AddWarning(fmt.Sprintf("replicas[%d].cluster_id", i), fmt.Sprintf("the cluster \"%s\" is not defined in the clusters field, the jobs can't be delegated to it", replica.ClusterID))
+			}
+		}
+	}
+
+	checkValues(service, cfg)
+
+	// Resources
+	if _, err := resource.ParseQuantity(service.CPU); err != nil {
+		res.AddError("cpu", types.ErrInvalidServiceDefinition, fmt.Sprintf("the cpu \"%s\" is not valid: %v", service.CPU, err))
+	}
+	if _, err := resource.ParseQuantity(service.Memory); err != nil {
+		res.AddError("memory", types.ErrInvalidServiceDefinition, fmt.Sprintf("the memory \"%s\" is not valid: %v", service.Memory, err))
+	}
+
+	addValidationError(res, "extended_resources", service.ValidateExtendedResources())
+	addValidationError(res, "scheduling", service.ValidateScheduling())
+	addValidationError(res, "runtime_class", service.ValidateRuntimeClass())
+	if !service.IsRuntimeClassAllowed(cfg) {
+		res.AddError("runtime_class", types.ErrRuntimeClassNotAllowed, fmt.Sprintf("the runtime class \"%s\" is not allowed in the cluster", service.RuntimeClass))
+	}
+	addValidationError(res, "security_context", service.ValidateSecurityContext(cfg))
+	addValidationError(res, "containers", service.ValidateContainers())
+
+	// Script
+	if service.Script == "" && service.ScriptGit == nil {
+		res.AddError("script", types.ErrInvalidServiceDefinition, "the script or script_git field is required")
+	}
+
+	// Policies and paths
+	addValidationError(res, "yunikorn", service.ValidateYunikorn(cfg))
+	if service.Placement != nil {
+		addValidationError(res, "placement", service.Placement.Validate(service.Replicas))
+	}
+	addValidationError(res, "delegation_policy", service.ValidateDelegationPolicy())
+	addValidationError(res, "max_delay", service.ValidateDeferral())
+	addValidationError(res, "synchronous", service.ValidateSynchronous())
+	addValidationError(res, "batch", service.ValidateBatch())
+	addValidationError(res, "workers", service.ValidateWorkers())
+	if service.HasWorkers() && !cfg.KEDAEnable {
+		res.AddError("workers", types.ErrInvalidServiceDefinition, "the workers consuming the events with KEDA (KEDA_ENABLE) are not enabled in the cluster")
+	}
+	addValidationError(res, "max_concurrent_jobs", service.ValidateConcurrency())
+	addValidationError(res, "callback", service.ValidateCallback())
+	addValidationError(res, "chat_notification", service.ValidateChatNotification())
+	addValidationError(res, "dependencies", service.ValidateDependencies())
+	addValidationError(res, "supervisor", service.ValidateSupervisor())
+	if service.Supervisor != nil && !service.Supervisor.IsAllowed(cfg.SupervisorImages) {
+		res.AddError("supervisor", types.ErrSupervisorNotAllowed, fmt.Sprintf("the supervisor image \"%s\" is not allowed in the cluster", service.Supervisor.GetImage()))
+	}
+	addValidationError(res, "dead_letter_path", service.ValidateDeadLetterPath())
+	addValidationError(res, "quarantine_path", service.ValidateQuarantinePath())
+	if service.QuarantinePath != "" && cfg.MalwareScannerURL == "" {
+		res.AddError("quarantine_path", types.ErrInvalidServiceDefinition, "the malware scanning of the inputs is not enabled in the cluster")
+	}
+	addValidationError(res, "assets", service.ValidateAssets())
+	addValidationError(res, "features", service.ValidateFeatures())
+	addValidationError(res, "schema", service.ValidateSchema())
+	addValidationError(res, "output_destinations", service.ValidateOutputDestinations())
+	addValidationError(res, "output_routes", service.ValidateOutputRoutes())
+	addValidationError(res, "inline_outputs", service.ValidateInlineOutputs(cfg))
+
+	// Storage
+	for i, in := range service.Input {
+		addValidationError(res, fmt.Sprintf("input[%d]", i), validateInput(service, cfg, in))
+	}
+	for i, out := range service.Output {
+		addValidationError(res, fmt.Sprintf("output[%d]", i), validateOutput(service, out))
+	}
+}
+
+// addValidationError adds err (if not nil) to the validation result
+func addValidationError(res *types.ValidationResult, field string, err error) {
+	if err != nil {
+		res.AddError(field, types.GetErrorCode(err, types.ErrInvalidServiceDefinition), err.Error())
+	}
+}
+
+// validationMessage returns the description of a failed binding validation
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "the field is required"
+	case "max":
+		return fmt.Sprintf("the value must have at most %s characters", fieldErr.Param())
+	case "min":
+		return fmt.Sprintf("the value must have at least %s characters", fieldErr.Param())
+	default:
+		return fmt.Sprintf("the value does not satisfy the \"%s\" constraint", fieldErr.Tag())
+	}
+}
+
+// jsonFieldPath converts the namespace of a Service field (e.g. "Service.ScriptGit.URL") to its JSON path ("script_git.url")
+func jsonFieldPath(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	path := []string{}
+	t := reflect.TypeOf(types.Service{})
+	for _, part := range parts[1:] {
+		name, index := part, ""
+		if i := strings.Index(part, "["); i != -1 {
+			name, index = part[:i], part[i:]
+		}
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, part)
+			continue
+		}
+		if jsonName := strings.Split(field.Tag.Get("json"), ",")[0]; jsonName != "" {
+			name = jsonName
+		}
+		path = append(path, name+index)
+
+		t = field.Type
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+	}
+	return strings.Join(path, ".")
+}
@@ -0,0 +1,147 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestMakeValidateHandler(t *testing.T) {
+	scenarios := []struct {
+		name             string
+		body             string
+		expectedCode     int
+		expectedErrors   map[string]string
+		expectedWarnings []string
+	}{
+		{
+			"valid",
+			`{"name": "test", "image": "test", "script": "echo"}`,
+			http.StatusOK, map[string]string{}, nil,
+		},
+		{
+			"field errors",
+			`{"name": "test", "cpu": "1 core", "log_level": "verbose", "script": "echo",
+			  "input": [{"storage_provider": "s3.default", "path": "in"}], "output": [{"storage_provider": "minio.other", "path": "out"}],
+			  "replicas": [{"type": "oscar", "cluster_id": "missing", "service_name": "test"}]}`,
+			http.StatusOK,
+			map[string]string{
+				"image":     types.ErrInvalidServiceDefinition.Code,
+				"cpu":       types.ErrInvalidServiceDefinition.Code,
+				"input[0]":  types.ErrInvalidInputProvider.Code,
+				"output[0]": types.ErrStorageProviderNotDefined.Code,
+			},
+			[]string{"log_level", "replicas[0].cluster_id"},
+		},
+		{
+			"wrong type",
+			`{"name": "test", "image": "test", "script": "echo", "memory": 512}`,
+			http.StatusOK, map[string]string{"memory": types.ErrInvalidServiceDefinition.Code}, nil,
+		},
+		{
+			"missing script and long name",
+			`{"name": "this-is-a-very-long-service-name-with-more-than-39-characters", "image": "test"}`,
+			http.StatusOK, map[string]string{"name": types.ErrInvalidServiceDefinition.Code, "script": types.ErrInvalidServiceDefinition.Code}, nil,
+		},
+		{
+			"supervisor not allowed",
+			`{"name": "test", "image": "test", "script": "echo", "supervisor": {"version": "1.5.9"}}`,
+			http.StatusOK, map[string]string{"supervisor": types.ErrSupervisorNotAllowed.Code}, nil,
+		},
+		{
+			"runtime class not allowed",
+			`{"name": "test", "image": "test", "script": "echo", "runtime_class": "kata"}`,
+			http.StatusOK, map[string]string{"runtime_class": types.ErrRuntimeClassNotAllowed.Code}, nil,
+		},
+		{
+			"invalid security context",
+			`{"name": "test", "image": "test", "script": "echo", "security_context": {"run_as_user": -1}}`,
+			http.StatusOK, map[string]string{"security_context": types.ErrInvalidServiceDefinition.Code}, nil,
+		},
+		{
+			"sidecar without command",
+			`{"name": "test", "image": "test", "script": "echo", "sidecars": [{"name": "proxy", "image": "nginx"}]}`,
+			http.StatusOK, map[string]string{"containers": types.ErrInvalidServiceDefinition.Code}, nil,
+		},
+		{"not JSON", `name: test`, http.StatusBadRequest, nil, nil},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			r := gin.Default()
+			r.POST("/system/services/validate", MakeValidateHandler(&testConfigValidRun))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/system/services/validate", strings.NewReader(s.body))
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+			if s.expectedCode != http.StatusOK {
+				if w.Header().Get(errorCodeHeader) != types.ErrBadRequest.Code {
+					t.Errorf("expecting error code %s, got %s", types.ErrBadRequest.Code, w.Header().Get(errorCodeHeader))
+				}
+				return
+			}
+
+			var res types.ValidationResult
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error decoding the result: %v", err)
+			}
+			if res.Valid != (len(s.expectedErrors) == 0) {
+				t.Errorf("expecting valid %v, got %+v", len(s.expectedErrors) == 0, res)
+			}
+			if len(res.Errors) != len(s.expectedErrors) {
+				t.Errorf("expecting %d errors, got %+v", len(s.expectedErrors), res.Errors)
+			}
+			for _, issue := range res.Errors {
+				if code, ok := s.expectedErrors[issue.Field]; !ok || code != issue.Code {
+					t.Errorf("unexpected error %+v", issue)
+				}
+			}
+			if len(res.Warnings) != len(s.expectedWarnings) {
+				t.Errorf("expecting warnings %v, got %+v", s.expectedWarnings, res.Warnings)
+			}
+			for i, field := range s.expectedWarnings {
+				if i < len(res.Warnings) && res.Warnings[i].Field != field {
+					t.Errorf("expecting warning for field %s, got %+v", field, res.Warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestJSONFieldPath(t *testing.T) {
+	scenarios := map[string]string{
+		"Service.Name":           "name",
+		"Service.ScriptGit.URL":  "script_git.url",
+		"Service.Assets[1].Path": "assets[1].path",
+	}
+	for namespace, expected := range scenarios {
+		if res := jsonFieldPath(namespace); res != expected {
+			t.Errorf("expecting %s for %s, got %s", expected, namespace, res)
+		}
+	}
+}
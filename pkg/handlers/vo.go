@@ -0,0 +1,148 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MakeVORequestCreateHandler makes a handler to request enabling a new VO in the cluster, pending on the approval
+// of the admin
+func MakeVORequestCreateHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := types.VORequest{}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("The VO request is not valid: %v", err))
+			return
+		}
+		if err := req.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+		req.Requester = c.GetString(gin.AuthUserKey)
+
+		if err := utils.CreateVORequest(cfg, kubeClientset, &req); err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+
+		c.JSON(http.StatusCreated, req)
+	}
+}
+
+// MakeVORequestsListHandler makes a handler to list the VO requests, newest first. The admin gets all the requests
+// and the rest of users only their own ones
+func MakeVORequestsListHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requester := c.GetString(gin.AuthUserKey)
+		if isAdmin(c, cfg) {
+			requester = ""
+		}
+		requests, err := utils.ListVORequests(kubeClientset, cfg.ServicesNamespace, requester)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+
+		if status := c.Query("status"); status != "" {
+			filtered := []types.VORequest{}
+			for _, req := range requests {
+				if req.Status == status {
+					filtered = append(filtered, req)
+				}
+			}
+			requests = filtered
+		}
+
+		c.JSON(http.StatusOK, requests)
+	}
+}
+
+// MakeVORequestReadHandler makes a handler to read a VO request (only the admin can read other users' requests)
+func MakeVORequestReadHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, err := utils.GetVORequest(kubeClientset, cfg.ServicesNamespace, c.Param("requestID"))
+		if err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+		if !isAdmin(c, cfg) && req.Requester != c.GetString(gin.AuthUserKey) {
+			sendError(c, types.ErrVORequestNotFound, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, req)
+	}
+}
+
+// MakeVORequestApproveHandler makes a handler for the admin to approve a VO request, enabling the VO with its quota
+// and provisioning its namespace and bucket (if requested) without restarting the OSCAR manager
+func MakeVORequestApproveHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return makeVORequestDecisionHandler(cfg, kubeClientset, utils.ApproveVORequest)
+}
+
+// MakeVORequestRejectHandler makes a handler for the admin to reject a VO request
+func MakeVORequestRejectHandler(cfg *types.Config, kubeClientset kubernetes.Interface) gin.HandlerFunc {
+	return makeVORequestDecisionHandler(cfg, kubeClientset, utils.RejectVORequest)
+}
+
+// makeVORequestDecisionHandler makes a handler for the admin to make a decision on a VO request
+func makeVORequestDecisionHandler(cfg *types.Config, kubeClientset kubernetes.Interface, decide func(*types.Config, kubernetes.Interface, string, string, *types.VORequestDecision) (*types.VORequest, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, cfg) {
+			sendError(c, types.ErrAdminRequired, "")
+			return
+		}
+
+		// The decision is optional
+		decision := types.VORequestDecision{}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&decision); err != nil {
+				sendError(c, types.ErrBadRequest, fmt.Sprintf("The decision is not valid: %v", err))
+				return
+			}
+		}
+		if err := decision.Validate(); err != nil {
+			sendError(c, types.ErrBadRequest, err.Error())
+			return
+		}
+
+		req, err := decide(cfg, kubeClientset, c.Param("requestID"), c.GetString(gin.AuthUserKey), &decision)
+		if err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+
+		c.JSON(http.StatusOK, req)
+	}
+}
+
+// isAdmin checks if the request has been made by the admin of the cluster (basic auth user) or a user with the
+// admin role. The requests authorised with a service access token never act as the admin, even if the owner of
+// the service is the admin
+func isAdmin(c *gin.Context, cfg *types.Config) bool {
+	if c.GetBool(serviceTokenAuthKey) {
+		return false
+	}
+	return c.GetString(gin.AuthUserKey) == cfg.Username || c.GetString(types.RoleKey) == types.RoleAdmin
+}
@@ -0,0 +1,139 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestVORequestHandlers(t *testing.T) {
+	defer types.SetApprovedVOs(map[string]types.ApprovedVO{})
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	cfg := testConfigValidRun
+	cfg.Username = "oscar"
+	cfg.MinIOProvider = testS3Provider(s3Server)
+	kubeClientset := testclient.NewSimpleClientset()
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-User"))
+	})
+	r.POST("/system/vo-requests", MakeVORequestCreateHandler(&cfg, kubeClientset))
+	r.GET("/system/vo-requests", MakeVORequestsListHandler(&cfg, kubeClientset))
+	r.GET("/system/vo-requests/:requestID", MakeVORequestReadHandler(&cfg, kubeClientset))
+	r.POST("/system/vo-requests/:requestID/approve", MakeVORequestApproveHandler(&cfg, kubeClientset))
+	r.POST("/system/vo-requests/:requestID/reject", MakeVORequestRejectHandler(&cfg, kubeClientset))
+
+	request := func(method string, path string, user string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	create := func(user string, body string) types.VORequest {
+		w := request("POST", "/system/vo-requests", user, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		req := types.VORequest{}
+		json.Unmarshal(w.Body.Bytes(), &req)
+		return req
+	}
+
+	approved := create("alice", `{"vo": "vo.new.eu", "reason": "testing", "quota": {"jobs": 100}, "namespace": true, "storage": true}`)
+	if approved.Status != types.VORequestPending || approved.Requester != "alice" || approved.ID == "" {
+		t.Errorf("unexpected VO request: %+v", approved)
+	}
+	rejected := create("bob", `{"vo": "vo.other.eu"}`)
+
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		user         string
+		body         string
+		expectedCode int
+	}{
+		{"invalid VO", "POST", "/system/vo-requests", "alice", `{"vo": "Invalid VO"}`, http.StatusBadRequest},
+		{"pending VO", "POST", "/system/vo-requests", "carol", `{"vo": "vo.new.eu"}`, http.StatusBadRequest},
+		{"other user's request", "GET", "/system/vo-requests/" + approved.ID, "bob", "", http.StatusNotFound},
+		{"request not found", "GET", "/system/vo-requests/missing", "oscar", "", http.StatusNotFound},
+		{"approve without admin", "POST", "/system/vo-requests/" + approved.ID + "/approve", "alice", "", http.StatusForbidden},
+		{"invalid decision", "POST", "/system/vo-requests/" + approved.ID + "/approve", "oscar", `{"quota": {"jobs": -1}}`, http.StatusBadRequest},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if w := request(s.method, s.path, s.user, s.body); w.Code != s.expectedCode {
+				t.Errorf("expected status %d, got %d: %s", s.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The users only list their own requests
+	w := request("GET", "/system/vo-requests", "alice", "")
+	requests := []types.VORequest{}
+	json.Unmarshal(w.Body.Bytes(), &requests)
+	if len(requests) != 1 || requests[0].ID != approved.ID {
+		t.Errorf("unexpected requests of the user: %s", w.Body.String())
+	}
+
+	// The approval enables the VO with the granted quota and provisions its resources
+	if w := request("POST", "/system/vo-requests/"+approved.ID+"/approve", "oscar", `{"quota": {"jobs": 50}, "message": "welcome"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !cfg.IsAllowedGroup("vo.new.eu") || cfg.GetQuotas()[types.QuotaScopeVO]["vo.new.eu"].Jobs != 50 {
+		t.Errorf("the VO has not been enabled with its quota: %v", types.GetApprovedVOs())
+	}
+	if _, err := kubeClientset.CoreV1().Namespaces().Get(context.TODO(), types.VONamespaceName("vo.new.eu"), metav1.GetOptions{}); err != nil {
+		t.Errorf("the namespace of the VO has not been provisioned: %v", err)
+	}
+	if !s3Server.HasBucket(types.VOBucketName("vo.new.eu")) {
+		t.Error("the bucket of the VO has not been provisioned")
+	}
+
+	// The rejected VOs are not enabled
+	if w := request("POST", "/system/vo-requests/"+rejected.ID+"/reject", "oscar", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if cfg.IsAllowedGroup("vo.other.eu") {
+		t.Error("the rejected VO has been enabled")
+	}
+
+	// The decisions are final
+	if w := request("POST", "/system/vo-requests/"+rejected.ID+"/approve", "oscar", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	w = request("GET", "/system/vo-requests?status=approved", "oscar", "")
+	requests = []types.VORequest{}
+	json.Unmarshal(w.Body.Bytes(), &requests)
+	if len(requests) != 1 || requests[0].DecidedBy != "oscar" || requests[0].Message != "welcome" || requests[0].Quota.Jobs != 50 {
+		t.Errorf("unexpected approved requests: %s", w.Body.String())
+	}
+}
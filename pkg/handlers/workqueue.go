@@ -0,0 +1,129 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MakeQueueDepthHandler makes a handler returning the number of queued events of a service with workers, polled by
+// KEDA to scale them. Authenticated with the queue token of the service (as bearer token or "token" parameter)
+func MakeQueueDepthHandler(back types.ServerlessBackend, dispatcher *EventDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, ok := readQueueService(c, back)
+		if !ok {
+			return
+		}
+		depth, err := utils.GetQueueDepth(dispatcher.kubeClientset, dispatcher.cfg.ServicesNamespace, service.Name)
+		if err != nil {
+			sendError(c, types.ErrInternal, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, types.QueueDepth{Service: service.Name, Depth: depth})
+	}
+}
+
+// MakeQueueNextHandler makes a handler leasing the oldest queued event of a service with workers to the worker
+// requesting it (with its lease ID in the "lease" parameter) until the queue is empty (204 No Content). The event
+// must be acknowledged before the visibility timeout of the service, or it is returned to the queue
+func MakeQueueNextHandler(back types.ServerlessBackend, dispatcher *EventDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, lease, ok := readQueueLease(c, back)
+		if !ok {
+			return
+		}
+		event, ok, err := utils.LeaseQueuedEvent(dispatcher.kubeClientset, dispatcher.cfg.ServicesNamespace, service, lease)
+		if err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+		if !ok {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", []byte(event))
+	}
+}
+
+// MakeQueueAckHandler makes a handler to acknowledge an event leased by a worker of a service, removing it from
+// the queue, or to return it to the queue with "requeue=true" (its processing failed)
+func MakeQueueAckHandler(back types.ServerlessBackend, dispatcher *EventDispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requeue, err := strconv.ParseBool(c.DefaultQuery(types.QueueRequeueParam, "false"))
+		if err != nil {
+			sendError(c, types.ErrBadRequest, fmt.Sprintf("Invalid requeue value: %v", err))
+			return
+		}
+		service, lease, ok := readQueueLease(c, back)
+		if !ok {
+			return
+		}
+		if err := utils.AckQueuedEvent(dispatcher.kubeClientset, dispatcher.cfg.ServicesNamespace, service, lease, requeue); err != nil {
+			sendCodedError(c, err, types.ErrInternal)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// readQueueLease reads the service and the lease ID of a request of a worker to its queue. Sends the error and
+// returns false if the service is not found, the token is not valid or the lease ID is missing
+func readQueueLease(c *gin.Context, back types.ServerlessBackend) (*types.Service, string, bool) {
+	service, ok := readQueueService(c, back)
+	if !ok {
+		return nil, "", false
+	}
+	lease := c.Query(types.QueueLeaseParam)
+	if !types.IsValidQueueLease(lease) {
+		sendError(c, types.ErrBadRequest, fmt.Sprintf("The \"%s\" parameter must be an ID of up to 64 letters, digits or hyphens", types.QueueLeaseParam))
+		return nil, "", false
+	}
+	return service, lease, true
+}
+
+// readQueueService reads the service of a request to its queue, checking its queue token. Sends the error and returns
+// false if the service is not found or the token is not valid
+func readQueueService(c *gin.Context, back types.ServerlessBackend) (*types.Service, bool) {
+	service, err := back.ReadService(c.Param("serviceName"))
+	if err != nil {
+		// Check if error is caused because the service is not found
+		if errors.IsNotFound(err) || errors.IsGone(err) {
+			sendError(c, types.ErrServiceNotFound, "")
+		} else {
+			sendError(c, types.ErrServiceReadFailed, err.Error())
+		}
+		return nil, false
+	}
+
+	token := c.Query(types.QueueTokenParam)
+	if splitToken := strings.Split(c.GetHeader("Authorization"), "Bearer "); len(splitToken) == 2 {
+		token = strings.TrimSpace(splitToken[1])
+	}
+	if !service.HasWorkers() || !service.CheckQueueToken(token) {
+		sendError(c, types.ErrUnauthorized, "")
+		return nil, false
+	}
+	return service, true
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/backends"
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestQueueHandlers(t *testing.T) {
+	service := types.Service{Name: "test", Image: "busybox", Token: "token", Workers: &types.WorkerPool{}}
+	back := backends.MakeMemoryBackend()
+	back.CreateService(service)
+	back.CreateService(types.Service{Name: "jobs", Image: "busybox", Token: "token"})
+
+	kubeClientset := testclient.NewSimpleClientset()
+	d := MakeEventDispatcher(&testConfigValidRun, kubeClientset, nil)
+	status, _, err := d.dispatch(&service, []byte(`{"a":1}`))
+	if err != nil || status != eventQueued {
+		t.Fatalf("expecting the event queued, got %d (%v)", status, err)
+	}
+	jobs, _ := kubeClientset.BatchV1().Jobs(testConfigValidRun.ServicesNamespace).List(context.TODO(), metav1.ListOptions{})
+	if len(jobs.Items) != 0 {
+		t.Errorf("expecting no jobs, got %d", len(jobs.Items))
+	}
+
+	r := gin.New()
+	r.GET("/queue/:serviceName", MakeQueueDepthHandler(back, d))
+	r.POST("/queue/:serviceName/next", MakeQueueNextHandler(back, d))
+	r.POST("/queue/:serviceName/ack", MakeQueueAckHandler(back, d))
+
+	scenarios := []struct {
+		name     string
+		method   string
+		path     string
+		bearer   bool
+		status   int
+		expected string
+	}{
+		{"depth without token", http.MethodGet, "/queue/test", false, http.StatusUnauthorized, ""},
+		{"service without workers", http.MethodGet, "/queue/jobs?token=" + service.GetQueueToken(), false, http.StatusUnauthorized, ""},
+		{"depth", http.MethodGet, "/queue/test?token=" + service.GetQueueToken(), false, http.StatusOK, `{"service":"test","depth":1}`},
+		{"next without lease", http.MethodPost, "/queue/test/next", true, http.StatusBadRequest, ""},
+		{"next", http.MethodPost, "/queue/test/next?lease=worker-1", true, http.StatusOK, `{"a":1}`},
+		{"depth with the event leased", http.MethodGet, "/queue/test?token=" + service.GetQueueToken(), false, http.StatusOK, `{"service":"test","depth":0}`},
+		{"empty queue", http.MethodPost, "/queue/test/next?lease=worker-2", true, http.StatusNoContent, ""},
+		{"requeue", http.MethodPost, "/queue/test/ack?lease=worker-1&requeue=true", true, http.StatusNoContent, ""},
+		{"next after requeue", http.MethodPost, "/queue/test/next?lease=worker-2", true, http.StatusOK, `{"a":1}`},
+		{"ack of a requeued lease", http.MethodPost, "/queue/test/ack?lease=worker-1", true, http.StatusNotFound, ""},
+		{"ack", http.MethodPost, "/queue/test/ack?lease=worker-2", true, http.StatusNoContent, ""},
+		{"empty queue after ack", http.MethodPost, "/queue/test/next?lease=worker-3", true, http.StatusNoContent, ""},
+		{"not found", http.MethodPost, "/queue/other/next?lease=worker-1", true, http.StatusNotFound, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(s.method, s.path, nil)
+			if s.bearer {
+				req.Header.Set("Authorization", "Bearer "+service.GetQueueToken())
+			}
+			r.ServeHTTP(w, req)
+			if w.Code != s.status {
+				t.Fatalf("expecting status %d, got %d: %s", s.status, w.Code, w.Body.String())
+			}
+			if s.expected != "" {
+				var expected, got interface{}
+				json.Unmarshal([]byte(s.expected), &expected)
+				json.Unmarshal(w.Body.Bytes(), &got)
+				if !reflect.DeepEqual(expected, got) {
+					t.Errorf("expecting body %s, got %s", s.expected, w.Body.String())
+				}
+			}
+		})
+	}
+}
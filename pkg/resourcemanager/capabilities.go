@@ -0,0 +1,173 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// gpuProductLabel label of the GPU model set by the NVIDIA GPU Feature Discovery
+	gpuProductLabel = "nvidia.com/gpu.product"
+	// cpuFeatureLabelPrefix prefix of the labels of the CPU flags set by the Node Feature Discovery
+	cpuFeatureLabelPrefix = "feature.node.kubernetes.io/cpu-cpuid."
+	// hugepagesResourcePrefix prefix of the hugepages resources of the nodes
+	hugepagesResourcePrefix = "hugepages-"
+)
+
+// ListCapabilities returns the hardware capabilities of the ready working nodes of the cluster, sorted by type and name
+func ListCapabilities(kubeClientset kubernetes.Interface) ([]types.Capability, error) {
+	nodes, err := listCapableNodes(kubeClientset)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*types.Capability{}
+	for _, node := range nodes {
+		for _, capability := range getNodeCapabilities(node) {
+			if _, ok := byName[capability.Name]; !ok {
+				byName[capability.Name] = &types.Capability{Name: capability.Name, Type: capability.Type, Nodes: []string{}}
+			}
+			byName[capability.Name].Nodes = append(byName[capability.Name].Nodes, node.Name)
+		}
+	}
+
+	capabilities := []types.Capability{}
+	for _, capability := range byName {
+		sort.Strings(capability.Nodes)
+		capabilities = append(capabilities, *capability)
+	}
+	sort.Slice(capabilities, func(i, j int) bool {
+		if capabilities[i].Type != capabilities[j].Type {
+			return capabilities[i].Type < capabilities[j].Type
+		}
+		return capabilities[i].Name < capabilities[j].Name
+	})
+	return capabilities, nil
+}
+
+// CheckRequirements checks that at least one ready working node of the cluster provides all the capabilities
+// required by a service, so its pods can be scheduled
+func CheckRequirements(kubeClientset kubernetes.Interface, requires []string) error {
+	if len(requires) == 0 {
+		return nil
+	}
+
+	nodes, err := listCapableNodes(kubeClientset)
+	if err != nil {
+		return err
+	}
+
+	provided := map[string]bool{}
+	for _, node := range nodes {
+		capabilities := getNodeCapabilities(node)
+		satisfiesAll := true
+		for _, requirement := range requires {
+			if nodeSatisfies(capabilities, requirement) {
+				provided[requirement] = true
+			} else {
+				satisfiesAll = false
+			}
+		}
+		if satisfiesAll {
+			return nil
+		}
+	}
+
+	for _, requirement := range requires {
+		if !provided[requirement] {
+			return fmt.Errorf("the required capability \"%s\" is not provided by any node of the cluster", requirement)
+		}
+	}
+	return fmt.Errorf("no node of the cluster provides all the required capabilities (%s)", strings.Join(requires, ", "))
+}
+
+// listCapableNodes returns the schedulable and ready working nodes of the cluster
+func listCapableNodes(kubeClientset kubernetes.Interface) ([]v1.Node, error) {
+	nodes, err := kubeClientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: "!node-role.kubernetes.io/control-plane,!node-role.kubernetes.io/master"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting node list: %v", err)
+	}
+
+	res := []v1.Node{}
+	for _, node := range nodes.Items {
+		if !node.Spec.Unschedulable && isNodeReady(node) {
+			res = append(res, node)
+		}
+	}
+	return res, nil
+}
+
+// getNodeCapabilities returns the capabilities (without nodes) provided by a node
+func getNodeCapabilities(node v1.Node) []types.Capability {
+	capabilities := []types.Capability{}
+
+	if product := node.Labels[gpuProductLabel]; product != "" {
+		if strings.HasPrefix(strings.ToUpper(product), "NVIDIA-") {
+			product = product[len("NVIDIA-"):]
+		}
+		capabilities = append(capabilities, types.Capability{Name: "nvidia-" + product, Type: types.GPUCapability})
+	}
+
+	avx512 := false
+	for label, value := range node.Labels {
+		if !strings.HasPrefix(label, cpuFeatureLabelPrefix) || value != "true" {
+			continue
+		}
+		flag := strings.ToLower(strings.TrimPrefix(label, cpuFeatureLabelPrefix))
+		capabilities = append(capabilities, types.Capability{Name: flag, Type: types.CPUCapability})
+		avx512 = avx512 || strings.HasPrefix(flag, "avx512")
+	}
+	// The AVX-512 extensions are also grouped as "avx512"
+	if avx512 {
+		capabilities = append(capabilities, types.Capability{Name: "avx512", Type: types.CPUCapability})
+	}
+
+	for name, quantity := range node.Status.Allocatable {
+		if strings.HasPrefix(string(name), hugepagesResourcePrefix) && !quantity.IsZero() {
+			capabilities = append(capabilities, types.Capability{Name: string(name), Type: types.HugepagesCapability})
+		}
+	}
+
+	arch := node.Status.NodeInfo.Architecture
+	if arch == "" {
+		arch = node.Labels[v1.LabelArchStable]
+	}
+	if arch != "" {
+		capabilities = append(capabilities, types.Capability{Name: arch, Type: types.ArchCapability})
+	}
+
+	return capabilities
+}
+
+// nodeSatisfies checks if any of the capabilities of a node satisfies the requirement
+func nodeSatisfies(capabilities []types.Capability, requirement string) bool {
+	for _, capability := range capabilities {
+		if types.MatchesCapability(requirement, capability.Name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemanager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testCapabilitiesClientset() *fake.Clientset {
+	makeNode := func(name string, ready bool, labels map[string]string, allocatable v1.ResourceList) *v1.Node {
+		status := v1.ConditionTrue
+		if !ready {
+			status = v1.ConditionFalse
+		}
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status: v1.NodeStatus{
+				Allocatable: allocatable,
+				Conditions:  []v1.NodeCondition{{Type: v1.NodeReady, Status: status}},
+				NodeInfo:    v1.NodeSystemInfo{Architecture: "amd64"},
+			},
+		}
+	}
+
+	return fake.NewSimpleClientset(
+		makeNode("gpu-node", true, map[string]string{
+			"nvidia.com/gpu.product":                       "NVIDIA-A100-SXM4-40GB",
+			"feature.node.kubernetes.io/cpu-cpuid.AVX512F": "true",
+		}, v1.ResourceList{"hugepages-2Mi": resource.MustParse("1Gi"), "hugepages-1Gi": resource.MustParse("0")}),
+		makeNode("cpu-node", true, map[string]string{
+			"feature.node.kubernetes.io/cpu-cpuid.AVX512F":  "true",
+			"feature.node.kubernetes.io/cpu-cpuid.AVX512BW": "true",
+		}, nil),
+		makeNode("not-ready", false, map[string]string{"nvidia.com/gpu.product": "Tesla-T4"}, nil),
+		makeNode("control-plane", true, map[string]string{
+			"node-role.kubernetes.io/control-plane": "",
+			"nvidia.com/gpu.product":                "Tesla-V100",
+		}, nil),
+	)
+}
+
+func TestListCapabilities(t *testing.T) {
+	capabilities, err := ListCapabilities(testCapabilitiesClientset())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []types.Capability{
+		{Name: "amd64", Type: types.ArchCapability, Nodes: []string{"cpu-node", "gpu-node"}},
+		{Name: "avx512", Type: types.CPUCapability, Nodes: []string{"cpu-node", "gpu-node"}},
+		{Name: "avx512bw", Type: types.CPUCapability, Nodes: []string{"cpu-node"}},
+		{Name: "avx512f", Type: types.CPUCapability, Nodes: []string{"cpu-node", "gpu-node"}},
+		{Name: "nvidia-A100-SXM4-40GB", Type: types.GPUCapability, Nodes: []string{"gpu-node"}},
+		{Name: "hugepages-2Mi", Type: types.HugepagesCapability, Nodes: []string{"gpu-node"}},
+	}
+	if !reflect.DeepEqual(capabilities, expected) {
+		t.Errorf("expecting capabilities %v, got %v", expected, capabilities)
+	}
+}
+
+func TestCheckRequirements(t *testing.T) {
+	kubeClientset := testCapabilitiesClientset()
+
+	scenarios := []struct {
+		name        string
+		requires    []string
+		returnError bool
+	}{
+		{"no requirements", nil, false},
+		{"gpu model prefix", []string{"nvidia-a100", "avx512"}, false},
+		{"hugepages", []string{"hugepages"}, false},
+		{"same node", []string{"nvidia-A100", "avx512bw"}, true},
+		{"not ready node", []string{"nvidia-Tesla-T4"}, true},
+		{"control plane", []string{"nvidia-Tesla-V100"}, true},
+		{"partial name", []string{"avx"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := CheckRequirements(kubeClientset, s.requires); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
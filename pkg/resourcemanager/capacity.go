@@ -0,0 +1,159 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemanager
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	"github.com/grycap/oscar/v2/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// localCapacityKey key of the current cluster in the capacityCache
+const localCapacityKey = ""
+
+type cachedCapacity struct {
+	capacity *types.ClusterCapacity
+	expires  time.Time
+}
+
+// capacityCache map to store the capacity of the clusters -> [CLUSTER_ENDPOINT]
+var capacityCache = map[string]cachedCapacity{}
+var capacityMutex sync.Mutex
+
+// GetLocalCapacity returns the capacity of the current cluster.
+// The available resources are obtained from the ResourceManager (if enabled) or listing the nodes otherwise
+func GetLocalCapacity(cfg *types.Config, kubeClientset kubernetes.Interface, rm ResourceManager) (*types.ClusterCapacity, error) {
+	capacity := &types.ClusterCapacity{
+		Zone:            cfg.ClusterZone,
+		CarbonIntensity: utils.GetCarbonIntensity(cfg),
+	}
+
+	if rm != nil {
+		capacity.FreeCPU, capacity.FreeMemory = rm.FreeResources()
+	} else {
+		res, err := listNodeResources(kubeClientset)
+		if err != nil {
+			return nil, err
+		}
+		capacity.FreeCPU, capacity.FreeMemory = sumNodeResources(res)
+	}
+
+	// Queue depth (only jobs' pods are taken into account)
+	pods, err := kubeClientset.CoreV1().Pods(cfg.GetJobsNamespace()).List(context.TODO(), metav1.ListOptions{LabelSelector: fmt.Sprintf("%s,job-name", types.ServiceLabel)})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pod list: %v", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodPending {
+			capacity.PendingJobs++
+		}
+	}
+
+	return capacity, nil
+}
+
+// getCachedCapacity returns the capacity stored in the capacityCache or fetches (and stores) it if expired
+func getCachedCapacity(key string, ttl time.Duration, fetch func() (*types.ClusterCapacity, error)) (*types.ClusterCapacity, error) {
+	capacityMutex.Lock()
+	cached, ok := capacityCache[key]
+	capacityMutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.capacity, nil
+	}
+
+	capacity, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	capacityMutex.Lock()
+	capacityCache[key] = cachedCapacity{capacity: capacity, expires: time.Now().Add(ttl)}
+	capacityMutex.Unlock()
+
+	return capacity, nil
+}
+
+// getClusterCapacity returns the capacity reported by a replica's cluster
+func getClusterCapacity(cluster types.Cluster) (*types.ClusterCapacity, error) {
+	// Parse the cluster's endpoint URL and add the capacity path
+	capacityURL, err := url.Parse(cluster.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse cluster endpoint \"%s\": %v", cluster.Endpoint, err)
+	}
+	capacityURL.Path = path.Join(capacityURL.Path, "system", "capacity")
+
+	req, err := http.NewRequest(http.MethodGet, capacityURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to make request to cluster endpoint \"%s\": %v", cluster.Endpoint, err)
+	}
+
+	// Add cluster's basic auth credentials
+	req.SetBasicAuth(cluster.AuthUser, cluster.AuthPassword)
+
+	// Make HTTP client (with a short timeout, as the job is waiting to be delegated)
+	var transport http.RoundTripper = &http.Transport{
+		// Enable/disable SSL verification
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: !cluster.SSLVerify},
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Second * 5,
+	}
+
+	// Send the request
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request to cluster endpoint \"%s\": %v", cluster.Endpoint, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error in response from cluster endpoint \"%s\": Status code %d", cluster.Endpoint, res.StatusCode)
+	}
+
+	var capacity *types.ClusterCapacity
+	if err := json.NewDecoder(res.Body).Decode(&capacity); err != nil {
+		return nil, fmt.Errorf("error decoding capacity from cluster endpoint \"%s\": %v", cluster.Endpoint, err)
+	}
+
+	return capacity, nil
+}
+
+// getReplicaCapacity returns the (cached) capacity of the cluster of a replica of type "oscar"
+func getReplicaCapacity(cfg *types.Config, service *types.Service, replica types.Replica) (*types.ClusterCapacity, error) {
+	cluster, ok := service.Clusters[replica.ClusterID]
+	if !ok {
+		return nil, fmt.Errorf("cluster \"%s\" not defined", replica.ClusterID)
+	}
+
+	return getCachedCapacity(strings.Trim(cluster.Endpoint, " /"), cfg.DelegationMetricsTTL, func() (*types.ClusterCapacity, error) {
+		return getClusterCapacity(cluster)
+	})
+}
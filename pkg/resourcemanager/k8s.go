@@ -31,6 +31,8 @@ type nodeResources struct {
 	memory int64
 	// cpu in MilliValue, as returned by quantity.MilliValue()
 	cpu int64
+	// labels of the node, used to match node selectors
+	labels map[string]string
 }
 
 // KubeResourceManager struct to represent the Kubernetes resource manager
@@ -42,28 +44,9 @@ type KubeResourceManager struct {
 
 // UpdateResources update the available resources in the cluster
 func (krm *KubeResourceManager) UpdateResources() error {
-	// List all (working) nodes
-	nodes, err := krm.kubeClientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: "!node-role.kubernetes.io/control-plane,!node-role.kubernetes.io/master"})
+	res, err := listNodeResources(krm.kubeClientset)
 	if err != nil {
-		return fmt.Errorf("error getting node list: %v", err)
-	}
-
-	// Get list all Running pods
-	pods, err := krm.kubeClientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{FieldSelector: "status.phase!=Succeeded,status.phase!=Failed"})
-	if err != nil {
-		return fmt.Errorf("error getting pod list: %v", err)
-	}
-
-	// Define new nodeResources slice
-	res := []nodeResources{}
-
-	for _, node := range nodes.Items {
-		// Only count Schedulable and Ready nodes
-		if !node.Spec.Unschedulable && isNodeReady(node) {
-			nodeCPU, nodeMemory := getNodeAvailableResources(node, pods)
-			nodeRes := nodeResources{memory: nodeMemory, cpu: nodeCPU}
-			res = append(res, nodeRes)
-		}
+		return err
 	}
 
 	// Ensure mutual exclusion
@@ -77,6 +60,11 @@ func (krm *KubeResourceManager) UpdateResources() error {
 
 // IsSchedulable check if a Service's v1.ResourceRequirements can be scheduled in the cluster
 func (krm *KubeResourceManager) IsSchedulable(resources v1.ResourceRequirements) bool {
+	return krm.IsSchedulableOnNodes(resources, nil)
+}
+
+// IsSchedulableOnNodes check if a Service's v1.ResourceRequirements can be scheduled in the nodes matching the nodeSelector
+func (krm *KubeResourceManager) IsSchedulableOnNodes(resources v1.ResourceRequirements, nodeSelector map[string]string) bool {
 	serviceMemory := resources.Limits.Memory().Value()
 	serviceCPU := resources.Limits.Cpu().MilliValue()
 
@@ -86,6 +74,9 @@ func (krm *KubeResourceManager) IsSchedulable(resources v1.ResourceRequirements)
 
 	// Check if the job can be scheduled at least in one node
 	for _, nodeRes := range krm.resources {
+		if !matchesNodeSelector(nodeRes.labels, nodeSelector) {
+			continue
+		}
 		if serviceMemory < nodeRes.memory && serviceCPU < nodeRes.cpu {
 			return true
 		}
@@ -94,6 +85,61 @@ func (krm *KubeResourceManager) IsSchedulable(resources v1.ResourceRequirements)
 	return false
 }
 
+// FreeResources returns the available CPU (in millicores) and memory (in bytes) in all the cluster's nodes
+func (krm *KubeResourceManager) FreeResources() (cpu int64, memory int64) {
+	// Ensure mutual exclusion
+	krm.mutex.Lock()
+	defer krm.mutex.Unlock()
+
+	return sumNodeResources(krm.resources)
+}
+
+// listNodeResources returns the available resources in the working nodes of the cluster
+func listNodeResources(kubeClientset kubernetes.Interface) ([]nodeResources, error) {
+	// List all (working) nodes
+	nodes, err := kubeClientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: "!node-role.kubernetes.io/control-plane,!node-role.kubernetes.io/master"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting node list: %v", err)
+	}
+
+	// Get list all Running pods
+	pods, err := kubeClientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{FieldSelector: "status.phase!=Succeeded,status.phase!=Failed"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pod list: %v", err)
+	}
+
+	// Define new nodeResources slice
+	res := []nodeResources{}
+
+	for _, node := range nodes.Items {
+		// Only count Schedulable and Ready nodes
+		if !node.Spec.Unschedulable && isNodeReady(node) {
+			nodeCPU, nodeMemory := getNodeAvailableResources(node, pods)
+			nodeRes := nodeResources{memory: nodeMemory, cpu: nodeCPU, labels: node.Labels}
+			res = append(res, nodeRes)
+		}
+	}
+
+	return res, nil
+}
+
+func sumNodeResources(resources []nodeResources) (cpu int64, memory int64) {
+	for _, nodeRes := range resources {
+		cpu += nodeRes.cpu
+		memory += nodeRes.memory
+	}
+	return
+}
+
+func matchesNodeSelector(labels map[string]string, nodeSelector map[string]string) bool {
+	for k, v := range nodeSelector {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
 func getNodeAvailableResources(node v1.Node, pods *v1.PodList) (cpu int64, memory int64) {
 	// Get allocatable resources from node status
 	memory = node.Status.Allocatable.Memory().Value()
@@ -233,3 +233,37 @@ func TestIsSchedulable(t *testing.T) {
 		}
 	})
 }
+
+func TestIsSchedulableOnNodes(t *testing.T) {
+	krm := KubeResourceManager{
+		resources: []nodeResources{
+			{memory: 1000000000, cpu: 1000, labels: map[string]string{"tier": "edge"}},
+			{memory: 8000000000, cpu: 8000, labels: map[string]string{"tier": "cloud"}},
+		},
+	}
+	resources := v1.ResourceRequirements{
+		Limits: v1.ResourceList{
+			"memory": *resource.NewQuantity(2*1024*1024*1024, resource.BinarySI),
+			"cpu":    *resource.NewMilliQuantity(500, resource.DecimalSI),
+		},
+	}
+
+	scenarios := []struct {
+		name         string
+		nodeSelector map[string]string
+		expected     bool
+	}{
+		{"any node", nil, true},
+		{"full tier", map[string]string{"tier": "edge"}, false},
+		{"tier with resources", map[string]string{"tier": "cloud"}, true},
+		{"no matching nodes", map[string]string{"tier": "fog"}, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if res := krm.IsSchedulableOnNodes(resources, s.nodeSelector); res != s.expected {
+				t.Errorf("expected %v, got %v", s.expected, res)
+			}
+		})
+	}
+}
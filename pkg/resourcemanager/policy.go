@@ -0,0 +1,176 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemanager
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DelegationTarget cluster where the job of a service with replicas can be run
+type DelegationTarget struct {
+	// Replica replica to delegate the job (nil for the current cluster)
+	Replica *types.Replica
+	// Capacity metrics of the target's cluster (nil if unknown)
+	Capacity *types.ClusterCapacity
+}
+
+// IsLocal checks if the target is the current cluster
+func (target DelegationTarget) IsLocal() bool {
+	return target.Replica == nil
+}
+
+// priority returns the delegation priority of the target (the current cluster has the highest one)
+func (target DelegationTarget) priority() uint {
+	if target.IsLocal() {
+		return 0
+	}
+	return target.Replica.Priority
+}
+
+// DelegationPolicy interface to define the policies that rank the targets of a job
+type DelegationPolicy interface {
+	// Less reports whether the target a must be tried before the target b
+	Less(a DelegationTarget, b DelegationTarget, local *types.ClusterCapacity) bool
+}
+
+// StaticPolicy ranks the targets by priority
+type StaticPolicy struct{}
+
+// Less implements DelegationPolicy
+func (StaticPolicy) Less(a DelegationTarget, b DelegationTarget, local *types.ClusterCapacity) bool {
+	return a.priority() < b.priority()
+}
+
+// LeastLoadedPolicy ranks the targets by pending jobs and free CPU (targets with unknown capacity are the last ones)
+type LeastLoadedPolicy struct{}
+
+// Less implements DelegationPolicy
+func (LeastLoadedPolicy) Less(a DelegationTarget, b DelegationTarget, local *types.ClusterCapacity) bool {
+	if (a.Capacity == nil) != (b.Capacity == nil) {
+		return a.Capacity != nil
+	}
+	if a.Capacity != nil {
+		if a.Capacity.PendingJobs != b.Capacity.PendingJobs {
+			return a.Capacity.PendingJobs < b.Capacity.PendingJobs
+		}
+		if a.Capacity.FreeCPU != b.Capacity.FreeCPU {
+			return a.Capacity.FreeCPU > b.Capacity.FreeCPU
+		}
+	}
+	return a.priority() < b.priority()
+}
+
+// DataLocalityPolicy ranks first the targets in the zone of the current cluster, where the input data is stored
+type DataLocalityPolicy struct{}
+
+// Less implements DelegationPolicy
+func (DataLocalityPolicy) Less(a DelegationTarget, b DelegationTarget, local *types.ClusterCapacity) bool {
+	aLocal, bLocal := inZone(a, local), inZone(b, local)
+	if aLocal != bLocal {
+		return aLocal
+	}
+	return a.priority() < b.priority()
+}
+
+func inZone(target DelegationTarget, local *types.ClusterCapacity) bool {
+	if target.IsLocal() {
+		return true
+	}
+	return target.Capacity != nil && local != nil && local.Zone != "" && target.Capacity.Zone == local.Zone
+}
+
+// EnergyAwarePolicy ranks the targets by carbon intensity (targets with unknown carbon intensity are the last ones)
+type EnergyAwarePolicy struct{}
+
+// Less implements DelegationPolicy
+func (EnergyAwarePolicy) Less(a DelegationTarget, b DelegationTarget, local *types.ClusterCapacity) bool {
+	aIntensity, bIntensity := carbonIntensity(a), carbonIntensity(b)
+	if (aIntensity == 0) != (bIntensity == 0) {
+		return aIntensity != 0
+	}
+	if aIntensity != bIntensity {
+		return aIntensity < bIntensity
+	}
+	return a.priority() < b.priority()
+}
+
+func carbonIntensity(target DelegationTarget) float64 {
+	if target.Capacity == nil {
+		return 0
+	}
+	return target.Capacity.CarbonIntensity
+}
+
+// GetDelegationPolicy returns the DelegationPolicy implementing the provided policy name ("static" by default)
+func GetDelegationPolicy(name string) DelegationPolicy {
+	switch name {
+	case types.LeastLoadedDelegationPolicy:
+		return LeastLoadedPolicy{}
+	case types.DataLocalityDelegationPolicy:
+		return DataLocalityPolicy{}
+	case types.EnergyAwareDelegationPolicy:
+		return EnergyAwarePolicy{}
+	default:
+		return StaticPolicy{}
+	}
+}
+
+// SortTargets sorts the targets following the provided policy. Ties keep the original order
+func SortTargets(targets []DelegationTarget, policy DelegationPolicy, local *types.ClusterCapacity) {
+	sort.SliceStable(targets, func(i, j int) bool {
+		return policy.Less(targets[i], targets[j], local)
+	})
+}
+
+// RankTargets returns the targets where the job of a service can be run, ordered following the service's
+// delegation policy. The current cluster is only included if the job can be scheduled in it
+func RankTargets(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, resources v1.ResourceRequirements, rm ResourceManager, logger *log.Logger) []DelegationTarget {
+	targets := []DelegationTarget{}
+
+	local, err := getCachedCapacity(localCapacityKey, cfg.DelegationMetricsTTL, func() (*types.ClusterCapacity, error) {
+		return GetLocalCapacity(cfg, kubeClientset, rm)
+	})
+	if err != nil {
+		logger.Printf("Error getting the capacity of the current cluster: %v\n", err)
+	}
+	if rm == nil || rm.IsSchedulable(resources) {
+		targets = append(targets, DelegationTarget{Capacity: local})
+	}
+
+	for i := range service.Replicas {
+		replica := service.Replicas[i]
+		target := DelegationTarget{Replica: &replica}
+		// Only the replicas of type "oscar" report their capacity
+		if strings.ToLower(replica.Type) == oscarReplicaType {
+			target.Capacity, err = getReplicaCapacity(cfg, service, replica)
+			if err != nil {
+				logger.Printf("Error getting the capacity of ClusterID \"%s\" for service \"%s\": %v\n", replica.ClusterID, service.Name, err)
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	SortTargets(targets, GetDelegationPolicy(service.GetDelegationPolicy()), local)
+
+	return targets
+}
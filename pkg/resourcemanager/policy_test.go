@@ -0,0 +1,146 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemanager
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSortTargets(t *testing.T) {
+	local := &types.ClusterCapacity{FreeCPU: 1000, PendingJobs: 5, Zone: "eu", CarbonIntensity: 300}
+	targets := []DelegationTarget{
+		{Capacity: local},
+		{Replica: &types.Replica{ClusterID: "busy", Priority: 1}, Capacity: &types.ClusterCapacity{FreeCPU: 8000, PendingJobs: 10, Zone: "eu", CarbonIntensity: 50}},
+		{Replica: &types.Replica{ClusterID: "idle", Priority: 2}, Capacity: &types.ClusterCapacity{FreeCPU: 4000, PendingJobs: 0, Zone: "us", CarbonIntensity: 100}},
+		{Replica: &types.Replica{ClusterID: "unknown", Priority: 0}},
+	}
+
+	scenarios := []struct {
+		policy   string
+		expected []string
+	}{
+		{types.StaticDelegationPolicy, []string{"", "unknown", "busy", "idle"}},
+		{types.LeastLoadedDelegationPolicy, []string{"idle", "", "busy", "unknown"}},
+		{types.DataLocalityDelegationPolicy, []string{"", "busy", "unknown", "idle"}},
+		{types.EnergyAwareDelegationPolicy, []string{"busy", "idle", "", "unknown"}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.policy, func(t *testing.T) {
+			sorted := make([]DelegationTarget, len(targets))
+			copy(sorted, targets)
+			SortTargets(sorted, GetDelegationPolicy(s.policy), local)
+
+			for i, target := range sorted {
+				id := ""
+				if !target.IsLocal() {
+					id = target.Replica.ClusterID
+				}
+				if id != s.expected[i] {
+					t.Errorf("expected target %d to be \"%s\", got \"%s\"", i, s.expected[i], id)
+				}
+			}
+		})
+	}
+}
+
+func TestRankTargets(t *testing.T) {
+	capacityCache = map[string]cachedCapacity{}
+	logger := log.New(os.Stdout, "[TEST] ", log.Flags())
+
+	makeServer := func(capacity types.ClusterCapacity) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/system/capacity" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(capacity)
+		}))
+	}
+	green := makeServer(types.ClusterCapacity{CarbonIntensity: 20})
+	defer green.Close()
+	brown := makeServer(types.ClusterCapacity{CarbonIntensity: 500})
+	defer brown.Close()
+
+	cfg := &types.Config{ServicesNamespace: "oscar-svc", CarbonIntensity: 200, DelegationMetricsTTL: time.Minute}
+	service := &types.Service{
+		Name:             "test",
+		DelegationPolicy: types.EnergyAwareDelegationPolicy,
+		Replicas: types.ReplicaList{
+			{Type: "oscar", ClusterID: "brown", ServiceName: "test"},
+			{Type: "oscar", ClusterID: "green", ServiceName: "test", Priority: 1},
+			{Type: "endpoint", URL: "http://example.com/job"},
+		},
+		Clusters: map[string]types.Cluster{
+			"brown": {Endpoint: brown.URL},
+			"green": {Endpoint: green.URL},
+		},
+	}
+	resources := v1.ResourceRequirements{
+		Limits: v1.ResourceList{
+			"memory": *resource.NewQuantity(1024*1024*1024, resource.BinarySI),
+			"cpu":    *resource.NewMilliQuantity(500, resource.DecimalSI),
+		},
+	}
+
+	t.Run("schedulable", func(t *testing.T) {
+		rm := &KubeResourceManager{resources: []nodeResources{{memory: 8000000000, cpu: 8000}}}
+		targets := RankTargets(cfg, fake.NewSimpleClientset(), service, resources, rm, logger)
+
+		expected := []string{"green", "", "brown", "endpoint"}
+		if len(targets) != len(expected) {
+			t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+		}
+		for i, target := range targets {
+			id := ""
+			if !target.IsLocal() {
+				id = target.Replica.ClusterID
+				if target.Replica.Type == "endpoint" {
+					id = "endpoint"
+				}
+			}
+			if id != expected[i] {
+				t.Errorf("expected target %d to be \"%s\", got \"%s\"", i, expected[i], id)
+			}
+		}
+		if targets[1].Capacity == nil || targets[1].Capacity.FreeCPU != 8000 {
+			t.Errorf("expected the capacity of the current cluster from the resource manager, got %+v", targets[1].Capacity)
+		}
+	})
+
+	t.Run("not schedulable", func(t *testing.T) {
+		rm := &KubeResourceManager{resources: []nodeResources{{memory: 1000, cpu: 100}}}
+		targets := RankTargets(cfg, fake.NewSimpleClientset(), service, resources, rm, logger)
+
+		for _, target := range targets {
+			if target.IsLocal() {
+				t.Error("the current cluster must not be a target if the job can't be scheduled")
+			}
+		}
+	})
+}
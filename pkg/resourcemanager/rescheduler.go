@@ -34,16 +34,17 @@ import (
 var reSchedulerLogger = log.New(os.Stdout, "[RE-SCHEDULER] ", log.Flags())
 
 type reScheduleInfo struct {
-	service *types.Service
-	jobName string
-	event   string
+	service   *types.Service
+	jobName   string
+	namespace string
+	event     string
 }
 
 // StartReScheduler starts the ReScheduler loop to check if there are pending pods exceeding the cfg.ReSchedulerThreshold every cfg.ReSchedulerInterval
 func StartReScheduler(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) {
 	for {
 		// Get ReSchedulable pods
-		pods, err := getReSchedulablePods(kubeClientset, cfg.ServicesNamespace)
+		pods, err := getReSchedulablePods(kubeClientset, cfg.GetJobsNamespace())
 		if err != nil {
 			reSchedulerLogger.Println(err.Error())
 			continue
@@ -64,7 +65,7 @@ func StartReScheduler(cfg *types.Config, back types.ServerlessBackend, kubeClien
 				delOpts := metav1.DeleteOptions{
 					PropagationPolicy: &background,
 				}
-				err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Delete(context.TODO(), rsi.jobName, delOpts)
+				err := kubeClientset.BatchV1().Jobs(rsi.namespace).Delete(context.TODO(), rsi.jobName, delOpts)
 				if err != nil {
 					reSchedulerLogger.Printf("error deleting job \"%s\": %v", rsi.jobName, err)
 				}
@@ -129,9 +130,10 @@ func getReScheduleInfos(pods []v1.Pod, back types.ServerlessBackend) []reSchedul
 		// Check if pod has the "job-name" label
 		if jobName, ok := pod.Labels["job-name"]; ok {
 			rsi = append(rsi, reScheduleInfo{
-				service: svcPtrs[serviceName],
-				event:   getEvent(pod.Spec),
-				jobName: jobName,
+				service:   svcPtrs[serviceName],
+				event:     getEvent(pod.Spec),
+				jobName:   jobName,
+				namespace: pod.Namespace,
 			})
 		}
 
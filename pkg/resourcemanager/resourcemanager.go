@@ -33,6 +33,8 @@ var ResourceManagerLogger = log.New(os.Stdout, "[RESOURCE-MANAGER] ", log.Flags(
 type ResourceManager interface {
 	UpdateResources() error
 	IsSchedulable(v1.ResourceRequirements) bool
+	IsSchedulableOnNodes(v1.ResourceRequirements, map[string]string) bool
+	FreeResources() (cpu int64, memory int64)
 }
 
 // MakeResourceManager returns a new ResourceManager if it is enabled in the config
@@ -0,0 +1,74 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// AliasLabel label key with the name of an invocation alias, set on the configMaps storing the aliases
+	AliasLabel = "oscar_alias"
+
+	// AliasFileName name of the file with the alias definition stored in the alias' configMap
+	AliasFileName = "alias.json"
+
+	// AliasPathPrefix prefix of the invocation paths of the aliases
+	AliasPathPrefix = "/i/"
+)
+
+// aliasRegex DNS-safe names (RFC 1123 labels) allowed for the aliases
+var aliasRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// InvocationAlias short path (/i/{alias}) to invoke a service
+type InvocationAlias struct {
+	// Alias DNS-safe name of the alias (lowercase alphanumeric characters or "-", up to 63 characters)
+	Alias string `json:"alias"`
+	// Service name of the invoked service
+	Service string `json:"service"`
+	// Public allows invoking the service through the alias without the service's token (requires RateLimit)
+	Public bool `json:"public"`
+	// RateLimit maximum number of invocations per minute through the alias (0 for unlimited)
+	RateLimit int `json:"rate_limit,omitempty"`
+	// Sync invokes the service synchronously (/run) instead of creating a job (/job)
+	Sync bool `json:"sync"`
+	// URL invocation path of the alias
+	URL string `json:"url,omitempty"`
+	// CreatedAt time when the alias was registered
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the name and the rate limit of the alias
+func (alias *InvocationAlias) Validate() error {
+	if len(alias.Alias) > 63 || !aliasRegex.MatchString(alias.Alias) {
+		return fmt.Errorf("the alias \"%s\" is not valid, it must consist of up to 63 lowercase alphanumeric characters or '-' and start and end with an alphanumeric character", alias.Alias)
+	}
+	if alias.RateLimit < 0 {
+		return fmt.Errorf("the rate_limit of the alias \"%s\" cannot be negative", alias.Alias)
+	}
+	if alias.Public && alias.RateLimit == 0 {
+		return fmt.Errorf("the public alias \"%s\" requires a rate_limit", alias.Alias)
+	}
+	return nil
+}
+
+// AliasConfigMapName returns the name of the configMap storing an invocation alias
+func AliasConfigMapName(alias string) string {
+	return fmt.Sprintf("oscar-alias-%s", alias)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"time"
+)
+
+const (
+	// APIKeyPrefix prefix of the API keys of the OSCAR manager, distinguishing them from the OIDC tokens and the
+	// access tokens of the services
+	APIKeyPrefix = "oscar-ak-"
+	// APIKeySubjectPrefix prefix of the user of the requests authenticated with an API key (followed by its name)
+	APIKeySubjectPrefix = "apikey:"
+
+	// APIKeysSecretName name of the secret (in the services namespace) storing the API keys
+	APIKeysSecretName = "oscar-api-keys"
+	// APIKeysFileName key of the API keys secret with the keys
+	APIKeysFileName = "apikeys.json"
+)
+
+// APIKey static API key of the OSCAR manager, created by the admins, granting a role until its expiry. Only the hash
+// of the key is stored
+type APIKey struct {
+	// Name name of the key (lowercase alphanumeric characters or "-", up to 63 characters)
+	Name string `json:"name"`
+	// Role role granted to the key ("admin", "operator", "invoker" or "viewer")
+	Role string `json:"role"`
+	// Hash SHA-256 hash of the key
+	Hash string `json:"hash,omitempty"`
+	// Key value of the key, only returned on its creation
+	Key string `json:"key,omitempty"`
+	// CreatedBy user that created the key
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// APIKeyRequest body of the requests to create an API key
+type APIKeyRequest struct {
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Validate checks the name, the role and the expiry of the key
+func (req *APIKeyRequest) Validate(now time.Time) error {
+	if len(req.Name) > 63 || !serviceTokenRegex.MatchString(req.Name) {
+		return fmt.Errorf("the name \"%s\" is not valid, it must consist of up to 63 lowercase alphanumeric characters or '-' and start and end with an alphanumeric character", req.Name)
+	}
+	if !IsValidRole(req.Role) {
+		return fmt.Errorf("the role \"%s\" is not valid, it must be \"%s\", \"%s\", \"%s\" or \"%s\"", req.Role, RoleAdmin, RoleOperator, RoleInvoker, RoleViewer)
+	}
+	if !req.ExpiresAt.After(now) {
+		return fmt.Errorf("the expiry of the key (expires_at) must be a future time")
+	}
+	return nil
+}
+
+// Subject returns the user of the requests authenticated with the key
+func (k APIKey) Subject() string {
+	return APIKeySubjectPrefix + k.Name
+}
+
+// Check checks if a key matches the hash of the API key and has not expired
+func (k APIKey) Check(hash string, now time.Time) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(k.Hash)) == 1 && now.Before(k.ExpiresAt)
+}
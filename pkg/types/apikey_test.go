@@ -0,0 +1,56 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeyRequestValidate(t *testing.T) {
+	now := time.Now()
+	scenarios := []struct {
+		name  string
+		req   APIKeyRequest
+		valid bool
+	}{
+		{"valid", APIKeyRequest{Name: "ci-pipeline", Role: RoleOperator, ExpiresAt: now.Add(time.Hour)}, true},
+		{"invalid name", APIKeyRequest{Name: "CI", Role: RoleOperator, ExpiresAt: now.Add(time.Hour)}, false},
+		{"invalid role", APIKeyRequest{Name: "ci", Role: "root", ExpiresAt: now.Add(time.Hour)}, false},
+		{"no expiry", APIKeyRequest{Name: "ci", Role: RoleViewer}, false},
+		{"past expiry", APIKeyRequest{Name: "ci", Role: RoleViewer, ExpiresAt: now.Add(-time.Hour)}, false},
+	}
+	for _, s := range scenarios {
+		if err := s.req.Validate(now); (err == nil) != s.valid {
+			t.Errorf("%s: expecting valid=%v, got %v", s.name, s.valid, err)
+		}
+	}
+}
+
+func TestAPIKeyCheck(t *testing.T) {
+	now := time.Now()
+	key := APIKey{Name: "ci", Hash: HashServiceToken("oscar-ak-value"), ExpiresAt: now.Add(time.Hour)}
+	if !key.Check(HashServiceToken("oscar-ak-value"), now) {
+		t.Error("expecting the key to be valid")
+	}
+	if key.Check(HashServiceToken("oscar-ak-other"), now) {
+		t.Error("expecting other keys to be rejected")
+	}
+	if key.Check(HashServiceToken("oscar-ak-value"), now.Add(2*time.Hour)) {
+		t.Error("expecting the expired key to be rejected")
+	}
+}
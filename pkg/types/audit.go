@@ -0,0 +1,123 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sinks of the audit log
+const (
+	AuditSinkFile   = "file"
+	AuditSinkMinIO  = "minio"
+	AuditSinkSyslog = "syslog"
+)
+
+// Operations recorded in the audit log
+const (
+	AuditOperationCreate = "create"
+	AuditOperationUpdate = "update"
+	AuditOperationDelete = "delete"
+	AuditOperationRun    = "run"
+)
+
+// Results of the operations recorded in the audit log
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// AuditServiceTokenSubject subject of the invocations authenticated with the token of the service
+const AuditServiceTokenSubject = "service-token"
+
+// Limits of the entries returned by the audit log queries
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// AuditEntry record of a management operation or an invocation in the audit log
+type AuditEntry struct {
+	// Time when the request was received
+	Time time.Time `json:"time"`
+	// Subject authenticated user of the request (AuditServiceTokenSubject for the service tokens)
+	Subject string `json:"subject,omitempty"`
+	// IP client IP of the request
+	IP string `json:"ip"`
+	// Method HTTP method of the request
+	Method string `json:"method"`
+	// Path path of the request
+	Path string `json:"path"`
+	// Operation create, update, delete or run
+	Operation string `json:"operation"`
+	// Service name of the service (or alias) of the request, if any
+	Service string `json:"service,omitempty"`
+	// RequestHash SHA-256 hash (hex) of the request body
+	RequestHash string `json:"request_hash"`
+	// Status HTTP status code of the response
+	Status int `json:"status"`
+	// Result success or failure
+	Result string `json:"result"`
+	// ErrorCode code of the error of the failed requests (X-Oscar-Error-Code), if any
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// AuditFilter filters of the audit log queries
+type AuditFilter struct {
+	Subject   string
+	Service   string
+	Operation string
+	Result    string
+	// Since only return the entries recorded at or after this time (if not zero)
+	Since time.Time
+	// Until only return the entries recorded before this time (if not zero)
+	Until time.Time
+	// Limit maximum number of entries to return (the most recent ones)
+	Limit int
+}
+
+// Validate checks the filters of an audit log query
+func (f AuditFilter) Validate() error {
+	if f.Limit < 1 || f.Limit > MaxAuditLimit {
+		return fmt.Errorf("the limit must be between 1 and %d", MaxAuditLimit)
+	}
+	switch f.Operation {
+	case "", AuditOperationCreate, AuditOperationUpdate, AuditOperationDelete, AuditOperationRun:
+	default:
+		return fmt.Errorf("the operation must be \"%s\", \"%s\", \"%s\" or \"%s\"", AuditOperationCreate, AuditOperationUpdate, AuditOperationDelete, AuditOperationRun)
+	}
+	switch f.Result {
+	case "", AuditResultSuccess, AuditResultFailure:
+	default:
+		return fmt.Errorf("the result must be \"%s\" or \"%s\"", AuditResultSuccess, AuditResultFailure)
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Since.Before(f.Until) {
+		return fmt.Errorf("since must be before until")
+	}
+	return nil
+}
+
+// Matches checks if an audit entry passes the filters (except the limit)
+func (f AuditFilter) Matches(entry *AuditEntry) bool {
+	return (f.Subject == "" || entry.Subject == f.Subject) &&
+		(f.Service == "" || entry.Service == f.Service) &&
+		(f.Operation == "" || entry.Operation == f.Operation) &&
+		(f.Result == "" || entry.Result == f.Result) &&
+		(f.Since.IsZero() || !entry.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || entry.Time.Before(f.Until))
+}
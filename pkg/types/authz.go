@@ -0,0 +1,75 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// AuthzRequest request authorized by the external authorization webhook, sent as the "input" of the decision
+// (compatible with the Data API of OPA)
+type AuthzRequest struct {
+	// Subject authenticated user (AuditServiceTokenSubject for the invocations with the token of the service)
+	Subject string `json:"subject,omitempty"`
+	// Groups OIDC groups of the user
+	Groups []string `json:"groups,omitempty"`
+	// Role role of the user (with RBAC enabled)
+	Role string `json:"role,omitempty"`
+	// Action create, update, delete or run
+	Action string `json:"action"`
+	// Resource resource of the request
+	Resource AuthzResource `json:"resource"`
+}
+
+// AuthzResource resource of a request authorized by the external authorization webhook
+type AuthzResource struct {
+	// Service name of the service (or alias) of the request, if any
+	Service string `json:"service,omitempty"`
+	// Method HTTP method of the request
+	Method string `json:"method"`
+	// Path path of the request
+	Path string `json:"path"`
+	// Definition definition of the service of the creations, updates and patches of the services
+	Definition json.RawMessage `json:"definition,omitempty"`
+}
+
+// AuthzDecision decision of the external authorization webhook, either in the root of its response or in its
+// "result" (as an object or a bool, like the OPA's Data API)
+type AuthzDecision struct {
+	Allow  *bool           `json:"allow,omitempty"`
+	Reason string          `json:"reason,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// GetDecision returns if the request is allowed and the reason of the decision
+func (d *AuthzDecision) GetDecision() (bool, string, error) {
+	if d.Allow != nil {
+		return *d.Allow, d.Reason, nil
+	}
+	if len(d.Result) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(d.Result, &allowed); err == nil {
+			return allowed, "", nil
+		}
+		result := &AuthzDecision{}
+		if err := json.Unmarshal(d.Result, result); err == nil && result.Allow != nil {
+			return *result.Allow, result.Reason, nil
+		}
+	}
+	return false, "", errors.New("the response of the authorization webhook has no decision")
+}
@@ -38,3 +38,24 @@ type SyncBackend interface {
 	ServerlessBackend
 	GetProxyDirector(serviceName string) func(req *http.Request)
 }
+
+// AsyncBackend define an interface for serverless backends that queue the invocations, sending their results to
+// a callback URL
+type AsyncBackend interface {
+	SyncBackend
+	GetAsyncProxyDirector(serviceName string, callbackURL string) func(req *http.Request)
+}
+
+// DryRunBackend define an interface for serverless backends able to check the creation or update of a service
+// (e.g. through a Kubernetes server-side dry-run) without persisting it
+type DryRunBackend interface {
+	ServerlessBackend
+	DryRunService(service Service, update bool) error
+}
+
+// DefinitionBackend define an interface for serverless backends able to update the stored definition of a service
+// (and the files of its configMap) without redeploying it
+type DefinitionBackend interface {
+	ServerlessBackend
+	UpdateServiceDefinition(service Service) error
+}
@@ -0,0 +1,32 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "fmt"
+
+const (
+	// CreatedBucketsLabel label key of the configMaps recording the buckets created by the services
+	CreatedBucketsLabel = "oscar_created_buckets"
+	// CreatedBucketsKey key of the configMaps recording the buckets created by a service with their identifiers
+	// ("<ENDPOINT>/<BUCKET>"), one per line
+	CreatedBucketsKey = "buckets"
+)
+
+// CreatedBucketsConfigMapName returns the name of the configMap recording the buckets created by a service
+func CreatedBucketsConfigMapName(serviceName string) string {
+	return fmt.Sprintf("%s.buckets", serviceName)
+}
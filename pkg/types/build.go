@@ -0,0 +1,209 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// BuildLabel label with the ID of the build set in the build jobs
+	BuildLabel = "oscar_build"
+
+	// BuildServiceStatusLabel label with the status of the creation of the service chained to a build
+	BuildServiceStatusLabel = "oscar_build_service_status"
+
+	// BuildImageAnnotation annotation with the image reference pushed by a build
+	BuildImageAnnotation = "oscar_build_image"
+
+	// BuildServiceAnnotation annotation with the name of the service created when a build succeeds
+	BuildServiceAnnotation = "oscar_build_service"
+
+	// BuildMessageAnnotation annotation with the error of the creation of the service chained to a build
+	BuildMessageAnnotation = "oscar_build_message"
+
+	// BuildOwnerAnnotation annotation with the user that requested a build
+	BuildOwnerAnnotation = "oscar_build_owner"
+
+	// BuildContainerName name of the container running the build
+	BuildContainerName = "oscar-build"
+
+	// BuildContextKey key of the build context (.tar.gz) in the build's secret
+	BuildContextKey = "context.tar.gz"
+
+	// BuildServiceKey key of the service definition in the build's secret
+	BuildServiceKey = "service.json"
+
+	// BuildMaxContextSize maximum size (in bytes) of the build context sent in the requests, as it is stored
+	// in a Kubernetes secret (limited to 1MiB)
+	BuildMaxContextSize = 768 << 10
+)
+
+// Status of the builds and of the creation of their chained services
+const (
+	BuildPending   = "pending"
+	BuildRunning   = "running"
+	BuildSucceeded = "succeeded"
+	BuildFailed    = "failed"
+	BuildCreated   = "created"
+)
+
+var (
+	// Repository name of the built images (relative to the cluster's registry)
+	buildNameRegexp = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+	// Characters not allowed in the owner's component of the repositories of the built images
+	buildOwnerInvalidRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+	// Tag of the built images
+	buildTagRegexp = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	// Branch, tag or commit of the Git repositories
+	buildRefRegexp = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_./-]*$`)
+	// Full SHA-1 commit hashes
+	commitRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// BuildRequest request to build an image from a Dockerfile and push it to the cluster's registry
+type BuildRequest struct {
+	// Name of the image repository in the cluster's registry
+	Name string `json:"name" binding:"required"`
+	// Tag of the image
+	// Optional. (default: "latest")
+	Tag string `json:"tag,omitempty"`
+	// Dockerfile content of the Dockerfile
+	// Required if Git is not defined
+	Dockerfile string `json:"dockerfile,omitempty"`
+	// Context .tar.gz file (base64 encoded in JSON) with the files of the build context
+	// Optional. Only allowed with Dockerfile
+	Context []byte `json:"context,omitempty"`
+	// Git repository used as build context
+	// Optional
+	Git *BuildGitSource `json:"git,omitempty"`
+	// Service definition created with the built image when the build succeeds. Its image is set by the build,
+	// so it is validated after binding the request
+	// Optional
+	Service *Service `json:"service,omitempty" binding:"-"`
+}
+
+// BuildGitSource Git repository used as build context
+type BuildGitSource struct {
+	// URL of the repository (HTTPS)
+	URL string `json:"url"`
+	// Ref branch, tag ("refs/tags/<TAG>") or commit to build
+	// Optional. (default: the repository's default branch)
+	Ref string `json:"ref,omitempty"`
+	// Path of the build context inside the repository
+	// Optional. (default: the root of the repository)
+	Path string `json:"path,omitempty"`
+	// Dockerfile path of the Dockerfile relative to the build context
+	// Optional. (default: "Dockerfile")
+	Dockerfile string `json:"dockerfile,omitempty"`
+}
+
+// Build status of an image build
+type Build struct {
+	ID     string `json:"id"`
+	Image  string `json:"image"`
+	Status string `json:"status"`
+	// Service name of the service created when the build succeeds
+	Service string `json:"service,omitempty"`
+	// ServiceStatus status of the creation of the service ("pending", "created" or "failed")
+	ServiceStatus string `json:"service_status,omitempty"`
+	// Message error of the creation of the service
+	Message string `json:"message,omitempty"`
+	// Owner user that requested the build
+	Owner      string     `json:"owner,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Validate checks the build request
+func (req *BuildRequest) Validate() error {
+	if !buildNameRegexp.MatchString(req.Name) {
+		return fmt.Errorf("the image name \"%s\" is not valid", req.Name)
+	}
+	if req.Tag != "" && !buildTagRegexp.MatchString(req.Tag) {
+		return fmt.Errorf("the image tag \"%s\" is not valid", req.Tag)
+	}
+	if len(req.Context) > BuildMaxContextSize {
+		return fmt.Errorf("the build context can not exceed %d bytes", BuildMaxContextSize)
+	}
+
+	if req.Git == nil {
+		if req.Dockerfile == "" {
+			return errors.New("the dockerfile or git field is required")
+		}
+		return nil
+	}
+	if req.Dockerfile != "" || len(req.Context) > 0 {
+		return errors.New("the dockerfile and context fields can not be used with a git repository")
+	}
+	if u, err := url.Parse(req.Git.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("the git url \"%s\" is not valid (only HTTPS repositories are supported)", req.Git.URL)
+	}
+	if req.Git.Ref != "" && !buildRefRegexp.MatchString(req.Git.Ref) {
+		return fmt.Errorf("the git ref \"%s\" is not valid", req.Git.Ref)
+	}
+	for _, p := range []string{req.Git.Path, req.Git.Dockerfile} {
+		if strings.HasPrefix(p, "/") || strings.Contains(p, "..") {
+			return fmt.Errorf("the path \"%s\" must be relative to the repository", p)
+		}
+	}
+	return nil
+}
+
+// GetImage returns the reference of the image built in the registry for the owner. The repositories are prefixed
+// with the owner (see GetBuildOwnerPrefix), so the users can not overwrite the images of others
+func (req *BuildRequest) GetImage(registry string, owner string) string {
+	tag := req.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	return fmt.Sprintf("%s/%s/%s:%s", strings.TrimSuffix(registry, "/"), GetBuildOwnerPrefix(owner), req.Name, tag)
+}
+
+// GetBuildOwnerPrefix returns the first component of the repositories of the images built for the owner: the owner
+// in lowercase, with the sequences of other characters than letters and digits replaced by "-". If the owner had to
+// be changed (or is empty), the start of its SHA-256 hash is appended, so that the prefixes of different owners do not
+// collide
+func GetBuildOwnerPrefix(owner string) string {
+	prefix := strings.Trim(buildOwnerInvalidRegexp.ReplaceAllString(strings.ToLower(owner), "-"), "-")
+	if prefix != owner || prefix == "" {
+		hash := sha256.Sum256([]byte(owner))
+		prefix = strings.TrimPrefix(prefix+"-"+hex.EncodeToString(hash[:])[:8], "-")
+	}
+	return prefix
+}
+
+// GetGitContext returns the Git repository as a kaniko build context ("git://<HOST>/<PATH>#<REF>").
+// Refs not starting with "refs/" nor being a commit hash are considered branches
+func (git *BuildGitSource) GetGitContext() string {
+	context := "git://" + strings.TrimPrefix(git.URL, "https://")
+	switch {
+	case git.Ref == "":
+	case strings.HasPrefix(git.Ref, "refs/") || commitRegexp.MatchString(git.Ref):
+		context += "#" + git.Ref
+	default:
+		context += "#refs/heads/" + git.Ref
+	}
+	return context
+}
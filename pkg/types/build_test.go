@@ -0,0 +1,91 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBuildRequest(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		req         BuildRequest
+		returnError bool
+	}{
+		{"dockerfile", BuildRequest{Name: "group/image", Tag: "v1.0", Dockerfile: "FROM busybox"}, false},
+		{"git", BuildRequest{Name: "image", Git: &BuildGitSource{URL: "https://github.com/grycap/oscar.git", Ref: "main", Path: "examples/test"}}, false},
+		{"invalid name", BuildRequest{Name: "Image", Dockerfile: "FROM busybox"}, true},
+		{"invalid tag", BuildRequest{Name: "image", Tag: ".v1", Dockerfile: "FROM busybox"}, true},
+		{"without dockerfile", BuildRequest{Name: "image"}, true},
+		{"dockerfile and git", BuildRequest{Name: "image", Dockerfile: "FROM busybox", Git: &BuildGitSource{URL: "https://github.com/grycap/oscar.git"}}, true},
+		{"ssh repository", BuildRequest{Name: "image", Git: &BuildGitSource{URL: "git@github.com:grycap/oscar.git"}}, true},
+		{"path outside the repository", BuildRequest{Name: "image", Git: &BuildGitSource{URL: "https://github.com/grycap/oscar.git", Path: "../other"}}, true},
+		{"context too large", BuildRequest{Name: "image", Dockerfile: "FROM busybox", Context: make([]byte, BuildMaxContextSize+1)}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.req.Validate(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestBuildGetImage(t *testing.T) {
+	req := BuildRequest{Name: "group/image"}
+	if image := req.GetImage("registry.example.com/oscar/", "alice"); image != "registry.example.com/oscar/alice/group/image:latest" {
+		t.Errorf("unexpected image \"%s\"", image)
+	}
+}
+
+func TestGetBuildOwnerPrefix(t *testing.T) {
+	if prefix := GetBuildOwnerPrefix("alice"); prefix != "alice" {
+		t.Errorf("expecting the valid owners to be kept, got \"%s\"", prefix)
+	}
+	prefix := GetBuildOwnerPrefix("Alice@egi.eu")
+	if !strings.HasPrefix(prefix, "alice-egi-eu-") || len(prefix) != len("alice-egi-eu-")+8 || !buildNameRegexp.MatchString(prefix) {
+		t.Errorf("unexpected prefix \"%s\"", prefix)
+	}
+	// The owners changed in the same way get different prefixes
+	if GetBuildOwnerPrefix("alice-egi-eu") == prefix || GetBuildOwnerPrefix("alice.egi.eu") == prefix {
+		t.Error("expecting different prefixes for different owners")
+	}
+	if prefix := GetBuildOwnerPrefix(""); len(prefix) != 8 || !buildNameRegexp.MatchString(prefix) {
+		t.Errorf("unexpected prefix for the empty owner \"%s\"", prefix)
+	}
+}
+
+func TestGetGitContext(t *testing.T) {
+	scenarios := []struct {
+		ref      string
+		expected string
+	}{
+		{"", "git://github.com/grycap/oscar.git"},
+		{"main", "git://github.com/grycap/oscar.git#refs/heads/main"},
+		{"refs/tags/v3.0.0", "git://github.com/grycap/oscar.git#refs/tags/v3.0.0"},
+		{"0123456789abcdef0123456789abcdef01234567", "git://github.com/grycap/oscar.git#0123456789abcdef0123456789abcdef01234567"},
+	}
+
+	for _, s := range scenarios {
+		git := BuildGitSource{URL: "https://github.com/grycap/oscar.git", Ref: s.ref}
+		if context := git.GetGitContext(); context != s.expected {
+			t.Errorf("expecting \"%s\", got \"%s\"", s.expected, context)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Authentication methods of the callbacks
+const (
+	// BearerCallbackAuth send the "token" field of the secret as bearer token
+	BearerCallbackAuth = "bearer"
+	// BasicCallbackAuth send the "username" and "password" fields of the secret using basic authentication
+	BasicCallbackAuth = "basic"
+	// OAuth2CallbackAuth obtain a token from the TokenURL using the OAuth2 client credentials flow
+	// with the "client_id" and "client_secret" fields of the secret
+	OAuth2CallbackAuth = "oauth2"
+)
+
+// Fields of the secrets referenced by CallbackAuth
+const (
+	CallbackTokenKey        = "token"
+	CallbackUsernameKey     = "username"
+	CallbackPasswordKey     = "password"
+	CallbackClientIDKey     = "client_id"
+	CallbackClientSecretKey = "client_secret"
+)
+
+// Status of the callback deliveries
+const (
+	CallbackPending   = "pending"
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+// Callback configuration of the HTTP endpoint notified on the completion/failure of the service's jobs
+type Callback struct {
+	// URL of the endpoint. The notification (CallbackPayload) is sent in the body of a POST request
+	URL string `json:"url"`
+	// OnSuccess notify the successful jobs
+	// Optional. (default: false)
+	OnSuccess bool `json:"on_success"`
+	// OnFailure notify the failed jobs
+	// Optional. (default: false)
+	OnFailure bool `json:"on_failure"`
+	// Auth credentials to authenticate the requests
+	// Optional
+	Auth *CallbackAuth `json:"auth,omitempty"`
+	// Retry policy of the failed deliveries
+	// Optional
+	Retry *CallbackRetry `json:"retry,omitempty"`
+}
+
+// CallbackAuth credentials used to authenticate the callback requests
+type CallbackAuth struct {
+	// Type authentication method ("bearer", "basic" or "oauth2")
+	Type string `json:"type"`
+	// Secret name of the Kubernetes secret (in the services namespace) containing the credentials
+	// ("token", "username" and "password" or "client_id" and "client_secret" fields).
+	// The secret must have the label "oscar_service=<SERVICE_NAME>"
+	Secret string `json:"secret"`
+	// TokenURL endpoint to obtain the tokens (only for "oauth2")
+	TokenURL string `json:"token_url,omitempty"`
+	// Scopes requested for the tokens (only for "oauth2")
+	// Optional
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// CallbackRetry retry policy of the callbacks. The backoff is doubled after each failed attempt
+type CallbackRetry struct {
+	// MaxAttempts maximum number of delivery attempts
+	// Optional. (default: 3)
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Backoff time (in seconds) to wait before the first retry
+	// Optional. (default: 5)
+	Backoff int `json:"backoff,omitempty"`
+}
+
+// CallbackPayload notification sent to the callback endpoint
+type CallbackPayload struct {
+	// Service name of the service
+	Service string `json:"service"`
+	// Job name of the finished job
+	Job string `json:"job"`
+	// Status of the job ("succeeded" or "failed")
+	Status string `json:"status"`
+	// Reason of the job failure
+	Reason string `json:"reason,omitempty"`
+	// Message details of the job failure
+	Message string `json:"message,omitempty"`
+	// FinishedAt time when the job finished
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// CallbackDelivery record of the delivery of a callback
+type CallbackDelivery struct {
+	// ID identifier of the delivery (the name of the job)
+	ID string `json:"id"`
+	// URL of the callback endpoint
+	URL string `json:"url"`
+	// Status of the delivery ("pending", "delivered" or "failed")
+	Status string `json:"status"`
+	// Attempts number of delivery attempts
+	Attempts int `json:"attempts"`
+	// StatusCode HTTP status code of the last attempt
+	StatusCode int `json:"status_code,omitempty"`
+	// Error of the last failed attempt
+	Error string `json:"error,omitempty"`
+	// Payload notification sent
+	Payload CallbackPayload `json:"payload"`
+	// CreatedAt time of the first attempt
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt time of the last attempt
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetMaxAttempts returns the maximum number of delivery attempts
+func (cb Callback) GetMaxAttempts() int {
+	if cb.Retry == nil || cb.Retry.MaxAttempts <= 0 {
+		return 3
+	}
+	return cb.Retry.MaxAttempts
+}
+
+// GetBackoff returns the time (in seconds) to wait before the first retry
+func (cb Callback) GetBackoff() int {
+	if cb.Retry == nil || cb.Retry.Backoff <= 0 {
+		return 5
+	}
+	return cb.Retry.Backoff
+}
+
+// ValidateCallback checks the callback of the service (if defined)
+func (service *Service) ValidateCallback() error {
+	cb := service.Callback
+	if cb == nil {
+		return nil
+	}
+	if u, err := url.Parse(cb.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("the callback url \"%s\" is not valid", cb.URL)
+	}
+	if cb.Retry != nil && (cb.Retry.MaxAttempts < 0 || cb.Retry.Backoff < 0) {
+		return fmt.Errorf("the callback retry settings can not be negative")
+	}
+	if cb.Auth == nil {
+		return nil
+	}
+	switch cb.Auth.Type {
+	case BearerCallbackAuth, BasicCallbackAuth:
+	case OAuth2CallbackAuth:
+		if cb.Auth.TokenURL == "" {
+			return fmt.Errorf("the callback token_url is required for the \"%s\" authentication", OAuth2CallbackAuth)
+		}
+	default:
+		return fmt.Errorf("the callback authentication type \"%s\" is not valid (valid types are \"%s\", \"%s\" and \"%s\")",
+			cb.Auth.Type, BearerCallbackAuth, BasicCallbackAuth, OAuth2CallbackAuth)
+	}
+	if cb.Auth.Secret == "" {
+		return fmt.Errorf("the callback authentication secret is required")
+	}
+	return nil
+}
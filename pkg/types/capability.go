@@ -0,0 +1,46 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "strings"
+
+// Types of the node capabilities
+const (
+	GPUCapability       = "gpu"
+	CPUCapability       = "cpu"
+	HugepagesCapability = "hugepages"
+	ArchCapability      = "arch"
+)
+
+// Capability hardware capability provided by the nodes of the cluster, discovered from their labels
+// (set by the NVIDIA GPU Feature Discovery and the Node Feature Discovery) and status
+type Capability struct {
+	// Name of the capability (e.g. "nvidia-A100-SXM4-40GB", "avx512f", "hugepages-2Mi" or "arm64")
+	Name string `json:"name"`
+	// Type of the capability ("gpu", "cpu", "hugepages" or "arch")
+	Type string `json:"type"`
+	// Nodes names of the (ready) nodes providing the capability
+	Nodes []string `json:"nodes"`
+}
+
+// MatchesCapability checks if a capability satisfies a requirement of a service, i.e. if the requirement is
+// the name of the capability or its prefix up to a "-" (e.g. "nvidia-A100" is satisfied by "nvidia-A100-SXM4-40GB").
+// The names are compared case-insensitively
+func MatchesCapability(requirement string, capability string) bool {
+	requirement, capability = strings.ToLower(requirement), strings.ToLower(capability)
+	return capability == requirement || strings.HasPrefix(capability, requirement+"-")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// CarbonWindow carbon intensity of the energy consumed by the cluster in a time window
+type CarbonWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Intensity carbon intensity (in gCO2eq/kWh)
+	Intensity float64 `json:"intensity"`
+}
+
+// CarbonForecast carbon intensity windows returned by the carbon intensity API
+type CarbonForecast []CarbonWindow
+
+// Current returns the carbon intensity of the window containing t
+func (forecast CarbonForecast) Current(t time.Time) (float64, bool) {
+	for _, w := range forecast {
+		if !t.Before(w.From) && t.Before(w.To) {
+			return w.Intensity, true
+		}
+	}
+	return 0, false
+}
+
+// BestStart returns the earliest time (between now and the deadline) of the window with the lowest carbon intensity
+// along with its intensity. Returns now if there are no windows in the period
+func (forecast CarbonForecast) BestStart(now time.Time, deadline time.Time) (time.Time, float64) {
+	best, intensity := now, -1.0
+	for _, w := range forecast {
+		// Only the windows overlapping the period
+		if !w.To.After(now) || w.From.After(deadline) {
+			continue
+		}
+		start := w.From
+		if start.Before(now) {
+			start = now
+		}
+		if intensity < 0 || w.Intensity < intensity || (w.Intensity == intensity && start.Before(best)) {
+			best, intensity = start, w.Intensity
+		}
+	}
+	if intensity < 0 {
+		return now, 0
+	}
+	return best, intensity
+}
+
+// ValidateDeferral checks the deferral settings of the service
+func (service *Service) ValidateDeferral() error {
+	if service.MaxDelay < 0 {
+		return fmt.Errorf("the max_delay can not be negative")
+	}
+	if service.MaxDelay > 0 && !service.Deferrable {
+		return fmt.Errorf("the max_delay can only be set in deferrable services")
+	}
+	return nil
+}
+
+// GetMaxDelay returns the maximum time that the jobs of a deferrable service can be delayed
+func (service *Service) GetMaxDelay(cfg *Config) time.Duration {
+	if service.MaxDelay > 0 {
+		return time.Duration(service.MaxDelay) * time.Second
+	}
+	return cfg.DeferrableMaxDelay
+}
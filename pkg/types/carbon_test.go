@@ -0,0 +1,63 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCarbonForecastBestStart(t *testing.T) {
+	now := time.Date(2023, 1, 1, 10, 30, 0, 0, time.UTC)
+	hour := func(h int) time.Time {
+		return time.Date(2023, 1, 1, h, 0, 0, 0, time.UTC)
+	}
+	forecast := CarbonForecast{
+		{From: hour(10), To: hour(11), Intensity: 300},
+		{From: hour(11), To: hour(12), Intensity: 100},
+		{From: hour(12), To: hour(13), Intensity: 100},
+		{From: hour(13), To: hour(14), Intensity: 50},
+	}
+
+	if current, ok := forecast.Current(now); !ok || current != 300 {
+		t.Errorf("expecting current intensity 300, got %v (%v)", current, ok)
+	}
+
+	scenarios := []struct {
+		name              string
+		deadline          time.Time
+		expectedStart     time.Time
+		expectedIntensity float64
+	}{
+		{"current window", now.Add(10 * time.Minute), now, 300},
+		{"earliest of the lowest windows", hour(12).Add(30 * time.Minute), hour(11), 100},
+		{"lowest window", hour(15), hour(13), 50},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			start, intensity := forecast.BestStart(now, s.deadline)
+			if !start.Equal(s.expectedStart) || intensity != s.expectedIntensity {
+				t.Errorf("expecting start %v (%v), got %v (%v)", s.expectedStart, s.expectedIntensity, start, intensity)
+			}
+		})
+	}
+
+	if start, _ := (CarbonForecast{}).BestStart(now, hour(15)); !start.Equal(now) {
+		t.Errorf("expecting now without forecast, got %v", start)
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// CatalogTemplate template of the service catalog, an FDL file with the definition of one or more services
+// ready to be deployed through the API
+type CatalogTemplate struct {
+	// Name of the template (the name of its directory in the catalog)
+	Name string `json:"name"`
+	// Description of the template (top-level "description" field of the FDL file)
+	Description string `json:"description,omitempty"`
+	// Services names of the services defined in the template (may contain parameters)
+	Services []string `json:"services"`
+	// Parameters values substituted in the template ("${NAME}") when it is deployed
+	Parameters []CatalogParameter `json:"parameters"`
+}
+
+// CatalogParameter parameter of a catalog template, declared in the top-level "parameters" field of its FDL file
+type CatalogParameter struct {
+	// Name of the parameter, referenced in the template as "${NAME}"
+	Name string `json:"name"`
+	// Description of the parameter
+	Description string `json:"description,omitempty"`
+	// Default value of the parameter. The parameters without default value are required
+	Default string `json:"default,omitempty"`
+	// Required the parameter must be provided to deploy the template
+	Required bool `json:"required"`
+}
+
+// CatalogDeployRequest request to deploy a catalog template
+type CatalogDeployRequest struct {
+	// Parameters values of the template's parameters
+	Parameters map[string]string `json:"parameters"`
+}
@@ -0,0 +1,205 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Messaging platforms of the chat notifications
+const (
+	SlackPlatform      = "slack"
+	MattermostPlatform = "mattermost"
+	TeamsPlatform      = "teams"
+)
+
+// ChatWebhookURLKey field of the incoming webhook URL in the secrets referenced by ChatNotification
+const ChatWebhookURLKey = "webhook_url"
+
+// Built-in templates of the chat messages
+const (
+	defaultChatSuccessTemplate = `✅ Job {{bold .Job}} of service {{bold .Service}} completed successfully
+{{- range .Outputs}}
+• {{link .URL .Name}}
+{{- end}}`
+
+	defaultChatFailureTemplate = `❌ Job {{bold .Job}} of service {{bold .Service}} failed
+{{- if .Reason}} ({{.Reason}}){{end}}
+{{- if .Message}}
+{{.Message}}
+{{- end}}
+{{- if .Logs}}
+{{code .Logs}}
+{{- end}}`
+)
+
+// ChatNotification configuration of the incoming webhook of a messaging platform notified on the completion/failure
+// of the service's jobs. The messages are rendered from Go templates over the execution context (ChatMessageContext)
+type ChatNotification struct {
+	// Platform messaging platform ("slack", "mattermost" or "teams")
+	Platform string `json:"platform"`
+	// WebhookSecret name of the Kubernetes secret (in the services namespace) containing the URL of the incoming
+	// webhook in the "webhook_url" field. The secret must have the label "oscar_service=<SERVICE_NAME>"
+	WebhookSecret string `json:"webhook_secret"`
+	// OnSuccess notify the successful jobs
+	// Optional. (default: false)
+	OnSuccess bool `json:"on_success"`
+	// OnFailure notify the failed jobs
+	// Optional. (default: false)
+	OnFailure bool `json:"on_failure"`
+	// SuccessTemplate Go template of the messages of the successful jobs
+	// Optional. (default: built-in template with the links to the output files)
+	SuccessTemplate string `json:"success_template,omitempty"`
+	// FailureTemplate Go template of the messages of the failed jobs
+	// Optional. (default: built-in template with the failure reason and an excerpt of the logs)
+	FailureTemplate string `json:"failure_template,omitempty"`
+}
+
+// ChatMessageContext execution context of a finished job available in the templates of the chat messages
+type ChatMessageContext struct {
+	// Service name of the service
+	Service string
+	// Job name of the finished job
+	Job string
+	// Status of the job ("succeeded" or "failed")
+	Status string
+	// Reason of the job failure
+	Reason string
+	// Message details of the job failure
+	Message string
+	// Logs last lines of the logs of the failed job
+	Logs string
+	// Outputs files stored by the job in the service's MinIO outputs (with presigned links)
+	Outputs []ChatOutput
+	// FinishedAt time when the job finished
+	FinishedAt time.Time
+}
+
+// ChatOutput output file linked in the chat messages
+type ChatOutput struct {
+	Name string
+	URL  string
+}
+
+// ValidateChatNotification checks the chat notification of the service (if defined), including its templates
+func (service *Service) ValidateChatNotification() error {
+	cn := service.ChatNotification
+	if cn == nil {
+		return nil
+	}
+	switch cn.Platform {
+	case SlackPlatform, MattermostPlatform, TeamsPlatform:
+	default:
+		return fmt.Errorf("the chat notification platform \"%s\" is not valid (valid platforms are \"%s\", \"%s\" and \"%s\")",
+			cn.Platform, SlackPlatform, MattermostPlatform, TeamsPlatform)
+	}
+	if cn.WebhookSecret == "" {
+		return fmt.Errorf("the chat notification webhook_secret is required")
+	}
+	if _, err := cn.parseTemplate("success_template", cn.SuccessTemplate); err != nil {
+		return err
+	}
+	if _, err := cn.parseTemplate("failure_template", cn.FailureTemplate); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RenderMessage renders the message of a finished job with the template of its status
+func (cn ChatNotification) RenderMessage(ctx ChatMessageContext) (string, error) {
+	name, text := "success_template", cn.SuccessTemplate
+	if text == "" {
+		text = defaultChatSuccessTemplate
+	}
+	if ctx.Status == "failed" {
+		name, text = "failure_template", cn.FailureTemplate
+		if text == "" {
+			text = defaultChatFailureTemplate
+		}
+	}
+
+	tmpl, err := cn.parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ctx); err != nil {
+		return "", fmt.Errorf("error rendering the chat notification %s: %v", name, err)
+	}
+	return b.String(), nil
+}
+
+// MakePayload returns the body of the webhook request with the message, in the format of the platform
+func (cn ChatNotification) MakePayload(message string, failed bool) ([]byte, error) {
+	if cn.Platform != TeamsPlatform {
+		return json.Marshal(map[string]string{"text": message})
+	}
+
+	// Microsoft Teams connector card, colored by the status of the job
+	color := "2EB886"
+	if failed {
+		color = "D00000"
+	}
+	// The line breaks of the cards' text must be explicit
+	message = strings.ReplaceAll(message, "\n", "\n\n")
+	return json.Marshal(map[string]string{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    strings.SplitN(message, "\n", 2)[0],
+		"themeColor": color,
+		"text":       message,
+	})
+}
+
+// parseTemplate parses a message template (the empty ones are not parsed) with the formatting functions of the platform:
+// "bold", "code" and "link" (URL and text)
+func (cn ChatNotification) parseTemplate(name string, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	funcs := template.FuncMap{
+		"bold": func(s string) string {
+			if cn.Platform == SlackPlatform {
+				return "*" + s + "*"
+			}
+			return "**" + s + "**"
+		},
+		"code": func(s string) string {
+			return "```\n" + strings.ReplaceAll(s, "```", "'''") + "\n```"
+		},
+		"link": func(url string, text string) string {
+			if cn.Platform == SlackPlatform {
+				return fmt.Sprintf("<%s|%s>", url, text)
+			}
+			return fmt.Sprintf("[%s](%s)", text, url)
+		},
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("the chat notification %s is not valid: %v", name, err)
+	}
+	// Check the template with an empty context, so errors such as missing fields are reported on validation
+	if err := tmpl.Execute(&bytes.Buffer{}, ChatMessageContext{}); err != nil {
+		return nil, fmt.Errorf("the chat notification %s is not valid: %v", name, err)
+	}
+	return tmpl, nil
+}
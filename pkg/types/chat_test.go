@@ -0,0 +1,104 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateChatNotification(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		notification *ChatNotification
+		returnError  bool
+	}{
+		{"no notification", nil, false},
+		{"valid", &ChatNotification{Platform: SlackPlatform, WebhookSecret: "secret", SuccessTemplate: "{{.Job}} done"}, false},
+		{"invalid platform", &ChatNotification{Platform: "irc", WebhookSecret: "secret"}, true},
+		{"missing secret", &ChatNotification{Platform: TeamsPlatform}, true},
+		{"template syntax", &ChatNotification{Platform: SlackPlatform, WebhookSecret: "secret", FailureTemplate: "{{.Job"}, true},
+		{"template field", &ChatNotification{Platform: SlackPlatform, WebhookSecret: "secret", SuccessTemplate: "{{.Owner}}"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{ChatNotification: s.notification}
+			if err := service.ValidateChatNotification(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestRenderChatMessage(t *testing.T) {
+	ctx := ChatMessageContext{
+		Service: "svc",
+		Job:     "job",
+		Status:  "succeeded",
+		Outputs: []ChatOutput{{Name: "out.png", URL: "https://minio/out.png"}},
+	}
+
+	scenarios := []struct {
+		name         string
+		notification ChatNotification
+		status       string
+		expected     string
+	}{
+		{"slack success", ChatNotification{Platform: SlackPlatform}, "succeeded",
+			"✅ Job *job* of service *svc* completed successfully\n• <https://minio/out.png|out.png>"},
+		{"mattermost success", ChatNotification{Platform: MattermostPlatform}, "succeeded",
+			"✅ Job **job** of service **svc** completed successfully\n• [out.png](https://minio/out.png)"},
+		{"failure", ChatNotification{Platform: MattermostPlatform}, "failed",
+			"❌ Job **job** of service **svc** failed (BackoffLimitExceeded)\nJob has reached the backoff limit\n```\nerror\n```"},
+		{"custom template", ChatNotification{Platform: SlackPlatform, SuccessTemplate: "{{.Service}}/{{.Job}}: {{.Status}}"}, "succeeded",
+			"svc/job: succeeded"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			c := ctx
+			c.Status = s.status
+			if s.status == "failed" {
+				c.Reason, c.Message, c.Logs, c.Outputs = "BackoffLimitExceeded", "Job has reached the backoff limit", "error", nil
+			}
+			message, err := s.notification.RenderMessage(c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if message != s.expected {
+				t.Errorf("expecting message %q, got %q", s.expected, message)
+			}
+		})
+	}
+}
+
+func TestMakeChatPayload(t *testing.T) {
+	payload, _ := ChatNotification{Platform: SlackPlatform}.MakePayload("done", false)
+	if string(payload) != `{"text":"done"}` {
+		t.Errorf("unexpected slack payload %s", payload)
+	}
+
+	payload, _ = ChatNotification{Platform: TeamsPlatform}.MakePayload("failed\nerror", true)
+	card := map[string]string{}
+	if err := json.Unmarshal(payload, &card); err != nil {
+		t.Fatal(err)
+	}
+	if card["@type"] != "MessageCard" || card["themeColor"] != "D00000" || card["summary"] != "failed" || card["text"] != "failed\n\nerror" {
+		t.Errorf("unexpected teams payload %s", payload)
+	}
+}
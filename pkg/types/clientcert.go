@@ -0,0 +1,79 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"strings"
+)
+
+// dnAttributeNames short names of the attributes of the subject DNs of the client certificates
+var dnAttributeNames = map[string]string{
+	"2.5.4.3":                    "CN",
+	"2.5.4.5":                    "serialNumber",
+	"2.5.4.6":                    "C",
+	"2.5.4.7":                    "L",
+	"2.5.4.8":                    "ST",
+	"2.5.4.9":                    "street",
+	"2.5.4.10":                   "O",
+	"2.5.4.11":                   "OU",
+	"2.5.4.17":                   "postalCode",
+	"0.9.2342.19200300.100.1.1":  "UID",
+	"0.9.2342.19200300.100.1.25": "DC",
+	"1.2.840.113549.1.9.1":       "emailAddress",
+}
+
+// FormatDN formats the subject DN of a certificate as in the Grid environments ("/DC=org/O=Example/CN=Alice"),
+// keeping the order of its attributes
+func FormatDN(rdns pkix.RDNSequence) string {
+	var sb strings.Builder
+	for _, rdn := range rdns {
+		for _, atv := range rdn {
+			sb.WriteString("/")
+			sb.WriteString(getDNAttributeName(atv.Type))
+			sb.WriteString("=")
+			sb.WriteString(fmt.Sprint(atv.Value))
+		}
+	}
+	return sb.String()
+}
+
+// getDNAttributeName returns the short name of an attribute type (its OID if unknown)
+func getDNAttributeName(oid asn1.ObjectIdentifier) string {
+	if name, ok := dnAttributeNames[oid.String()]; ok {
+		return name
+	}
+	return oid.String()
+}
+
+// GetClientCertUser returns the user mapped by ClientCertUsers to the subject DN of a client certificate (empty if
+// not mapped)
+func (cfg *Config) GetClientCertUser(dn string) string {
+	for _, entry := range cfg.ClientCertUsers {
+		// The DNs contain "=", so the user follows the last one
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			continue
+		}
+		if strings.TrimSpace(entry[:i]) == dn {
+			return strings.TrimSpace(entry[i+1:])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestFormatDN(t *testing.T) {
+	name := pkix.Name{Country: []string{"ES"}, Organization: []string{"UPV"}, OrganizationalUnit: []string{"GRyCAP"}, CommonName: "Alice"}
+	if dn := FormatDN(name.ToRDNSequence()); dn != "/C=ES/O=UPV/OU=GRyCAP/CN=Alice" {
+		t.Errorf("unexpected DN: %s", dn)
+	}
+}
+
+func TestGetClientCertUser(t *testing.T) {
+	cfg := &Config{ClientCertUsers: []string{"/C=ES/O=UPV/CN=Alice = alice", "/C=ES/O=UPV/CN=Bob=bob", "invalid"}}
+
+	if user := cfg.GetClientCertUser("/C=ES/O=UPV/CN=Alice"); user != "alice" {
+		t.Errorf("expecting user \"alice\", got \"%s\"", user)
+	}
+	if user := cfg.GetClientCertUser("/C=ES/O=UPV/CN=Bob"); user != "bob" {
+		t.Errorf("expecting user \"bob\", got \"%s\"", user)
+	}
+	if user := cfg.GetClientCertUser("/C=ES/O=UPV/CN=Carol"); user != "" {
+		t.Errorf("expecting no user, got \"%s\"", user)
+	}
+}
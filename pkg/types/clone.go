@@ -0,0 +1,70 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// CloneRequest request to clone a service
+type CloneRequest struct {
+	// Name name of the new service
+	Name string `json:"name" binding:"required"`
+	// Overrides JSON merge patch (RFC 7386) applied to the cloned definition
+	Overrides json.RawMessage `json:"overrides,omitempty"`
+	// CopyBuckets use new buckets (named after the new service) in the MinIO and S3 paths instead of sharing
+	// the buckets of the original service
+	CopyBuckets bool `json:"copy_buckets,omitempty"`
+}
+
+// RenameBuckets renames the buckets of the MinIO and S3 input, output and error paths of a service cloned
+// from oldName. The oldName in the bucket names is replaced by the service's name, or the service's name
+// is prepended if the bucket is not named after oldName
+func (service *Service) RenameBuckets(oldName string) {
+	renamePath := func(path string) string {
+		bucket, folder := StorageIOConfig{Path: path}.SplitPath()
+		if strings.Contains(bucket, oldName) {
+			bucket = strings.ReplaceAll(bucket, oldName, service.Name)
+		} else {
+			bucket = service.Name + "-" + bucket
+		}
+		if folder == "" {
+			return bucket
+		}
+		return bucket + "/" + folder
+	}
+	isBucketProvider := func(storageIO StorageIOConfig) bool {
+		name, _ := storageIO.GetProvider()
+		return name == MinIOName || name == S3Name
+	}
+
+	for i, in := range service.Input {
+		if !isBucketProvider(in) {
+			continue
+		}
+		service.Input[i].Path = renamePath(in.Path)
+		if in.ErrorPath != "" {
+			service.Input[i].ErrorPath = renamePath(in.ErrorPath)
+		}
+	}
+	for i, out := range service.Output {
+		if isBucketProvider(out) {
+			service.Output[i].Path = renamePath(out.Path)
+		}
+	}
+}
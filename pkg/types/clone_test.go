@@ -0,0 +1,47 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "testing"
+
+func TestRenameBuckets(t *testing.T) {
+	service := &Service{
+		Name: "variant",
+		Input: []StorageIOConfig{
+			{Provider: "minio", Path: "original-in/folder", ErrorPath: "errors"},
+			{Provider: "dcache.test", Path: "original/in"},
+		},
+		Output: []StorageIOConfig{
+			{Provider: "s3.aws", Path: "/original/out/"},
+			{Provider: "onedata.test", Path: "original/out"},
+		},
+	}
+	service.RenameBuckets("original")
+
+	expected := []string{
+		service.Input[0].Path, "variant-in/folder",
+		service.Input[0].ErrorPath, "variant-errors",
+		service.Input[1].Path, "original/in",
+		service.Output[0].Path, "variant/out",
+		service.Output[1].Path, "original/out",
+	}
+	for i := 0; i < len(expected); i += 2 {
+		if expected[i] != expected[i+1] {
+			t.Errorf("expecting path %s, got %s", expected[i+1], expected[i])
+		}
+	}
+}
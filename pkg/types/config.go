@@ -23,6 +23,7 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -40,10 +41,16 @@ const (
 	stringType            = "string"
 	stringSliceType       = "slice"
 	intType               = "int"
+	floatType             = "float"
 	boolType              = "bool"
 	secondsType           = "seconds"
 	urlType               = "url"
 	serverlessBackendType = "serverlessBackend"
+	oidcIssuersType       = "oidcIssuers"
+	voMappingFileType     = "voMappingFile"
+	regexpType            = "regexp"
+	roleType              = "role"
+	resourceListType      = "resourceList"
 )
 
 type configVar struct {
@@ -59,6 +66,11 @@ type Config struct {
 	// MinIOProvider access info
 	MinIOProvider *MinIOProvider `json:"minio_provider"`
 
+	// SecondaryMinIOProvider access info of the MinIO (or S3-compatible) provider replicating the OSCAR's MinIO, used
+	// as the default provider of the new services and jobs while the former is unreachable (no failover if its
+	// endpoint is empty)
+	SecondaryMinIOProvider *MinIOProvider `json:"-"`
+
 	// Basic auth username
 	Username string `json:"-"`
 
@@ -80,6 +92,9 @@ type Config struct {
 	// Port used for the ClusterIP k8s service (default: 8080)
 	ServicePort int `json:"-"`
 
+	// GRPCPort port of the gRPC API, served alongside the REST API (default: 0, disabled)
+	GRPCPort int `json:"-"`
+
 	// Serverless framework used to deploy services (Openfaas | Knative)
 	// If not defined only async invocations allowed (Using KubeBackend)
 	ServerlessBackend string `json:"serverless_backend,omitempty"`
@@ -123,6 +138,35 @@ type Config struct {
 	// WatchdogHealthCheckInterval
 	WatchdogHealthCheckInterval int `json:"-"`
 
+	// SupervisorImage image repository used for the services setting only the version of their FaaS Supervisor
+	SupervisorImage string `json:"-"`
+
+	// SupervisorImages allow-list of the images (repositories or references with tag) of the FaaS Supervisor
+	// builds that can be used by the services. Custom builds are not allowed if empty
+	SupervisorImages []string `json:"-"`
+
+	// DefaultRuntimeClass RuntimeClass of the services not defining their runtime_class (e.g. "gvisor").
+	// The runtime of the nodes is used if empty
+	DefaultRuntimeClass string `json:"-"`
+
+	// RuntimeClasses allow-list of the RuntimeClasses (besides the default one) that can be used by the services
+	RuntimeClasses []string `json:"-"`
+
+	// UntrustedVOs VOs whose services run with the secure defaults: non-root user, all capabilities dropped, no
+	// privilege escalation and the runtime's default seccomp profile ("*" for all the services). The services
+	// without VO are also untrusted if it is not empty
+	UntrustedVOs []string `json:"-"`
+
+	// UntrustedRunAsUser UID running the services of the untrusted VOs not setting their run_as_user
+	UntrustedRunAsUser int `json:"-"`
+
+	// SupervisorBinaryPath path of the FaaS Supervisor binary in the allowed images
+	SupervisorBinaryPath string `json:"-"`
+
+	// SupervisorVersion version of the FaaS Supervisor installed in the OSCAR PVC, reported as the one used by the
+	// services without a custom build
+	SupervisorVersion string `json:"-"`
+
 	// HTTP timeout for reading the payload (default: 300)
 	ReadTimeout time.Duration `json:"-"`
 
@@ -141,6 +185,24 @@ type Config struct {
 	// YunikornConfigFileName
 	YunikornConfigFileName string `json:"-"`
 
+	// YunikornPartition YuniKorn partition where the services' queues are created
+	YunikornPartition string `json:"-"`
+
+	// YunikornParentQueue full name of the default YuniKorn queue where the services' queues are created
+	YunikornParentQueue string `json:"-"`
+
+	// YunikornApplicationID default scheme of the YuniKorn application ID of the services' pods ("{service}" and
+	// "{vo}" are replaced by the name and the VO of the service)
+	YunikornApplicationID string `json:"-"`
+
+	// YunikornPlacement default placement of the services' jobs ("provided" to set the service's queue or "rules"
+	// to leave it to the placement rules of YuniKorn)
+	YunikornPlacement string `json:"-"`
+
+	// YunikornVOSettings YuniKorn settings of the services of each VO, with the format
+	// "<VO>:<queue|application_id|placement>=<VALUE>"
+	YunikornVOSettings []string `json:"-"`
+
 	// ResourceManagerEnable option to enable the Resource Manager to delegate jobs
 	// when there are no available resources in the cluster (if the service has replicas)
 	ResourceManagerEnable bool `json:"-"`
@@ -162,6 +224,214 @@ type Config struct {
 	// ReSchedulerThreshold default time (in seconds) that a job (with replicas) can be queued before delegating it
 	ReSchedulerThreshold int `json:"-"`
 
+	// ClusterZone zone (e.g. region or site) where the cluster is deployed, used by the "data-locality" delegation policy
+	ClusterZone string `json:"-"`
+
+	// CarbonIntensity carbon intensity of the energy consumed by the cluster (in gCO2eq/kWh),
+	// used by the "energy-aware" delegation policy
+	CarbonIntensity float64 `json:"-"`
+
+	// CarbonIntensityURL URL of the API returning the carbon intensity forecast of the cluster (list of windows
+	// with "from", "to" and "intensity" fields), used to delay the jobs of deferrable services to low-carbon windows
+	CarbonIntensityURL string `json:"-"`
+
+	// CarbonIntensityThreshold carbon intensity (in gCO2eq/kWh) below which the jobs of deferrable services are not delayed
+	CarbonIntensityThreshold float64 `json:"-"`
+
+	// DeferrableMaxDelay default time (in seconds) that the jobs of deferrable services can be delayed
+	DeferrableMaxDelay time.Duration `json:"-"`
+
+	// DeferredJobsInterval time interval (in seconds) to check if the deferred jobs must be released
+	DeferredJobsInterval int `json:"-"`
+
+	// KEDAEnable option to consume the events of the services with workers (see WorkerPool) with KEDA ScaledJobs
+	KEDAEnable bool `json:"keda_enable"`
+
+	// KEDAReconcileInterval time interval (in seconds) to reconcile the ScaledJobs of the services with workers
+	KEDAReconcileInterval int `json:"-"`
+
+	// CPUPowerWatts estimated power consumption (in watts) of a CPU core, used to report the avoided emissions
+	CPUPowerWatts float64 `json:"-"`
+
+	// DelegationMetricsTTL time (in seconds) that the capacity of the clusters is cached for the delegation policies
+	DelegationMetricsTTL time.Duration `json:"-"`
+
+	// DeadLetterInterval time interval (in seconds) to check for failed jobs to be stored in the services' dead-letter path
+	DeadLetterInterval int `json:"-"`
+
+	// OutputRoutingInterval time interval (in seconds) to check for finished jobs whose staged outputs have to be
+	// routed to the services' outputs by the tags of the files
+	OutputRoutingInterval int `json:"-"`
+
+	// InlineOutputsMaxSize maximum size (in bytes) of the output files passed in base64 to the jobs of the chained
+	// services (services' inline_outputs)
+	InlineOutputsMaxSize int `json:"-"`
+
+	// CallbackInterval time interval (in seconds) to check for finished jobs to be notified to the services' callbacks
+	CallbackInterval int `json:"-"`
+
+	// QueuedJobsInterval time interval (in seconds) to release the jobs queued by the services' max_concurrent_jobs
+	QueuedJobsInterval int `json:"-"`
+
+	// ServiceRevisionsLimit maximum number of revisions of each service definition kept for rollbacks
+	ServiceRevisionsLimit int `json:"-"`
+
+	// DeletedServicesRetention time (in seconds) that the definitions of the services deleted with "purge=false"
+	// are kept to be restored
+	DeletedServicesRetention time.Duration `json:"-"`
+
+	// DeletedServicesInterval time interval (in seconds) to purge the soft-deleted services whose retention
+	// period has expired
+	DeletedServicesInterval int `json:"-"`
+
+	// DefaultOutputProvider storage provider (e.g. "minio.default") of the service outputs declared without any.
+	// Empty to require the provider of all the outputs
+	DefaultOutputProvider string `json:"-"`
+
+	// DefaultOutputPath path of the outputs declared without provider nor path ("{service}" is replaced by the
+	// service's name)
+	DefaultOutputPath string `json:"-"`
+
+	// UploadStagingBucket bucket in the OSCAR's MinIO where the assets of the two-phase service creations are staged
+	UploadStagingBucket string `json:"-"`
+
+	// UploadSessionTTL time (in seconds) that an upload session can be used before it expires
+	UploadSessionTTL time.Duration `json:"-"`
+
+	// MalwareScannerURL address of the malware scanner of the input objects of the services with a quarantine_path,
+	// a ClamAV daemon ("clamav://host:3310") or an ICAP server ("icap://host:1344/service"). Empty to disable
+	MalwareScannerURL string `json:"-"`
+
+	// MalwareScanTimeout maximum time (in seconds) to scan an input object
+	MalwareScanTimeout time.Duration `json:"-"`
+
+	// CatalogURL location of the service catalog, a Git repository (HTTPS or SSH) or an OCI artifact
+	// ("oci://registry/repository:tag") with the FDL templates. The catalog is disabled if not set
+	CatalogURL string `json:"-"`
+
+	// CatalogRef branch or tag of the catalog's Git repository (default: the repository's default branch)
+	CatalogRef string `json:"-"`
+
+	// CatalogPath directory of the catalog with a subdirectory per template (default: the root directory)
+	CatalogPath string `json:"-"`
+
+	// CatalogInsecure option to pull the catalog's OCI artifact using plain HTTP
+	CatalogInsecure bool `json:"-"`
+
+	// CatalogRefreshInterval time (in seconds) that the fetched catalog is cached
+	CatalogRefreshInterval time.Duration `json:"-"`
+
+	// BuildsRegistry registry (and optional prefix) where the images built through the API are pushed.
+	// The image builds are disabled if not set
+	BuildsRegistry string `json:"-"`
+
+	// BuildsRegistrySecret name of the Kubernetes secret (of type kubernetes.io/dockerconfigjson) in the services
+	// namespace with the credentials to push to the BuildsRegistry. They can be read by the RUN instructions of the
+	// built Dockerfiles, so they should only allow pushing to the BuildsRegistry
+	BuildsRegistrySecret string `json:"-"`
+
+	// BuildsRegistryInsecure option to push to the BuildsRegistry using plain HTTP
+	BuildsRegistryInsecure bool `json:"-"`
+
+	// BuildsImage image of the kaniko executor used to run the builds
+	BuildsImage string `json:"-"`
+
+	// BuildsInterval time interval (in seconds) to check the finished builds and create their services
+	BuildsInterval int `json:"-"`
+
+	// LogsArchiveEnable option to archive the logs of the finished jobs in the LogsArchiveBucket, serving them once
+	// the jobs are removed
+	LogsArchiveEnable bool `json:"-"`
+
+	// LogsArchiveBucket bucket in the OSCAR's MinIO where the jobs' logs are archived ("<SERVICE>/<JOB>.log")
+	LogsArchiveBucket string `json:"-"`
+
+	// LogsArchiveInterval time interval (in seconds) to check for finished jobs whose logs are not archived
+	LogsArchiveInterval int `json:"-"`
+
+	// EmailTriggersEnable option to enable the polling of the services' IMAP mailboxes (EmailTrigger)
+	EmailTriggersEnable bool `json:"-"`
+
+	// EmailNotificationsEnable option to enable the notification of the services' finished jobs by email (EmailNotification)
+	EmailNotificationsEnable bool `json:"-"`
+
+	// ChatNotificationsEnable option to enable the notification of the services' finished jobs to messaging platforms
+	// (ChatNotification)
+	ChatNotificationsEnable bool `json:"-"`
+
+	// NotificationInterval time interval (in seconds) to check for finished jobs to be notified by email or
+	// to messaging platforms
+	NotificationInterval int `json:"-"`
+
+	// SMTPHost address of the SMTP server with port used to send the cluster emails (e.g. usage reports)
+	SMTPHost string `json:"-"`
+
+	// SMTPUsername username to login in the SMTP server
+	SMTPUsername string `json:"-"`
+
+	// SMTPPassword password to login in the SMTP server
+	SMTPPassword string `json:"-"`
+
+	// SMTPFrom sender address of the cluster emails
+	SMTPFrom string `json:"-"`
+
+	// ReportsEnable option to enable the periodic usage reports emailed to the VO managers
+	ReportsEnable bool `json:"-"`
+
+	// ReportsInterval time interval (in seconds) covered by each usage report
+	ReportsInterval time.Duration `json:"-"`
+
+	// ReportsRecipients comma-separated list of "VO=email" pairs with the managers of each VO
+	ReportsRecipients []string `json:"-"`
+
+	// Quotas comma-separated list of "<vo|user>:<NAME>:<QUOTA>=<LIMIT>" entries with the resources that each VO
+	// and user can consume in the QuotasPeriod. Valid quotas are "jobs", "cpu_seconds", "memory_seconds" (GiB-seconds)
+	// and "storage_bytes"
+	Quotas []string `json:"-"`
+
+	// RBACEnable parameter to enforce the roles of the users (admin, operator, invoker or viewer) in the API
+	RBACEnable bool `json:"-"`
+
+	// RBACRoles comma-separated list of "<user|group>:<NAME>=<ROLE>" entries mapping the users (basic auth
+	// usernames or OIDC subjects) and OIDC groups to their roles. The highest role mapped to a user applies
+	RBACRoles []string `json:"-"`
+
+	// RBACDefaultRole role of the authenticated users not mapped in RBACRoles
+	RBACDefaultRole string `json:"-"`
+
+	// VONamespaces parameter to create the services with a VO in a dedicated namespace per VO, limited by the
+	// VONamespaceQuota ResourceQuota and the VONamespaceLimits LimitRange
+	VONamespaces bool `json:"-"`
+
+	// VONamespaceQuota comma-separated list of "<RESOURCE>=<QUANTITY>" pairs with the hard limits of the
+	// ResourceQuota of each VO namespace (e.g. "requests.cpu=16,requests.memory=32Gi,pods=100")
+	VONamespaceQuota string `json:"-"`
+
+	// VONamespaceLimits comma-separated list of "<RESOURCE>=<QUANTITY>" pairs with the default limits of the
+	// containers (LimitRange) in each VO namespace, also used as their default requests (e.g. "cpu=1,memory=1Gi")
+	VONamespaceLimits string `json:"-"`
+
+	// QuotasPeriod time interval (in seconds) in which the usage of the quotas is accounted
+	QuotasPeriod time.Duration `json:"-"`
+
+	// QuotasInterval time interval (in seconds) to refresh the storage usage of the VOs and users with quotas
+	QuotasInterval int `json:"-"`
+
+	// JobCredentialsEnable option to pass to the jobs temporary credentials of the OSCAR's MinIO (obtained through its
+	// STS AssumeRole API) limited to the inputs and outputs of their services, instead of the long-lived ones
+	JobCredentialsEnable bool `json:"-"`
+
+	// JobCredentialsDuration time (in seconds) that the temporary credentials of the jobs are valid (min. 900)
+	JobCredentialsDuration time.Duration `json:"-"`
+
+	// JobCredentialsWebIdentity option to obtain the temporary credentials of the jobs invoked with the OIDC token of
+	// a user (IdentityTokenHeader) through the STS AssumeRoleWithWebIdentity API of the OSCAR's MinIO
+	JobCredentialsWebIdentity bool `json:"-"`
+
+	// JobCredentialsRoleARN ARN of the MinIO role assumed with the OIDC tokens of the users (optional, the claim-based
+	// policies of the users are applied if not set)
+	JobCredentialsRoleARN string `json:"-"`
+
 	// OIDCEnable parameter to enable OIDC support
 	OIDCEnable bool `json:"-"`
 
@@ -176,8 +446,154 @@ type Config struct {
 	// as described here: https://docs.egi.eu/providers/check-in/sp/#10-groups
 	OIDCGroups []string `json:"-"`
 
+	// OIDCGroupsClaim claim of the user info with the groups of the users (e.g. "groups", "roles" or the nested
+	// "realm_access.roles"). Default: "eduperson_entitlement"
+	OIDCGroupsClaim string `json:"-"`
+
+	// OIDCGroupsPattern regular expression to extract the group names from the values of the groups claim, ignoring
+	// the values not matching it
+	OIDCGroupsPattern string `json:"-"`
+
+	// OIDCGroupsTemplate template of the group names with the submatches of OIDCGroupsPattern (e.g. "$1" or
+	// "${vo}"). Default: the first submatch, or the whole match if the pattern has no submatches
+	OIDCGroupsTemplate string `json:"-"`
+
+	// OIDCOffline parameter to authorise from the claims of the validated tokens (subject, audience and groups),
+	// without requesting the user info to the issuer on each new token
+	OIDCOffline bool `json:"-"`
+
+	// OIDCAudience audience required in the "aud" claim of the tokens, not checked if empty
+	OIDCAudience string `json:"-"`
+
+	// OIDCJWKSURL URL of the JSON Web Key Set of the issuer to validate the tokens in offline mode, without
+	// discovering its provider
+	OIDCJWKSURL string `json:"-"`
+
+	// OIDCIssuers JSON list of additional trusted OpenID Connect issuers, each with its own subject, groups, claim
+	// names and group mapping (see OIDCIssuer). The issuer of each token is selected by its "iss" claim
+	OIDCIssuers []OIDCIssuer `json:"-"`
+
+	// VOMappings membership rules of the VOs declared by the services (aliases, sub-group wildcards and required
+	// roles), read from the JSON or YAML list of the VO_MAPPING_FILE (see VOMapping)
+	VOMappings []VOMapping `json:"-"`
+
 	//
 	IngressHost string `json:"-"`
+
+	// CertManagerIssuer name of the cert-manager ClusterIssuer of the certificates of the exposed services' custom
+	// domains (unless they define their own)
+	CertManagerIssuer string `json:"-"`
+
+	// VaultAddress URL of the HashiCorp Vault server to read the credentials of the storage providers referenced
+	// as "vault://<PATH>#<FIELD>"
+	VaultAddress string `json:"-"`
+
+	// VaultToken token to authenticate in Vault. If empty, OSCAR logs in with its service account token through the
+	// Kubernetes auth method (VaultRole)
+	VaultToken string `json:"-"`
+
+	// VaultRole role of the Kubernetes auth method of Vault
+	VaultRole string `json:"-"`
+
+	// VaultAuthPath mount path of the Kubernetes auth method of Vault
+	VaultAuthPath string `json:"-"`
+
+	// VaultPathPrefix comma-separated list of the prefixes of the Vault paths that the services can reference. Each
+	// service can only reference the paths under "<PREFIX>/<VO>" of its VO (no path if empty)
+	VaultPathPrefix []string `json:"-"`
+
+	// ClientCertCA path of the PEM bundle of the CAs signing the client certificates accepted to authenticate the
+	// users (the client certificate authentication is disabled if empty)
+	ClientCertCA string `json:"-"`
+
+	// ClientCertUsers comma-separated list of "<DN>=<USER>" entries mapping the subject DNs of the client certificates
+	// (in the "/DC=org/O=Example/CN=Alice" format) to users
+	ClientCertUsers []string `json:"-"`
+
+	// ClientCertHeader header with the (URL-encoded PEM) client certificate verified by the TLS-terminating proxy in
+	// front of OSCAR (e.g. "ssl-client-cert" in the NGINX Ingress Controller), only accepted from the proxies in
+	// TRUSTED_PROXIES. Only the client certificates of the TLS connections are accepted if empty
+	ClientCertHeader string `json:"-"`
+
+	// TLSCertFile path of the certificate to serve the API over HTTPS (plain HTTP if empty)
+	TLSCertFile string `json:"-"`
+
+	// TLSKeyFile path of the private key of TLSCertFile
+	TLSKeyFile string `json:"-"`
+
+	// AuditSink sink of the audit log of the management operations and invocations ("file", "minio" or "syslog",
+	// disabled if empty)
+	AuditSink string `json:"-"`
+
+	// AuditFile path of the file of the audit log (file sink)
+	AuditFile string `json:"-"`
+
+	// AuditBucket bucket of the OSCAR's MinIO storing the audit log (minio sink)
+	AuditBucket string `json:"-"`
+
+	// AuditSyslogAddress address of the syslog server ("<udp|tcp>://<host>:<port>", the local one if empty) of the
+	// audit log (syslog sink)
+	AuditSyslogAddress string `json:"-"`
+
+	// RateLimitIP requests per minute allowed to each client IP in the /system and invocation paths (0 disables it)
+	RateLimitIP int `json:"-"`
+
+	// RateLimitToken requests per minute allowed to each credential (basic auth or token) in the /system and
+	// invocation paths (0 disables it)
+	RateLimitToken int `json:"-"`
+
+	// RateLimitBurst requests allowed at once by the rate limits (their requests per minute if 0)
+	RateLimitBurst int `json:"-"`
+
+	// AuthFailureLimit failed authentications of a client IP after which its requests are rejected for
+	// AuthFailureWindow (0 disables it)
+	AuthFailureLimit int `json:"-"`
+
+	// AuthFailureWindow time (in seconds) in which the failed authentications are counted and the client IPs are
+	// blocked
+	AuthFailureWindow time.Duration `json:"-"`
+
+	// TrustedProxies comma-separated list of the IPs or CIDRs of the proxies whose X-Forwarded-For header is trusted to
+	// get the client IPs (none if empty, using the IPs of the connections)
+	TrustedProxies []string `json:"-"`
+
+	// AuthzWebhook URL of the external policy service (e.g. OPA) authorizing the management operations and the
+	// invocations (disabled if empty)
+	AuthzWebhook string `json:"-"`
+
+	// AuthzWebhookTimeout time (in seconds) to wait for the decisions of AuthzWebhook
+	AuthzWebhookTimeout time.Duration `json:"-"`
+
+	// AuthzWebhookFailOpen option to allow the requests when AuthzWebhook fails (they are denied otherwise)
+	AuthzWebhookFailOpen bool `json:"-"`
+
+	// UserStore store of the users authenticated with basic auth besides the admin ("file", "ldap" or "scim",
+	// disabled if empty)
+	UserStore string `json:"-"`
+
+	// UserStoreFile path of the JSON file with the users of the "file" and "scim" stores
+	UserStoreFile string `json:"-"`
+
+	// LDAPURL URL of the LDAP server of the "ldap" store (ldap:// or ldaps://)
+	LDAPURL string `json:"-"`
+
+	// LDAPBindDN DN of the account searching the users in the LDAP server (anonymous search if empty)
+	LDAPBindDN string `json:"-"`
+
+	// LDAPBindPassword password of LDAPBindDN
+	LDAPBindPassword string `json:"-"`
+
+	// LDAPBaseDN base DN of the users in the LDAP server
+	LDAPBaseDN string `json:"-"`
+
+	// LDAPUserFilter filter of the LDAP search of the users, with "%s" replaced by the username
+	LDAPUserFilter string `json:"-"`
+
+	// LDAPGroupAttribute attribute of the LDAP users with the DNs of their groups
+	LDAPGroupAttribute string `json:"-"`
+
+	// SCIMToken bearer token of the identity provider provisioning the users of the "scim" store
+	SCIMToken string `json:"-"`
 }
 
 var configVars = []configVar{
@@ -188,6 +604,11 @@ var configVars = []configVar{
 	{"MinIOProvider.Region", "MINIO_REGION", false, stringType, "us-east-1"},
 	{"MinIOProvider.Verify", "MINIO_TLS_VERIFY", false, boolType, "true"},
 	{"MinIOProvider.Endpoint", "MINIO_ENDPOINT", false, urlType, "https://minio-service.minio:9000"},
+	{"SecondaryMinIOProvider.AccessKey", "SECONDARY_MINIO_ACCESS_KEY", false, stringType, ""},
+	{"SecondaryMinIOProvider.SecretKey", "SECONDARY_MINIO_SECRET_KEY", false, stringType, ""},
+	{"SecondaryMinIOProvider.Region", "SECONDARY_MINIO_REGION", false, stringType, "us-east-1"},
+	{"SecondaryMinIOProvider.Verify", "SECONDARY_MINIO_TLS_VERIFY", false, boolType, "true"},
+	{"SecondaryMinIOProvider.Endpoint", "SECONDARY_MINIO_ENDPOINT", false, urlType, ""},
 	{"Name", "OSCAR_NAME", false, stringType, "oscar"},
 	{"Namespace", "OSCAR_NAMESPACE", false, stringType, "oscar"},
 	{"ServicesNamespace", "OSCAR_SERVICES_NAMESPACE", false, stringType, "oscar-svc"},
@@ -205,24 +626,140 @@ var configVars = []configVar{
 	{"WatchdogReadTimeout", "WATCHDOG_READ_TIMEOUT", false, intType, "300"},
 	{"WatchdogWriteTimeout", "WATCHDOG_WRITE_TIMEOUT", false, intType, "300"},
 	{"WatchdogHealthCheckInterval", "WATCHDOG_HEALTHCHECK_INTERVAL", false, intType, "5"},
+	{"SupervisorImage", "SUPERVISOR_IMAGE", false, stringType, "ghcr.io/grycap/faas-supervisor"},
+	{"SupervisorImages", "SUPERVISOR_IMAGES", false, stringSliceType, ""},
+	{"DefaultRuntimeClass", "DEFAULT_RUNTIME_CLASS", false, stringType, ""},
+	{"RuntimeClasses", "RUNTIME_CLASSES", false, stringSliceType, ""},
+	{"UntrustedVOs", "UNTRUSTED_VOS", false, stringSliceType, ""},
+	{"UntrustedRunAsUser", "UNTRUSTED_RUN_AS_USER", false, intType, "1000"},
+	{"SupervisorBinaryPath", "SUPERVISOR_BINARY_PATH", false, stringType, "/supervisor"},
+	{"SupervisorVersion", "SUPERVISOR_VERSION", false, stringType, ""},
 	{"ReadTimeout", "READ_TIMEOUT", false, secondsType, "300"},
 	{"WriteTimeout", "WRITE_TIMEOUT", false, secondsType, "300"},
 	{"ServicePort", "OSCAR_SERVICE_PORT", false, intType, "8080"},
+	{"GRPCPort", "GRPC_PORT", false, intType, "0"},
 	{"YunikornEnable", "YUNIKORN_ENABLE", false, boolType, "false"},
 	{"YunikornNamespace", "YUNIKORN_NAMESPACE", false, stringType, "yunikorn"},
 	{"YunikornConfigMap", "YUNIKORN_CONFIGMAP", false, stringType, "yunikorn-configs"},
 	{"YunikornConfigFileName", "YUNIKORN_CONFIG_FILENAME", false, stringType, "queues.yaml"},
+	{"YunikornPartition", "YUNIKORN_PARTITION", false, stringType, "default"},
+	{"YunikornParentQueue", "YUNIKORN_PARENT_QUEUE", false, stringType, "root.oscar-queue"},
+	{"YunikornApplicationID", "YUNIKORN_APPLICATION_ID", false, stringType, "{service}"},
+	{"YunikornPlacement", "YUNIKORN_PLACEMENT", false, stringType, "provided"},
+	{"YunikornVOSettings", "YUNIKORN_VO_SETTINGS", false, stringSliceType, ""},
 	{"ResourceManagerEnable", "RESOURCE_MANAGER_ENABLE", false, boolType, "false"},
 	//{"ResourceManager", "RESOURCE_MANAGER", false, resourceManagerType, "kubernetes"},
 	{"ResourceManagerInterval", "RESOURCE_MANAGER_INTERVAL", false, intType, "15"},
 	{"ReSchedulerEnable", "RESCHEDULER_ENABLE", false, boolType, "false"},
 	{"ReSchedulerInterval", "RESCHEDULER_INTERVAL", false, intType, "15"},
 	{"ReSchedulerThreshold", "RESCHEDULER_THRESHOLD", false, intType, "30"},
+	{"ClusterZone", "CLUSTER_ZONE", false, stringType, ""},
+	{"CarbonIntensity", "CARBON_INTENSITY", false, floatType, "0"},
+	{"DelegationMetricsTTL", "DELEGATION_METRICS_TTL", false, secondsType, "30"},
+	{"CarbonIntensityURL", "CARBON_INTENSITY_URL", false, stringType, ""},
+	{"CarbonIntensityThreshold", "CARBON_INTENSITY_THRESHOLD", false, floatType, "0"},
+	{"DeferrableMaxDelay", "DEFERRABLE_MAX_DELAY", false, secondsType, "21600"},
+	{"DeferredJobsInterval", "DEFERRED_JOBS_INTERVAL", false, intType, "60"},
+	{"KEDAEnable", "KEDA_ENABLE", false, boolType, "false"},
+	{"KEDAReconcileInterval", "KEDA_RECONCILE_INTERVAL", false, intType, "30"},
+	{"CPUPowerWatts", "CPU_POWER_WATTS", false, floatType, "10"},
+	{"DeadLetterInterval", "DEADLETTER_INTERVAL", false, intType, "30"},
+	{"OutputRoutingInterval", "OUTPUT_ROUTING_INTERVAL", false, intType, "10"},
+	{"InlineOutputsMaxSize", "INLINE_OUTPUTS_MAX_SIZE", false, intType, "262144"},
+	{"CallbackInterval", "CALLBACK_INTERVAL", false, intType, "30"},
+	{"QueuedJobsInterval", "QUEUED_JOBS_INTERVAL", false, intType, "10"},
+	{"ServiceRevisionsLimit", "SERVICE_REVISIONS_LIMIT", false, intType, "10"},
+	{"DeletedServicesRetention", "DELETED_SERVICES_RETENTION", false, secondsType, "604800"},
+	{"DeletedServicesInterval", "DELETED_SERVICES_INTERVAL", false, intType, "300"},
+	{"DefaultOutputProvider", "DEFAULT_OUTPUT_PROVIDER", false, stringType, "minio.default"},
+	{"DefaultOutputPath", "DEFAULT_OUTPUT_PATH", false, stringType, "outputs/{service}"},
+	{"UploadStagingBucket", "UPLOAD_STAGING_BUCKET", false, stringType, "oscar-uploads"},
+	{"UploadSessionTTL", "UPLOAD_SESSION_TTL", false, secondsType, "86400"},
+	{"MalwareScannerURL", "MALWARE_SCANNER_URL", false, stringType, ""},
+	{"MalwareScanTimeout", "MALWARE_SCAN_TIMEOUT", false, secondsType, "60"},
+	{"CatalogURL", "CATALOG_URL", false, stringType, ""},
+	{"CatalogRef", "CATALOG_REF", false, stringType, ""},
+	{"CatalogPath", "CATALOG_PATH", false, stringType, ""},
+	{"CatalogInsecure", "CATALOG_INSECURE", false, boolType, "false"},
+	{"CatalogRefreshInterval", "CATALOG_REFRESH_INTERVAL", false, secondsType, "600"},
+	{"BuildsRegistry", "BUILDS_REGISTRY", false, stringType, ""},
+	{"BuildsRegistrySecret", "BUILDS_REGISTRY_SECRET", false, stringType, ""},
+	{"BuildsRegistryInsecure", "BUILDS_REGISTRY_INSECURE", false, boolType, "false"},
+	{"BuildsImage", "BUILDS_IMAGE", false, stringType, "gcr.io/kaniko-project/executor:v1.23.2"},
+	{"BuildsInterval", "BUILDS_INTERVAL", false, intType, "30"},
+	{"LogsArchiveEnable", "LOGS_ARCHIVE_ENABLE", false, boolType, "false"},
+	{"LogsArchiveBucket", "LOGS_ARCHIVE_BUCKET", false, stringType, "oscar-logs"},
+	{"LogsArchiveInterval", "LOGS_ARCHIVE_INTERVAL", false, intType, "30"},
+	{"EmailTriggersEnable", "EMAIL_TRIGGERS_ENABLE", false, boolType, "false"},
+	{"EmailNotificationsEnable", "EMAIL_NOTIFICATIONS_ENABLE", false, boolType, "false"},
+	{"ChatNotificationsEnable", "CHAT_NOTIFICATIONS_ENABLE", false, boolType, "false"},
+	{"NotificationInterval", "NOTIFICATION_INTERVAL", false, intType, "30"},
+	{"SMTPHost", "SMTP_HOST", false, stringType, ""},
+	{"SMTPUsername", "SMTP_USERNAME", false, stringType, ""},
+	{"SMTPPassword", "SMTP_PASSWORD", false, stringType, ""},
+	{"SMTPFrom", "SMTP_FROM", false, stringType, ""},
+	{"ReportsEnable", "REPORTS_ENABLE", false, boolType, "false"},
+	{"ReportsInterval", "REPORTS_INTERVAL", false, secondsType, "604800"},
+	{"ReportsRecipients", "REPORTS_RECIPIENTS", false, stringSliceType, ""},
+	{"Quotas", "QUOTAS", false, stringSliceType, ""},
+	{"QuotasPeriod", "QUOTAS_PERIOD", false, secondsType, "2592000"},
+	{"RBACEnable", "RBAC_ENABLE", false, boolType, "false"},
+	{"RBACRoles", "RBAC_ROLES", false, stringSliceType, ""},
+	{"RBACDefaultRole", "RBAC_DEFAULT_ROLE", false, roleType, "viewer"},
+	{"VONamespaces", "VO_NAMESPACES", false, boolType, "false"},
+	{"VONamespaceQuota", "VO_NAMESPACE_QUOTA", false, resourceListType, ""},
+	{"VONamespaceLimits", "VO_NAMESPACE_LIMITS", false, resourceListType, ""},
+	{"QuotasInterval", "QUOTAS_INTERVAL", false, intType, "300"},
+	{"JobCredentialsEnable", "JOB_CREDENTIALS_ENABLE", false, boolType, "false"},
+	{"JobCredentialsDuration", "JOB_CREDENTIALS_DURATION", false, secondsType, "3600"},
+	{"JobCredentialsWebIdentity", "JOB_CREDENTIALS_WEB_IDENTITY", false, boolType, "false"},
+	{"JobCredentialsRoleARN", "JOB_CREDENTIALS_ROLE_ARN", false, stringType, ""},
 	{"OIDCEnable", "OIDC_ENABLE", false, boolType, "false"},
 	{"OIDCIssuer", "OIDC_ISSUER", false, stringType, "https://aai.egi.eu/oidc/"},
 	{"OIDCSubject", "OIDC_SUBJECT", false, stringType, ""},
 	{"OIDCGroups", "OIDC_GROUPS", false, stringSliceType, ""},
+	{"OIDCGroupsClaim", "OIDC_GROUPS_CLAIM", false, stringType, ""},
+	{"OIDCGroupsPattern", "OIDC_GROUPS_PATTERN", false, regexpType, ""},
+	{"OIDCGroupsTemplate", "OIDC_GROUPS_TEMPLATE", false, stringType, ""},
+	{"OIDCOffline", "OIDC_OFFLINE", false, boolType, "false"},
+	{"OIDCAudience", "OIDC_AUDIENCE", false, stringType, ""},
+	{"OIDCJWKSURL", "OIDC_JWKS_URL", false, urlType, ""},
+	{"OIDCIssuers", "OIDC_ISSUERS", false, oidcIssuersType, ""},
+	{"VOMappings", "VO_MAPPING_FILE", false, voMappingFileType, ""},
 	{"IngressHost", "INGRESS_HOST", false, stringType, ""},
+	{"CertManagerIssuer", "CERT_MANAGER_ISSUER", false, stringType, ""},
+	{"VaultAddress", "VAULT_ADDR", false, urlType, ""},
+	{"VaultToken", "VAULT_TOKEN", false, stringType, ""},
+	{"VaultRole", "VAULT_ROLE", false, stringType, "oscar"},
+	{"VaultAuthPath", "VAULT_AUTH_PATH", false, stringType, "kubernetes"},
+	{"VaultPathPrefix", "VAULT_PATH_PREFIX", false, stringSliceType, ""},
+	{"ClientCertCA", "CLIENT_CERT_CA", false, stringType, ""},
+	{"ClientCertUsers", "CLIENT_CERT_USERS", false, stringSliceType, ""},
+	{"ClientCertHeader", "CLIENT_CERT_HEADER", false, stringType, ""},
+	{"TLSCertFile", "TLS_CERT_FILE", false, stringType, ""},
+	{"TLSKeyFile", "TLS_KEY_FILE", false, stringType, ""},
+	{"AuditSink", "AUDIT_SINK", false, stringType, ""},
+	{"AuditFile", "AUDIT_FILE", false, stringType, "/var/log/oscar/audit.log"},
+	{"AuditBucket", "AUDIT_BUCKET", false, stringType, "oscar-audit"},
+	{"AuditSyslogAddress", "AUDIT_SYSLOG_ADDRESS", false, stringType, ""},
+	{"RateLimitIP", "RATE_LIMIT_IP", false, intType, "0"},
+	{"RateLimitToken", "RATE_LIMIT_TOKEN", false, intType, "0"},
+	{"RateLimitBurst", "RATE_LIMIT_BURST", false, intType, "0"},
+	{"AuthFailureLimit", "AUTH_FAILURE_LIMIT", false, intType, "0"},
+	{"AuthFailureWindow", "AUTH_FAILURE_WINDOW", false, secondsType, "300"},
+	{"TrustedProxies", "TRUSTED_PROXIES", false, stringSliceType, ""},
+	{"AuthzWebhook", "AUTHZ_WEBHOOK", false, urlType, ""},
+	{"AuthzWebhookTimeout", "AUTHZ_WEBHOOK_TIMEOUT", false, secondsType, "5"},
+	{"AuthzWebhookFailOpen", "AUTHZ_WEBHOOK_FAIL_OPEN", false, boolType, "false"},
+	{"UserStore", "USER_STORE", false, stringType, ""},
+	{"UserStoreFile", "USER_STORE_FILE", false, stringType, "/var/lib/oscar/users.json"},
+	{"LDAPURL", "LDAP_URL", false, urlType, ""},
+	{"LDAPBindDN", "LDAP_BIND_DN", false, stringType, ""},
+	{"LDAPBindPassword", "LDAP_BIND_PASSWORD", false, stringType, ""},
+	{"LDAPBaseDN", "LDAP_BASE_DN", false, stringType, ""},
+	{"LDAPUserFilter", "LDAP_USER_FILTER", false, stringType, "(uid=%s)"},
+	{"LDAPGroupAttribute", "LDAP_GROUP_ATTRIBUTE", false, stringType, "memberOf"},
+	{"SCIMToken", "SCIM_TOKEN", false, stringType, ""},
 }
 
 func readConfigVar(cfgVar configVar) (string, error) {
@@ -296,6 +833,7 @@ func parseServerlessBackend(s string) (string, error) {
 func ReadConfig() (*Config, error) {
 	config := &Config{}
 	config.MinIOProvider = &MinIOProvider{}
+	config.SecondaryMinIOProvider = &MinIOProvider{}
 
 	for _, cv := range configVars {
 		var value any
@@ -313,16 +851,35 @@ func ReadConfig() (*Config, error) {
 			value = parseStringSlice(strValue)
 		case intType:
 			value, parseErr = strconv.Atoi(strValue)
+		case floatType:
+			value, parseErr = strconv.ParseFloat(strValue, 64)
 		case boolType:
 			value, parseErr = strconv.ParseBool(strValue)
 		case secondsType:
 			value, parseErr = parseSeconds(strValue)
 		case serverlessBackendType:
 			value, parseErr = parseServerlessBackend(strValue)
+		case oidcIssuersType:
+			value, parseErr = ParseOIDCIssuers(strValue)
+		case voMappingFileType:
+			value, parseErr = ReadVOMappingFile(strValue)
 		case urlType:
 			// Only check if can be parsed
 			_, parseErr = url.Parse(strValue)
 			value = strValue
+		case regexpType:
+			// Only check if can be compiled
+			_, parseErr = regexp.Compile(strings.TrimSpace(strValue))
+			value = strings.TrimSpace(strValue)
+		case resourceListType:
+			// Only check if can be parsed
+			_, parseErr = ParseResourceList(strValue)
+			value = strings.TrimSpace(strValue)
+		case roleType:
+			value = strings.TrimSpace(strValue)
+			if !IsValidRole(strings.TrimSpace(strValue)) {
+				parseErr = fmt.Errorf("valid roles are \"%s\", \"%s\", \"%s\" and \"%s\"", RoleAdmin, RoleOperator, RoleInvoker, RoleViewer)
+			}
 		default:
 			continue
 		}
@@ -340,6 +897,116 @@ func ReadConfig() (*Config, error) {
 	return config, nil
 }
 
+// HasSecondaryMinIO checks if the failover of the OSCAR's MinIO to a secondary provider is configured
+func (cfg *Config) HasSecondaryMinIO() bool {
+	return cfg.SecondaryMinIOProvider != nil && cfg.SecondaryMinIOProvider.Endpoint != ""
+}
+
+// GetTrustedProxies returns the IPs or CIDRs of the trusted proxies, ignoring the empty entries (nil if there are
+// none, so no proxy is trusted)
+func (cfg *Config) GetTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range cfg.TrustedProxies {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// GetVaultPathPrefixes returns the prefixes of the Vault paths that the services can reference, without the
+// surrounding slashes and ignoring the empty entries
+func (cfg *Config) GetVaultPathPrefixes() []string {
+	var prefixes []string
+	for _, prefix := range cfg.VaultPathPrefix {
+		if prefix = strings.Trim(strings.TrimSpace(prefix), "/"); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// GetReportsRecipients returns the email addresses of the managers of each VO
+func (cfg *Config) GetReportsRecipients() map[string][]string {
+	recipients := map[string][]string{}
+	for _, pair := range cfg.ReportsRecipients {
+		split := strings.SplitN(pair, "=", 2)
+		if len(split) != 2 || strings.TrimSpace(split[0]) == "" || strings.TrimSpace(split[1]) == "" {
+			continue
+		}
+		vo := strings.TrimSpace(split[0])
+		recipients[vo] = append(recipients[vo], strings.TrimSpace(split[1]))
+	}
+	return recipients
+}
+
+// GetQuotas returns the quotas of each VO and user, indexed by scope ("vo" or "user") and name, including the ones
+// of the approved VOs. Invalid entries are ignored
+func (cfg *Config) GetQuotas() map[string]map[string]*Quota {
+	quotas := map[string]map[string]*Quota{QuotaScopeVO: {}, QuotaScopeUser: {}}
+	for _, entry := range cfg.Quotas {
+		split := strings.SplitN(entry, "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(split[1]), 64)
+		if err != nil || limit < 0 {
+			continue
+		}
+		// The quota name is the last field, as the names of the users can contain ":"
+		key := strings.TrimSpace(split[0])
+		scopeSep, quotaSep := strings.Index(key, ":"), strings.LastIndex(key, ":")
+		if scopeSep < 0 || quotaSep <= scopeSep+1 {
+			continue
+		}
+		scoped, ok := quotas[key[:scopeSep]]
+		if !ok {
+			continue
+		}
+		name := key[scopeSep+1 : quotaSep]
+		quota := scoped[name]
+		if quota == nil {
+			quota = &Quota{}
+		}
+		if err := quota.Set(key[quotaSep+1:], limit); err != nil {
+			continue
+		}
+		scoped[name] = quota
+	}
+	// The quotas granted when approving the VOs apply if the QUOTAS do not define them
+	for _, vo := range GetApprovedVOs() {
+		if _, defined := quotas[QuotaScopeVO][vo.VO]; !defined && vo.Quota != nil {
+			quota := *vo.Quota
+			quotas[QuotaScopeVO][vo.VO] = &quota
+		}
+	}
+	return quotas
+}
+
+// GetYunikornVOSettings returns the YuniKorn settings of the VOs defined in YunikornVOSettings (the invalid
+// entries are ignored)
+func (cfg *Config) GetYunikornVOSettings() map[string]YunikornSettings {
+	voSettings := map[string]YunikornSettings{}
+	for _, entry := range cfg.YunikornVOSettings {
+		split := strings.SplitN(entry, "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		// The setting name is the last field, as the names of the VOs can contain ":"
+		key := strings.TrimSpace(split[0])
+		sep := strings.LastIndex(key, ":")
+		if sep <= 0 {
+			continue
+		}
+		settings := voSettings[key[:sep]]
+		if err := settings.Set(key[sep+1:], strings.TrimSpace(split[1])); err != nil || settings.Validate() != nil {
+			continue
+		}
+		voSettings[key[:sep]] = settings
+	}
+	return voSettings
+}
+
 // CheckAvailableGPUs checks if there are "nvidia.com/gpu" resources in the cluster
 func (cfg *Config) CheckAvailableGPUs(kubeClientset kubernetes.Interface) {
 	nodes, err := kubeClientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: "!node-role.kubernetes.io/control-plane,!node-role.kubernetes.io/master"})
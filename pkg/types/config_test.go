@@ -263,3 +263,92 @@ func TestServerlessBackend(t *testing.T) {
 		})
 	}
 }
+
+func TestGetReportsRecipients(t *testing.T) {
+	cfg := &Config{ReportsRecipients: []string{"vo.a=a@example.com", " vo.a = b@example.com ", "vo.b=c@example.com", "invalid", "=d@example.com"}}
+
+	recipients := cfg.GetReportsRecipients()
+
+	if len(recipients) != 2 {
+		t.Errorf("expecting 2 VOs, got %d", len(recipients))
+	}
+	if len(recipients["vo.a"]) != 2 || recipients["vo.a"][1] != "b@example.com" {
+		t.Errorf("unexpected recipients for vo.a: %v", recipients["vo.a"])
+	}
+	if len(recipients["vo.b"]) != 1 || recipients["vo.b"][0] != "c@example.com" {
+		t.Errorf("unexpected recipients for vo.b: %v", recipients["vo.b"])
+	}
+}
+
+func TestGetTrustedProxies(t *testing.T) {
+	cfg := &Config{TrustedProxies: []string{" ", ""}}
+	if proxies := cfg.GetTrustedProxies(); proxies != nil {
+		t.Errorf("expecting no trusted proxies, got %v", proxies)
+	}
+
+	cfg.TrustedProxies = []string{" 10.42.0.0/16", "", "192.168.1.1 "}
+	proxies := cfg.GetTrustedProxies()
+	if len(proxies) != 2 || proxies[0] != "10.42.0.0/16" || proxies[1] != "192.168.1.1" {
+		t.Errorf("unexpected trusted proxies: %v", proxies)
+	}
+}
+
+func TestGetVaultPathPrefixes(t *testing.T) {
+	cfg := &Config{VaultPathPrefix: []string{" /secret/data/oscar/ ", "", "/", "aws/creds"}}
+	prefixes := cfg.GetVaultPathPrefixes()
+	if len(prefixes) != 2 || prefixes[0] != "secret/data/oscar" || prefixes[1] != "aws/creds" {
+		t.Errorf("unexpected Vault path prefixes: %v", prefixes)
+	}
+}
+
+func TestGetQuotas(t *testing.T) {
+	cfg := &Config{Quotas: []string{
+		"vo:vo.example.eu:jobs=100",
+		"vo:vo.example.eu:cpu_seconds=3600",
+		"user:user:with:colons:storage_bytes=1024",
+		"user:bob:memory_seconds=7200.5",
+		"group:vo.example.eu:jobs=1",
+		"vo:vo.example.eu:gpus=1",
+		"vo:vo.example.eu:jobs=-1",
+		"user::jobs=1",
+		"",
+	}}
+
+	quotas := cfg.GetQuotas()
+	expectedVO := Quota{Jobs: 100, CPUSeconds: 3600}
+	if len(quotas[QuotaScopeVO]) != 1 || *quotas[QuotaScopeVO]["vo.example.eu"] != expectedVO {
+		t.Errorf("unexpected VO quotas: %v", quotas[QuotaScopeVO])
+	}
+	if len(quotas[QuotaScopeUser]) != 2 {
+		t.Fatalf("expected 2 user quotas, got %v", quotas[QuotaScopeUser])
+	}
+	if q := quotas[QuotaScopeUser]["user:with:colons"]; q == nil || q.StorageBytes != 1024 {
+		t.Errorf("unexpected quota of user \"user:with:colons\": %v", q)
+	}
+	if q := quotas[QuotaScopeUser]["bob"]; q == nil || q.MemorySeconds != 7200.5 {
+		t.Errorf("unexpected quota of user \"bob\": %v", q)
+	}
+}
+
+func TestOIDCGroupsPattern(t *testing.T) {
+	t.Setenv("OSCAR_USERNAME", "testuser")
+	t.Setenv("OSCAR_PASSWORD", "testpass")
+	t.Setenv("MINIO_ACCESS_KEY", "minioaccess")
+	t.Setenv("MINIO_SECRET_KEY", "miniosecret")
+	t.Setenv("OIDC_GROUPS_CLAIM", "realm_access.roles")
+	t.Setenv("OIDC_GROUPS_PATTERN", "^oscar-(.+)$")
+
+	cfg, err := ReadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	iss := cfg.GetOIDCIssuer(cfg.OIDCIssuer)
+	if iss == nil || iss.GetGroupsClaim() != "realm_access.roles" || iss.GroupsPattern != "^oscar-(.+)$" {
+		t.Errorf("unexpected primary issuer: %v", iss)
+	}
+
+	t.Setenv("OIDC_GROUPS_PATTERN", "(oscar")
+	if _, err := ReadConfig(); err == nil {
+		t.Error("expecting error with an invalid OIDC_GROUPS_PATTERN")
+	}
+}
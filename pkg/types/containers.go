@@ -0,0 +1,198 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// SharedVolumeName name of the volume shared by the service container, its init containers and its sidecars
+	SharedVolumeName = "oscar-shared"
+
+	// SharedVolumePath path to mount the shared volume
+	SharedVolumePath = "/oscar/shared"
+
+	// reservedContainerPrefix prefix of the names of the containers added by OSCAR
+	reservedContainerPrefix = "oscar-"
+
+	// sidecarsDoneFile file of the shared volume created when the service container of a job exits, to stop its
+	// sidecars
+	sidecarsDoneFile = SharedVolumePath + "/.done"
+)
+
+// ContainerSpec additional container of the service's pods, run before the service container (init containers,
+// e.g. to pre-stage data or download a model) or alongside it (sidecars, e.g. a license server proxy or a telemetry
+// agent). All of them mount the shared volume (/oscar/shared)
+type ContainerSpec struct {
+	// Name name of the container, unique in the service
+	Name string `json:"name"`
+	// Image Docker image of the container
+	Image string `json:"image"`
+	// Command entrypoint of the container, required by the sidecars
+	// Optional. (default: the entrypoint of the image)
+	Command []string `json:"command,omitempty"`
+	// Args arguments of the command
+	// Optional.
+	Args []string `json:"args,omitempty"`
+	// Environment environment variables of the container
+	// Optional.
+	Environment map[string]string `json:"environment,omitempty"`
+	// CPU and Memory limits of the container, in the Kubernetes format
+	// Optional.
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// ValidateContainers checks the init containers and the sidecars of the service
+func (service *Service) ValidateContainers() error {
+	names := map[string]bool{}
+	check := func(field string, i int, container ContainerSpec) error {
+		if errs := validation.IsDNS1123Label(container.Name); len(errs) > 0 {
+			return fmt.Errorf("the name of the %s %d is not valid: %s", field, i, errs[0])
+		}
+		if strings.HasPrefix(container.Name, reservedContainerPrefix) {
+			return fmt.Errorf("the name of the %s %d can not start with \"%s\"", field, i, reservedContainerPrefix)
+		}
+		if names[container.Name] {
+			return fmt.Errorf("the name \"%s\" of the %s %d is duplicated", container.Name, field, i)
+		}
+		names[container.Name] = true
+		if container.Image == "" {
+			return fmt.Errorf("the %s \"%s\" must define its image", field, container.Name)
+		}
+		for name := range container.Environment {
+			if errs := validation.IsEnvVarName(name); len(errs) > 0 {
+				return fmt.Errorf("the environment variable \"%s\" of the %s \"%s\" is not valid: %s", name, field, container.Name, errs[0])
+			}
+		}
+		if _, err := container.getResources(); err != nil {
+			return fmt.Errorf("the resources of the %s \"%s\" are not valid: %v", field, container.Name, err)
+		}
+		return nil
+	}
+
+	for i, container := range service.InitContainers {
+		if err := check("init container", i, container); err != nil {
+			return err
+		}
+	}
+	for i, container := range service.Sidecars {
+		if err := check("sidecar", i, container); err != nil {
+			return err
+		}
+		if len(container.Command) == 0 {
+			return fmt.Errorf("the sidecar \"%s\" must define its command", container.Name)
+		}
+	}
+	return nil
+}
+
+// AddContainers adds the init containers and the sidecars of a service to a pod spec, with the volume shared with
+// the service container
+func AddContainers(podSpec *v1.PodSpec, initContainers []ContainerSpec, sidecars []ContainerSpec) {
+	if len(initContainers) == 0 && len(sidecars) == 0 {
+		return
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name:         SharedVolumeName,
+		VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+	})
+	sharedMount := v1.VolumeMount{Name: SharedVolumeName, MountPath: SharedVolumePath}
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, sharedMount)
+	}
+
+	for _, container := range initContainers {
+		podSpec.InitContainers = append(podSpec.InitContainers, container.toContainer(sharedMount))
+	}
+	for _, container := range sidecars {
+		podSpec.Containers = append(podSpec.Containers, container.toContainer(sharedMount))
+	}
+}
+
+// TerminateSidecars stops the sidecars of a job's pod when its service container (running a "/bin/sh -c" script)
+// exits, so the job can complete
+func TerminateSidecars(podSpec *v1.PodSpec) {
+	if len(podSpec.Containers) < 2 {
+		return
+	}
+
+	for i, container := range podSpec.Containers {
+		if container.Name == ContainerName {
+			if len(container.Args) == 2 && container.Args[0] == "-c" {
+				podSpec.Containers[i].Args[1] = fmt.Sprintf("trap 'touch %s' EXIT; %s", sidecarsDoneFile, container.Args[1])
+			}
+			continue
+		}
+		script := fmt.Sprintf(`"$@" & pid=$!; while [ ! -f %s ] && kill -0 $pid 2>/dev/null; do sleep 1; done; kill $pid 2>/dev/null; exit 0`, sidecarsDoneFile)
+		command := append([]string{"/bin/sh", "-c", script, container.Name}, container.Command...)
+		podSpec.Containers[i].Command = append(command, container.Args...)
+		podSpec.Containers[i].Args = nil
+	}
+}
+
+func (container ContainerSpec) toContainer(sharedMount v1.VolumeMount) v1.Container {
+	// The resources are checked when the service is created
+	resources, _ := container.getResources()
+
+	names := make([]string, 0, len(container.Environment))
+	for name := range container.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var env []v1.EnvVar
+	for _, name := range names {
+		env = append(env, v1.EnvVar{Name: name, Value: container.Environment[name]})
+	}
+
+	return v1.Container{
+		Name:         container.Name,
+		Image:        container.Image,
+		Command:      container.Command,
+		Args:         container.Args,
+		Env:          env,
+		Resources:    resources,
+		VolumeMounts: []v1.VolumeMount{sharedMount},
+	}
+}
+
+func (container ContainerSpec) getResources() (v1.ResourceRequirements, error) {
+	resources := v1.ResourceRequirements{}
+	limits := map[v1.ResourceName]string{v1.ResourceCPU: container.CPU, v1.ResourceMemory: container.Memory}
+	for name, value := range limits {
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return resources, err
+		}
+		if resources.Limits == nil {
+			resources.Limits = v1.ResourceList{}
+		}
+		resources.Limits[name] = quantity
+	}
+	return resources, nil
+}
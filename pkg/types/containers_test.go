@@ -0,0 +1,114 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestValidateContainers(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		service     Service
+		returnError bool
+	}{
+		{"empty", Service{}, false},
+		{"valid", Service{
+			InitContainers: []ContainerSpec{{Name: "model", Image: "alpine", Command: []string{"wget", "-O", "/oscar/shared/model", "https://example.org/model"}}},
+			Sidecars:       []ContainerSpec{{Name: "telemetry", Image: "otel/collector", Command: []string{"/otelcol"}, CPU: "100m", Memory: "64Mi", Environment: map[string]string{"LEVEL": "info"}}},
+		}, false},
+		{"invalid name", Service{InitContainers: []ContainerSpec{{Name: "Model", Image: "alpine"}}}, true},
+		{"reserved name", Service{InitContainers: []ContainerSpec{{Name: "oscar-model", Image: "alpine"}}}, true},
+		{"duplicated name", Service{
+			InitContainers: []ContainerSpec{{Name: "model", Image: "alpine"}},
+			Sidecars:       []ContainerSpec{{Name: "model", Image: "alpine", Command: []string{"sleep"}}},
+		}, true},
+		{"missing image", Service{InitContainers: []ContainerSpec{{Name: "model"}}}, true},
+		{"invalid environment variable", Service{InitContainers: []ContainerSpec{{Name: "model", Image: "alpine", Environment: map[string]string{"1VAR": "value"}}}}, true},
+		{"invalid memory", Service{InitContainers: []ContainerSpec{{Name: "model", Image: "alpine", Memory: "1 GB"}}}, true},
+		{"sidecar without command", Service{Sidecars: []ContainerSpec{{Name: "proxy", Image: "nginx"}}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.service.ValidateContainers(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestAddContainers(t *testing.T) {
+	podSpec := &v1.PodSpec{Containers: []v1.Container{{Name: ContainerName}}}
+	AddContainers(podSpec, nil, nil)
+	if len(podSpec.Volumes) != 0 || len(podSpec.Containers) != 1 {
+		t.Fatalf("expecting the pod spec not to be modified, got %+v", podSpec)
+	}
+
+	AddContainers(podSpec,
+		[]ContainerSpec{{Name: "model", Image: "alpine", Args: []string{"download"}}},
+		[]ContainerSpec{{Name: "proxy", Image: "nginx", Command: []string{"nginx"}, Memory: "64Mi", Environment: map[string]string{"B": "2", "A": "1"}}},
+	)
+
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].Name != SharedVolumeName || podSpec.Volumes[0].EmptyDir == nil {
+		t.Errorf("expecting the shared volume, got %+v", podSpec.Volumes)
+	}
+	if len(podSpec.InitContainers) != 1 || podSpec.InitContainers[0].Name != "model" || podSpec.InitContainers[0].Args[0] != "download" {
+		t.Errorf("unexpected init containers %+v", podSpec.InitContainers)
+	}
+	if len(podSpec.Containers) != 2 || podSpec.Containers[1].Name != "proxy" {
+		t.Fatalf("unexpected containers %+v", podSpec.Containers)
+	}
+	sidecar := podSpec.Containers[1]
+	if len(sidecar.Env) != 2 || sidecar.Env[0].Name != "A" || sidecar.Env[1].Name != "B" {
+		t.Errorf("unexpected environment variables %+v", sidecar.Env)
+	}
+	if memory := sidecar.Resources.Limits[v1.ResourceMemory]; memory.String() != "64Mi" {
+		t.Errorf("expecting memory limit 64Mi, got %s", memory.String())
+	}
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		mounts := container.VolumeMounts
+		if len(mounts) == 0 || mounts[len(mounts)-1].MountPath != SharedVolumePath {
+			t.Errorf("expecting the shared volume mounted in the container %s, got %+v", container.Name, mounts)
+		}
+	}
+}
+
+func TestTerminateSidecars(t *testing.T) {
+	podSpec := &v1.PodSpec{Containers: []v1.Container{{Name: ContainerName, Command: []string{"/bin/sh"}, Args: []string{"-c", "supervisor"}}}}
+	TerminateSidecars(podSpec)
+	if podSpec.Containers[0].Args[1] != "supervisor" {
+		t.Errorf("expecting the script not to be modified without sidecars, got %s", podSpec.Containers[0].Args[1])
+	}
+
+	podSpec.Containers = append(podSpec.Containers, v1.Container{Name: "proxy", Command: []string{"nginx"}, Args: []string{"-g", "daemon off;"}})
+	TerminateSidecars(podSpec)
+
+	if script := podSpec.Containers[0].Args[1]; !strings.HasPrefix(script, "trap 'touch "+sidecarsDoneFile+"' EXIT; ") || !strings.HasSuffix(script, "supervisor") {
+		t.Errorf("unexpected script of the service container: %s", script)
+	}
+	sidecar := podSpec.Containers[1]
+	if len(sidecar.Command) != 7 || sidecar.Command[0] != "/bin/sh" || !strings.Contains(sidecar.Command[2], sidecarsDoneFile) {
+		t.Errorf("unexpected command of the sidecar: %v", sidecar.Command)
+	}
+	if sidecar.Command[3] != "proxy" || sidecar.Command[4] != "nginx" || sidecar.Command[6] != "daemon off;" || sidecar.Args != nil {
+		t.Errorf("expecting the original command of the sidecar as arguments, got %v %v", sidecar.Command, sidecar.Args)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// IdentityTokenHeader header of the asynchronous invocations with the OIDC token of the invoking user, exchanged by
+// the temporary credentials of the job
+const IdentityTokenHeader = "X-Oscar-Identity-Token"
+
+// sessionPolicy IAM policy attached to the temporary credentials of a job
+type sessionPolicy struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// GetJobPolicy returns the session policy limiting the temporary credentials of a job to read the inputs and write
+// the outputs of the service in the default MinIO provider. An empty policy is returned if the service doesn't use
+// the default MinIO provider
+func (service *Service) GetJobPolicy() (string, error) {
+	read := getDefaultMinIOResources(service.Input)
+	write := getDefaultMinIOResources(service.Output)
+
+	policy := sessionPolicy{Version: "2012-10-17", Statement: []policyStatement{}}
+	if len(read) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Effect:   "Allow",
+			Action:   []string{"s3:GetObject"},
+			Resource: read,
+		})
+	}
+	if len(write) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Effect:   "Allow",
+			Action:   []string{"s3:PutObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"},
+			Resource: write,
+		})
+	}
+	if len(policy.Statement) == 0 {
+		return "", nil
+	}
+
+	bytes, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// getDefaultMinIOResources returns the ARNs of the objects under the paths of the default MinIO provider
+func getDefaultMinIOResources(storageIO []StorageIOConfig) []string {
+	resources := []string{}
+	for _, io := range storageIO {
+		provName, provID := io.GetProvider()
+		if provName != MinIOName || provID != DefaultProvider {
+			continue
+		}
+		bucket, folder := io.SplitPath()
+		if folder == "" {
+			resources = append(resources, fmt.Sprintf("arn:aws:s3:::%s/*", bucket))
+		} else {
+			resources = append(resources, fmt.Sprintf("arn:aws:s3:::%s/%s/*", bucket, folder))
+		}
+	}
+	return resources
+}
+
+// WithJobCredentials returns a copy of the service whose default MinIO provider uses the temporary credentials of a
+// job (removed if nil), passed to the job's pod in its own FDL instead of the service's one
+func (service *Service) WithJobCredentials(credentials *MinIOProvider) *Service {
+	svc := service.withDefaultMinIO(credentials)
+	svc.ScopedCredentials = true
+	return svc
+}
+
+// WithIdentityToken returns a copy of the service carrying the OIDC token of the invoking user
+func (service *Service) WithIdentityToken(token string) *Service {
+	svc := *service
+	svc.IdentityToken = token
+	return &svc
+}
+
+// WithFailoverStorage returns a copy of the service whose default MinIO provider is the secondary one, passed to
+// the job's pod in its own FDL instead of the service's one
+func (service *Service) WithFailoverStorage(secondary *MinIOProvider) *Service {
+	svc := service.withDefaultMinIO(secondary)
+	svc.StorageFailover = true
+	return svc
+}
+
+// withDefaultMinIO returns a copy of the service with another default MinIO provider (removed if nil)
+func (service *Service) withDefaultMinIO(provider *MinIOProvider) *Service {
+	svc := *service
+
+	providers := &StorageProviders{}
+	if service.StorageProviders != nil {
+		*providers = *service.StorageProviders
+	}
+	providers.MinIO = map[string]*MinIOProvider{}
+	if service.StorageProviders != nil {
+		for id, p := range service.StorageProviders.MinIO {
+			providers.MinIO[id] = p
+		}
+	}
+	if provider != nil {
+		providers.MinIO[DefaultProvider] = provider
+	} else {
+		delete(providers.MinIO, DefaultProvider)
+	}
+	svc.StorageProviders = providers
+
+	return &svc
+}
+
+// HasJobConfig checks if the FDL of the jobs' pods is passed in the OutputConfigAnnotation instead of mounting the
+// service's one (overridden outputs, temporary credentials, failover storage or credentials read from Vault)
+func (service *Service) HasJobConfig() bool {
+	return service.OutputOverride != nil || service.ScopedCredentials || service.StorageFailover || service.VaultCredentials
+}
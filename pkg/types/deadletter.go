@@ -0,0 +1,54 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// DeadLetterRecord details of a failed job stored in the service's dead-letter path
+type DeadLetterRecord struct {
+	// ID identifier of the record (the name of the failed job)
+	ID string `json:"id"`
+	// Service name of the service
+	Service string `json:"service"`
+	// Event event that triggered the failed job
+	Event string `json:"event"`
+	// Bucket of the input object that triggered the job (if triggered by a MinIO event)
+	Bucket string `json:"bucket,omitempty"`
+	// Key of the input object that triggered the job (if triggered by a MinIO event)
+	Key string `json:"key,omitempty"`
+	// Reason of the job failure
+	Reason string `json:"reason,omitempty"`
+	// Message details of the job failure
+	Message string `json:"message,omitempty"`
+	// ExitCode exit code of the service container
+	ExitCode int32 `json:"exit_code"`
+	// FailedAt time of the job failure
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// RedriveRequest defines the dead-letter records to be re-driven
+type RedriveRequest struct {
+	// IDs identifiers of the records to re-drive
+	// Optional. (default: [] [All records])
+	IDs []string `json:"ids,omitempty"`
+}
+
+// RedriveResult summary of a redrive request
+type RedriveResult struct {
+	// Jobs map with the ID of the re-driven records and the name of the new jobs
+	Jobs map[string]string `json:"jobs"`
+}
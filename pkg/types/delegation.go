@@ -0,0 +1,73 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "fmt"
+
+// Delegation policies to select where the jobs of a service with replicas are run
+const (
+	// StaticDelegationPolicy runs the jobs in the current cluster and delegates them to the replicas
+	// (ordered by priority) only if there are not enough resources
+	StaticDelegationPolicy = "static"
+	// LeastLoadedDelegationPolicy runs the jobs in the cluster (current or replica) with less pending jobs
+	// and more free resources
+	LeastLoadedDelegationPolicy = "least-loaded"
+	// DataLocalityDelegationPolicy runs the jobs in the clusters of the same zone where the input data is stored
+	DataLocalityDelegationPolicy = "data-locality"
+	// EnergyAwareDelegationPolicy runs the jobs in the cluster with the lowest carbon intensity
+	EnergyAwareDelegationPolicy = "energy-aware"
+)
+
+var delegationPolicies = []string{
+	StaticDelegationPolicy,
+	LeastLoadedDelegationPolicy,
+	DataLocalityDelegationPolicy,
+	EnergyAwareDelegationPolicy,
+}
+
+// ClusterCapacity represents the metrics of a cluster used by the delegation policies
+type ClusterCapacity struct {
+	// FreeCPU available CPU in the cluster's working nodes (in millicores)
+	FreeCPU int64 `json:"free_cpu"`
+	// FreeMemory available memory in the cluster's working nodes (in bytes)
+	FreeMemory int64 `json:"free_memory"`
+	// PendingJobs number of jobs waiting to be scheduled
+	PendingJobs int `json:"pending_jobs"`
+	// Zone zone (e.g. region or site) where the cluster is deployed
+	Zone string `json:"zone,omitempty"`
+	// CarbonIntensity carbon intensity of the energy consumed by the cluster (in gCO2eq/kWh, 0 if unknown)
+	CarbonIntensity float64 `json:"carbon_intensity,omitempty"`
+}
+
+// GetDelegationPolicy returns the delegation policy of the service ("static" by default)
+func (service *Service) GetDelegationPolicy() string {
+	if service.DelegationPolicy == "" {
+		return StaticDelegationPolicy
+	}
+	return service.DelegationPolicy
+}
+
+// ValidateDelegationPolicy checks that the service's delegation policy is supported
+func (service *Service) ValidateDelegationPolicy() error {
+	policy := service.GetDelegationPolicy()
+	for _, p := range delegationPolicies {
+		if policy == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("the delegation policy \"%s\" is not supported, must be one of %v", policy, delegationPolicies)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DeletedLabel label key of the configMaps storing the definitions of the soft-deleted services
+	DeletedLabel = "oscar_deleted"
+	// DeletedAtAnnotation annotation key with the deletion time of a soft-deleted service
+	DeletedAtAnnotation = "oscar_deleted_at"
+)
+
+// DeletedService service deleted with "purge=false", whose definition is kept until its retention period expires
+type DeletedService struct {
+	// Name of the service
+	Name string `json:"name"`
+	// DeletedAt time of the deletion
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt time after which the definition is removed and the service can no longer be restored
+	PurgeAt time.Time `json:"purge_at"`
+	// Service kept definition of the service (without its script), used to check who can access it
+	Service *Service `json:"-"`
+}
+
+// DeletedServiceConfigMapName returns the name of the configMap storing the definition of a soft-deleted service
+func DeletedServiceConfigMapName(serviceName string) string {
+	return fmt.Sprintf("%s.deleted", serviceName)
+}
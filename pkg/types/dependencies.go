@@ -0,0 +1,214 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DependenciesContainerName name of the init container installing the service's dependencies
+	DependenciesContainerName = "oscar-dependencies"
+
+	// DependenciesDirectory name of the directory of the OSCAR PVC where the dependencies are cached
+	DependenciesDirectory = "deps"
+
+	// DefaultPath value of the PATH environment variable extended with the dependencies' binaries
+	// if it is not defined in the service's environment variables
+	DefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// Package names (and version specifiers) allowed in the dependencies, e.g. "numpy>=1.24", "requests[socks]"
+// or "conda-forge::gdal=3.6". Options (starting with "-"), quotes and whitespaces are not allowed
+var dependencyRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._+=<>!~,:@/\[\]*-]*$`)
+
+// Dependencies packages installed by an init container in the service's image before running its jobs.
+// They are installed in the OSCAR PVC, so they are only installed once per image and set of packages
+type Dependencies struct {
+	// Pip Python packages installed with "pip install --target"
+	// Optional
+	Pip []string `json:"pip,omitempty"`
+	// Conda packages installed in a conda environment (the image must provide conda)
+	// Optional
+	Conda []string `json:"conda,omitempty"`
+	// Apt Debian packages extracted from the .deb files downloaded with "apt-get download"
+	// (their dependencies are not resolved, so they must be listed too)
+	// Optional
+	Apt []string `json:"apt,omitempty"`
+}
+
+// IsEmpty returns true if there are no packages to install
+func (deps *Dependencies) IsEmpty() bool {
+	return deps == nil || (len(deps.Pip) == 0 && len(deps.Conda) == 0 && len(deps.Apt) == 0)
+}
+
+// ValidateDependencies checks the packages of the service's dependencies (if defined)
+func (service *Service) ValidateDependencies() error {
+	if service.Dependencies == nil {
+		return nil
+	}
+	for manager, pkgs := range map[string][]string{
+		"pip":   service.Dependencies.Pip,
+		"conda": service.Dependencies.Conda,
+		"apt":   service.Dependencies.Apt,
+	} {
+		for _, pkg := range pkgs {
+			if !dependencyRegexp.MatchString(pkg) {
+				return fmt.Errorf("the %s package \"%s\" is not valid", manager, pkg)
+			}
+		}
+	}
+	return nil
+}
+
+// GetDependenciesPath returns the path of the OSCAR PVC where the service's dependencies are installed.
+// It is identified by the hash of the image and the packages, so it is shared by the services with the same
+// dependencies and a new one is created when they change
+func (service *Service) GetDependenciesPath() string {
+	deps, _ := json.Marshal(service.Dependencies)
+	hash := sha256.Sum256(append([]byte(service.Image+"\n"), deps...))
+	return fmt.Sprintf("%s/%s/%s", VolumePath, DependenciesDirectory, hex.EncodeToString(hash[:])[:16])
+}
+
+// addDependencies adds the init container installing the service's dependencies to the podSpec and sets
+// the environment variables of the service container to use them
+func addDependencies(p *v1.PodSpec, service *Service) {
+	if service.Dependencies.IsEmpty() {
+		return
+	}
+	path := service.GetDependenciesPath()
+
+	p.InitContainers = append(p.InitContainers, v1.Container{
+		Name:    DependenciesContainerName,
+		Image:   service.Image,
+		Command: []string{"/bin/sh", "-c", dependenciesScript(service.Dependencies, path)},
+		VolumeMounts: []v1.VolumeMount{
+			{
+				Name:      VolumeName,
+				MountPath: VolumePath,
+			},
+		},
+	})
+
+	var envVars []v1.EnvVar
+	if len(service.Dependencies.Pip) > 0 {
+		envVars = append(envVars, v1.EnvVar{
+			Name:  "PYTHONPATH",
+			Value: joinEnvPath(path+"/pip", service.Environment.Vars["PYTHONPATH"]),
+		})
+	}
+	var bins []string
+	if len(service.Dependencies.Pip) > 0 {
+		bins = append(bins, path+"/pip/bin")
+	}
+	if len(service.Dependencies.Conda) > 0 {
+		bins = append(bins, path+"/conda/bin")
+	}
+	if len(service.Dependencies.Apt) > 0 {
+		bins = append(bins, path+"/apt/usr/bin", path+"/apt/bin")
+		envVars = append(envVars, v1.EnvVar{
+			Name: "LD_LIBRARY_PATH",
+			Value: joinEnvPath(strings.Join([]string{
+				path + "/apt/usr/lib",
+				path + "/apt/usr/lib/x86_64-linux-gnu",
+				path + "/apt/usr/lib/aarch64-linux-gnu",
+			}, ":"), service.Environment.Vars["LD_LIBRARY_PATH"]),
+		})
+	}
+	systemPath := service.Environment.Vars["PATH"]
+	if systemPath == "" {
+		systemPath = DefaultPath
+	}
+	envVars = append(envVars, v1.EnvVar{
+		Name:  "PATH",
+		Value: joinEnvPath(strings.Join(bins, ":"), systemPath),
+	})
+
+	for i, cont := range p.Containers {
+		if cont.Name == ContainerName {
+			p.Containers[i].Env = append(removeEnvVars(cont.Env, envVars), envVars...)
+		}
+	}
+}
+
+// dependenciesScript returns the shell script that installs the dependencies in path, if they are not already
+// installed. They are installed in a temporary directory renamed at the end, so concurrent pods do not use
+// incomplete installations
+func dependenciesScript(deps *Dependencies, path string) string {
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	fmt.Fprintf(&b, "if [ -d %s ]; then exit 0; fi\n", path)
+	b.WriteString("TMP_DIR=\"" + path + ".tmp.$(hostname)\"\n")
+	b.WriteString("rm -rf \"$TMP_DIR\" && mkdir -p \"$TMP_DIR\"\n")
+	if len(deps.Pip) > 0 {
+		fmt.Fprintf(&b, "pip install --no-cache-dir --target \"$TMP_DIR/pip\" %s\n", quotePackages(deps.Pip))
+		b.WriteString("if [ -d \"$TMP_DIR/pip/bin\" ]; then sed -i \"s|$TMP_DIR|" + path + "|g\" \"$TMP_DIR\"/pip/bin/*; fi\n")
+	}
+	if len(deps.Conda) > 0 {
+		fmt.Fprintf(&b, "conda create -y -p \"$TMP_DIR/conda\" %s\n", quotePackages(deps.Conda))
+	}
+	if len(deps.Apt) > 0 {
+		b.WriteString("mkdir -p \"$TMP_DIR/debs\" && cd \"$TMP_DIR/debs\"\n")
+		fmt.Fprintf(&b, "apt-get update && apt-get download %s\n", quotePackages(deps.Apt))
+		b.WriteString("for deb in *.deb; do dpkg -x \"$deb\" \"$TMP_DIR/apt\"; done\n")
+		b.WriteString("cd / && rm -rf \"$TMP_DIR/debs\"\n")
+	}
+	fmt.Fprintf(&b, "if [ -d %s ]; then rm -rf \"$TMP_DIR\"; else mv \"$TMP_DIR\" %s; fi\n", path, path)
+	return b.String()
+}
+
+// quotePackages returns the packages quoted to be passed as shell arguments
+func quotePackages(pkgs []string) string {
+	quoted := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		quoted[i] = "'" + pkg + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// joinEnvPath prepends the paths to the value of a "PATH-like" environment variable
+func joinEnvPath(paths string, value string) string {
+	if value == "" {
+		return paths
+	}
+	return paths + ":" + value
+}
+
+// removeEnvVars returns the environment variables without the ones overridden by the replacements
+func removeEnvVars(envVars []v1.EnvVar, replacements []v1.EnvVar) []v1.EnvVar {
+	res := []v1.EnvVar{}
+	for _, ev := range envVars {
+		replaced := false
+		for _, r := range replacements {
+			if ev.Name == r.Name {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			res = append(res, ev)
+		}
+	}
+	return res
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		dependencies *Dependencies
+		returnError  bool
+	}{
+		{"no dependencies", nil, false},
+		{"valid", &Dependencies{Pip: []string{"numpy>=1.24", "requests[socks]"}, Conda: []string{"conda-forge::gdal=3.6"}, Apt: []string{"libgl1"}}, false},
+		{"option", &Dependencies{Pip: []string{"--index-url=http://example.com"}}, true},
+		{"quote", &Dependencies{Conda: []string{"gdal'; rm -rf /"}}, true},
+		{"whitespace", &Dependencies{Apt: []string{"libgl1 curl"}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{Dependencies: s.dependencies}
+			if err := service.ValidateDependencies(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestGetDependenciesPath(t *testing.T) {
+	service := &Service{Image: "python:3.11", Dependencies: &Dependencies{Pip: []string{"numpy"}}}
+	path := service.GetDependenciesPath()
+	if !strings.HasPrefix(path, VolumePath+"/"+DependenciesDirectory+"/") {
+		t.Errorf("invalid dependencies path \"%s\"", path)
+	}
+
+	other := &Service{Image: "python:3.12", Dependencies: &Dependencies{Pip: []string{"numpy"}}}
+	if other.GetDependenciesPath() == path {
+		t.Error("expecting a different path for a different image")
+	}
+}
+
+func TestToPodSpecDependencies(t *testing.T) {
+	service := &Service{
+		Name:         "testname",
+		Image:        "python:3.11",
+		Dependencies: &Dependencies{Pip: []string{"numpy"}, Apt: []string{"libgl1"}},
+	}
+	service.Environment.Vars = map[string]string{"PATH": "/opt/bin"}
+
+	podSpec, err := service.ToPodSpec(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(podSpec.InitContainers) != 1 || podSpec.InitContainers[0].Name != DependenciesContainerName {
+		t.Fatalf("expecting the init container \"%s\", got %v", DependenciesContainerName, podSpec.InitContainers)
+	}
+	script := podSpec.InitContainers[0].Command[2]
+	if !strings.Contains(script, "pip install") || !strings.Contains(script, "apt-get download 'libgl1'") || strings.Contains(script, "conda") {
+		t.Errorf("unexpected dependencies script:\n%s", script)
+	}
+
+	path := service.GetDependenciesPath()
+	env := map[string]string{}
+	count := map[string]int{}
+	for _, ev := range podSpec.Containers[0].Env {
+		env[ev.Name] = ev.Value
+		count[ev.Name]++
+	}
+	if count["PATH"] != 1 {
+		t.Errorf("expecting a single PATH variable, got %d", count["PATH"])
+	}
+	if expected := path + "/pip/bin:" + path + "/apt/usr/bin:" + path + "/apt/bin:/opt/bin"; env["PATH"] != expected {
+		t.Errorf("expecting PATH \"%s\", got \"%s\"", expected, env["PATH"])
+	}
+	if env["PYTHONPATH"] != path+"/pip" {
+		t.Errorf("expecting PYTHONPATH \"%s\", got \"%s\"", path+"/pip", env["PYTHONPATH"])
+	}
+	if !strings.HasPrefix(env["LD_LIBRARY_PATH"], path+"/apt/usr/lib") {
+		t.Errorf("unexpected LD_LIBRARY_PATH \"%s\"", env["LD_LIBRARY_PATH"])
+	}
+}
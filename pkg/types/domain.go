@@ -0,0 +1,130 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// DomainAnnotation annotation of the Ingresses of the exposed services' custom domains with the hostname
+	DomainAnnotation = "oscar_domain_host"
+
+	// DomainLabel label of the Ingresses of the exposed services' custom domains
+	DomainLabel = "oscar_domain"
+)
+
+// AllowedDomainAnnotations annotations of the NGINX ingress controller that the services can set in the Ingresses
+// of their custom domains. They only tune how the requests are proxied to the service, while the rest (e.g.
+// "server-alias", "default-backend" or the snippets) could claim other hosts or route to other backends
+var AllowedDomainAnnotations = []string{
+	"nginx.ingress.kubernetes.io/proxy-body-size",
+	"nginx.ingress.kubernetes.io/proxy-connect-timeout",
+	"nginx.ingress.kubernetes.io/proxy-read-timeout",
+	"nginx.ingress.kubernetes.io/proxy-send-timeout",
+	"nginx.ingress.kubernetes.io/proxy-buffering",
+	"nginx.ingress.kubernetes.io/proxy-buffer-size",
+	"nginx.ingress.kubernetes.io/proxy-request-buffering",
+	"nginx.ingress.kubernetes.io/client-body-buffer-size",
+	"nginx.ingress.kubernetes.io/ssl-redirect",
+	"nginx.ingress.kubernetes.io/force-ssl-redirect",
+	"nginx.ingress.kubernetes.io/enable-cors",
+	"nginx.ingress.kubernetes.io/cors-allow-origin",
+	"nginx.ingress.kubernetes.io/cors-allow-methods",
+	"nginx.ingress.kubernetes.io/cors-allow-headers",
+	"nginx.ingress.kubernetes.io/cors-allow-credentials",
+	"nginx.ingress.kubernetes.io/cors-expose-headers",
+	"nginx.ingress.kubernetes.io/cors-max-age",
+	"nginx.ingress.kubernetes.io/limit-rps",
+	"nginx.ingress.kubernetes.io/limit-rpm",
+	"nginx.ingress.kubernetes.io/limit-connections",
+	"nginx.ingress.kubernetes.io/whitelist-source-range",
+}
+
+// ExposedDomain custom hostname of an exposed service, served by its own Ingress
+type ExposedDomain struct {
+	// Host hostname of the domain (it must resolve to the ingress controller of the cluster)
+	Host string `json:"host"`
+
+	// Annotations additional annotations of the domain's Ingress (e.g. "nginx.ingress.kubernetes.io/proxy-body-size")
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// TLS option to serve the domain with HTTPS, requesting its certificate to cert-manager
+	TLS bool `json:"tls"`
+
+	// Issuer name of the cert-manager ClusterIssuer of the certificate (default: CERT_MANAGER_ISSUER)
+	Issuer string `json:"issuer,omitempty"`
+
+	// TLSSecret name of the secret with the certificate (set by OSCAR)
+	TLSSecret string `json:"tls_secret,omitempty"`
+}
+
+// Validate checks the hostname, the annotations and the issuer (if TLS) of the domain
+func (d *ExposedDomain) Validate(cfg *Config) error {
+	if errs := validation.IsDNS1123Subdomain(d.Host); len(errs) > 0 || !strings.Contains(d.Host, ".") {
+		return fmt.Errorf("the domain \"%s\" is not a valid hostname", d.Host)
+	}
+	if d.Host == cfg.IngressHost {
+		return fmt.Errorf("the domain \"%s\" is the hostname of the cluster", d.Host)
+	}
+
+	for key := range d.Annotations {
+		if !isAllowedDomainAnnotation(key) {
+			return fmt.Errorf("the annotation \"%s\" is not allowed in the custom domains", key)
+		}
+	}
+
+	if d.TLS && d.GetIssuer(cfg) == "" {
+		return fmt.Errorf("the issuer of the certificate is required (there is no default issuer in the cluster)")
+	}
+
+	return nil
+}
+
+// isAllowedDomainAnnotation checks if an annotation is in AllowedDomainAnnotations
+func isAllowedDomainAnnotation(key string) bool {
+	for _, allowed := range AllowedDomainAnnotations {
+		if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIssuer returns the cert-manager ClusterIssuer of the domain's certificate
+func (d *ExposedDomain) GetIssuer(cfg *Config) string {
+	if d.Issuer != "" {
+		return d.Issuer
+	}
+	return cfg.CertManagerIssuer
+}
+
+// DomainIngressName returns the name of the Ingress of a custom domain of an exposed service
+func DomainIngressName(serviceName string, host string) string {
+	sum := sha256.Sum256([]byte(host))
+	return fmt.Sprintf("%s-dom-%s", serviceName, hex.EncodeToString(sum[:])[:12])
+}
+
+// DomainTLSSecretName returns the name of the secret with the certificate of a custom domain of an exposed service
+func DomainTLSSecretName(serviceName string, host string) string {
+	return DomainIngressName(serviceName, host) + "-tls"
+}
@@ -0,0 +1,53 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+)
+
+func TestValidateExposedDomain(t *testing.T) {
+	cfg := &Config{IngressHost: "oscar.example.org", CertManagerIssuer: "letsencrypt"}
+
+	scenarios := []struct {
+		name        string
+		domain      ExposedDomain
+		cfg         *Config
+		returnError bool
+	}{
+		{"valid", ExposedDomain{Host: "app.example.org", TLS: true}, cfg, false},
+		{"annotations", ExposedDomain{Host: "app.example.org", Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "8m"}}, cfg, false},
+		{"no dot", ExposedDomain{Host: "app"}, cfg, true},
+		{"invalid hostname", ExposedDomain{Host: "App_1.example.org"}, cfg, true},
+		{"cluster hostname", ExposedDomain{Host: "oscar.example.org"}, cfg, true},
+		{"snippet", ExposedDomain{Host: "app.example.org", Annotations: map[string]string{"nginx.ingress.kubernetes.io/server-snippet": "x"}}, cfg, true},
+		{"server alias", ExposedDomain{Host: "app.example.org", Annotations: map[string]string{"nginx.ingress.kubernetes.io/server-alias": "oscar.example.org"}}, cfg, true},
+		{"default backend", ExposedDomain{Host: "app.example.org", Annotations: map[string]string{"nginx.ingress.kubernetes.io/default-backend": "other"}}, cfg, true},
+		{"reserved annotation", ExposedDomain{Host: "app.example.org", Annotations: map[string]string{"cert-manager.io/cluster-issuer": "x"}}, cfg, true},
+		{"invalid annotation", ExposedDomain{Host: "app.example.org", Annotations: map[string]string{"in valid": "x"}}, cfg, true},
+		{"no issuer", ExposedDomain{Host: "app.example.org", TLS: true}, &Config{}, true},
+		{"custom issuer", ExposedDomain{Host: "app.example.org", TLS: true, Issuer: "custom"}, &Config{}, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.domain.Validate(s.cfg); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
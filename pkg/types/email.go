@@ -0,0 +1,97 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// EmailTrigger configuration of the IMAP mailbox polled to trigger the service on new emails
+type EmailTrigger struct {
+	// Host address of the IMAP server with port (IMAPS) (e.g. "imap.example.com:993")
+	Host string `json:"host"`
+	// Username to login in the IMAP server
+	Username string `json:"username"`
+	// PasswordSecret name of the Kubernetes secret (in the services namespace) containing the password
+	// to login in the IMAP server in the "password" field. The secret must have the label "oscar_service=<SERVICE_NAME>"
+	PasswordSecret string `json:"password_secret"`
+	// Mailbox to poll
+	// Optional. (default: INBOX)
+	Mailbox string `json:"mailbox,omitempty"`
+	// Interval time (in seconds) between polls
+	// Optional. (default: 60)
+	Interval int `json:"interval,omitempty"`
+	// From only trigger the service with emails whose sender contains this string
+	// Optional
+	From string `json:"from,omitempty"`
+	// Subject only trigger the service with emails whose subject contains this string
+	// Optional
+	Subject string `json:"subject,omitempty"`
+}
+
+// EmailNotification configuration of the SMTP server used to notify the completion/failure of the service's jobs
+type EmailNotification struct {
+	// Host address of the SMTP server with port (e.g. "smtp.example.com:587")
+	Host string `json:"host"`
+	// Username to login in the SMTP server
+	// Optional
+	Username string `json:"username,omitempty"`
+	// PasswordSecret name of the Kubernetes secret (in the services namespace) containing the password
+	// to login in the SMTP server in the "password" field. The secret must have the label "oscar_service=<SERVICE_NAME>"
+	// Optional
+	PasswordSecret string `json:"password_secret,omitempty"`
+	// From sender address
+	From string `json:"from"`
+	// To recipient addresses (e.g. the service owner)
+	To []string `json:"to"`
+	// OnSuccess notify the successful jobs
+	// Optional. (default: false)
+	OnSuccess bool `json:"on_success"`
+	// OnFailure notify the failed jobs
+	// Optional. (default: false)
+	OnFailure bool `json:"on_failure"`
+}
+
+// EmailPasswordKey field of the password in the secrets referenced by EmailTrigger and EmailNotification
+const EmailPasswordKey = "password"
+
+// EmailEvent event passed to the jobs triggered by an EmailTrigger
+type EmailEvent struct {
+	Email EmailMessage `json:"email"`
+}
+
+// EmailMessage email that triggered the service
+type EmailMessage struct {
+	MessageID string   `json:"message_id"`
+	From      string   `json:"from"`
+	To        []string `json:"to"`
+	Subject   string   `json:"subject"`
+	Date      string   `json:"date"`
+	Body      string   `json:"body"`
+}
+
+// GetMailbox returns the mailbox to poll
+func (trigger EmailTrigger) GetMailbox() string {
+	if trigger.Mailbox == "" {
+		return "INBOX"
+	}
+	return trigger.Mailbox
+}
+
+// GetInterval returns the time (in seconds) between polls
+func (trigger EmailTrigger) GetInterval() int {
+	if trigger.Interval <= 0 {
+		return 60
+	}
+	return trigger.Interval
+}
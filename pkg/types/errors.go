@@ -0,0 +1,338 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+)
+
+// ErrorCode stable, machine-readable identifier of an OSCAR API error
+type ErrorCode struct {
+	// Code unique code of the error with the format OSCAR-NNNN
+	Code string `json:"code"`
+	// Name short kebab-case name of the error
+	Name string `json:"name"`
+	// Status HTTP status code returned along with the error
+	Status int `json:"status"`
+	// Description generic (non localized) description of the error
+	Description string `json:"description"`
+}
+
+// APIError structured error body returned by the OSCAR API
+type APIError struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Error catalog. Codes are grouped by category:
+//   - OSCAR-1xxx: storage (buckets, folders and notifications)
+//   - OSCAR-2xxx: services
+//   - OSCAR-3xxx: jobs and logs
+//   - OSCAR-4xxx: upload sessions (two-phase service create)
+//   - OSCAR-5xxx: image builds
+//   - OSCAR-9xxx: generic errors
+//
+// The codes are part of the API, so existing entries must not be modified
+var (
+	ErrBucketCreateFailed = ErrorCode{"OSCAR-1001", "bucket-create-failed", http.StatusInternalServerError,
+		"The bucket of an input or output could not be created in the storage provider"}
+	ErrFolderCreateFailed = ErrorCode{"OSCAR-1002", "folder-create-failed", http.StatusInternalServerError,
+		"The folder of an input or output could not be created in the storage provider"}
+	ErrStorageProviderNotDefined = ErrorCode{"OSCAR-1003", "storage-provider-not-defined", http.StatusBadRequest,
+		"An input or output references a storage provider that is not defined in the service"}
+	ErrInvalidInputProvider = ErrorCode{"OSCAR-1004", "invalid-input-provider", http.StatusBadRequest,
+		"The input storage provider is not supported (valid inputs are MinIO and dCache)"}
+	ErrWebhookRegisterFailed = ErrorCode{"OSCAR-1005", "webhook-register-failed", http.StatusInternalServerError,
+		"The service's webhook could not be registered in MinIO"}
+	ErrNotificationFailed = ErrorCode{"OSCAR-1006", "notification-config-failed", http.StatusInternalServerError,
+		"The bucket notifications could not be configured in MinIO"}
+	ErrStorageConnectionFailed = ErrorCode{"OSCAR-1007", "storage-connection-failed", http.StatusInternalServerError,
+		"Unable to connect to the storage provider"}
+	ErrMalwareScanFailed = ErrorCode{"OSCAR-1008", "malware-scan-failed", http.StatusInternalServerError,
+		"The input object could not be scanned or quarantined"}
+	ErrVaultCredentialsFailed = ErrorCode{"OSCAR-1009", "vault-credentials-failed", http.StatusInternalServerError,
+		"The credentials of a storage provider could not be read from Vault"}
+
+	ErrInvalidServiceDefinition = ErrorCode{"OSCAR-2001", "invalid-service-definition", http.StatusBadRequest,
+		"The service specification is not valid"}
+	ErrServiceAlreadyExists = ErrorCode{"OSCAR-2002", "service-already-exists", http.StatusConflict,
+		"A service with the provided name already exists"}
+	ErrVONotEnrolled = ErrorCode{"OSCAR-2003", "vo-not-enrolled", http.StatusBadRequest,
+		"The user is not enrolled in the Virtual Organization specified in the service"}
+	ErrVOCheckFailed = ErrorCode{"OSCAR-2004", "vo-check-failed", http.StatusInternalServerError,
+		"The membership of the user in the Virtual Organization could not be checked"}
+	ErrServiceNotFound = ErrorCode{"OSCAR-2005", "service-not-found", http.StatusNotFound,
+		"The requested service does not exist"}
+	ErrServiceCreateFailed = ErrorCode{"OSCAR-2006", "service-create-failed", http.StatusInternalServerError,
+		"The service could not be created in the serverless backend"}
+	ErrServiceUpdateFailed = ErrorCode{"OSCAR-2007", "service-update-failed", http.StatusInternalServerError,
+		"The service could not be updated in the serverless backend"}
+	ErrServiceDeleteFailed = ErrorCode{"OSCAR-2008", "service-delete-failed", http.StatusInternalServerError,
+		"The service could not be deleted from the serverless backend"}
+	ErrServiceReadFailed = ErrorCode{"OSCAR-2009", "service-read-failed", http.StatusInternalServerError,
+		"The service could not be read from the serverless backend"}
+	ErrScriptFetchFailed = ErrorCode{"OSCAR-2010", "script-fetch-failed", http.StatusBadRequest,
+		"The service's script could not be fetched from its Git repository"}
+	ErrRevisionNotFound = ErrorCode{"OSCAR-2011", "revision-not-found", http.StatusNotFound,
+		"The requested revision of the service does not exist"}
+	ErrOperationNotFound = ErrorCode{"OSCAR-2012", "operation-not-found", http.StatusNotFound,
+		"The requested operation does not exist or has expired"}
+	ErrAliasNotFound = ErrorCode{"OSCAR-2013", "alias-not-found", http.StatusNotFound,
+		"The requested invocation alias does not exist"}
+	ErrAliasAlreadyExists = ErrorCode{"OSCAR-2014", "alias-already-exists", http.StatusConflict,
+		"The invocation alias is already registered"}
+	ErrInvalidPayload = ErrorCode{"OSCAR-2015", "invalid-payload", http.StatusUnprocessableEntity,
+		"The request body does not match the input schema of the service"}
+	ErrSchemaNotFound = ErrorCode{"OSCAR-2016", "schema-not-found", http.StatusNotFound,
+		"The service does not define an input/output schema"}
+	ErrOutputOverrideNotAllowed = ErrorCode{"OSCAR-2017", "output-override-not-allowed", http.StatusForbidden,
+		"The destination of the output_override is not allowed by the output_destinations of the service"}
+	ErrTemplateNotFound = ErrorCode{"OSCAR-2018", "template-not-found", http.StatusNotFound,
+		"The requested template does not exist in the service catalog"}
+	ErrCatalogUnavailable = ErrorCode{"OSCAR-2019", "catalog-unavailable", http.StatusServiceUnavailable,
+		"The service catalog is not configured (CATALOG_URL is not set) or could not be fetched"}
+	ErrDeletedServiceNotFound = ErrorCode{"OSCAR-2020", "deleted-service-not-found", http.StatusNotFound,
+		"The service has not been deleted with purge=false or its retention period has expired"}
+	ErrSupervisorNotAllowed = ErrorCode{"OSCAR-2021", "supervisor-not-allowed", http.StatusForbidden,
+		"The FaaS Supervisor image is not in the cluster's allow-list"}
+	ErrDomainNotFound = ErrorCode{"OSCAR-2022", "domain-not-found", http.StatusNotFound,
+		"The custom domain is not attached to the exposed service"}
+	ErrDomainAlreadyExists = ErrorCode{"OSCAR-2023", "domain-already-exists", http.StatusConflict,
+		"The custom domain is already attached to another exposed service"}
+	ErrVORequestNotFound = ErrorCode{"OSCAR-2024", "vo-request-not-found", http.StatusNotFound,
+		"The VO request does not exist"}
+	ErrVORequestDecided = ErrorCode{"OSCAR-2025", "vo-request-decided", http.StatusConflict,
+		"The VO request has already been approved or rejected"}
+	ErrServiceProtected = ErrorCode{"OSCAR-2026", "service-protected", http.StatusPreconditionRequired,
+		"The service is protected, its update or deletion must be confirmed with the X-Oscar-Confirm header or a previous unlock"}
+	ErrServiceTokenNotFound = ErrorCode{"OSCAR-2027", "service-token-not-found", http.StatusNotFound,
+		"The access token does not exist in the service"}
+	ErrServiceTokenAlreadyExists = ErrorCode{"OSCAR-2028", "service-token-already-exists", http.StatusConflict,
+		"An access token with the same name already exists in the service"}
+	ErrServiceAccessDenied = ErrorCode{"OSCAR-2029", "service-access-denied", http.StatusForbidden,
+		"The service can only be accessed by its owners, the members of its VO and the admins"}
+	ErrPresignedURLInvalid = ErrorCode{"OSCAR-2030", "presigned-url-invalid", http.StatusForbidden,
+		"The signature of the presigned URL is not valid or the URL has expired"}
+	ErrPresignedPayloadRejected = ErrorCode{"OSCAR-2031", "presigned-payload-rejected", http.StatusUnprocessableEntity,
+		"The request body does not meet the constraints of the presigned URL (size, content type or hash)"}
+	ErrRuntimeClassNotAllowed = ErrorCode{"OSCAR-2032", "runtime-class-not-allowed", http.StatusForbidden,
+		"The RuntimeClass is not the cluster's default nor in its allow-list"}
+
+	ErrJobNotFound = ErrorCode{"OSCAR-3001", "job-not-found", http.StatusNotFound,
+		"The requested job does not exist"}
+	ErrJobCreateFailed = ErrorCode{"OSCAR-3002", "job-create-failed", http.StatusInternalServerError,
+		"The job could not be created"}
+	ErrJobDeleteFailed = ErrorCode{"OSCAR-3003", "job-delete-failed", http.StatusInternalServerError,
+		"The job(s) could not be deleted"}
+	ErrJobReadFailed = ErrorCode{"OSCAR-3004", "job-read-failed", http.StatusInternalServerError,
+		"The information or logs of the job(s) could not be read"}
+	ErrInvocationNotFound = ErrorCode{"OSCAR-3005", "invocation-not-found", http.StatusNotFound,
+		"The requested asynchronous invocation does not exist or has expired"}
+	ErrQuotaExceeded = ErrorCode{"OSCAR-3006", "quota-exceeded", http.StatusTooManyRequests,
+		"The VO or the owner of the service has reached a quota, so no more jobs can be created in the quotas period"}
+	ErrQueueFull = ErrorCode{"OSCAR-3007", "queue-full", http.StatusTooManyRequests,
+		"The queue of the service's workers is full, so no more events can be queued until they are processed"}
+	ErrQueueLeaseNotFound = ErrorCode{"OSCAR-3008", "queue-lease-not-found", http.StatusNotFound,
+		"The leased event does not exist or it has been leased again after its visibility timeout"}
+
+	ErrUploadNotFound = ErrorCode{"OSCAR-4001", "upload-not-found", http.StatusNotFound,
+		"The requested upload session (or asset) does not exist or has expired"}
+	ErrUploadFailed = ErrorCode{"OSCAR-4002", "upload-failed", http.StatusInternalServerError,
+		"The upload session or the asset could not be stored in the staging bucket"}
+	ErrUploadOffsetMismatch = ErrorCode{"OSCAR-4003", "upload-offset-mismatch", http.StatusConflict,
+		"The uploaded chunk does not start at the current offset of the asset"}
+	ErrUploadIncomplete = ErrorCode{"OSCAR-4004", "upload-incomplete", http.StatusConflict,
+		"The service cannot be activated until all its assets are completely uploaded"}
+
+	ErrBuildNotFound = ErrorCode{"OSCAR-5001", "build-not-found", http.StatusNotFound,
+		"The requested build does not exist"}
+	ErrBuildFailed = ErrorCode{"OSCAR-5002", "build-failed", http.StatusInternalServerError,
+		"The build could not be created, read or deleted"}
+	ErrBuildsDisabled = ErrorCode{"OSCAR-5003", "builds-disabled", http.StatusNotImplemented,
+		"The image builds are not enabled in the cluster (BUILDS_REGISTRY is not set)"}
+
+	ErrInternal = ErrorCode{"OSCAR-9001", "internal-error", http.StatusInternalServerError,
+		"Unexpected internal error"}
+	ErrBadRequest = ErrorCode{"OSCAR-9002", "bad-request", http.StatusBadRequest,
+		"The request is not valid"}
+	ErrUnauthorized = ErrorCode{"OSCAR-9003", "unauthorized", http.StatusUnauthorized,
+		"The request is not authorized (missing or invalid token)"}
+	ErrRateLimited = ErrorCode{"OSCAR-9004", "rate-limited", http.StatusTooManyRequests,
+		"The rate limit has been reached, the request can be retried after the time in the Retry-After header"}
+	ErrIdempotencyKeyInUse = ErrorCode{"OSCAR-9005", "idempotency-key-in-use", http.StatusConflict,
+		"A request with the same Idempotency-Key is being processed"}
+	ErrIdempotencyKeyMismatch = ErrorCode{"OSCAR-9006", "idempotency-key-mismatch", http.StatusUnprocessableEntity,
+		"The Idempotency-Key has already been used by a different request"}
+	ErrMaintenanceMode = ErrorCode{"OSCAR-9007", "maintenance-mode", http.StatusServiceUnavailable,
+		"The API is in read-only mode for maintenance, the request can be retried after the time in the Retry-After header"}
+	ErrAdminRequired = ErrorCode{"OSCAR-9008", "admin-required", http.StatusForbidden,
+		"The request can only be made by the admin of the cluster"}
+	ErrRoleRequired = ErrorCode{"OSCAR-9009", "role-required", http.StatusForbidden,
+		"The role of the user does not allow the request"}
+	ErrAuditUnavailable = ErrorCode{"OSCAR-9010", "audit-unavailable", http.StatusNotImplemented,
+		"The audit log is not enabled in the cluster (AUDIT_SINK is not set) or its sink can not be queried"}
+	ErrAuthzDenied = ErrorCode{"OSCAR-9011", "authz-denied", http.StatusForbidden,
+		"The request has been denied by the authorization webhook of the cluster"}
+	ErrAuthzFailed = ErrorCode{"OSCAR-9012", "authz-failed", http.StatusServiceUnavailable,
+		"The authorization webhook of the cluster could not decide on the request"}
+	ErrAPIKeyNotFound = ErrorCode{"OSCAR-9013", "api-key-not-found", http.StatusNotFound,
+		"The API key does not exist"}
+	ErrAPIKeyAlreadyExists = ErrorCode{"OSCAR-9014", "api-key-already-exists", http.StatusConflict,
+		"An API key with the same name already exists"}
+	ErrUserNotFound = ErrorCode{"OSCAR-9015", "user-not-found", http.StatusNotFound,
+		"The user does not exist in the user store"}
+	ErrUserAlreadyExists = ErrorCode{"OSCAR-9016", "user-already-exists", http.StatusConflict,
+		"A user with the same username already exists in the user store"}
+	ErrUserStoreUnavailable = ErrorCode{"OSCAR-9017", "user-store-unavailable", http.StatusNotImplemented,
+		"The user store is not enabled in the cluster (USER_STORE is not set) or its users are managed externally (ldap)"}
+)
+
+var errorCatalog = []ErrorCode{
+	ErrBucketCreateFailed,
+	ErrFolderCreateFailed,
+	ErrStorageProviderNotDefined,
+	ErrInvalidInputProvider,
+	ErrWebhookRegisterFailed,
+	ErrNotificationFailed,
+	ErrStorageConnectionFailed,
+	ErrMalwareScanFailed,
+	ErrVaultCredentialsFailed,
+	ErrInvalidServiceDefinition,
+	ErrServiceAlreadyExists,
+	ErrVONotEnrolled,
+	ErrVOCheckFailed,
+	ErrServiceNotFound,
+	ErrServiceCreateFailed,
+	ErrServiceUpdateFailed,
+	ErrServiceDeleteFailed,
+	ErrServiceReadFailed,
+	ErrScriptFetchFailed,
+	ErrRevisionNotFound,
+	ErrOperationNotFound,
+	ErrAliasNotFound,
+	ErrAliasAlreadyExists,
+	ErrInvalidPayload,
+	ErrSchemaNotFound,
+	ErrOutputOverrideNotAllowed,
+	ErrTemplateNotFound,
+	ErrCatalogUnavailable,
+	ErrDeletedServiceNotFound,
+	ErrSupervisorNotAllowed,
+	ErrDomainNotFound,
+	ErrDomainAlreadyExists,
+	ErrVORequestNotFound,
+	ErrVORequestDecided,
+	ErrServiceProtected,
+	ErrServiceTokenNotFound,
+	ErrServiceTokenAlreadyExists,
+	ErrServiceAccessDenied,
+	ErrPresignedURLInvalid,
+	ErrPresignedPayloadRejected,
+	ErrRuntimeClassNotAllowed,
+	ErrJobNotFound,
+	ErrJobCreateFailed,
+	ErrJobDeleteFailed,
+	ErrJobReadFailed,
+	ErrInvocationNotFound,
+	ErrQuotaExceeded,
+	ErrQueueFull,
+	ErrQueueLeaseNotFound,
+	ErrUploadNotFound,
+	ErrUploadFailed,
+	ErrUploadOffsetMismatch,
+	ErrUploadIncomplete,
+	ErrBuildNotFound,
+	ErrBuildFailed,
+	ErrBuildsDisabled,
+	ErrInternal,
+	ErrBadRequest,
+	ErrUnauthorized,
+	ErrRateLimited,
+	ErrIdempotencyKeyInUse,
+	ErrIdempotencyKeyMismatch,
+	ErrMaintenanceMode,
+	ErrAdminRequired,
+	ErrRoleRequired,
+	ErrAuditUnavailable,
+	ErrAuthzDenied,
+	ErrAuthzFailed,
+	ErrAPIKeyNotFound,
+	ErrAPIKeyAlreadyExists,
+	ErrUserNotFound,
+	ErrUserAlreadyExists,
+	ErrUserStoreUnavailable,
+}
+
+// GetErrorCatalog returns all the error codes of the API sorted by code
+func GetErrorCatalog() []ErrorCode {
+	catalog := make([]ErrorCode, len(errorCatalog))
+	copy(catalog, errorCatalog)
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Code < catalog[j].Code
+	})
+	return catalog
+}
+
+// CodedError error annotated with an ErrorCode of the catalog
+type CodedError struct {
+	ErrorCode
+	Err error
+}
+
+// NewCodedError annotates err with the provided ErrorCode
+func NewCodedError(code ErrorCode, err error) *CodedError {
+	return &CodedError{ErrorCode: code, Err: err}
+}
+
+// Error returns the message of the wrapped error
+func (e *CodedError) Error() string {
+	if e.Err == nil {
+		return e.Description
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// GetErrorCode returns the ErrorCode of err, or the provided default
+// code if err has not been annotated
+func GetErrorCode(err error, defaultCode ErrorCode) ErrorCode {
+	var codedErr *CodedError
+	if errors.As(err, &codedErr) {
+		return codedErr.ErrorCode
+	}
+	return defaultCode
+}
+
+// NewAPIError returns the structured error body for the provided code and message
+func NewAPIError(code ErrorCode, message string) APIError {
+	if message == "" {
+		message = code.Description
+	}
+	return APIError{
+		Code:    code.Code,
+		Name:    code.Name,
+		Message: message,
+	}
+}
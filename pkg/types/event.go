@@ -0,0 +1,119 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const objectCreatedEventName = "s3:ObjectCreated:Put"
+
+// MinIOEvent represents the notification sent by MinIO to the service's webhook
+type MinIOEvent struct {
+	EventName string             `json:"EventName"`
+	Key       string             `json:"Key"`
+	Records   []MinIOEventRecord `json:"Records"`
+}
+
+// MinIOEventRecord record of a MinIO notification
+type MinIOEventRecord struct {
+	EventName string       `json:"eventName"`
+	EventTime string       `json:"eventTime"`
+	S3        MinIOEventS3 `json:"s3"`
+}
+
+// MinIOEventS3 bucket and object info of a MinIO notification record
+type MinIOEventS3 struct {
+	Bucket MinIOEventBucket `json:"bucket"`
+	Object MinIOEventObject `json:"object"`
+}
+
+// MinIOEventBucket bucket info of a MinIO notification record
+type MinIOEventBucket struct {
+	Name string `json:"name"`
+}
+
+// MinIOEventObject object info of a MinIO notification record
+type MinIOEventObject struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ETag        string `json:"eTag,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// ParseMinIOEvent parses a MinIO notification, returning an error if the
+// event is not a MinIO notification (e.g. a delegated or user-defined event)
+func ParseMinIOEvent(event []byte) (*MinIOEvent, error) {
+	minIOEvent := &MinIOEvent{}
+	if err := json.Unmarshal(event, minIOEvent); err != nil {
+		return nil, err
+	}
+
+	if len(minIOEvent.Records) == 0 {
+		return nil, errors.New("the event does not contain any record")
+	}
+
+	return minIOEvent, nil
+}
+
+// GetBucket returns the bucket name of the event's first record
+func (event *MinIOEvent) GetBucket() string {
+	return event.Records[0].S3.Bucket.Name
+}
+
+// GetObjectKey returns the (unescaped) object key of the event's first record
+func (event *MinIOEvent) GetObjectKey() string {
+	key := event.Records[0].S3.Object.Key
+	// MinIO sends the object keys URL-encoded
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
+
+// GetObject returns the object info of the event's first record
+func (event *MinIOEvent) GetObject() MinIOEventObject {
+	return event.Records[0].S3.Object
+}
+
+// NewMinIOEvent returns a synthetic "s3:ObjectCreated:Put" MinIO event for the provided object
+func NewMinIOEvent(bucket string, object MinIOEventObject, eventTime time.Time) *MinIOEvent {
+	return &MinIOEvent{
+		EventName: objectCreatedEventName,
+		Key:       fmt.Sprintf("%s/%s", bucket, object.Key),
+		Records: []MinIOEventRecord{
+			{
+				EventName: objectCreatedEventName,
+				EventTime: eventTime.UTC().Format(time.RFC3339),
+				S3: MinIOEventS3{
+					Bucket: MinIOEventBucket{Name: bucket},
+					Object: MinIOEventObject{
+						// MinIO sends the object keys URL-encoded
+						Key:         url.QueryEscape(object.Key),
+						Size:        object.Size,
+						ETag:        object.ETag,
+						ContentType: object.ContentType,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SecretReferencePrefix prefix of the values referencing a field of a Kubernetes secret ("secret://<name>/<key>")
+const SecretReferencePrefix = "secret://"
+
+// FDL Functions Definition Language document with the services to be deployed in each OSCAR cluster
+type FDL struct {
+	Functions struct {
+		OSCAR []map[string]*Service `json:"oscar"`
+	} `json:"functions"`
+}
+
+// SecretReference returns the reference to a field of a Kubernetes secret
+func SecretReference(name string, key string) string {
+	return fmt.Sprintf("%s%s/%s", SecretReferencePrefix, name, key)
+}
+
+// ParseSecretReference returns the name and the key of the secret referenced by a value
+func ParseSecretReference(value string) (name string, key string, ok bool) {
+	if !strings.HasPrefix(value, SecretReferencePrefix) {
+		return "", "", false
+	}
+	name, key, ok = strings.Cut(strings.TrimPrefix(value, SecretReferencePrefix), "/")
+	if !ok || name == "" || key == "" {
+		return "", "", false
+	}
+	return name, key, true
+}
+
+// ExportSecretName returns the name of the secret referenced by the credentials of an exported service
+func ExportSecretName(serviceName string) string {
+	return serviceName + "-credentials"
+}
+
+// RewriteCredentials replaces the non-empty credentials of the service's storage providers, clusters
+// and replicas ("Authorization" headers) with the value returned by fn, which receives the key identifying
+// each credential (e.g. "s3.aws.secret_key")
+func (service *Service) RewriteCredentials(fn func(key string, value string) (string, error)) error {
+	rewrite := func(key string, value *string) error {
+		if *value == "" {
+			return nil
+		}
+		newValue, err := fn(key, *value)
+		if err != nil {
+			return err
+		}
+		*value = newValue
+		return nil
+	}
+
+	if sp := service.StorageProviders; sp != nil {
+		for id, p := range sp.S3 {
+			if err := rewrite(fmt.Sprintf("%s.%s.secret_key", S3Name, id), &p.SecretKey); err != nil {
+				return err
+			}
+		}
+		for id, p := range sp.MinIO {
+			if err := rewrite(fmt.Sprintf("%s.%s.secret_key", MinIOName, id), &p.SecretKey); err != nil {
+				return err
+			}
+		}
+		for id, p := range sp.Onedata {
+			if err := rewrite(fmt.Sprintf("%s.%s.token", OnedataName, id), &p.Token); err != nil {
+				return err
+			}
+		}
+		for id, p := range sp.WebDav {
+			if err := rewrite(fmt.Sprintf("%s.%s.password", WebDavName, id), &p.Password); err != nil {
+				return err
+			}
+		}
+	}
+
+	for id, cluster := range service.Clusters {
+		if err := rewrite(fmt.Sprintf("clusters.%s.auth_password", id), &cluster.AuthPassword); err != nil {
+			return err
+		}
+		service.Clusters[id] = cluster
+	}
+
+	for i := range service.Replicas {
+		for header, value := range service.Replicas[i].Headers {
+			if !strings.EqualFold(header, "Authorization") {
+				continue
+			}
+			if err := rewrite(fmt.Sprintf("replicas.%d.authorization", i), &value); err != nil {
+				return err
+			}
+			service.Replicas[i].Headers[header] = value
+		}
+	}
+
+	return nil
+}
+
+// Export returns a portable copy of the service, without the fields set by the cluster (token and default
+// MinIO provider) and with its credentials replaced by references to the ExportSecretName secret.
+// Returns also the keys of the secret referenced
+func (service Service) Export() (*Service, []string, error) {
+	// Deep copy to not modify the service
+	exported := &Service{}
+	serviceBytes, err := json.Marshal(service)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(serviceBytes, exported); err != nil {
+		return nil, nil, err
+	}
+
+	exported.Token = ""
+	exported.AccessTokens = nil
+	exported.Namespace = ""
+	exported.Revision = 0
+	if sp := exported.StorageProviders; sp != nil {
+		delete(sp.MinIO, DefaultProvider)
+		if len(sp.S3) == 0 && len(sp.MinIO) == 0 && len(sp.Onedata) == 0 && len(sp.WebDav) == 0 {
+			exported.StorageProviders = nil
+		}
+	}
+
+	keys := []string{}
+	secretName := ExportSecretName(service.Name)
+	err = exported.RewriteCredentials(func(key string, value string) (string, error) {
+		keys = append(keys, key)
+		return SecretReference(secretName, key), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(keys)
+
+	return exported, keys, nil
+}
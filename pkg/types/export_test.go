@@ -0,0 +1,86 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSecretReference(t *testing.T) {
+	scenarios := []struct {
+		value        string
+		expectedName string
+		expectedKey  string
+		expectedOK   bool
+	}{
+		{"secret://creds/s3.aws.secret_key", "creds", "s3.aws.secret_key", true},
+		{"secret://creds", "", "", false},
+		{"secret:///key", "", "", false},
+		{"plain-value", "", "", false},
+	}
+
+	for _, s := range scenarios {
+		name, key, ok := ParseSecretReference(s.value)
+		if name != s.expectedName || key != s.expectedKey || ok != s.expectedOK {
+			t.Errorf("%s: expecting (%s, %s, %v), got (%s, %s, %v)", s.value, s.expectedName, s.expectedKey, s.expectedOK, name, key, ok)
+		}
+	}
+}
+
+func TestServiceExport(t *testing.T) {
+	service := Service{
+		Name:  "test",
+		Token: "token",
+		StorageProviders: &StorageProviders{
+			MinIO: map[string]*MinIOProvider{DefaultProvider: {Endpoint: "http://minio", SecretKey: "minio-secret"}},
+			S3:    map[string]*S3Provider{"aws": {AccessKey: "access", SecretKey: "s3-secret"}},
+		},
+		Clusters: map[string]Cluster{"other": {Endpoint: "http://other", AuthUser: "oscar", AuthPassword: "password"}},
+		Replicas: ReplicaList{{Type: "endpoint", URL: "http://endpoint", Headers: map[string]string{"Authorization": "Bearer token", "X-Custom": "value"}}},
+	}
+
+	exported, keys, err := service.Export()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedKeys := []string{"clusters.other.auth_password", "replicas.0.authorization", "s3.aws.secret_key"}
+	if !reflect.DeepEqual(keys, expectedKeys) {
+		t.Errorf("expecting keys %v, got %v", expectedKeys, keys)
+	}
+	if exported.Token != "" {
+		t.Error("expecting the token to be removed")
+	}
+	if _, ok := exported.StorageProviders.MinIO[DefaultProvider]; ok {
+		t.Error("expecting the default MinIO provider to be removed")
+	}
+	if exported.StorageProviders.S3["aws"].SecretKey != "secret://test-credentials/s3.aws.secret_key" || exported.StorageProviders.S3["aws"].AccessKey != "access" {
+		t.Errorf("unexpected S3 provider: %+v", exported.StorageProviders.S3["aws"])
+	}
+	if exported.Clusters["other"].AuthPassword != "secret://test-credentials/clusters.other.auth_password" {
+		t.Errorf("unexpected cluster: %+v", exported.Clusters["other"])
+	}
+	if exported.Replicas[0].Headers["Authorization"] != "secret://test-credentials/replicas.0.authorization" || exported.Replicas[0].Headers["X-Custom"] != "value" {
+		t.Errorf("unexpected replica headers: %v", exported.Replicas[0].Headers)
+	}
+
+	// The original service must not be modified
+	if service.Token != "token" || service.StorageProviders.S3["aws"].SecretKey != "s3-secret" || service.Clusters["other"].AuthPassword != "password" {
+		t.Error("the original service has been modified")
+	}
+}
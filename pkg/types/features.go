@@ -0,0 +1,95 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// FeatureEnvPrefix prefix of the environment variables with the service's feature flags
+const FeatureEnvPrefix = "OSCAR_FEATURE_"
+
+// Names allowed for the feature flags, so they can be used in the names of the environment variables
+var featureNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// ValidateFeatures checks the names of the service's feature flags
+func (service *Service) ValidateFeatures() error {
+	for name := range service.Features {
+		if err := ValidateFeatureName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateFeatureName checks that a feature flag name only contains letters, digits and underscores
+// (starting with a letter)
+func ValidateFeatureName(name string) error {
+	if !featureNameRegexp.MatchString(name) {
+		return fmt.Errorf("the feature name \"%s\" is not valid, it must start with a letter and only contain letters, digits and underscores", name)
+	}
+	return nil
+}
+
+// FeatureEnvName returns the name of the environment variable of a feature flag
+func FeatureEnvName(name string) string {
+	return FeatureEnvPrefix + strings.ToUpper(name)
+}
+
+// FeaturesEnvFile returns the content of the env file with the service's feature flags, sorted by name
+// and quoted to be sourced from a shell script
+func (service *Service) FeaturesEnvFile() string {
+	names := make([]string, 0, len(service.Features))
+	for name := range service.Features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		value := strings.ReplaceAll(service.Features[name], "'", `'\''`)
+		fmt.Fprintf(&b, "%s='%s'\n", FeatureEnvName(name), value)
+	}
+	return b.String()
+}
+
+// addFeatureEnvVars sets the service's feature flags as environment variables of the service container
+func addFeatureEnvVars(p *v1.PodSpec, service *Service) {
+	if len(service.Features) == 0 {
+		return
+	}
+
+	envVars := []v1.EnvVar{}
+	for name, value := range service.Features {
+		envVars = append(envVars, v1.EnvVar{
+			Name:  FeatureEnvName(name),
+			Value: value,
+		})
+	}
+	sort.Slice(envVars, func(i, j int) bool { return envVars[i].Name < envVars[j].Name })
+
+	for i, cont := range p.Containers {
+		if cont.Name == ContainerName {
+			p.Containers[i].Env = append(removeEnvVars(cont.Env, envVars), envVars...)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+)
+
+func TestValidateFeatures(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		features    map[string]string
+		returnError bool
+	}{
+		{"no features", nil, false},
+		{"valid", map[string]string{"new_model": "true", "Threshold2": "0.5"}, false},
+		{"leading digit", map[string]string{"2fast": "true"}, true},
+		{"dash", map[string]string{"new-model": "true"}, true},
+		{"empty", map[string]string{"": "true"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{Features: s.features}
+			if err := service.ValidateFeatures(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestFeaturesEnvFile(t *testing.T) {
+	service := &Service{Features: map[string]string{"new_model": "true", "message": "it's on"}}
+
+	expected := "OSCAR_FEATURE_MESSAGE='it'\\''s on'\nOSCAR_FEATURE_NEW_MODEL='true'\n"
+	if file := service.FeaturesEnvFile(); file != expected {
+		t.Errorf("expecting features file %q, got %q", expected, file)
+	}
+}
+
+func TestToPodSpecFeatures(t *testing.T) {
+	service := &Service{
+		Name:     "testname",
+		Image:    "testimage",
+		Features: map[string]string{"new_model": "true"},
+	}
+	service.Environment.Vars = map[string]string{"OSCAR_FEATURE_NEW_MODEL": "false"}
+
+	podSpec, err := service.ToPodSpec(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found := 0
+	for _, ev := range podSpec.Containers[0].Env {
+		if ev.Name == "OSCAR_FEATURE_NEW_MODEL" {
+			found++
+			if ev.Value != "true" {
+				t.Errorf("expecting feature value \"true\", got \"%s\"", ev.Value)
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("expecting one OSCAR_FEATURE_NEW_MODEL variable, got %d", found)
+	}
+}
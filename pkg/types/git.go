@@ -0,0 +1,44 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// GitScriptSource defines the location of a service's script in a Git repository
+type GitScriptSource struct {
+	// URL of the Git repository (HTTPS or SSH)
+	URL string `json:"url" binding:"required"`
+	// Ref branch or tag to checkout
+	// Optional. (default: the repository's default branch)
+	Ref string `json:"ref,omitempty"`
+	// Path of the script file inside the repository
+	// Optional. (default: script.sh)
+	Path string `json:"path,omitempty"`
+	// DeployKeySecret name of the Kubernetes secret (in the services namespace)
+	// containing the SSH private key in the "ssh-privatekey" field
+	// Optional
+	DeployKeySecret string `json:"deploy_key_secret,omitempty"`
+	// SyncOnWebhook re-sync the script when the service's Git webhook is invoked
+	// Optional. (default: false)
+	SyncOnWebhook bool `json:"sync_on_webhook,omitempty"`
+}
+
+// GetPath returns the path of the script file inside the repository
+func (source GitScriptSource) GetPath() string {
+	if source.Path == "" {
+		return ScriptFileName
+	}
+	return source.Path
+}
@@ -0,0 +1,40 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader header with the client-generated key of a retryable request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader header set on the responses replayed from a previous request with the same key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// IdempotentRequest outcome of a request sent with an Idempotency-Key
+type IdempotentRequest struct {
+	// Fingerprint hash of the request (its body), to detect the reuse of a key with a different request
+	Fingerprint string
+	// Completed the response has been stored (false while the request is being processed)
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+	CreatedAt time.Time
+}
@@ -16,6 +16,8 @@ limitations under the License.
 
 package types
 
+import "time"
+
 // Info represents the system information to be exposed
 type Info struct {
 	Version               string                 `json:"version"`
@@ -23,6 +25,20 @@ type Info struct {
 	Architecture          string                 `json:"architecture"`
 	KubeVersion           string                 `json:"kubernetes_version"`
 	ServerlessBackendInfo *ServerlessBackendInfo `json:"serverless_backend,omitempty"`
+	// StorageFailover status of the failover of the OSCAR's MinIO (only if a secondary provider is configured)
+	StorageFailover *StorageFailoverStatus `json:"storage_failover,omitempty"`
+}
+
+// StorageFailoverStatus status of the failover of the OSCAR's MinIO to the secondary provider
+type StorageFailoverStatus struct {
+	// Active the new services and jobs use the secondary provider (degraded state)
+	Active bool `json:"active"`
+	// Since time when the failover was activated
+	Since *time.Time `json:"since,omitempty"`
+	// Reason error reaching the OSCAR's MinIO
+	Reason string `json:"reason,omitempty"`
+	// CheckedAt time of the last check of the OSCAR's MinIO
+	CheckedAt time.Time `json:"checked_at"`
 }
 
 // ServerlessBackendInfo shows the name and version of the underlying serverless backend
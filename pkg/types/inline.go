@@ -0,0 +1,102 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+)
+
+const (
+	// InlineOutputsBase64 mode passing the content of the output files (base64-encoded) as the events of the jobs
+	InlineOutputsBase64 = "base64"
+	// InlineOutputsReference mode passing the MinIO events of the staged output files, so the jobs read them from
+	// the upload staging bucket
+	InlineOutputsReference = "reference"
+)
+
+// InlineOutputs passing of the output files of a service to the jobs of its chained services, without storing them
+// in the output bucket and waiting for its notification
+type InlineOutputs struct {
+	// Mode "base64" to pass the content of the files in the events, or "reference" to pass the location of the
+	// staged files
+	// Optional. (default: "base64")
+	Mode string `json:"mode,omitempty"`
+	// MaxSize maximum size (in bytes) of the files passed in base64, the larger ones are stored in the outputs
+	// Optional. (default and limit: INLINE_OUTPUTS_MAX_SIZE)
+	MaxSize int64 `json:"max_size,omitempty"`
+}
+
+// HasStagedOutputs returns true if the outputs of the service's jobs are staged to be routed (output_routes) or
+// passed to the chained services (inline_outputs) once the jobs finish
+func (service *Service) HasStagedOutputs() bool {
+	return service.HasOutputRoutes() || service.InlineOutputs != nil
+}
+
+// ValidateInlineOutputs checks the mode and the maximum size of the inline outputs
+func (service *Service) ValidateInlineOutputs(cfg *Config) error {
+	inline := service.InlineOutputs
+	if inline == nil {
+		return nil
+	}
+	switch inline.GetMode() {
+	case InlineOutputsBase64:
+		if inline.MaxSize < 0 || inline.MaxSize > int64(cfg.InlineOutputsMaxSize) {
+			return fmt.Errorf("the max_size of the inline outputs must be between 0 and %d bytes", cfg.InlineOutputsMaxSize)
+		}
+	case InlineOutputsReference:
+		// The temporary credentials of the jobs do not grant access to the staging bucket
+		if cfg.JobCredentialsEnable || cfg.JobCredentialsWebIdentity {
+			return fmt.Errorf("the \"%s\" mode of the inline outputs is not available in the cluster", InlineOutputsReference)
+		}
+	default:
+		return fmt.Errorf("the mode of the inline outputs must be \"%s\" or \"%s\"", InlineOutputsBase64, InlineOutputsReference)
+	}
+	return nil
+}
+
+// GetMode returns the mode of the inline outputs
+func (inline *InlineOutputs) GetMode() string {
+	if inline.Mode == "" {
+		return InlineOutputsBase64
+	}
+	return inline.Mode
+}
+
+// GetMaxSize returns the maximum size of the files passed in base64
+func (inline *InlineOutputs) GetMaxSize(cfg *Config) int64 {
+	if inline.MaxSize > 0 {
+		return inline.MaxSize
+	}
+	return int64(cfg.InlineOutputsMaxSize)
+}
+
+// AcceptsInlineOutput checks if an output file stored in the default MinIO provider (bucket and key) would trigger
+// a job of the service that can receive it inline: its input is enabled and the file satisfies its filters, with no
+// file set, limits, batches nor malware scanning, as they are applied to the MinIO notifications
+func (service *Service) AcceptsInlineOutput(bucket string, key string, size int64, contentType string) bool {
+	if service.HasBatch() || service.QuarantinePath != "" {
+		return false
+	}
+	for _, in := range service.Input {
+		if provName, provID := in.GetProvider(); provName != MinIOName || provID != DefaultProvider || !in.MatchesObject(bucket, key) {
+			continue
+		}
+		return !in.Disabled && len(in.FileSet) == 0 && in.MaxEventsPerMinute == 0 && in.MaxInFlight == 0 &&
+			in.MatchesFileName(key) && in.CheckObjectFilters(size, contentType) == nil
+	}
+	return false
+}
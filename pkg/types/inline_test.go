@@ -0,0 +1,75 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "testing"
+
+func TestValidateInlineOutputs(t *testing.T) {
+	cfg := &Config{InlineOutputsMaxSize: 1024}
+	cases := map[string]struct {
+		inline  *InlineOutputs
+		jobCred bool
+		valid   bool
+	}{
+		"none":              {nil, false, true},
+		"default":           {&InlineOutputs{}, false, true},
+		"base64":            {&InlineOutputs{Mode: InlineOutputsBase64, MaxSize: 512}, false, true},
+		"too large":         {&InlineOutputs{MaxSize: 2048}, false, false},
+		"negative":          {&InlineOutputs{MaxSize: -1}, false, false},
+		"reference":         {&InlineOutputs{Mode: InlineOutputsReference}, false, true},
+		"reference scoped":  {&InlineOutputs{Mode: InlineOutputsReference}, true, false},
+		"invalid mode":      {&InlineOutputs{Mode: "copy"}, false, false},
+		"base64 jobs creds": {&InlineOutputs{}, true, true},
+	}
+	for name, c := range cases {
+		cfg.JobCredentialsEnable = c.jobCred
+		service := &Service{InlineOutputs: c.inline}
+		if err := service.ValidateInlineOutputs(cfg); (err == nil) != c.valid {
+			t.Errorf("%s: expected valid %v, got error %v", name, c.valid, err)
+		}
+	}
+
+	if size := (&InlineOutputs{}).GetMaxSize(cfg); size != 1024 {
+		t.Errorf("expected the default maximum size 1024, got %d", size)
+	}
+}
+
+func TestAcceptsInlineOutput(t *testing.T) {
+	in := StorageIOConfig{Provider: "minio", Path: "chain/in", Suffix: []string{".txt"}}
+	batched := &Service{Input: []StorageIOConfig{in}}
+	batched.Batch.Size = 2
+	cases := map[string]struct {
+		service *Service
+		key     string
+		accepts bool
+	}{
+		"matching":        {&Service{Input: []StorageIOConfig{in}}, "in/file.txt", true},
+		"suffix":          {&Service{Input: []StorageIOConfig{in}}, "in/file.png", false},
+		"other path":      {&Service{Input: []StorageIOConfig{in}}, "out/file.txt", false},
+		"batch":           {batched, "in/file.txt", false},
+		"quarantine":      {&Service{Input: []StorageIOConfig{in}, QuarantinePath: "chain/quarantine"}, "in/file.txt", false},
+		"other provider":  {&Service{Input: []StorageIOConfig{{Provider: "minio.other", Path: "chain/in"}}}, "in/file.txt", false},
+		"disabled input":  {&Service{Input: []StorageIOConfig{{Provider: "minio", Path: "chain/in", Disabled: true}}}, "in/file.txt", false},
+		"limited input":   {&Service{Input: []StorageIOConfig{{Provider: "minio", Path: "chain/in", MaxInFlight: 1}}}, "in/file.txt", false},
+		"without filters": {&Service{Input: []StorageIOConfig{{Provider: "minio", Path: "chain/in"}}}, "in/file.png", true},
+	}
+	for name, c := range cases {
+		if accepts := c.service.AcceptsInlineOutput("chain", c.key, 10, "text/plain"); accepts != c.accepts {
+			t.Errorf("%s: expected %v, got %v", name, c.accepts, accepts)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// Status of the asynchronous invocations
+const (
+	InvocationPending   = "pending"
+	InvocationSucceeded = "succeeded"
+	InvocationFailed    = "failed"
+)
+
+// AsyncInvocation record of an asynchronous invocation of a service queued in the ServerlessBackend (OpenFaaS),
+// whose result is notified back to OSCAR
+type AsyncInvocation struct {
+	// ID identifier of the invocation
+	ID string `json:"id"`
+	// Service name of the invoked service
+	Service string `json:"service"`
+	// Status of the invocation ("pending", "succeeded" or "failed")
+	Status string `json:"status"`
+	// StatusCode HTTP status code returned by the service
+	StatusCode int `json:"status_code,omitempty"`
+	// Output path of the object storing the result ("<PROVIDER>:<BUCKET>/<KEY>"). Empty if the service has no outputs
+	Output string `json:"output,omitempty"`
+	// Error details of the failed invocation
+	Error string `json:"error,omitempty"`
+	// CreatedAt time when the invocation was queued
+	CreatedAt time.Time `json:"created_at"`
+	// FinishedAt time when the result was received
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
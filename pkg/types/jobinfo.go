@@ -16,7 +16,17 @@ limitations under the License.
 
 package types
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Sources of the events that trigger the jobs
+const (
+	JobEventMinIO  = "minio"
+	JobEventCustom = "custom"
+)
 
 // JobInfo details the current status of a service's job
 type JobInfo struct {
@@ -24,4 +34,111 @@ type JobInfo struct {
 	CreationTime *metav1.Time `json:"creation_time,omitempty"`
 	StartTime    *metav1.Time `json:"start_time,omitempty"`
 	FinishTime   *metav1.Time `json:"finish_time,omitempty"`
+	// Revision of the service definition that handled the job
+	Revision int `json:"revision,omitempty"`
+}
+
+// JobSummary details the status of a job including its name
+type JobSummary struct {
+	Name string `json:"name"`
+	JobInfo
+}
+
+// JobStatus details the status of a service's job, including the timing, exit details and triggering event
+type JobStatus struct {
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	JobInfo
+	// NodeName node running the job's pod (empty until it is scheduled)
+	NodeName string `json:"node_name,omitempty"`
+	// ExitCode of the service container (only for terminated jobs)
+	ExitCode *int32 `json:"exit_code,omitempty"`
+	// Reason of the service container termination (e.g. "Completed", "Error" or "OOMKilled")
+	Reason    string `json:"reason,omitempty"`
+	OOMKilled bool   `json:"oom_killed"`
+	// Requests resources requested by the service container (e.g. "cpu": "500m")
+	Requests map[string]string `json:"requests,omitempty"`
+	// RetryOf name of the job retried by this one
+	RetryOf string        `json:"retry_of,omitempty"`
+	Event   *JobEventInfo `json:"event,omitempty"`
+	// Timings milestones and duration of the phases of the job (cold start breakdown)
+	Timings *JobTimings `json:"timings,omitempty"`
+}
+
+// JobEventInfo metadata of the event that triggered a job
+type JobEventInfo struct {
+	// Source "minio" for the MinIO notifications, "custom" for other events (e.g. synchronous or user-defined)
+	Source    string `json:"source"`
+	EventName string `json:"event_name,omitempty"`
+	EventTime string `json:"event_time,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Key       string `json:"key,omitempty"`
+	// Size of the object (MinIO notifications) or of the event
+	Size int64 `json:"size"`
+}
+
+// NewJobEventInfo returns the metadata of the event of a job, or nil if the job has no event
+func NewJobEventInfo(event string) *JobEventInfo {
+	if event == "" {
+		return nil
+	}
+
+	minIOEvent, err := ParseMinIOEvent([]byte(event))
+	if err != nil {
+		return &JobEventInfo{Source: JobEventCustom, Size: int64(len(event))}
+	}
+
+	record := minIOEvent.Records[0]
+	return &JobEventInfo{
+		Source:    JobEventMinIO,
+		EventName: record.EventName,
+		EventTime: record.EventTime,
+		Bucket:    minIOEvent.GetBucket(),
+		Key:       minIOEvent.GetObjectKey(),
+		Size:      minIOEvent.GetObject().Size,
+	}
+}
+
+// JobRetry result of retrying a job
+type JobRetry struct {
+	// Job name of the new job (empty if the event has been delegated to a replica)
+	Job string `json:"job"`
+	// RetryOf name of the retried job
+	RetryOf string `json:"retry_of"`
+}
+
+// JobInvocation result of an async invocation through the /job/{serviceName} path
+type JobInvocation struct {
+	// JobID name of the created job (empty if the event has been delegated to a replica)
+	JobID string `json:"job_id"`
+	// StatusURL path to get the status of the job with the service's token
+	StatusURL string `json:"status_url,omitempty"`
+}
+
+// JobLogMatches lines of the logs of a job matching a search
+type JobLogMatches struct {
+	Job   string   `json:"job"`
+	Lines []string `json:"lines"`
+}
+
+// JobOutputs output files stored by a job in the MinIO outputs of its service, with presigned download URLs
+type JobOutputs struct {
+	Job     string `json:"job"`
+	Service string `json:"service"`
+	// Correlated false if the event of the job is not a MinIO notification, so its output files cannot be located
+	Correlated bool `json:"correlated"`
+	// Finished true if the job has finished (the routed outputs may still be pending)
+	Finished bool `json:"finished"`
+	// Expires expiration time of the URLs
+	Expires time.Time       `json:"expires"`
+	Files   []JobOutputFile `json:"files"`
+}
+
+// JobOutputFile output file of a job
+type JobOutputFile struct {
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	URL          string    `json:"url"`
 }
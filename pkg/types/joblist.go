@@ -0,0 +1,166 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxJobListLimit maximum number of jobs returned in a page of the cluster-wide job list
+const MaxJobListLimit = 500
+
+// Sort keys of the job list (prefixed with "-" for descending order), besides SortByName
+const (
+	SortByService      = "service"
+	SortByCreationTime = "creation_time"
+)
+
+// Status of the jobs in the job list
+const (
+	JobPending   = "Pending"
+	JobRunning   = "Running"
+	JobSucceeded = "Succeeded"
+	JobFailed    = "Failed"
+	JobSuspended = "Suspended"
+)
+
+// ClusterJob job of the cluster-wide job list
+type ClusterJob struct {
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	VO      string `json:"vo,omitempty"`
+	JobInfo
+}
+
+// JobListOptions filtering, sorting and pagination of the cluster-wide job list
+type JobListOptions struct {
+	// Limit maximum number of jobs to return (0 returns all the jobs)
+	Limit int
+	// Offset number of jobs (after filtering and sorting) to skip
+	Offset int
+	// Status only list the jobs with this status (case insensitive)
+	Status string
+	// VO only list the jobs of the services of this VO
+	VO string
+	// Service only list the jobs of this service
+	Service string
+	// OlderThan only list the jobs created before this duration ago
+	OlderThan time.Duration
+	// Sort key to sort the jobs by (default: "-creation_time")
+	Sort string
+}
+
+// Validate checks the options of the job list
+func (opts JobListOptions) Validate() error {
+	if opts.Limit < 0 || opts.Limit > MaxJobListLimit {
+		return fmt.Errorf("the limit must be between 1 and %d", MaxJobListLimit)
+	}
+	if opts.Offset < 0 {
+		return fmt.Errorf("the offset must be a non-negative integer")
+	}
+	if opts.OlderThan < 0 {
+		return fmt.Errorf("the olderThan duration can not be negative")
+	}
+	switch strings.ToLower(opts.Status) {
+	case "", strings.ToLower(JobPending), strings.ToLower(JobRunning), strings.ToLower(JobSucceeded), strings.ToLower(JobFailed), strings.ToLower(JobSuspended):
+	default:
+		return fmt.Errorf("invalid status \"%s\", it must be \"%s\", \"%s\", \"%s\", \"%s\" or \"%s\"", opts.Status, JobPending, JobRunning, JobSucceeded, JobFailed, JobSuspended)
+	}
+	switch strings.TrimPrefix(opts.Sort, "-") {
+	case "", SortByName, SortByService, SortByCreationTime:
+	default:
+		return fmt.Errorf("invalid sort key \"%s\", it must be \"%s\", \"%s\" or \"%s\"", opts.Sort, SortByName, SortByService, SortByCreationTime)
+	}
+	return nil
+}
+
+// Apply filters, sorts and paginates the jobs. Returns the page of jobs, the total number of jobs
+// satisfying the filters and the continue token of the next page (empty if it is the last page)
+func (opts JobListOptions) Apply(jobs []ClusterJob, now time.Time) ([]ClusterJob, int, string) {
+	filtered := []ClusterJob{}
+	for _, job := range jobs {
+		if opts.matches(job, now) {
+			filtered = append(filtered, job)
+		}
+	}
+
+	sortKey := opts.Sort
+	if sortKey == "" {
+		sortKey = "-" + SortByCreationTime
+	}
+	key := strings.TrimPrefix(sortKey, "-")
+	desc := strings.HasPrefix(sortKey, "-")
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i].getSortValue(key), filtered[j].getSortValue(key)
+		if a == b {
+			// Keep a deterministic order between pages
+			a, b = filtered[i].Name, filtered[j].Name
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+
+	total := len(filtered)
+	if opts.Offset >= total {
+		return []ClusterJob{}, total, ""
+	}
+	page := filtered[opts.Offset:]
+	next := ""
+	if opts.Limit > 0 && len(page) > opts.Limit {
+		page = page[:opts.Limit]
+		next = MakeServiceListContinue(opts.Offset + opts.Limit)
+	}
+
+	return page, total, next
+}
+
+// matches checks if a job satisfies the status, VO, service and age filters
+func (opts JobListOptions) matches(job ClusterJob, now time.Time) bool {
+	if opts.Status != "" && !strings.EqualFold(job.Status, opts.Status) {
+		return false
+	}
+	if opts.VO != "" && job.VO != opts.VO {
+		return false
+	}
+	if opts.Service != "" && job.Service != opts.Service {
+		return false
+	}
+	if opts.OlderThan > 0 && (job.CreationTime == nil || job.CreationTime.After(now.Add(-opts.OlderThan))) {
+		return false
+	}
+	return true
+}
+
+// getSortValue returns the value of the job field used to sort by key
+func (job ClusterJob) getSortValue(key string) string {
+	switch key {
+	case SortByService:
+		return job.Service
+	case SortByCreationTime:
+		if job.CreationTime == nil {
+			return ""
+		}
+		// RFC3339 timestamps (in UTC) sort lexicographically
+		return job.CreationTime.UTC().Format(time.RFC3339)
+	}
+	return job.Name
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJobListOptionsApply(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	makeJob := func(name string, service string, vo string, status string, age time.Duration) ClusterJob {
+		creation := metav1.NewTime(now.Add(-age))
+		return ClusterJob{Name: name, Service: service, VO: vo, JobInfo: JobInfo{Status: status, CreationTime: &creation}}
+	}
+	jobs := []ClusterJob{
+		makeJob("a-1", "a", "vo1", JobRunning, time.Hour),
+		makeJob("b-1", "b", "vo2", JobSucceeded, 48*time.Hour),
+		makeJob("a-2", "a", "vo1", JobRunning, 72*time.Hour),
+		makeJob("c-1", "c", "", JobPending, 10*time.Minute),
+	}
+
+	scenarios := []struct {
+		name          string
+		opts          JobListOptions
+		expectedNames []string
+		expectedTotal int
+		expectedNext  string
+	}{
+		{"default (newest first)", JobListOptions{}, []string{"c-1", "a-1", "b-1", "a-2"}, 4, ""},
+		{"oldest first", JobListOptions{Sort: SortByCreationTime}, []string{"a-2", "b-1", "a-1", "c-1"}, 4, ""},
+		{"service with ties by name", JobListOptions{Sort: "-service"}, []string{"c-1", "b-1", "a-2", "a-1"}, 4, ""},
+		{"running status (case insensitive)", JobListOptions{Status: "running"}, []string{"a-1", "a-2"}, 2, ""},
+		{"vo", JobListOptions{VO: "vo2"}, []string{"b-1"}, 1, ""},
+		{"service", JobListOptions{Service: "c"}, []string{"c-1"}, 1, ""},
+		{"older than", JobListOptions{OlderThan: 24 * time.Hour}, []string{"b-1", "a-2"}, 2, ""},
+		{"running and older than", JobListOptions{Status: JobRunning, OlderThan: 24 * time.Hour}, []string{"a-2"}, 1, ""},
+		{"first page", JobListOptions{Limit: 3}, []string{"c-1", "a-1", "b-1"}, 4, MakeServiceListContinue(3)},
+		{"last page", JobListOptions{Limit: 3, Offset: 3}, []string{"a-2"}, 4, ""},
+		{"offset out of range", JobListOptions{Offset: 10}, []string{}, 4, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			page, total, next := s.opts.Apply(jobs, now)
+			names := []string{}
+			for _, job := range page {
+				names = append(names, job.Name)
+			}
+			if len(names) != len(s.expectedNames) {
+				t.Fatalf("expecting %v, got %v", s.expectedNames, names)
+			}
+			for i := range names {
+				if names[i] != s.expectedNames[i] {
+					t.Fatalf("expecting %v, got %v", s.expectedNames, names)
+				}
+			}
+			if total != s.expectedTotal {
+				t.Errorf("expecting total %d, got %d", s.expectedTotal, total)
+			}
+			if next != s.expectedNext {
+				t.Errorf("expecting next token \"%s\", got \"%s\"", s.expectedNext, next)
+			}
+		})
+	}
+}
+
+func TestJobListOptionsValidate(t *testing.T) {
+	invalid := []JobListOptions{
+		{Limit: MaxJobListLimit + 1},
+		{Offset: -1},
+		{OlderThan: -time.Hour},
+		{Status: "unknown"},
+		{Sort: "image"},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expecting error validating %+v", opts)
+		}
+	}
+	if err := (JobListOptions{Status: "Failed", Sort: "-creation_time", Limit: 10}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Phases of the executions of the jobs, from the receipt of the event to the end of the script
+const (
+	// LatencyPhaseDispatch from the receipt of the event to the creation of the job
+	LatencyPhaseDispatch = "dispatch"
+	// LatencyPhaseScheduling from the creation of the job to the scheduling of its pod (including the time
+	// queued or deferred)
+	LatencyPhaseScheduling = "scheduling"
+	// LatencyPhaseContainerStartup from the scheduling of the pod to the start of the container (image pull)
+	LatencyPhaseContainerStartup = "container_startup"
+	// LatencyPhaseInitialization from the start of the container to the start of the script (FaaS Supervisor
+	// initialization and input download)
+	LatencyPhaseInitialization = "initialization"
+	// LatencyPhaseExecution from the start of the script to the end of the container
+	LatencyPhaseExecution = "execution"
+)
+
+// LatencyPhases phases of the executions, in order
+var LatencyPhases = []string{
+	LatencyPhaseDispatch,
+	LatencyPhaseScheduling,
+	LatencyPhaseContainerStartup,
+	LatencyPhaseInitialization,
+	LatencyPhaseExecution,
+}
+
+// JobTimings milestones of the execution of a job and the duration (in seconds) of its completed phases
+type JobTimings struct {
+	EventReceived    *time.Time `json:"event_received,omitempty"`
+	JobCreated       *time.Time `json:"job_created,omitempty"`
+	PodScheduled     *time.Time `json:"pod_scheduled,omitempty"`
+	ContainerStarted *time.Time `json:"container_started,omitempty"`
+	ScriptStarted    *time.Time `json:"script_started,omitempty"`
+	Finished         *time.Time `json:"finished,omitempty"`
+	// Phases duration of the phases whose start and end milestones are known
+	Phases map[string]float64 `json:"phases,omitempty"`
+	// Startup time from the receipt of the event to the start of the script (cold start)
+	Startup *float64 `json:"startup,omitempty"`
+}
+
+// SetPhases computes the duration of the phases from the milestones
+func (t *JobTimings) SetPhases() {
+	milestones := []*time.Time{t.EventReceived, t.JobCreated, t.PodScheduled, t.ContainerStarted, t.ScriptStarted, t.Finished}
+	t.Phases = map[string]float64{}
+	for i, phase := range LatencyPhases {
+		if start, end := milestones[i], milestones[i+1]; start != nil && end != nil {
+			t.Phases[phase] = end.Sub(*start).Seconds()
+		}
+	}
+	t.Startup = nil
+	if t.EventReceived != nil && t.ScriptStarted != nil {
+		startup := t.ScriptStarted.Sub(*t.EventReceived).Seconds()
+		t.Startup = &startup
+	}
+}
+
+// LatencyStats statistics (in seconds) of the durations of a phase
+type LatencyStats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+// NewLatencyStats returns the statistics of the provided durations
+func NewLatencyStats(durations []float64) LatencyStats {
+	stats := LatencyStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+	sorted := make([]float64, len(durations))
+	copy(sorted, durations)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, d := range sorted {
+		sum += d
+	}
+	stats.Mean = sum / float64(len(sorted))
+	stats.P50 = percentile(sorted, 50)
+	stats.P95 = percentile(sorted, 95)
+	stats.Max = sorted[len(sorted)-1]
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// JobLatency timings of a job of a latency report
+type JobLatency struct {
+	Name    string     `json:"name"`
+	Status  string     `json:"status"`
+	Timings JobTimings `json:"timings"`
+}
+
+// ServiceLatencyReport latency of the last jobs of a service aggregated per phase
+type ServiceLatencyReport struct {
+	Service string `json:"service"`
+	// Phases statistics of the phases of the jobs
+	Phases map[string]LatencyStats `json:"phases"`
+	// Startup statistics of the time from the receipt of the events to the start of the scripts
+	Startup LatencyStats `json:"startup"`
+	// Bottleneck startup phase (all except the execution) with the highest mean
+	Bottleneck string `json:"bottleneck,omitempty"`
+	// Budget latency budget (in seconds) of the service's startup (0 if not defined)
+	Budget int `json:"budget,omitempty"`
+	// OverBudget number of jobs whose startup exceeded the budget
+	OverBudget int          `json:"over_budget"`
+	Jobs       []JobLatency `json:"jobs"`
+}
+
+// NewServiceLatencyReport aggregates the timings of the jobs of a service
+func NewServiceLatencyReport(service string, budget int, jobs []JobLatency) *ServiceLatencyReport {
+	report := &ServiceLatencyReport{
+		Service: service,
+		Phases:  map[string]LatencyStats{},
+		Budget:  budget,
+		Jobs:    jobs,
+	}
+
+	durations := map[string][]float64{}
+	startups := []float64{}
+	for _, job := range jobs {
+		for phase, d := range job.Timings.Phases {
+			durations[phase] = append(durations[phase], d)
+		}
+		if job.Timings.Startup != nil {
+			startups = append(startups, *job.Timings.Startup)
+			if budget > 0 && *job.Timings.Startup > float64(budget) {
+				report.OverBudget++
+			}
+		}
+	}
+
+	highest := 0.0
+	for _, phase := range LatencyPhases {
+		stats := NewLatencyStats(durations[phase])
+		report.Phases[phase] = stats
+		if phase != LatencyPhaseExecution && stats.Count > 0 && stats.Mean > highest {
+			highest = stats.Mean
+			report.Bottleneck = phase
+		}
+	}
+	report.Startup = NewLatencyStats(startups)
+
+	return report
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobTimingsSetPhases(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		t := start.Add(time.Duration(seconds) * time.Second)
+		return &t
+	}
+	timings := &JobTimings{EventReceived: at(0), JobCreated: at(1), PodScheduled: at(3), ContainerStarted: at(13), Finished: at(60)}
+	timings.SetPhases()
+
+	expected := map[string]float64{LatencyPhaseDispatch: 1, LatencyPhaseScheduling: 2, LatencyPhaseContainerStartup: 10}
+	if len(timings.Phases) != len(expected) {
+		t.Fatalf("expecting phases %v, got %v", expected, timings.Phases)
+	}
+	for phase, d := range expected {
+		if timings.Phases[phase] != d {
+			t.Errorf("expecting %s %v, got %v", phase, d, timings.Phases[phase])
+		}
+	}
+	if timings.Startup != nil {
+		t.Errorf("expecting no startup without the script start, got %v", *timings.Startup)
+	}
+
+	timings.ScriptStarted = at(15)
+	timings.SetPhases()
+	if timings.Phases[LatencyPhaseInitialization] != 2 || timings.Phases[LatencyPhaseExecution] != 45 {
+		t.Errorf("unexpected phases: %v", timings.Phases)
+	}
+	if timings.Startup == nil || *timings.Startup != 15 {
+		t.Errorf("expecting startup 15, got %v", timings.Startup)
+	}
+}
+
+func TestNewServiceLatencyReport(t *testing.T) {
+	job := func(scheduling, startup float64) JobLatency {
+		return JobLatency{Timings: JobTimings{
+			Phases:  map[string]float64{LatencyPhaseDispatch: 0.1, LatencyPhaseScheduling: scheduling, LatencyPhaseExecution: 100},
+			Startup: &startup,
+		}}
+	}
+	report := NewServiceLatencyReport("test", 10, []JobLatency{job(1, 2), job(3, 4), job(20, 21), job(4, 5)})
+
+	if report.Bottleneck != LatencyPhaseScheduling {
+		t.Errorf("expecting bottleneck %s, got %s", LatencyPhaseScheduling, report.Bottleneck)
+	}
+	if report.OverBudget != 1 {
+		t.Errorf("expecting 1 job over budget, got %d", report.OverBudget)
+	}
+	stats := report.Phases[LatencyPhaseScheduling]
+	if stats.Count != 4 || stats.Mean != 7 || stats.P50 != 3 || stats.P95 != 20 || stats.Max != 20 {
+		t.Errorf("unexpected scheduling stats: %+v", stats)
+	}
+	if report.Phases[LatencyPhaseContainerStartup].Count != 0 {
+		t.Errorf("expecting no container startup durations, got %+v", report.Phases[LatencyPhaseContainerStartup])
+	}
+	if report.Startup.Count != 4 || report.Startup.Max != 21 {
+		t.Errorf("unexpected startup stats: %+v", report.Startup)
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// Types of the service lifecycle events
+const (
+	JobCreatedEvent        = "job_created"
+	JobSucceededEvent      = "job_succeeded"
+	JobFailedEvent         = "job_failed"
+	ServiceCreatedEvent    = "service_created"
+	ServiceUpdatedEvent    = "service_updated"
+	ServiceDeletedEvent    = "service_deleted"
+	ObjectQuarantinedEvent = "object_quarantined"
+)
+
+// LifecycleEvent lifecycle event of a service or of one of its jobs, pushed to the subscribers of /system/events/ws
+type LifecycleEvent struct {
+	Type    string `json:"type"`
+	Service string `json:"service"`
+	// Job name of the job (only for the job events)
+	Job string `json:"job,omitempty"`
+	// Message details of the event (e.g. the reason of a failed job)
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// LifecycleSubscription update of the services whose events are received by a subscriber. An empty list of
+// services receives the events of all the services
+type LifecycleSubscription struct {
+	Subscribe   []string `json:"subscribe,omitempty"`
+	Unsubscribe []string `json:"unsubscribe,omitempty"`
+}
@@ -0,0 +1,59 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+const (
+	// MaintenanceConfigMapName name of the configMap (in the services namespace) storing the maintenance status
+	MaintenanceConfigMapName = "oscar-maintenance"
+	// MaintenanceFileName key of the maintenance configMap with the status
+	MaintenanceFileName = "maintenance.json"
+	// DefaultMaintenanceRetryAfter time (in seconds) returned in the Retry-After header of the rejected requests
+	// if the freeze does not define it
+	DefaultMaintenanceRetryAfter = 300
+)
+
+// MaintenanceStatus status of the read-only mode of the API, enabled to perform upgrades of the cluster
+// components (e.g. MinIO or Kubernetes)
+type MaintenanceStatus struct {
+	// Frozen the management requests modifying the cluster are rejected
+	Frozen bool `json:"frozen"`
+	// Reason of the freeze, returned in the errors of the rejected requests
+	Reason string `json:"reason,omitempty"`
+	// Since time of the freeze
+	Since *time.Time `json:"since,omitempty"`
+	// RetryAfter time (in seconds) returned in the Retry-After header of the rejected requests
+	RetryAfter int `json:"retry_after,omitempty"`
+	// QueueInvocations the async invocations are queued (instead of processed) until the end of the freeze
+	QueueInvocations bool `json:"queue_invocations"`
+	// QueuedInvocations number of async invocations queued in the OSCAR manager replica serving the request
+	QueuedInvocations int `json:"queued_invocations"`
+}
+
+// MaintenanceFreezeRequest request to enable the read-only mode of the API
+type MaintenanceFreezeRequest struct {
+	// Reason of the freeze
+	// Optional
+	Reason string `json:"reason"`
+	// RetryAfter time (in seconds) returned in the Retry-After header of the rejected requests
+	// Optional. (default: 300)
+	RetryAfter int `json:"retry_after" binding:"gte=0"`
+	// QueueInvocations queue the async invocations until the end of the freeze
+	// Optional. (default: false)
+	QueueInvocations bool `json:"queue_invocations"`
+}
@@ -0,0 +1,64 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuarantineRecordSuffix suffix of the audit records stored next to the quarantined objects
+const QuarantineRecordSuffix = ".quarantine.json"
+
+// QuarantineRecord audit entry of an infected input object moved to the service's quarantine path
+type QuarantineRecord struct {
+	// Service name of the service
+	Service string `json:"service"`
+	// Bucket of the infected input object
+	Bucket string `json:"bucket"`
+	// Key of the infected input object
+	Key string `json:"key"`
+	// QuarantineKey key of the object in the quarantine path
+	QuarantineKey string `json:"quarantine_key"`
+	// Threat name of the threat detected by the scanner
+	Threat string `json:"threat"`
+	// Scanner type of the scanner ("clamav" or "icap")
+	Scanner string `json:"scanner"`
+	// ScannedAt time of the scan
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// ValidateQuarantinePath checks that the quarantine path is placed in one of the buckets of the service's inputs or
+// outputs in the default MinIO provider, but not inside any of its inputs (which would trigger the service again)
+func (service *Service) ValidateQuarantinePath() error {
+	if service.QuarantinePath == "" {
+		return nil
+	}
+
+	bucket, _ := StorageIOConfig{Path: service.QuarantinePath}.SplitPath()
+	if !service.ownsBucket(bucket) {
+		return fmt.Errorf("the quarantine_path \"%s\" must be placed in the bucket of one of the service's inputs or outputs in the default MinIO provider", service.QuarantinePath)
+	}
+
+	for _, in := range service.Input {
+		if provName, provID := in.GetProvider(); provName == MinIOName && provID == DefaultProvider && in.ContainsPath(service.QuarantinePath) {
+			return fmt.Errorf("the quarantine_path \"%s\" can not be placed in the input \"%s\"", service.QuarantinePath, in.Path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// TriggerDeliveryStats delivery stats of the MinIO notifications (webhook) triggering the jobs of a service, as
+// reported by the metrics of MinIO
+type TriggerDeliveryStats struct {
+	Service string `json:"service"`
+	// Delivered events sent to OSCAR
+	Delivered float64 `json:"delivered"`
+	// Failed events whose delivery failed
+	Failed float64 `json:"failed"`
+	// Pending events queued or being sent
+	Pending float64 `json:"pending"`
+}
@@ -0,0 +1,180 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Default claims of the OIDC tokens identifying the users and their groups
+const (
+	DefaultOIDCSubjectClaim = "sub"
+	// DefaultOIDCGroupsClaim EGI Check-in entitlements ("urn:mace:egi.eu:group:<GROUP>:..." URNs)
+	DefaultOIDCGroupsClaim = "eduperson_entitlement"
+)
+
+// OIDCIssuer trusted OpenID Connect issuer
+type OIDCIssuer struct {
+	// Issuer as returned in the "iss" claim of the tokens
+	Issuer string `json:"issuer"`
+	// Subject user identifier granted to access the cluster
+	Subject string `json:"subject,omitempty"`
+	// Groups granted to access the cluster (after the mapping), along with the approved VOs
+	Groups []string `json:"groups,omitempty"`
+	// SubjectClaim claim of the user info identifying the users (default: "sub")
+	SubjectClaim string `json:"subject_claim,omitempty"`
+	// GroupsClaim claim of the user info with the groups of the users (default: "eduperson_entitlement"). Nested
+	// claims are separated by dots (e.g. "realm_access.roles"). Without GroupsPattern, the groups of the EGI
+	// entitlements are taken from their URNs, the rest of the claims must be a list of group names (or paths, e.g.
+	// "/team" in Keycloak, taking their last segment)
+	GroupsClaim string `json:"groups_claim,omitempty"`
+	// GroupsPattern regular expression to extract the group names from the values of the groups claim, ignoring
+	// the values not matching it
+	GroupsPattern string `json:"groups_pattern,omitempty"`
+	// GroupsTemplate template of the group names with the submatches of GroupsPattern (e.g. "$1" or "${vo}").
+	// Default: the first submatch, or the whole match if the pattern has no submatches
+	GroupsTemplate string `json:"groups_template,omitempty"`
+	// GroupsMapping names of the groups in OSCAR (e.g. VOs) by name of the group in the issuer. The unmapped
+	// groups keep their names
+	GroupsMapping map[string]string `json:"groups_mapping,omitempty"`
+	// Offline authorise from the claims of the validated tokens, without requesting the user info to the issuer
+	Offline bool `json:"offline,omitempty"`
+	// Audience required in the "aud" claim of the tokens, not checked if empty
+	Audience string `json:"audience,omitempty"`
+	// JWKSURL URL of the JSON Web Key Set of the issuer, to validate the tokens without discovering its provider in
+	// offline mode (e.g. during issuer outages)
+	JWKSURL string `json:"jwks_url,omitempty"`
+}
+
+// ParseOIDCIssuers parses the JSON list of additional trusted OIDC issuers
+func ParseOIDCIssuers(s string) ([]OIDCIssuer, error) {
+	issuers := []OIDCIssuer{}
+	if strings.TrimSpace(s) == "" {
+		return issuers, nil
+	}
+	if err := json.Unmarshal([]byte(s), &issuers); err != nil {
+		return nil, fmt.Errorf("the issuers must be a JSON list: %v", err)
+	}
+	for _, iss := range issuers {
+		if strings.TrimSpace(iss.Issuer) == "" {
+			return nil, fmt.Errorf("the issuer of all the entries is required")
+		}
+		if _, err := iss.GetGroupsRegexp(); err != nil {
+			return nil, fmt.Errorf("the groups pattern of the issuer \"%s\" is not valid: %v", iss.Issuer, err)
+		}
+	}
+	return issuers, nil
+}
+
+// GetSubjectClaim returns the claim identifying the users
+func (iss OIDCIssuer) GetSubjectClaim() string {
+	if iss.SubjectClaim == "" {
+		return DefaultOIDCSubjectClaim
+	}
+	return iss.SubjectClaim
+}
+
+// GetGroupsClaim returns the claim with the groups of the users
+func (iss OIDCIssuer) GetGroupsClaim() string {
+	if iss.GroupsClaim == "" {
+		return DefaultOIDCGroupsClaim
+	}
+	return iss.GroupsClaim
+}
+
+// GetGroupsRegexp returns the compiled GroupsPattern, nil if it is not defined
+func (iss OIDCIssuer) GetGroupsRegexp() (*regexp.Regexp, error) {
+	if iss.GroupsPattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(iss.GroupsPattern)
+}
+
+// ExtractGroup returns the group name extracted from a value of the groups claim with the pattern re (compiled
+// from GroupsPattern) and GroupsTemplate, or false if the value does not match it
+func (iss OIDCIssuer) ExtractGroup(re *regexp.Regexp, value string) (string, bool) {
+	match := re.FindStringSubmatchIndex(value)
+	if match == nil {
+		return "", false
+	}
+	template := iss.GroupsTemplate
+	if template == "" {
+		template = "$0"
+		if re.NumSubexp() > 0 {
+			template = "$1"
+		}
+	}
+	group := string(re.ExpandString(nil, template, value, match))
+	return group, group != ""
+}
+
+// GetAllowedGroups returns the groups of the issuer granted to access the cluster, along with the approved VOs
+func (iss OIDCIssuer) GetAllowedGroups() []string {
+	return withApprovedVOs(iss.Groups)
+}
+
+// MapGroup returns the name in OSCAR of a group of the issuer
+func (iss OIDCIssuer) MapGroup(group string) string {
+	if mapped, ok := iss.GroupsMapping[group]; ok {
+		return mapped
+	}
+	return group
+}
+
+// GetOIDCIssuers returns the trusted OIDC issuers: the one of the OIDC_ISSUER, OIDC_SUBJECT, OIDC_GROUPS,
+// OIDC_GROUPS_*, OIDC_OFFLINE, OIDC_AUDIENCE and OIDC_JWKS_URL options followed by the OIDC_ISSUERS (an entry of the OIDC_ISSUER replaces it)
+func (cfg *Config) GetOIDCIssuers() []OIDCIssuer {
+	issuers := []OIDCIssuer{}
+	if cfg.OIDCIssuer != "" {
+		replaced := false
+		for _, iss := range cfg.OIDCIssuers {
+			replaced = replaced || sameOIDCIssuer(iss.Issuer, cfg.OIDCIssuer)
+		}
+		if !replaced {
+			issuers = append(issuers, OIDCIssuer{
+				Issuer:         cfg.OIDCIssuer,
+				Subject:        cfg.OIDCSubject,
+				Groups:         cfg.OIDCGroups,
+				GroupsClaim:    cfg.OIDCGroupsClaim,
+				GroupsPattern:  cfg.OIDCGroupsPattern,
+				GroupsTemplate: cfg.OIDCGroupsTemplate,
+				Offline:        cfg.OIDCOffline,
+				Audience:       cfg.OIDCAudience,
+				JWKSURL:        cfg.OIDCJWKSURL,
+			})
+		}
+	}
+	return append(issuers, cfg.OIDCIssuers...)
+}
+
+// GetOIDCIssuer returns the trusted OIDC issuer of the "iss" claim of a token, nil if it is not trusted
+func (cfg *Config) GetOIDCIssuer(issuer string) *OIDCIssuer {
+	for _, iss := range cfg.GetOIDCIssuers() {
+		if sameOIDCIssuer(iss.Issuer, issuer) {
+			return &iss
+		}
+	}
+	return nil
+}
+
+// sameOIDCIssuer checks if two issuers are the same, ignoring the trailing slashes
+func sameOIDCIssuer(a string, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,97 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOIDCIssuers(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		value       string
+		expected    []OIDCIssuer
+		returnError bool
+	}{
+		{"empty", "", []OIDCIssuer{}, false},
+		{"valid", `[{"issuer":"https://keycloak.example.org/realms/oscar","groups":["team"],"groups_claim":"groups","groups_mapping":{"team":"vo.example.eu"}}]`,
+			[]OIDCIssuer{{Issuer: "https://keycloak.example.org/realms/oscar", Groups: []string{"team"}, GroupsClaim: "groups", GroupsMapping: map[string]string{"team": "vo.example.eu"}}}, false},
+		{"not a list", `{"issuer":"https://keycloak.example.org"}`, nil, true},
+		{"no issuer", `[{"groups":["team"]}]`, nil, true},
+		{"invalid pattern", `[{"issuer":"https://keycloak.example.org","groups_pattern":"(team"}]`, nil, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			issuers, err := ParseOIDCIssuers(s.value)
+			if (err != nil) != s.returnError {
+				t.Fatalf("expecting error %v, got %v", s.returnError, err)
+			}
+			if !s.returnError && !reflect.DeepEqual(issuers, s.expected) {
+				t.Errorf("expecting %v, got %v", s.expected, issuers)
+			}
+		})
+	}
+}
+
+func TestGetOIDCIssuer(t *testing.T) {
+	cfg := &Config{
+		OIDCIssuer: "https://aai.egi.eu/auth/realms/egi",
+		OIDCGroups: []string{"vo.example.eu"},
+		OIDCIssuers: []OIDCIssuer{
+			{Issuer: "https://keycloak.example.org/realms/oscar/", SubjectClaim: "preferred_username", GroupsClaim: "groups"},
+		},
+	}
+
+	if issuers := cfg.GetOIDCIssuers(); len(issuers) != 2 {
+		t.Fatalf("expecting 2 issuers, got %d", len(issuers))
+	}
+
+	egi := cfg.GetOIDCIssuer("https://aai.egi.eu/auth/realms/egi")
+	if egi == nil || egi.GetGroupsClaim() != DefaultOIDCGroupsClaim || !reflect.DeepEqual(egi.Groups, cfg.OIDCGroups) {
+		t.Errorf("unexpected primary issuer: %v", egi)
+	}
+
+	keycloak := cfg.GetOIDCIssuer("https://keycloak.example.org/realms/oscar")
+	if keycloak == nil || keycloak.GetSubjectClaim() != "preferred_username" || keycloak.GetGroupsClaim() != "groups" {
+		t.Errorf("unexpected additional issuer: %v", keycloak)
+	}
+
+	if iss := cfg.GetOIDCIssuer("https://untrusted.example.org"); iss != nil {
+		t.Errorf("expecting the issuer not to be trusted, got %v", iss)
+	}
+
+	// An entry of the OIDC_ISSUER replaces it
+	cfg.OIDCIssuers = append(cfg.OIDCIssuers, OIDCIssuer{Issuer: cfg.OIDCIssuer + "/", Groups: []string{"other"}})
+	if issuers := cfg.GetOIDCIssuers(); len(issuers) != 2 {
+		t.Fatalf("expecting 2 issuers, got %d", len(issuers))
+	}
+	if egi := cfg.GetOIDCIssuer(cfg.OIDCIssuer); egi == nil || !reflect.DeepEqual(egi.Groups, []string{"other"}) {
+		t.Errorf("expecting the replaced primary issuer, got %v", egi)
+	}
+}
+
+func TestOIDCIssuerMapGroup(t *testing.T) {
+	iss := OIDCIssuer{GroupsMapping: map[string]string{"team": "vo.example.eu"}}
+	if group := iss.MapGroup("team"); group != "vo.example.eu" {
+		t.Errorf("expecting the mapped group, got %s", group)
+	}
+	if group := iss.MapGroup("other"); group != "other" {
+		t.Errorf("expecting the unmapped group, got %s", group)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// Status of the asynchronous operations and of their steps
+const (
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+	OperationPending   = "pending"
+	OperationSkipped   = "skipped"
+)
+
+// Steps of the asynchronous service creations
+const (
+	StepBackendCreated       = "backend_created"
+	StepWebhookRegistered    = "webhook_registered"
+	StepBucketsCreated       = "buckets_created"
+	StepNotificationsEnabled = "notifications_enabled"
+)
+
+// CreateOperationSteps steps reported by the asynchronous service creations, in order
+var CreateOperationSteps = []string{StepBackendCreated, StepWebhookRegistered, StepBucketsCreated, StepNotificationsEnabled}
+
+// Operation progress of an asynchronous operation on a service
+type Operation struct {
+	ID string `json:"id"`
+	// Type of the operation (e.g. "create")
+	Type    string `json:"type"`
+	Service string `json:"service"`
+	// Status "running", "succeeded" or "failed"
+	Status string          `json:"status"`
+	Steps  []OperationStep `json:"steps"`
+	// Error of the failed operations
+	Error     *APIError `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OperationStep progress of a step of an operation
+type OperationStep struct {
+	Name string `json:"name"`
+	// Status "pending", "running", "succeeded", "failed" or "skipped" (after a failed step)
+	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// OutputOverrideParam query parameter of the invocations with the destination ("provider:path") of their outputs
+	OutputOverrideParam = "output_override"
+
+	// OutputConfigAnnotation annotation of the pods of the jobs with overridden outputs or temporary credentials, with
+	// the FDL mounted instead of the service's one
+	OutputConfigAnnotation = "oscar_output_config"
+)
+
+// ParseOutputDestination parses an output destination with the format "provider:path" (e.g.
+// "minio.default:results/alice"). The provider can be omitted for the default MinIO provider
+func ParseOutputDestination(value string) (StorageIOConfig, error) {
+	dest := StorageIOConfig{Provider: MinIOName + ProviderSeparator + DefaultProvider, Path: value}
+	if i := strings.Index(value, ":"); i >= 0 {
+		dest.Provider = value[:i]
+		dest.Path = value[i+1:]
+	}
+	dest.Path = strings.Trim(dest.Path, " /")
+	if err := validateOutputDestinationPath(dest.Path); err != nil {
+		return dest, err
+	}
+	return dest, nil
+}
+
+// validateOutputDestinationPath checks that the path of an output destination is not empty and has no relative
+// elements, so it can't escape the allowed destinations
+func validateOutputDestinationPath(destPath string) error {
+	if destPath == "" {
+		return fmt.Errorf("the output destination has no path")
+	}
+	for _, elem := range strings.Split(destPath, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return fmt.Errorf("the path of the output destination \"%s\" is not valid", destPath)
+		}
+	}
+	return nil
+}
+
+// ValidateOutputDestinations checks that the allowed output destinations are placed in the MinIO providers of the
+// service, outside its inputs
+func (service *Service) ValidateOutputDestinations() error {
+	for _, dest := range service.OutputDestinations {
+		provName, provID := dest.GetProvider()
+		if provName != MinIOName || service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+			return fmt.Errorf("the output destination \"%s\" must be placed in one of the MinIO providers of the service", dest.Path)
+		}
+		if err := validateOutputDestinationPath(strings.Trim(dest.Path, " /")); err != nil {
+			return err
+		}
+		for _, in := range service.Input {
+			if inName, inID := in.GetProvider(); inName == provName && inID == provID && (in.ContainsPath(dest.Path) || dest.ContainsPath(in.Path)) {
+				return fmt.Errorf("the output destination \"%s\" can not overlap the input \"%s\"", dest.Path, in.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// WithOutputOverride returns a copy of the service whose outputs are stored in dest, which must be placed in one of
+// the service's output destinations. The jobs of the copy are not delegated to the replicas
+func (service *Service) WithOutputOverride(dest StorageIOConfig) (*Service, error) {
+	if len(service.Output) == 0 {
+		return nil, fmt.Errorf("the service has no outputs to override")
+	}
+	destName, destID := dest.GetProvider()
+	allowed := false
+	for _, allowedDest := range service.OutputDestinations {
+		if name, id := allowedDest.GetProvider(); name == destName && id == destID && allowedDest.ContainsPath(dest.Path) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("the output destination \"%s:%s\" is not allowed by the output_destinations of the service", dest.Provider, dest.Path)
+	}
+
+	overridden := *service
+	overridden.Output = make([]StorageIOConfig, len(service.Output))
+	for i, out := range service.Output {
+		out.Provider = dest.Provider
+		out.Path = dest.Path
+		overridden.Output[i] = out
+	}
+	overridden.Replicas = nil
+	overridden.OutputOverride = &dest
+	return &overridden, nil
+}
+
+// setOutputConfigVolume replaces the FDL of the service's configMap by the one in the OutputConfigAnnotation of the
+// pod, keeping the rest of the files
+func setOutputConfigVolume(p *v1.PodSpec, service *Service) {
+	optional := true
+	for i, volume := range p.Volumes {
+		if volume.Name != ConfigVolumeName {
+			continue
+		}
+		p.Volumes[i].VolumeSource = v1.VolumeSource{
+			Projected: &v1.ProjectedVolumeSource{
+				Sources: []v1.VolumeProjection{
+					{
+						ConfigMap: &v1.ConfigMapProjection{
+							LocalObjectReference: v1.LocalObjectReference{Name: service.Name},
+							Items: []v1.KeyToPath{
+								{Key: ScriptFileName, Path: ScriptFileName},
+								{Key: FeaturesFileName, Path: FeaturesFileName},
+							},
+							Optional: &optional,
+						},
+					},
+					{
+						DownwardAPI: &v1.DownwardAPIProjection{
+							Items: []v1.DownwardAPIVolumeFile{
+								{
+									Path:     FDLFileName,
+									FieldRef: &v1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", OutputConfigAnnotation)},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+}
+
+// GetOutputConfig returns the FDL of a service with overridden outputs or temporary credentials, stored in the
+// OutputConfigAnnotation of its jobs' pods
+func (service *Service) GetOutputConfig() (string, error) {
+	svc := *service
+	svc.Script = ""
+	return svc.ToYAML()
+}
@@ -0,0 +1,149 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutputDestination(t *testing.T) {
+	scenarios := []struct {
+		value            string
+		expectedProvider string
+		expectedPath     string
+		returnError      bool
+	}{
+		{"minio.other:results/alice", "minio.other", "results/alice", false},
+		{"results/bob/", "minio.default", "results/bob", false},
+		{"minio:", "", "", true},
+		{"results/../other", "", "", true},
+		{"results//alice", "", "", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.value, func(t *testing.T) {
+			dest, err := ParseOutputDestination(s.value)
+			if s.returnError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dest.Provider != s.expectedProvider || dest.Path != s.expectedPath {
+				t.Errorf("expecting %s:%s, got %s:%s", s.expectedProvider, s.expectedPath, dest.Provider, dest.Path)
+			}
+		})
+	}
+}
+
+func TestValidateOutputDestinations(t *testing.T) {
+	service := &Service{
+		Input: []StorageIOConfig{{Provider: "minio.default", Path: "bucket/in"}},
+		StorageProviders: &StorageProviders{
+			MinIO: map[string]*MinIOProvider{DefaultProvider: {}},
+			S3:    map[string]*S3Provider{"aws": {}},
+		},
+	}
+
+	scenarios := []struct {
+		name        string
+		dest        StorageIOConfig
+		returnError bool
+	}{
+		{"valid", StorageIOConfig{Provider: "minio", Path: "results"}, false},
+		{"input bucket", StorageIOConfig{Provider: "minio.default", Path: "bucket/results"}, false},
+		{"inside the input", StorageIOConfig{Provider: "minio", Path: "bucket/in/results"}, true},
+		{"containing the input", StorageIOConfig{Provider: "minio", Path: "bucket"}, true},
+		{"undefined provider", StorageIOConfig{Provider: "minio.other", Path: "results"}, true},
+		{"not minio", StorageIOConfig{Provider: "s3.aws", Path: "results"}, true},
+		{"relative path", StorageIOConfig{Provider: "minio", Path: "results/../bucket"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service.OutputDestinations = []StorageIOConfig{s.dest}
+			err := service.ValidateOutputDestinations()
+			if s.returnError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.returnError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithOutputOverride(t *testing.T) {
+	service := &Service{
+		Name:               "test",
+		Image:              "busybox",
+		Script:             "echo hi",
+		Output:             []StorageIOConfig{{Provider: "minio.default", Path: "bucket/out", Suffix: []string{".txt"}}},
+		OutputDestinations: []StorageIOConfig{{Provider: "minio", Path: "results"}},
+		Replicas:           ReplicaList{{Type: "oscar", ServiceName: "other"}},
+	}
+
+	if _, err := service.WithOutputOverride(StorageIOConfig{Provider: "minio.default", Path: "other/alice"}); err == nil {
+		t.Error("expecting error for a destination outside the allowed ones")
+	}
+	if _, err := service.WithOutputOverride(StorageIOConfig{Provider: "minio.other", Path: "results/alice"}); err == nil {
+		t.Error("expecting error for a destination in other provider")
+	}
+
+	overridden, err := service.WithOutputOverride(StorageIOConfig{Provider: "minio.default", Path: "results/alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := overridden.Output[0]
+	if out.Path != "results/alice" || len(out.Suffix) != 1 || overridden.Replicas != nil || overridden.OutputOverride == nil {
+		t.Errorf("unexpected overridden service: %+v", overridden)
+	}
+	if service.Output[0].Path != "bucket/out" || service.Replicas == nil {
+		t.Error("the original service has been modified")
+	}
+
+	fdl, err := overridden.GetOutputConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fdl, "results/alice") || strings.Contains(fdl, "echo hi") {
+		t.Errorf("unexpected output config:\n%s", fdl)
+	}
+
+	// The FDL of the pods is projected from their annotation
+	podSpec, err := overridden.ToPodSpec(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, volume := range podSpec.Volumes {
+		if volume.Name != ConfigVolumeName {
+			continue
+		}
+		if volume.Projected == nil || len(volume.Projected.Sources) != 2 || volume.Projected.Sources[1].DownwardAPI == nil {
+			t.Fatalf("unexpected config volume: %+v", volume)
+		}
+		if path := volume.Projected.Sources[1].DownwardAPI.Items[0].Path; path != FDLFileName {
+			t.Errorf("expecting the FDL in %s, got %s", FDLFileName, path)
+		}
+		return
+	}
+	t.Error("the config volume has not been found")
+}
@@ -0,0 +1,115 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "fmt"
+
+// PlacementPolicy defines the tiers (node pools of the cluster or replicas) where the service's jobs can be placed.
+// Jobs are placed in the tier closest to the object that triggered them (data gravity)
+type PlacementPolicy struct {
+	// Tiers list of tiers ordered by preference. The first one is the default for
+	// events that are not related to the storage of any tier
+	Tiers []PlacementTier `json:"tiers"`
+	// Fallback try the rest of tiers (in order) if the job can't be placed in the selected one
+	// Optional. (default: false)
+	Fallback bool `json:"fallback"`
+}
+
+// PlacementTier tier where the service's jobs can be placed
+type PlacementTier struct {
+	// Name identifier of the tier (e.g. "edge" or "cloud")
+	Name string `json:"name"`
+	// NodeSelector labels of the tier's node pool in the current cluster
+	// Optional
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+	// ClusterID identifier of the replica (of type "oscar") to delegate the jobs placed in the tier
+	// Optional
+	ClusterID string `json:"cluster_id,omitempty"`
+	// Storage paths ("bucket[/folder]") whose data is stored close to the tier
+	// Optional
+	Storage []string `json:"storage,omitempty"`
+}
+
+// IsRemote checks if the jobs placed in the tier are delegated to a replica
+func (tier PlacementTier) IsRemote() bool {
+	return tier.ClusterID != ""
+}
+
+// MatchesObject checks if an object (bucket and key) is stored close to the tier
+func (tier PlacementTier) MatchesObject(bucket string, key string) bool {
+	for _, path := range tier.Storage {
+		if (StorageIOConfig{Path: path}).MatchesObject(bucket, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectTiers returns the tiers where a job triggered by the provided object must be tried to be placed, in order
+func (policy PlacementPolicy) SelectTiers(bucket string, key string) []PlacementTier {
+	selected := -1
+	if bucket != "" {
+		for i, tier := range policy.Tiers {
+			if tier.MatchesObject(bucket, key) {
+				selected = i
+				break
+			}
+		}
+	}
+	if selected == -1 {
+		selected = 0
+	}
+
+	if len(policy.Tiers) == 0 {
+		return nil
+	}
+	if !policy.Fallback {
+		return []PlacementTier{policy.Tiers[selected]}
+	}
+
+	tiers := []PlacementTier{policy.Tiers[selected]}
+	for i, tier := range policy.Tiers {
+		if i != selected {
+			tiers = append(tiers, tier)
+		}
+	}
+	return tiers
+}
+
+// Validate checks that the tiers are properly defined for the service's replicas
+func (policy PlacementPolicy) Validate(replicas ReplicaList) error {
+	names := map[string]bool{}
+	for _, tier := range policy.Tiers {
+		if tier.Name == "" {
+			return fmt.Errorf("the placement tiers must have a name")
+		}
+		if names[tier.Name] {
+			return fmt.Errorf("the placement tier \"%s\" is duplicated", tier.Name)
+		}
+		names[tier.Name] = true
+
+		if tier.IsRemote() {
+			if len(tier.NodeSelector) > 0 {
+				return fmt.Errorf("the placement tier \"%s\" can't define both cluster_id and node_selector", tier.Name)
+			}
+			if len(replicas.FilterByCluster(tier.ClusterID)) == 0 {
+				return fmt.Errorf("the cluster_id \"%s\" of the placement tier \"%s\" is not defined in the service's replicas", tier.ClusterID, tier.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectTiers(t *testing.T) {
+	policy := PlacementPolicy{
+		Tiers: []PlacementTier{
+			{Name: "cloud", NodeSelector: map[string]string{"tier": "cloud"}},
+			{Name: "edge", NodeSelector: map[string]string{"tier": "edge"}, Storage: []string{"edge-data/sensors"}},
+			{Name: "remote", ClusterID: "remote", Storage: []string{"remote-data"}},
+		},
+	}
+
+	scenarios := []struct {
+		name     string
+		fallback bool
+		bucket   string
+		key      string
+		expected []string
+	}{
+		{"edge object", false, "edge-data", "sensors/file.csv", []string{"edge"}},
+		{"remote object", false, "remote-data", "file.csv", []string{"remote"}},
+		{"other object", false, "other", "file.csv", []string{"cloud"}},
+		{"non storage event", false, "", "", []string{"cloud"}},
+		{"edge object with fallback", true, "edge-data", "sensors/file.csv", []string{"edge", "cloud", "remote"}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			policy.Fallback = s.fallback
+			names := []string{}
+			for _, tier := range policy.SelectTiers(s.bucket, s.key) {
+				names = append(names, tier.Name)
+			}
+			if !reflect.DeepEqual(names, s.expected) {
+				t.Errorf("expected tiers %v, got %v", s.expected, names)
+			}
+		})
+	}
+}
+
+func TestValidatePlacement(t *testing.T) {
+	replicas := ReplicaList{{Type: "oscar", ClusterID: "remote"}}
+
+	scenarios := []struct {
+		name        string
+		tiers       []PlacementTier
+		returnError bool
+	}{
+		{"valid", []PlacementTier{{Name: "edge"}, {Name: "remote", ClusterID: "remote"}}, false},
+		{"without name", []PlacementTier{{NodeSelector: map[string]string{"tier": "edge"}}}, true},
+		{"duplicated", []PlacementTier{{Name: "edge"}, {Name: "edge"}}, true},
+		{"undefined replica", []PlacementTier{{Name: "remote", ClusterID: "other"}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := PlacementPolicy{Tiers: s.tiers}.Validate(replicas)
+			if s.returnError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.returnError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
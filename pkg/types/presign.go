@@ -0,0 +1,124 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// PresignExpiresParam query parameter of the presigned URLs with their expiry (Unix time)
+	PresignExpiresParam = "oscar_expires"
+	// PresignMaxSizeParam query parameter of the presigned URLs with the maximum size of the body (bytes)
+	PresignMaxSizeParam = "oscar_max_size"
+	// PresignContentTypeParam query parameter of the presigned URLs with the content type required for the body
+	PresignContentTypeParam = "oscar_content_type"
+	// PresignSHA256Param query parameter of the presigned URLs with the SHA-256 hash required for the body
+	PresignSHA256Param = "oscar_sha256"
+	// PresignSignatureParam query parameter of the presigned URLs with their signature
+	PresignSignatureParam = "oscar_signature"
+
+	// DefaultPresignExpiresIn default validity (in seconds) of the presigned URLs
+	DefaultPresignExpiresIn = 900
+	// MaxPresignExpiresIn maximum validity (in seconds) of the presigned URLs (7 days)
+	MaxPresignExpiresIn = 7 * 24 * 3600
+)
+
+// presignSHA256Regex hex-encoded SHA-256 hashes
+var presignSHA256Regex = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// PresignRequest body of the requests to generate a presigned URL to invoke a service synchronously, with the
+// optional constraints of the body of the invocation
+type PresignRequest struct {
+	// ExpiresIn validity of the URL in seconds (DefaultPresignExpiresIn if not set)
+	ExpiresIn int `json:"expires_in"`
+	// MaxSize maximum size of the body in bytes
+	MaxSize int64 `json:"max_size,omitempty"`
+	// ContentType content type required for the body
+	ContentType string `json:"content_type,omitempty"`
+	// SHA256 hex-encoded SHA-256 hash required for the body
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// PresignedURL presigned URL to invoke a service synchronously without credentials
+type PresignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Validate checks the validity and the constraints of the URL, setting the default validity if not set
+func (req *PresignRequest) Validate() error {
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = DefaultPresignExpiresIn
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > MaxPresignExpiresIn {
+		return fmt.Errorf("expires_in must be between 1 and %d seconds", MaxPresignExpiresIn)
+	}
+	if req.MaxSize < 0 {
+		return fmt.Errorf("max_size can't be negative")
+	}
+	if req.SHA256 != "" && !presignSHA256Regex.MatchString(req.SHA256) {
+		return fmt.Errorf("sha256 must be a hex-encoded (lowercase) SHA-256 hash")
+	}
+	return nil
+}
+
+// Query returns the query of the URL presigned by the service until expiresAt, with the constraints of the request
+func (req *PresignRequest) Query(service *Service, expiresAt time.Time) url.Values {
+	query := url.Values{}
+	query.Set(PresignExpiresParam, strconv.FormatInt(expiresAt.Unix(), 10))
+	if req.MaxSize > 0 {
+		query.Set(PresignMaxSizeParam, strconv.FormatInt(req.MaxSize, 10))
+	}
+	if req.ContentType != "" {
+		query.Set(PresignContentTypeParam, req.ContentType)
+	}
+	if req.SHA256 != "" {
+		query.Set(PresignSHA256Param, req.SHA256)
+	}
+	query.Set(PresignSignatureParam, service.SignInvocation(query))
+	return query
+}
+
+// SignInvocation returns the signature of the query of a presigned URL of the service (HMAC-SHA256 with the token of
+// the service, so the URLs are invalidated with the token)
+func (service *Service) SignInvocation(query url.Values) string {
+	mac := hmac.New(sha256.New, []byte(service.Token))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", service.Name, query.Get(PresignExpiresParam), query.Get(PresignMaxSizeParam),
+		query.Get(PresignContentTypeParam), query.Get(PresignSHA256Param))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckPresignedQuery checks the signature and the expiry of the query of a presigned URL of the service
+func (service *Service) CheckPresignedQuery(query url.Values, now time.Time) bool {
+	if service.Token == "" {
+		return false
+	}
+	expires, err := strconv.ParseInt(query.Get(PresignExpiresParam), 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return false
+	}
+	expected := service.SignInvocation(query)
+	return hmac.Equal([]byte(expected), []byte(query.Get(PresignSignatureParam)))
+}
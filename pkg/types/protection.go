@@ -0,0 +1,30 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// ConfirmHeader header confirming the update or deletion of a protected service, set to the name of the service
+const ConfirmHeader = "X-Oscar-Confirm"
+
+// ServiceUnlock unlock of a protected service, allowing a single update or deletion without the ConfirmHeader
+type ServiceUnlock struct {
+	Service string `json:"service"`
+	// UnlockedBy user that unlocked the service
+	UnlockedBy string    `json:"unlocked_by,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,124 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// OwnerAnnotation annotation of the jobs with the owner of their service, used to account its usage
+	OwnerAnnotation = "oscar_owner"
+
+	// QuotaScopeVO scope of the quotas limiting the usage of all the services of a VO
+	QuotaScopeVO = "vo"
+	// QuotaScopeUser scope of the quotas limiting the usage of all the services of a user (their owner)
+	QuotaScopeUser = "user"
+
+	// QuotaJobs name of the quota limiting the number of jobs
+	QuotaJobs = "jobs"
+	// QuotaCPUSeconds name of the quota limiting the CPU-seconds (requested CPUs x running time)
+	QuotaCPUSeconds = "cpu_seconds"
+	// QuotaMemorySeconds name of the quota limiting the memory-seconds (requested GiB x running time)
+	QuotaMemorySeconds = "memory_seconds"
+	// QuotaStorageBytes name of the quota limiting the size of the objects in the MinIO inputs and outputs
+	QuotaStorageBytes = "storage_bytes"
+)
+
+// Quota limits of the resources consumed by a VO or a user in the quotas period (0 for no limit)
+type Quota struct {
+	Jobs          int     `json:"jobs,omitempty"`
+	CPUSeconds    float64 `json:"cpu_seconds,omitempty"`
+	MemorySeconds float64 `json:"memory_seconds,omitempty"`
+	StorageBytes  int64   `json:"storage_bytes,omitempty"`
+}
+
+// ResourceUsage resources consumed by a VO or a user
+type ResourceUsage struct {
+	// Jobs number of jobs created in the period
+	Jobs int `json:"jobs"`
+	// CPUSeconds CPU time (requested CPUs x running time) consumed by the jobs in the period
+	CPUSeconds float64 `json:"cpu_seconds"`
+	// MemorySeconds memory (requested GiB x running time) consumed by the jobs in the period
+	MemorySeconds float64 `json:"memory_seconds"`
+	// StorageBytes size of the objects currently stored in the MinIO inputs and outputs of the services
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+// AccountUsage usage and quota of a VO or a user
+type AccountUsage struct {
+	Name string `json:"name"`
+	ResourceUsage
+	Quota *Quota `json:"quota,omitempty"`
+	// Exceeded quotas reached by the account, rejecting its new jobs
+	Exceeded []string `json:"exceeded,omitempty"`
+}
+
+// UsageSummary usage of the cluster's resources per VO and per user in the quotas period
+type UsageSummary struct {
+	From  time.Time      `json:"from"`
+	To    time.Time      `json:"to"`
+	VOs   []AccountUsage `json:"vos"`
+	Users []AccountUsage `json:"users"`
+}
+
+// Set sets the limit of the quota with the provided name
+func (quota *Quota) Set(name string, limit float64) error {
+	switch name {
+	case QuotaJobs:
+		quota.Jobs = int(limit)
+	case QuotaCPUSeconds:
+		quota.CPUSeconds = limit
+	case QuotaMemorySeconds:
+		quota.MemorySeconds = limit
+	case QuotaStorageBytes:
+		quota.StorageBytes = int64(limit)
+	default:
+		return fmt.Errorf("unknown quota \"%s\"", name)
+	}
+	return nil
+}
+
+// Exceeded returns the description of the limits of the quota reached by the usage
+func (quota *Quota) Exceeded(usage ResourceUsage) []string {
+	exceeded := []string{}
+	if quota == nil {
+		return exceeded
+	}
+	if quota.Jobs > 0 && usage.Jobs >= quota.Jobs {
+		exceeded = append(exceeded, fmt.Sprintf("%s (%d/%d)", QuotaJobs, usage.Jobs, quota.Jobs))
+	}
+	if quota.CPUSeconds > 0 && usage.CPUSeconds >= quota.CPUSeconds {
+		exceeded = append(exceeded, fmt.Sprintf("%s (%.0f/%.0f)", QuotaCPUSeconds, usage.CPUSeconds, quota.CPUSeconds))
+	}
+	if quota.MemorySeconds > 0 && usage.MemorySeconds >= quota.MemorySeconds {
+		exceeded = append(exceeded, fmt.Sprintf("%s (%.0f/%.0f)", QuotaMemorySeconds, usage.MemorySeconds, quota.MemorySeconds))
+	}
+	if quota.StorageBytes > 0 && usage.StorageBytes >= quota.StorageBytes {
+		exceeded = append(exceeded, fmt.Sprintf("%s (%d/%d)", QuotaStorageBytes, usage.StorageBytes, quota.StorageBytes))
+	}
+	return exceeded
+}
+
+// Add adds the resources consumed by a job to the usage
+func (usage *ResourceUsage) Add(other ResourceUsage) {
+	usage.Jobs += other.Jobs
+	usage.CPUSeconds += other.CPUSeconds
+	usage.MemorySeconds += other.MemorySeconds
+	usage.StorageBytes += other.StorageBytes
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "strings"
+
+const (
+	// RoleAdmin manages everything, including the services of other owners
+	RoleAdmin = "admin"
+	// RoleOperator creates services and manages its own services, their jobs and logs
+	RoleOperator = "operator"
+	// RoleInvoker runs the services (e.g. replaying or simulating events and retrying jobs)
+	RoleInvoker = "invoker"
+	// RoleViewer lists and reads the services, jobs and logs
+	RoleViewer = "viewer"
+
+	// RoleKey key of the role of the authenticated user in the context of the requests
+	RoleKey = "oscarRole"
+	// UserGroupsKey key of the groups of the authenticated user (OIDC) in the context of the requests
+	UserGroupsKey = "oscarUserGroups"
+
+	// RoleScopeUser scope of the RBAC_ROLES entries mapping a user (basic auth username, OIDC subject or user of a
+	// client certificate)
+	RoleScopeUser = "user"
+	// RoleScopeGroup scope of the RBAC_ROLES entries mapping an OIDC group
+	RoleScopeGroup = "group"
+)
+
+// roleLevels privileges of the roles, each one including the privileges of the lower ones
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleInvoker:  2,
+	RoleOperator: 3,
+	RoleAdmin:    4,
+}
+
+// IsValidRole checks if a role exists
+func IsValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// HasRole checks if a role includes the privileges of the required one
+func HasRole(role string, required string) bool {
+	return IsValidRole(role) && roleLevels[role] >= roleLevels[required]
+}
+
+// GetUserRole returns the highest role mapped by RBACRoles to a user or its groups, RBACDefaultRole if none is
+// mapped. The admin of the cluster (basic auth) is always an admin
+func (cfg *Config) GetUserRole(user string, groups []string) string {
+	if user == cfg.Username {
+		return RoleAdmin
+	}
+
+	role := ""
+	for _, entry := range cfg.RBACRoles {
+		split := strings.SplitN(entry, "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		mapped := strings.TrimSpace(split[1])
+		scope, name, ok := strings.Cut(strings.TrimSpace(split[0]), ":")
+		if !ok || !IsValidRole(mapped) {
+			continue
+		}
+
+		matches := false
+		switch scope {
+		case RoleScopeUser:
+			matches = name == user
+		case RoleScopeGroup:
+			for _, g := range groups {
+				matches = matches || name == g
+			}
+		}
+		if matches && (role == "" || roleLevels[mapped] > roleLevels[role]) {
+			role = mapped
+		}
+	}
+
+	if role == "" {
+		return cfg.RBACDefaultRole
+	}
+	return role
+}
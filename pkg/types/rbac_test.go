@@ -0,0 +1,61 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "testing"
+
+func TestGetUserRole(t *testing.T) {
+	cfg := &Config{
+		Username:        "oscar",
+		RBACDefaultRole: RoleViewer,
+		RBACRoles: []string{
+			"user:alice=operator",
+			"group:vo.example.eu=invoker",
+			"group:admins=admin",
+			"user:bob=unknown",
+			"invalid",
+		},
+	}
+
+	scenarios := []struct {
+		name     string
+		user     string
+		groups   []string
+		expected string
+	}{
+		{"cluster admin", "oscar", nil, RoleAdmin},
+		{"mapped user", "alice", nil, RoleOperator},
+		{"mapped group", "dave", []string{"vo.example.eu"}, RoleInvoker},
+		{"highest role", "alice", []string{"vo.example.eu", "admins"}, RoleAdmin},
+		{"invalid role", "bob", nil, RoleViewer},
+		{"default role", "dave", []string{"other"}, RoleViewer},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if role := cfg.GetUserRole(s.user, s.groups); role != s.expected {
+				t.Errorf("expecting role \"%s\", got \"%s\"", s.expected, role)
+			}
+		})
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	if !HasRole(RoleOperator, RoleInvoker) || HasRole(RoleInvoker, RoleOperator) || HasRole("unknown", RoleViewer) {
+		t.Error("unexpected privileges of the roles")
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+const (
+	// ReconcileOK the resource matches the service definition
+	ReconcileOK = "ok"
+	// ReconcileMissing the resource is missing (only reported in dry-run mode)
+	ReconcileMissing = "missing"
+	// ReconcileFixed the missing resource has been restored
+	ReconcileFixed = "fixed"
+	// ReconcileFailed the resource could not be checked or restored
+	ReconcileFailed = "failed"
+)
+
+// ReconcileReport details the drift between the stored services and their MinIO webhooks and bucket notifications
+type ReconcileReport struct {
+	// DryRun the drift has only been reported, not fixed
+	DryRun bool `json:"dry_run"`
+	// MinIORestarted the MinIO server has been restarted to apply the restored webhooks
+	MinIORestarted bool `json:"minio_restarted"`
+	// Message error restarting the MinIO server (if any)
+	Message string `json:"message,omitempty"`
+	// Services reconciliation of each service
+	Services []ServiceReconciliation `json:"services"`
+}
+
+// ServiceReconciliation details the reconciliation of the webhook and the bucket notifications of a service
+type ServiceReconciliation struct {
+	Service string `json:"service"`
+	// Webhook the service's webhook in MinIO
+	Webhook ReconcileItem `json:"webhook"`
+	// Notifications the bucket notifications of the enabled MinIO inputs
+	Notifications []ReconcileItem `json:"notifications"`
+}
+
+// ReconcileItem represents the reconciliation of a single resource
+type ReconcileItem struct {
+	// Resource name of the webhook or path of the input
+	Resource string `json:"resource"`
+	// Status one of "ok", "missing", "fixed" or "failed"
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
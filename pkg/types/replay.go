@@ -0,0 +1,58 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// ReplayRequest defines the objects of a service's inputs to be replayed
+type ReplayRequest struct {
+	// Prefix only replay objects whose key (relative to the input folder) starts with it
+	// Optional.
+	Prefix string `json:"prefix,omitempty"`
+	// From only replay objects modified after this time (RFC 3339)
+	// Optional.
+	From *time.Time `json:"from,omitempty"`
+	// To only replay objects modified before this time (RFC 3339)
+	// Optional.
+	To *time.Time `json:"to,omitempty"`
+	// Limit maximum number of objects to replay (1000 at most)
+	// Optional. (default: 1000)
+	Limit int `json:"limit,omitempty"`
+}
+
+// ReplayResult summary of a replay request
+type ReplayResult struct {
+	// Events number of events re-enqueued
+	Events int `json:"events"`
+	// Skipped number of objects not satisfying the input filters
+	Skipped int `json:"skipped"`
+	// Jobs names of the created jobs (events aggregated in batches or waiting for a file set are not included)
+	Jobs []string `json:"jobs"`
+	// Truncated true if the limit has been reached before replaying all the objects
+	Truncated bool `json:"truncated"`
+}
+
+// InTimeRange checks if the provided time is inside the request's time range
+func (req ReplayRequest) InTimeRange(t time.Time) bool {
+	if req.From != nil && t.Before(*req.From) {
+		return false
+	}
+	if req.To != nil && t.After(*req.To) {
+		return false
+	}
+	return true
+}
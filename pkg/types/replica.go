@@ -16,6 +16,8 @@ limitations under the License.
 
 package types
 
+import "strings"
+
 // Replica struct to define service's replicas in other clusters or endpoints
 type Replica struct {
 	// Type of the replica to re-send events (can be "oscar" or "endpoint")
@@ -59,3 +61,14 @@ func (rl ReplicaList) Swap(i, j int) {
 func (rl ReplicaList) Less(i, j int) bool {
 	return rl[i].Priority < rl[j].Priority
 }
+
+// FilterByCluster returns the replicas of type "oscar" pointing to the provided cluster
+func (rl ReplicaList) FilterByCluster(clusterID string) ReplicaList {
+	filtered := ReplicaList{}
+	for _, replica := range rl {
+		if strings.ToLower(replica.Type) == "oscar" && replica.ClusterID == clusterID {
+			filtered = append(filtered, replica)
+		}
+	}
+	return filtered
+}
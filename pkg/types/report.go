@@ -0,0 +1,70 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// UsageReport usage of the cluster's resources per VO in a time period
+type UsageReport struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	VOs  []VOUsage `json:"vos"`
+}
+
+// VOUsage usage of the services of a VO
+type VOUsage struct {
+	// VO name of the VO ("" for services without VO)
+	VO string `json:"vo"`
+	// Executions number of jobs created in the period
+	Executions int `json:"executions"`
+	// CPUHours CPU time (requested CPUs x running time) consumed by the jobs in the period
+	CPUHours float64 `json:"cpu_hours"`
+	// StorageBytes size of the objects stored in the services' MinIO inputs and outputs
+	StorageBytes int64 `json:"storage_bytes"`
+	// AvoidedEmissions emissions (in gCO2eq) avoided by deferring the jobs to low-carbon windows
+	AvoidedEmissions float64 `json:"avoided_emissions"`
+	// Services usage of each service of the VO
+	Services []ServiceUsage `json:"services"`
+}
+
+// ServiceUsage usage of a service
+type ServiceUsage struct {
+	Name             string  `json:"name"`
+	Executions       int     `json:"executions"`
+	CPUHours         float64 `json:"cpu_hours"`
+	StorageBytes     int64   `json:"storage_bytes"`
+	AvoidedEmissions float64 `json:"avoided_emissions"`
+}
+
+// Add adds the usage of a service to the VO usage
+func (usage *VOUsage) Add(serviceUsage ServiceUsage) {
+	usage.Executions += serviceUsage.Executions
+	usage.CPUHours += serviceUsage.CPUHours
+	usage.StorageBytes += serviceUsage.StorageBytes
+	usage.AvoidedEmissions += serviceUsage.AvoidedEmissions
+	usage.Services = append(usage.Services, serviceUsage)
+}
+
+// GetVO returns the usage of the provided VO (nil if not present in the report)
+func (report *UsageReport) GetVO(vo string) *VOUsage {
+	for i := range report.VOs {
+		if report.VOs[i].VO == vo {
+			return &report.VOs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// GPUResourceName resource of the GPUs requested by the services
+const GPUResourceName v1.ResourceName = "nvidia.com/gpu"
+
+// ValidateExtendedResources checks the GPUs and the extended resources (e.g. "nvidia.com/mig-1g.5gb") of the
+// service: fully-qualified names outside the kubernetes.io domain and positive integer quantities
+func (service *Service) ValidateExtendedResources() error {
+	if service.GPU < 0 {
+		return fmt.Errorf("the gpu can not be negative")
+	}
+	names := make([]string, 0, len(service.ExtendedResources))
+	for name := range service.ExtendedResources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if errs := validation.IsQualifiedName(name); len(errs) > 0 || !strings.Contains(name, "/") {
+			return fmt.Errorf("the extended resource \"%s\" is not valid: it must be a fully-qualified resource name (e.g. \"nvidia.com/gpu\")", name)
+		}
+		if domain, _, _ := strings.Cut(name, "/"); domain == "kubernetes.io" || strings.HasSuffix(domain, ".kubernetes.io") {
+			return fmt.Errorf("the extended resource \"%s\" is not valid: the kubernetes.io domain is reserved", name)
+		}
+		if v1.ResourceName(name) == GPUResourceName && service.GPU > 0 {
+			return fmt.Errorf("the extended resource \"%s\" can not be combined with the gpu field", name)
+		}
+		quantity, err := resource.ParseQuantity(service.ExtendedResources[name])
+		if err != nil {
+			return fmt.Errorf("the quantity of the extended resource \"%s\" is not valid: %v", name, err)
+		}
+		if quantity.Sign() <= 0 || quantity.MilliValue()%1000 != 0 {
+			return fmt.Errorf("the quantity of the extended resource \"%s\" must be a positive integer", name)
+		}
+	}
+	return nil
+}
+
+// GetExtendedResourceLimits returns the limits of the GPUs and the extended resources of the service (the invalid
+// quantities are ignored)
+func (service *Service) GetExtendedResourceLimits() v1.ResourceList {
+	limits := v1.ResourceList{}
+	gpu := service.GPU
+	if gpu == 0 && service.EnableGPU {
+		gpu = 1
+	}
+	if gpu > 0 {
+		limits[GPUResourceName] = *resource.NewQuantity(int64(gpu), resource.DecimalSI)
+	}
+	for name, value := range service.ExtendedResources {
+		if quantity, err := resource.ParseQuantity(value); err == nil {
+			limits[v1.ResourceName(name)] = quantity
+		}
+	}
+	return limits
+}
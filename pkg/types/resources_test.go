@@ -0,0 +1,81 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestValidateExtendedResources(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		service     Service
+		returnError bool
+	}{
+		{"empty", Service{}, false},
+		{"gpu and MIG profile", Service{GPU: 2, ExtendedResources: map[string]string{"nvidia.com/mig-1g.5gb": "1"}}, false},
+		{"negative gpu", Service{GPU: -1}, true},
+		{"unqualified name", Service{ExtendedResources: map[string]string{"gpu": "1"}}, true},
+		{"reserved domain", Service{ExtendedResources: map[string]string{"kubernetes.io/gpu": "1"}}, true},
+		{"duplicated gpu", Service{GPU: 1, ExtendedResources: map[string]string{"nvidia.com/gpu": "1"}}, true},
+		{"invalid quantity", Service{ExtendedResources: map[string]string{"nvidia.com/gpu": "one"}}, true},
+		{"fractional quantity", Service{ExtendedResources: map[string]string{"nvidia.com/gpu": "500m"}}, true},
+		{"zero quantity", Service{ExtendedResources: map[string]string{"nvidia.com/gpu": "0"}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.service.ValidateExtendedResources(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestToPodSpecExtendedResources(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		service  Service
+		expected v1.ResourceList
+	}{
+		{"enable_gpu", Service{EnableGPU: true}, v1.ResourceList{GPUResourceName: resource.MustParse("1")}},
+		{"gpu", Service{EnableGPU: true, GPU: 2}, v1.ResourceList{GPUResourceName: resource.MustParse("2")}},
+		{"MIG profile", Service{ExtendedResources: map[string]string{"nvidia.com/mig-1g.5gb": "1"}}, v1.ResourceList{"nvidia.com/mig-1g.5gb": resource.MustParse("1")}},
+		{"none", Service{}, v1.ResourceList{}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			podSpec, err := s.service.ToPodSpec(&Config{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			limits := podSpec.Containers[0].Resources.Limits
+			if len(limits) != len(s.expected) {
+				t.Errorf("expecting limits %v, got %v", s.expected, limits)
+			}
+			for name, quantity := range s.expected {
+				if got, ok := limits[name]; !ok || got.Cmp(quantity) != 0 {
+					t.Errorf("expecting %s=%s, got %s", name, quantity.String(), got.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// RevisionLabel label key with the revision of the service definition, set on the configMaps storing
+// the revisions and on the jobs created by each revision
+const RevisionLabel = "oscar_revision"
+
+// ServiceRevision revision of a service definition
+type ServiceRevision struct {
+	// Revision number of the revision
+	Revision int `json:"revision"`
+	// CreatedAt time when the revision was created
+	CreatedAt time.Time `json:"created_at"`
+	// Service definition of the service in the revision
+	Service *Service `json:"service"`
+}
+
+// RevisionConfigMapName returns the name of the configMap storing a revision of a service definition
+func RevisionConfigMapName(serviceName string, revision int) string {
+	return fmt.Sprintf("%s.revision-%d", serviceName, revision)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	// OutputManifestName name of the manifest written by the scripts in their output folder to tag the output files
+	OutputManifestName = "oscar-manifest.json"
+
+	// OutputRoutingLabel label of the jobs whose outputs are staged to be routed by the tags of their files
+	OutputRoutingLabel = "oscar_output_routing"
+	// OutputRoutingPending value of the OutputRoutingLabel of the jobs whose outputs have not been routed yet
+	OutputRoutingPending = "pending"
+	// OutputRoutingDone value of the OutputRoutingLabel of the jobs whose outputs have been routed
+	OutputRoutingDone = "done"
+
+	// OutputRoutingPrefix prefix of the upload staging bucket where the outputs of the jobs are staged
+	// ("routing/<SERVICE>/<JOB>/")
+	OutputRoutingPrefix = "routing"
+)
+
+// OutputRoute rule routing the output files with a tag to the outputs of the service with a storage provider
+type OutputRoute struct {
+	// Tag tag of the files in the output manifest
+	Tag string `json:"tag"`
+	// Output storage provider of the outputs receiving the files (e.g. "s3.archive")
+	Output string `json:"output"`
+}
+
+// OutputManifest manifest with the tags of the output files, written by the scripts in their output folder
+type OutputManifest struct {
+	Files []TaggedFile `json:"files"`
+}
+
+// TaggedFile output file (path relative to the output folder) and its tags
+type TaggedFile struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// GetTags returns the tags of the output files, indexed by name
+func (manifest *OutputManifest) GetTags() map[string][]string {
+	tags := map[string][]string{}
+	for _, file := range manifest.Files {
+		name := strings.TrimPrefix(path.Clean("/"+file.Name), "/")
+		tags[name] = append(tags[name], file.Tags...)
+	}
+	return tags
+}
+
+// HasOutputRoutes returns true if the outputs of the service are routed by the tags of the files
+func (service *Service) HasOutputRoutes() bool {
+	return len(service.OutputRoutes) > 0
+}
+
+// ValidateOutputRoutes checks that the routes have a tag and route the files to the MinIO or S3 outputs of the service
+func (service *Service) ValidateOutputRoutes() error {
+	for _, route := range service.OutputRoutes {
+		if strings.TrimSpace(route.Tag) == "" {
+			return fmt.Errorf("the output routes must have a tag")
+		}
+		found := false
+		for _, out := range service.Output {
+			if out.Provider == route.Output {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("the output route of tag \"%s\" must reference the storage provider of an output of the service", route.Tag)
+		}
+		if provName, _ := (StorageIOConfig{Provider: route.Output}).GetProvider(); provName != MinIOName && provName != S3Name {
+			return fmt.Errorf("the output route of tag \"%s\" must reference a MinIO or S3 output", route.Tag)
+		}
+	}
+	return nil
+}
+
+// WithOutputStaging returns a copy of the service whose outputs are stored in the staging path of a job, in the
+// provided bucket of the default MinIO provider, to be routed after the job finishes
+func (service *Service) WithOutputStaging(bucket string, jobName string) *Service {
+	dest := StorageIOConfig{
+		Provider: MinIOName + ProviderSeparator + DefaultProvider,
+		Path:     path.Join(bucket, OutputRoutingPrefix, service.Name, jobName),
+	}
+	staged := *service
+	staged.Output = []StorageIOConfig{dest}
+	staged.Replicas = nil
+	staged.OutputOverride = &dest
+	return &staged
+}
+
+// GetRoutedOutputs returns the outputs receiving an output file with the provided tags: the outputs of the routes
+// matching any of the tags or, if none matches, the outputs not referenced by any route. The suffix and prefix
+// filters of the outputs are applied to the name of the file
+func (service *Service) GetRoutedOutputs(name string, tags []string) []StorageIOConfig {
+	routed := map[string]bool{}
+	referenced := map[string]bool{}
+	for _, route := range service.OutputRoutes {
+		referenced[route.Output] = true
+		for _, tag := range tags {
+			if tag == route.Tag {
+				routed[route.Output] = true
+			}
+		}
+	}
+
+	outputs := []StorageIOConfig{}
+	for _, out := range service.Output {
+		if len(routed) > 0 && !routed[out.Provider] || len(routed) == 0 && referenced[out.Provider] {
+			continue
+		}
+		if out.MatchesFileName(name) {
+			outputs = append(outputs, out)
+		}
+	}
+	return outputs
+}
+
+// MatchesFileName checks if the base name of a file satisfies the suffix and prefix filters of the output
+func (out StorageIOConfig) MatchesFileName(name string) bool {
+	base := path.Base(name)
+	matches := func(values []string, match func(string, string) bool) bool {
+		if len(values) == 0 {
+			return true
+		}
+		for _, value := range values {
+			if match(base, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return matches(out.Suffix, strings.HasSuffix) && matches(out.Prefix, strings.HasPrefix)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+)
+
+func TestValidateOutputRoutes(t *testing.T) {
+	output := []StorageIOConfig{
+		{Provider: "minio.default", Path: "out"},
+		{Provider: "onedata.space", Path: "out"},
+	}
+
+	scenarios := []struct {
+		name        string
+		routes      []OutputRoute
+		expectError bool
+	}{
+		{"no routes", nil, false},
+		{"valid", []OutputRoute{{Tag: "report", Output: "minio.default"}}, false},
+		{"no tag", []OutputRoute{{Tag: " ", Output: "minio.default"}}, true},
+		{"undeclared output", []OutputRoute{{Tag: "report", Output: "s3.archive"}}, true},
+		{"not S3 compatible", []OutputRoute{{Tag: "report", Output: "onedata.space"}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{Output: output, OutputRoutes: s.routes}
+			err := service.ValidateOutputRoutes()
+			if s.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithOutputStaging(t *testing.T) {
+	service := &Service{
+		Name:     "test",
+		Output:   []StorageIOConfig{{Provider: "s3.archive", Path: "out"}},
+		Replicas: ReplicaList{{Type: "oscar"}},
+	}
+
+	staged := service.WithOutputStaging("oscar-uploads", "job")
+	if len(staged.Output) != 1 || staged.Output[0].Path != "oscar-uploads/routing/test/job" || staged.Output[0].Provider != "minio.default" {
+		t.Errorf("unexpected staged outputs: %v", staged.Output)
+	}
+	if staged.OutputOverride == nil || staged.Replicas != nil {
+		t.Errorf("expected the staged service to override its outputs and have no replicas")
+	}
+	if service.Output[0].Provider != "s3.archive" {
+		t.Errorf("the original service must not be modified")
+	}
+}
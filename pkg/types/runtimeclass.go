@@ -0,0 +1,57 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateRuntimeClass checks the name of the RuntimeClass of the service
+func (service *Service) ValidateRuntimeClass() error {
+	if service.RuntimeClass == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(service.RuntimeClass); len(errs) > 0 {
+		return fmt.Errorf("the runtime_class \"%s\" is not valid: %s", service.RuntimeClass, errs[0])
+	}
+	return nil
+}
+
+// IsRuntimeClassAllowed checks if the RuntimeClass of the service is the cluster's default (DEFAULT_RUNTIME_CLASS)
+// or is in its allow-list (RUNTIME_CLASSES)
+func (service *Service) IsRuntimeClassAllowed(cfg *Config) bool {
+	if service.RuntimeClass == "" || service.RuntimeClass == cfg.DefaultRuntimeClass {
+		return true
+	}
+	for _, runtimeClass := range cfg.RuntimeClasses {
+		if service.RuntimeClass == runtimeClass {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRuntimeClass sets the RuntimeClass of the service (if defined) in a pod spec
+func (service *Service) SetRuntimeClass(podSpec *v1.PodSpec) {
+	if service.RuntimeClass != "" {
+		runtimeClass := service.RuntimeClass
+		podSpec.RuntimeClassName = &runtimeClass
+	}
+}
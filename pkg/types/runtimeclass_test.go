@@ -0,0 +1,80 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestValidateRuntimeClass(t *testing.T) {
+	scenarios := []struct {
+		runtimeClass string
+		returnError  bool
+	}{
+		{"", false},
+		{"gvisor", false},
+		{"kata-qemu", false},
+		{"Kata", true},
+		{"kata_qemu", true},
+	}
+
+	for _, s := range scenarios {
+		service := Service{RuntimeClass: s.runtimeClass}
+		if err := service.ValidateRuntimeClass(); (err != nil) != s.returnError {
+			t.Errorf("runtime class \"%s\": expecting error %v, got %v", s.runtimeClass, s.returnError, err)
+		}
+	}
+}
+
+func TestIsRuntimeClassAllowed(t *testing.T) {
+	cfg := &Config{DefaultRuntimeClass: "gvisor", RuntimeClasses: []string{"kata"}}
+	scenarios := []struct {
+		runtimeClass string
+		allowed      bool
+	}{
+		{"", true},
+		{"gvisor", true},
+		{"kata", true},
+		{"runc", false},
+	}
+
+	for _, s := range scenarios {
+		service := Service{RuntimeClass: s.runtimeClass}
+		if allowed := service.IsRuntimeClassAllowed(cfg); allowed != s.allowed {
+			t.Errorf("runtime class \"%s\": expecting allowed %v, got %v", s.runtimeClass, s.allowed, allowed)
+		}
+	}
+
+	if (&Service{RuntimeClass: "kata"}).IsRuntimeClassAllowed(&Config{}) {
+		t.Error("expecting the runtime class not to be allowed without an allow-list")
+	}
+}
+
+func TestSetRuntimeClass(t *testing.T) {
+	podSpec := &v1.PodSpec{}
+	(&Service{}).SetRuntimeClass(podSpec)
+	if podSpec.RuntimeClassName != nil {
+		t.Errorf("expecting no runtime class, got %s", *podSpec.RuntimeClassName)
+	}
+
+	(&Service{RuntimeClass: "gvisor"}).SetRuntimeClass(podSpec)
+	if podSpec.RuntimeClassName == nil || *podSpec.RuntimeClassName != "gvisor" {
+		t.Errorf("expecting runtime class gvisor, got %v", podSpec.RuntimeClassName)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateScheduling checks the node selector and the tolerations of the service (the affinity rules are checked
+// by Kubernetes when the service is deployed)
+func (service *Service) ValidateScheduling() error {
+	keys := make([]string, 0, len(service.NodeSelector))
+	for key := range service.NodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("the node_selector label \"%s\" is not valid: %s", key, errs[0])
+		}
+		if errs := validation.IsValidLabelValue(service.NodeSelector[key]); len(errs) > 0 {
+			return fmt.Errorf("the node_selector value of the label \"%s\" is not valid: %s", key, errs[0])
+		}
+	}
+
+	for i, toleration := range service.Tolerations {
+		if toleration.Key != "" {
+			if errs := validation.IsQualifiedName(toleration.Key); len(errs) > 0 {
+				return fmt.Errorf("the key of the toleration %d is not valid: %s", i, errs[0])
+			}
+		}
+		switch toleration.Operator {
+		case "", v1.TolerationOpEqual:
+			if toleration.Key == "" {
+				return fmt.Errorf("the toleration %d requires a key with the operator \"%s\"", i, v1.TolerationOpEqual)
+			}
+		case v1.TolerationOpExists:
+			if toleration.Value != "" {
+				return fmt.Errorf("the toleration %d can not have a value with the operator \"%s\"", i, v1.TolerationOpExists)
+			}
+		default:
+			return fmt.Errorf("the operator of the toleration %d is not valid (valid operators are \"%s\" and \"%s\")", i, v1.TolerationOpEqual, v1.TolerationOpExists)
+		}
+		switch toleration.Effect {
+		case "", v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("the effect of the toleration %d is not valid (valid effects are \"%s\", \"%s\" and \"%s\")", i, v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute)
+		}
+		if toleration.TolerationSeconds != nil && toleration.Effect != v1.TaintEffectNoExecute {
+			return fmt.Errorf("the toleration %d can only set its tolerationSeconds with the effect \"%s\"", i, v1.TaintEffectNoExecute)
+		}
+	}
+	return nil
+}
+
+// SetScheduling sets the node selector, affinity rules and tolerations of the service in a pod spec
+func (service *Service) SetScheduling(podSpec *v1.PodSpec) {
+	if len(service.NodeSelector) > 0 {
+		podSpec.NodeSelector = map[string]string{}
+		for key, value := range service.NodeSelector {
+			podSpec.NodeSelector[key] = value
+		}
+	}
+	if service.Affinity != nil {
+		podSpec.Affinity = service.Affinity.DeepCopy()
+	}
+	for _, toleration := range service.Tolerations {
+		podSpec.Tolerations = append(podSpec.Tolerations, *toleration.DeepCopy())
+	}
+}
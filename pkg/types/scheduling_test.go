@@ -0,0 +1,107 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestValidateScheduling(t *testing.T) {
+	seconds := int64(60)
+	scenarios := []struct {
+		name        string
+		service     Service
+		returnError bool
+	}{
+		{"empty", Service{}, false},
+		{"valid", Service{
+			NodeSelector: map[string]string{"kubernetes.io/arch": "arm64"},
+			Tolerations: []v1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+				{Key: "edge", Value: "true", Effect: v1.TaintEffectNoExecute, TolerationSeconds: &seconds},
+			},
+		}, false},
+		{"invalid label", Service{NodeSelector: map[string]string{"invalid label": "a"}}, true},
+		{"invalid label value", Service{NodeSelector: map[string]string{"arch": "arm 64"}}, true},
+		{"invalid operator", Service{Tolerations: []v1.Toleration{{Key: "a", Operator: "In"}}}, true},
+		{"equal without key", Service{Tolerations: []v1.Toleration{{Value: "a"}}}, true},
+		{"exists with value", Service{Tolerations: []v1.Toleration{{Key: "a", Operator: v1.TolerationOpExists, Value: "b"}}}, true},
+		{"invalid effect", Service{Tolerations: []v1.Toleration{{Key: "a", Effect: "Never"}}}, true},
+		{"seconds without NoExecute", Service{Tolerations: []v1.Toleration{{Key: "a", TolerationSeconds: &seconds}}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.service.ValidateScheduling(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestToPodSpecScheduling(t *testing.T) {
+	fdl := `
+name: test
+node_selector:
+  kubernetes.io/arch: arm64
+affinity:
+  nodeAffinity:
+    requiredDuringSchedulingIgnoredDuringExecution:
+      nodeSelectorTerms:
+      - matchExpressions:
+        - key: node-role
+          operator: In
+          values: [edge]
+tolerations:
+- key: nvidia.com/gpu
+  operator: Exists
+  effect: NoSchedule
+`
+	service := Service{}
+	if err := yaml.Unmarshal([]byte(fdl), &service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	podSpec, err := service.ToPodSpec(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(podSpec.NodeSelector, map[string]string{"kubernetes.io/arch": "arm64"}) {
+		t.Errorf("unexpected node selector: %v", podSpec.NodeSelector)
+	}
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil {
+		t.Fatal("expecting the node affinity")
+	}
+	terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || terms[0].MatchExpressions[0].Key != "node-role" || terms[0].MatchExpressions[0].Values[0] != "edge" {
+		t.Errorf("unexpected node affinity: %v", terms)
+	}
+	expected := []v1.Toleration{{Key: "nvidia.com/gpu", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}}
+	if !reflect.DeepEqual(podSpec.Tolerations, expected) {
+		t.Errorf("expecting tolerations %v, got %v", expected, podSpec.Tolerations)
+	}
+
+	// The pod spec does not share the maps of the service
+	podSpec.NodeSelector["other"] = "value"
+	if _, ok := service.NodeSelector["other"]; ok {
+		t.Error("the node selector of the service has been modified")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ServiceSchema JSON Schemas of the body of the service's synchronous invocations and of their responses
+type ServiceSchema struct {
+	// Input JSON Schema of the request body, checked on each synchronous invocation
+	// Optional
+	Input map[string]interface{} `json:"input,omitempty"`
+
+	// Output JSON Schema of the response body, only published for the clients of the service
+	// Optional
+	Output map[string]interface{} `json:"output,omitempty"`
+}
+
+// ValidateSchema checks that the input and output schemas of the service are valid JSON Schemas
+func (service *Service) ValidateSchema() error {
+	if service.Schema == nil {
+		return nil
+	}
+	if _, err := compileSchema(service.Schema.Input); err != nil {
+		return fmt.Errorf("the input schema is not valid: %v", err)
+	}
+	if _, err := compileSchema(service.Schema.Output); err != nil {
+		return fmt.Errorf("the output schema is not valid: %v", err)
+	}
+	return nil
+}
+
+// ValidateInput checks a request body against the input schema, returning the description of the issues found
+// (prefixed by the path of the invalid field). No issues are returned if the input schema is not defined
+func (schema *ServiceSchema) ValidateInput(body []byte) []string {
+	if schema == nil || schema.Input == nil {
+		return nil
+	}
+	compiled, err := compileSchema(schema.Input)
+	if err != nil {
+		return []string{fmt.Sprintf("the input schema is not valid: %v", err)}
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return []string{fmt.Sprintf("the body is not a valid JSON document: %v", err)}
+	}
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(document))
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	issues := []string{}
+	for _, resErr := range result.Errors() {
+		issues = append(issues, fmt.Sprintf("%s: %s", resErr.Field(), resErr.Description()))
+	}
+	return issues
+}
+
+// compileSchema compiles a JSON Schema, returning nil if it is not defined
+func compileSchema(schema map[string]interface{}) (*gojsonschema.Schema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	// The schemas decoded from YAML are normalized through JSON
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	return gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+}
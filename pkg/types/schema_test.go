@@ -0,0 +1,70 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestValidateSchema(t *testing.T) {
+	fdl := `
+name: test
+image: alpine
+schema:
+  input:
+    type: object
+    required: [text]
+    properties:
+      text:
+        type: string
+        maxLength: 5
+  output:
+    type: string
+`
+	service := &Service{}
+	if err := yaml.Unmarshal([]byte(fdl), service); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.ValidateSchema(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]int{
+		`{"text": "hi"}`:     0,
+		`{"text": "hello!"}`: 1,
+		`{"text": 1}`:        1,
+		`{}`:                 1,
+		`[`:                  1,
+	}
+	for body, issues := range cases {
+		if got := service.Schema.ValidateInput([]byte(body)); len(got) != issues {
+			t.Errorf("%s: expecting %d issues, got %v", body, issues, got)
+		}
+	}
+
+	service.Schema.Input = map[string]interface{}{"type": "unknown"}
+	if err := service.ValidateSchema(); err == nil {
+		t.Error("expecting error for an invalid input schema")
+	}
+
+	var nilSchema *ServiceSchema
+	if issues := nilSchema.ValidateInput([]byte("not json")); len(issues) != 0 {
+		t.Errorf("expecting no issues without schema, got %v", issues)
+	}
+}
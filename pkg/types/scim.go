@@ -0,0 +1,141 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+const (
+	// SCIMUserSchema schema of the SCIM 2.0 users (RFC 7643)
+	SCIMUserSchema = "urn:ietf:params:scim:schemas:core:2.0:User"
+	// SCIMListResponseSchema schema of the SCIM 2.0 list responses (RFC 7644)
+	SCIMListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	// SCIMPatchOpSchema schema of the SCIM 2.0 patch requests (RFC 7644)
+	SCIMPatchOpSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	// SCIMErrorSchema schema of the SCIM 2.0 errors (RFC 7644)
+	SCIMErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+	// SCIMContentType content type of the SCIM 2.0 responses
+	SCIMContentType = "application/scim+json"
+)
+
+// SCIMUser user of the user store in the SCIM 2.0 format, whose id is its username. The password is write-only and
+// the primary role (or the first one) is the role of the user
+type SCIMUser struct {
+	Schemas  []string    `json:"schemas"`
+	ID       string      `json:"id,omitempty"`
+	UserName string      `json:"userName"`
+	Password string      `json:"password,omitempty"`
+	Active   *bool       `json:"active,omitempty"`
+	Roles    []SCIMValue `json:"roles,omitempty"`
+	Groups   []SCIMValue `json:"groups,omitempty"`
+	Meta     *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// SCIMValue multi-valued attribute of the SCIM users
+type SCIMValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMMeta metadata of the SCIM resources
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+// SCIMListResponse list of SCIM resources
+type SCIMListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int        `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []SCIMUser `json:"Resources"`
+}
+
+// SCIMPatchRequest SCIM patch request of a user
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation operation of a SCIM patch request ("add", "replace" or "remove" an attribute)
+type SCIMPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// SCIMError SCIM error
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// NewSCIMUser returns the SCIM representation of a user
+func NewSCIMUser(user User) SCIMUser {
+	active := !user.Disabled
+	scimUser := SCIMUser{
+		Schemas:  []string{SCIMUserSchema},
+		ID:       user.Username,
+		UserName: user.Username,
+		Active:   &active,
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     "/scim/v2/Users/" + user.Username,
+		},
+	}
+	if user.Role != "" {
+		scimUser.Roles = []SCIMValue{{Value: user.Role, Primary: true}}
+	}
+	for _, group := range user.Groups {
+		scimUser.Groups = append(scimUser.Groups, SCIMValue{Value: group, Display: group})
+	}
+	return scimUser
+}
+
+// UserRequest returns the request to create or update the user of a SCIM user
+func (u SCIMUser) UserRequest() UserRequest {
+	req := UserRequest{
+		Username: u.UserName,
+		Password: u.Password,
+		Role:     getSCIMRole(u.Roles),
+	}
+	if u.Active != nil {
+		disabled := !*u.Active
+		req.Disabled = &disabled
+	}
+	return req
+}
+
+// getSCIMRole returns the primary role of the SCIM roles (or the first one)
+func getSCIMRole(roles []SCIMValue) string {
+	for _, role := range roles {
+		if role.Primary {
+			return role.Value
+		}
+	}
+	if len(roles) > 0 {
+		return roles[0].Value
+	}
+	return ""
+}
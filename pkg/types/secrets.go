@@ -0,0 +1,78 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// secretNameRegexp valid names of the service secrets (environment variable names)
+var secretNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ServiceSecrets secrets of a service, stored in a Kubernetes secret and set as environment variables of its
+// containers (not included in the service definition)
+type ServiceSecrets struct {
+	// Secrets values of the secrets by name. Only the names are returned when reading them
+	Secrets map[string]string `json:"secrets,omitempty"`
+	// Names names of the stored secrets
+	Names []string `json:"names"`
+}
+
+// ServiceSecretsName returns the name of the Kubernetes secret storing the secrets of a service
+func ServiceSecretsName(serviceName string) string {
+	return serviceName + "-secrets"
+}
+
+// Validate checks the names of the secrets
+func (s ServiceSecrets) Validate() error {
+	for name := range s.Secrets {
+		if !secretNameRegexp.MatchString(name) {
+			return fmt.Errorf("the secret name \"%s\" is not valid (it must be a valid environment variable name)", name)
+		}
+	}
+	return nil
+}
+
+// GetNames returns the sorted names of the secrets
+func (s ServiceSecrets) GetNames() []string {
+	names := []string{}
+	for name := range s.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addSecretsEnvFrom sets the service's secrets (if any) as environment variables of the service container. The
+// environment variables of the service take precedence
+func addSecretsEnvFrom(p *v1.PodSpec, service *Service) {
+	optional := true
+	for i, cont := range p.Containers {
+		if cont.Name == ContainerName {
+			p.Containers[i].EnvFrom = append(p.Containers[i].EnvFrom, v1.EnvFromSource{
+				SecretRef: &v1.SecretEnvSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: ServiceSecretsName(service.Name)},
+					Optional:             &optional,
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+)
+
+func TestValidateServiceSecrets(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		secrets     map[string]string
+		returnError bool
+	}{
+		{"no secrets", nil, false},
+		{"valid", map[string]string{"API_TOKEN": "x", "_key2": "y"}, false},
+		{"leading digit", map[string]string{"2TOKEN": "x"}, true},
+		{"dash", map[string]string{"API-TOKEN": "x"}, true},
+		{"empty", map[string]string{"": "x"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := (ServiceSecrets{Secrets: s.secrets}).Validate(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestToPodSpecSecrets(t *testing.T) {
+	service := &Service{Name: "testname", Image: "testimage"}
+
+	podSpec, err := service.ToPodSpec(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	envFrom := podSpec.Containers[0].EnvFrom
+	if len(envFrom) != 1 || envFrom[0].SecretRef == nil {
+		t.Fatalf("expecting the secrets of the service, got %+v", envFrom)
+	}
+	if envFrom[0].SecretRef.Name != "testname-secrets" || !*envFrom[0].SecretRef.Optional {
+		t.Errorf("unexpected secret reference: %+v", envFrom[0].SecretRef)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// AllCapabilities name of the capability dropping all the Linux capabilities of a container
+const AllCapabilities = "ALL"
+
+// Names of the Linux capabilities (e.g. "NET_RAW" or "ALL")
+var capabilityRegexp = regexp.MustCompile(`^[A-Z][A-Z_]*$`)
+
+// SecurityContext security settings of the service's pods (user and groups running the containers, read-only root
+// filesystem and dropped Linux capabilities)
+type SecurityContext struct {
+	// RunAsUser UID running the containers
+	// Optional. (default: the user of the image)
+	RunAsUser *int64 `json:"run_as_user,omitempty"`
+	// RunAsGroup GID running the containers
+	// Optional. (default: the group of the image)
+	RunAsGroup *int64 `json:"run_as_group,omitempty"`
+	// FSGroup supplementary GID owning the volumes of the pods
+	// Optional.
+	FSGroup *int64 `json:"fs_group,omitempty"`
+	// RunAsNonRoot parameter to reject the containers running as root
+	// Optional. (default: false)
+	RunAsNonRoot bool `json:"run_as_non_root,omitempty"`
+	// ReadOnlyRootFilesystem parameter to mount the root filesystem of the containers as read-only
+	// Optional. (default: false)
+	ReadOnlyRootFilesystem bool `json:"read_only_root_filesystem,omitempty"`
+	// DropCapabilities Linux capabilities dropped from the containers (e.g. "ALL")
+	// Optional.
+	DropCapabilities []string `json:"drop_capabilities,omitempty"`
+
+	// restricted set by the untrusted VOs policy to disable the privilege escalation and use the runtime's
+	// default seccomp profile
+	restricted bool
+}
+
+// IsUntrustedVO checks if the services of a VO must run with the secure defaults of the UNTRUSTED_VOS policy ("*"
+// matches all the services). The services without VO are untrusted if any VO is, so they can not escape the policy
+func (cfg *Config) IsUntrustedVO(vo string) bool {
+	for _, untrusted := range cfg.UntrustedVOs {
+		if untrusted == "*" || untrusted == vo || (vo == "" && untrusted != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSecurityContext checks the security context of the service and that it does not run as root if its VO
+// is untrusted
+func (service *Service) ValidateSecurityContext(cfg *Config) error {
+	sc := service.SecurityContext
+	if sc == nil {
+		return nil
+	}
+	ids := []struct {
+		field string
+		value *int64
+	}{{"run_as_user", sc.RunAsUser}, {"run_as_group", sc.RunAsGroup}, {"fs_group", sc.FSGroup}}
+	for _, id := range ids {
+		if id.value != nil && *id.value < 0 {
+			return fmt.Errorf("the %s of the security_context must be a non-negative integer", id.field)
+		}
+	}
+	for _, capability := range sc.DropCapabilities {
+		if !capabilityRegexp.MatchString(capability) {
+			return fmt.Errorf("the capability \"%s\" of the security_context is not valid", capability)
+		}
+	}
+	if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+		if sc.RunAsNonRoot {
+			return fmt.Errorf("the security_context can not set run_as_non_root and run as root (run_as_user 0)")
+		}
+		if cfg.IsUntrustedVO(service.VO) {
+			if service.VO == "" {
+				return fmt.Errorf("the services without VO can not run as root (run_as_user 0)")
+			}
+			return fmt.Errorf("the services of the VO \"%s\" can not run as root (run_as_user 0)", service.VO)
+		}
+	}
+	return nil
+}
+
+// GetSecurityContext returns the security context of the service's pods, enforcing the secure defaults if its
+// VO is untrusted (UNTRUSTED_VOS): non-root user (UNTRUSTED_RUN_AS_USER if not set), all capabilities dropped,
+// no privilege escalation and the runtime's default seccomp profile
+func (service *Service) GetSecurityContext(cfg *Config) *SecurityContext {
+	if !cfg.IsUntrustedVO(service.VO) {
+		return service.SecurityContext
+	}
+
+	sc := SecurityContext{}
+	if service.SecurityContext != nil {
+		sc = *service.SecurityContext
+	}
+	sc.RunAsNonRoot = true
+	if sc.RunAsUser == nil {
+		user := int64(cfg.UntrustedRunAsUser)
+		sc.RunAsUser = &user
+	}
+	sc.DropCapabilities = []string{AllCapabilities}
+	sc.restricted = true
+	return &sc
+}
+
+// Apply sets the security context in the pod and its containers (including the init containers)
+func (sc *SecurityContext) Apply(podSpec *v1.PodSpec) {
+	if sc == nil {
+		return
+	}
+
+	if podSpec.SecurityContext == nil {
+		podSpec.SecurityContext = &v1.PodSecurityContext{}
+	}
+	podSpec.SecurityContext.RunAsUser = copyInt64(sc.RunAsUser)
+	podSpec.SecurityContext.RunAsGroup = copyInt64(sc.RunAsGroup)
+	podSpec.SecurityContext.FSGroup = copyInt64(sc.FSGroup)
+	if sc.RunAsNonRoot {
+		runAsNonRoot := true
+		podSpec.SecurityContext.RunAsNonRoot = &runAsNonRoot
+	}
+	if sc.restricted {
+		podSpec.SecurityContext.SeccompProfile = &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault}
+	}
+
+	sc.applyToContainers(podSpec.InitContainers)
+	sc.applyToContainers(podSpec.Containers)
+}
+
+func (sc *SecurityContext) applyToContainers(containers []v1.Container) {
+	for i := range containers {
+		container := &containers[i]
+		if container.SecurityContext == nil {
+			container.SecurityContext = &v1.SecurityContext{}
+		}
+		if sc.ReadOnlyRootFilesystem {
+			readOnly := true
+			container.SecurityContext.ReadOnlyRootFilesystem = &readOnly
+		}
+		if sc.restricted {
+			allowPrivilegeEscalation := false
+			container.SecurityContext.AllowPrivilegeEscalation = &allowPrivilegeEscalation
+		}
+		if len(sc.DropCapabilities) > 0 {
+			if container.SecurityContext.Capabilities == nil {
+				container.SecurityContext.Capabilities = &v1.Capabilities{}
+			}
+			for _, capability := range sc.DropCapabilities {
+				container.SecurityContext.Capabilities.Drop = append(container.SecurityContext.Capabilities.Drop, v1.Capability(capability))
+			}
+		}
+	}
+}
+
+func copyInt64(value *int64) *int64 {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
@@ -0,0 +1,126 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestValidateSecurityContext(t *testing.T) {
+	root := int64(0)
+	user := int64(1000)
+	negative := int64(-1)
+	cfg := &Config{UntrustedVOs: []string{"untrusted"}}
+	scenarios := []struct {
+		name        string
+		service     Service
+		returnError bool
+	}{
+		{"empty", Service{}, false},
+		{"valid", Service{SecurityContext: &SecurityContext{RunAsUser: &user, RunAsGroup: &user, FSGroup: &user, ReadOnlyRootFilesystem: true, DropCapabilities: []string{"ALL"}}}, false},
+		{"root", Service{VO: "trusted", SecurityContext: &SecurityContext{RunAsUser: &root}}, false},
+		{"root without VO", Service{SecurityContext: &SecurityContext{RunAsUser: &root}}, true},
+		{"negative group", Service{SecurityContext: &SecurityContext{RunAsGroup: &negative}}, true},
+		{"invalid capability", Service{SecurityContext: &SecurityContext{DropCapabilities: []string{"net_raw"}}}, true},
+		{"root and non root", Service{SecurityContext: &SecurityContext{RunAsUser: &root, RunAsNonRoot: true}}, true},
+		{"root in untrusted VO", Service{VO: "untrusted", SecurityContext: &SecurityContext{RunAsUser: &root}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.service.ValidateSecurityContext(cfg); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestIsUntrustedVO(t *testing.T) {
+	cfg := &Config{UntrustedVOs: []string{"untrusted"}}
+	if !cfg.IsUntrustedVO("untrusted") || cfg.IsUntrustedVO("trusted") {
+		t.Error("expecting only the VO \"untrusted\" to be untrusted")
+	}
+	if !cfg.IsUntrustedVO("") {
+		t.Error("expecting the services without VO to be untrusted")
+	}
+	if (&Config{}).IsUntrustedVO("") {
+		t.Error("expecting the services without VO to be trusted without untrusted VOs")
+	}
+
+	cfg.UntrustedVOs = []string{"*"}
+	if !cfg.IsUntrustedVO("trusted") || !cfg.IsUntrustedVO("") {
+		t.Error("expecting all the services to be untrusted")
+	}
+}
+
+func TestGetSecurityContext(t *testing.T) {
+	user := int64(2000)
+	cfg := &Config{UntrustedVOs: []string{"untrusted"}, UntrustedRunAsUser: 1000}
+
+	service := Service{VO: "trusted"}
+	if sc := service.GetSecurityContext(cfg); sc != nil {
+		t.Errorf("expecting no security context, got %+v", sc)
+	}
+
+	service = Service{VO: "untrusted"}
+	sc := service.GetSecurityContext(cfg)
+	if sc == nil || !sc.RunAsNonRoot || sc.RunAsUser == nil || *sc.RunAsUser != 1000 || !sc.restricted {
+		t.Fatalf("expecting the secure defaults, got %+v", sc)
+	}
+
+	service.SecurityContext = &SecurityContext{RunAsUser: &user, DropCapabilities: []string{"NET_RAW"}}
+	sc = service.GetSecurityContext(cfg)
+	if *sc.RunAsUser != 2000 || len(sc.DropCapabilities) != 1 || sc.DropCapabilities[0] != AllCapabilities {
+		t.Errorf("expecting user 2000 and all capabilities dropped, got %+v", sc)
+	}
+	if len(service.SecurityContext.DropCapabilities) != 1 || service.SecurityContext.DropCapabilities[0] != "NET_RAW" {
+		t.Errorf("expecting the security context of the service not to be modified, got %+v", service.SecurityContext)
+	}
+}
+
+func TestApplySecurityContext(t *testing.T) {
+	podSpec := &v1.PodSpec{Containers: []v1.Container{{Name: ContainerName}}}
+	AddContainers(podSpec, []ContainerSpec{{Name: "model", Image: "alpine"}}, nil)
+	var nilContext *SecurityContext
+	nilContext.Apply(podSpec)
+	if podSpec.SecurityContext != nil || podSpec.Containers[0].SecurityContext != nil || podSpec.InitContainers[0].SecurityContext != nil {
+		t.Fatal("expecting no security context")
+	}
+
+	user := int64(1000)
+	sc := &SecurityContext{RunAsUser: &user, FSGroup: &user, RunAsNonRoot: true, ReadOnlyRootFilesystem: true, DropCapabilities: []string{AllCapabilities}, restricted: true}
+	sc.Apply(podSpec)
+
+	podCtx := podSpec.SecurityContext
+	if podCtx == nil || *podCtx.RunAsUser != 1000 || *podCtx.FSGroup != 1000 || podCtx.RunAsGroup != nil || !*podCtx.RunAsNonRoot {
+		t.Errorf("unexpected pod security context %+v", podCtx)
+	}
+	if podCtx.SeccompProfile == nil || podCtx.SeccompProfile.Type != v1.SeccompProfileTypeRuntimeDefault {
+		t.Errorf("expecting the RuntimeDefault seccomp profile, got %+v", podCtx.SeccompProfile)
+	}
+	for _, container := range []v1.Container{podSpec.Containers[0], podSpec.InitContainers[0]} {
+		containerCtx := container.SecurityContext
+		if containerCtx == nil || !*containerCtx.ReadOnlyRootFilesystem || *containerCtx.AllowPrivilegeEscalation {
+			t.Fatalf("unexpected security context of the container %s: %+v", container.Name, containerCtx)
+		}
+		if len(containerCtx.Capabilities.Drop) != 1 || containerCtx.Capabilities.Drop[0] != AllCapabilities {
+			t.Errorf("expecting all capabilities dropped in the container %s, got %v", container.Name, containerCtx.Capabilities.Drop)
+		}
+	}
+}
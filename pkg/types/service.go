@@ -19,10 +19,12 @@ package types
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/goccy/go-yaml"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -50,12 +52,18 @@ const (
 	// ScriptFileName name of the user script file to be stored in the service's configMap
 	ScriptFileName = "script.sh"
 
+	// FeaturesFileName name of the env file with the service's feature flags stored in the service's configMap
+	FeaturesFileName = "features.env"
+
 	// PVCName name of the OSCAR PVC
 	PVCName = "oscar-pvc"
 
 	// WatchdogName name of the OpenFaaS watchdog binary
 	WatchdogName = "fwatchdog"
 
+	// StreamingWatchdogName name of the OpenFaaS of-watchdog binary, used by the streaming services
+	StreamingWatchdogName = "of-watchdog"
+
 	// WatchdogProcess name of the environment variable used by the watchdog to handle requests
 	WatchdogProcess = "fprocess"
 
@@ -95,14 +103,71 @@ const (
 	// KnativeClusterLocalValue cluster-local value for the visibility label
 	KnativeClusterLocalValue = "cluster-local"
 
+	// KnativeServiceLabel label set by Knative on the resources created for a Knative service
+	KnativeServiceLabel = "serving.knative.dev/service"
+
 	// KnativeMinScaleAnnotation annotation key to set the minimum number of replicas for a Knative service
 	KnativeMinScaleAnnotation = "autoscaling.knative.dev/min-scale"
 
 	// KnativeMaxScaleAnnotation annotation key to set the maximum number of replicas for a Knative service
 	KnativeMaxScaleAnnotation = "autoscaling.knative.dev/max-scale"
 
+	// KnativeTargetAnnotation annotation key to set the concurrency target of the autoscaler of a Knative service
+	KnativeTargetAnnotation = "autoscaling.knative.dev/target"
+
+	// KnativeScaleDownDelayAnnotation annotation key to set the delay before scaling down a Knative service
+	KnativeScaleDownDelayAnnotation = "autoscaling.knative.dev/scale-down-delay"
+
 	// ReSchedulerLabelKey label key to enable/disable the ReScheduler
 	ReSchedulerLabelKey = "oscar_rescheduler"
+
+	// DeadLetterLabelKey label key set on failed jobs already stored in the service's dead-letter path
+	DeadLetterLabelKey = "oscar_deadletter"
+
+	// LogsArchivedLabelKey label key set on finished jobs whose logs are already archived
+	LogsArchivedLabelKey = "oscar_logs_archived"
+
+	// NotifiedLabelKey label key set on finished jobs already notified by email
+	NotifiedLabelKey = "oscar_notified"
+
+	// ChatNotifiedLabelKey label key set on finished jobs already notified to a messaging platform
+	ChatNotifiedLabelKey = "oscar_chat_notified"
+
+	// CallbackLabelKey label key set on finished jobs whose callback has already been delivered (or scheduled)
+	CallbackLabelKey = "oscar_callback"
+
+	// DeferredLabelKey label key set on the suspended jobs of deferrable services waiting for a low-carbon window
+	DeferredLabelKey = "oscar_deferred"
+
+	// DeferredUntilAnnotation annotation key with the time (RFC3339) when a deferred job must be released
+	DeferredUntilAnnotation = "oscar_deferred_until"
+
+	// CarbonSubmitAnnotation annotation key with the carbon intensity when the job of a deferrable service was submitted
+	CarbonSubmitAnnotation = "oscar_carbon_submit"
+
+	// CarbonRunAnnotation annotation key with the carbon intensity when the job of a deferrable service was released
+	CarbonRunAnnotation = "oscar_carbon_run"
+
+	// EventReceivedAnnotation annotation key with the time (RFC3339Nano) when the event of a job was received
+	EventReceivedAnnotation = "oscar_event_received"
+
+	// RetryOfAnnotation annotation key with the name of the original job of a retried job
+	RetryOfAnnotation = "oscar_retry_of"
+
+	// QueuedLabelKey label key set on the suspended jobs waiting for a free slot of the service's max_concurrent_jobs
+	QueuedLabelKey = "oscar_queued"
+
+	// MaxConcurrentJobsAnnotation annotation key with the max_concurrent_jobs of the service of a queued job
+	MaxConcurrentJobsAnnotation = "oscar_max_concurrent_jobs"
+
+	// InputLabelKey label key with the index of the input whose event created the job (only for inputs with limits)
+	InputLabelKey = "oscar_input"
+
+	// InputMaxInFlightAnnotation annotation key with the max_in_flight of the input of a queued job
+	InputMaxInFlightAnnotation = "oscar_input_max_in_flight"
+
+	// InputMaxEventsPerMinuteAnnotation annotation key with the max_events_per_minute of the input of a queued job
+	InputMaxEventsPerMinuteAnnotation = "oscar_input_max_events_per_minute"
 )
 
 // YAMLMarshal package-level yaml marshal function
@@ -139,10 +204,55 @@ type Service struct {
 	// Optional. (default: "")
 	TotalCPU string `json:"total_cpu"`
 
+	// Yunikorn Apache YuniKorn queue and application ID of the service's jobs, overriding the ones of its VO and the
+	// OSCAR's defaults
+	// Optional
+	Yunikorn *YunikornSettings `json:"yunikorn,omitempty"`
+
 	// EnableGPU parameter to request gpu usage in service's executions (synchronous and asynchronous)
 	// Optional. (default: false)
 	EnableGPU bool `json:"enable_gpu"`
 
+	// GPU number of GPUs ("nvidia.com/gpu") requested in service's executions, overriding enable_gpu
+	// Optional. (default: 1 if enable_gpu is set)
+	GPU int `json:"gpu,omitempty"`
+
+	// ExtendedResources quantities of the extended resources requested in service's executions by resource name
+	// (e.g. "nvidia.com/mig-1g.5gb": "1" for a MIG profile)
+	// Optional.
+	ExtendedResources map[string]string `json:"extended_resources,omitempty"`
+
+	// NodeSelector labels of the nodes where the service's pods can run (e.g. "kubernetes.io/arch": "arm64")
+	// Optional.
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+
+	// Affinity Kubernetes (anti-)affinity rules of the service's pods with the nodes and other pods
+	// Optional.
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
+	// Tolerations Kubernetes tolerations of the service's pods, to run them in tainted nodes (e.g. GPU or edge nodes)
+	// Optional.
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+
+	// RuntimeClass Kubernetes RuntimeClass of the service's pods, to run untrusted containers in a sandbox
+	// (e.g. gVisor or Kata Containers). Must be the cluster's default or be in its allow-list (RUNTIME_CLASSES)
+	// Optional. (default: DEFAULT_RUNTIME_CLASS)
+	RuntimeClass string `json:"runtime_class,omitempty"`
+
+	// SecurityContext user and groups running the service's containers, read-only root filesystem and dropped
+	// Linux capabilities. The secure defaults are enforced if the VO of the service is untrusted (UNTRUSTED_VOS)
+	// Optional.
+	SecurityContext *SecurityContext `json:"security_context,omitempty"`
+
+	// InitContainers containers run before the service container (e.g. to pre-stage data or download a model)
+	// Optional.
+	InitContainers []ContainerSpec `json:"init_containers,omitempty"`
+
+	// Sidecars containers run alongside the service container (e.g. a license server proxy or a telemetry agent),
+	// stopped when the service container of a job exits
+	// Optional.
+	Sidecars []ContainerSpec `json:"sidecars,omitempty"`
+
 	// EnableSGX parameter to use the SCONE k8s plugin
 	// Optional. (default: false)
 	EnableSGX bool `json:"enable_sgx"`
@@ -161,16 +271,120 @@ type Service struct {
 		// MaxScale maximum number of active replicas (pods) for the service
 		// Optional. (default: 0 [Unlimited])
 		MaxScale int `json:"max_scale"`
+		// TargetConcurrency number of simultaneous requests per replica targeted by the autoscaler
+		// Optional. (default: 0 [Knative's default])
+		TargetConcurrency int `json:"target_concurrency,omitempty"`
+		// ContainerConcurrency maximum number of simultaneous requests per replica
+		// Optional. (default: 1)
+		ContainerConcurrency int `json:"container_concurrency,omitempty"`
+		// ScaleDownDelay time (e.g. "15m") to wait before scaling down the replicas, to keep them warm
+		// Optional. (default: "" [Knative's default])
+		ScaleDownDelay string `json:"scale_down_delay,omitempty"`
 	} `json:"synchronous"`
 
+	// Workers settings of the KEDA ScaledJob consuming the events of the service from a queue, so the bursts of
+	// events are processed by an autoscaled pool of workers instead of a job per event (requires KEDA_ENABLE)
+	// Optional.
+	Workers *WorkerPool `json:"workers,omitempty"`
+
+	// Batch struct to configure the aggregation of input events into a single job
+	// Events are delivered to the job as a JSON array in the EVENT environment variable
+	// (the FaaS Supervisor does not parse it, so the script must process the array).
+	// Pending events are kept in memory, so they are lost if the OSCAR manager restarts
+	// Optional.
+	Batch struct {
+		// Size number of events that triggers the creation of the job
+		// Optional. (default: 0 [Only the window is used])
+		Size int `json:"size"`
+		// Window maximum time (in seconds) to wait for more events before creating the job
+		// Mandatory if Size is greater than 1
+		Window int `json:"window"`
+	} `json:"batch"`
+
+	// EmailTrigger IMAP mailbox polled to trigger the service on new emails
+	// Optional
+	EmailTrigger *EmailTrigger `json:"email_trigger,omitempty"`
+
+	// EmailNotification SMTP configuration to notify the completion/failure of the service's jobs
+	// Optional
+	EmailNotification *EmailNotification `json:"email_notification,omitempty"`
+
+	// ChatNotification incoming webhook of a messaging platform (Slack, Mattermost or Microsoft Teams) notified
+	// with templated messages on the completion/failure of the service's jobs
+	// Optional
+	ChatNotification *ChatNotification `json:"chat_notification,omitempty"`
+
+	// Callback HTTP endpoint notified on the completion/failure of the service's jobs
+	// Optional
+	Callback *Callback `json:"callback,omitempty"`
+
+	// DeadLetterPath path ("bucket/prefix") in the OSCAR's MinIO where the details of the failed jobs are stored
+	// to be listed and re-driven through the API
+	// Optional. (default: "" [Disabled])
+	DeadLetterPath string `json:"dead_letter_path,omitempty"`
+
+	// QuarantinePath path ("bucket/prefix") in the OSCAR's MinIO where the infected input objects are moved. Setting it
+	// enables the malware scanning of the objects of the inputs in the default MinIO provider before creating their
+	// jobs, which requires a malware scanner configured in the cluster (MALWARE_SCANNER_URL)
+	// Optional. (default: "" [Disabled])
+	QuarantinePath string `json:"quarantine_path,omitempty"`
+
 	// Replicas list of replicas to delegate jobs
 	// Optional
 	Replicas ReplicaList `json:"replicas,omitempty"`
 
+	// DelegationPolicy policy to select where the jobs are run when there are replicas defined
+	// ("static", "least-loaded", "data-locality" or "energy-aware")
+	// Optional. (default: "static")
+	DelegationPolicy string `json:"delegation_policy,omitempty"`
+
+	// Deferrable allows delaying the jobs to low-carbon windows (if the cluster has a carbon intensity API configured)
+	// Optional. (default: false)
+	Deferrable bool `json:"deferrable,omitempty"`
+
+	// MaxDelay maximum time (in seconds) that the jobs of a deferrable service can be delayed
+	// Optional. (default: the cluster's DEFERRABLE_MAX_DELAY)
+	MaxDelay int `json:"max_delay,omitempty"`
+
+	// MaxConcurrentJobs maximum number of jobs of the service running simultaneously. The jobs exceeding it are
+	// created suspended and released (oldest first) when the running ones finish
+	// Optional. (default: 0 [Unlimited])
+	MaxConcurrentJobs int `json:"max_concurrent_jobs,omitempty"`
+
+	// Requires hardware capabilities (e.g. "nvidia-A100", "avx512", "hugepages-2Mi" or "arm64") that must be
+	// provided by a node of the cluster, checked when the service is created or updated
+	// Optional
+	Requires []string `json:"requires,omitempty"`
+
+	// LatencyBudget maximum time (in seconds) from the receipt of an event to the start of the script of its job,
+	// reported in the service's latency report
+	// Optional
+	LatencyBudget int `json:"latency_budget,omitempty"`
+
+	// Priority name of the Kubernetes PriorityClass of the service's pods, also used by Apache YuniKorn
+	// to sort the applications of the queues
+	// Optional
+	Priority string `json:"priority,omitempty"`
+
+	// Placement policy to place the jobs in the tier (node pool or replica) closest to the triggering object
+	// Optional
+	Placement *PlacementPolicy `json:"placement,omitempty"`
+
 	// ReSchedulerThreshold time (in seconds) that a job (with replicas) can be queued before delegating it
 	// Optional
 	ReSchedulerThreshold int `json:"rescheduler_threshold"`
 
+	// Streaming runs the synchronous invocations through the OpenFaaS of-watchdog in streaming mode, executing the
+	// script directly (without the FaaS Supervisor) with the request body as its standard input and sending its
+	// standard output to the client as it is written, instead of buffering it until the script finishes
+	// Optional. (default: false)
+	Streaming bool `json:"streaming,omitempty"`
+
+	// Schema JSON Schemas of the input and output of the synchronous invocations. The request bodies not
+	// matching the input schema are rejected
+	// Optional
+	Schema *ServiceSchema `json:"schema,omitempty"`
+
 	// LogLevel log level for the FaaS Supervisor
 	// Optional. (default: INFO)
 	LogLevel string `json:"log_level"`
@@ -187,6 +401,14 @@ type Service struct {
 	// Read only. This field is automatically generated by OSCAR
 	Token string `json:"token"`
 
+	// AccessTokens named access tokens of the service, with their scopes (only their hashes are stored)
+	// Read only. This field is managed through the /system/services/{serviceName}/tokens endpoints
+	AccessTokens []ServiceAccessToken `json:"access_tokens,omitempty"`
+
+	// Revision number of the revision of the service definition
+	// Read only. This field is automatically set by OSCAR on each create, update or rollback
+	Revision int `json:"revision,omitempty"`
+
 	// A parameter to disable the download of input files by the FaaS Supervisor
 	// Optional. (default: false)
 	FileStageIn bool `json:"file_stage_in"`
@@ -199,8 +421,65 @@ type Service struct {
 	// Optional
 	Output []StorageIOConfig `json:"output"`
 
+	// OutputDestinations paths (in the service's MinIO providers) where the invocations can redirect the outputs
+	// with the output_override parameter, so the same service can deliver the results of each caller to a
+	// different location
+	// Optional
+	OutputDestinations []StorageIOConfig `json:"output_destinations,omitempty"`
+
+	// OutputRoutes rules routing the output files tagged by the script (in the oscar-manifest.json file of its output
+	// folder) to the outputs of the service. The outputs are staged and routed by OSCAR once the job finishes
+	// Optional
+	OutputRoutes []OutputRoute `json:"output_routes,omitempty"`
+
+	// InlineOutputs passing of the small output files to the jobs of the chained services (the ones whose input is
+	// the output of the service) in their events, instead of storing them in the output bucket
+	// Optional
+	InlineOutputs *InlineOutputs `json:"inline_outputs,omitempty"`
+
+	// OutputOverride destination of the outputs of the current invocation (output_override parameter)
+	// Read only. It is not stored in the service definition
+	OutputOverride *StorageIOConfig `json:"-"`
+
+	// ScopedCredentials the default MinIO provider uses the temporary credentials of the current job
+	// Read only. It is not stored in the service definition
+	ScopedCredentials bool `json:"-"`
+
+	// IdentityToken OIDC token of the user invoking the service, to obtain the temporary credentials of the job
+	// Read only. It is not stored in the service definition
+	IdentityToken string `json:"-"`
+
+	// StorageFailover the default MinIO provider of the current job is the secondary one, as the OSCAR's MinIO is
+	// unreachable
+	// Read only. It is not stored in the service definition
+	StorageFailover bool `json:"-"`
+
+	// VaultCredentials the credentials of the storage providers of the current job have been read from Vault
+	// Read only. It is not stored in the service definition
+	VaultCredentials bool `json:"-"`
+
 	// Script the user script to execute when the service is invoked
-	Script string `json:"script,omitempty" binding:"required"`
+	// Required if ScriptGit is not defined
+	Script string `json:"script,omitempty"`
+
+	// ScriptGit Git repository from which the user script is fetched
+	// If defined, the Script is obtained from the repository when the service is created or updated
+	// Optional
+	ScriptGit *GitScriptSource `json:"script_git,omitempty"`
+
+	// Assets files uploaded through an upload session (two-phase create) and copied to the OSCAR's MinIO
+	// before the service is activated
+	// Optional
+	Assets []Asset `json:"assets,omitempty"`
+
+	// Supervisor FaaS Supervisor build (image and version) used instead of the one installed in the cluster
+	// Optional
+	Supervisor *SupervisorConfig `json:"supervisor,omitempty"`
+
+	// Dependencies pip, conda and apt packages installed (and cached in the OSCAR PVC) by an init container
+	// before running the service
+	// Optional
+	Dependencies *Dependencies `json:"dependencies,omitempty"`
 
 	// ImagePullSecrets list of Kubernetes secrets to login to a private registry
 	// Optional
@@ -219,6 +498,12 @@ type Service struct {
 		Vars map[string]string `json:"Variables"`
 	} `json:"environment"`
 
+	// Features feature flags of the service, set in the service's containers as OSCAR_FEATURE_<NAME> environment
+	// variables and written to the /oscar/config/features.env file, which is updated in the running containers
+	// when the flags are toggled through the API
+	// Optional
+	Features map[string]string `json:"features,omitempty"`
+
 	// Annotations user-defined Kubernetes annotations to be set in job's definition
 	// https://kubernetes.io/docs/concepts/overview/working-with-objects/annotations/
 	// Optional
@@ -228,6 +513,25 @@ type Service struct {
 	// Optional
 	VO string `json:"vo"`
 
+	// Owner user that created the service (basic auth username or OIDC subject), whose quotas limit its jobs.
+	// Set by OSCAR
+	Owner string `json:"owner,omitempty"`
+
+	// Namespace namespace of the service's resources: the services namespace or the namespace of its VO (see
+	// VO_NAMESPACES). Set by OSCAR when the service is created, so it is kept if the VO changes
+	Namespace string `json:"namespace,omitempty"`
+
+	// Owners additional users (basic auth usernames or OIDC subjects) allowed to read and manage the service, along
+	// with its owner and the members of its VO
+	// Optional
+	Owners []string `json:"owners,omitempty"`
+
+	// Protected the service can not be updated or deleted without confirming it (with the X-Oscar-Confirm header
+	// set to the name of the service) or unlocking it first (POST /system/services/{serviceName}/unlock). Only the
+	// admin of the cluster can set or unset it
+	// Optional. (default: false)
+	Protected bool `json:"protected,omitempty"`
+
 	// Labels user-defined Kubernetes labels to be set in job's definition
 	// https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/
 	// Optional
@@ -250,7 +554,8 @@ func (service *Service) ToPodSpec(cfg *Config) (*v1.PodSpec, error) {
 	}
 
 	podSpec := &v1.PodSpec{
-		ImagePullSecrets: SetImagePullSecrets(service.ImagePullSecrets),
+		ImagePullSecrets:  SetImagePullSecrets(service.ImagePullSecrets),
+		PriorityClassName: service.Priority,
 		Containers: []v1.Container{
 			{
 				Name:  ContainerName,
@@ -268,7 +573,7 @@ func (service *Service) ToPodSpec(cfg *Config) (*v1.PodSpec, error) {
 						MountPath: ConfigPath,
 					},
 				},
-				Command:   []string{fmt.Sprintf("%s/%s", VolumePath, WatchdogName)},
+				Command:   []string{service.GetWatchdogPath()},
 				Resources: resources,
 			},
 		},
@@ -297,10 +602,39 @@ func (service *Service) ToPodSpec(cfg *Config) (*v1.PodSpec, error) {
 	// Add the required environment variables for the watchdog
 	addWatchdogEnvVars(podSpec, cfg, service)
 
+	// Set the node selector, affinity rules and tolerations of the service (if defined)
+	service.SetScheduling(podSpec)
+
+	// Set the RuntimeClass of the service (if defined)
+	service.SetRuntimeClass(podSpec)
+
+	// Set the feature flags of the service (if defined)
+	addFeatureEnvVars(podSpec, service)
+
+	// Set the secrets of the service (if stored)
+	addSecretsEnvFrom(podSpec, service)
+
+	// Install the dependencies of the service (if defined)
+	addDependencies(podSpec, service)
+
+	// Copy the custom FaaS Supervisor of the service (if defined)
+	addSupervisor(podSpec, cfg, service)
+
+	// Mount the FDL of the job (overridden outputs or temporary credentials) if defined
+	if service.HasJobConfig() {
+		setOutputConfigVolume(podSpec, service)
+	}
+
+	// Add the init containers and the sidecars of the service (if defined)
+	AddContainers(podSpec, service.InitContainers, service.Sidecars)
+
 	if service.EnableSGX {
 		SetSecurityContext(podSpec)
 	}
 
+	// Set the security context of the service (if defined or enforced by the untrusted VOs policy)
+	service.GetSecurityContext(cfg).Apply(podSpec)
+
 	return podSpec, nil
 }
 
@@ -318,6 +652,25 @@ func (service *Service) GetMinIOWebhookARN() string {
 	return fmt.Sprintf("arn:minio:sqs:%s:%s:webhook", service.StorageProviders.MinIO[DefaultProvider].Region, service.Name)
 }
 
+// IsAccessibleBy checks if a user (with its OIDC groups) can access the service: its owner, the additional owners
+// and the members of its VO. The services without owner are shared by all the users
+func (service *Service) IsAccessibleBy(user string, groups []string) bool {
+	if service.Owner == "" || service.Owner == user {
+		return true
+	}
+	for _, owner := range service.Owners {
+		if owner == user {
+			return true
+		}
+	}
+	for _, group := range groups {
+		if service.VO != "" && group == service.VO {
+			return true
+		}
+	}
+	return false
+}
+
 func ConvertEnvVars(vars map[string]string) []v1.EnvVar {
 	envVars := []v1.EnvVar{}
 	for k, v := range vars {
@@ -370,12 +723,9 @@ func createResources(service *Service) (v1.ResourceRequirements, error) {
 		resources.Limits[v1.ResourceMemory] = memory
 	}
 
-	if service.EnableGPU {
-		gpu, err := resource.ParseQuantity("1")
-		if err != nil {
-			return resources, err
-		}
-		resources.Limits["nvidia.com/gpu"] = gpu
+	// GPUs and extended resources (e.g. MIG profiles)
+	for name, quantity := range service.GetExtendedResourceLimits() {
+		resources.Limits[name] = quantity
 	}
 
 	if service.EnableSGX {
@@ -391,13 +741,14 @@ func createResources(service *Service) (v1.ResourceRequirements, error) {
 
 func addWatchdogEnvVars(p *v1.PodSpec, cfg *Config, service *Service) {
 	requiredEnvVars := []v1.EnvVar{
-		// Use FaaS Supervisor to handle requests
+		// Use FaaS Supervisor (or the script in streaming services) to handle requests
 		{
 			Name:  WatchdogProcess,
-			Value: service.GetSupervisorPath(),
+			Value: service.GetWatchdogProcess(),
 		},
 		// Other OpenFaaS Watchdog options
 		// https://github.com/openfaas/classic-watchdog
+		// https://github.com/openfaas/of-watchdog
 		{
 			Name:  "max_inflight",
 			Value: strconv.Itoa(cfg.WatchdogMaxInflight),
@@ -424,6 +775,13 @@ func addWatchdogEnvVars(p *v1.PodSpec, cfg *Config, service *Service) {
 		},
 	}
 
+	if service.Streaming {
+		requiredEnvVars = append(requiredEnvVars, v1.EnvVar{
+			Name:  "mode",
+			Value: "streaming",
+		})
+	}
+
 	for i, cont := range p.Containers {
 		if cont.Name == ContainerName {
 			p.Containers[i].Env = append(p.Containers[i].Env, requiredEnvVars...)
@@ -431,14 +789,119 @@ func addWatchdogEnvVars(p *v1.PodSpec, cfg *Config, service *Service) {
 	}
 }
 
+// GetWatchdogPath returns the path of the watchdog binary used by the service
+func (service *Service) GetWatchdogPath() string {
+	if service.Streaming {
+		return fmt.Sprintf("%s/%s", VolumePath, StreamingWatchdogName)
+	}
+	return fmt.Sprintf("%s/%s", VolumePath, WatchdogName)
+}
+
+// GetWatchdogProcess returns the process forked by the watchdog to handle each request: the FaaS Supervisor or, in
+// streaming services, the user script
+func (service *Service) GetWatchdogProcess() string {
+	if service.Streaming {
+		return fmt.Sprintf("/bin/sh %s/%s", ConfigPath, ScriptFileName)
+	}
+	return service.GetSupervisorPath()
+}
+
 // GetSupervisorPath returns the appropriate supervisor path
 func (service *Service) GetSupervisorPath() string {
+	if service.Supervisor != nil {
+		return fmt.Sprintf("%s/%s", service.getCustomSupervisorDirectory(), SupervisorName)
+	}
 	if service.Alpine {
 		return fmt.Sprintf("%s/%s/%s", VolumePath, AlpineDirectory, SupervisorName)
 	}
 	return fmt.Sprintf("%s/%s", VolumePath, SupervisorName)
 }
 
+// GetInputForObject returns the MinIO input whose path contains the provided object, or nil if there is no match
+func (service *Service) GetInputForObject(bucket string, key string) *StorageIOConfig {
+	if i := service.GetInputIndexForObject(bucket, key); i >= 0 {
+		return &service.Input[i]
+	}
+	return nil
+}
+
+// GetInputIndexForObject returns the index of the MinIO input whose path contains the object, or -1 if there is none
+func (service *Service) GetInputIndexForObject(bucket string, key string) int {
+	for i, in := range service.Input {
+		if provName, _ := in.GetProvider(); provName != MinIOName {
+			continue
+		}
+		if in.MatchesObject(bucket, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ValidateDeadLetterPath checks that the dead-letter path is placed in one of the buckets of the
+// service's inputs or outputs in the default MinIO provider, but not inside any of its inputs
+func (service *Service) ValidateDeadLetterPath() error {
+	if service.DeadLetterPath == "" {
+		return nil
+	}
+
+	bucket, _ := StorageIOConfig{Path: service.DeadLetterPath}.SplitPath()
+	if !service.ownsBucket(bucket) {
+		return fmt.Errorf("the dead_letter_path \"%s\" must be placed in the bucket of one of the service's inputs or outputs in the default MinIO provider", service.DeadLetterPath)
+	}
+
+	for _, in := range service.Input {
+		if provName, provID := in.GetProvider(); provName == MinIOName && provID == DefaultProvider && in.ContainsPath(service.DeadLetterPath) {
+			return fmt.Errorf("the dead_letter_path \"%s\" can not be placed in the input \"%s\"", service.DeadLetterPath, in.Path)
+		}
+	}
+
+	return nil
+}
+
+// ownsBucket checks if the bucket is used by one of the service's inputs or outputs in the default MinIO provider
+func (service *Service) ownsBucket(bucket string) bool {
+	for _, storageIO := range append(append([]StorageIOConfig{}, service.Input...), service.Output...) {
+		provName, provID := storageIO.GetProvider()
+		if provName != MinIOName || provID != DefaultProvider {
+			continue
+		}
+		if ioBucket, _ := storageIO.SplitPath(); ioBucket == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateBatch checks that the service's batches are always delivered (i.e. they have a time window)
+func (service *Service) ValidateBatch() error {
+	if service.Batch.Size < 0 || service.Batch.Window < 0 {
+		return fmt.Errorf("the batch size and window can not be negative")
+	}
+	if service.Batch.Size > 1 && service.Batch.Window == 0 {
+		return fmt.Errorf("the batch window is required to deliver the incomplete batches")
+	}
+	return nil
+}
+
+// ValidateConcurrency checks the concurrency limit and the priority of the service's jobs
+func (service *Service) ValidateConcurrency() error {
+	if service.MaxConcurrentJobs < 0 {
+		return fmt.Errorf("the max_concurrent_jobs can not be negative")
+	}
+	if service.Priority != "" {
+		if errs := validation.IsDNS1123Subdomain(service.Priority); len(errs) > 0 {
+			return fmt.Errorf("the priority \"%s\" is not a valid PriorityClass name: %s", service.Priority, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// HasBatch checks if the service aggregates its input events in batches
+func (service *Service) HasBatch() bool {
+	return service.Batch.Size > 1 || service.Batch.Window > 0
+}
+
 // HasReplicas checks if the service has replicas defined
 func (service *Service) HasReplicas() bool {
 	return len(service.Replicas) > 0
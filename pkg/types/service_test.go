@@ -220,6 +220,9 @@ image_prefetch: false
 synchronous:
   min_scale: 0
   max_scale: 0
+batch:
+  size: 0
+  window: 0
 replicas:
 - type: oscar
   cluster_id: test
@@ -343,6 +346,28 @@ func TestToPodSpec(t *testing.T) {
 	}
 }
 
+func TestToPodSpecStreaming(t *testing.T) {
+	svc := Service{Name: "stream", Image: "image", Streaming: true}
+	podSpec, err := svc.ToPodSpec(&testConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cmd := podSpec.Containers[0].Command; len(cmd) != 1 || cmd[0] != "/oscar/bin/of-watchdog" {
+		t.Errorf("expecting the of-watchdog as command, got %v", cmd)
+	}
+	env := map[string]string{}
+	for _, envVar := range podSpec.Containers[0].Env {
+		env[envVar.Name] = envVar.Value
+	}
+	if env["mode"] != "streaming" {
+		t.Errorf("expecting the streaming mode, got \"%s\"", env["mode"])
+	}
+	if env[WatchdogProcess] != "/bin/sh /oscar/config/script.sh" {
+		t.Errorf("expecting the script as watchdog process, got \"%s\"", env[WatchdogProcess])
+	}
+}
+
 func checkEnvVars(cfg *Config, podSpec *v1.PodSpec) error {
 	var expected string
 	var found = []string{}
@@ -386,3 +411,105 @@ func checkEnvVars(cfg *Config, podSpec *v1.PodSpec) error {
 
 	return nil
 }
+
+func TestValidateDeadLetterPath(t *testing.T) {
+	service := &Service{
+		Input:  []StorageIOConfig{{Provider: "minio", Path: "bucket/in"}, {Provider: "minio.other", Path: "other/in"}},
+		Output: []StorageIOConfig{{Provider: "minio.default", Path: "out"}},
+	}
+
+	scenarios := []struct {
+		name        string
+		path        string
+		returnError bool
+	}{
+		{"not defined", "", false},
+		{"input bucket", "bucket/failed", false},
+		{"output bucket", "out/failed", false},
+		{"inside the input", "bucket/in/failed", true},
+		{"bucket of other provider", "other/failed", true},
+		{"foreign bucket", "foreign/failed", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service.DeadLetterPath = s.path
+			err := service.ValidateDeadLetterPath()
+			if s.returnError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.returnError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		size        int
+		window      int
+		returnError bool
+	}{
+		{"no batch", 0, 0, false},
+		{"single event", 1, 0, false},
+		{"window only", 0, 60, false},
+		{"size and window", 10, 60, false},
+		{"size only", 10, 0, true},
+		{"negative window", 10, -1, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{}
+			service.Batch.Size = s.size
+			service.Batch.Window = s.window
+			err := service.ValidateBatch()
+			if s.returnError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.returnError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		maxConcurrentJobs int
+		priority          string
+		returnError       bool
+	}{
+		{"unlimited", 0, "", false},
+		{"limit and priority", 5, "oscar-high", false},
+		{"negative limit", -1, "", true},
+		{"invalid priority", 0, "High Priority", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{MaxConcurrentJobs: s.maxConcurrentJobs, Priority: s.priority}
+			err := service.ValidateConcurrency()
+			if s.returnError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.returnError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestToPodSpecPriority(t *testing.T) {
+	service := &Service{Name: "testname", Image: "testimage", Priority: "oscar-high"}
+	podSpec, err := service.ToPodSpec(&Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if podSpec.PriorityClassName != "oscar-high" {
+		t.Errorf("expecting the PriorityClass \"oscar-high\", got \"%s\"", podSpec.PriorityClassName)
+	}
+}
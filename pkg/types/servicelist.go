@@ -0,0 +1,152 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxServiceListLimit maximum number of services returned in a page of the service list
+const MaxServiceListLimit = 500
+
+// Sort keys of the service list (prefixed with "-" for descending order)
+const (
+	SortByName  = "name"
+	SortByImage = "image"
+	SortByVO    = "vo"
+)
+
+// ServiceListOptions filtering, sorting and pagination of the service list
+type ServiceListOptions struct {
+	// Limit maximum number of services to return (0 returns all the services)
+	Limit int
+	// Offset number of services (after filtering and sorting) to skip
+	Offset int
+	// Labels label requirements ("key=value" or "key") the services must satisfy
+	Labels []string
+	// VO only list the services of this VO
+	VO string
+	// Sort key to sort the services by (default: "name")
+	Sort string
+}
+
+// Validate checks the options of the service list
+func (opts ServiceListOptions) Validate() error {
+	if opts.Limit < 0 || opts.Limit > MaxServiceListLimit {
+		return fmt.Errorf("the limit must be between 1 and %d", MaxServiceListLimit)
+	}
+	if opts.Offset < 0 {
+		return fmt.Errorf("the offset must be a non-negative integer")
+	}
+	switch strings.TrimPrefix(opts.Sort, "-") {
+	case "", SortByName, SortByImage, SortByVO:
+	default:
+		return fmt.Errorf("invalid sort key \"%s\", it must be \"%s\", \"%s\" or \"%s\"", opts.Sort, SortByName, SortByImage, SortByVO)
+	}
+	for _, req := range opts.Labels {
+		if strings.TrimSpace(strings.SplitN(req, "=", 2)[0]) == "" {
+			return fmt.Errorf("invalid label requirement \"%s\"", req)
+		}
+	}
+	return nil
+}
+
+// Apply filters, sorts and paginates the services. Returns the page of services, the total number
+// of services satisfying the filters and the continue token of the next page (empty if it is the last page)
+func (opts ServiceListOptions) Apply(services []*Service) ([]*Service, int, string) {
+	filtered := []*Service{}
+	for _, service := range services {
+		if opts.Matches(service) {
+			filtered = append(filtered, service)
+		}
+	}
+
+	key := strings.TrimPrefix(opts.Sort, "-")
+	desc := strings.HasPrefix(opts.Sort, "-")
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i].getSortValue(key), filtered[j].getSortValue(key)
+		if a == b {
+			// Keep a deterministic order between pages
+			a, b = filtered[i].Name, filtered[j].Name
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+
+	total := len(filtered)
+	if opts.Offset >= total {
+		return []*Service{}, total, ""
+	}
+	page := filtered[opts.Offset:]
+	next := ""
+	if opts.Limit > 0 && len(page) > opts.Limit {
+		page = page[:opts.Limit]
+		next = MakeServiceListContinue(opts.Offset + opts.Limit)
+	}
+
+	return page, total, next
+}
+
+// Matches checks if a service satisfies the VO and label filters
+func (opts ServiceListOptions) Matches(service *Service) bool {
+	if opts.VO != "" && service.VO != opts.VO {
+		return false
+	}
+	for _, req := range opts.Labels {
+		split := strings.SplitN(req, "=", 2)
+		value, ok := service.Labels[strings.TrimSpace(split[0])]
+		if !ok || (len(split) == 2 && value != strings.TrimSpace(split[1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// getSortValue returns the value of the service field used to sort by key
+func (service *Service) getSortValue(key string) string {
+	switch key {
+	case SortByImage:
+		return service.Image
+	case SortByVO:
+		return service.VO
+	}
+	return service.Name
+}
+
+// MakeServiceListContinue returns the continue token to list the services from the provided offset
+func MakeServiceListContinue(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("offset:%d", offset)))
+}
+
+// ParseServiceListContinue returns the offset of a continue token
+func ParseServiceListContinue(token string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid continue token")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), "offset:"))
+	if err != nil || !strings.HasPrefix(string(decoded), "offset:") || offset < 0 {
+		return 0, fmt.Errorf("invalid continue token")
+	}
+	return offset, nil
+}
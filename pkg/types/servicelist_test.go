@@ -0,0 +1,111 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+)
+
+func TestServiceListOptionsApply(t *testing.T) {
+	services := []*Service{
+		{Name: "c", Image: "img-a", VO: "vo1", Labels: map[string]string{"env": "prod"}},
+		{Name: "a", Image: "img-c", VO: "vo2", Labels: map[string]string{"env": "dev"}},
+		{Name: "b", Image: "img-b", VO: "vo1", Labels: map[string]string{"env": "prod", "team": "x"}},
+		{Name: "d", Image: "img-b", VO: "vo1"},
+	}
+
+	scenarios := []struct {
+		name          string
+		opts          ServiceListOptions
+		expectedNames []string
+		expectedTotal int
+		expectedNext  string
+	}{
+		{"default", ServiceListOptions{}, []string{"a", "b", "c", "d"}, 4, ""},
+		{"descending name", ServiceListOptions{Sort: "-name"}, []string{"d", "c", "b", "a"}, 4, ""},
+		{"image with ties by name", ServiceListOptions{Sort: "image"}, []string{"c", "b", "d", "a"}, 4, ""},
+		{"vo", ServiceListOptions{VO: "vo1"}, []string{"b", "c", "d"}, 3, ""},
+		{"label value", ServiceListOptions{Labels: []string{"env=prod"}}, []string{"b", "c"}, 2, ""},
+		{"label exists", ServiceListOptions{Labels: []string{"team"}}, []string{"b"}, 1, ""},
+		{"first page", ServiceListOptions{Limit: 2}, []string{"a", "b"}, 4, MakeServiceListContinue(2)},
+		{"last page", ServiceListOptions{Limit: 2, Offset: 2}, []string{"c", "d"}, 4, ""},
+		{"offset out of range", ServiceListOptions{Offset: 10}, []string{}, 4, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			page, total, next := s.opts.Apply(services)
+			names := []string{}
+			for _, service := range page {
+				names = append(names, service.Name)
+			}
+			if len(names) != len(s.expectedNames) {
+				t.Fatalf("expecting services %v, got %v", s.expectedNames, names)
+			}
+			for i := range names {
+				if names[i] != s.expectedNames[i] {
+					t.Fatalf("expecting services %v, got %v", s.expectedNames, names)
+				}
+			}
+			if total != s.expectedTotal {
+				t.Errorf("expecting total %d, got %d", s.expectedTotal, total)
+			}
+			if next != s.expectedNext {
+				t.Errorf("expecting continue token \"%s\", got \"%s\"", s.expectedNext, next)
+			}
+		})
+	}
+}
+
+func TestServiceListOptionsValidate(t *testing.T) {
+	scenarios := []struct {
+		name  string
+		opts  ServiceListOptions
+		valid bool
+	}{
+		{"valid", ServiceListOptions{Limit: 10, Sort: "-vo", Labels: []string{"env=prod"}}, true},
+		{"limit too high", ServiceListOptions{Limit: MaxServiceListLimit + 1}, false},
+		{"negative offset", ServiceListOptions{Offset: -1}, false},
+		{"invalid sort", ServiceListOptions{Sort: "memory"}, false},
+		{"invalid label", ServiceListOptions{Labels: []string{"=prod"}}, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := s.opts.Validate()
+			if s.valid && err != nil {
+				t.Errorf("expecting valid options, got: %v", err)
+			}
+			if !s.valid && err == nil {
+				t.Error("expecting invalid options")
+			}
+		})
+	}
+}
+
+func TestParseServiceListContinue(t *testing.T) {
+	offset, err := ParseServiceListContinue(MakeServiceListContinue(20))
+	if err != nil || offset != 20 {
+		t.Errorf("expecting offset 20, got %d (%v)", offset, err)
+	}
+
+	for _, token := range []string{"20", "!!", MakeServiceListContinue(-1)} {
+		if _, err := ParseServiceListContinue(token); err == nil {
+			t.Errorf("expecting invalid token \"%s\"", token)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// ServiceTokenPrefix prefix of the named access tokens of the services, distinguishing them from the OIDC tokens
+	ServiceTokenPrefix = "oscar-st-"
+
+	// ServiceTokenScopeInvoke scope to invoke the service (/run, /job, /run-async and its aliases)
+	ServiceTokenScopeInvoke = "invoke"
+	// ServiceTokenScopeLogs scope to read the jobs and logs of the service
+	ServiceTokenScopeLogs = "logs"
+	// ServiceTokenScopeManage scope to manage the service, its jobs and logs (including the other scopes)
+	ServiceTokenScopeManage = "manage"
+)
+
+// serviceTokenRegex names allowed for the access tokens (RFC 1123 labels)
+var serviceTokenRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ServiceAccessToken named access token of a service, with the scopes granted. Only the hash of the token is stored
+type ServiceAccessToken struct {
+	// Name name of the token (lowercase alphanumeric characters or "-", up to 63 characters)
+	Name string `json:"name"`
+	// Scopes scopes granted to the token ("invoke", "logs" or "manage")
+	Scopes []string `json:"scopes"`
+	// Hash SHA-256 hash of the token
+	Hash string `json:"hash,omitempty"`
+	// Token value of the token, only returned on its creation and rotation
+	Token string `json:"token,omitempty"`
+	// CreatedBy user that created the token
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// RotatedAt time of the last rotation of the token
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+}
+
+// ServiceAccessTokenRequest body of the requests to create an access token of a service
+type ServiceAccessTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// Validate checks the name and the scopes of the token
+func (req *ServiceAccessTokenRequest) Validate() error {
+	if len(req.Name) > 63 || !serviceTokenRegex.MatchString(req.Name) {
+		return fmt.Errorf("the name \"%s\" is not valid, it must consist of up to 63 lowercase alphanumeric characters or '-' and start and end with an alphanumeric character", req.Name)
+	}
+	if len(req.Scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if scope != ServiceTokenScopeInvoke && scope != ServiceTokenScopeLogs && scope != ServiceTokenScopeManage {
+			return fmt.Errorf("the scope \"%s\" is not valid, it must be \"%s\", \"%s\" or \"%s\"", scope, ServiceTokenScopeInvoke, ServiceTokenScopeLogs, ServiceTokenScopeManage)
+		}
+	}
+	return nil
+}
+
+// HashServiceToken returns the hash of an access token stored in the service's definition
+func HashServiceToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// HasScope checks if the token is granted a scope ("manage" grants all of them)
+func (t ServiceAccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ServiceTokenScopeManage {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAccessToken returns the index of the access token of the service with that name, -1 if it does not exist
+func (service *Service) GetAccessToken(name string) int {
+	for i, t := range service.AccessTokens {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// CheckToken checks if a token is granted a scope of the service: the service's token grants the "invoke" scope and
+// the named access tokens their scopes
+func (service *Service) CheckToken(token string, scope string) bool {
+	if token == "" {
+		return false
+	}
+	if scope == ServiceTokenScopeInvoke && service.Token != "" && token == service.Token {
+		return true
+	}
+	if !strings.HasPrefix(token, ServiceTokenPrefix) {
+		return false
+	}
+	hash := []byte(HashServiceToken(token))
+	for _, t := range service.AccessTokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			return t.HasScope(scope)
+		}
+	}
+	return false
+}
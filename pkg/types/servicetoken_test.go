@@ -0,0 +1,72 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "testing"
+
+func TestServiceAccessTokenRequestValidate(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		req         ServiceAccessTokenRequest
+		returnError bool
+	}{
+		{"valid", ServiceAccessTokenRequest{Name: "ci-logs", Scopes: []string{ServiceTokenScopeLogs, ServiceTokenScopeInvoke}}, false},
+		{"invalid name", ServiceAccessTokenRequest{Name: "CI Logs", Scopes: []string{ServiceTokenScopeLogs}}, true},
+		{"no scopes", ServiceAccessTokenRequest{Name: "ci-logs"}, true},
+		{"invalid scope", ServiceAccessTokenRequest{Name: "ci-logs", Scopes: []string{"admin"}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.req.Validate(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestServiceCheckToken(t *testing.T) {
+	manage := ServiceTokenPrefix + "manage"
+	service := &Service{
+		Token: "token",
+		AccessTokens: []ServiceAccessToken{
+			{Name: "manage", Scopes: []string{ServiceTokenScopeManage}, Hash: HashServiceToken(manage)},
+		},
+	}
+
+	scenarios := []struct {
+		name     string
+		token    string
+		scope    string
+		expected bool
+	}{
+		{"service token invoke", "token", ServiceTokenScopeInvoke, true},
+		{"service token logs", "token", ServiceTokenScopeLogs, false},
+		{"manage token logs", manage, ServiceTokenScopeLogs, true},
+		{"manage token invoke", manage, ServiceTokenScopeInvoke, true},
+		{"unknown token", ServiceTokenPrefix + "other", ServiceTokenScopeInvoke, false},
+		{"empty token", "", ServiceTokenScopeInvoke, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if got := service.CheckToken(s.token, s.scope); got != s.expected {
+				t.Errorf("expecting %v, got %v", s.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// Types of the events of the service watch stream
+const (
+	// ServiceAddedWatchEvent service existing when the watch starts
+	ServiceAddedWatchEvent = "added"
+	// ServiceSyncedWatchEvent end of the services existing when the watch starts
+	ServiceSyncedWatchEvent  = "synced"
+	ServiceCreatedWatchEvent = "created"
+	ServiceUpdatedWatchEvent = "updated"
+	// ServiceDeletedWatchEvent service deleted or no longer satisfying the filters of the watch
+	ServiceDeletedWatchEvent = "deleted"
+)
+
+// ServiceWatchEvent change of a service definition, pushed to the clients of /system/services/watch
+type ServiceWatchEvent struct {
+	Type string `json:"type"`
+	// Name of the service (empty in the "synced" event)
+	Name string `json:"name,omitempty"`
+	// Service definition of the service (except in the "deleted" and "synced" events)
+	Service *Service  `json:"service,omitempty"`
+	Time    time.Time `json:"time"`
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// Results of an event simulation
+const (
+	// SimulationDiscarded the event does not satisfy the filters of the input (or the input is disabled)
+	SimulationDiscarded = "discarded"
+	// SimulationWaiting the event is waiting for the rest of its file set
+	SimulationWaiting = "waiting"
+	// SimulationBatched the event would be aggregated in the service's batch
+	SimulationBatched = "batched"
+	// SimulationDelivered the event would be delivered to a job
+	SimulationDelivered = "delivered"
+)
+
+// EventSimulationRequest synthetic storage event to simulate the trigger of a service.
+// Either the Event (as sent by MinIO) or the Bucket and Key of an uploaded object must be provided
+type EventSimulationRequest struct {
+	// Event raw event as sent to the service's webhook
+	// Optional.
+	Event json.RawMessage `json:"event,omitempty"`
+	// Bucket of the uploaded object
+	// Optional.
+	Bucket string `json:"bucket,omitempty"`
+	// Key of the uploaded object
+	// Optional.
+	Key string `json:"key,omitempty"`
+	// Size of the uploaded object in bytes
+	// Optional. (default: 0)
+	Size int64 `json:"size,omitempty"`
+	// ContentType of the uploaded object
+	// Optional.
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// EventSimulation result of running the trigger pipeline of a service for an event without creating the job
+type EventSimulation struct {
+	// Status result of the simulation ("discarded", "waiting", "batched" or "delivered")
+	Status string `json:"status"`
+	// Reason details of the status (e.g. the filter not satisfied by the object)
+	Reason string `json:"reason,omitempty"`
+	// Input service input matching the event's object
+	Input *StorageIOConfig `json:"input,omitempty"`
+	// Tiers names of the placement tiers where the job would be placed (in order of preference)
+	Tiers []string `json:"tiers,omitempty"`
+	// Event value passed to the job (i.e. the EVENT variable of the FaaS Supervisor)
+	Event string `json:"event,omitempty"`
+	// Job definition of the job that would be created
+	Job *batchv1.Job `json:"job,omitempty"`
+}
+
+// GetEvent returns the event to be simulated
+func (req EventSimulationRequest) GetEvent() ([]byte, error) {
+	if len(req.Event) > 0 {
+		return req.Event, nil
+	}
+	if req.Bucket == "" || req.Key == "" {
+		return nil, errors.New("the event or the bucket and key of the object are required")
+	}
+
+	object := MinIOEventObject{Key: req.Key, Size: req.Size, ContentType: req.ContentType}
+	return json.Marshal(NewMinIOEvent(req.Bucket, object, time.Now()))
+}
@@ -0,0 +1,83 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// ServiceStatus represents the runtime status of a service
+type ServiceStatus struct {
+	// Deployments readiness of the deployments backing synchronous and exposed services
+	Deployments []DeploymentStatus `json:"deployments"`
+	// PendingJobs number of jobs waiting to be scheduled (queue depth)
+	PendingJobs int `json:"pending_jobs"`
+	// RunningJobs number of jobs currently running
+	RunningJobs int `json:"running_jobs"`
+	// Triggers health of the trigger path (webhook and bucket notifications) of each input
+	Triggers []TriggerStatus `json:"triggers"`
+	// Webhook registration of the service's webhook in MinIO (only for services with MinIO inputs)
+	Webhook *WebhookStatus `json:"webhook,omitempty"`
+	// SupervisorVersion version of the FaaS Supervisor used by the service (empty if unknown)
+	SupervisorVersion string `json:"supervisor_version,omitempty"`
+	// SupervisorImage image of the custom FaaS Supervisor of the service (if defined)
+	SupervisorImage string `json:"supervisor_image,omitempty"`
+}
+
+// DeploymentStatus details the readiness of a deployment
+type DeploymentStatus struct {
+	Name          string `json:"name"`
+	Ready         bool   `json:"ready"`
+	Replicas      int32  `json:"replicas"`
+	ReadyReplicas int32  `json:"ready_replicas"`
+}
+
+// WebhookStatus details the registration of the service's webhook in MinIO
+type WebhookStatus struct {
+	Registered bool   `json:"registered"`
+	Message    string `json:"message,omitempty"`
+}
+
+// TriggerStatus details the health of an input trigger
+type TriggerStatus struct {
+	Provider string `json:"storage_provider"`
+	Path     string `json:"path"`
+	Enabled  bool   `json:"enabled"`
+	Healthy  bool   `json:"healthy"`
+	Message  string `json:"message,omitempty"`
+}
+
+// ServiceHealth summary of the health of a service, aggregating its runtime status, the reachability of its
+// storage providers and the outcome of its last jobs
+type ServiceHealth struct {
+	Service string `json:"service"`
+	// Healthy true if no issues have been found
+	Healthy bool `json:"healthy"`
+	// Issues problems found by the checks
+	Issues []string `json:"issues"`
+	ServiceStatus
+	// StorageProviders reachability of the storage providers of the inputs and outputs
+	StorageProviders []StorageProviderStatus `json:"storage_providers"`
+	// LastJobs last jobs of the service, newest first
+	LastJobs []JobSummary `json:"last_jobs"`
+	// JobOutcomes number of the last jobs by status
+	JobOutcomes map[string]int `json:"job_outcomes"`
+}
+
+// StorageProviderStatus details the reachability of a storage provider, checked by accessing the paths of the
+// service's inputs and outputs
+type StorageProviderStatus struct {
+	Provider  string `json:"storage_provider"`
+	Reachable bool   `json:"reachable"`
+	Message   string `json:"message,omitempty"`
+}
@@ -28,6 +28,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/grycap/cdmi-client-go"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const (
@@ -58,8 +60,47 @@ type StorageIOConfig struct {
 	Path     string   `json:"path"`
 	Suffix   []string `json:"suffix,omitempty"`
 	Prefix   []string `json:"prefix,omitempty"`
+	// MaxSize maximum size of the objects that trigger the service following the kubernetes format (e.g. "100Mi")
+	// Only applies to inputs. Optional. (default: "" [Unlimited])
+	MaxSize string `json:"max_size,omitempty"`
+	// ContentTypes list of content types allowed to trigger the service (wildcards like "image/*" are supported)
+	// Only applies to inputs. Optional. (default: [] [All])
+	ContentTypes []string `json:"content_types,omitempty"`
+	// ErrorPath path (in the same provider) where the objects discarded by the filters are copied
+	// Only applies to inputs. Optional. (default: "" [Discarded objects are not copied])
+	ErrorPath string `json:"error_path,omitempty"`
+	// FileSet list of patterns (relative to the input path) defining a set of related files that must
+	// exist before triggering the service (e.g. ["{name}.tif", "{name}.json"]). Each pattern must
+	// contain the "{name}" placeholder once. Only a job with all the keys of the set is created
+	// Only applies to inputs. Optional. (default: [] [Each object triggers the service])
+	FileSet []string `json:"file_set,omitempty"`
+	// Disabled pause the trigger of the input (the bucket notification is not enabled)
+	// Only applies to inputs. Optional. (default: false)
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxEventsPerMinute maximum number of jobs started per minute from the events of the input
+	// Only applies to inputs. Optional. (default: 0 [Unlimited])
+	MaxEventsPerMinute int `json:"max_events_per_minute,omitempty"`
+	// MaxInFlight maximum number of unfinished jobs created from the events of the input
+	// Only applies to inputs. Optional. (default: 0 [Unlimited])
+	MaxInFlight int `json:"max_in_flight,omitempty"`
+	// LimitPolicy action on the events exceeding the max_events_per_minute or max_in_flight limits:
+	// "drop" to discard them or "queue" to queue their jobs until the limits allow running them
+	// Only applies to inputs. Optional. (default: "drop")
+	LimitPolicy string `json:"limit_policy,omitempty"`
 }
 
+// Policies of the events exceeding the limits of an input
+const (
+	LimitPolicyDrop  = "drop"
+	LimitPolicyQueue = "queue"
+)
+
+// ServicePlaceholder placeholder of the default output path replaced by the service's name
+const ServicePlaceholder = "{service}"
+
+// FileSetPlaceholder placeholder of the file set patterns replaced by the common name of the set
+const FileSetPlaceholder = "{name}"
+
 // StorageProviders stores the credentials of all supported storage providers
 type StorageProviders struct {
 	S3      map[string]*S3Provider      `json:"s3,omitempty"`
@@ -82,6 +123,8 @@ type MinIOProvider struct {
 	AccessKey string `json:"access_key"`
 	SecretKey string `json:"secret_key"`
 	Region    string `json:"region"`
+	// SessionToken token of the temporary credentials (only set in the FDL of the jobs)
+	SessionToken string `json:"session_token,omitempty"`
 }
 
 // OnedataProvider stores the credentials of the Onedata storage provider
@@ -98,6 +141,74 @@ type WebDavProvider struct {
 	Password string `json:"password"`
 }
 
+// GetProvider returns the provider's name and identifier of the StorageIOConfig
+func (storageIO StorageIOConfig) GetProvider() (name string, id string) {
+	provSlice := strings.SplitN(strings.TrimSpace(storageIO.Provider), ProviderSeparator, 2)
+	if len(provSlice) == 1 {
+		return strings.ToLower(provSlice[0]), DefaultProvider
+	}
+	return strings.ToLower(provSlice[0]), provSlice[1]
+}
+
+// SplitPath returns the bucket and the folder (if any) of the StorageIOConfig's path
+func (storageIO StorageIOConfig) SplitPath() (bucket string, folder string) {
+	path := strings.Trim(storageIO.Path, " /")
+	splitPath := strings.SplitN(path, "/", 2)
+	if len(splitPath) == 2 {
+		return splitPath[0], splitPath[1]
+	}
+	return splitPath[0], ""
+}
+
+// MatchesObject checks if an object (bucket and key) is placed in the StorageIOConfig's path
+func (storageIO StorageIOConfig) MatchesObject(bucket string, key string) bool {
+	inBucket, inFolder := storageIO.SplitPath()
+	if inBucket != bucket {
+		return false
+	}
+	return inFolder == "" || strings.HasPrefix(key, inFolder+"/")
+}
+
+// ContainsPath checks if a path ("bucket/folder") is placed in the StorageIOConfig's path
+func (storageIO StorageIOConfig) ContainsPath(path string) bool {
+	bucket, folder := StorageIOConfig{Path: path}.SplitPath()
+	inBucket, inFolder := storageIO.SplitPath()
+	if inBucket != bucket {
+		return false
+	}
+	return inFolder == "" || folder == inFolder || strings.HasPrefix(folder, inFolder+"/")
+}
+
+// CheckObjectFilters checks if an object satisfies the size and content type filters of the StorageIOConfig
+func (storageIO StorageIOConfig) CheckObjectFilters(size int64, contentType string) error {
+	if storageIO.MaxSize != "" {
+		maxSize, err := resource.ParseQuantity(storageIO.MaxSize)
+		if err != nil {
+			return fmt.Errorf("invalid max_size \"%s\": %v", storageIO.MaxSize, err)
+		}
+		if size > maxSize.Value() {
+			return fmt.Errorf("the object size (%d bytes) exceeds the max_size \"%s\"", size, storageIO.MaxSize)
+		}
+	}
+
+	if len(storageIO.ContentTypes) > 0 {
+		// Ignore content type parameters (e.g. "text/plain; charset=utf-8")
+		mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+		for _, allowed := range storageIO.ContentTypes {
+			allowed = strings.ToLower(strings.TrimSpace(allowed))
+			if allowed == mediaType || allowed == "*/*" {
+				return nil
+			}
+			if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+				return nil
+			}
+		}
+		return fmt.Errorf("the object content type \"%s\" is not allowed", contentType)
+	}
+
+	return nil
+}
+
 // GetS3Client creates a new S3 Client from a S3Provider
 func (s3Provider S3Provider) GetS3Client() *s3.S3 {
 	s3Config := &aws.Config{
@@ -106,14 +217,16 @@ func (s3Provider S3Provider) GetS3Client() *s3.S3 {
 	}
 
 	s3Session, _ := session.NewSession(s3Config)
+	s3Client := s3.New(s3Session)
+	chaos.AddS3Hooks(s3Client)
 
-	return s3.New(s3Session)
+	return s3Client
 }
 
 // GetS3Client creates a new S3 Client from a MinIOProvider
 func (minIOProvider MinIOProvider) GetS3Client() *s3.S3 {
 	s3MinIOConfig := &aws.Config{
-		Credentials:      credentials.NewStaticCredentials(minIOProvider.AccessKey, minIOProvider.SecretKey, ""),
+		Credentials:      credentials.NewStaticCredentials(minIOProvider.AccessKey, minIOProvider.SecretKey, minIOProvider.SessionToken),
 		Endpoint:         aws.String(minIOProvider.Endpoint),
 		Region:           aws.String(minIOProvider.Region),
 		S3ForcePathStyle: aws.Bool(true),
@@ -128,8 +241,10 @@ func (minIOProvider MinIOProvider) GetS3Client() *s3.S3 {
 	}
 
 	minIOSession, _ := session.NewSession(s3MinIOConfig)
+	s3Client := s3.New(minIOSession)
+	chaos.AddS3Hooks(s3Client)
 
-	return s3.New(minIOSession)
+	return s3Client
 }
 
 // GetCDMIClient creates a new CDMI Client from a OnedataProvider
@@ -140,3 +255,77 @@ func (onedataProvider OnedataProvider) GetCDMIClient() *cdmi.Client {
 
 	return cdmi.New(opHostCDMI, onedataProvider.Token, true)
 }
+
+// ValidateFileSet checks that all the file set patterns contain the placeholder once
+func (storageIO StorageIOConfig) ValidateFileSet() error {
+	for _, pattern := range storageIO.FileSet {
+		if strings.Count(pattern, FileSetPlaceholder) != 1 {
+			return fmt.Errorf("the file_set pattern \"%s\" must contain the placeholder \"%s\" once", pattern, FileSetPlaceholder)
+		}
+	}
+	return nil
+}
+
+// HasLimits checks if the StorageIOConfig limits the rate or the concurrency of its jobs
+func (storageIO StorageIOConfig) HasLimits() bool {
+	return storageIO.MaxEventsPerMinute > 0 || storageIO.MaxInFlight > 0
+}
+
+// GetLimitPolicy returns the policy of the events exceeding the limits of the StorageIOConfig ("drop" by default)
+func (storageIO StorageIOConfig) GetLimitPolicy() string {
+	if storageIO.LimitPolicy == "" {
+		return LimitPolicyDrop
+	}
+	return storageIO.LimitPolicy
+}
+
+// ValidateLimits checks the rate and concurrency limits of the StorageIOConfig
+func (storageIO StorageIOConfig) ValidateLimits() error {
+	if storageIO.MaxEventsPerMinute < 0 {
+		return fmt.Errorf("the max_events_per_minute of the input \"%s\" can not be negative", storageIO.Path)
+	}
+	if storageIO.MaxInFlight < 0 {
+		return fmt.Errorf("the max_in_flight of the input \"%s\" can not be negative", storageIO.Path)
+	}
+	switch storageIO.LimitPolicy {
+	case "", LimitPolicyDrop, LimitPolicyQueue:
+	default:
+		return fmt.Errorf("the limit_policy \"%s\" of the input \"%s\" is not valid (\"%s\" or \"%s\")", storageIO.LimitPolicy, storageIO.Path, LimitPolicyDrop, LimitPolicyQueue)
+	}
+	return nil
+}
+
+// MatchFileSet returns the common name of the file set and the keys of all its members
+// if the object key matches one of the file set patterns
+func (storageIO StorageIOConfig) MatchFileSet(key string) (name string, keys []string, ok bool) {
+	_, folder := storageIO.SplitPath()
+	relKey := key
+	if folder != "" {
+		relKey = strings.TrimPrefix(key, folder+"/")
+	}
+
+	for _, pattern := range storageIO.FileSet {
+		split := strings.SplitN(pattern, FileSetPlaceholder, 2)
+		if len(split) != 2 {
+			continue
+		}
+		prefix, suffix := split[0], split[1]
+		if len(relKey) > len(prefix)+len(suffix) && strings.HasPrefix(relKey, prefix) && strings.HasSuffix(relKey, suffix) {
+			name = relKey[len(prefix) : len(relKey)-len(suffix)]
+			break
+		}
+	}
+	if name == "" {
+		return "", nil, false
+	}
+
+	for _, pattern := range storageIO.FileSet {
+		memberKey := strings.Replace(pattern, FileSetPlaceholder, name, 1)
+		if folder != "" {
+			memberKey = fmt.Sprintf("%s/%s", folder, memberKey)
+		}
+		keys = append(keys, memberKey)
+	}
+
+	return name, keys, true
+}
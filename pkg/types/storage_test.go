@@ -19,6 +19,7 @@ package types
 import (
 	"fmt"
 	"net/http"
+	"reflect"
 	"testing"
 )
 
@@ -117,3 +118,143 @@ func TestGetCDMIClient(t *testing.T) {
 		t.Errorf("expected Oneprovider host: %s, got: %s", onedataProvider.OneproviderHost, client.Endpoint)
 	}
 }
+
+func TestMatchesObject(t *testing.T) {
+	in := StorageIOConfig{Provider: "minio", Path: "/bucket/folder/"}
+
+	scenarios := []struct {
+		name     string
+		bucket   string
+		key      string
+		expected bool
+	}{
+		{"same folder", "bucket", "folder/file.txt", true},
+		{"subfolder", "bucket", "folder/sub/file.txt", true},
+		{"different folder", "bucket", "folder2/file.txt", false},
+		{"different bucket", "other", "folder/file.txt", false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if res := in.MatchesObject(s.bucket, s.key); res != s.expected {
+				t.Errorf("expected %v, got %v", s.expected, res)
+			}
+		})
+	}
+}
+
+func TestContainsPath(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		in       string
+		path     string
+		expected bool
+	}{
+		{"bucket-wide input", "bucket", "bucket/errors", true},
+		{"same folder", "bucket/in", "/bucket/in/", true},
+		{"subfolder", "bucket/in", "bucket/in/errors", true},
+		{"sibling folder", "bucket/in", "bucket/errors", false},
+		{"folder with same prefix", "bucket/in", "bucket/in-errors", false},
+		{"parent folder", "bucket/in", "bucket", false},
+		{"different bucket", "bucket", "errors", false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if res := (StorageIOConfig{Path: s.in}).ContainsPath(s.path); res != s.expected {
+				t.Errorf("expected %v, got %v", s.expected, res)
+			}
+		})
+	}
+}
+
+func TestCheckObjectFilters(t *testing.T) {
+	in := StorageIOConfig{
+		MaxSize:      "1Ki",
+		ContentTypes: []string{"image/*", "application/json"},
+	}
+
+	scenarios := []struct {
+		name        string
+		size        int64
+		contentType string
+		returnError bool
+	}{
+		{"valid wildcard", 100, "image/png", false},
+		{"valid with parameters", 100, "application/json; charset=utf-8", false},
+		{"too big", 2048, "image/png", true},
+		{"content type not allowed", 100, "video/mp4", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := in.CheckObjectFilters(s.size, s.contentType)
+			if s.returnError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.returnError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMatchFileSet(t *testing.T) {
+	in := StorageIOConfig{Provider: "minio", Path: "/bucket/folder/", FileSet: []string{"{name}.tif", "{name}.json"}}
+
+	scenarios := []struct {
+		name         string
+		key          string
+		expectedName string
+		expectedKeys []string
+	}{
+		{"image", "folder/scene1.tif", "scene1", []string{"folder/scene1.tif", "folder/scene1.json"}},
+		{"sidecar", "folder/scene1.json", "scene1", []string{"folder/scene1.tif", "folder/scene1.json"}},
+		{"not member", "folder/scene1.txt", "", nil},
+		{"empty name", "folder/.tif", "", nil},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			name, keys, ok := in.MatchFileSet(s.key)
+			if ok != (s.expectedName != "") {
+				t.Fatalf("expected match %v, got %v", s.expectedName != "", ok)
+			}
+			if name != s.expectedName {
+				t.Errorf("expected name \"%s\", got \"%s\"", s.expectedName, name)
+			}
+			if !reflect.DeepEqual(keys, s.expectedKeys) {
+				t.Errorf("expected keys %v, got %v", s.expectedKeys, keys)
+			}
+		})
+	}
+
+	if err := (StorageIOConfig{FileSet: []string{"{name}.tif", "metadata.json"}}).ValidateFileSet(); err == nil {
+		t.Error("expected error validating a pattern without placeholder")
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		in          StorageIOConfig
+		returnError bool
+	}{
+		{"no limits", StorageIOConfig{}, false},
+		{"queue policy", StorageIOConfig{MaxEventsPerMinute: 10, MaxInFlight: 2, LimitPolicy: LimitPolicyQueue}, false},
+		{"negative limit", StorageIOConfig{MaxInFlight: -1}, true},
+		{"invalid policy", StorageIOConfig{MaxInFlight: 1, LimitPolicy: "wait"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := s.in.ValidateLimits()
+			if s.returnError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !s.returnError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
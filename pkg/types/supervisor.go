@@ -0,0 +1,131 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// SupervisorContainerName name of the init container copying the service's FaaS Supervisor to the OSCAR PVC
+	SupervisorContainerName = "oscar-supervisor"
+
+	// SupervisorsDirectory name of the directory of the OSCAR PVC where the services' FaaS Supervisors are cached
+	SupervisorsDirectory = "supervisors"
+)
+
+var (
+	// Image repositories (with optional registry and port), without tag or digest
+	supervisorImageRegexp = regexp.MustCompile(`^[a-z0-9]+([._:/-][a-z0-9]+)*$`)
+	// Image tags
+	supervisorVersionRegexp = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+)
+
+// SupervisorConfig FaaS Supervisor build used by a service instead of the one installed in the cluster, to pin or
+// test a release without upgrading the whole cluster
+type SupervisorConfig struct {
+	// Image repository of the image providing the FaaS Supervisor binary, which must be in the cluster's
+	// allow-list (SUPERVISOR_IMAGES)
+	// Optional. (default: SUPERVISOR_IMAGE)
+	Image string `json:"image,omitempty"`
+	// Version tag of the image
+	Version string `json:"version"`
+}
+
+// GetImage returns the reference of the image providing the FaaS Supervisor binary
+func (supervisor *SupervisorConfig) GetImage() string {
+	return supervisor.Image + ":" + supervisor.Version
+}
+
+// IsAllowed returns true if the image is included in the allow-list, whose items are image repositories
+// (allowing all their versions) or references with a tag (allowing only that version)
+func (supervisor *SupervisorConfig) IsAllowed(allowed []string) bool {
+	for _, image := range allowed {
+		if image == supervisor.Image || image == supervisor.GetImage() {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSupervisor checks the custom FaaS Supervisor of the service (if defined)
+func (service *Service) ValidateSupervisor() error {
+	if service.Supervisor == nil {
+		return nil
+	}
+	if service.Supervisor.Version == "" {
+		return errors.New("the supervisor version is required")
+	}
+	if !supervisorVersionRegexp.MatchString(service.Supervisor.Version) {
+		return fmt.Errorf("the supervisor version \"%s\" is not valid", service.Supervisor.Version)
+	}
+	if service.Supervisor.Image != "" && !supervisorImageRegexp.MatchString(service.Supervisor.Image) {
+		return fmt.Errorf("the supervisor image \"%s\" is not valid, it must not include the tag (set in version)", service.Supervisor.Image)
+	}
+	return nil
+}
+
+// GetSupervisorVersion returns the version of the FaaS Supervisor effectively used by the service: the custom one
+// or, if it is not defined, the one installed in the cluster (unknown if SUPERVISOR_VERSION is not set)
+func (service *Service) GetSupervisorVersion(cfg *Config) string {
+	if service.Supervisor != nil {
+		return service.Supervisor.Version
+	}
+	return cfg.SupervisorVersion
+}
+
+// getCustomSupervisorDirectory returns the directory of the OSCAR PVC where the custom FaaS Supervisor of the
+// service is copied, identified by the hash of its image so it is shared by the services using the same build
+func (service *Service) getCustomSupervisorDirectory() string {
+	hash := sha256.Sum256([]byte(service.Supervisor.GetImage()))
+	return fmt.Sprintf("%s/%s/%s", VolumePath, SupervisorsDirectory, hex.EncodeToString(hash[:])[:16])
+}
+
+// addSupervisor adds the init container copying the custom FaaS Supervisor of the service to the OSCAR PVC
+func addSupervisor(p *v1.PodSpec, cfg *Config, service *Service) {
+	if service.Supervisor == nil {
+		return
+	}
+	dir := service.getCustomSupervisorDirectory()
+	// The binary is copied through a temporary file, so concurrent pods never run a partial copy
+	script := strings.Join([]string{
+		fmt.Sprintf("[ -x %s/%s ] && exit 0", dir, SupervisorName),
+		fmt.Sprintf("mkdir -p %s", dir),
+		fmt.Sprintf("cp %s %s/%s.$$", cfg.SupervisorBinaryPath, dir, SupervisorName),
+		fmt.Sprintf("chmod +x %s/%s.$$", dir, SupervisorName),
+		fmt.Sprintf("mv %s/%s.$$ %s/%s", dir, SupervisorName, dir, SupervisorName),
+	}, " && ")
+
+	p.InitContainers = append(p.InitContainers, v1.Container{
+		Name:    SupervisorContainerName,
+		Image:   service.Supervisor.GetImage(),
+		Command: []string{"/bin/sh", "-c", script},
+		VolumeMounts: []v1.VolumeMount{
+			{
+				Name:      VolumeName,
+				MountPath: VolumePath,
+			},
+		},
+	})
+}
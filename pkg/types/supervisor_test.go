@@ -0,0 +1,102 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSupervisor(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		supervisor  *SupervisorConfig
+		returnError bool
+	}{
+		{"no supervisor", nil, false},
+		{"valid", &SupervisorConfig{Image: "registry.example.com:5000/grycap/faas-supervisor", Version: "1.5.9-beta1"}, false},
+		{"default image", &SupervisorConfig{Version: "1.5.9"}, false},
+		{"missing version", &SupervisorConfig{Image: "ghcr.io/grycap/faas-supervisor"}, true},
+		{"invalid version", &SupervisorConfig{Version: "1.5.9; rm -rf /"}, true},
+		{"image with digest", &SupervisorConfig{Image: "ghcr.io/grycap/faas-supervisor@sha256:abc", Version: "1.5.9"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{Supervisor: s.supervisor}
+			if err := service.ValidateSupervisor(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestSupervisorIsAllowed(t *testing.T) {
+	allowed := []string{"ghcr.io/grycap/faas-supervisor", "registry.example.com/supervisor:1.6.0"}
+	scenarios := []struct {
+		supervisor SupervisorConfig
+		expected   bool
+	}{
+		{SupervisorConfig{Image: "ghcr.io/grycap/faas-supervisor", Version: "1.5.9"}, true},
+		{SupervisorConfig{Image: "registry.example.com/supervisor", Version: "1.6.0"}, true},
+		{SupervisorConfig{Image: "registry.example.com/supervisor", Version: "1.6.1"}, false},
+		{SupervisorConfig{Image: "docker.io/evil/supervisor", Version: "1.5.9"}, false},
+	}
+
+	for _, s := range scenarios {
+		if s.supervisor.IsAllowed(allowed) != s.expected {
+			t.Errorf("expecting %s allowed %v", s.supervisor.GetImage(), s.expected)
+		}
+	}
+}
+
+func TestToPodSpecSupervisor(t *testing.T) {
+	cfg := &Config{SupervisorBinaryPath: "/supervisor", SupervisorVersion: "1.5.8"}
+	service := &Service{
+		Name:       "testname",
+		Image:      "ubuntu",
+		Supervisor: &SupervisorConfig{Image: "ghcr.io/grycap/faas-supervisor", Version: "1.5.9"},
+	}
+
+	podSpec, err := service.ToPodSpec(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(podSpec.InitContainers) != 1 || podSpec.InitContainers[0].Name != SupervisorContainerName {
+		t.Fatalf("expecting the init container \"%s\", got %v", SupervisorContainerName, podSpec.InitContainers)
+	}
+	if podSpec.InitContainers[0].Image != "ghcr.io/grycap/faas-supervisor:1.5.9" {
+		t.Errorf("unexpected supervisor image %s", podSpec.InitContainers[0].Image)
+	}
+	path := service.GetSupervisorPath()
+	if !strings.HasPrefix(path, VolumePath+"/"+SupervisorsDirectory+"/") || !strings.Contains(podSpec.InitContainers[0].Command[2], "cp /supervisor ") {
+		t.Errorf("unexpected supervisor path \"%s\" or script:\n%s", path, podSpec.InitContainers[0].Command[2])
+	}
+	for _, env := range podSpec.Containers[0].Env {
+		if env.Name == WatchdogProcess && env.Value != path {
+			t.Errorf("expecting the watchdog to fork %s, got %s", path, env.Value)
+		}
+	}
+
+	if service.GetSupervisorVersion(cfg) != "1.5.9" {
+		t.Errorf("expecting the custom supervisor version, got %s", service.GetSupervisorVersion(cfg))
+	}
+	if (&Service{}).GetSupervisorVersion(cfg) != "1.5.8" {
+		t.Error("expecting the cluster's supervisor version")
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultContainerConcurrency simultaneous requests per replica of the synchronous services, to avoid parallel
+	// invocations in the same container
+	defaultContainerConcurrency = 1
+	// maxScaleDownDelay maximum scale-down delay supported by Knative
+	maxScaleDownDelay = time.Hour
+)
+
+// ValidateSynchronous checks the autoscaling settings of the synchronous invocations (Knative)
+func (service *Service) ValidateSynchronous() error {
+	sync := service.Synchronous
+	if sync.MinScale < 0 || sync.MaxScale < 0 {
+		return fmt.Errorf("the synchronous min_scale and max_scale can not be negative")
+	}
+	if sync.MaxScale > 0 && sync.MinScale > sync.MaxScale {
+		return fmt.Errorf("the synchronous min_scale can not be greater than its max_scale")
+	}
+	if sync.TargetConcurrency < 0 || sync.ContainerConcurrency < 0 {
+		return fmt.Errorf("the synchronous target_concurrency and container_concurrency can not be negative")
+	}
+	if sync.TargetConcurrency > service.GetContainerConcurrency() {
+		return fmt.Errorf("the synchronous target_concurrency can not be greater than its container_concurrency (%d)", service.GetContainerConcurrency())
+	}
+	if sync.ScaleDownDelay != "" {
+		delay, err := time.ParseDuration(sync.ScaleDownDelay)
+		if err != nil {
+			return fmt.Errorf("the synchronous scale_down_delay \"%s\" is not valid: %v", sync.ScaleDownDelay, err)
+		}
+		if delay < 0 || delay > maxScaleDownDelay {
+			return fmt.Errorf("the synchronous scale_down_delay must be between 0s and %s", maxScaleDownDelay)
+		}
+	}
+	return nil
+}
+
+// GetContainerConcurrency returns the maximum number of simultaneous requests per replica of the synchronous
+// invocations
+func (service *Service) GetContainerConcurrency() int {
+	if service.Synchronous.ContainerConcurrency > 0 {
+		return service.Synchronous.ContainerConcurrency
+	}
+	return defaultContainerConcurrency
+}
+
+// GetKnativeAutoscalingAnnotations returns the annotations of the revisions of the Knative service with its
+// autoscaling settings (the target and scale-down delay only if they are set)
+func (service *Service) GetKnativeAutoscalingAnnotations() map[string]string {
+	annotations := map[string]string{
+		KnativeMinScaleAnnotation: strconv.Itoa(service.Synchronous.MinScale),
+		KnativeMaxScaleAnnotation: strconv.Itoa(service.Synchronous.MaxScale),
+	}
+	if service.Synchronous.TargetConcurrency > 0 {
+		annotations[KnativeTargetAnnotation] = strconv.Itoa(service.Synchronous.TargetConcurrency)
+	}
+	if service.Synchronous.ScaleDownDelay != "" {
+		annotations[KnativeScaleDownDelayAnnotation] = service.Synchronous.ScaleDownDelay
+	}
+	return annotations
+}
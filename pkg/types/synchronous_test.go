@@ -0,0 +1,83 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateSynchronous(t *testing.T) {
+	scenarios := []struct {
+		name                 string
+		minScale, maxScale   int
+		target, containerCon int
+		scaleDownDelay       string
+		returnError          bool
+	}{
+		{"defaults", 0, 0, 0, 0, "", false},
+		{"valid", 1, 5, 8, 10, "15m", false},
+		{"negative scale", -1, 0, 0, 0, "", true},
+		{"min greater than max", 5, 2, 0, 0, "", true},
+		{"target greater than container concurrency", 0, 0, 2, 0, "", true},
+		{"negative container concurrency", 0, 0, 0, -1, "", true},
+		{"invalid delay", 0, 0, 0, 0, "soon", true},
+		{"delay too long", 0, 0, 0, 0, "2h", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{}
+			service.Synchronous.MinScale = s.minScale
+			service.Synchronous.MaxScale = s.maxScale
+			service.Synchronous.TargetConcurrency = s.target
+			service.Synchronous.ContainerConcurrency = s.containerCon
+			service.Synchronous.ScaleDownDelay = s.scaleDownDelay
+			if err := service.ValidateSynchronous(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestGetKnativeAutoscalingAnnotations(t *testing.T) {
+	service := &Service{}
+	expected := map[string]string{KnativeMinScaleAnnotation: "0", KnativeMaxScaleAnnotation: "0"}
+	if annotations := service.GetKnativeAutoscalingAnnotations(); !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("expecting %v, got %v", expected, annotations)
+	}
+	if concurrency := service.GetContainerConcurrency(); concurrency != 1 {
+		t.Errorf("expecting container concurrency 1, got %d", concurrency)
+	}
+
+	service.Synchronous.MinScale = 1
+	service.Synchronous.TargetConcurrency = 4
+	service.Synchronous.ContainerConcurrency = 8
+	service.Synchronous.ScaleDownDelay = "10m"
+	expected = map[string]string{
+		KnativeMinScaleAnnotation:       "1",
+		KnativeMaxScaleAnnotation:       "0",
+		KnativeTargetAnnotation:         "4",
+		KnativeScaleDownDelayAnnotation: "10m",
+	}
+	if annotations := service.GetKnativeAutoscalingAnnotations(); !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("expecting %v, got %v", expected, annotations)
+	}
+	if concurrency := service.GetContainerConcurrency(); concurrency != 8 {
+		t.Errorf("expecting container concurrency 8, got %d", concurrency)
+	}
+}
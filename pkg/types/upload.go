@@ -0,0 +1,119 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// ScriptAssetName name of the asset uploaded as the service's script in two-phase creations
+	ScriptAssetName = "script"
+
+	// UploadMinChunkSize minimum size of the chunks of an asset (except the last one), as required by S3 multipart uploads
+	UploadMinChunkSize = 5 << 20
+
+	// UploadMaxChunkSize maximum size of a single chunk of an asset
+	UploadMaxChunkSize = 64 << 20
+
+	// UploadMaxScriptSize maximum size of the script asset (it is stored in the service's ConfigMap)
+	UploadMaxScriptSize = 512 << 10
+)
+
+var assetNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Asset file of a service uploaded in a two-phase creation (e.g. a model) and copied to the OSCAR's MinIO
+// when the service is activated
+type Asset struct {
+	// Name identifier of the asset in the upload session
+	Name string `json:"name" binding:"required"`
+	// Path destination ("bucket/key") of the asset in the OSCAR's MinIO
+	Path string `json:"path" binding:"required"`
+}
+
+// UploadSession represents the pending upload of the script and assets of a service (two-phase create)
+type UploadSession struct {
+	// ID identifier of the session
+	ID string `json:"id"`
+	// Service name of the service to be activated
+	Service string `json:"service"`
+	// Assets upload status of the service's assets
+	Assets []UploadAsset `json:"assets"`
+	// CreatedAt creation time of the session
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt time after which the session and its staged assets are discarded
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadAsset upload status of an asset in an UploadSession
+type UploadAsset struct {
+	// Name identifier of the asset ("script" for the service's script)
+	Name string `json:"name"`
+	// Path destination ("bucket/key") of the asset in the OSCAR's MinIO (empty for the script)
+	Path string `json:"path,omitempty"`
+	// Size total size of the asset in bytes (0 until the first chunk is uploaded)
+	Size int64 `json:"size"`
+	// Offset number of bytes already uploaded, the next chunk must start at this offset
+	Offset int64 `json:"offset"`
+	// Complete the asset is completely uploaded
+	Complete bool `json:"complete"`
+}
+
+// IsComplete checks if all the assets of the session are completely uploaded
+func (session UploadSession) IsComplete() bool {
+	for _, asset := range session.Assets {
+		if !asset.Complete {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateAssets checks the names and destination paths of the service's assets. As the dead-letter path,
+// assets must be placed in the bucket of one of the service's inputs or outputs in the default MinIO provider,
+// but not inside any of its inputs (to not trigger the service when they are copied)
+func (service *Service) ValidateAssets() error {
+	names := map[string]bool{}
+	for _, asset := range service.Assets {
+		if !assetNameRegexp.MatchString(asset.Name) {
+			return fmt.Errorf("invalid asset name \"%s\"", asset.Name)
+		}
+		if asset.Name == ScriptAssetName {
+			return fmt.Errorf("the asset name \"%s\" is reserved for the service's script", ScriptAssetName)
+		}
+		if names[asset.Name] {
+			return fmt.Errorf("duplicated asset \"%s\"", asset.Name)
+		}
+		names[asset.Name] = true
+
+		bucket, key := StorageIOConfig{Path: asset.Path}.SplitPath()
+		if bucket == "" || key == "" {
+			return fmt.Errorf("the path of the asset \"%s\" must have the format \"bucket/key\"", asset.Name)
+		}
+		if !service.ownsBucket(bucket) {
+			return fmt.Errorf("the asset \"%s\" must be placed in the bucket of one of the service's inputs or outputs in the default MinIO provider", asset.Name)
+		}
+		for _, in := range service.Input {
+			if provName, provID := in.GetProvider(); provName == MinIOName && provID == DefaultProvider && in.ContainsPath(asset.Path) {
+				return fmt.Errorf("the asset \"%s\" can not be placed in the input \"%s\"", asset.Name, in.Path)
+			}
+		}
+	}
+	return nil
+}
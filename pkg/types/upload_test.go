@@ -0,0 +1,61 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "testing"
+
+func TestValidateAssets(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		assets      []Asset
+		returnError bool
+	}{
+		{"valid", []Asset{{"model", "bucket/models/model.bin"}, {"labels.txt", "out/labels.txt"}}, false},
+		{"no assets", nil, false},
+		{"reserved name", []Asset{{ScriptAssetName, "bucket/script.sh"}}, true},
+		{"invalid name", []Asset{{"../model", "bucket/model.bin"}}, true},
+		{"duplicated name", []Asset{{"model", "bucket/a.bin"}, {"model", "bucket/b.bin"}}, true},
+		{"without key", []Asset{{"model", "bucket"}}, true},
+		{"not owned bucket", []Asset{{"model", "other/model.bin"}}, true},
+		{"inside input", []Asset{{"model", "bucket/in/model.bin"}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{
+				Input:  []StorageIOConfig{{Provider: "minio", Path: "bucket/in"}},
+				Output: []StorageIOConfig{{Provider: "minio.default", Path: "out"}},
+				Assets: s.assets,
+			}
+			if err := service.ValidateAssets(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestUploadSessionIsComplete(t *testing.T) {
+	session := UploadSession{Assets: []UploadAsset{{Name: "script", Complete: true}, {Name: "model"}}}
+	if session.IsComplete() {
+		t.Error("expecting incomplete session")
+	}
+
+	session.Assets[1].Complete = true
+	if !session.IsComplete() {
+		t.Error("expecting complete session")
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// UserStoreFile store of the users in a JSON file, managed through the /system/users paths
+	UserStoreFile = "file"
+	// UserStoreLDAP store of the users authenticated against an LDAP server (managed in the LDAP directory)
+	UserStoreLDAP = "ldap"
+	// UserStoreSCIM store of the users in a JSON file provisioned by an identity provider through SCIM 2.0 (also
+	// managed through the /system/users paths)
+	UserStoreSCIM = "scim"
+
+	// MinUserPasswordLength minimum length of the passwords of the users
+	MinUserPasswordLength = 8
+)
+
+// usernameRegex usernames allowed in the user stores (e.g. emails), without ":" as they are sent with basic auth
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@+-]{0,127}$`)
+
+// User user of the user store authenticated with basic auth. Only the hash of the password is stored
+type User struct {
+	Username string `json:"username"`
+	// PasswordHash bcrypt hash of the password
+	PasswordHash string `json:"password_hash,omitempty"`
+	// Role role assigned to the user ("admin", "operator", "invoker" or "viewer"), mapped by RBAC_ROLES if empty
+	Role string `json:"role,omitempty"`
+	// Groups groups of the user (as the OIDC groups), mapping its role in RBAC_ROLES and its VOs
+	Groups []string `json:"groups,omitempty"`
+	// Disabled option to reject the authentication of the user
+	Disabled  bool      `json:"disabled,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserRequest body of the requests to create or update a user of the user store. The empty fields are kept in
+// the updates
+type UserRequest struct {
+	Username string   `json:"username"`
+	Password string   `json:"password,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Disabled *bool    `json:"disabled,omitempty"`
+}
+
+// Validate checks the username, the password and the role of the user. The password is only required on its
+// creation
+func (req *UserRequest) Validate(create bool) error {
+	if !usernameRegex.MatchString(req.Username) {
+		return fmt.Errorf("the username \"%s\" is not valid, it must consist of up to 128 alphanumeric characters or '.', '_', '@', '+' or '-' and start with an alphanumeric character", req.Username)
+	}
+	if (create || req.Password != "") && len(req.Password) < MinUserPasswordLength {
+		return fmt.Errorf("the password must have at least %d characters", MinUserPasswordLength)
+	}
+	if req.Role != "" && !IsValidRole(req.Role) {
+		return fmt.Errorf("the role \"%s\" is not valid, it must be \"%s\", \"%s\", \"%s\" or \"%s\"", req.Role, RoleAdmin, RoleOperator, RoleInvoker, RoleViewer)
+	}
+	return nil
+}
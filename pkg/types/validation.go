@@ -0,0 +1,48 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// ValidationIssue problem found in a field of a service definition
+type ValidationIssue struct {
+	// Field path of the field in the service definition (e.g. "input[0]" or "cpu")
+	Field string `json:"field"`
+	// Code identifier of the error that would be returned when creating the service (e.g. "OSCAR-2001")
+	Code string `json:"code"`
+	// Message description of the issue
+	Message string `json:"message"`
+}
+
+// ValidationResult result of the validation of a service definition
+type ValidationResult struct {
+	// Valid true if the service can be created (i.e. there are no errors)
+	Valid bool `json:"valid"`
+	// Errors issues that prevent the creation of the service
+	Errors []ValidationIssue `json:"errors"`
+	// Warnings issues that don't prevent the creation of the service but may cause an unexpected behaviour
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// AddError adds an error to the validation result
+func (res *ValidationResult) AddError(field string, code ErrorCode, message string) {
+	res.Errors = append(res.Errors, ValidationIssue{Field: field, Code: code.Code, Message: message})
+	res.Valid = false
+}
+
+// AddWarning adds a warning to the validation result
+func (res *ValidationResult) AddWarning(field string, message string) {
+	res.Warnings = append(res.Warnings, ValidationIssue{Field: field, Message: message})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VaultReferencePrefix prefix of the credentials referencing a field of a Vault secret ("vault://<path>#<field>")
+const VaultReferencePrefix = "vault://"
+
+// ParseVaultReference returns the path and the field of the Vault secret referenced by a value
+func ParseVaultReference(value string) (path string, field string, ok bool) {
+	if !strings.HasPrefix(value, VaultReferencePrefix) {
+		return "", "", false
+	}
+	path, field, ok = strings.Cut(strings.TrimPrefix(value, VaultReferencePrefix), "#")
+	path = strings.Trim(path, "/")
+	if !ok || path == "" || field == "" {
+		return "", "", false
+	}
+	return path, field, true
+}
+
+// RewriteStorageCredentials replaces the non-empty credentials (access keys included) of the service's storage
+// providers with the value returned by fn, which receives the key identifying each credential
+// (e.g. "s3.aws.access_key")
+func (service *Service) RewriteStorageCredentials(fn func(key string, value string) (string, error)) error {
+	sp := service.StorageProviders
+	if sp == nil {
+		return nil
+	}
+	rewrite := func(key string, value *string) error {
+		if *value == "" {
+			return nil
+		}
+		newValue, err := fn(key, *value)
+		if err != nil {
+			return err
+		}
+		*value = newValue
+		return nil
+	}
+
+	for id, p := range sp.S3 {
+		if err := rewrite(fmt.Sprintf("%s.%s.access_key", S3Name, id), &p.AccessKey); err != nil {
+			return err
+		}
+		if err := rewrite(fmt.Sprintf("%s.%s.secret_key", S3Name, id), &p.SecretKey); err != nil {
+			return err
+		}
+	}
+	for id, p := range sp.MinIO {
+		if err := rewrite(fmt.Sprintf("%s.%s.access_key", MinIOName, id), &p.AccessKey); err != nil {
+			return err
+		}
+		if err := rewrite(fmt.Sprintf("%s.%s.secret_key", MinIOName, id), &p.SecretKey); err != nil {
+			return err
+		}
+	}
+	for id, p := range sp.Onedata {
+		if err := rewrite(fmt.Sprintf("%s.%s.token", OnedataName, id), &p.Token); err != nil {
+			return err
+		}
+	}
+	for id, p := range sp.WebDav {
+		if err := rewrite(fmt.Sprintf("%s.%s.login", WebDavName, id), &p.Login); err != nil {
+			return err
+		}
+		if err := rewrite(fmt.Sprintf("%s.%s.password", WebDavName, id), &p.Password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasVaultReferences checks if any credential of the service's storage providers references a Vault secret
+func (service *Service) HasVaultReferences() bool {
+	found := false
+	service.RewriteStorageCredentials(func(key string, value string) (string, error) {
+		if strings.HasPrefix(value, VaultReferencePrefix) {
+			found = true
+		}
+		return value, nil
+	})
+	return found
+}
+
+// ValidateVaultReferences checks the format of the credentials referencing Vault secrets, and that they reference
+// the paths of the service's VO under the allowed prefixes
+func (service *Service) ValidateVaultReferences(prefixes []string) error {
+	return service.RewriteStorageCredentials(func(key string, value string) (string, error) {
+		if !strings.HasPrefix(value, VaultReferencePrefix) {
+			return value, nil
+		}
+		path, _, ok := ParseVaultReference(value)
+		if !ok {
+			return "", fmt.Errorf("the credential \"%s\" must reference a Vault secret as \"%s<path>#<field>\"", key, VaultReferencePrefix)
+		}
+		return value, service.CheckVaultPath(key, path, prefixes)
+	})
+}
+
+// VaultPathPrefixes returns the prefixes of the Vault paths that the service can reference ("<prefix>/<vo>" for each
+// allowed prefix). The services without VO can not reference Vault secrets
+func (service *Service) VaultPathPrefixes(prefixes []string) []string {
+	var servicePrefixes []string
+	if service.VO == "" {
+		return servicePrefixes
+	}
+	for _, prefix := range prefixes {
+		servicePrefixes = append(servicePrefixes, prefix+"/"+service.VO)
+	}
+	return servicePrefixes
+}
+
+// IsAllowedVaultPath checks if the service can reference a Vault path, which must be (or be under) one of the
+// prefixes of its VO
+func (service *Service) IsAllowedVaultPath(path string, prefixes []string) bool {
+	// The relative segments could escape the prefix
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	for _, prefix := range service.VaultPathPrefixes(prefixes) {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVaultPath checks if the service can reference the Vault path of a credential (see IsAllowedVaultPath)
+func (service *Service) CheckVaultPath(key string, path string, prefixes []string) error {
+	if service.IsAllowedVaultPath(path, prefixes) {
+		return nil
+	}
+	servicePrefixes := service.VaultPathPrefixes(prefixes)
+	if len(servicePrefixes) == 0 {
+		return fmt.Errorf("the credential \"%s\" can not reference the Vault secret \"%s\": only the services with VO can reference Vault secrets (under the allowed prefixes)", key, path)
+	}
+	return fmt.Errorf("the credential \"%s\" can not reference the Vault secret \"%s\": it must be under \"%s\"", key, path, strings.Join(servicePrefixes, "\", \""))
+}
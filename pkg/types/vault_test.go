@@ -0,0 +1,78 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "testing"
+
+func TestParseVaultReference(t *testing.T) {
+	scenarios := []struct {
+		value string
+		path  string
+		field string
+		ok    bool
+	}{
+		{"vault://secret/data/oscar/s3#access_key", "secret/data/oscar/s3", "access_key", true},
+		{"vault:///aws/creds/oscar/#secret_key", "aws/creds/oscar", "secret_key", true},
+		{"vault://secret/data/oscar/s3", "", "", false},
+		{"vault://#access_key", "", "", false},
+		{"AKIAEXAMPLE", "", "", false},
+	}
+
+	for _, s := range scenarios {
+		path, field, ok := ParseVaultReference(s.value)
+		if path != s.path || field != s.field || ok != s.ok {
+			t.Errorf("%s: expecting (\"%s\", \"%s\", %v), got (\"%s\", \"%s\", %v)", s.value, s.path, s.field, s.ok, path, field, ok)
+		}
+	}
+}
+
+func TestVaultReferences(t *testing.T) {
+	prefixes := []string{"aws/creds", "secret/data"}
+	service := &Service{VO: "oscar", StorageProviders: &StorageProviders{
+		S3: map[string]*S3Provider{"aws": {AccessKey: "vault://aws/creds/oscar#access_key", SecretKey: "vault://secret/data/oscar/s3#secret_key"}},
+	}}
+	if !service.HasVaultReferences() {
+		t.Error("expecting the service to reference Vault secrets")
+	}
+	if err := service.ValidateVaultReferences(prefixes); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Paths outside the prefixes of the service's VO
+	for _, path := range []string{"aws/creds/admin", "aws/creds/oscar-admin", "secret/data/oscar/../admin/s3", "kv/oscar/s3"} {
+		service.StorageProviders.S3["aws"].SecretKey = "vault://" + path + "#secret_key"
+		if err := service.ValidateVaultReferences(prefixes); err == nil {
+			t.Errorf("expecting an error for the path \"%s\"", path)
+		}
+	}
+	service.StorageProviders.S3["aws"].SecretKey = "vault://secret/data/oscar/s3#secret_key"
+	if err := (&Service{StorageProviders: service.StorageProviders}).ValidateVaultReferences(prefixes); err == nil {
+		t.Error("expecting an error for the service without VO")
+	}
+	if err := service.ValidateVaultReferences(nil); err == nil {
+		t.Error("expecting an error without allowed prefixes")
+	}
+
+	service.StorageProviders.S3["aws"].SecretKey = "vault://aws/creds/oscar"
+	if err := service.ValidateVaultReferences(prefixes); err == nil {
+		t.Error("expecting an error for the reference without field")
+	}
+
+	if (&Service{StorageProviders: &StorageProviders{WebDav: map[string]*WebDavProvider{"dcache": {Login: "user", Password: "pass"}}}}).HasVaultReferences() {
+		t.Error("expecting the service not to reference Vault secrets")
+	}
+}
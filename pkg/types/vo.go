@@ -0,0 +1,208 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// VORequestLabel label of the configMaps (in the services namespace) storing the requests to enable new VOs
+	VORequestLabel = "oscar_vo_request"
+	// VORequestFileName key of the VO requests' configMaps with the request
+	VORequestFileName = "request.json"
+	// ApprovedVOsConfigMapName name of the configMap (in the services namespace) storing the approved VOs
+	ApprovedVOsConfigMapName = "oscar-approved-vos"
+
+	// VORequestPending status of the VO requests waiting for the decision of an admin
+	VORequestPending = "pending"
+	// VORequestApproved status of the approved VO requests
+	VORequestApproved = "approved"
+	// VORequestRejected status of the rejected VO requests
+	VORequestRejected = "rejected"
+)
+
+// VORequest request of a user to enable a new VO (OIDC group) in the cluster, approved or rejected by an admin
+type VORequest struct {
+	// ID identifier of the request (set by OSCAR)
+	ID string `json:"id"`
+	// VO name of the VO (group of the "eduperson_entitlement" claim)
+	VO string `json:"vo"`
+	// Reason description of the use of the cluster by the VO
+	// Optional
+	Reason string `json:"reason,omitempty"`
+	// Quota resources requested for the VO (see the QUOTAS of the cluster)
+	// Optional
+	Quota *Quota `json:"quota,omitempty"`
+	// Namespace the VO requests its own Kubernetes namespace
+	// Optional. (default: false)
+	Namespace bool `json:"namespace"`
+	// Storage the VO requests its own bucket in the MinIO of the cluster
+	// Optional. (default: false)
+	Storage bool `json:"storage"`
+	// Requester user that made the request (set by OSCAR)
+	Requester string `json:"requester"`
+	// Status of the request ("pending", "approved" or "rejected")
+	Status string `json:"status"`
+	// CreatedAt time of the request
+	CreatedAt time.Time `json:"created_at"`
+	// DecidedAt time of the decision of the admin
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	// DecidedBy admin that approved or rejected the request
+	DecidedBy string `json:"decided_by,omitempty"`
+	// Message comment of the admin on the decision
+	Message string `json:"message,omitempty"`
+}
+
+// VORequestDecision decision of an admin on a VO request. When approving, the quota and the provisioned resources
+// override the requested ones if set
+type VORequestDecision struct {
+	// Message comment on the decision, returned to the requester
+	// Optional
+	Message string `json:"message"`
+	// Quota granted to the VO
+	// Optional. (default: the requested quota)
+	Quota *Quota `json:"quota,omitempty"`
+	// Namespace provision the namespace of the VO
+	// Optional. (default: the requested namespace)
+	Namespace *bool `json:"namespace,omitempty"`
+	// Storage provision the bucket of the VO
+	// Optional. (default: the requested storage)
+	Storage *bool `json:"storage,omitempty"`
+}
+
+// ApprovedVO VO enabled in the cluster through an approved VO request
+type ApprovedVO struct {
+	// VO name of the VO
+	VO string `json:"vo"`
+	// RequestID identifier of the approved request
+	RequestID string `json:"request_id"`
+	// Quota resources granted to the VO (the QUOTAS of the cluster take precedence)
+	Quota *Quota `json:"quota,omitempty"`
+	// Namespace name of the namespace provisioned for the VO
+	Namespace string `json:"namespace,omitempty"`
+	// Bucket name of the bucket provisioned for the VO in the MinIO of the cluster
+	Bucket string `json:"bucket,omitempty"`
+	// ApprovedAt time of the approval
+	ApprovedAt time.Time `json:"approved_at"`
+}
+
+// approvedVOs VOs approved at runtime, shared by all the components of the OSCAR manager
+var approvedVOs = struct {
+	mutex sync.RWMutex
+	vos   map[string]ApprovedVO
+}{vos: map[string]ApprovedVO{}}
+
+// Validate checks the name of the VO and the requested quota
+func (req *VORequest) Validate() error {
+	if errs := validation.IsDNS1123Subdomain(req.VO); len(errs) > 0 {
+		return fmt.Errorf("the VO name \"%s\" is not valid: %s", req.VO, strings.Join(errs, ", "))
+	}
+	return req.Quota.validate()
+}
+
+// Validate checks the granted quota
+func (decision *VORequestDecision) Validate() error {
+	return decision.Quota.validate()
+}
+
+// validate checks that the limits of the quota are not negative
+func (quota *Quota) validate() error {
+	if quota == nil {
+		return nil
+	}
+	if quota.Jobs < 0 || quota.CPUSeconds < 0 || quota.MemorySeconds < 0 || quota.StorageBytes < 0 {
+		return fmt.Errorf("the limits of the quota can't be negative")
+	}
+	return nil
+}
+
+// VORequestConfigMapName returns the name of the configMap storing a VO request
+func VORequestConfigMapName(id string) string {
+	return fmt.Sprintf("vo-request-%s", id)
+}
+
+// VONamespaceName returns the name of the namespace provisioned for a VO
+func VONamespaceName(vo string) string {
+	return fmt.Sprintf("oscar-vo-%s", strings.ReplaceAll(vo, ".", "-"))
+}
+
+// VOBucketName returns the name of the bucket provisioned for a VO
+func VOBucketName(vo string) string {
+	return fmt.Sprintf("oscar-vo-%s", vo)
+}
+
+// SetApprovedVOs replaces the VOs approved at runtime
+func SetApprovedVOs(vos map[string]ApprovedVO) {
+	approvedVOs.mutex.Lock()
+	defer approvedVOs.mutex.Unlock()
+	approvedVOs.vos = vos
+}
+
+// GetApprovedVOs returns the VOs approved at runtime, sorted by name
+func GetApprovedVOs() []ApprovedVO {
+	approvedVOs.mutex.RLock()
+	defer approvedVOs.mutex.RUnlock()
+
+	vos := []ApprovedVO{}
+	for _, vo := range approvedVOs.vos {
+		vos = append(vos, vo)
+	}
+	sort.Slice(vos, func(i, j int) bool {
+		return vos[i].VO < vos[j].VO
+	})
+	return vos
+}
+
+// GetAllowedGroups returns the OIDC groups granted to access the cluster: the OIDC_GROUPS and the approved VOs
+func (cfg *Config) GetAllowedGroups() []string {
+	return withApprovedVOs(cfg.OIDCGroups)
+}
+
+// withApprovedVOs returns the groups along with the approved VOs
+func withApprovedVOs(groups []string) []string {
+	allowed := append([]string{}, groups...)
+	for _, vo := range GetApprovedVOs() {
+		found := false
+		for _, g := range groups {
+			found = found || g == vo.VO
+		}
+		if !found {
+			allowed = append(allowed, vo.VO)
+		}
+	}
+	return allowed
+}
+
+// IsAllowedGroup checks if the OIDC group is granted to access the cluster (in the OIDC_GROUPS or approved)
+func (cfg *Config) IsAllowedGroup(group string) bool {
+	for _, g := range cfg.OIDCGroups {
+		if g == group {
+			return true
+		}
+	}
+	approvedVOs.mutex.RLock()
+	defer approvedVOs.mutex.RUnlock()
+	_, approved := approvedVOs.vos[group]
+	return approved
+}
@@ -0,0 +1,65 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateVORequest(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		req         VORequest
+		returnError bool
+	}{
+		{"valid", VORequest{VO: "vo.example.eu", Quota: &Quota{Jobs: 100}}, false},
+		{"empty", VORequest{}, true},
+		{"invalid name", VORequest{VO: "VO Example"}, true},
+		{"negative quota", VORequest{VO: "vo.example.eu", Quota: &Quota{CPUSeconds: -1}}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.req.Validate(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
+
+func TestApprovedVOs(t *testing.T) {
+	defer SetApprovedVOs(map[string]ApprovedVO{})
+	SetApprovedVOs(map[string]ApprovedVO{
+		"vo.new.eu":     {VO: "vo.new.eu", Quota: &Quota{Jobs: 10}},
+		"vo.example.eu": {VO: "vo.example.eu", Quota: &Quota{Jobs: 20}},
+	})
+	cfg := &Config{OIDCGroups: []string{"vo.example.eu"}, Quotas: []string{"vo:vo.example.eu:jobs=5"}}
+
+	if groups := cfg.GetAllowedGroups(); !reflect.DeepEqual(groups, []string{"vo.example.eu", "vo.new.eu"}) {
+		t.Errorf("unexpected allowed groups: %v", groups)
+	}
+	if !cfg.IsAllowedGroup("vo.new.eu") || cfg.IsAllowedGroup("vo.other.eu") {
+		t.Error("unexpected allowed group")
+	}
+
+	// The QUOTAS take precedence over the approved quotas
+	quotas := cfg.GetQuotas()[QuotaScopeVO]
+	if quotas["vo.example.eu"].Jobs != 5 || quotas["vo.new.eu"].Jobs != 10 {
+		t.Errorf("unexpected quotas: %+v, %+v", quotas["vo.example.eu"], quotas["vo.new.eu"])
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	// EGIGroupsURNPrefix prefix of the EGI group entitlements
+	EGIGroupsURNPrefix = "urn:mace:egi.eu:group"
+	// voRolePrefix prefix of the role in the EGI group entitlements (e.g. "urn:mace:egi.eu:group:<VO>:role=member")
+	voRolePrefix = "role="
+)
+
+// VOMapping membership rules of a VO, defined in the VO_MAPPING_FILE
+type VOMapping struct {
+	// VO name of the VO in the services
+	VO string `json:"vo"`
+	// Aliases other names of the VO, both in the services and in the groups of the users (e.g. in other issuers)
+	Aliases []string `json:"aliases,omitempty"`
+	// Groups additional groups of the users granting the membership of the VO. A trailing "*" segment matches
+	// any of their sub-groups (e.g. "vo.example.eu:*")
+	Groups []string `json:"groups,omitempty"`
+	// Roles required to the users in any of the groups of the VO (e.g. "vm_operator"), not checked if empty
+	Roles []string `json:"roles,omitempty"`
+}
+
+// VOMembership group of a user, with its role in the group if the issuer provides it (e.g. EGI entitlements)
+type VOMembership struct {
+	// Group name of the group, with its sub-groups separated by colons (e.g. "vo.example.eu:subgroup")
+	Group string
+	Role  string
+}
+
+// ReadVOMappingFile reads the JSON or YAML list of VO mappings of a file, none if the path is empty
+func ReadVOMappingFile(path string) ([]VOMapping, error) {
+	mappings := []VOMapping{}
+	if strings.TrimSpace(path) == "" {
+		return mappings, nil
+	}
+	data, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("the VO mappings must be a list: %v", err)
+	}
+	for _, m := range mappings {
+		if strings.TrimSpace(m.VO) == "" {
+			return nil, fmt.Errorf("the vo of all the mappings is required")
+		}
+		for _, group := range m.Groups {
+			if strings.Contains(strings.TrimSuffix(group, ":*"), "*") {
+				return nil, fmt.Errorf("the group \"%s\" of the VO \"%s\" is not valid: only a trailing \":*\" wildcard is supported", group, m.VO)
+			}
+		}
+	}
+	return mappings, nil
+}
+
+// ParseEGIEntitlement returns the group (with its sub-groups) and role of an EGI group entitlement (e.g.
+// "urn:mace:egi.eu:group:vo.example.eu:subgroup:role=vm_operator#aai.egi.eu"), or false if it is not a group URN
+func ParseEGIEntitlement(urn string) (VOMembership, bool) {
+	urn = strings.ToLower(strings.TrimSpace(urn))
+	if !strings.HasPrefix(urn, EGIGroupsURNPrefix+":") {
+		return VOMembership{}, false
+	}
+	urn, _, _ = strings.Cut(strings.TrimPrefix(urn, EGIGroupsURNPrefix+":"), "#")
+	fields := strings.Split(urn, ":")
+	membership := VOMembership{}
+	if last := fields[len(fields)-1]; strings.HasPrefix(last, voRolePrefix) {
+		membership.Role = strings.TrimPrefix(last, voRolePrefix)
+		fields = fields[:len(fields)-1]
+	}
+	membership.Group = strings.Join(fields, ":")
+	return membership, membership.Group != ""
+}
+
+// TopGroup returns the top-level group of the membership, without its sub-groups
+func (m VOMembership) TopGroup() string {
+	group, _, _ := strings.Cut(m.Group, ":")
+	return group
+}
+
+// GetVOMapping returns the mapping of a VO (by its name or aliases), or one matching the members of the VO and
+// its sub-groups if the VO_MAPPING_FILE does not define it
+func (cfg *Config) GetVOMapping(vo string) VOMapping {
+	for _, m := range cfg.VOMappings {
+		if strings.EqualFold(m.VO, vo) {
+			return m
+		}
+		for _, alias := range m.Aliases {
+			if strings.EqualFold(alias, vo) {
+				return m
+			}
+		}
+	}
+	return VOMapping{VO: vo, Groups: []string{vo + ":*"}}
+}
+
+// HasMember checks if any of the memberships of a user grants the membership of the VO: a group matching its
+// name, aliases or groups, with any of the required roles
+func (m VOMapping) HasMember(memberships []VOMembership) bool {
+	patterns := append(append([]string{m.VO}, m.Aliases...), m.Groups...)
+	for _, membership := range memberships {
+		if !m.hasRole(membership.Role) {
+			continue
+		}
+		for _, pattern := range patterns {
+			if matchVOGroup(pattern, membership.Group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasRole checks if a role is one of the required by the VO
+func (m VOMapping) hasRole(role string) bool {
+	if len(m.Roles) == 0 {
+		return true
+	}
+	for _, r := range m.Roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchVOGroup checks if a group matches a pattern, being its name or ending with a ":*" wildcard matching any
+// of its sub-groups
+func matchVOGroup(pattern string, group string) bool {
+	if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern && strings.HasSuffix(prefix, ":") {
+		return len(group) > len(prefix) && strings.EqualFold(group[:len(prefix)], prefix)
+	}
+	return strings.EqualFold(pattern, group)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadVOMappingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vos.yaml")
+	content := `
+- vo: vo.example.eu
+  aliases: [example]
+  groups: ["vo.example.eu:*"]
+  roles: [vm_operator]
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mappings, err := ReadVOMappingFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []VOMapping{{VO: "vo.example.eu", Aliases: []string{"example"}, Groups: []string{"vo.example.eu:*"}, Roles: []string{"vm_operator"}}}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("expecting %v, got %v", expected, mappings)
+	}
+
+	if mappings, err := ReadVOMappingFile(""); err != nil || len(mappings) != 0 {
+		t.Errorf("expecting no mappings, got %v (error: %v)", mappings, err)
+	}
+	for _, invalid := range []string{`[{"groups": ["a"]}]`, `[{"vo": "a", "groups": ["a:*:b"]}]`, `{"vo": "a"}`} {
+		if err := os.WriteFile(path, []byte(invalid), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadVOMappingFile(path); err == nil {
+			t.Errorf("expecting error for %s, got nil", invalid)
+		}
+	}
+}
+
+func TestParseEGIEntitlement(t *testing.T) {
+	scenarios := []struct {
+		urn      string
+		expected VOMembership
+		ok       bool
+	}{
+		{"urn:mace:egi.eu:group:vo.example.eu:role=member#aai.egi.eu", VOMembership{Group: "vo.example.eu", Role: "member"}, true},
+		{"urn:mace:egi.eu:group:vo.example.eu:Sub:role=vm_operator#aai.egi.eu", VOMembership{Group: "vo.example.eu:sub", Role: "vm_operator"}, true},
+		{"urn:mace:egi.eu:group:vo.example.eu", VOMembership{Group: "vo.example.eu"}, true},
+		{"urn:mace:egi.eu:res:other", VOMembership{}, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.urn, func(t *testing.T) {
+			membership, ok := ParseEGIEntitlement(s.urn)
+			if ok != s.ok || membership != s.expected {
+				t.Errorf("expecting %v (%v), got %v (%v)", s.expected, s.ok, membership, ok)
+			}
+		})
+	}
+}
+
+func TestVOMappingHasMember(t *testing.T) {
+	cfg := &Config{VOMappings: []VOMapping{
+		{VO: "vo.example.eu", Aliases: []string{"example"}, Groups: []string{"vo.parent.eu:example:*"}, Roles: []string{"vm_operator"}},
+	}}
+
+	scenarios := []struct {
+		name        string
+		vo          string
+		memberships []VOMembership
+		expected    bool
+	}{
+		{"unmapped vo", "vo.other.eu", []VOMembership{{Group: "vo.other.eu", Role: "member"}}, true},
+		{"unmapped vo sub-group", "vo.other.eu", []VOMembership{{Group: "vo.other.eu:sub", Role: "member"}}, true},
+		{"unmapped vo other group", "vo.other.eu", []VOMembership{{Group: "vo.other.eu.org"}}, false},
+		{"required role", "vo.example.eu", []VOMembership{{Group: "vo.example.eu", Role: "vm_operator"}}, true},
+		{"missing role", "vo.example.eu", []VOMembership{{Group: "vo.example.eu", Role: "member"}}, false},
+		{"alias group", "vo.example.eu", []VOMembership{{Group: "example", Role: "vm_operator"}}, true},
+		{"alias vo", "example", []VOMembership{{Group: "vo.example.eu", Role: "vm_operator"}}, true},
+		{"wildcard sub-group", "vo.example.eu", []VOMembership{{Group: "vo.parent.eu:example:team", Role: "vm_operator"}}, true},
+		{"wildcard parent", "vo.example.eu", []VOMembership{{Group: "vo.parent.eu:example", Role: "vm_operator"}}, false},
+		{"mapped vo sub-group", "vo.example.eu", []VOMembership{{Group: "vo.example.eu:sub", Role: "vm_operator"}}, false},
+		{"no memberships", "vo.example.eu", nil, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if res := cfg.GetVOMapping(s.vo).HasMember(s.memberships); res != s.expected {
+				t.Errorf("expecting %v, got %v", s.expected, res)
+			}
+		})
+	}
+}
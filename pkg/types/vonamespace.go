@@ -0,0 +1,95 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VONamespaceLabel label of the namespaces created for the VOs
+const VONamespaceLabel = "oscar_vo_namespace"
+
+// VOAnnotation annotation of the namespaces created for the VOs with the name of the VO
+const VOAnnotation = "oscar_vo"
+
+// maxNamespaceLength maximum length of the name of a Kubernetes namespace (DNS label)
+const maxNamespaceLength = 63
+
+var invalidNamespaceChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// GetVONamespace returns the name of the namespace of a VO: the services namespace followed by the VO name, with
+// the characters not allowed in namespaces replaced by "-"
+func (cfg *Config) GetVONamespace(vo string) string {
+	name := fmt.Sprintf("%s-%s", cfg.ServicesNamespace, invalidNamespaceChars.ReplaceAllString(strings.ToLower(vo), "-"))
+	if len(name) > maxNamespaceLength {
+		name = name[:maxNamespaceLength]
+	}
+	return strings.Trim(name, "-")
+}
+
+// SelectServiceNamespace returns the namespace for the resources of a new service: the namespace of its VO with
+// VONamespaces enabled (not supported by the OpenFaaS backend), or the services namespace otherwise
+func (cfg *Config) SelectServiceNamespace(service *Service) string {
+	if !cfg.VONamespaces || service.VO == "" || cfg.ServerlessBackend == OpenFaaSBackend {
+		return cfg.ServicesNamespace
+	}
+	return cfg.GetVONamespace(service.VO)
+}
+
+// GetServiceNamespace returns the namespace of a service's resources (definition, jobs, secrets...), selected
+// when the service was created
+func (cfg *Config) GetServiceNamespace(service *Service) string {
+	if service.Namespace == "" {
+		return cfg.ServicesNamespace
+	}
+	return service.Namespace
+}
+
+// GetJobsNamespace returns the namespace to list the jobs of all the services: all the namespaces with
+// VONamespaces enabled (the jobs are selected by their labels), or the services namespace otherwise
+func (cfg *Config) GetJobsNamespace() string {
+	if cfg.VONamespaces {
+		return metav1.NamespaceAll
+	}
+	return cfg.ServicesNamespace
+}
+
+// ParseResourceList parses a comma-separated list of "<RESOURCE>=<QUANTITY>" pairs (e.g. "cpu=8,memory=16Gi")
+func ParseResourceList(s string) (v1.ResourceList, error) {
+	resources := v1.ResourceList{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("the resource \"%s\" must be in the form \"<RESOURCE>=<QUANTITY>\"", pair)
+		}
+		quantity, err := resource.ParseQuantity(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("the quantity of the resource \"%s\" is not valid: %v", strings.TrimSpace(name), err)
+		}
+		resources[v1.ResourceName(strings.TrimSpace(name))] = quantity
+	}
+	return resources, nil
+}
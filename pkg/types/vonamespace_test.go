@@ -0,0 +1,69 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetServiceNamespace(t *testing.T) {
+	cfg := &Config{ServicesNamespace: "oscar-svc", ServerlessBackend: KnativeBackend}
+	service := &Service{VO: "vo.example.eu"}
+
+	if ns := cfg.SelectServiceNamespace(service); ns != "oscar-svc" {
+		t.Errorf("expecting the services namespace with VO namespaces disabled, got \"%s\"", ns)
+	}
+	cfg.VONamespaces = true
+	if ns := cfg.SelectServiceNamespace(service); ns != "oscar-svc-vo-example-eu" {
+		t.Errorf("expecting namespace \"oscar-svc-vo-example-eu\", got \"%s\"", ns)
+	}
+	if ns := cfg.SelectServiceNamespace(&Service{}); ns != "oscar-svc" {
+		t.Errorf("expecting the services namespace for the services without VO, got \"%s\"", ns)
+	}
+	if ns := cfg.GetVONamespace(strings.Repeat("a", 70)); len(ns) != 63 {
+		t.Errorf("expecting a namespace of 63 characters, got %d", len(ns))
+	}
+
+	// The namespace of the existing services is kept
+	if ns := cfg.GetServiceNamespace(service); ns != "oscar-svc" {
+		t.Errorf("expecting the services namespace for the services without namespace, got \"%s\"", ns)
+	}
+	service.Namespace = "oscar-svc-other"
+	if ns := cfg.GetServiceNamespace(service); ns != "oscar-svc-other" {
+		t.Errorf("expecting namespace \"oscar-svc-other\", got \"%s\"", ns)
+	}
+}
+
+func TestParseResourceList(t *testing.T) {
+	resources, err := ParseResourceList("requests.cpu=4, requests.memory=8Gi,pods=20")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if memory := resources["requests.memory"]; memory.String() != "8Gi" {
+		t.Errorf("expecting 8Gi of memory, got %s", memory.String())
+	}
+	if len(resources) != 3 {
+		t.Errorf("expecting 3 resources, got %d", len(resources))
+	}
+
+	for _, invalid := range []string{"cpu", "cpu=four", "=4"} {
+		if _, err := ParseResourceList(invalid); err == nil {
+			t.Errorf("expecting error parsing \"%s\"", invalid)
+		}
+	}
+}
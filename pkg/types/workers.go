@@ -0,0 +1,211 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+const (
+	// QueueTokenParam query parameter with the queue token of a service in the requests of KEDA
+	QueueTokenParam = "token"
+	// QueueLeaseParam query parameter with the lease ID (chosen by the worker) of the event taken from the queue
+	QueueLeaseParam = "lease"
+	// QueueRequeueParam query parameter to return a leased event to the queue (its processing failed) instead of
+	// removing it
+	QueueRequeueParam = "requeue"
+	// QueueURLVariable environment variable of the worker pods with the URL to get the next event of the queue
+	QueueURLVariable = "OSCAR_QUEUE_URL"
+	// QueueAckURLVariable environment variable of the worker pods with the URL to acknowledge (or requeue) the
+	// leased events
+	QueueAckURLVariable = "OSCAR_QUEUE_ACK_URL"
+	// QueueTokenVariable environment variable of the worker pods with the queue token of the service
+	QueueTokenVariable = "OSCAR_QUEUE_TOKEN"
+
+	// defaultWorkersMaxReplicas maximum number of simultaneous worker jobs of a service
+	defaultWorkersMaxReplicas = 10
+	// defaultWorkersEventsPerWorker queued events per running worker targeted by KEDA
+	defaultWorkersEventsPerWorker = 1
+	// defaultWorkersPollingInterval seconds between the checks of the queue depth by KEDA
+	defaultWorkersPollingInterval = 30
+	// defaultWorkersMaxQueuedEvents maximum number of queued events of a service
+	defaultWorkersMaxQueuedEvents = 1000
+	// defaultWorkersVisibilityTimeout seconds that a leased event is hidden from the workers until it is acknowledged
+	defaultWorkersVisibilityTimeout = 600
+	// defaultWorkersMaxAttempts times that an event is leased before discarding it
+	defaultWorkersMaxAttempts = 3
+
+	// EventQueueLabel label key of the configMaps storing the queues of the services with workers
+	EventQueueLabel = "oscar_event_queue"
+	// EventQueueKey key of the configMaps storing the queue of a service, with the JSON list of its QueuedEvents
+	EventQueueKey = "events"
+	// MaxEventQueueSize maximum size in bytes of the events of a queue, below the 1MiB limit of the configMaps
+	MaxEventQueueSize = 900 * 1024
+)
+
+// QueuedEvent event of a service with workers stored in its queue, oldest first. The event is leased by a worker
+// (Lease) until it acknowledges it or the visibility timeout expires (LeasedUntil, in Unix seconds), and returned to
+// the queue otherwise
+type QueuedEvent struct {
+	Event       string `json:"event"`
+	Lease       string `json:"lease,omitempty"`
+	LeasedUntil int64  `json:"leased_until,omitempty"`
+	Attempts    int    `json:"attempts,omitempty"`
+}
+
+// queueLeaseRegex allowed lease IDs of the queued events (e.g. the UUIDs of the worker jobs)
+var queueLeaseRegex = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// IsValidQueueLease checks the lease ID of an event taken from the queue
+func IsValidQueueLease(lease string) bool {
+	return queueLeaseRegex.MatchString(lease)
+}
+
+// EventQueueConfigMapName returns the name of the configMap storing the queue of a service with workers
+func EventQueueConfigMapName(serviceName string) string {
+	return fmt.Sprintf("%s.queue", serviceName)
+}
+
+// WorkerPool settings of the KEDA ScaledJob consuming the queued events of a service, instead of creating a job per
+// event. The workers process the events of the queue until it is empty
+type WorkerPool struct {
+	// MaxReplicas maximum number of simultaneous worker jobs (default: 10)
+	MaxReplicas int `json:"max_replicas,omitempty"`
+	// EventsPerWorker number of queued events per running worker targeted by the autoscaler (default: 1)
+	EventsPerWorker int `json:"events_per_worker,omitempty"`
+	// PollingInterval seconds between the checks of the queue depth (default: 30)
+	PollingInterval int `json:"polling_interval,omitempty"`
+	// MaxQueuedEvents maximum number of queued events, the new ones are rejected when it is full (default: 1000)
+	MaxQueuedEvents int `json:"max_queued_events,omitempty"`
+	// VisibilityTimeout seconds to process a leased event before returning it to the queue (default: 600)
+	VisibilityTimeout int `json:"visibility_timeout,omitempty"`
+	// MaxAttempts times that an event is leased (failed or expired) before discarding it (default: 3)
+	MaxAttempts int `json:"max_attempts,omitempty"`
+}
+
+// QueueDepth number of queued events of a service, polled by KEDA to scale its workers
+type QueueDepth struct {
+	Service string `json:"service"`
+	Depth   int    `json:"depth"`
+}
+
+// HasWorkers checks if the events of the service are consumed by a pool of workers
+func (service *Service) HasWorkers() bool {
+	return service.Workers != nil
+}
+
+// ValidateWorkers checks the settings of the worker pool of the service
+func (service *Service) ValidateWorkers() error {
+	w := service.Workers
+	if w == nil {
+		return nil
+	}
+	if w.MaxReplicas < 0 || w.EventsPerWorker < 0 || w.PollingInterval < 0 || w.MaxQueuedEvents < 0 || w.VisibilityTimeout < 0 || w.MaxAttempts < 0 {
+		return fmt.Errorf("the workers settings can not be negative")
+	}
+	if service.HasBatch() {
+		return fmt.Errorf("the workers can not be combined with the batch processing of the events")
+	}
+	return nil
+}
+
+// GetMaxReplicas returns the maximum number of simultaneous worker jobs
+func (w *WorkerPool) GetMaxReplicas() int {
+	if w.MaxReplicas > 0 {
+		return w.MaxReplicas
+	}
+	return defaultWorkersMaxReplicas
+}
+
+// GetEventsPerWorker returns the number of queued events per running worker targeted by the autoscaler
+func (w *WorkerPool) GetEventsPerWorker() int {
+	if w.EventsPerWorker > 0 {
+		return w.EventsPerWorker
+	}
+	return defaultWorkersEventsPerWorker
+}
+
+// GetPollingInterval returns the seconds between the checks of the queue depth
+func (w *WorkerPool) GetPollingInterval() int {
+	if w.PollingInterval > 0 {
+		return w.PollingInterval
+	}
+	return defaultWorkersPollingInterval
+}
+
+// GetMaxQueuedEvents returns the maximum number of queued events
+func (w *WorkerPool) GetMaxQueuedEvents() int {
+	if w.MaxQueuedEvents > 0 {
+		return w.MaxQueuedEvents
+	}
+	return defaultWorkersMaxQueuedEvents
+}
+
+// GetVisibilityTimeout returns the seconds to process a leased event before returning it to the queue
+func (w *WorkerPool) GetVisibilityTimeout() int {
+	if w.VisibilityTimeout > 0 {
+		return w.VisibilityTimeout
+	}
+	return defaultWorkersVisibilityTimeout
+}
+
+// GetMaxAttempts returns the times that an event is leased before discarding it
+func (w *WorkerPool) GetMaxAttempts() int {
+	if w.MaxAttempts > 0 {
+		return w.MaxAttempts
+	}
+	return defaultWorkersMaxAttempts
+}
+
+// GetQueueToken returns the token of the service to read its queue (derived from its token, so it does not grant
+// the invocation of the service)
+func (service *Service) GetQueueToken() string {
+	mac := hmac.New(sha256.New, []byte(service.Token))
+	mac.Write([]byte("queue:" + service.Name))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckQueueToken checks the token of a request to the queue of the service
+func (service *Service) CheckQueueToken(token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(service.GetQueueToken()))
+}
+
+// GetWorkerScript returns the script of the worker containers, processing the events of the queue with the FaaS
+// Supervisor until it is empty. Each event is leased with the UUID of its job, and acknowledged after processing
+// it (or requeued if the FaaS Supervisor fails). The image of the service must provide curl or wget
+func (service *Service) GetWorkerScript() string {
+	return fmt.Sprintf(`request() {
+  if command -v curl >/dev/null 2>&1; then
+    curl -sf -X POST -H "Authorization: Bearer $%[1]s" "$1"
+  else
+    wget -q -O - --post-data "" --header "Authorization: Bearer $%[1]s" "$1"
+  fi
+}
+while %[4]s="$(cat /proc/sys/kernel/random/uuid)" && %[3]s="$(request "$%[2]s?%[7]s=$%[4]s")" && [ -n "$%[3]s" ]; do
+  export %[3]s %[4]s
+  if echo "$%[3]s" | %[5]s; then
+    request "$%[6]s?%[7]s=$%[4]s" >/dev/null
+  else
+    request "$%[6]s?%[7]s=$%[4]s&%[8]s=true" >/dev/null
+  fi
+done`, QueueTokenVariable, QueueURLVariable, EventVariable, JobUUIDVariable, service.GetSupervisorPath(),
+		QueueAckURLVariable, QueueLeaseParam, QueueRequeueParam)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "testing"
+
+func TestValidateWorkers(t *testing.T) {
+	batch := &Service{Workers: &WorkerPool{}}
+	batch.Batch.Size = 2
+	scenarios := []struct {
+		name        string
+		service     *Service
+		returnError bool
+	}{
+		{"without workers", &Service{}, false},
+		{"defaults", &Service{Workers: &WorkerPool{}}, false},
+		{"negative", &Service{Workers: &WorkerPool{MaxReplicas: -1}}, true},
+		{"negative visibility timeout", &Service{Workers: &WorkerPool{VisibilityTimeout: -1}}, true},
+		{"batch", batch, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.service.ValidateWorkers(); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+
+	w := &WorkerPool{}
+	if w.GetMaxReplicas() != 10 || w.GetEventsPerWorker() != 1 || w.GetPollingInterval() != 30 {
+		t.Errorf("unexpected defaults: %d, %d, %d", w.GetMaxReplicas(), w.GetEventsPerWorker(), w.GetPollingInterval())
+	}
+	if w.GetMaxQueuedEvents() != 1000 || w.GetVisibilityTimeout() != 600 || w.GetMaxAttempts() != 3 {
+		t.Errorf("unexpected queue defaults: %d, %d, %d", w.GetMaxQueuedEvents(), w.GetVisibilityTimeout(), w.GetMaxAttempts())
+	}
+}
+
+func TestIsValidQueueLease(t *testing.T) {
+	for lease, expected := range map[string]bool{
+		"8b4e0f5c-3a3f-4a0e-9d7c-1f2e3d4c5b6a": true,
+		"":                                     false,
+		"a&requeue=true":                       false,
+		"../next":                              false,
+	} {
+		if IsValidQueueLease(lease) != expected {
+			t.Errorf("expecting %v for lease \"%s\"", expected, lease)
+		}
+	}
+}
+
+func TestCheckQueueToken(t *testing.T) {
+	service := &Service{Name: "test", Token: "token"}
+	token := service.GetQueueToken()
+	if token == service.Token {
+		t.Error("expecting a queue token different from the service token")
+	}
+	if !service.CheckQueueToken(token) {
+		t.Error("expecting the queue token valid")
+	}
+	if service.CheckQueueToken("") || service.CheckQueueToken(service.Token) {
+		t.Error("expecting the token invalid")
+	}
+	if (&Service{Name: "other", Token: "token"}).CheckQueueToken(token) {
+		t.Error("expecting the token of another service invalid")
+	}
+}
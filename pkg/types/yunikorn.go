@@ -0,0 +1,175 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// YunikornPlacementProvided the jobs are placed in the service's queue, created by OSCAR under the parent queue
+	YunikornPlacementProvided = "provided"
+	// YunikornPlacementRules the queue of the jobs is left to the placement rules of YuniKorn (no queue is created)
+	YunikornPlacementRules = "rules"
+
+	// YunikornQueueSetting name of the parent queue setting in YUNIKORN_VO_SETTINGS
+	YunikornQueueSetting = "queue"
+	// YunikornApplicationIDSetting name of the application ID scheme setting in YUNIKORN_VO_SETTINGS
+	YunikornApplicationIDSetting = "application_id"
+	// YunikornPlacementSetting name of the placement setting in YUNIKORN_VO_SETTINGS
+	YunikornPlacementSetting = "placement"
+)
+
+// yunikornQueueNameRegexp valid names of the YuniKorn queues
+var yunikornQueueNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_:#/@-]{1,64}$`)
+
+// YunikornSettings Apache YuniKorn application metadata of the service's jobs. The settings not defined in the
+// service are taken from the ones of its VO (YUNIKORN_VO_SETTINGS) or the OSCAR's defaults
+type YunikornSettings struct {
+	// Queue full name of the parent queue (e.g. "root.hpc.oscar") where the service's queue is created
+	// Optional. (default: YUNIKORN_PARENT_QUEUE, "root.oscar-queue")
+	Queue string `json:"queue,omitempty"`
+
+	// ApplicationID scheme of the YuniKorn application ID of the service's pods ("{service}" and "{vo}" are
+	// replaced by the name and the VO of the service)
+	// Optional. (default: YUNIKORN_APPLICATION_ID, "{service}")
+	ApplicationID string `json:"application_id,omitempty"`
+
+	// Placement "provided" to set the service's queue in the pods, or "rules" to leave it to the placement rules
+	// of YuniKorn
+	// Optional. (default: YUNIKORN_PLACEMENT, "provided")
+	Placement string `json:"placement,omitempty"`
+}
+
+// Set sets a setting by name
+func (ys *YunikornSettings) Set(name string, value string) error {
+	switch name {
+	case YunikornQueueSetting:
+		ys.Queue = value
+	case YunikornApplicationIDSetting:
+		ys.ApplicationID = value
+	case YunikornPlacementSetting:
+		ys.Placement = value
+	default:
+		return fmt.Errorf("unknown YuniKorn setting \"%s\"", name)
+	}
+	return nil
+}
+
+// merge returns the settings with the undefined ones taken from the defaults
+func (ys YunikornSettings) merge(defaults YunikornSettings) YunikornSettings {
+	if ys.Queue == "" {
+		ys.Queue = defaults.Queue
+	}
+	if ys.ApplicationID == "" {
+		ys.ApplicationID = defaults.ApplicationID
+	}
+	if ys.Placement == "" {
+		ys.Placement = defaults.Placement
+	}
+	return ys
+}
+
+// Validate checks the parent queue and the placement
+func (ys YunikornSettings) Validate() error {
+	if ys.Queue != "" {
+		if err := validateYunikornQueue(ys.Queue); err != nil {
+			return err
+		}
+	}
+	switch ys.Placement {
+	case "", YunikornPlacementProvided, YunikornPlacementRules:
+	default:
+		return fmt.Errorf("the YuniKorn placement \"%s\" is not valid (it must be \"%s\" or \"%s\")", ys.Placement, YunikornPlacementProvided, YunikornPlacementRules)
+	}
+	return nil
+}
+
+// validateYunikornQueue checks the full name of a YuniKorn queue, which must be in the root queue
+func validateYunikornQueue(queue string) error {
+	elems := strings.Split(queue, ".")
+	if elems[0] != YunikornRootQueue {
+		return fmt.Errorf("the YuniKorn queue \"%s\" must be in the \"%s\" queue", queue, YunikornRootQueue)
+	}
+	for _, elem := range elems {
+		if !yunikornQueueNameRegexp.MatchString(elem) {
+			return fmt.Errorf("the YuniKorn queue \"%s\" is not valid", queue)
+		}
+	}
+	return nil
+}
+
+// GetApplicationID returns the application ID of the service's pods
+func (ys YunikornSettings) GetApplicationID(service *Service) string {
+	return strings.NewReplacer("{service}", service.Name, "{vo}", service.VO).Replace(ys.ApplicationID)
+}
+
+// GetQueue returns the full name of the service's queue
+func (ys YunikornSettings) GetQueue(service *Service) string {
+	return fmt.Sprintf("%s.%s", ys.Queue, service.Name)
+}
+
+// GetYunikornSettings returns the YuniKorn settings of the service, merging the ones of the service, its VO and
+// the OSCAR's defaults
+func (service *Service) GetYunikornSettings(cfg *Config) YunikornSettings {
+	settings := YunikornSettings{}
+	if service.Yunikorn != nil {
+		settings = *service.Yunikorn
+	}
+	if voSettings, ok := cfg.GetYunikornVOSettings()[service.VO]; ok {
+		settings = settings.merge(voSettings)
+	}
+	settings = settings.merge(YunikornSettings{
+		Queue:         cfg.YunikornParentQueue,
+		ApplicationID: cfg.YunikornApplicationID,
+		Placement:     cfg.YunikornPlacement,
+	})
+	return settings.merge(YunikornSettings{
+		Queue:         fmt.Sprintf("%s.%s", YunikornRootQueue, YunikornOscarQueue),
+		ApplicationID: "{service}",
+		Placement:     YunikornPlacementProvided,
+	})
+}
+
+// ValidateYunikorn checks the YuniKorn settings of the service and its resulting application ID
+func (service *Service) ValidateYunikorn(cfg *Config) error {
+	if service.Yunikorn != nil {
+		if err := service.Yunikorn.Validate(); err != nil {
+			return err
+		}
+	}
+
+	settings := service.GetYunikornSettings(cfg)
+	appID := settings.GetApplicationID(service)
+	if errs := validation.IsValidLabelValue(appID); len(errs) > 0 || strings.ContainsAny(appID, "{}") || appID == "" {
+		return fmt.Errorf("the YuniKorn application ID \"%s\" (scheme \"%s\") is not valid", appID, settings.ApplicationID)
+	}
+	return nil
+}
+
+// SplitYunikornQueue splits the full name of a queue in the full name of its parent queue and its name
+func SplitYunikornQueue(queue string) (string, string) {
+	i := strings.LastIndex(queue, ".")
+	if i < 0 {
+		return "", queue
+	}
+	return queue[:i], queue[i+1:]
+}
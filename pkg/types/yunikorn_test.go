@@ -0,0 +1,94 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+)
+
+func TestGetYunikornSettings(t *testing.T) {
+	cfg := &Config{
+		YunikornParentQueue:   "root.oscar",
+		YunikornApplicationID: "oscar-{service}",
+		YunikornVOSettings: []string{
+			"vo.example.eu:queue=root.hpc.bio",
+			"vo.example.eu:application_id={vo}-{service}",
+			"vo.example.eu:unknown=x",
+			"vo.other.eu:placement=invalid",
+			"invalid",
+		},
+	}
+
+	scenarios := []struct {
+		name              string
+		service           *Service
+		expectedQueue     string
+		expectedAppID     string
+		expectedPlacement string
+	}{
+		{"defaults", &Service{Name: "test"}, "root.oscar.test", "oscar-test", YunikornPlacementProvided},
+		{"vo", &Service{Name: "test", VO: "vo.example.eu"}, "root.hpc.bio.test", "vo.example.eu-test", YunikornPlacementProvided},
+		{"invalid vo settings", &Service{Name: "test", VO: "vo.other.eu"}, "root.oscar.test", "oscar-test", YunikornPlacementProvided},
+		{"service", &Service{Name: "test", VO: "vo.example.eu", Yunikorn: &YunikornSettings{Queue: "root.team", Placement: YunikornPlacementRules}}, "root.team.test", "vo.example.eu-test", YunikornPlacementRules},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			settings := s.service.GetYunikornSettings(cfg)
+			if queue := settings.GetQueue(s.service); queue != s.expectedQueue {
+				t.Errorf("expecting queue \"%s\", got \"%s\"", s.expectedQueue, queue)
+			}
+			if appID := settings.GetApplicationID(s.service); appID != s.expectedAppID {
+				t.Errorf("expecting application ID \"%s\", got \"%s\"", s.expectedAppID, appID)
+			}
+			if settings.Placement != s.expectedPlacement {
+				t.Errorf("expecting placement \"%s\", got \"%s\"", s.expectedPlacement, settings.Placement)
+			}
+		})
+	}
+
+	// The OSCAR's defaults are used if not configured
+	service := &Service{Name: "test"}
+	if queue := service.GetYunikornSettings(&Config{}).GetQueue(service); queue != "root.oscar-queue.test" {
+		t.Errorf("expecting queue \"root.oscar-queue.test\", got \"%s\"", queue)
+	}
+}
+
+func TestValidateYunikorn(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		settings    *YunikornSettings
+		returnError bool
+	}{
+		{"no settings", nil, false},
+		{"valid", &YunikornSettings{Queue: "root.hpc.oscar", ApplicationID: "{vo}-{service}", Placement: YunikornPlacementRules}, false},
+		{"not in root", &YunikornSettings{Queue: "hpc.oscar"}, true},
+		{"empty queue name", &YunikornSettings{Queue: "root..oscar"}, true},
+		{"invalid placement", &YunikornSettings{Placement: "fixed"}, true},
+		{"unknown placeholder", &YunikornSettings{ApplicationID: "{user}-{service}"}, true},
+		{"invalid application ID", &YunikornSettings{ApplicationID: "app {service}"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			service := &Service{Name: "test", VO: "vo.example.eu", Yunikorn: s.settings}
+			if err := service.ValidateYunikorn(&Config{}); (err != nil) != s.returnError {
+				t.Errorf("expecting error %v, got %v", s.returnError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CreateAlias stores an invocation alias. The Kubernetes AlreadyExists error is returned if the alias is
+// already registered (for any service)
+func CreateAlias(kubeClientset kubernetes.Interface, namespace string, alias *types.InvocationAlias) error {
+	data, err := json.Marshal(alias)
+	if err != nil {
+		return err
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.AliasConfigMapName(alias.Alias),
+			Namespace: namespace,
+			Labels: map[string]string{
+				types.ServiceLabel: alias.Service,
+				types.AliasLabel:   alias.Alias,
+			},
+		},
+		Data: map[string]string{
+			types.AliasFileName: string(data),
+		},
+	}
+	_, err = kubeClientset.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+	return err
+}
+
+// GetAlias returns an invocation alias. The Kubernetes NotFound error is returned if the alias does not exist
+func GetAlias(kubeClientset kubernetes.Interface, namespace string, name string) (*types.InvocationAlias, error) {
+	cm, err := kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), types.AliasConfigMapName(name), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return getConfigMapAlias(cm)
+}
+
+// ListServiceAliases returns the invocation aliases of a service sorted by name
+func ListServiceAliases(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]types.InvocationAlias, error) {
+	cms, err := listAliasConfigMaps(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := []types.InvocationAlias{}
+	for _, cm := range cms {
+		alias, err := getConfigMapAlias(&cm)
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, *alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Alias < aliases[j].Alias
+	})
+	return aliases, nil
+}
+
+// DeleteAlias removes an invocation alias
+func DeleteAlias(kubeClientset kubernetes.Interface, namespace string, name string) error {
+	return kubeClientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), types.AliasConfigMapName(name), metav1.DeleteOptions{})
+}
+
+// DeleteServiceAliases removes all the invocation aliases of a service
+func DeleteServiceAliases(kubeClientset kubernetes.Interface, namespace string, serviceName string) error {
+	cms, err := listAliasConfigMaps(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return err
+	}
+	for _, cm := range cms {
+		if err := kubeClientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), cm.Name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("error removing the alias \"%s\": %v", cm.Labels[types.AliasLabel], err)
+		}
+	}
+	return nil
+}
+
+// listAliasConfigMaps returns the configMaps storing the invocation aliases of a service
+func listAliasConfigMaps(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]v1.ConfigMap, error) {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s", types.ServiceLabel, serviceName, types.AliasLabel),
+	}
+	cms, err := kubeClientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the aliases of service \"%s\": %v", serviceName, err)
+	}
+	return cms.Items, nil
+}
+
+// getConfigMapAlias returns the invocation alias stored in an alias' configMap
+func getConfigMapAlias(cm *v1.ConfigMap) (*types.InvocationAlias, error) {
+	alias := &types.InvocationAlias{}
+	if err := json.Unmarshal([]byte(cm.Data[types.AliasFileName]), alias); err != nil {
+		return nil, fmt.Errorf("the alias \"%s\" cannot be read: %v", cm.Name, err)
+	}
+	return alias, nil
+}
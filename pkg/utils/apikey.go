@@ -0,0 +1,196 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// apiKeysRefreshInterval time that the API keys are cached to authenticate the requests, so the keys created and
+// revoked through other replicas of the OSCAR manager are applied
+const apiKeysRefreshInterval = 10 * time.Second
+
+var (
+	// ErrAPIKeyNotFound returned when the API key does not exist
+	ErrAPIKeyNotFound = errors.New("the API key does not exist")
+	// ErrAPIKeyAlreadyExists returned when an API key with the same name already exists
+	ErrAPIKeyAlreadyExists = errors.New("an API key with the same name already exists")
+
+	apiKeysLogger = log.New(os.Stdout, "[API-KEYS] ", log.Flags())
+)
+
+// APIKeys static API keys of the OSCAR manager, stored (hashed) in the APIKeysSecretName secret to be shared by the
+// replicas of the OSCAR manager
+type APIKeys struct {
+	kubeClientset kubernetes.Interface
+	namespace     string
+	mutex         sync.Mutex
+	keys          []types.APIKey
+	refreshedAt   time.Time
+}
+
+// NewAPIKeys creates the store of the API keys
+func NewAPIKeys(cfg *types.Config, kubeClientset kubernetes.Interface) *APIKeys {
+	return &APIKeys{
+		kubeClientset: kubeClientset,
+		namespace:     cfg.ServicesNamespace,
+	}
+}
+
+// List returns the API keys (including the expired ones)
+func (k *APIKeys) List() ([]types.APIKey, error) {
+	keys, _, err := k.read()
+	return keys, err
+}
+
+// Create creates an API key, returning it with its value (only available in the response)
+func (k *APIKeys) Create(req types.APIKeyRequest, createdBy string) (types.APIKey, error) {
+	value := types.APIKeyPrefix + GenerateToken()
+	key := types.APIKey{
+		Name:      req.Name,
+		Role:      req.Role,
+		Hash:      types.HashServiceToken(value),
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: req.ExpiresAt.UTC(),
+	}
+
+	err := k.update(func(keys []types.APIKey) ([]types.APIKey, error) {
+		for _, existing := range keys {
+			if existing.Name == key.Name {
+				return nil, ErrAPIKeyAlreadyExists
+			}
+		}
+		return append(keys, key), nil
+	})
+	if err != nil {
+		return key, err
+	}
+
+	key.Hash = ""
+	key.Key = value
+	return key, nil
+}
+
+// Revoke removes an API key, which is no longer accepted
+func (k *APIKeys) Revoke(name string) error {
+	return k.update(func(keys []types.APIKey) ([]types.APIKey, error) {
+		for i, existing := range keys {
+			if existing.Name == name {
+				return append(keys[:i], keys[i+1:]...), nil
+			}
+		}
+		return nil, ErrAPIKeyNotFound
+	})
+}
+
+// Authenticate returns the API key with that value if it exists and has not expired, reading the keys again if the
+// cached ones have expired
+func (k *APIKeys) Authenticate(value string) (types.APIKey, bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if time.Since(k.refreshedAt) >= apiKeysRefreshInterval {
+		keys, _, err := k.read()
+		if err != nil {
+			apiKeysLogger.Printf("Error reading the API keys, using the cached ones: %v\n", err)
+		} else {
+			k.keys = keys
+		}
+		k.refreshedAt = time.Now()
+	}
+
+	hash := types.HashServiceToken(value)
+	now := time.Now()
+	for _, key := range k.keys {
+		if key.Check(hash, now) {
+			return key, true
+		}
+	}
+	return types.APIKey{}, false
+}
+
+// read reads the API keys from their secret (none if it does not exist)
+func (k *APIKeys) read() ([]types.APIKey, *v1.Secret, error) {
+	keys := []types.APIKey{}
+	secret, err := k.kubeClientset.CoreV1().Secrets(k.namespace).Get(context.TODO(), types.APIKeysSecretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return keys, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if data, ok := secret.Data[types.APIKeysFileName]; ok {
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, nil, err
+		}
+	}
+	return keys, secret, nil
+}
+
+// update modifies the API keys stored in their secret, failing if it has been modified concurrently
+func (k *APIKeys) update(modify func([]types.APIKey) ([]types.APIKey, error)) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	keys, secret, err := k.read()
+	if err != nil {
+		return err
+	}
+	keys, err = modify(keys)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	secrets := k.kubeClientset.CoreV1().Secrets(k.namespace)
+	if secret == nil {
+		secret = &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      types.APIKeysSecretName,
+				Namespace: k.namespace,
+			},
+			Data: map[string][]byte{types.APIKeysFileName: data},
+		}
+		_, err = secrets.Create(context.TODO(), secret, metav1.CreateOptions{})
+	} else {
+		secret.Data[types.APIKeysFileName] = data
+		_, err = secrets.Update(context.TODO(), secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	k.keys = keys
+	k.refreshedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,286 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/uuid"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// auditBufferSize number of entries waiting to be written in the sink before blocking the requests
+const auditBufferSize = 1000
+
+// auditMaxLineSize maximum size of the lines of the audit log file
+const auditMaxLineSize = 1024 * 1024
+
+// Custom logger
+var auditLogger = log.New(os.Stdout, "[AUDIT] ", log.Flags())
+
+// auditSink destination of the audit entries
+type auditSink interface {
+	write(entry *types.AuditEntry) error
+	// list returns the most recent entries passing the filter, newest first
+	list(filter types.AuditFilter) ([]*types.AuditEntry, error)
+}
+
+// Auditor records the entries of the audit log in its sink, in the background and in the order they are recorded
+type Auditor struct {
+	sink    auditSink
+	entries chan *types.AuditEntry
+}
+
+// NewAuditor creates the auditor of the sink configured in AUDIT_SINK and starts writing its entries. Nil is
+// returned if the audit log is disabled
+func NewAuditor(cfg *types.Config) (*Auditor, error) {
+	var sink auditSink
+	switch cfg.AuditSink {
+	case "":
+		return nil, nil
+	case types.AuditSinkFile:
+		if err := os.MkdirAll(filepath.Dir(cfg.AuditFile), 0700); err != nil {
+			return nil, fmt.Errorf("error creating the directory of the audit log: %v", err)
+		}
+		sink = &fileAuditSink{path: cfg.AuditFile}
+	case types.AuditSinkMinIO:
+		sink = &minioAuditSink{cfg: cfg}
+	case types.AuditSinkSyslog:
+		network, address := "", ""
+		if cfg.AuditSyslogAddress != "" {
+			var ok bool
+			network, address, ok = strings.Cut(cfg.AuditSyslogAddress, "://")
+			if !ok || (network != "udp" && network != "tcp") {
+				return nil, fmt.Errorf("the syslog address of the audit log must be \"<udp|tcp>://<host>:<port>\"")
+			}
+		}
+		writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTHPRIV, "oscar")
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to the syslog server of the audit log: %v", err)
+		}
+		sink = &syslogAuditSink{writer: writer}
+	default:
+		return nil, fmt.Errorf("the sink of the audit log must be \"%s\", \"%s\" or \"%s\"", types.AuditSinkFile, types.AuditSinkMinIO, types.AuditSinkSyslog)
+	}
+
+	auditor := &Auditor{sink: sink, entries: make(chan *types.AuditEntry, auditBufferSize)}
+	go auditor.run()
+	return auditor, nil
+}
+
+// Record queues an entry to be written in the sink (no-op if the audit log is disabled)
+func (a *Auditor) Record(entry *types.AuditEntry) {
+	if a == nil {
+		return
+	}
+	a.entries <- entry
+}
+
+// List returns the most recent entries of the audit log passing the filter, newest first
+func (a *Auditor) List(filter types.AuditFilter) ([]*types.AuditEntry, error) {
+	if a == nil {
+		return nil, types.NewCodedError(types.ErrAuditUnavailable, errors.New("the audit log is not enabled"))
+	}
+	return a.sink.list(filter)
+}
+
+func (a *Auditor) run() {
+	for entry := range a.entries {
+		if err := a.sink.write(entry); err != nil {
+			auditLogger.Printf("error writing the entry of %s %s (%s): %v\n", entry.Method, entry.Path, entry.Subject, err)
+		}
+	}
+}
+
+// fileAuditSink appends the entries to a file (one JSON object per line)
+type fileAuditSink struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func (s *fileAuditSink) write(entry *types.AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func (s *fileAuditSink) list(filter types.AuditFilter) ([]*types.AuditEntry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*types.AuditEntry{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	// Keep the last matching entries
+	entries := []*types.AuditEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), auditMaxLineSize)
+	for scanner.Scan() {
+		entry := &types.AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil || !filter.Matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > filter.Limit {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// minioAuditSink stores each entry as an object of the AUDIT_BUCKET of the OSCAR's MinIO, whose key
+// ("<YYYY>/<MM>/<DD>/<UnixNano>-<ID>.json") sorts them by time
+type minioAuditSink struct {
+	cfg           *types.Config
+	bucketCreated bool
+}
+
+func (s *minioAuditSink) write(entry *types.AuditEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s3Client := s.cfg.MinIOProvider.GetS3Client()
+	if !s.bucketCreated {
+		_, err := s3Client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(s.cfg.AuditBucket)})
+		if aerr, ok := err.(awserr.Error); err != nil && !(ok && (aerr.Code() == s3.ErrCodeBucketAlreadyExists || aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou)) {
+			return fmt.Errorf("error creating bucket %s: %v", s.cfg.AuditBucket, err)
+		}
+		s.bucketCreated = true
+	}
+
+	_, err = s3Client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.cfg.AuditBucket),
+		Key:         aws.String(getAuditEntryKey(entry.Time, uuid.New().String()[:8])),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s *minioAuditSink) list(filter types.AuditFilter) ([]*types.AuditEntry, error) {
+	s3Client := s.cfg.MinIOProvider.GetS3Client()
+
+	// Select the keys of the time range by their time
+	keys := []string{}
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(s.cfg.AuditBucket)}
+	if !filter.Since.IsZero() {
+		input.StartAfter = aws.String(getAuditEntryKey(filter.Since, ""))
+	}
+	err := s3Client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			if !filter.Until.IsZero() && key >= getAuditEntryKey(filter.Until, "") {
+				return false
+			}
+			keys = append(keys, key)
+		}
+		return true
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+			return []*types.AuditEntry{}, nil
+		}
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	entries := []*types.AuditEntry{}
+	for _, key := range keys {
+		out, err := s3Client.GetObject(&s3.GetObjectInput{Bucket: aws.String(s.cfg.AuditBucket), Key: aws.String(key)})
+		if err != nil {
+			return nil, err
+		}
+		entry := &types.AuditEntry{}
+		err = json.NewDecoder(out.Body).Decode(entry)
+		out.Body.Close()
+		if err != nil || !filter.Matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// getAuditEntryKey returns the key of an audit entry (the prefix of the entries of a time if id is empty)
+func getAuditEntryKey(t time.Time, id string) string {
+	t = t.UTC()
+	key := fmt.Sprintf("%s/%019d", t.Format("2006/01/02"), t.UnixNano())
+	if id == "" {
+		return key
+	}
+	return fmt.Sprintf("%s-%s.json", key, id)
+}
+
+// syslogAuditSink sends the entries (as JSON) to a syslog server. The entries can't be listed
+type syslogAuditSink struct {
+	writer *syslog.Writer
+}
+
+func (s *syslogAuditSink) write(entry *types.AuditEntry) error {
+	msg, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.writer.Info(string(msg))
+}
+
+func (s *syslogAuditSink) list(filter types.AuditFilter) ([]*types.AuditEntry, error) {
+	return nil, types.NewCodedError(types.ErrAuditUnavailable, errors.New("the entries of the audit log sent to syslog can not be listed"))
+}
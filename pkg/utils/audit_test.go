@@ -0,0 +1,76 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestFileAuditSink(t *testing.T) {
+	sink := &fileAuditSink{path: filepath.Join(t.TempDir(), "audit.log")}
+
+	// The entries of a missing file are empty
+	if entries, err := sink.list(types.AuditFilter{Limit: 10}); err != nil || len(entries) != 0 {
+		t.Fatalf("expecting no entries, got %v (%v)", entries, err)
+	}
+
+	now := time.Now().UTC()
+	for i, subject := range []string{"alice", "bob", "alice", "alice"} {
+		sink.write(&types.AuditEntry{Time: now.Add(time.Duration(i) * time.Minute), Subject: subject, Operation: types.AuditOperationCreate})
+	}
+
+	entries, err := sink.list(types.AuditFilter{Subject: "alice", Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || !entries[0].Time.Equal(now.Add(3*time.Minute)) || !entries[1].Time.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("expecting the 2 most recent entries of alice, got %+v", entries)
+	}
+
+	entries, _ = sink.list(types.AuditFilter{Since: now.Add(time.Minute), Until: now.Add(3 * time.Minute), Limit: 10})
+	if len(entries) != 2 {
+		t.Errorf("expecting 2 entries in the time range, got %d", len(entries))
+	}
+}
+
+func TestGetAuditEntryKey(t *testing.T) {
+	t1 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := t1.Add(time.Nanosecond)
+	key1, key2 := getAuditEntryKey(t1, "aaaa"), getAuditEntryKey(t2, "0000")
+	if key1 >= key2 || key1[:11] != "2026/01/02/" {
+		t.Errorf("unexpected keys: %s, %s", key1, key2)
+	}
+	if prefix := getAuditEntryKey(t2, ""); prefix <= key1 || prefix >= key2 {
+		t.Errorf("the prefix %s must sort between %s and %s", prefix, key1, key2)
+	}
+}
+
+func TestNewAuditor(t *testing.T) {
+	if auditor, err := NewAuditor(&types.Config{}); auditor != nil || err != nil {
+		t.Errorf("expecting no auditor, got %v (%v)", auditor, err)
+	}
+	if _, err := NewAuditor(&types.Config{AuditSink: "kafka"}); err == nil {
+		t.Error("expecting an error for an unknown sink")
+	}
+	if _, err := NewAuditor(&types.Config{AuditSink: types.AuditSinkSyslog, AuditSyslogAddress: "host:514"}); err == nil {
+		t.Error("expecting an error for a syslog address without protocol")
+	}
+}
@@ -23,25 +23,27 @@ import (
 	"github.com/grycap/oscar/v2/pkg/types"
 )
 
-// GetAuthMiddleware returns the appropriate gin auth middleware
-func GetAuthMiddleware(cfg *types.Config) gin.HandlerFunc {
+// GetAuthMiddleware returns the appropriate gin auth middleware, accepting also the users of the user directory
+// (if any) with basic auth and the client certificates signed by CLIENT_CERT_CA if configured
+func GetAuthMiddleware(cfg *types.Config, users *UserDirectory) (gin.HandlerFunc, error) {
+	var handler gin.HandlerFunc
 	if !cfg.OIDCEnable {
-		return gin.BasicAuth(gin.Accounts{
-			// Use the config's username and password for basic auth
-			cfg.Username: cfg.Password,
-		})
+		handler = getBasicAuthMiddleware(cfg, users)
+	} else {
+		handler = CustomAuth(cfg, users)
 	}
-	return CustomAuth(cfg)
+
+	if cfg.ClientCertCA != "" {
+		return getClientCertMiddleware(cfg, handler)
+	}
+	return handler, nil
 }
 
 // CustomAuth returns a custom auth handler (gin middleware)
-func CustomAuth(cfg *types.Config) gin.HandlerFunc {
-	basicAuthHandler := gin.BasicAuth(gin.Accounts{
-		// Use the config's username and password for basic auth
-		cfg.Username: cfg.Password,
-	})
+func CustomAuth(cfg *types.Config, users *UserDirectory) gin.HandlerFunc {
+	basicAuthHandler := getBasicAuthMiddleware(cfg, users)
 
-	oidcHandler := getOIDCMiddleware(cfg.OIDCIssuer, cfg.OIDCSubject, cfg.OIDCGroups)
+	oidcHandler := getOIDCMiddleware(cfg)
 
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
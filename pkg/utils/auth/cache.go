@@ -0,0 +1,154 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultTokenCacheSize maximum number of tokens cached by each OIDC manager
+	defaultTokenCacheSize = 1000
+	// defaultTokenTTL time to cache the tokens without expiration
+	defaultTokenTTL = 5 * time.Minute
+	// tokenCacheSweepInterval interval to remove the expired tokens from the cache
+	tokenCacheSweepInterval = time.Minute
+)
+
+// tokenCache concurrency-safe cache of the user info of the tokens, removing them on expiration and the least
+// recently used ones when it is full
+type tokenCache struct {
+	maxEntries    int
+	sweepInterval time.Duration
+	entries       map[string]*list.Element
+	// lru tokens ordered from the most to the least recently used
+	lru      *list.List
+	sweeping bool
+	mutex    sync.Mutex
+}
+
+// tokenCacheEntry user info of a cached token
+type tokenCacheEntry struct {
+	rawToken  string
+	ui        *userInfo
+	expiresAt time.Time
+}
+
+// newTokenCache returns a new tokenCache storing up to maxEntries tokens
+func newTokenCache(maxEntries int) *tokenCache {
+	return &tokenCache{
+		maxEntries:    maxEntries,
+		sweepInterval: tokenCacheSweepInterval,
+		entries:       map[string]*list.Element{},
+		lru:           list.New(),
+	}
+}
+
+// get returns the user info of a token, if it is cached and not expired
+func (tc *tokenCache) get(rawToken string) (*userInfo, bool) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	elem, ok := tc.entries[rawToken]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*tokenCacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		tc.remove(elem)
+		return nil, false
+	}
+	tc.lru.MoveToFront(elem)
+	return entry.ui, true
+}
+
+// set caches the user info of a token until expiresAt (or defaultTokenTTL if it is zero), evicting the least
+// recently used tokens if the cache is full
+func (tc *tokenCache) set(rawToken string, ui *userInfo, expiresAt time.Time) {
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(defaultTokenTTL)
+	}
+
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if elem, ok := tc.entries[rawToken]; ok {
+		elem.Value = &tokenCacheEntry{rawToken: rawToken, ui: ui, expiresAt: expiresAt}
+		tc.lru.MoveToFront(elem)
+		return
+	}
+	tc.entries[rawToken] = tc.lru.PushFront(&tokenCacheEntry{rawToken: rawToken, ui: ui, expiresAt: expiresAt})
+	for tc.maxEntries > 0 && tc.lru.Len() > tc.maxEntries {
+		tc.remove(tc.lru.Back())
+	}
+
+	// Start the sweeper, that stops when the cache is empty
+	if !tc.sweeping {
+		tc.sweeping = true
+		go tc.sweep()
+	}
+}
+
+// len returns the number of cached tokens (including the expired ones not removed yet)
+func (tc *tokenCache) len() int {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	return tc.lru.Len()
+}
+
+// clearExpired removes the expired tokens, returning the number of tokens left
+func (tc *tokenCache) clearExpired() int {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	now := time.Now()
+	for elem := tc.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if !now.Before(elem.Value.(*tokenCacheEntry).expiresAt) {
+			tc.remove(elem)
+		}
+		elem = next
+	}
+	return tc.lru.Len()
+}
+
+// sweep removes periodically the expired tokens until the cache is empty
+func (tc *tokenCache) sweep() {
+	ticker := time.NewTicker(tc.sweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if tc.clearExpired() > 0 {
+			continue
+		}
+		tc.mutex.Lock()
+		// Check again, a token could be cached after clearing the expired ones
+		if tc.lru.Len() == 0 {
+			tc.sweeping = false
+			tc.mutex.Unlock()
+			return
+		}
+		tc.mutex.Unlock()
+	}
+}
+
+// remove removes a cached token, the mutex must be locked
+func (tc *tokenCache) remove(elem *list.Element) {
+	tc.lru.Remove(elem)
+	delete(tc.entries, elem.Value.(*tokenCacheEntry).rawToken)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenCacheExpiry(t *testing.T) {
+	tc := newTokenCache(10)
+	tc.set("valid", &userInfo{subject: "user"}, time.Now().Add(time.Hour))
+	tc.set("expired", &userInfo{subject: "user"}, time.Now().Add(-time.Second))
+
+	if ui, ok := tc.get("valid"); !ok || ui.subject != "user" {
+		t.Errorf("expecting the cached token, got %v", ui)
+	}
+	if _, ok := tc.get("expired"); ok {
+		t.Error("expecting the expired token not to be returned")
+	}
+	if tc.len() != 1 {
+		t.Errorf("expecting 1 cached token, got %d", tc.len())
+	}
+}
+
+func TestTokenCacheLRU(t *testing.T) {
+	tc := newTokenCache(2)
+	expiresAt := time.Now().Add(time.Hour)
+	tc.set("a", &userInfo{subject: "a"}, expiresAt)
+	tc.set("b", &userInfo{subject: "b"}, expiresAt)
+
+	// "a" becomes the most recently used, evicting "b"
+	tc.get("a")
+	tc.set("c", &userInfo{subject: "c"}, expiresAt)
+
+	if _, ok := tc.get("b"); ok {
+		t.Error("expecting the least recently used token to be evicted")
+	}
+	for _, token := range []string{"a", "c"} {
+		if _, ok := tc.get(token); !ok {
+			t.Errorf("expecting the token \"%s\" to be cached", token)
+		}
+	}
+}
+
+func TestTokenCacheSweep(t *testing.T) {
+	tc := newTokenCache(10)
+	tc.sweepInterval = 10 * time.Millisecond
+	tc.set("token", &userInfo{subject: "user"}, time.Now().Add(20*time.Millisecond))
+
+	time.Sleep(100 * time.Millisecond)
+
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if tc.lru.Len() != 0 || len(tc.entries) != 0 {
+		t.Errorf("expecting the expired token to be removed, got %d tokens", tc.lru.Len())
+	}
+	if tc.sweeping {
+		t.Error("expecting the sweeper to stop with the cache empty")
+	}
+}
+
+func TestTokenCacheConcurrency(t *testing.T) {
+	tc := newTokenCache(50)
+	expiresAt := time.Now().Add(time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				token := fmt.Sprintf("token-%d-%d", i, j)
+				tc.set(token, &userInfo{subject: token}, expiresAt)
+				tc.get(token)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if tc.len() != 50 {
+		t.Errorf("expecting 50 cached tokens, got %d", tc.len())
+	}
+}
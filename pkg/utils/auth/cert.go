@@ -0,0 +1,141 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// loadClientCAs reads the pool of CAs signing the client certificates
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the CAs of the client certificates: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("the file \"%s\" doesn't contain any PEM certificate", path)
+	}
+	return pool, nil
+}
+
+// GetTLSConfig returns the TLS configuration of the API server, requesting (but not requiring) the client
+// certificates signed by CLIENT_CERT_CA if configured
+func GetTLSConfig(cfg *types.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCertCA != "" {
+		pool, err := loadClientCAs(cfg.ClientCertCA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// getClientCertMiddleware returns the Gin's handler middleware to authenticate the users with their client
+// certificates, calling next for the requests without certificate
+func getClientCertMiddleware(cfg *types.Config, next gin.HandlerFunc) (gin.HandlerFunc, error) {
+	pool, err := loadClientCAs(cfg.ClientCertCA)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		// The header is only accepted from the trusted proxies, as the certificates are public
+		header := ""
+		if isTrustedProxy(c.RemoteIP(), cfg.GetTrustedProxies()) {
+			header = cfg.ClientCertHeader
+		}
+		cert, err := getClientCertificate(c.Request, header, pool)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if cert == nil {
+			next(c)
+			return
+		}
+
+		// Set the user mapped to the DN of the certificate as the authenticated user
+		user := cfg.GetClientCertUser(types.FormatDN(cert.Subject.ToRDNSequence()))
+		if user == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(gin.AuthUserKey, user)
+	}, nil
+}
+
+// isTrustedProxy checks if the IP of the peer of a connection is one of the trusted proxies (IPs or CIDRs)
+func isTrustedProxy(remoteIP string, proxies []string) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if _, cidr, err := net.ParseCIDR(proxy); err == nil {
+			if cidr.Contains(ip) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// getClientCertificate returns the client certificate verified in the TLS handshake or, if header is set, the one
+// passed in the header by the TLS-terminating proxy (verified against the pool of CAs). Nil is returned if the
+// request has no client certificate
+func getClientCertificate(req *http.Request, header string, pool *x509.CertPool) (*x509.Certificate, error) {
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+		return req.TLS.VerifiedChains[0][0], nil
+	}
+	if header == "" || req.Header.Get(header) == "" {
+		return nil, nil
+	}
+
+	pemString, err := url.QueryUnescape(req.Header.Get(header))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(pemString))
+	if block == nil {
+		return nil, errors.New("the client certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// testCertificate creates a certificate signed by parent (self-signed if nil)
+func testCertificate(t *testing.T, subject pkix.Name, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestClientCertMiddleware(t *testing.T) {
+	ca, caKey := testCertificate(t, pkix.Name{CommonName: "Grid CA"}, nil, nil)
+	other, otherKey := testCertificate(t, pkix.Name{CommonName: "Other CA"}, nil, nil)
+	alice, _ := testCertificate(t, pkix.Name{Organization: []string{"Example"}, CommonName: "Alice"}, ca, caKey)
+	bob, _ := testCertificate(t, pkix.Name{Organization: []string{"Example"}, CommonName: "Bob"}, ca, caKey)
+	forged, _ := testCertificate(t, pkix.Name{Organization: []string{"Example"}, CommonName: "Alice"}, other, otherKey)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600)
+
+	cfg := &types.Config{
+		Username:         "oscar",
+		Password:         "oscar",
+		ClientCertCA:     caPath,
+		ClientCertUsers:  []string{"/O=Example/CN=Alice=alice"},
+		ClientCertHeader: "ssl-client-cert",
+		TrustedProxies:   []string{"10.42.0.0/16"},
+	}
+	middleware, err := GetAuthMiddleware(cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/system/config", middleware, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(gin.AuthUserKey))
+	})
+
+	encode := func(cert *x509.Certificate) string {
+		return url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+	}
+	proxied := func(cert *x509.Certificate) func(req *http.Request) {
+		return func(req *http.Request) {
+			req.RemoteAddr = "10.42.0.5:41000"
+			req.Header.Set("ssl-client-cert", encode(cert))
+		}
+	}
+
+	scenarios := []struct {
+		name         string
+		prepare      func(req *http.Request)
+		expectedCode int
+		expectedUser string
+	}{
+		{"TLS certificate", func(req *http.Request) {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{alice, ca}}}
+		}, http.StatusOK, "alice"},
+		{"proxied certificate", proxied(alice), http.StatusOK, "alice"},
+		{"unmapped DN", proxied(bob), http.StatusUnauthorized, ""},
+		{"untrusted CA", proxied(forged), http.StatusUnauthorized, ""},
+		{"header from untrusted peer", func(req *http.Request) {
+			req.RemoteAddr = "10.1.0.7:41000"
+			req.Header.Set("ssl-client-cert", encode(alice))
+		}, http.StatusUnauthorized, ""},
+		{"basic auth", func(req *http.Request) { req.SetBasicAuth("oscar", "oscar") }, http.StatusOK, "oscar"},
+		{"no credentials", func(req *http.Request) {}, http.StatusUnauthorized, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/system/config", nil)
+			s.prepare(req)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != s.expectedCode {
+				t.Fatalf("expecting code %d, got %d", s.expectedCode, w.Code)
+			}
+			if s.expectedCode == http.StatusOK && w.Body.String() != s.expectedUser {
+				t.Errorf("expecting user \"%s\", got \"%s\"", s.expectedUser, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTLSConfig(t *testing.T) {
+	if _, err := GetTLSConfig(&types.Config{ClientCertCA: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("expecting an error for a missing CA bundle")
+	}
+	tlsConfig, err := GetTLSConfig(&types.Config{})
+	if err != nil || tlsConfig.ClientAuth != tls.NoClientCert {
+		t.Errorf("expecting no client certificates to be requested, got %v (%v)", tlsConfig, err)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// ldapTimeout timeout of the connections and searches of the LDAP server
+const ldapTimeout = 10 * time.Second
+
+// ldapUserStore user store authenticating the users against an LDAP server: the user is searched (with the
+// LDAP_BIND_DN account) and its password checked binding with its DN. The CNs of its groups are set as its groups
+type ldapUserStore struct {
+	cfg *types.Config
+}
+
+func (s *ldapUserStore) authenticate(username string, password string) (*types.User, error) {
+	conn, err := ldap.DialURL(s.cfg.LDAPURL, ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the LDAP server: %v", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(ldapTimeout)
+
+	if s.cfg.LDAPBindDN != "" {
+		if err := conn.Bind(s.cfg.LDAPBindDN, s.cfg.LDAPBindPassword); err != nil {
+			return nil, fmt.Errorf("error binding to the LDAP server: %v", err)
+		}
+	}
+	search := ldap.NewSearchRequest(s.cfg.LDAPBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout.Seconds()), false,
+		getLDAPUserFilter(s.cfg.LDAPUserFilter, username), []string{s.cfg.LDAPGroupAttribute}, nil)
+	result, err := conn.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("error searching the user in the LDAP server: %v", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, nil
+	}
+
+	// Check the password (not empty, as it would be an unauthenticated bind)
+	entry := result.Entries[0]
+	if password == "" {
+		return nil, nil
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error binding to the LDAP server: %v", err)
+	}
+
+	return &types.User{
+		Username: username,
+		Groups:   getLDAPGroups(entry.GetAttributeValues(s.cfg.LDAPGroupAttribute)),
+	}, nil
+}
+
+func (s *ldapUserStore) list() ([]types.User, error) {
+	return nil, ErrUserStoreReadOnly
+}
+
+func (s *ldapUserStore) modify(fn func(users map[string]*types.User) error) error {
+	return ErrUserStoreReadOnly
+}
+
+// getLDAPUserFilter returns the LDAP filter searching a user, escaping the username
+func getLDAPUserFilter(filter string, username string) string {
+	return fmt.Sprintf(filter, ldap.EscapeFilter(username))
+}
+
+// getLDAPGroups returns the names of the groups (the value of the first RDN of their DNs, usually their CN)
+func getLDAPGroups(dns []string) []string {
+	groups := []string{}
+	for _, dn := range dns {
+		parsed, err := ldap.ParseDN(dn)
+		if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+			groups = append(groups, dn)
+			continue
+		}
+		groups = append(groups, parsed.RDNs[0].Attributes[0].Value)
+	}
+	return groups
+}
@@ -18,61 +18,151 @@ package auth
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
 	"golang.org/x/oauth2"
 )
 
 // EGIGroupsURNPrefix prefix to identify EGI group URNs
-const EGIGroupsURNPrefix = "urn:mace:egi.eu:group"
+const EGIGroupsURNPrefix = types.EGIGroupsURNPrefix
 
 // oidcManager struct to represent a OIDC manager, including a cache of tokens
 type oidcManager struct {
-	provider   *oidc.Provider
-	config     *oidc.Config
-	subject    string
-	groups     []string
-	tokenCache map[string]*userInfo
+	// provider nil in offline mode with the JWKS URL of the issuer (without discovery)
+	provider *oidc.Provider
+	config   *oidc.Config
+	// verifier validates the tokens with the JWKS of the issuer, cached until a token is signed by an unknown key
+	verifier *oidc.IDTokenVerifier
+	issuer   types.OIDCIssuer
+	// groupsRegexp compiled groups pattern of the issuer, nil if it is not defined
+	groupsRegexp *regexp.Regexp
+	subject      string
+	groups       []string
+	// groupsFunc returns the groups granted to access the API, overriding groups (e.g. to add the VOs approved at
+	// runtime)
+	groupsFunc func() []string
+	tokenCache *tokenCache
 }
 
 // userInfo custom struct to store essential fields from UserInfo
 type userInfo struct {
 	subject string
 	groups  []string
+	// memberships groups of the user with their sub-groups and roles, to check the membership of the VOs
+	memberships []types.VOMembership
 }
 
 // newOIDCManager returns a new oidcManager or error if the oidc.Provider can't be created
 func NewOIDCManager(issuer string, subject string, groups []string) (*oidcManager, error) {
-	provider, err := oidc.NewProvider(context.TODO(), issuer)
+	return newIssuerManager(types.OIDCIssuer{Issuer: issuer, Subject: subject, Groups: groups})
+}
+
+// newIssuerManager returns a new oidcManager of a trusted issuer (with its subject, groups and claim names) or error
+// if the oidc.Provider can't be created. In offline mode with the JWKS URL of the issuer, the provider is not
+// discovered
+func newIssuerManager(issuer types.OIDCIssuer) (*oidcManager, error) {
+	groupsRegexp, err := issuer.GetGroupsRegexp()
 	if err != nil {
 		return nil, err
 	}
 
 	config := &oidc.Config{
-		SkipClientIDCheck: true,
+		ClientID:          issuer.Audience,
+		SkipClientIDCheck: issuer.Audience == "",
+	}
+
+	var provider *oidc.Provider
+	var verifier *oidc.IDTokenVerifier
+	if issuer.Offline && issuer.JWKSURL != "" {
+		verifier = oidc.NewVerifier(issuer.Issuer, oidc.NewRemoteKeySet(context.Background(), issuer.JWKSURL), config)
+	} else {
+		provider, err = oidc.NewProvider(context.TODO(), issuer.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		verifier = provider.Verifier(config)
 	}
 
 	return &oidcManager{
-		provider:   provider,
-		config:     config,
-		subject:    subject,
-		groups:     groups,
-		tokenCache: map[string]*userInfo{},
+		provider:     provider,
+		config:       config,
+		verifier:     verifier,
+		issuer:       issuer,
+		groupsRegexp: groupsRegexp,
+		subject:      issuer.Subject,
+		groups:       issuer.Groups,
+		tokenCache:   newTokenCache(defaultTokenCacheSize),
 	}, nil
 }
 
-// getIODCMiddleware returns the Gin's handler middleware to validate OIDC-based auth
-func getOIDCMiddleware(issuer string, subject string, groups []string) gin.HandlerFunc {
-	oidcManager, err := NewOIDCManager(issuer, subject, groups)
+// oidcManagers managers of the trusted issuers, created on the first token of each issuer
+type oidcManagers struct {
+	cfg      *types.Config
+	managers map[string]*oidcManager
+	mutex    sync.Mutex
+}
+
+// get returns the manager of the issuer of a token (selected by its "iss" claim) or error if the issuer is not
+// trusted or its oidc.Provider can't be created (it is retried with the next token)
+func (oms *oidcManagers) get(rawToken string) (*oidcManager, error) {
+	iss, err := getTokenIssuer(rawToken)
 	if err != nil {
-		return func(c *gin.Context) {
-			c.AbortWithStatus(http.StatusUnauthorized)
-		}
+		return nil, err
+	}
+	issuer := oms.cfg.GetOIDCIssuer(iss)
+	if issuer == nil {
+		return nil, fmt.Errorf("the issuer \"%s\" is not trusted", iss)
 	}
 
+	oms.mutex.Lock()
+	defer oms.mutex.Unlock()
+	if om, ok := oms.managers[issuer.Issuer]; ok {
+		return om, nil
+	}
+	om, err := newIssuerManager(*issuer)
+	if err != nil {
+		return nil, err
+	}
+	// The groups granted to access the API are obtained on each authorisation to add the VOs approved at runtime
+	om.groupsFunc = issuer.GetAllowedGroups
+	oms.managers[issuer.Issuer] = om
+	return om, nil
+}
+
+// getTokenIssuer returns the "iss" claim of a JWT, without verifying it
+func getTokenIssuer(rawToken string) (string, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("the token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", fmt.Errorf("the payload of the token can't be decoded: %v", err)
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("the payload of the token can't be decoded: %v", err)
+	}
+	return claims.Issuer, nil
+}
+
+// getIODCMiddleware returns the Gin's handler middleware to validate OIDC-based auth, trusting the OIDC_ISSUER and
+// the OIDC_ISSUERS of cfg
+func getOIDCMiddleware(cfg *types.Config) gin.HandlerFunc {
+	managers := &oidcManagers{cfg: cfg, managers: map[string]*oidcManager{}}
+
 	return func(c *gin.Context) {
 		// Get token from headers
 		authHeader := c.GetHeader("Authorization")
@@ -82,26 +172,44 @@ func getOIDCMiddleware(issuer string, subject string, groups []string) gin.Handl
 		}
 		rawToken := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Check the token
-		if !oidcManager.isAuthorised(rawToken) {
+		// Check the token with the manager of its issuer
+		oidcManager, err := managers.get(rawToken)
+		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		ui, authorised := oidcManager.isAuthorised(rawToken)
+		if !authorised {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// Set the subject as the authenticated user (e.g. the owner of the created services) and its groups
+		c.Set(gin.AuthUserKey, ui.subject)
+		c.Set(types.UserGroupsKey, ui.groups)
 	}
 }
 
-// clearExpired delete expired tokens from the cache
-func (om *oidcManager) clearExpired() {
-	for rawToken := range om.tokenCache {
-		_, err := om.provider.Verifier(om.config).Verify(context.TODO(), rawToken)
-		if err != nil {
-			delete(om.tokenCache, rawToken)
-		}
+// UserHasVO checks if the user of a token (of any trusted issuer) belongs to the VO, following its mapping in the
+// VO_MAPPING_FILE
+func UserHasVO(cfg *types.Config, rawToken string, vo string) (bool, error) {
+	oidcManager, err := (&oidcManagers{cfg: cfg, managers: map[string]*oidcManager{}}).get(rawToken)
+	if err != nil {
+		return false, err
 	}
+	return oidcManager.UserHasVO(rawToken, cfg.GetVOMapping(vo))
 }
 
-// getUserInfo obtains UserInfo from the issuer
+// getUserInfo obtains UserInfo from the issuer, or from the claims of the token in offline mode
 func (om *oidcManager) getUserInfo(rawToken string) (*userInfo, error) {
+	if om.issuer.Offline {
+		token, err := om.verifier.Verify(context.TODO(), rawToken)
+		if err != nil {
+			return nil, err
+		}
+		return om.getTokenUserInfo(token)
+	}
+
 	ot := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: rawToken})
 
 	// Get OIDC UserInfo
@@ -110,17 +218,116 @@ func (om *oidcManager) getUserInfo(rawToken string) (*userInfo, error) {
 		return nil, err
 	}
 
-	// Get "eduperson_entitlement" claims
-	var claims struct {
-		EdupersonEntitlement []string `json:"eduperson_entitlement"`
-	}
+	// Get the subject and groups claims of the issuer
+	claims := map[string]interface{}{}
 	ui.Claims(&claims)
+	return om.newUserInfo(ui.Subject, claims), nil
+}
+
+// getTokenUserInfo obtains the user info from the claims of a validated token, without requesting the issuer
+func (om *oidcManager) getTokenUserInfo(token *oidc.IDToken) (*userInfo, error) {
+	claims := map[string]interface{}{}
+	if err := token.Claims(&claims); err != nil {
+		return nil, err
+	}
+	return om.newUserInfo(token.Subject, claims), nil
+}
+
+// newUserInfo returns the user info with the subject and groups claims of the issuer
+func (om *oidcManager) newUserInfo(subject string, claims map[string]interface{}) *userInfo {
+	if claim := om.issuer.GetSubjectClaim(); claim != types.DefaultOIDCSubjectClaim {
+		subject, _ = getClaim(claims, claim).(string)
+	}
 
 	// Create "userInfo" struct and add the groups
+	claim := getClaim(claims, om.issuer.GetGroupsClaim())
 	return &userInfo{
-		subject: ui.Subject,
-		groups:  getGroups(claims.EdupersonEntitlement),
-	}, nil
+		subject:     subject,
+		groups:      om.getClaimGroups(claim),
+		memberships: om.getClaimMemberships(claim),
+	}
+}
+
+// getClaim returns the value of a claim, looking up the nested claims separated by dots (e.g. "realm_access.roles")
+// if there is no claim with the whole name (e.g. the "https://example.org/roles" namespaced claims of Auth0)
+func getClaim(claims map[string]interface{}, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+	var value interface{} = claims
+	for _, key := range strings.Split(name, ".") {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = nested[key]
+	}
+	return value
+}
+
+// getClaimValues returns the string values of a claim
+func getClaimValues(claim interface{}) []string {
+	values := []string{}
+	switch v := claim.(type) {
+	case string:
+		values = append(values, v)
+	case []interface{}:
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// getClaimGroups returns the groups (mapped to their names in OSCAR) of the groups claim of the issuer
+func (om *oidcManager) getClaimGroups(claim interface{}) []string {
+	values := getClaimValues(claim)
+
+	var groups []string
+	switch {
+	case om.groupsRegexp != nil:
+		for _, value := range values {
+			if group, ok := om.issuer.ExtractGroup(om.groupsRegexp, value); ok {
+				groups = append(groups, group)
+			}
+		}
+	case om.issuer.GetGroupsClaim() == types.DefaultOIDCGroupsClaim:
+		groups = getGroups(values)
+	default:
+		// Take the last segment of the group paths (e.g. "/team" in Keycloak)
+		for _, value := range values {
+			if group := value[strings.LastIndex(value, "/")+1:]; group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	for i, group := range groups {
+		groups[i] = om.issuer.MapGroup(group)
+	}
+	return groups
+}
+
+// getClaimMemberships returns the memberships of the groups claim of the issuer. The EGI entitlements keep their
+// sub-groups and roles (with the top-level group mapped to its name in OSCAR), the rest of the groups have no role
+func (om *oidcManager) getClaimMemberships(claim interface{}) []types.VOMembership {
+	memberships := []types.VOMembership{}
+	if om.groupsRegexp == nil && om.issuer.GetGroupsClaim() == types.DefaultOIDCGroupsClaim {
+		for _, value := range getClaimValues(claim) {
+			if membership, ok := types.ParseEGIEntitlement(value); ok {
+				top := membership.TopGroup()
+				membership.Group = om.issuer.MapGroup(top) + strings.TrimPrefix(membership.Group, top)
+				memberships = append(memberships, membership)
+			}
+		}
+		return memberships
+	}
+	for _, group := range om.getClaimGroups(claim) {
+		memberships = append(memberships, types.VOMembership{Group: group})
+	}
+	return memberships
 }
 
 // getGroups transforms "eduperson_entitlement" EGI URNs to a slice of group fields
@@ -140,57 +347,64 @@ func getGroups(urns []string) []string {
 	return groups
 }
 
-func (om *oidcManager) UserHasVO(rawToken string, vo string) (bool, error) {
+// UserHasVO checks if the user of a token belongs to a VO, with a group matching its mapping (see
+// types.VOMapping)
+func (om *oidcManager) UserHasVO(rawToken string, mapping types.VOMapping) (bool, error) {
 	ui, err := om.getUserInfo(rawToken)
 	if err != nil {
 		return false, err
 	}
-	for _, gr := range ui.groups {
-		if vo == gr {
-			return true, nil
-		}
-	}
-	return false, nil
+	return mapping.HasMember(ui.memberships), nil
 }
 
-// isAuthorised checks if a token is authorised to access the API
-func (om *oidcManager) isAuthorised(rawToken string) bool {
+// isAuthorised checks if a token is authorised to access the API, returning the user info of the token
+func (om *oidcManager) isAuthorised(rawToken string) (*userInfo, bool) {
+	// Inject the OIDC faults (only in chaos builds)
+	if err := chaos.Inject(chaos.TargetOIDC, "Verify"); err != nil {
+		return nil, false
+	}
+
 	// Check if the token is valid
-	_, err := om.provider.Verifier(om.config).Verify(context.TODO(), rawToken)
+	token, err := om.verifier.Verify(context.TODO(), rawToken)
 	if err != nil {
-		return false
+		return nil, false
 	}
 
 	// Check if token is in cache
-	ui, found := om.tokenCache[rawToken]
+	ui, found := om.tokenCache.get(rawToken)
 	if !found {
-		// Get userInfo from the issuer
-		ui, err = om.getUserInfo(rawToken)
+		// Get userInfo from the issuer, or from the validated token in offline mode
+		if om.issuer.Offline {
+			ui, err = om.getTokenUserInfo(token)
+		} else {
+			ui, err = om.getUserInfo(rawToken)
+		}
 		if err != nil {
-			return false
+			return nil, false
 		}
 
-		// Store userInfo in cache
-		om.tokenCache[rawToken] = ui
-
-		// Call clearExpired to delete expired tokens
-		om.clearExpired()
+		// Store userInfo in cache until the token expires
+		om.tokenCache.set(rawToken, ui, token.Expiry)
 	}
 
 	// Check if is authorised
 	// Same subject
 	if ui.subject == om.subject {
-		return true
+		return ui, true
 	}
 
 	// Groups
+	authGroups := om.groups
+	if om.groupsFunc != nil {
+		authGroups = om.groupsFunc()
+	}
 	for _, tokenGroup := range ui.groups {
-		for _, authGroup := range om.groups {
+		for _, authGroup := range authGroups {
 			if tokenGroup == authGroup {
-				return true
+				return ui, true
 			}
 		}
 	}
 
-	return false
+	return ui, false
 }
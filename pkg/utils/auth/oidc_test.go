@@ -0,0 +1,221 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestGetTokenIssuer(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://keycloak.example.org/realms/oscar","sub":"user"}`))
+	iss, err := getTokenIssuer("header." + payload + ".signature")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if iss != "https://keycloak.example.org/realms/oscar" {
+		t.Errorf("unexpected issuer: %s", iss)
+	}
+
+	if _, err := getTokenIssuer("not-a-jwt"); err == nil {
+		t.Error("expecting error, got nil")
+	}
+}
+
+func TestGetClaimGroups(t *testing.T) {
+	egi := &oidcManager{issuer: types.OIDCIssuer{GroupsMapping: map[string]string{"vo.old.eu": "vo.example.eu"}}}
+	groups := egi.getClaimGroups([]interface{}{
+		"urn:mace:egi.eu:group:vo.old.eu:role=member#aai.egi.eu",
+		"urn:mace:egi.eu:group:vo.other.eu:role=member#aai.egi.eu",
+	})
+	if expected := []string{"vo.example.eu", "vo.other.eu"}; !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expecting %v, got %v", expected, groups)
+	}
+
+	keycloak := &oidcManager{issuer: types.OIDCIssuer{GroupsClaim: "groups", GroupsMapping: map[string]string{"team": "vo.example.eu"}}}
+	groups = keycloak.getClaimGroups([]interface{}{"/team", "/dept/other"})
+	if expected := []string{"vo.example.eu", "other"}; !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expecting %v, got %v", expected, groups)
+	}
+}
+
+func TestGetClaimMemberships(t *testing.T) {
+	egi := &oidcManager{issuer: types.OIDCIssuer{GroupsMapping: map[string]string{"vo.old.eu": "vo.example.eu"}}}
+	memberships := egi.getClaimMemberships([]interface{}{
+		"urn:mace:egi.eu:group:vo.old.eu:sub:role=vm_operator#aai.egi.eu",
+		"urn:mace:egi.eu:group:vo.other.eu:role=member#aai.egi.eu",
+		"urn:mace:egi.eu:res:other",
+	})
+	expected := []types.VOMembership{{Group: "vo.example.eu:sub", Role: "vm_operator"}, {Group: "vo.other.eu", Role: "member"}}
+	if !reflect.DeepEqual(memberships, expected) {
+		t.Errorf("expecting %v, got %v", expected, memberships)
+	}
+
+	keycloak := &oidcManager{issuer: types.OIDCIssuer{GroupsClaim: "groups"}}
+	memberships = keycloak.getClaimMemberships([]interface{}{"/team"})
+	if expected := []types.VOMembership{{Group: "team"}}; !reflect.DeepEqual(memberships, expected) {
+		t.Errorf("expecting %v, got %v", expected, memberships)
+	}
+}
+
+func TestGetClaimGroupsPattern(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		issuer   types.OIDCIssuer
+		values   []interface{}
+		expected []string
+	}{
+		{"submatch", types.OIDCIssuer{GroupsPattern: `^oscar-(.+)$`}, []interface{}{"oscar-team", "admins"}, []string{"team"}},
+		{"whole match", types.OIDCIssuer{GroupsPattern: `^vo\.[a-z.]+$`}, []interface{}{"vo.example.eu", "offline_access"}, []string{"vo.example.eu"}},
+		{"template", types.OIDCIssuer{GroupsPattern: `^(?P<role>[a-z]+)@(?P<vo>[a-z.]+)$`, GroupsTemplate: "${vo}"}, []interface{}{"member@vo.example.eu"}, []string{"vo.example.eu"}},
+		{"mapping", types.OIDCIssuer{GroupsPattern: `^oscar-(.+)$`, GroupsMapping: map[string]string{"team": "vo.example.eu"}}, []interface{}{"oscar-team"}, []string{"vo.example.eu"}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			om := &oidcManager{issuer: s.issuer, groupsRegexp: regexp.MustCompile(s.issuer.GroupsPattern)}
+			if groups := om.getClaimGroups(s.values); !reflect.DeepEqual(groups, s.expected) {
+				t.Errorf("expecting %v, got %v", s.expected, groups)
+			}
+		})
+	}
+}
+
+func TestGetClaim(t *testing.T) {
+	claims := map[string]interface{}{
+		"groups":                    []interface{}{"team"},
+		"realm_access":              map[string]interface{}{"roles": []interface{}{"admin"}},
+		"https://example.org/roles": []interface{}{"user"},
+	}
+
+	if value := getClaim(claims, "groups"); !reflect.DeepEqual(value, []interface{}{"team"}) {
+		t.Errorf("unexpected claim: %v", value)
+	}
+	if value := getClaim(claims, "realm_access.roles"); !reflect.DeepEqual(value, []interface{}{"admin"}) {
+		t.Errorf("unexpected nested claim: %v", value)
+	}
+	if value := getClaim(claims, "https://example.org/roles"); !reflect.DeepEqual(value, []interface{}{"user"}) {
+		t.Errorf("unexpected namespaced claim: %v", value)
+	}
+	if value := getClaim(claims, "realm_access.groups"); value != nil {
+		t.Errorf("expecting nil, got %v", value)
+	}
+}
+
+// signTestToken returns a JWT with the claims signed (RS256) by the key
+func signTestToken(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatalf("error signing the token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOfflineAuthorisation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating the key: %v", err)
+	}
+
+	// Only the JWKS is served, the provider must not be discovered nor the user info requested
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/jwks" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	issuer := "https://keycloak.example.org/realms/oscar"
+	om, err := newIssuerManager(types.OIDCIssuer{
+		Issuer:      issuer,
+		Groups:      []string{"team"},
+		GroupsClaim: "groups",
+		Offline:     true,
+		Audience:    "oscar",
+		JWKSURL:     server.URL + "/jwks",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims := func(aud string, groups ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"iss":    issuer,
+			"sub":    "user",
+			"aud":    aud,
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"groups": groups,
+		}
+	}
+
+	scenarios := []struct {
+		name       string
+		token      string
+		authorised bool
+	}{
+		{"allowed group", signTestToken(t, key, claims("oscar", "/team")), true},
+		{"other group", signTestToken(t, key, claims("oscar", "/other")), false},
+		{"other audience", signTestToken(t, key, claims("other", "/team")), false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			ui, authorised := om.isAuthorised(s.token)
+			if authorised != s.authorised {
+				t.Errorf("expecting authorised %v, got %v", s.authorised, authorised)
+			}
+			if authorised && ui.subject != "user" {
+				t.Errorf("expecting the subject \"user\", got \"%s\"", ui.subject)
+			}
+		})
+	}
+
+	// The JWKS is cached
+	if requests != 1 {
+		t.Errorf("expecting 1 request to the issuer, got %d", requests)
+	}
+}
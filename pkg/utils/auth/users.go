@@ -0,0 +1,422 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// userAuthCacheTTL time that the successful authentications of the users are cached, avoiding the hashing of the
+// passwords (or the LDAP binds) in each request
+const userAuthCacheTTL = time.Minute
+
+var (
+	// ErrUserNotFound returned when the user does not exist
+	ErrUserNotFound = errors.New("the user does not exist")
+	// ErrUserAlreadyExists returned when a user with the same username already exists
+	ErrUserAlreadyExists = errors.New("a user with the same username already exists")
+	// ErrUserStoreReadOnly returned when the users of the store can't be managed by OSCAR (LDAP)
+	ErrUserStoreReadOnly = errors.New("the users of the store are managed externally")
+)
+
+// userStore backend of the UserDirectory
+type userStore interface {
+	// authenticate returns the user if the password is valid, nil otherwise
+	authenticate(username string, password string) (*types.User, error)
+	list() ([]types.User, error)
+	// modify applies the changes of fn to the users, stored if it doesn't fail
+	modify(fn func(users map[string]*types.User) error) error
+}
+
+// UserDirectory pluggable store of the users authenticated with basic auth besides the admin of the cluster, with
+// their roles and groups
+type UserDirectory struct {
+	store userStore
+	cache map[[sha256.Size]byte]userAuthCacheEntry
+	mutex sync.Mutex
+	// Managed option of the stores whose users are managed by OSCAR
+	Managed bool
+	// SCIM option of the stores provisioned through SCIM
+	SCIM bool
+}
+
+// userAuthCacheEntry cached authentication of a user
+type userAuthCacheEntry struct {
+	user      types.User
+	expiresAt time.Time
+}
+
+// NewUserDirectory creates the user directory of USER_STORE (nil if it is not set)
+func NewUserDirectory(cfg *types.Config) (*UserDirectory, error) {
+	users := &UserDirectory{cache: map[[sha256.Size]byte]userAuthCacheEntry{}}
+	switch cfg.UserStore {
+	case "":
+		return nil, nil
+	case types.UserStoreFile, types.UserStoreSCIM:
+		if err := os.MkdirAll(filepath.Dir(cfg.UserStoreFile), 0700); err != nil {
+			return nil, fmt.Errorf("error creating the directory of the user store: %v", err)
+		}
+		users.store = &fileUserStore{path: cfg.UserStoreFile}
+		users.Managed = true
+		users.SCIM = cfg.UserStore == types.UserStoreSCIM
+		if users.SCIM && cfg.SCIMToken == "" {
+			return nil, errors.New("the SCIM_TOKEN of the identity provider is required by the \"scim\" user store")
+		}
+	case types.UserStoreLDAP:
+		if cfg.LDAPURL == "" || cfg.LDAPBaseDN == "" {
+			return nil, errors.New("the LDAP_URL and LDAP_BASE_DN are required by the \"ldap\" user store")
+		}
+		users.store = &ldapUserStore{cfg: cfg}
+	default:
+		return nil, fmt.Errorf("the user store \"%s\" is not valid, it must be \"%s\", \"%s\" or \"%s\"", cfg.UserStore, types.UserStoreFile, types.UserStoreLDAP, types.UserStoreSCIM)
+	}
+	return users, nil
+}
+
+// Authenticate returns the user if the password is valid and it is not disabled
+func (d *UserDirectory) Authenticate(username string, password string) (*types.User, bool) {
+	if username == "" || password == "" {
+		return nil, false
+	}
+	key := sha256.Sum256([]byte(username + ":" + password))
+
+	d.mutex.Lock()
+	entry, ok := d.cache[key]
+	d.mutex.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return &entry.user, true
+	}
+
+	user, err := d.store.authenticate(username, password)
+	if err != nil || user == nil || user.Disabled {
+		return nil, false
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	now := time.Now()
+	for k, e := range d.cache {
+		if !now.Before(e.expiresAt) {
+			delete(d.cache, k)
+		}
+	}
+	d.cache[key] = userAuthCacheEntry{user: *user, expiresAt: now.Add(userAuthCacheTTL)}
+	return user, true
+}
+
+// List returns the users sorted by username (without the hashes of their passwords)
+func (d *UserDirectory) List() ([]types.User, error) {
+	if !d.Managed {
+		return nil, ErrUserStoreReadOnly
+	}
+	users, err := d.store.list()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].PasswordHash = ""
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
+}
+
+// Get returns a user (without the hash of its password)
+func (d *UserDirectory) Get(username string) (*types.User, error) {
+	users, err := d.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// Create creates a user with the (validated) request. The users without password (provisioned through SCIM) can't
+// authenticate until it is set
+func (d *UserDirectory) Create(req types.UserRequest) (*types.User, error) {
+	if !d.Managed {
+		return nil, ErrUserStoreReadOnly
+	}
+	hash, err := hashUserPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	user := types.User{
+		Username:     req.Username,
+		PasswordHash: hash,
+		Role:         req.Role,
+		Groups:       req.Groups,
+		Disabled:     req.Disabled != nil && *req.Disabled,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	err = d.modify(func(users map[string]*types.User) error {
+		if _, ok := users[user.Username]; ok {
+			return ErrUserAlreadyExists
+		}
+		stored := user
+		users[user.Username] = &stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+	return &user, nil
+}
+
+// Update updates the password, the role, the groups and the status of a user with the non-empty fields of the
+// (validated) request
+func (d *UserDirectory) Update(req types.UserRequest) (*types.User, error) {
+	if !d.Managed {
+		return nil, ErrUserStoreReadOnly
+	}
+	hash, err := hashUserPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated types.User
+	err = d.modify(func(users map[string]*types.User) error {
+		user, ok := users[req.Username]
+		if !ok {
+			return ErrUserNotFound
+		}
+		if hash != "" {
+			user.PasswordHash = hash
+		}
+		if req.Role != "" {
+			user.Role = req.Role
+		}
+		if req.Groups != nil {
+			user.Groups = req.Groups
+		}
+		if req.Disabled != nil {
+			user.Disabled = *req.Disabled
+		}
+		user.UpdatedAt = time.Now().UTC()
+		updated = *user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated.PasswordHash = ""
+	return &updated, nil
+}
+
+// Delete removes a user
+func (d *UserDirectory) Delete(username string) error {
+	if !d.Managed {
+		return ErrUserStoreReadOnly
+	}
+	return d.modify(func(users map[string]*types.User) error {
+		if _, ok := users[username]; !ok {
+			return ErrUserNotFound
+		}
+		delete(users, username)
+		return nil
+	})
+}
+
+// hashUserPassword returns the bcrypt hash of a password (empty if the password is empty)
+func hashUserPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// modify modifies the users of the store, clearing the cached authentications
+func (d *UserDirectory) modify(fn func(users map[string]*types.User) error) error {
+	if err := d.store.modify(fn); err != nil {
+		return err
+	}
+	d.mutex.Lock()
+	d.cache = map[[sha256.Size]byte]userAuthCacheEntry{}
+	d.mutex.Unlock()
+	return nil
+}
+
+// getBasicAuthMiddleware returns the Gin's handler middleware to authenticate the admin of the cluster and the users
+// of the user directory (if any) with basic auth, setting their roles and groups
+func getBasicAuthMiddleware(cfg *types.Config, users *UserDirectory) gin.HandlerFunc {
+	adminHandler := gin.BasicAuth(gin.Accounts{
+		// Use the config's username and password for basic auth
+		cfg.Username: cfg.Password,
+	})
+	if users == nil {
+		return adminHandler
+	}
+
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok || username == cfg.Username {
+			adminHandler(c)
+			return
+		}
+		user, ok := users.Authenticate(username, password)
+		if !ok {
+			c.Header("WWW-Authenticate", `Basic realm="Authorization Required"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(gin.AuthUserKey, user.Username)
+		if len(user.Groups) > 0 {
+			c.Set(types.UserGroupsKey, user.Groups)
+		}
+		if user.Role != "" {
+			c.Set(types.RoleKey, user.Role)
+		}
+	}
+}
+
+// fileUserStore user store in a JSON file, read again when it is modified externally
+type fileUserStore struct {
+	path    string
+	mutex   sync.Mutex
+	users   map[string]*types.User
+	modTime time.Time
+	size    int64
+}
+
+func (s *fileUserStore) authenticate(username string, password string) (*types.User, error) {
+	s.mutex.Lock()
+	if err := s.load(); err != nil {
+		s.mutex.Unlock()
+		return nil, err
+	}
+	user, ok := s.users[username]
+	var found types.User
+	if ok {
+		found = *user
+	}
+	s.mutex.Unlock()
+
+	if !ok || bcrypt.CompareHashAndPassword([]byte(found.PasswordHash), []byte(password)) != nil {
+		return nil, nil
+	}
+	return &found, nil
+}
+
+func (s *fileUserStore) list() ([]types.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	users := []types.User{}
+	for _, user := range s.users {
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+func (s *fileUserStore) modify(fn func(users map[string]*types.User) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	// Modify a copy, so the cached users are kept if the changes can't be stored
+	users := map[string]*types.User{}
+	for name, user := range s.users {
+		copied := *user
+		users[name] = &copied
+	}
+	if err := fn(users); err != nil {
+		return err
+	}
+
+	list := []types.User{}
+	for _, user := range users {
+		list = append(list, *user)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Username < list[j].Username
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error writing the user store: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error writing the user store: %v", err)
+	}
+
+	s.users = users
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+// load reads the users of the file if it has been modified (none if it does not exist). The mutex must be locked
+func (s *fileUserStore) load() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.users = map[string]*types.User{}
+		s.modTime, s.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading the user store: %v", err)
+	}
+	if s.users != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("error reading the user store: %v", err)
+	}
+	list := []types.User{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("error decoding the user store: %v", err)
+	}
+	s.users = map[string]*types.User{}
+	for i := range list {
+		s.users[list[i].Username] = &list[i]
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestNewUserDirectory(t *testing.T) {
+	scenarios := []struct {
+		name  string
+		cfg   types.Config
+		valid bool
+	}{
+		{"disabled", types.Config{}, true},
+		{"file", types.Config{UserStore: types.UserStoreFile, UserStoreFile: filepath.Join(t.TempDir(), "users.json")}, true},
+		{"scim without token", types.Config{UserStore: types.UserStoreSCIM, UserStoreFile: filepath.Join(t.TempDir(), "users.json")}, false},
+		{"ldap without server", types.Config{UserStore: types.UserStoreLDAP}, false},
+		{"ldap", types.Config{UserStore: types.UserStoreLDAP, LDAPURL: "ldap://ldap.example.org", LDAPBaseDN: "dc=example,dc=org"}, true},
+		{"unknown", types.Config{UserStore: "db"}, false},
+	}
+	for _, s := range scenarios {
+		if _, err := NewUserDirectory(&s.cfg); (err == nil) != s.valid {
+			t.Errorf("%s: expecting valid=%v, got %v", s.name, s.valid, err)
+		}
+	}
+}
+
+func TestUserDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oscar", "users.json")
+	users, err := NewUserDirectory(&types.Config{UserStore: types.UserStoreFile, UserStoreFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := users.Create(types.UserRequest{Username: "alice", Password: "password1", Role: types.RoleOperator, Groups: []string{"vo.example.eu"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Create(types.UserRequest{Username: "alice", Password: "password2"}); err != ErrUserAlreadyExists {
+		t.Errorf("expecting ErrUserAlreadyExists, got %v", err)
+	}
+	if _, ok := users.Authenticate("alice", "wrong-password"); ok {
+		t.Error("expecting the wrong password to be rejected")
+	}
+	user, ok := users.Authenticate("alice", "password1")
+	if !ok || user.Role != types.RoleOperator || !reflect.DeepEqual(user.Groups, []string{"vo.example.eu"}) {
+		t.Errorf("unexpected authenticated user %+v", user)
+	}
+
+	// Only the hashes of the passwords are stored, and they are not listed
+	data, _ := os.ReadFile(path)
+	if len(data) == 0 || strings.Contains(string(data), "password1") {
+		t.Errorf("expecting the hashed passwords in the store, got %s", data)
+	}
+	list, err := users.List()
+	if err != nil || len(list) != 1 || list[0].PasswordHash != "" {
+		t.Errorf("unexpected users %+v (%v)", list, err)
+	}
+
+	// The updates clear the cached authentications
+	disabled := true
+	if _, err := users.Update(types.UserRequest{Username: "alice", Disabled: &disabled}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := users.Authenticate("alice", "password1"); ok {
+		t.Error("expecting the disabled user to be rejected")
+	}
+	if _, err := users.Update(types.UserRequest{Username: "bob"}); err != ErrUserNotFound {
+		t.Errorf("expecting ErrUserNotFound, got %v", err)
+	}
+
+	// The users are read again from the file if it changes
+	other, _ := NewUserDirectory(&types.Config{UserStore: types.UserStoreFile, UserStoreFile: path})
+	if err := other.Delete("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Get("alice"); err != ErrUserNotFound {
+		t.Errorf("expecting the deleted user not to be found, got %v", err)
+	}
+}
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	cfg := &types.Config{Username: "oscar", Password: "oscar-password", UserStore: types.UserStoreFile, UserStoreFile: filepath.Join(t.TempDir(), "users.json")}
+	users, _ := NewUserDirectory(cfg)
+	users.Create(types.UserRequest{Username: "alice", Password: "password1", Role: types.RoleViewer})
+
+	middleware, err := GetAuthMiddleware(cfg, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/", middleware, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(gin.AuthUserKey)+"|"+c.GetString(types.RoleKey))
+	})
+
+	scenarios := []struct {
+		name         string
+		username     string
+		password     string
+		expectedCode int
+		expectedBody string
+	}{
+		{"admin", "oscar", "oscar-password", http.StatusOK, "oscar|"},
+		{"user", "alice", "password1", http.StatusOK, "alice|viewer"},
+		{"wrong password", "alice", "password2", http.StatusUnauthorized, ""},
+		{"admin's wrong password", "oscar", "password1", http.StatusUnauthorized, ""},
+	}
+	for _, s := range scenarios {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(s.username, s.password)
+		r.ServeHTTP(w, req)
+		if w.Code != s.expectedCode || w.Body.String() != s.expectedBody {
+			t.Errorf("%s: expecting %d (%s), got %d (%s)", s.name, s.expectedCode, s.expectedBody, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestLDAPHelpers(t *testing.T) {
+	if filter := getLDAPUserFilter("(uid=%s)", "alice*)(uid=*"); filter != `(uid=alice\2a\29\28uid=\2a)` {
+		t.Errorf("expecting the username to be escaped, got %s", filter)
+	}
+	groups := getLDAPGroups([]string{"cn=developers,ou=groups,dc=example,dc=org", "not a dn"})
+	if !reflect.DeepEqual(groups, []string{"developers", "not a dn"}) {
+		t.Errorf("unexpected groups %v", groups)
+	}
+}
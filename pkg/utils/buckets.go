@@ -0,0 +1,113 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetCreatedBuckets returns the identifiers of the buckets created by a service (empty if none has been recorded)
+func GetCreatedBuckets(kubeClientset kubernetes.Interface, namespace string, serviceName string) (map[string]bool, error) {
+	buckets := map[string]bool{}
+	cm, err := kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), types.CreatedBucketsConfigMapName(serviceName), metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return buckets, nil
+		}
+		return nil, fmt.Errorf("error reading the buckets created by the service \"%s\": %v", serviceName, err)
+	}
+	for _, id := range strings.Split(cm.Data[types.CreatedBucketsKey], "\n") {
+		if id != "" {
+			buckets[id] = true
+		}
+	}
+	return buckets, nil
+}
+
+// AddCreatedBuckets records the identifiers of buckets created by a service, along with the ones already recorded
+func AddCreatedBuckets(kubeClientset kubernetes.Interface, namespace string, serviceName string, ids []string) error {
+	return updateCreatedBuckets(kubeClientset, namespace, serviceName, func(buckets map[string]bool) {
+		for _, id := range ids {
+			buckets[id] = true
+		}
+	})
+}
+
+// RemoveCreatedBuckets removes the identifiers of buckets (e.g. deleted) from the ones recorded for a service
+func RemoveCreatedBuckets(kubeClientset kubernetes.Interface, namespace string, serviceName string, ids []string) error {
+	return updateCreatedBuckets(kubeClientset, namespace, serviceName, func(buckets map[string]bool) {
+		for _, id := range ids {
+			delete(buckets, id)
+		}
+	})
+}
+
+// DeleteCreatedBuckets removes the record of the buckets created by a service (the buckets are kept)
+func DeleteCreatedBuckets(kubeClientset kubernetes.Interface, namespace string, serviceName string) error {
+	err := kubeClientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), types.CreatedBucketsConfigMapName(serviceName), metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("error removing the buckets created by the service \"%s\": %v", serviceName, err)
+	}
+	return nil
+}
+
+// updateCreatedBuckets stores the buckets created by a service after applying fn to the recorded ones
+func updateCreatedBuckets(kubeClientset kubernetes.Interface, namespace string, serviceName string, fn func(buckets map[string]bool)) error {
+	buckets, err := GetCreatedBuckets(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return err
+	}
+	fn(buckets)
+
+	ids := []string{}
+	for id := range buckets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.CreatedBucketsConfigMapName(serviceName),
+			Namespace: namespace,
+			Labels: map[string]string{
+				types.ServiceLabel:        serviceName,
+				types.CreatedBucketsLabel: "true",
+			},
+		},
+		Data: map[string]string{
+			types.CreatedBucketsKey: strings.Join(ids, "\n"),
+		},
+	}
+	cms := kubeClientset.CoreV1().ConfigMaps(namespace)
+	_, err = cms.Create(context.TODO(), cm, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = cms.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error recording the buckets created by the service \"%s\": %v", serviceName, err)
+	}
+	return nil
+}
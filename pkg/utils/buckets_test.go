@@ -0,0 +1,55 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreatedBuckets(t *testing.T) {
+	kubeClientset := testclient.NewSimpleClientset()
+
+	if buckets, err := GetCreatedBuckets(kubeClientset, "oscar-svc", "test"); err != nil || len(buckets) != 0 {
+		t.Fatalf("expecting no created buckets, got %v (%v)", buckets, err)
+	}
+
+	if err := AddCreatedBuckets(kubeClientset, "oscar-svc", "test", []string{"minio/input", "minio/output"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AddCreatedBuckets(kubeClientset, "oscar-svc", "test", []string{"minio/output", "s3/results"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RemoveCreatedBuckets(kubeClientset, "oscar-svc", "test", []string{"minio/input"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buckets, err := GetCreatedBuckets(kubeClientset, "oscar-svc", "test")
+	if err != nil || len(buckets) != 2 || !buckets["minio/output"] || !buckets["s3/results"] {
+		t.Errorf("unexpected created buckets: %v (%v)", buckets, err)
+	}
+
+	if err := DeleteCreatedBuckets(kubeClientset, "oscar-svc", "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buckets, _ := GetCreatedBuckets(kubeClientset, "oscar-svc", "test"); len(buckets) != 0 {
+		t.Errorf("expecting the record to be removed, got %v", buckets)
+	}
+	if err := DeleteCreatedBuckets(kubeClientset, "oscar-svc", "test"); err != nil {
+		t.Errorf("unexpected error removing a missing record: %v", err)
+	}
+}
@@ -0,0 +1,434 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var buildLogger = log.New(os.Stdout, "[BUILD] ", log.Flags())
+
+const (
+	// buildWorkspace path where the build context is mounted in the build container
+	buildWorkspace = "/workspace"
+	// buildDockerConfigPath path where kaniko reads the registry credentials
+	buildDockerConfigPath = "/kaniko/.docker"
+)
+
+// getBuildJobName returns the name of the job (and of its secret) of a build
+func getBuildJobName(id string) string {
+	return "oscar-build-" + id
+}
+
+// CreateBuild creates the job that builds the image of the request with kaniko and pushes it to cfg.BuildsRegistry,
+// under the repositories of the owner. The build context and the service definition (if any, already validated) are
+// stored in a secret owned by the job, so they are removed with it
+func CreateBuild(cfg *types.Config, kubeClientset kubernetes.Interface, req *types.BuildRequest, owner string) (*types.Build, error) {
+	id := GenerateToken()[:16]
+	name := getBuildJobName(id)
+	image := req.GetImage(cfg.BuildsRegistry, owner)
+
+	secretData := map[string][]byte{}
+	args := []string{"--destination=" + image}
+	if req.Git != nil {
+		dockerfile := req.Git.Dockerfile
+		if dockerfile == "" {
+			dockerfile = "Dockerfile"
+		}
+		args = append(args, "--context="+req.Git.GetGitContext(), "--dockerfile="+dockerfile)
+		if req.Git.Path != "" {
+			args = append(args, "--context-sub-path="+req.Git.Path)
+		}
+	} else {
+		buildContext, err := makeBuildContext(req.Dockerfile, req.Context)
+		if err != nil {
+			return nil, types.NewCodedError(types.ErrBadRequest, err)
+		}
+		secretData[types.BuildContextKey] = buildContext
+		args = append(args, fmt.Sprintf("--context=tar://%s/%s", buildWorkspace, types.BuildContextKey), "--dockerfile=Dockerfile")
+	}
+	if cfg.BuildsRegistryInsecure {
+		args = append(args, "--insecure")
+	}
+
+	labels := map[string]string{types.BuildLabel: id}
+	annotations := map[string]string{types.BuildImageAnnotation: image, types.BuildOwnerAnnotation: owner}
+	if req.Service != nil {
+		definition, err := json.Marshal(req.Service)
+		if err != nil {
+			return nil, err
+		}
+		secretData[types.BuildServiceKey] = definition
+		labels[types.BuildServiceStatusLabel] = types.BuildPending
+		annotations[types.BuildServiceAnnotation] = req.Service.Name
+	}
+
+	job := makeBuildJob(cfg, name, args, len(secretData[types.BuildContextKey]) > 0)
+	job.Labels = labels
+	job.Annotations = annotations
+	job, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Create(context.TODO(), job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, types.NewCodedError(types.ErrBuildFailed, fmt.Errorf("error creating the build job: %v", err))
+	}
+
+	if len(secretData) > 0 {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cfg.ServicesNamespace,
+				Labels:    map[string]string{types.BuildLabel: id},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(job, batchv1.SchemeGroupVersion.WithKind("Job")),
+				},
+			},
+			Data: secretData,
+		}
+		if _, err := kubeClientset.CoreV1().Secrets(cfg.ServicesNamespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+			if err := deleteBuildJob(cfg, kubeClientset, name); err != nil {
+				buildLogger.Printf("error deleting the build job \"%s\": %v\n", name, err)
+			}
+			return nil, types.NewCodedError(types.ErrBuildFailed, fmt.Errorf("error storing the build context: %v", err))
+		}
+	}
+
+	return getBuild(job), nil
+}
+
+// makeBuildJob returns the job running kaniko with the provided arguments. The build context is mounted from the
+// build's secret if mountContext is true
+func makeBuildJob(cfg *types.Config, name string, args []string, mountContext bool) *batchv1.Job {
+	backoffLimit := int32(0)
+	container := v1.Container{
+		Name:  types.BuildContainerName,
+		Image: cfg.BuildsImage,
+		Args:  args,
+	}
+	podSpec := v1.PodSpec{
+		RestartPolicy: v1.RestartPolicyNever,
+		Containers:    []v1.Container{},
+		Volumes:       []v1.Volume{},
+	}
+
+	if mountContext {
+		podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+			Name: "context",
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: name,
+					Items:      []v1.KeyToPath{{Key: types.BuildContextKey, Path: types.BuildContextKey}},
+				},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      "context",
+			ReadOnly:  true,
+			MountPath: buildWorkspace,
+		})
+	}
+
+	if cfg.BuildsRegistrySecret != "" {
+		podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+			Name: "registry",
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: cfg.BuildsRegistrySecret,
+					Items:      []v1.KeyToPath{{Key: v1.DockerConfigJsonKey, Path: "config.json"}},
+				},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      "registry",
+			ReadOnly:  true,
+			MountPath: buildDockerConfigPath,
+		})
+	}
+
+	podSpec.Containers = append(podSpec.Containers, container)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cfg.ServicesNamespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+// makeBuildContext returns the build context (.tar.gz) with the files of the provided context (if any) and the Dockerfile
+func makeBuildContext(dockerfile string, buildContext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	if len(buildContext) > 0 {
+		gr, err := gzip.NewReader(bytes.NewReader(buildContext))
+		if err != nil {
+			return nil, fmt.Errorf("the build context is not a valid .tar.gz file: %v", err)
+		}
+		tr := tar.NewReader(gr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("the build context is not a valid .tar.gz file: %v", err)
+			}
+			// The Dockerfile of the request replaces the one of the context
+			if path.Clean(hdr.Name) == "Dockerfile" {
+				continue
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return nil, fmt.Errorf("the build context is not a valid .tar.gz file: %v", err)
+			}
+		}
+	}
+
+	hdr := &tar.Header{
+		Name:    "Dockerfile",
+		Mode:    0644,
+		Size:    int64(len(dockerfile)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write([]byte(dockerfile)); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// getBuild returns the status of the build run by a job
+func getBuild(job *batchv1.Job) *types.Build {
+	build := &types.Build{
+		ID:            job.Labels[types.BuildLabel],
+		Image:         job.Annotations[types.BuildImageAnnotation],
+		Status:        types.BuildPending,
+		Service:       job.Annotations[types.BuildServiceAnnotation],
+		ServiceStatus: job.Labels[types.BuildServiceStatusLabel],
+		Message:       job.Annotations[types.BuildMessageAnnotation],
+		Owner:         job.Annotations[types.BuildOwnerAnnotation],
+		CreatedAt:     job.CreationTimestamp.Time,
+	}
+
+	if isJobComplete(job) {
+		build.Status = types.BuildSucceeded
+		if job.Status.CompletionTime != nil {
+			build.FinishedAt = &job.Status.CompletionTime.Time
+		}
+	} else if _, _, failed := getJobFailure(job); failed {
+		build.Status = types.BuildFailed
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == batchv1.JobFailed {
+				build.FinishedAt = &cond.LastTransitionTime.Time
+			}
+		}
+	} else if job.Status.Active > 0 {
+		build.Status = types.BuildRunning
+	}
+
+	return build
+}
+
+// ListBuilds returns the status of the builds, newest first
+func ListBuilds(cfg *types.Config, kubeClientset kubernetes.Interface) ([]types.Build, error) {
+	listOpts := metav1.ListOptions{
+		LabelSelector: types.BuildLabel,
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, types.NewCodedError(types.ErrBuildFailed, fmt.Errorf("error listing the builds: %v", err))
+	}
+
+	builds := []types.Build{}
+	for i := range jobs.Items {
+		builds = append(builds, *getBuild(&jobs.Items[i]))
+	}
+	sort.SliceStable(builds, func(i, j int) bool {
+		return builds[i].CreatedAt.After(builds[j].CreatedAt)
+	})
+	return builds, nil
+}
+
+// getBuildJob returns the job of a build
+func getBuildJob(cfg *types.Config, kubeClientset kubernetes.Interface, id string) (*batchv1.Job, error) {
+	job, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), getBuildJobName(id), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, types.NewCodedError(types.ErrBuildNotFound, fmt.Errorf("the build \"%s\" does not exist", id))
+		}
+		return nil, types.NewCodedError(types.ErrBuildFailed, fmt.Errorf("error reading the build \"%s\": %v", id, err))
+	}
+	if job.Labels[types.BuildLabel] != id {
+		return nil, types.NewCodedError(types.ErrBuildNotFound, fmt.Errorf("the build \"%s\" does not exist", id))
+	}
+	return job, nil
+}
+
+// GetBuild returns the status of a build
+func GetBuild(cfg *types.Config, kubeClientset kubernetes.Interface, id string) (*types.Build, error) {
+	job, err := getBuildJob(cfg, kubeClientset, id)
+	if err != nil {
+		return nil, err
+	}
+	return getBuild(job), nil
+}
+
+// GetBuildLogs returns the logs of the build container
+func GetBuildLogs(cfg *types.Config, kubeClientset kubernetes.Interface, id string) (string, error) {
+	if _, err := getBuildJob(cfg, kubeClientset, id); err != nil {
+		return "", err
+	}
+
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", getBuildJobName(id)),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(cfg.ServicesNamespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return "", types.NewCodedError(types.ErrBuildFailed, fmt.Errorf("error reading the pods of the build \"%s\": %v", id, err))
+	}
+	if len(pods.Items) == 0 {
+		// The build has not started yet
+		return "", nil
+	}
+
+	logs, err := kubeClientset.CoreV1().Pods(cfg.ServicesNamespace).GetLogs(pods.Items[0].Name, &v1.PodLogOptions{Container: types.BuildContainerName}).Do(context.TODO()).Raw()
+	if err != nil {
+		return "", types.NewCodedError(types.ErrBuildFailed, fmt.Errorf("error reading the logs of the build \"%s\": %v", id, err))
+	}
+	return string(logs), nil
+}
+
+// DeleteBuild removes the job of a build (and its secret and pods). The pushed image is not removed from the registry
+func DeleteBuild(cfg *types.Config, kubeClientset kubernetes.Interface, id string) error {
+	if _, err := getBuildJob(cfg, kubeClientset, id); err != nil {
+		return err
+	}
+	if err := deleteBuildJob(cfg, kubeClientset, getBuildJobName(id)); err != nil {
+		return types.NewCodedError(types.ErrBuildFailed, fmt.Errorf("error deleting the build \"%s\": %v", id, err))
+	}
+	return nil
+}
+
+// deleteBuildJob removes a build job and its dependents in background
+func deleteBuildJob(cfg *types.Config, kubeClientset kubernetes.Interface, name string) error {
+	background := metav1.DeletePropagationBackground
+	delOpts := metav1.DeleteOptions{
+		PropagationPolicy: &background,
+	}
+	return kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Delete(context.TODO(), name, delOpts)
+}
+
+// StartBuildWatcher starts the loop to create the services of the finished builds every cfg.BuildsInterval
+func StartBuildWatcher(cfg *types.Config, kubeClientset kubernetes.Interface, deploy func(service *types.Service) error) {
+	for {
+		if err := createBuiltServices(cfg, kubeClientset, deploy); err != nil {
+			buildLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.BuildsInterval) * time.Second)
+	}
+}
+
+// createBuiltServices deploys the services of the succeeded builds, marking them as created (or failed)
+func createBuiltServices(cfg *types.Config, kubeClientset kubernetes.Interface, deploy func(service *types.Service) error) error {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,%s=%s", types.BuildLabel, types.BuildServiceStatusLabel, types.BuildPending),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting the build list: %v", err)
+	}
+
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		var deployErr error
+		switch getBuild(job).Status {
+		case types.BuildSucceeded:
+			deployErr = deployBuiltService(cfg, kubeClientset, job, deploy)
+		case types.BuildFailed:
+			deployErr = fmt.Errorf("the build of the image failed")
+		default:
+			continue
+		}
+
+		status, message := types.BuildCreated, ""
+		if deployErr != nil {
+			status, message = types.BuildFailed, deployErr.Error()
+			buildLogger.Printf("error creating the service of the build \"%s\": %v\n", job.Labels[types.BuildLabel], deployErr)
+		}
+		patch, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels":      map[string]string{types.BuildServiceStatusLabel: status},
+				"annotations": map[string]string{types.BuildMessageAnnotation: message},
+			},
+		})
+		if _, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Patch(context.TODO(), job.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			buildLogger.Printf("error labelling the build job \"%s\": %v\n", job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// deployBuiltService deploys the service definition stored in the secret of a build job
+func deployBuiltService(cfg *types.Config, kubeClientset kubernetes.Interface, job *batchv1.Job, deploy func(service *types.Service) error) error {
+	secret, err := kubeClientset.CoreV1().Secrets(cfg.ServicesNamespace).Get(context.TODO(), job.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error reading the service definition: %v", err)
+	}
+	service := &types.Service{}
+	if err := json.Unmarshal(secret.Data[types.BuildServiceKey], service); err != nil {
+		return fmt.Errorf("error decoding the service definition: %v", err)
+	}
+	return deploy(service)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// readTarGz returns the files of a .tar.gz file
+func readTarGz(t *testing.T, data []byte) map[string]string {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(content)
+	}
+}
+
+func TestMakeBuildContext(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range map[string]string{"./Dockerfile": "FROM old", "requirements.txt": "numpy"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gw.Close()
+
+	data, err := makeBuildContext("FROM busybox", buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := readTarGz(t, data)
+	if len(files) != 2 || files["Dockerfile"] != "FROM busybox" || files["requirements.txt"] != "numpy" {
+		t.Errorf("unexpected build context %v", files)
+	}
+
+	if _, err := makeBuildContext("FROM busybox", []byte("not a tar.gz")); err == nil {
+		t.Error("expecting error with an invalid context")
+	}
+}
+
+func TestCreateBuiltServices(t *testing.T) {
+	cfg := &types.Config{ServicesNamespace: "oscar-svc", BuildsRegistry: "registry.example.com", BuildsImage: "kaniko"}
+	kubeClientset := testclient.NewSimpleClientset()
+
+	builds := map[string]*types.Build{}
+	for _, name := range []string{"succeeded", "failed", "running"} {
+		build, err := CreateBuild(cfg, kubeClientset, &types.BuildRequest{Name: name, Dockerfile: "FROM busybox", Service: &types.Service{Name: name}}, "user")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		builds[name] = build
+	}
+	setJobCondition := func(id string, condition batchv1.JobConditionType) {
+		job, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), getBuildJobName(id), metav1.GetOptions{})
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: v1.ConditionTrue}}
+		kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Update(context.TODO(), job, metav1.UpdateOptions{})
+	}
+	setJobCondition(builds["succeeded"].ID, batchv1.JobComplete)
+	setJobCondition(builds["failed"].ID, batchv1.JobFailed)
+
+	deployed := []string{}
+	deploy := func(service *types.Service) error {
+		deployed = append(deployed, service.Name)
+		return nil
+	}
+	if err := createBuiltServices(cfg, kubeClientset, deploy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(deployed) != 1 || deployed[0] != "succeeded" {
+		t.Errorf("expecting only the service of the succeeded build, got %v", deployed)
+	}
+	for name, expected := range map[string]string{"succeeded": types.BuildCreated, "failed": types.BuildFailed, "running": types.BuildPending} {
+		build, err := GetBuild(cfg, kubeClientset, builds[name].ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if build.ServiceStatus != expected {
+			t.Errorf("expecting the service status \"%s\" for the %s build, got \"%s\"", expected, name, build.ServiceStatus)
+		}
+	}
+
+	// The created services are not deployed again
+	if err := createBuiltServices(cfg, kubeClientset, deploy); err != nil || len(deployed) != 1 {
+		t.Errorf("expecting no more deployments, got %v (%v)", deployed, err)
+	}
+}
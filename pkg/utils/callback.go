@@ -0,0 +1,343 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	"golang.org/x/oauth2/clientcredentials"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var callbackLogger = log.New(os.Stdout, "[CALLBACK] ", log.Flags())
+
+const (
+	// callbackLogSize maximum number of deliveries kept in the log of each service
+	callbackLogSize = 100
+	// callbackTimeout timeout of the callback requests
+	callbackTimeout = 10 * time.Second
+)
+
+// callbackBackoffUnit unit of the services' callback backoff
+var callbackBackoffUnit = time.Second
+
+// callbackLog deliveries of the services' callbacks (by service name), oldest first
+var callbackLog = map[string][]*types.CallbackDelivery{}
+var callbackMutex sync.Mutex
+
+// StartCallbackWatcher starts the loop to notify the finished jobs to the callbacks of the services every cfg.CallbackInterval
+func StartCallbackWatcher(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) {
+	for {
+		if err := notifyCallbacks(cfg, back, kubeClientset, true); err != nil {
+			callbackLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.CallbackInterval) * time.Second)
+	}
+}
+
+// notifyCallbacks delivers the callbacks of the finished jobs (in background if async is true to not block on retries)
+func notifyCallbacks(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface, async bool) error {
+	// List the services' jobs not notified yet
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,!%s", types.ServiceLabel, types.CallbackLabelKey),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+
+	// Map to store services' pointers
+	svcPtrs := map[string]*types.Service{}
+
+	for _, job := range jobs.Items {
+		succeeded := isJobComplete(&job)
+		reason, message, failed := getJobFailure(&job)
+		if !succeeded && !failed {
+			continue
+		}
+
+		serviceName := job.Labels[types.ServiceLabel]
+		if _, ok := svcPtrs[serviceName]; !ok {
+			svcPtrs[serviceName], err = back.ReadService(serviceName)
+			if err != nil {
+				callbackLogger.Printf("error getting service \"%s\": %v\n", serviceName, err)
+				svcPtrs[serviceName] = nil
+			}
+		}
+		service := svcPtrs[serviceName]
+		if service == nil || service.Callback == nil {
+			continue
+		}
+
+		// Mark the job as notified before the delivery, the retries are handled by DeliverCallback
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{"%s":"true"}}}`, types.CallbackLabelKey))
+		if _, err := kubeClientset.BatchV1().Jobs(job.Namespace).Patch(context.TODO(), job.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			callbackLogger.Printf("error labelling the notified job \"%s\": %v\n", job.Name, err)
+			continue
+		}
+
+		if (!succeeded || !service.Callback.OnSuccess) && (!failed || !service.Callback.OnFailure) {
+			continue
+		}
+
+		payload := types.CallbackPayload{
+			Service:    serviceName,
+			Job:        job.Name,
+			Status:     "succeeded",
+			FinishedAt: getJobFinishTime(&job),
+		}
+		if failed {
+			payload.Status, payload.Reason, payload.Message = "failed", reason, message
+		}
+		delivery := addCallbackDelivery(service, payload)
+
+		if async {
+			go DeliverCallback(cfg, kubeClientset, service, delivery.ID, service.Callback.GetMaxAttempts())
+		} else {
+			DeliverCallback(cfg, kubeClientset, service, delivery.ID, service.Callback.GetMaxAttempts())
+		}
+	}
+
+	return nil
+}
+
+// getJobFinishTime returns the completion (or failure) time of a finished job
+func getJobFinishTime(job *batchv1.Job) time.Time {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime.Time
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed {
+			return cond.LastTransitionTime.Time
+		}
+	}
+	return time.Now()
+}
+
+// addCallbackDelivery adds a pending delivery to the service's log, discarding the oldest ones if the log is full
+func addCallbackDelivery(service *types.Service, payload types.CallbackPayload) types.CallbackDelivery {
+	callbackMutex.Lock()
+	defer callbackMutex.Unlock()
+
+	now := time.Now()
+	delivery := &types.CallbackDelivery{
+		ID:        payload.Job,
+		URL:       service.Callback.URL,
+		Status:    types.CallbackPending,
+		Payload:   payload,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	deliveries := append(callbackLog[service.Name], delivery)
+	if len(deliveries) > callbackLogSize {
+		deliveries = deliveries[len(deliveries)-callbackLogSize:]
+	}
+	callbackLog[service.Name] = deliveries
+
+	return *delivery
+}
+
+// ListCallbackDeliveries returns the deliveries of the service's callback, newest first
+func ListCallbackDeliveries(serviceName string) []types.CallbackDelivery {
+	callbackMutex.Lock()
+	defer callbackMutex.Unlock()
+
+	deliveries := []types.CallbackDelivery{}
+	for _, d := range callbackLog[serviceName] {
+		deliveries = append(deliveries, *d)
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return deliveries
+}
+
+// GetCallbackDelivery returns a delivery of the service's callback
+func GetCallbackDelivery(serviceName string, id string) (types.CallbackDelivery, bool) {
+	callbackMutex.Lock()
+	defer callbackMutex.Unlock()
+
+	if d := findCallbackDelivery(serviceName, id); d != nil {
+		return *d, true
+	}
+	return types.CallbackDelivery{}, false
+}
+
+// findCallbackDelivery returns the delivery from the log (callbackMutex must be locked)
+func findCallbackDelivery(serviceName string, id string) *types.CallbackDelivery {
+	for _, d := range callbackLog[serviceName] {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// updateCallbackDelivery records the result of a delivery attempt
+func updateCallbackDelivery(serviceName string, id string, status string, statusCode int, err error) {
+	callbackMutex.Lock()
+	defer callbackMutex.Unlock()
+
+	d := findCallbackDelivery(serviceName, id)
+	if d == nil {
+		return
+	}
+	d.Status = status
+	d.Attempts++
+	d.StatusCode = statusCode
+	d.Error = ""
+	if err != nil {
+		d.Error = err.Error()
+	}
+	d.UpdatedAt = time.Now()
+}
+
+// DeliverCallback sends the payload of a delivery to the service's callback, retrying up to maxAttempts times
+// with exponential backoff. Returns the delivery after the last attempt
+func DeliverCallback(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, id string, maxAttempts int) (types.CallbackDelivery, error) {
+	delivery, ok := GetCallbackDelivery(service.Name, id)
+	if !ok {
+		return delivery, fmt.Errorf("the callback delivery \"%s\" does not exist", id)
+	}
+
+	backoff := time.Duration(service.Callback.GetBackoff()) * callbackBackoffUnit
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		statusCode, err := sendCallback(cfg, kubeClientset, service, delivery.Payload)
+		if err == nil {
+			updateCallbackDelivery(service.Name, id, types.CallbackDelivered, statusCode, nil)
+			callbackLogger.Printf("Callback of job \"%s\" delivered to \"%s\"\n", id, service.Callback.URL)
+			break
+		}
+
+		status := types.CallbackPending
+		if attempt == maxAttempts {
+			status = types.CallbackFailed
+		}
+		updateCallbackDelivery(service.Name, id, status, statusCode, err)
+		callbackLogger.Printf("error delivering the callback of job \"%s\" (attempt %d/%d): %v\n", id, attempt, maxAttempts, err)
+
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	delivery, _ = GetCallbackDelivery(service.Name, id)
+	return delivery, nil
+}
+
+// sendCallback sends the payload to the service's callback. Returns the status code of the response
+func sendCallback(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, payload types.CallbackPayload) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, service.Callback.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := authorizeCallback(cfg, kubeClientset, service, req); err != nil {
+		return 0, err
+	}
+
+	client := &http.Client{Timeout: callbackTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// authorizeCallback sets the credentials of the callback's auth (if defined) in the request
+func authorizeCallback(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, req *http.Request) error {
+	auth := service.Callback.Auth
+	if auth == nil {
+		return nil
+	}
+
+	getValue := func(key string) (string, error) {
+		value, err := getServiceSecretValue(kubeClientset, cfg.GetServiceNamespace(service), service.Name, auth.Secret, key)
+		return string(value), err
+	}
+
+	switch auth.Type {
+	case types.BearerCallbackAuth:
+		token, err := getValue(types.CallbackTokenKey)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case types.BasicCallbackAuth:
+		username, err := getValue(types.CallbackUsernameKey)
+		if err != nil {
+			return err
+		}
+		password, err := getValue(types.CallbackPasswordKey)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(username, password)
+	case types.OAuth2CallbackAuth:
+		clientID, err := getValue(types.CallbackClientIDKey)
+		if err != nil {
+			return err
+		}
+		clientSecret, err := getValue(types.CallbackClientSecretKey)
+		if err != nil {
+			return err
+		}
+		ccCfg := clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     auth.TokenURL,
+			Scopes:       auth.Scopes,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), callbackTimeout)
+		defer cancel()
+		token, err := ccCfg.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting the OAuth2 token: %v", err)
+		}
+		token.SetAuthHeader(req)
+	default:
+		return fmt.Errorf("invalid callback authentication type \"%s\"", auth.Type)
+	}
+	return nil
+}
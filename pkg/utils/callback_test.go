@@ -0,0 +1,204 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeCallbackServer records the callbacks received, failing the first "failures" requests
+type fakeCallbackServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	failures int
+	payloads []types.CallbackPayload
+	auth     []string
+}
+
+func startFakeCallbackServer(failures int) *fakeCallbackServer {
+	server := &fakeCallbackServer{failures: failures}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"oauth2-token","token_type":"bearer","expires_in":3600}`))
+			return
+		}
+
+		server.auth = append(server.auth, r.Header.Get("Authorization"))
+		if server.failures > 0 {
+			server.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload types.CallbackPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		server.payloads = append(server.payloads, payload)
+	}))
+	return server
+}
+
+func testCallbackSecret(data map[string][]byte) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "callback-secret",
+			Namespace: "oscar-svc",
+			Labels:    map[string]string{types.ServiceLabel: "test"},
+		},
+		Data: data,
+	}
+}
+
+func TestDeliverCallback(t *testing.T) {
+	callbackBackoffUnit = time.Millisecond
+	cfg := &types.Config{ServicesNamespace: "oscar-svc"}
+
+	scenarios := []struct {
+		name             string
+		failures         int
+		auth             *types.CallbackAuth
+		secret           map[string][]byte
+		expectedStatus   string
+		expectedAttempts int
+		expectedAuth     string
+	}{
+		{"no auth", 0, nil, nil, types.CallbackDelivered, 1, ""},
+		{"retried", 2, nil, nil, types.CallbackDelivered, 3, ""},
+		{"failed", 5, nil, nil, types.CallbackFailed, 3, ""},
+		{"bearer", 0, &types.CallbackAuth{Type: types.BearerCallbackAuth, Secret: "callback-secret"},
+			map[string][]byte{types.CallbackTokenKey: []byte("token")}, types.CallbackDelivered, 1, "Bearer token"},
+		{"basic", 0, &types.CallbackAuth{Type: types.BasicCallbackAuth, Secret: "callback-secret"},
+			map[string][]byte{types.CallbackUsernameKey: []byte("user"), types.CallbackPasswordKey: []byte("pass")}, types.CallbackDelivered, 1, "Basic dXNlcjpwYXNz"},
+		{"oauth2", 0, &types.CallbackAuth{Type: types.OAuth2CallbackAuth, Secret: "callback-secret"},
+			map[string][]byte{types.CallbackClientIDKey: []byte("id"), types.CallbackClientSecretKey: []byte("secret")}, types.CallbackDelivered, 1, "Bearer oauth2-token"},
+		{"missing secret field", 0, &types.CallbackAuth{Type: types.BearerCallbackAuth, Secret: "callback-secret"},
+			map[string][]byte{}, types.CallbackFailed, 3, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			server := startFakeCallbackServer(s.failures)
+			defer server.Close()
+			callbackLog = map[string][]*types.CallbackDelivery{}
+
+			if s.auth != nil && s.auth.Type == types.OAuth2CallbackAuth {
+				s.auth.TokenURL = server.URL + "/token"
+			}
+			service := &types.Service{Name: "test", Callback: &types.Callback{URL: server.URL, OnSuccess: true, Auth: s.auth, Retry: &types.CallbackRetry{Backoff: 1}}}
+			kubeClientset := testclient.NewSimpleClientset()
+			if s.secret != nil {
+				kubeClientset = testclient.NewSimpleClientset(testCallbackSecret(s.secret))
+			}
+
+			added := addCallbackDelivery(service, types.CallbackPayload{Service: "test", Job: "job", Status: "succeeded"})
+			delivery, err := DeliverCallback(cfg, kubeClientset, service, added.ID, service.Callback.GetMaxAttempts())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if delivery.Status != s.expectedStatus || delivery.Attempts != s.expectedAttempts {
+				t.Errorf("expecting status \"%s\" after %d attempts, got \"%s\" after %d", s.expectedStatus, s.expectedAttempts, delivery.Status, delivery.Attempts)
+			}
+			if s.expectedStatus == types.CallbackDelivered {
+				if len(server.payloads) != 1 || server.payloads[0].Job != "job" {
+					t.Errorf("unexpected payloads: %v", server.payloads)
+				}
+				if auth := server.auth[len(server.auth)-1]; auth != s.expectedAuth {
+					t.Errorf("expecting Authorization \"%s\", got \"%s\"", s.expectedAuth, auth)
+				}
+			}
+		})
+	}
+
+	if _, err := DeliverCallback(cfg, testclient.NewSimpleClientset(), &types.Service{Name: "test", Callback: &types.Callback{}}, "missing", 1); err == nil {
+		t.Error("expecting error delivering a missing delivery")
+	}
+}
+
+func TestNotifyCallbacks(t *testing.T) {
+	server := startFakeCallbackServer(0)
+	defer server.Close()
+	callbackLog = map[string][]*types.CallbackDelivery{}
+	cfg := &types.Config{ServicesNamespace: "oscar-svc"}
+
+	back := &fakeServiceBackend{services: map[string]*types.Service{
+		"notified":     {Name: "notified", Callback: &types.Callback{URL: server.URL, OnFailure: true}},
+		"not-notified": {Name: "not-notified"},
+	}}
+
+	makeJob := func(name string, service string, condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cfg.ServicesNamespace,
+				Labels:    map[string]string{types.ServiceLabel: service},
+			},
+		}
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+		}
+		return job
+	}
+	kubeClientset := testclient.NewSimpleClientset(
+		makeJob("completed", "notified", batchv1.JobComplete),
+		makeJob("failed", "notified", batchv1.JobFailed),
+		makeJob("running", "notified", ""),
+		makeJob("other-failed", "not-notified", batchv1.JobFailed),
+	)
+
+	if err := notifyCallbacks(cfg, back, kubeClientset, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(server.payloads) != 1 || server.payloads[0].Job != "failed" || server.payloads[0].Status != "failed" || server.payloads[0].Reason != "BackoffLimitExceeded" {
+		t.Fatalf("unexpected payloads: %v", server.payloads)
+	}
+
+	deliveries := ListCallbackDeliveries("notified")
+	if len(deliveries) != 1 || deliveries[0].ID != "failed" || deliveries[0].Status != types.CallbackDelivered {
+		t.Errorf("unexpected deliveries: %v", deliveries)
+	}
+
+	expectedLabelled := map[string]bool{
+		"completed":    true,
+		"failed":       true,
+		"running":      false,
+		"other-failed": false,
+	}
+	for name, expected := range expectedLabelled {
+		job, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, labelled := job.Labels[types.CallbackLabelKey]; labelled != expected {
+			t.Errorf("job \"%s\": expecting labelled %v, got %v", name, expected, labelled)
+		}
+	}
+}
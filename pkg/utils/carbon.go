@@ -0,0 +1,219 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var carbonLogger = log.New(os.Stdout, "[CARBON] ", log.Flags())
+
+// carbonForecastTTL time that the carbon intensity forecast is cached
+const carbonForecastTTL = 5 * time.Minute
+
+var carbonForecast types.CarbonForecast
+var carbonForecastExpires time.Time
+var carbonMutex sync.Mutex
+
+// StartDeferredJobsReleaser starts the loop to release the deferred jobs whose low-carbon window has started
+// every cfg.DeferredJobsInterval
+func StartDeferredJobsReleaser(cfg *types.Config, kubeClientset kubernetes.Interface) {
+	for {
+		if err := releaseDeferredJobs(cfg, kubeClientset); err != nil {
+			carbonLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.DeferredJobsInterval) * time.Second)
+	}
+}
+
+// GetCarbonForecast returns the (cached) carbon intensity forecast from the cfg.CarbonIntensityURL API
+func GetCarbonForecast(cfg *types.Config) (types.CarbonForecast, error) {
+	carbonMutex.Lock()
+	defer carbonMutex.Unlock()
+
+	if carbonForecast != nil && time.Now().Before(carbonForecastExpires) {
+		return carbonForecast, nil
+	}
+
+	client := &http.Client{Timeout: time.Second * 10}
+	res, err := client.Get(cfg.CarbonIntensityURL)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the carbon intensity forecast: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting the carbon intensity forecast: Status code %d", res.StatusCode)
+	}
+
+	var forecast types.CarbonForecast
+	if err := json.NewDecoder(res.Body).Decode(&forecast); err != nil {
+		return nil, fmt.Errorf("error decoding the carbon intensity forecast: %v", err)
+	}
+
+	carbonForecast = forecast
+	carbonForecastExpires = time.Now().Add(carbonForecastTTL)
+
+	return forecast, nil
+}
+
+// GetCarbonIntensity returns the current carbon intensity of the cluster, obtained from the carbon intensity API
+// (if configured) or the static cfg.CarbonIntensity otherwise
+func GetCarbonIntensity(cfg *types.Config) float64 {
+	if cfg.CarbonIntensityURL != "" {
+		forecast, err := GetCarbonForecast(cfg)
+		if err != nil {
+			carbonLogger.Println(err.Error())
+		} else if intensity, ok := forecast.Current(time.Now()); ok {
+			return intensity
+		}
+	}
+	return cfg.CarbonIntensity
+}
+
+// DeferJob suspends the job of a deferrable service until the window with the lowest carbon intensity
+// (before the service's max delay). Returns true if the job has been deferred
+func DeferJob(cfg *types.Config, service *types.Service, job *batchv1.Job) bool {
+	if !service.Deferrable || cfg.CarbonIntensityURL == "" {
+		return false
+	}
+
+	forecast, err := GetCarbonForecast(cfg)
+	if err != nil {
+		carbonLogger.Printf("Unable to defer job of service \"%s\": %v\n", service.Name, err)
+		return false
+	}
+
+	// The job's maps are shared with the service definition
+	job.Labels = copyStringMap(job.Labels)
+	job.Annotations = copyStringMap(job.Annotations)
+
+	now := time.Now()
+	current, known := forecast.Current(now)
+	if known {
+		job.Annotations[types.CarbonSubmitAnnotation] = formatIntensity(current)
+		if current <= cfg.CarbonIntensityThreshold {
+			job.Annotations[types.CarbonRunAnnotation] = formatIntensity(current)
+			return false
+		}
+	}
+
+	start, intensity := forecast.BestStart(now, now.Add(service.GetMaxDelay(cfg)))
+	if !start.After(now) || (known && intensity >= current) {
+		if known {
+			job.Annotations[types.CarbonRunAnnotation] = formatIntensity(current)
+		}
+		return false
+	}
+
+	suspend := true
+	job.Spec.Suspend = &suspend
+	job.Labels[types.DeferredLabelKey] = "true"
+	job.Annotations[types.DeferredUntilAnnotation] = start.UTC().Format(time.RFC3339)
+	carbonLogger.Printf("Job of service \"%s\" deferred until %s (carbon intensity %.1f)\n", service.Name, start.UTC().Format(time.RFC3339), intensity)
+
+	return true
+}
+
+// releaseDeferredJobs resumes the deferred jobs whose window has started or if the carbon intensity is below the threshold
+func releaseDeferredJobs(cfg *types.Config, kubeClientset kubernetes.Interface) error {
+	listOpts := metav1.ListOptions{
+		LabelSelector: types.DeferredLabelKey,
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+	if len(jobs.Items) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var current float64
+	known := false
+	if forecast, err := GetCarbonForecast(cfg); err != nil {
+		carbonLogger.Println(err.Error())
+	} else {
+		current, known = forecast.Current(now)
+	}
+
+	for _, job := range jobs.Items {
+		until, err := time.Parse(time.RFC3339, job.Annotations[types.DeferredUntilAnnotation])
+		// Jobs with an invalid deadline are released to avoid keeping them forever
+		if err == nil && now.Before(until) && (!known || current > cfg.CarbonIntensityThreshold) {
+			continue
+		}
+
+		suspend := false
+		job.Spec.Suspend = &suspend
+		delete(job.Labels, types.DeferredLabelKey)
+		if known {
+			if job.Annotations == nil {
+				job.Annotations = map[string]string{}
+			}
+			job.Annotations[types.CarbonRunAnnotation] = formatIntensity(current)
+		}
+		if _, err := kubeClientset.BatchV1().Jobs(job.Namespace).Update(context.TODO(), &job, metav1.UpdateOptions{}); err != nil {
+			carbonLogger.Printf("Error releasing deferred job \"%s\": %v\n", job.Name, err)
+			continue
+		}
+		carbonLogger.Printf("Deferred job \"%s\" released\n", job.Name)
+	}
+
+	return nil
+}
+
+// getAvoidedEmissions returns the emissions (in gCO2eq) avoided by deferring a job that consumed the provided CPU-hours
+func getAvoidedEmissions(cfg *types.Config, annotations map[string]string, cpuHours float64) float64 {
+	submit, err := strconv.ParseFloat(annotations[types.CarbonSubmitAnnotation], 64)
+	if err != nil {
+		return 0
+	}
+	run, err := strconv.ParseFloat(annotations[types.CarbonRunAnnotation], 64)
+	if err != nil {
+		return 0
+	}
+	// Energy (kWh) x intensity difference (gCO2eq/kWh)
+	return cpuHours * cfg.CPUPowerWatts / 1000 * (submit - run)
+}
+
+func formatIntensity(intensity float64) string {
+	return strconv.FormatFloat(intensity, 'f', -1, 64)
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
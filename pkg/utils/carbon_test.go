@@ -0,0 +1,136 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func testCarbonServer(forecast types.CarbonForecast) *httptest.Server {
+	// Clear the cached forecast
+	carbonForecast = nil
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(forecast)
+	}))
+}
+
+func TestDeferJob(t *testing.T) {
+	now := time.Now()
+	server := testCarbonServer(types.CarbonForecast{
+		{From: now.Add(-time.Hour), To: now.Add(time.Hour), Intensity: 300},
+		{From: now.Add(time.Hour), To: now.Add(2 * time.Hour), Intensity: 100},
+	})
+	defer server.Close()
+
+	scenarios := []struct {
+		name          string
+		deferrable    bool
+		maxDelay      int
+		threshold     float64
+		expected      bool
+		expectedUntil time.Time
+	}{
+		{"not deferrable", false, 0, 0, false, time.Time{}},
+		{"deferred", true, 0, 0, true, now.Add(time.Hour)},
+		{"max delay before the low-carbon window", true, 1800, 0, false, time.Time{}},
+		{"below the threshold", true, 0, 400, false, time.Time{}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			cfg := &types.Config{CarbonIntensityURL: server.URL, CarbonIntensityThreshold: s.threshold, DeferrableMaxDelay: 6 * time.Hour}
+			service := &types.Service{Name: "test", Deferrable: s.deferrable, MaxDelay: s.maxDelay, Labels: map[string]string{types.ServiceLabel: "test"}}
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job", Labels: service.Labels}}
+
+			if res := DeferJob(cfg, service, job); res != s.expected {
+				t.Fatalf("expecting %v, got %v", s.expected, res)
+			}
+			if _, ok := service.Labels[types.DeferredLabelKey]; ok {
+				t.Error("the service labels must not be modified")
+			}
+			if !s.expected {
+				if job.Spec.Suspend != nil && *job.Spec.Suspend {
+					t.Error("expecting the job not to be suspended")
+				}
+				return
+			}
+			if job.Spec.Suspend == nil || !*job.Spec.Suspend {
+				t.Error("expecting the job to be suspended")
+			}
+			until, _ := time.Parse(time.RFC3339, job.Annotations[types.DeferredUntilAnnotation])
+			if !until.Equal(s.expectedUntil.Truncate(time.Second)) {
+				t.Errorf("expecting the job to be deferred until %v, got %v", s.expectedUntil, until)
+			}
+			if job.Annotations[types.CarbonSubmitAnnotation] != "300" {
+				t.Errorf("expecting the submit intensity 300, got %s", job.Annotations[types.CarbonSubmitAnnotation])
+			}
+		})
+	}
+}
+
+func TestReleaseDeferredJobs(t *testing.T) {
+	now := time.Now()
+	server := testCarbonServer(types.CarbonForecast{
+		{From: now.Add(-time.Hour), To: now.Add(time.Hour), Intensity: 120},
+	})
+	defer server.Close()
+	cfg := &types.Config{CarbonIntensityURL: server.URL, ServicesNamespace: "oscar-svc"}
+
+	suspend := true
+	makeJob := func(name string, until time.Time) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   cfg.ServicesNamespace,
+				Labels:      map[string]string{types.DeferredLabelKey: "true"},
+				Annotations: map[string]string{types.DeferredUntilAnnotation: until.UTC().Format(time.RFC3339), types.CarbonSubmitAnnotation: "300"},
+			},
+			Spec: batchv1.JobSpec{Suspend: &suspend},
+		}
+	}
+	kubeClientset := testclient.NewSimpleClientset(makeJob("ready", now.Add(-time.Minute)), makeJob("waiting", now.Add(time.Hour)))
+
+	if err := releaseDeferredJobs(cfg, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ready, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), "ready", metav1.GetOptions{})
+	if *ready.Spec.Suspend || ready.Labels[types.DeferredLabelKey] != "" || ready.Annotations[types.CarbonRunAnnotation] != "120" {
+		t.Errorf("expecting the job \"ready\" to be released, got %+v", ready)
+	}
+	waiting, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), "waiting", metav1.GetOptions{})
+	if !*waiting.Spec.Suspend {
+		t.Error("expecting the job \"waiting\" to be kept suspended")
+	}
+
+	// The avoided emissions of the released job: 2 CPU-hours x 10 W x (300 - 120) gCO2eq/kWh
+	cfg.CPUPowerWatts = 10
+	if res := getAvoidedEmissions(cfg, ready.Annotations, 2); math.Abs(res-3.6) > 1e-9 {
+		t.Errorf("expecting 3.6 gCO2eq avoided, got %f", res)
+	}
+}
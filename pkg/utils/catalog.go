@@ -0,0 +1,328 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// ociCatalogPrefix prefix of the catalog URLs referencing an OCI artifact
+const ociCatalogPrefix = "oci://"
+
+var (
+	// ErrTemplateNotFound returned when a template is not defined in the catalog
+	ErrTemplateNotFound = errors.New("the template does not exist")
+
+	catalogLogger = log.New(os.Stdout, "[CATALOG] ", log.Flags())
+
+	// catalogParameterRegex matches the references to the parameters in the templates ("${NAME}")
+	catalogParameterRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Catalog cache of the FDL templates of the service catalog (CATALOG_URL), fetched again when they are requested
+// after the CATALOG_REFRESH_INTERVAL
+type Catalog struct {
+	cfg       *types.Config
+	mutex     sync.Mutex
+	templates map[string]*catalogTemplate
+	fetchedAt time.Time
+}
+
+// catalogTemplate template loaded from the catalog, with the scripts of its services inlined
+type catalogTemplate struct {
+	info types.CatalogTemplate
+	// functions "functions" block of the FDL file
+	functions interface{}
+}
+
+// catalogFDL top-level fields of the FDL files of the templates
+type catalogFDL struct {
+	Description string `json:"description"`
+	Parameters  map[string]struct {
+		Description string  `json:"description"`
+		Default     *string `json:"default"`
+	} `json:"parameters"`
+	Functions interface{} `json:"functions"`
+}
+
+// NewCatalog creates the cache of the service catalog
+func NewCatalog(cfg *types.Config) *Catalog {
+	return &Catalog{cfg: cfg}
+}
+
+// Enabled returns if the service catalog is configured
+func (c *Catalog) Enabled() bool {
+	return c.cfg.CatalogURL != ""
+}
+
+// ListTemplates returns the templates of the catalog sorted by name
+func (c *Catalog) ListTemplates() ([]types.CatalogTemplate, error) {
+	templates, err := c.getTemplates()
+	if err != nil {
+		return nil, err
+	}
+	list := []types.CatalogTemplate{}
+	for _, template := range templates {
+		list = append(list, template.info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// RenderTemplate returns the "functions" block (JSON encoded) of a template with its parameters replaced by the
+// provided values or their defaults. ErrTemplateNotFound is returned if the template does not exist, and a
+// types.ErrBadRequest coded error if the parameters are not valid
+func (c *Catalog) RenderTemplate(name string, params map[string]string) ([]byte, error) {
+	templates, err := c.getTemplates()
+	if err != nil {
+		return nil, err
+	}
+	template, ok := templates[name]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+
+	values := map[string]string{}
+	for _, param := range template.info.Parameters {
+		value, ok := params[param.Name]
+		if !ok && param.Required {
+			return nil, types.NewCodedError(types.ErrBadRequest, fmt.Errorf("the parameter \"%s\" is required", param.Name))
+		}
+		if !ok {
+			value = param.Default
+		}
+		values[param.Name] = value
+	}
+	for param := range params {
+		if _, ok := values[param]; !ok {
+			return nil, types.NewCodedError(types.ErrBadRequest, fmt.Errorf("the template has no parameter \"%s\"", param))
+		}
+	}
+
+	return json.Marshal(map[string]interface{}{"functions": renderCatalogValue(template.functions, "", values)})
+}
+
+// getTemplates returns the cached templates, fetching the catalog if they have expired. The expired templates are
+// kept if the catalog can't be fetched
+func (c *Catalog) getTemplates() (map[string]*catalogTemplate, error) {
+	if !c.Enabled() {
+		return nil, types.NewCodedError(types.ErrCatalogUnavailable, fmt.Errorf("the service catalog is not configured"))
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.templates != nil && time.Since(c.fetchedAt) < c.cfg.CatalogRefreshInterval {
+		return c.templates, nil
+	}
+
+	templates, err := fetchCatalog(c.cfg)
+	if err != nil {
+		if c.templates == nil {
+			return nil, types.NewCodedError(types.ErrCatalogUnavailable, err)
+		}
+		catalogLogger.Printf("Error fetching the service catalog, using the cached templates: %v\n", err)
+		templates = c.templates
+	}
+	c.templates = templates
+	c.fetchedAt = time.Now()
+	return templates, nil
+}
+
+// fetchCatalog downloads the catalog from its Git repository or OCI artifact and loads its templates
+func fetchCatalog(cfg *types.Config) (map[string]*catalogTemplate, error) {
+	tmpDir, err := os.MkdirTemp("", "oscar-catalog-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if strings.HasPrefix(cfg.CatalogURL, ociCatalogPrefix) {
+		err = PullOCIArtifact(strings.TrimPrefix(cfg.CatalogURL, ociCatalogPrefix), cfg.CatalogInsecure, tmpDir)
+	} else {
+		err = cloneGitRepository(cfg.CatalogURL, cfg.CatalogRef, gitEnv(), tmpDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return loadCatalogDir(filepath.Join(tmpDir, filepath.Clean("/"+cfg.CatalogPath)))
+}
+
+// loadCatalogDir loads the templates of a catalog directory. Each subdirectory with an FDL file (the first YAML
+// file with a "functions" block) is a template, whose services can reference their script as a file of the
+// subdirectory (e.g. "script: script.sh")
+func loadCatalogDir(dir string) (map[string]*catalogTemplate, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the catalog: %v", err)
+	}
+
+	templates := map[string]*catalogTemplate{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		template, err := loadCatalogTemplate(dir, entry.Name())
+		if err != nil {
+			catalogLogger.Printf("Skipping the template \"%s\": %v\n", entry.Name(), err)
+			continue
+		}
+		if template != nil {
+			templates[entry.Name()] = template
+		}
+	}
+	return templates, nil
+}
+
+// loadCatalogTemplate loads the template of a catalog subdirectory (nil if it has no FDL file)
+func loadCatalogTemplate(catalogDir string, name string) (*catalogTemplate, error) {
+	templateDir := filepath.Join(catalogDir, name)
+	files, err := filepath.Glob(filepath.Join(templateDir, "*.y*ml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		content, err := readRepoFile(catalogDir, filepath.Join(name, filepath.Base(file)))
+		if err != nil {
+			continue
+		}
+		var fdl catalogFDL
+		if err := yaml.Unmarshal(content, &fdl); err != nil || fdl.Functions == nil {
+			continue
+		}
+
+		template := &catalogTemplate{
+			info: types.CatalogTemplate{
+				Name:        name,
+				Description: fdl.Description,
+				Services:    []string{},
+				Parameters:  []types.CatalogParameter{},
+			},
+			functions: fdl.Functions,
+		}
+		for paramName, param := range fdl.Parameters {
+			p := types.CatalogParameter{Name: paramName, Description: param.Description, Required: param.Default == nil}
+			if param.Default != nil {
+				p.Default = *param.Default
+			}
+			template.info.Parameters = append(template.info.Parameters, p)
+		}
+		sort.Slice(template.info.Parameters, func(i, j int) bool {
+			return template.info.Parameters[i].Name < template.info.Parameters[j].Name
+		})
+
+		// Inline the scripts referenced as files of the template
+		if err := forEachCatalogService(fdl.Functions, func(service map[string]interface{}) error {
+			if serviceName, ok := service["name"].(string); ok {
+				template.info.Services = append(template.info.Services, serviceName)
+			}
+			script, ok := service["script"].(string)
+			if !ok || strings.Contains(script, "\n") {
+				return nil
+			}
+			content, err := readRepoFile(templateDir, script)
+			if err != nil {
+				return fmt.Errorf("error reading the script \"%s\": %v", script, err)
+			}
+			service["script"] = string(content)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return template, nil
+	}
+	return nil, nil
+}
+
+// forEachCatalogService calls fn with the definition of each service of a "functions" block
+func forEachCatalogService(functions interface{}, fn func(service map[string]interface{}) error) error {
+	block, _ := functions.(map[string]interface{})
+	clusters, _ := block["oscar"].([]interface{})
+	for _, cluster := range clusters {
+		services, _ := cluster.(map[string]interface{})
+		for _, service := range services {
+			if s, ok := service.(map[string]interface{}); ok {
+				if err := fn(s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// renderCatalogValue returns a copy of a template value replacing the parameter references of its strings, except
+// in the scripts. The strings that only reference a number or boolean parameter get its typed value
+func renderCatalogValue(value interface{}, key string, values map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rendered := map[string]interface{}{}
+		for k, item := range v {
+			rendered[k] = renderCatalogValue(item, k, values)
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered[i] = renderCatalogValue(item, key, values)
+		}
+		return rendered
+	case string:
+		if key == "script" {
+			return v
+		}
+		if match := catalogParameterRegex.FindStringSubmatch(v); match != nil && match[0] == v {
+			if value, ok := values[match[1]]; ok {
+				var typed interface{}
+				if err := yaml.Unmarshal([]byte(value), &typed); err == nil {
+					switch typed.(type) {
+					case bool, int, int64, uint64, float64:
+						return typed
+					}
+				}
+			}
+		}
+		return catalogParameterRegex.ReplaceAllStringFunc(v, func(ref string) string {
+			if value, ok := values[ref[2:len(ref)-1]]; ok {
+				return value
+			}
+			return ref
+		})
+	default:
+		return v
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+const testCatalogFDL = `
+description: Convert images to grayscale
+parameters:
+  NAME:
+    description: Name of the service
+    default: grayify
+  MEMORY:
+    default: 1Gi
+  REPLICAS:
+    default: "2"
+  BUCKET:
+    description: Bucket of the input and output
+functions:
+  oscar:
+  - oscar-cluster:
+      name: ${NAME}
+      image: grycap/imagemagick
+      memory: ${MEMORY}
+      script: script.sh
+      synchronous:
+        min_scale: ${REPLICAS}
+      input:
+      - storage_provider: minio.default
+        path: ${BUCKET}/in
+`
+
+func writeTestCatalog(t *testing.T) string {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "grayify"), 0755)
+	os.WriteFile(filepath.Join(dir, "grayify", "README.md"), []byte("# grayify"), 0644)
+	os.WriteFile(filepath.Join(dir, "grayify", "grayify.yaml"), []byte(testCatalogFDL), 0644)
+	os.WriteFile(filepath.Join(dir, "grayify", "script.sh"), []byte("convert \"${INPUT_FILE_PATH}\" -type Grayscale out.png\n"), 0644)
+	// Templates without FDL and with scripts outside their directory are skipped
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dir, "docs", "index.yaml"), []byte("title: docs"), 0644)
+	os.MkdirAll(filepath.Join(dir, "escape"), 0755)
+	os.WriteFile(filepath.Join(dir, "escape", "fdl.yml"), []byte("functions:\n  oscar:\n  - c:\n      name: x\n      script: ../grayify/script.sh\n"), 0644)
+	return dir
+}
+
+func TestLoadCatalogDir(t *testing.T) {
+	templates, err := loadCatalogDir(writeTestCatalog(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates["grayify"] == nil {
+		t.Fatalf("expecting only the grayify template, got %v", templates)
+	}
+
+	info := templates["grayify"].info
+	if info.Description != "Convert images to grayscale" || len(info.Services) != 1 || info.Services[0] != "${NAME}" {
+		t.Errorf("unexpected template info: %+v", info)
+	}
+	if len(info.Parameters) != 4 || info.Parameters[0].Name != "BUCKET" || !info.Parameters[0].Required || info.Parameters[1].Required {
+		t.Errorf("unexpected parameters: %+v", info.Parameters)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	templates, err := loadCatalogDir(writeTestCatalog(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := NewCatalog(&types.Config{CatalogURL: "oci://registry/catalog", CatalogRefreshInterval: time.Hour})
+	catalog.templates = templates
+	catalog.fetchedAt = time.Now()
+
+	if _, err := catalog.RenderTemplate("other", nil); err != ErrTemplateNotFound {
+		t.Errorf("expecting ErrTemplateNotFound, got %v", err)
+	}
+	if _, err := catalog.RenderTemplate("grayify", nil); types.GetErrorCode(err, types.ErrInternal) != types.ErrBadRequest {
+		t.Errorf("expecting error for the missing required parameter, got %v", err)
+	}
+	if _, err := catalog.RenderTemplate("grayify", map[string]string{"BUCKET": "b", "OTHER": "x"}); types.GetErrorCode(err, types.ErrInternal) != types.ErrBadRequest {
+		t.Errorf("expecting error for the unknown parameter, got %v", err)
+	}
+
+	rendered, err := catalog.RenderTemplate("grayify", map[string]string{"BUCKET": "alice", "NAME": "gray-alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var fdl types.FDL
+	if err := json.Unmarshal(rendered, &fdl); err != nil {
+		t.Fatalf("the rendered template is not valid: %v", err)
+	}
+	service := fdl.Functions.OSCAR[0]["oscar-cluster"]
+	if service.Name != "gray-alice" || service.Memory != "1Gi" || service.Input[0].Path != "alice/in" || service.Synchronous.MinScale != 2 {
+		t.Errorf("unexpected rendered service: %+v", service)
+	}
+	if !strings.Contains(service.Script, "${INPUT_FILE_PATH}") {
+		t.Errorf("the script must be inlined without replacing its variables, got: %s", service.Script)
+	}
+}
+
+func TestCatalogNotConfigured(t *testing.T) {
+	catalog := NewCatalog(&types.Config{})
+	if _, err := catalog.ListTemplates(); types.GetErrorCode(err, types.ErrInternal) != types.ErrCatalogUnavailable {
+		t.Errorf("expecting the %s error, got %v", types.ErrCatalogUnavailable.Code, err)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var chatLogger = log.New(os.Stdout, "[CHAT] ", log.Flags())
+
+const (
+	// chatLogLines maximum number of lines of the logs excerpt of the failed jobs
+	chatLogLines = 10
+	// chatLogSize maximum size (in bytes) of the logs excerpt of the failed jobs
+	chatLogSize = 2000
+	// chatTimeout timeout of the webhook requests
+	chatTimeout = 10 * time.Second
+)
+
+// StartChatNotificationWatcher starts the loop to notify the finished jobs of the services with a ChatNotification
+// every cfg.NotificationInterval
+func StartChatNotificationWatcher(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) {
+	for {
+		if err := notifyChatFinishedJobs(cfg, back, kubeClientset); err != nil {
+			chatLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.NotificationInterval) * time.Second)
+	}
+}
+
+func notifyChatFinishedJobs(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) error {
+	// List the services' jobs not notified yet
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,!%s", types.ServiceLabel, types.ChatNotifiedLabelKey),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+
+	// Map to store services' pointers
+	svcPtrs := map[string]*types.Service{}
+
+	for _, job := range jobs.Items {
+		succeeded := isJobComplete(&job)
+		reason, message, failed := getJobFailure(&job)
+		if !succeeded && !failed {
+			continue
+		}
+
+		serviceName := job.Labels[types.ServiceLabel]
+		if _, ok := svcPtrs[serviceName]; !ok {
+			svcPtrs[serviceName], err = back.ReadService(serviceName)
+			if err != nil {
+				chatLogger.Printf("error getting service \"%s\": %v\n", serviceName, err)
+				svcPtrs[serviceName] = nil
+			}
+		}
+		service := svcPtrs[serviceName]
+		if service == nil {
+			continue
+		}
+
+		// The finished jobs of services without notifications are also marked, so they are not listed again
+		notification := service.ChatNotification
+		if notification != nil && ((succeeded && notification.OnSuccess) || (failed && notification.OnFailure)) {
+			msgCtx := types.ChatMessageContext{
+				Service:    serviceName,
+				Job:        job.Name,
+				Status:     "succeeded",
+				FinishedAt: getJobFinishTime(&job),
+			}
+			if failed {
+				msgCtx.Status, msgCtx.Reason, msgCtx.Message = "failed", reason, message
+				msgCtx.Logs = getJobLogsExcerpt(kubeClientset, cfg.ServicesNamespace, job.Name)
+			} else {
+				msgCtx.Outputs = getChatOutputs(service, &job)
+			}
+
+			if err := sendChatNotification(cfg, kubeClientset, service, msgCtx); err != nil {
+				chatLogger.Printf("error notifying job \"%s\": %v\n", job.Name, err)
+				continue
+			}
+		}
+
+		// Mark the job as notified
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{"%s":"true"}}}`, types.ChatNotifiedLabelKey))
+		if _, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Patch(context.TODO(), job.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			chatLogger.Printf("error labelling the notified job \"%s\": %v\n", job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// sendChatNotification renders the message of a finished job and posts it to the webhook of the service's ChatNotification
+func sendChatNotification(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, msgCtx types.ChatMessageContext) error {
+	notification := service.ChatNotification
+	message, err := notification.RenderMessage(msgCtx)
+	if err != nil {
+		return err
+	}
+	body, err := notification.MakePayload(message, msgCtx.Status == "failed")
+	if err != nil {
+		return err
+	}
+
+	webhookURL, err := getServiceSecretValue(kubeClientset, cfg.ServicesNamespace, service.Name, notification.WebhookSecret, types.ChatWebhookURLKey)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: chatTimeout}
+	res, err := client.Post(strings.TrimSpace(string(webhookURL)), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+// getChatOutputs returns the output files of a job (see getOutputLinks) named after their keys
+func getChatOutputs(service *types.Service, job *batchv1.Job) []types.ChatOutput {
+	outputs := []types.ChatOutput{}
+	for _, link := range getOutputLinks(service, job) {
+		name := link
+		if u, err := url.Parse(link); err == nil {
+			name = path.Base(u.Path)
+		}
+		outputs = append(outputs, types.ChatOutput{Name: name, URL: link})
+	}
+	return outputs
+}
+
+// getJobLogsExcerpt returns the last lines (without timestamps) of the logs of a job, limited to chatLogSize bytes
+func getJobLogsExcerpt(kubeClientset kubernetes.Interface, namespace string, jobName string) string {
+	logs, err := getLastPodLogs(kubeClientset, namespace, jobName)
+	if err != nil {
+		chatLogger.Printf("error getting the logs of job \"%s\": %v\n", jobName, err)
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimRight(string(logs), "\n"), "\n")
+	if len(lines) > chatLogLines {
+		lines = lines[len(lines)-chatLogLines:]
+	}
+	for i, line := range lines {
+		// Remove the timestamp added by Kubernetes
+		if parts := strings.SplitN(line, " ", 2); len(parts) == 2 {
+			if _, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+				lines[i] = parts[1]
+			}
+		}
+	}
+
+	excerpt := strings.Join(lines, "\n")
+	if len(excerpt) > chatLogSize {
+		excerpt = excerpt[len(excerpt)-chatLogSize:]
+	}
+	return excerpt
+}
@@ -0,0 +1,115 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNotifyChatFinishedJobs(t *testing.T) {
+	messages := []map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]string
+		json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+	cfg := &types.Config{ServicesNamespace: "oscar-svc"}
+
+	back := &fakeServiceBackend{services: map[string]*types.Service{
+		"notified": {Name: "notified", ChatNotification: &types.ChatNotification{
+			Platform:      types.MattermostPlatform,
+			WebhookSecret: "chat-secret",
+			OnFailure:     true,
+		}},
+		"not-notified": {Name: "not-notified"},
+	}}
+
+	makeJob := func(name string, service string, condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cfg.ServicesNamespace,
+				Labels:    map[string]string{types.ServiceLabel: service},
+			},
+		}
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+		}
+		return job
+	}
+	kubeClientset := testclient.NewSimpleClientset(
+		makeJob("completed", "notified", batchv1.JobComplete),
+		makeJob("failed", "notified", batchv1.JobFailed),
+		makeJob("running", "notified", ""),
+		makeJob("other-failed", "not-notified", batchv1.JobFailed),
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "failed-pod",
+			Namespace: cfg.ServicesNamespace,
+			Labels:    map[string]string{"job-name": "failed"},
+		}},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "chat-secret",
+				Namespace: cfg.ServicesNamespace,
+				Labels:    map[string]string{types.ServiceLabel: "notified"},
+			},
+			Data: map[string][]byte{types.ChatWebhookURLKey: []byte(server.URL + "\n")},
+		},
+	)
+
+	if err := notifyChatFinishedJobs(cfg, back, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expecting 1 message, got %d: %v", len(messages), messages)
+	}
+	text := messages[0]["text"]
+	for _, expected := range []string{"**failed**", "**notified**", "BackoffLimitExceeded", "```\nfake logs\n```"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expecting message containing %q, got %q", expected, text)
+		}
+	}
+
+	expectedLabelled := map[string]bool{
+		"completed":    true,
+		"failed":       true,
+		"running":      false,
+		"other-failed": true,
+	}
+	for name, expected := range expectedLabelled {
+		job, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, labelled := job.Labels[types.ChatNotifiedLabelKey]; labelled != expected {
+			t.Errorf("job \"%s\": expecting labelled %v, got %v", name, expected, labelled)
+		}
+	}
+}
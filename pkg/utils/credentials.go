@@ -0,0 +1,131 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// minJobCredentialsDuration minimum duration of the temporary credentials accepted by MinIO
+const minJobCredentialsDuration = 15 * time.Minute
+
+// ScopeJobCredentials returns a copy of the service whose default MinIO provider uses temporary credentials, limited
+// to read the inputs and write the outputs of the service, obtained from the STS AssumeRole API of the OSCAR's MinIO
+// (or its AssumeRoleWithWebIdentity API if the service is invoked with the OIDC token of a user, so the credentials
+// are also limited by the policies of the user). The default MinIO provider is removed if the service doesn't use it
+func ScopeJobCredentials(cfg *types.Config, service *types.Service, jobName string) (*types.Service, error) {
+	policy, err := service.GetJobPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("error making the policy of the job's credentials: %v", err)
+	}
+	if policy == "" {
+		return service.WithJobCredentials(nil), nil
+	}
+
+	duration := cfg.JobCredentialsDuration
+	if duration < minJobCredentialsDuration {
+		duration = minJobCredentialsDuration
+	}
+
+	// The credentials of the failover jobs are obtained from the secondary provider
+	minIO := cfg.MinIOProvider
+	if service.StorageFailover {
+		minIO = service.StorageProviders.MinIO[types.DefaultProvider]
+	}
+
+	var creds *sts.Credentials
+	if cfg.JobCredentialsWebIdentity && service.IdentityToken != "" && !service.StorageFailover {
+		creds, err = assumeRoleWithWebIdentity(cfg, minIO, service.IdentityToken, jobName, duration, policy)
+	} else {
+		// The RoleArn is required by the SDK but ignored by MinIO
+		var out *sts.AssumeRoleOutput
+		out, err = getSTSClient(minIO).AssumeRole(&sts.AssumeRoleInput{
+			RoleArn:         aws.String("arn:xxx:xxx:xxx:xxxx"),
+			RoleSessionName: aws.String(jobName),
+			DurationSeconds: aws.Int64(int64(duration.Seconds())),
+			Policy:          aws.String(policy),
+		})
+		if out != nil {
+			creds = out.Credentials
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting the temporary credentials of the job: %v", err)
+	}
+
+	provider := *cfg.MinIOProvider
+	if service.StorageProviders != nil && service.StorageProviders.MinIO[types.DefaultProvider] != nil {
+		provider = *service.StorageProviders.MinIO[types.DefaultProvider]
+	}
+	provider.AccessKey = aws.StringValue(creds.AccessKeyId)
+	provider.SecretKey = aws.StringValue(creds.SecretAccessKey)
+	provider.SessionToken = aws.StringValue(creds.SessionToken)
+
+	return service.WithJobCredentials(&provider), nil
+}
+
+// assumeRoleWithWebIdentity exchanges the OIDC token of a user by temporary credentials of the MinIO provider,
+// limited by the session policy. The RoleArn is only sent if JOB_CREDENTIALS_ROLE_ARN is set, as MinIO uses the
+// claim-based policies of the user otherwise
+func assumeRoleWithWebIdentity(cfg *types.Config, minIO *types.MinIOProvider, token string, jobName string, duration time.Duration, policy string) (*sts.Credentials, error) {
+	input := &sts.AssumeRoleWithWebIdentityInput{
+		RoleSessionName:  aws.String(jobName),
+		WebIdentityToken: aws.String(token),
+		DurationSeconds:  aws.Int64(int64(duration.Seconds())),
+		Policy:           aws.String(policy),
+	}
+	req, out := getSTSClient(minIO).AssumeRoleWithWebIdentityRequest(input)
+	if cfg.JobCredentialsRoleARN != "" {
+		input.RoleArn = aws.String(cfg.JobCredentialsRoleARN)
+	} else {
+		// Skip the validation of the (required by the SDK) RoleArn
+		req.Handlers.Validate.Clear()
+	}
+	if err := req.Send(); err != nil {
+		return nil, err
+	}
+	return out.Credentials, nil
+}
+
+// getSTSClient creates a STS client for the MinIO provider
+func getSTSClient(minIOProvider *types.MinIOProvider) *sts.STS {
+	stsConfig := &aws.Config{
+		Credentials: credentials.NewStaticCredentials(minIOProvider.AccessKey, minIOProvider.SecretKey, ""),
+		Endpoint:    aws.String(minIOProvider.Endpoint),
+		Region:      aws.String(minIOProvider.Region),
+	}
+
+	// Disable tls verification in client transport if Verify == false
+	if !minIOProvider.Verify {
+		tr := &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+		stsConfig.HTTPClient = &http.Client{Transport: tr}
+	}
+
+	stsSession, _ := session.NewSession(stsConfig)
+	return sts.New(stsSession)
+}
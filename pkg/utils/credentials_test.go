@@ -0,0 +1,146 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestScopeJobCredentials(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		fmt.Fprint(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>
+<Credentials><AccessKeyId>TMPKEY</AccessKeyId><SecretAccessKey>TMPSECRET</SecretAccessKey><SessionToken>TOKEN</SessionToken>
+<Expiration>2030-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`)
+	}))
+	defer server.Close()
+
+	minIO := &types.MinIOProvider{Endpoint: server.URL, Region: "us-east-1", AccessKey: "minioadmin", SecretKey: "minioadmin", Verify: true}
+	cfg := &types.Config{MinIOProvider: minIO}
+	s3 := &types.S3Provider{AccessKey: "key", SecretKey: "secret", Region: "us-east-1"}
+	service := &types.Service{
+		Name:   "test",
+		Input:  []types.StorageIOConfig{{Provider: "minio.default", Path: "test/input"}},
+		Output: []types.StorageIOConfig{{Provider: "minio.default", Path: "test/output/"}, {Provider: "s3.aws", Path: "results"}},
+		StorageProviders: &types.StorageProviders{
+			MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: minIO},
+			S3:    map[string]*types.S3Provider{"aws": s3},
+		},
+	}
+
+	scoped, err := ScopeJobCredentials(cfg, service, "job")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if form["Action"][0] != "AssumeRole" || form["DurationSeconds"][0] != "900" || form["RoleSessionName"][0] != "job" {
+		t.Errorf("unexpected AssumeRole request: %v", form)
+	}
+	policy := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(form["Policy"][0]), &policy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(form["Policy"][0], "arn:aws:s3:::test/input/*") || !strings.Contains(form["Policy"][0], "arn:aws:s3:::test/output/*") || strings.Contains(form["Policy"][0], "results") {
+		t.Errorf("unexpected policy: %s", form["Policy"][0])
+	}
+
+	provider := scoped.StorageProviders.MinIO[types.DefaultProvider]
+	if provider.AccessKey != "TMPKEY" || provider.SecretKey != "TMPSECRET" || provider.SessionToken != "TOKEN" || provider.Endpoint != server.URL {
+		t.Errorf("unexpected provider: %+v", provider)
+	}
+	if scoped.StorageProviders.S3["aws"] != s3 || !scoped.HasJobConfig() {
+		t.Errorf("unexpected service: %+v", scoped)
+	}
+	// The service's definition is not modified
+	if minIO.AccessKey != "minioadmin" || service.HasJobConfig() {
+		t.Errorf("the service has been modified: %+v", service)
+	}
+
+	// The default MinIO provider is removed from the services not using it
+	form = nil
+	service.Input = nil
+	service.Output = service.Output[1:]
+	scoped, err = ScopeJobCredentials(cfg, service, "job")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if form != nil {
+		t.Errorf("unexpected AssumeRole request: %v", form)
+	}
+	if _, ok := scoped.StorageProviders.MinIO[types.DefaultProvider]; ok {
+		t.Errorf("expected no default MinIO provider, got %+v", scoped.StorageProviders.MinIO)
+	}
+}
+
+func TestScopeJobCredentialsWebIdentity(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleWithWebIdentityResult>
+<Credentials><AccessKeyId>USERKEY</AccessKeyId><SecretAccessKey>USERSECRET</SecretAccessKey><SessionToken>TOKEN</SessionToken>
+<Expiration>2030-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	minIO := &types.MinIOProvider{Endpoint: server.URL, Region: "us-east-1", AccessKey: "minioadmin", SecretKey: "minioadmin", Verify: true}
+	cfg := &types.Config{MinIOProvider: minIO, JobCredentialsWebIdentity: true}
+	service := &types.Service{
+		Name:             "test",
+		Input:            []types.StorageIOConfig{{Provider: "minio.default", Path: "test/input"}},
+		StorageProviders: &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: minIO}},
+	}
+
+	scoped, err := ScopeJobCredentials(cfg, service.WithIdentityToken("oidc-token"), "job")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if form["Action"][0] != "AssumeRoleWithWebIdentity" || form["WebIdentityToken"][0] != "oidc-token" || form["RoleSessionName"][0] != "job" {
+		t.Errorf("unexpected AssumeRoleWithWebIdentity request: %v", form)
+	}
+	if _, ok := form["RoleArn"]; ok {
+		t.Errorf("unexpected RoleArn: %v", form["RoleArn"])
+	}
+	if !strings.Contains(form["Policy"][0], "arn:aws:s3:::test/input/*") {
+		t.Errorf("unexpected policy: %s", form["Policy"][0])
+	}
+	if provider := scoped.StorageProviders.MinIO[types.DefaultProvider]; provider.AccessKey != "USERKEY" || provider.SessionToken != "TOKEN" {
+		t.Errorf("unexpected provider: %+v", provider)
+	}
+
+	// The configured role is assumed
+	cfg.JobCredentialsRoleARN = "arn:minio:iam:::role/oscar-jobs"
+	if _, err := ScopeJobCredentials(cfg, service.WithIdentityToken("oidc-token"), "job"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if form["RoleArn"][0] != "arn:minio:iam:::role/oscar-jobs" {
+		t.Errorf("unexpected RoleArn: %v", form["RoleArn"])
+	}
+}
@@ -0,0 +1,287 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var deadLetterLogger = log.New(os.Stdout, "[DEAD-LETTER] ", log.Flags())
+
+const deadLetterRecordExt = ".json"
+
+// StartDeadLetterWatcher starts the loop to store the failed jobs in their services' dead-letter path every cfg.DeadLetterInterval
+func StartDeadLetterWatcher(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) {
+	for {
+		if err := storeFailedJobs(cfg, back, kubeClientset); err != nil {
+			deadLetterLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.DeadLetterInterval) * time.Second)
+	}
+}
+
+func storeFailedJobs(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) error {
+	// List the services' jobs not stored yet
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,!%s", types.ServiceLabel, types.DeadLetterLabelKey),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+
+	// Map to store services' pointers
+	svcPtrs := map[string]*types.Service{}
+
+	for _, job := range jobs.Items {
+		reason, message, failed := getJobFailure(&job)
+		if !failed {
+			continue
+		}
+
+		serviceName := job.Labels[types.ServiceLabel]
+		if _, ok := svcPtrs[serviceName]; !ok {
+			svcPtrs[serviceName], err = back.ReadService(serviceName)
+			if err != nil {
+				deadLetterLogger.Printf("error getting service \"%s\": %v\n", serviceName, err)
+				svcPtrs[serviceName] = nil
+			}
+		}
+		service := svcPtrs[serviceName]
+		if service == nil || service.DeadLetterPath == "" {
+			continue
+		}
+
+		record := types.DeadLetterRecord{
+			ID:       job.Name,
+			Service:  serviceName,
+			Event:    GetJobEvent(&job),
+			Reason:   reason,
+			Message:  message,
+			ExitCode: getJobExitCode(kubeClientset, job.Namespace, job.Name),
+			FailedAt: time.Now(),
+		}
+		if minIOEvent, err := types.ParseMinIOEvent([]byte(record.Event)); err == nil {
+			record.Bucket = minIOEvent.GetBucket()
+			record.Key = minIOEvent.GetObjectKey()
+		}
+
+		if err := PutDeadLetterRecord(cfg, service, record); err != nil {
+			deadLetterLogger.Printf("error storing the failed job \"%s\": %v\n", job.Name, err)
+			continue
+		}
+
+		// Mark the job as stored
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{"%s":"true"}}}`, types.DeadLetterLabelKey))
+		if _, err := kubeClientset.BatchV1().Jobs(job.Namespace).Patch(context.TODO(), job.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			deadLetterLogger.Printf("error labelling the failed job \"%s\": %v\n", job.Name, err)
+		}
+		deadLetterLogger.Printf("Failed job \"%s\" of service \"%s\" stored in \"%s\"\n", job.Name, serviceName, service.DeadLetterPath)
+	}
+
+	return nil
+}
+
+// getJobFailure returns the reason and message of a (permanently) failed job
+func getJobFailure(job *batchv1.Job) (reason string, message string, failed bool) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == v1.ConditionTrue {
+			return cond.Reason, cond.Message, true
+		}
+	}
+	return "", "", false
+}
+
+// GetJobEvent returns the event passed to the job's container
+func GetJobEvent(job *batchv1.Job) string {
+	for _, c := range job.Spec.Template.Spec.Containers {
+		if c.Name == types.ContainerName {
+			for _, envVar := range c.Env {
+				if envVar.Name == types.EventVariable {
+					return envVar.Value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// getJobExitCode returns the exit code of the job's service container
+func getJobExitCode(kubeClientset kubernetes.Interface, namespace string, jobName string) int32 {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", jobName),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return 0
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == types.ContainerName && status.State.Terminated != nil {
+				return status.State.Terminated.ExitCode
+			}
+		}
+	}
+	return 0
+}
+
+// getDeadLetterLocation returns the bucket and the key prefix of the service's dead-letter path
+func getDeadLetterLocation(service *types.Service) (bucket string, prefix string) {
+	bucket, folder := types.StorageIOConfig{Path: service.DeadLetterPath}.SplitPath()
+	if folder != "" {
+		prefix = folder + "/"
+	}
+	return bucket, prefix
+}
+
+// CreateDeadLetterBucket creates the bucket of the service's dead-letter path if it doesn't exist
+func CreateDeadLetterBucket(cfg *types.Config, service *types.Service) error {
+	bucket, _ := getDeadLetterLocation(service)
+	_, err := cfg.MinIOProvider.GetS3Client().CreateBucket(&s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		// Check if the error is caused because the bucket already exists
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeBucketAlreadyExists || aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou) {
+			return nil
+		}
+		return fmt.Errorf("error creating bucket %s: %v", bucket, err)
+	}
+	return nil
+}
+
+// PutDeadLetterRecord stores a record in the service's dead-letter path
+func PutDeadLetterRecord(cfg *types.Config, service *types.Service, record types.DeadLetterRecord) error {
+	bucket, prefix := getDeadLetterLocation(service)
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.MinIOProvider.GetS3Client().PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(prefix + record.ID + deadLetterRecordExt),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// GetDeadLetterRecord returns a record of the service from its dead-letter path
+func GetDeadLetterRecord(cfg *types.Config, service *types.Service, id string) (*types.DeadLetterRecord, error) {
+	record, err := readDeadLetterRecord(cfg, service, id)
+	if err != nil {
+		return nil, err
+	}
+	// Only return the records stored by OSCAR for the service
+	if record.Service != service.Name {
+		return nil, fmt.Errorf("the record \"%s\" does not belong to the service \"%s\"", id, service.Name)
+	}
+	return record, nil
+}
+
+func readDeadLetterRecord(cfg *types.Config, service *types.Service, id string) (*types.DeadLetterRecord, error) {
+	bucket, prefix := getDeadLetterLocation(service)
+	out, err := cfg.MinIOProvider.GetS3Client().GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(prefix + path.Base(id) + deadLetterRecordExt),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	body, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &types.DeadLetterRecord{}
+	if err := json.Unmarshal(body, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ListDeadLetterRecords returns all the records of the service stored in its dead-letter path
+func ListDeadLetterRecords(cfg *types.Config, service *types.Service) ([]types.DeadLetterRecord, error) {
+	records := []types.DeadLetterRecord{}
+	bucket, prefix := getDeadLetterLocation(service)
+
+	var getErr error
+	err := cfg.MinIOProvider.GetS3Client().ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			// Skip nested folders and non-record objects
+			id := strings.TrimPrefix(key, prefix)
+			if strings.Contains(id, "/") || !strings.HasSuffix(id, deadLetterRecordExt) {
+				continue
+			}
+			record, err := readDeadLetterRecord(cfg, service, strings.TrimSuffix(id, deadLetterRecordExt))
+			if err != nil {
+				getErr = fmt.Errorf("error reading the dead-letter record \"%s\": %v", key, err)
+				return false
+			}
+			// Skip the records of other services
+			if record.Service != service.Name {
+				continue
+			}
+			records = append(records, *record)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the dead-letter records: %v", err)
+	}
+
+	return records, getErr
+}
+
+// DeleteDeadLetterRecord removes a record from the service's dead-letter path
+func DeleteDeadLetterRecord(cfg *types.Config, service *types.Service, id string) error {
+	bucket, prefix := getDeadLetterLocation(service)
+	_, err := cfg.MinIOProvider.GetS3Client().DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(prefix + path.Base(id) + deadLetterRecordExt),
+	})
+	return err
+}
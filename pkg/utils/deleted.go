@@ -0,0 +1,188 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var deletedLogger = log.New(os.Stdout, "[DELETED-SERVICES] ", log.Flags())
+
+// SaveDeletedService stores the definition of a service deleted with "purge=false", replacing the one of a previous
+// deletion of the same service
+func SaveDeletedService(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service) error {
+	// The script is stored apart, as in the service's configMap
+	svc := *service
+	svc.Script = ""
+	fdl, err := svc.ToYAML()
+	if err != nil {
+		return err
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.DeletedServiceConfigMapName(service.Name),
+			Namespace: cfg.ServicesNamespace,
+			Labels: map[string]string{
+				types.ServiceLabel: service.Name,
+				types.DeletedLabel: "true",
+			},
+			Annotations: map[string]string{
+				types.DeletedAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+		Data: map[string]string{
+			types.ScriptFileName: service.Script,
+			types.FDLFileName:    fdl,
+		},
+	}
+	cms := kubeClientset.CoreV1().ConfigMaps(cfg.ServicesNamespace)
+	_, err = cms.Create(context.TODO(), cm, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = cms.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error storing the definition of the deleted service \"%s\": %v", service.Name, err)
+	}
+	return nil
+}
+
+// GetDeletedService returns the definition of a soft-deleted service. The Kubernetes NotFound error is returned if
+// the service has not been soft-deleted
+func GetDeletedService(kubeClientset kubernetes.Interface, namespace string, serviceName string) (*types.Service, error) {
+	cm, err := kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), types.DeletedServiceConfigMapName(serviceName), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	service, err := parseDeletedService(cm)
+	if err != nil {
+		return nil, err
+	}
+	service.Script = cm.Data[types.ScriptFileName]
+	return service, nil
+}
+
+// parseDeletedService reads the definition of a soft-deleted service from its configMap
+func parseDeletedService(cm *v1.ConfigMap) (*types.Service, error) {
+	service := &types.Service{}
+	if err := yaml.Unmarshal([]byte(cm.Data[types.FDLFileName]), service); err != nil {
+		return nil, fmt.Errorf("the definition of the deleted service \"%s\" cannot be read: %v", cm.Labels[types.ServiceLabel], err)
+	}
+	return service, nil
+}
+
+// RemoveDeletedService removes the definition of a soft-deleted service
+func RemoveDeletedService(kubeClientset kubernetes.Interface, namespace string, serviceName string) error {
+	err := kubeClientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), types.DeletedServiceConfigMapName(serviceName), metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("error removing the definition of the deleted service \"%s\": %v", serviceName, err)
+	}
+	return nil
+}
+
+// ListDeletedServices returns the soft-deleted services, most recently deleted first
+func ListDeletedServices(cfg *types.Config, kubeClientset kubernetes.Interface) ([]types.DeletedService, error) {
+	cms, err := kubeClientset.CoreV1().ConfigMaps(cfg.ServicesNamespace).List(context.TODO(), metav1.ListOptions{LabelSelector: types.DeletedLabel})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the deleted services: %v", err)
+	}
+
+	deleted := []types.DeletedService{}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		deletedAt, _ := time.Parse(time.RFC3339, cm.Annotations[types.DeletedAtAnnotation])
+		// An unreadable definition is still listed, so that it is purged
+		service, err := parseDeletedService(cm)
+		if err != nil {
+			deletedLogger.Println(err.Error())
+		}
+		deleted = append(deleted, types.DeletedService{
+			Name:      cm.Labels[types.ServiceLabel],
+			DeletedAt: deletedAt,
+			PurgeAt:   deletedAt.Add(cfg.DeletedServicesRetention),
+			Service:   service,
+		})
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+	})
+	return deleted, nil
+}
+
+// StartDeletedServicesPurger starts the loop to purge the soft-deleted services whose retention period has expired
+// every cfg.DeletedServicesInterval
+func StartDeletedServicesPurger(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) {
+	for {
+		if err := purgeDeletedServices(cfg, back, kubeClientset); err != nil {
+			deletedLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.DeletedServicesInterval) * time.Second)
+	}
+}
+
+// purgeDeletedServices removes the definitions of the expired soft-deleted services, along with their revision
+// history, invocation aliases and record of created buckets (unless a service with the same name has been created since)
+func purgeDeletedServices(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) error {
+	deleted, err := ListDeletedServices(cfg, kubeClientset)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range deleted {
+		if time.Now().Before(service.PurgeAt) {
+			continue
+		}
+		_, err := back.ReadService(service.Name)
+		if err != nil && !k8serrors.IsNotFound(err) && !k8serrors.IsGone(err) {
+			deletedLogger.Printf("Error checking if the deleted service \"%s\" has been created again: %v\n", service.Name, err)
+			continue
+		}
+		if err != nil {
+			if err := DeleteServiceRevisions(kubeClientset, cfg.ServicesNamespace, service.Name); err != nil {
+				deletedLogger.Println(err.Error())
+				continue
+			}
+			if err := DeleteServiceAliases(kubeClientset, cfg.ServicesNamespace, service.Name); err != nil {
+				deletedLogger.Println(err.Error())
+				continue
+			}
+			if err := DeleteCreatedBuckets(kubeClientset, cfg.ServicesNamespace, service.Name); err != nil {
+				deletedLogger.Println(err.Error())
+				continue
+			}
+		}
+		if err := RemoveDeletedService(kubeClientset, cfg.ServicesNamespace, service.Name); err != nil {
+			deletedLogger.Println(err.Error())
+			continue
+		}
+		deletedLogger.Printf("The deleted service \"%s\" has been purged\n", service.Name)
+	}
+	return nil
+}
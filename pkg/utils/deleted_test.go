@@ -0,0 +1,70 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPurgeDeletedServices(t *testing.T) {
+	cfg := &types.Config{ServicesNamespace: "oscar-svc", DeletedServicesRetention: time.Hour}
+	kubeClientset := testclient.NewSimpleClientset()
+	back := &fakeServiceBackend{services: map[string]*types.Service{
+		"recreated": {Name: "recreated"},
+	}}
+
+	for _, name := range []string{"expired", "recreated", "recent"} {
+		service := &types.Service{Name: name, Revision: 1}
+		if err := SaveDeletedService(cfg, kubeClientset, service); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := SaveServiceRevision(cfg, kubeClientset, service); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if name == "recent" {
+			continue
+		}
+		// Backdate the deletion beyond the retention period
+		cms := kubeClientset.CoreV1().ConfigMaps(cfg.ServicesNamespace)
+		cm, _ := cms.Get(context.TODO(), types.DeletedServiceConfigMapName(name), metav1.GetOptions{})
+		cm.Annotations[types.DeletedAtAnnotation] = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+		cms.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	}
+
+	if err := purgeDeletedServices(cfg, back, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deleted, _ := ListDeletedServices(cfg, kubeClientset)
+	if len(deleted) != 1 || deleted[0].Name != "recent" {
+		t.Errorf("expecting only the recent service to be kept, got %+v", deleted)
+	}
+
+	scenarios := map[string]int{"expired": 0, "recreated": 1, "recent": 1}
+	for name, expected := range scenarios {
+		revisions, _ := ListServiceRevisions(kubeClientset, cfg.ServicesNamespace, name)
+		if len(revisions) != expected {
+			t.Errorf("expecting %d revisions of %s, got %d", expected, name, len(revisions))
+		}
+	}
+}
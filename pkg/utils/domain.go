@@ -0,0 +1,203 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	net "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// certManagerIssuerAnnotation annotation to request the certificate of an Ingress to a cert-manager ClusterIssuer
+const certManagerIssuerAnnotation = "cert-manager.io/cluster-issuer"
+
+// SetExposedDomain creates or updates the Ingress serving a custom domain of an exposed service, in the namespace
+// of the service (where its Kubernetes service and the TLS secret live). The error coded
+// types.ErrDomainAlreadyExists is returned if the domain is used by another service of any namespace
+func SetExposedDomain(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, domain *types.ExposedDomain) error {
+	// With VONamespaces enabled the services (and their domains) are spread over several namespaces
+	listNamespace := cfg.ServicesNamespace
+	if cfg.VONamespaces {
+		listNamespace = metav1.NamespaceAll
+	}
+	ingresses, err := listDomainIngresses(kubeClientset, listNamespace, "")
+	if err != nil {
+		return err
+	}
+	for _, ing := range ingresses {
+		if ing.Annotations[types.DomainAnnotation] == domain.Host && ing.Labels[types.ServiceLabel] != service.Name {
+			return types.NewCodedError(types.ErrDomainAlreadyExists, fmt.Errorf("the domain \"%s\" is used by another service", domain.Host))
+		}
+	}
+
+	namespace := cfg.GetServiceNamespace(service)
+	ingress := getDomainIngress(cfg, namespace, service.Name, domain)
+	_, err = kubeClientset.NetworkingV1().Ingresses(namespace).Update(context.TODO(), ingress, metav1.UpdateOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = kubeClientset.NetworkingV1().Ingresses(namespace).Create(context.TODO(), ingress, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error setting the domain \"%s\" of service \"%s\": %v", domain.Host, service.Name, err)
+	}
+	return nil
+}
+
+// ListExposedDomains returns the custom domains of an exposed service, sorted by hostname
+func ListExposedDomains(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]types.ExposedDomain, error) {
+	ingresses, err := listDomainIngresses(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []types.ExposedDomain{}
+	for _, ing := range ingresses {
+		domains = append(domains, getIngressDomain(&ing))
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Host < domains[j].Host
+	})
+	return domains, nil
+}
+
+// DeleteExposedDomain removes the Ingress serving a custom domain of an exposed service. The error coded
+// types.ErrDomainNotFound is returned if the service does not have the domain
+func DeleteExposedDomain(kubeClientset kubernetes.Interface, namespace string, serviceName string, host string) error {
+	err := kubeClientset.NetworkingV1().Ingresses(namespace).Delete(context.TODO(), types.DomainIngressName(serviceName, host), metav1.DeleteOptions{})
+	if k8serrors.IsNotFound(err) {
+		return types.NewCodedError(types.ErrDomainNotFound, fmt.Errorf("the service \"%s\" does not have the domain \"%s\"", serviceName, host))
+	}
+	if err != nil {
+		return fmt.Errorf("error removing the domain \"%s\" of service \"%s\": %v", host, serviceName, err)
+	}
+	return nil
+}
+
+// DeleteServiceDomains removes the Ingresses serving the custom domains of an exposed service (if any)
+func DeleteServiceDomains(kubeClientset kubernetes.Interface, namespace string, serviceName string) error {
+	ingresses, err := listDomainIngresses(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return err
+	}
+	for _, ing := range ingresses {
+		err := kubeClientset.NetworkingV1().Ingresses(namespace).Delete(context.TODO(), ing.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("error removing the domain \"%s\" of service \"%s\": %v", ing.Annotations[types.DomainAnnotation], serviceName, err)
+		}
+	}
+	return nil
+}
+
+// listDomainIngresses returns the Ingresses of the custom domains of a service (of all services if serviceName is empty)
+func listDomainIngresses(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]net.Ingress, error) {
+	selector := types.DomainLabel
+	if serviceName != "" {
+		selector = fmt.Sprintf("%s,%s=%s", types.DomainLabel, types.ServiceLabel, serviceName)
+	}
+	ingresses, err := kubeClientset.NetworkingV1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the domains of the exposed services: %v", err)
+	}
+	return ingresses.Items, nil
+}
+
+// getDomainIngress returns the Ingress serving a custom domain of an exposed service, routing all its paths
+// to the Kubernetes service of the exposed service (so it must be created in the namespace of the service)
+func getDomainIngress(cfg *types.Config, namespace string, serviceName string, domain *types.ExposedDomain) *net.Ingress {
+	name := types.DomainIngressName(serviceName, domain.Host)
+
+	annotations := map[string]string{}
+	for key, value := range domain.Annotations {
+		annotations[key] = value
+	}
+	annotations["kubernetes.io/ingress.class"] = "nginx"
+	annotations[types.DomainAnnotation] = domain.Host
+
+	pathType := net.PathTypePrefix
+	spec := net.IngressSpec{
+		Rules: []net.IngressRule{
+			{
+				Host: domain.Host,
+				IngressRuleValue: net.IngressRuleValue{
+					HTTP: &net.HTTPIngressRuleValue{
+						Paths: []net.HTTPIngressPath{
+							{
+								Path:     "/",
+								PathType: &pathType,
+								Backend: net.IngressBackend{
+									Service: &net.IngressServiceBackend{
+										Name: getNameService(serviceName),
+										Port: net.ServiceBackendPort{Number: 80},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if domain.TLS {
+		annotations[certManagerIssuerAnnotation] = domain.GetIssuer(cfg)
+		spec.TLS = []net.IngressTLS{
+			{
+				Hosts:      []string{domain.Host},
+				SecretName: types.DomainTLSSecretName(serviceName, domain.Host),
+			},
+		}
+	}
+
+	return &net.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				types.ServiceLabel: serviceName,
+				types.DomainLabel:  "true",
+			},
+			Annotations: annotations,
+		},
+		Spec: spec,
+	}
+}
+
+// getIngressDomain returns the custom domain served by an Ingress
+func getIngressDomain(ing *net.Ingress) types.ExposedDomain {
+	domain := types.ExposedDomain{
+		Host:   ing.Annotations[types.DomainAnnotation],
+		Issuer: ing.Annotations[certManagerIssuerAnnotation],
+	}
+	for key, value := range ing.Annotations {
+		if key == types.DomainAnnotation || key == certManagerIssuerAnnotation || key == "kubernetes.io/ingress.class" {
+			continue
+		}
+		if domain.Annotations == nil {
+			domain.Annotations = map[string]string{}
+		}
+		domain.Annotations[key] = value
+	}
+	if len(ing.Spec.TLS) > 0 {
+		domain.TLS = true
+		domain.TLSSecret = ing.Spec.TLS[0].SecretName
+	}
+	return domain
+}
@@ -0,0 +1,280 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var emailLogger = log.New(os.Stdout, "[EMAIL] ", log.Flags())
+
+const (
+	// emailPollerTick time between checks of the services' poll intervals
+	emailPollerTick = 10 * time.Second
+	// outputLinksExpiration expiration time of the presigned output links sent in the notifications
+	outputLinksExpiration = 24 * time.Hour
+)
+
+// StartEmailPoller starts the loop polling the IMAP mailboxes of the services with an EmailTrigger,
+// calling deliver with the event of each new email
+func StartEmailPoller(cfg *types.Config, back types.ServerlessBackend, deliver func(service *types.Service, event string)) {
+	lastPolls := map[string]time.Time{}
+	for {
+		services, err := back.ListServices()
+		if err != nil {
+			emailLogger.Printf("error listing services: %v\n", err)
+		}
+
+		for _, service := range services {
+			if service.EmailTrigger == nil {
+				continue
+			}
+			interval := time.Duration(service.EmailTrigger.GetInterval()) * time.Second
+			if time.Since(lastPolls[service.Name]) < interval {
+				continue
+			}
+			lastPolls[service.Name] = time.Now()
+
+			if err := pollMailbox(cfg, back.GetKubeClientset(), service, deliver); err != nil {
+				emailLogger.Printf("error polling the mailbox of service \"%s\": %v\n", service.Name, err)
+			}
+		}
+
+		time.Sleep(emailPollerTick)
+	}
+}
+
+func pollMailbox(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, deliver func(service *types.Service, event string)) error {
+	password, err := getServiceSecretValue(kubeClientset, cfg.ServicesNamespace, service.Name, service.EmailTrigger.PasswordSecret, types.EmailPasswordKey)
+	if err != nil {
+		return err
+	}
+
+	client, err := dialIMAP(service.EmailTrigger, string(password))
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	uids, err := client.searchUnseen(service.EmailTrigger)
+	if err != nil {
+		return err
+	}
+
+	for _, uid := range uids {
+		email, err := client.fetch(uid)
+		if err != nil {
+			emailLogger.Printf("error fetching email for service \"%s\": %v\n", service.Name, err)
+			continue
+		}
+		event, err := json.Marshal(types.EmailEvent{Email: *email})
+		if err != nil {
+			continue
+		}
+		emailLogger.Printf("New email \"%s\" triggers service \"%s\"\n", email.Subject, service.Name)
+		deliver(service, string(event))
+	}
+
+	return nil
+}
+
+// StartNotificationWatcher starts the loop to notify by email the finished jobs of the services with an EmailNotification every cfg.NotificationInterval
+func StartNotificationWatcher(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) {
+	for {
+		if err := notifyFinishedJobs(cfg, back, kubeClientset); err != nil {
+			emailLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.NotificationInterval) * time.Second)
+	}
+}
+
+func notifyFinishedJobs(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) error {
+	// List the services' jobs not notified yet
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,!%s", types.ServiceLabel, types.NotifiedLabelKey),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+
+	// Map to store services' pointers
+	svcPtrs := map[string]*types.Service{}
+
+	for _, job := range jobs.Items {
+		succeeded := isJobComplete(&job)
+		reason, message, failed := getJobFailure(&job)
+		if !succeeded && !failed {
+			continue
+		}
+
+		serviceName := job.Labels[types.ServiceLabel]
+		if _, ok := svcPtrs[serviceName]; !ok {
+			svcPtrs[serviceName], err = back.ReadService(serviceName)
+			if err != nil {
+				emailLogger.Printf("error getting service \"%s\": %v\n", serviceName, err)
+				svcPtrs[serviceName] = nil
+			}
+		}
+		service := svcPtrs[serviceName]
+		if service == nil {
+			continue
+		}
+
+		// The finished jobs of services without notifications are also marked, so they are not listed again
+		notification := service.EmailNotification
+		if notification != nil && ((succeeded && notification.OnSuccess) || (failed && notification.OnFailure)) {
+			var subject, body string
+			if succeeded {
+				subject = fmt.Sprintf("[OSCAR] Job \"%s\" of service \"%s\" completed", job.Name, serviceName)
+				body = fmt.Sprintf("The job \"%s\" of service \"%s\" has completed successfully.\n", job.Name, serviceName)
+				if links := getOutputLinks(service, &job); len(links) > 0 {
+					body += fmt.Sprintf("\nOutput files (links valid for %s):\n%s\n", outputLinksExpiration, strings.Join(links, "\n"))
+				}
+			} else {
+				subject = fmt.Sprintf("[OSCAR] Job \"%s\" of service \"%s\" failed", job.Name, serviceName)
+				body = fmt.Sprintf("The job \"%s\" of service \"%s\" has failed.\n\nReason: %s\nMessage: %s\n", job.Name, serviceName, reason, message)
+			}
+
+			var password []byte
+			if notification.PasswordSecret != "" {
+				password, err = getServiceSecretValue(kubeClientset, cfg.ServicesNamespace, serviceName, notification.PasswordSecret, types.EmailPasswordKey)
+				if err != nil {
+					emailLogger.Printf("error notifying job \"%s\": %v\n", job.Name, err)
+					continue
+				}
+			}
+
+			if err := SendEmail(notification, string(password), subject, body); err != nil {
+				emailLogger.Printf("error notifying job \"%s\": %v\n", job.Name, err)
+				continue
+			}
+		}
+
+		// Mark the job as notified
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{"%s":"true"}}}`, types.NotifiedLabelKey))
+		if _, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Patch(context.TODO(), job.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			emailLogger.Printf("error labelling the notified job \"%s\": %v\n", job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// isJobComplete checks if a job has completed successfully
+func isJobComplete(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobComplete && cond.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// getOutputLinks returns presigned URLs of the objects stored by a job in the service's MinIO outputs (see
+// ListJobOutputs)
+func getOutputLinks(service *types.Service, job *batchv1.Job) []string {
+	links := []string{}
+	for _, file := range ListJobOutputs(service, job, outputLinksExpiration).Files {
+		links = append(links, file.URL)
+	}
+	return links
+}
+
+// inputNameMatcher returns the function checking if the name of an output object starts with the name (without
+// extension) of the input file. No object matches the input files without name (e.g. ".jpg")
+func inputNameMatcher(inputKey string) func(name string) bool {
+	inputName := path.Base(inputKey)
+	inputName = strings.TrimSuffix(inputName, path.Ext(inputName))
+	return func(name string) bool {
+		return inputName != "" && strings.HasPrefix(name, inputName)
+	}
+}
+
+// forEachOutputObject calls fn with the objects stored in the service's MinIO outputs between since and until
+// whose name is accepted by match
+func forEachOutputObject(service *types.Service, match func(name string) bool, since time.Time, until time.Time, fn func(s3Client *s3.S3, bucket string, obj *s3.Object)) {
+	for _, out := range service.Output {
+		provName, provID := out.GetProvider()
+		if provName != types.MinIOName || service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+			continue
+		}
+		s3Client := service.StorageProviders.MinIO[provID].GetS3Client()
+		bucket, folder := out.SplitPath()
+		prefix := ""
+		if folder != "" {
+			prefix = folder + "/"
+		}
+
+		s3Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, obj := range page.Contents {
+				key := aws.StringValue(obj.Key)
+				modified := aws.TimeValue(obj.LastModified)
+				if strings.HasSuffix(key, "/") || modified.Before(since) || modified.After(until) || !match(path.Base(key)) {
+					continue
+				}
+				fn(s3Client, bucket, obj)
+			}
+			return true
+		})
+	}
+}
+
+// SendEmail sends a plain text email using the notification's SMTP server
+func SendEmail(notification *types.EmailNotification, password string, subject string, body string) error {
+	return sendMail(notification, password, subject, "text/plain; charset=UTF-8", []byte(body))
+}
+
+// sendMail sends an email with the provided content type using the notification's SMTP server
+func sendMail(notification *types.EmailNotification, password string, subject string, contentType string, body []byte) error {
+	var auth smtp.Auth
+	if notification.Username != "" {
+		host, _, err := net.SplitHostPort(notification.Host)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP host \"%s\": %v", notification.Host, err)
+		}
+		auth = smtp.PlainAuth("", notification.Username, password, host)
+	}
+
+	header := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s\r\n\r\n",
+		notification.From, strings.Join(notification.To, ", "), subject, time.Now().Format(time.RFC1123Z), contentType)
+
+	return smtp.SendMail(notification.Host, auth, notification.From, notification.To, append([]byte(header), body...))
+}
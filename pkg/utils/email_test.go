@@ -0,0 +1,196 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeServiceBackend ServerlessBackend returning the provided services
+type fakeServiceBackend struct {
+	types.ServerlessBackend
+	services map[string]*types.Service
+}
+
+func (back *fakeServiceBackend) ReadService(name string) (*types.Service, error) {
+	service, ok := back.services[name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "services"}, name)
+	}
+	return service, nil
+}
+
+func (back *fakeServiceBackend) ListServices() ([]*types.Service, error) {
+	services := []*types.Service{}
+	for _, service := range back.services {
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// fakeSMTPServer minimal SMTP server storing the received messages
+type fakeSMTPServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	messages []string
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error starting the fake SMTP server: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+
+	return server
+}
+
+func (server *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			fmt.Fprint(conn, "250 localhost\r\n")
+		case strings.HasPrefix(command, "DATA"):
+			fmt.Fprint(conn, "354 go ahead\r\n")
+			data := ""
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data += dataLine
+			}
+			server.mutex.Lock()
+			server.messages = append(server.messages, data)
+			server.mutex.Unlock()
+			fmt.Fprint(conn, "250 OK\r\n")
+		case strings.HasPrefix(command, "QUIT"):
+			fmt.Fprint(conn, "221 bye\r\n")
+			return
+		default:
+			fmt.Fprint(conn, "250 OK\r\n")
+		}
+	}
+}
+
+func (server *fakeSMTPServer) getMessages() []string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]string{}, server.messages...)
+}
+
+func TestNotifyFinishedJobs(t *testing.T) {
+	smtpServer := startFakeSMTPServer(t)
+	cfg := &types.Config{ServicesNamespace: "oscar-svc"}
+
+	notification := &types.EmailNotification{
+		Host:      smtpServer.listener.Addr().String(),
+		From:      "oscar@example.com",
+		To:        []string{"owner@example.com"},
+		OnSuccess: true,
+	}
+	back := &fakeServiceBackend{services: map[string]*types.Service{
+		"notified":     {Name: "notified", EmailNotification: notification},
+		"not-notified": {Name: "not-notified"},
+		"bad-secret":   {Name: "bad-secret", EmailNotification: &types.EmailNotification{Host: notification.Host, Username: "user", PasswordSecret: "missing", OnSuccess: true}},
+	}}
+
+	makeJob := func(name string, service string, condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cfg.ServicesNamespace,
+				Labels:    map[string]string{types.ServiceLabel: service},
+			},
+		}
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: v1.ConditionTrue}}
+		}
+		return job
+	}
+	kubeClientset := testclient.NewSimpleClientset(
+		makeJob("completed", "notified", batchv1.JobComplete),
+		makeJob("failed", "notified", batchv1.JobFailed),
+		makeJob("running", "notified", ""),
+		makeJob("other-completed", "not-notified", batchv1.JobComplete),
+		makeJob("bad-secret-completed", "bad-secret", batchv1.JobComplete),
+	)
+
+	if err := notifyFinishedJobs(cfg, back, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := smtpServer.getMessages()
+	if len(messages) != 1 {
+		t.Fatalf("expecting 1 email, got %d", len(messages))
+	}
+	if !strings.Contains(messages[0], "Subject: [OSCAR] Job \"completed\" of service \"notified\" completed") {
+		t.Errorf("unexpected email: %s", messages[0])
+	}
+
+	expectedLabelled := map[string]bool{
+		"completed":            true,
+		"failed":               true,
+		"running":              false,
+		"other-completed":      true,
+		"bad-secret-completed": false,
+	}
+	for name, expected := range expectedLabelled {
+		job, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, labelled := job.Labels[types.NotifiedLabelKey]; labelled != expected {
+			t.Errorf("job \"%s\": expecting labelled %v, got %v", name, expected, labelled)
+		}
+	}
+}
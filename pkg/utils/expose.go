@@ -43,6 +43,19 @@ type Expose struct {
 	Port         int   ` binding:"required" default:"80"`
 	CpuThreshold int32 `default:"80"`
 	EnableSGX    bool
+	// ExtendedResources limits of the GPUs and extended resources of the service
+	ExtendedResources v1.ResourceList
+	// NodeSelector, Affinity and Tolerations scheduling constraints of the service
+	NodeSelector map[string]string
+	Affinity     *v1.Affinity
+	Tolerations  []v1.Toleration
+	// RuntimeClass RuntimeClass of the service's pods (if defined)
+	RuntimeClass string
+	// SecurityContext security context of the service's pods (if defined or enforced)
+	SecurityContext *types.SecurityContext
+	// InitContainers and Sidecars additional containers of the service's pods (if defined)
+	InitContainers []types.ContainerSpec
+	Sidecars       []types.ContainerSpec
 }
 
 // Custom logger
@@ -244,12 +257,24 @@ func getPodTemplateSpec(e Expose) v1.PodTemplateSpec {
 		},
 	}
 
+	for name, quantity := range e.ExtendedResources {
+		template.Spec.Containers[0].Resources.Limits[name] = quantity
+	}
+	template.Spec.NodeSelector = e.NodeSelector
+	template.Spec.Affinity = e.Affinity
+	template.Spec.Tolerations = e.Tolerations
+	if e.RuntimeClass != "" {
+		template.Spec.RuntimeClassName = &e.RuntimeClass
+	}
+	types.AddContainers(&template.Spec, e.InitContainers, e.Sidecars)
+
 	if e.EnableSGX {
 		ExposeLogger.Printf("DEBUG: Enabling components to use SGX plugin\n")
 		types.SetSecurityContext(&template.Spec)
 		sgx, _ := resource.ParseQuantity("1")
 		template.Spec.Containers[0].Resources.Limits["sgx.intel.com/enclave"] = sgx
 	}
+	e.SecurityContext.Apply(&template.Spec)
 
 	return template
 }
@@ -490,6 +515,11 @@ func getPathAPI(name_container string) string {
 	return "/system/services/" + name_container + "/exposed/?(.*)"
 }
 
+// GetExposeDeploymentName returns the name of the deployment of an exposed service
+func GetExposeDeploymentName(name string) string {
+	return getNameDeployment(name)
+}
+
 func getNameDeployment(name_container string) string {
 	return name_container + "-dlp"
 }
@@ -0,0 +1,134 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+const (
+	// storageFailoverCheckInterval minimum time between the checks of the OSCAR's MinIO
+	storageFailoverCheckInterval = 30 * time.Second
+	// storageCheckTimeout timeout of the checks of the MinIO providers
+	storageCheckTimeout = 5 * time.Second
+)
+
+var failoverLogger = log.New(os.Stdout, "[STORAGE-FAILOVER] ", log.Flags())
+
+// storageFailover status of the failover of the OSCAR's MinIO (nil until checked)
+var storageFailover struct {
+	sync.Mutex
+	status *types.StorageFailoverStatus
+}
+
+// checkMinIOProvider checks if a MinIO provider is reachable (overridable in tests)
+var checkMinIOProvider = func(provider *types.MinIOProvider) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storageCheckTimeout)
+	defer cancel()
+	_, err := provider.GetS3Client().ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	return err
+}
+
+// GetDefaultMinIOProvider returns the provider used as the default MinIO provider of the new services (their
+// buckets and notifications) and jobs: the OSCAR's MinIO or, while it is unreachable, the secondary provider
+// (cfg.SecondaryMinIOProvider). The OSCAR's MinIO is checked at most every storageFailoverCheckInterval
+func GetDefaultMinIOProvider(cfg *types.Config) *types.MinIOProvider {
+	if !cfg.HasSecondaryMinIO() {
+		return cfg.MinIOProvider
+	}
+
+	storageFailover.Lock()
+	defer storageFailover.Unlock()
+
+	status := storageFailover.status
+	if status == nil || time.Since(status.CheckedAt) >= storageFailoverCheckInterval {
+		status = checkStorageFailover(cfg, status)
+		storageFailover.status = status
+	}
+	if status.Active {
+		return cfg.SecondaryMinIOProvider
+	}
+	return cfg.MinIOProvider
+}
+
+// checkStorageFailover returns the new status of the failover after checking the MinIO providers. The failover is
+// only activated if the secondary provider is reachable
+func checkStorageFailover(cfg *types.Config, current *types.StorageFailoverStatus) *types.StorageFailoverStatus {
+	now := time.Now()
+	status := &types.StorageFailoverStatus{CheckedAt: now}
+	if current != nil {
+		*status = *current
+		status.CheckedAt = now
+	}
+
+	err := checkMinIOProvider(cfg.MinIOProvider)
+	if err == nil {
+		if status.Active {
+			failoverLogger.Printf("The OSCAR's MinIO is reachable again, switching back from the secondary provider (active since %s)\n", status.Since.Format(time.RFC3339))
+		}
+		return &types.StorageFailoverStatus{CheckedAt: now}
+	}
+
+	if status.Active {
+		status.Reason = err.Error()
+		return status
+	}
+	if secondaryErr := checkMinIOProvider(cfg.SecondaryMinIOProvider); secondaryErr != nil {
+		failoverLogger.Printf("The OSCAR's MinIO is unreachable (%v), but the secondary provider too: %v\n", err, secondaryErr)
+		return status
+	}
+
+	failoverLogger.Printf("The OSCAR's MinIO is unreachable, switching the new services and jobs to the secondary provider \"%s\": %v\n", cfg.SecondaryMinIOProvider.Endpoint, err)
+	status.Active = true
+	status.Since = &now
+	status.Reason = err.Error()
+	return status
+}
+
+// GetFailoverProvider returns the secondary provider to replace the default MinIO provider of a job while the
+// failover is active, or nil if the service does not use the OSCAR's MinIO
+func GetFailoverProvider(cfg *types.Config, service *types.Service) *types.MinIOProvider {
+	if service.StorageProviders == nil || service.StorageProviders.MinIO[types.DefaultProvider] == nil ||
+		service.StorageProviders.MinIO[types.DefaultProvider].Endpoint != cfg.MinIOProvider.Endpoint {
+		return nil
+	}
+	if provider := GetDefaultMinIOProvider(cfg); provider != cfg.MinIOProvider {
+		return provider
+	}
+	return nil
+}
+
+// GetStorageFailoverStatus returns the status of the failover of the OSCAR's MinIO, or nil if no secondary provider
+// is configured
+func GetStorageFailoverStatus(cfg *types.Config) *types.StorageFailoverStatus {
+	if !cfg.HasSecondaryMinIO() {
+		return nil
+	}
+	GetDefaultMinIOProvider(cfg)
+
+	storageFailover.Lock()
+	defer storageFailover.Unlock()
+	status := *storageFailover.status
+	return &status
+}
@@ -0,0 +1,94 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func TestGetDefaultMinIOProvider(t *testing.T) {
+	primary := &types.MinIOProvider{Endpoint: "https://minio.primary:9000"}
+	secondary := &types.MinIOProvider{Endpoint: "https://minio.secondary:9000"}
+	cfg := &types.Config{MinIOProvider: primary, SecondaryMinIOProvider: &types.MinIOProvider{}}
+
+	reachable := map[string]bool{primary.Endpoint: true, secondary.Endpoint: true}
+	defaultCheck := checkMinIOProvider
+	defer func() {
+		checkMinIOProvider = defaultCheck
+		storageFailover.status = nil
+	}()
+	checkMinIOProvider = func(provider *types.MinIOProvider) error {
+		if !reachable[provider.Endpoint] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	// expireCheck forces the next check of the OSCAR's MinIO
+	expireCheck := func() {
+		storageFailover.status.CheckedAt = time.Now().Add(-storageFailoverCheckInterval)
+	}
+
+	// No failover without secondary provider
+	reachable[primary.Endpoint] = false
+	if GetDefaultMinIOProvider(cfg) != primary || GetStorageFailoverStatus(cfg) != nil {
+		t.Error("expected the OSCAR's MinIO without secondary provider")
+	}
+
+	cfg.SecondaryMinIOProvider = secondary
+	if GetDefaultMinIOProvider(cfg) != secondary {
+		t.Error("expected the secondary provider while the OSCAR's MinIO is unreachable")
+	}
+	status := GetStorageFailoverStatus(cfg)
+	if status == nil || !status.Active || status.Since == nil || status.Reason != "connection refused" {
+		t.Errorf("unexpected failover status: %+v", status)
+	}
+
+	// The jobs of the services using the OSCAR's MinIO are switched to the secondary provider
+	service := &types.Service{StorageProviders: &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: primary}}}
+	if GetFailoverProvider(cfg, service) != secondary {
+		t.Error("expected the failover provider of the service")
+	}
+	other := &types.Service{StorageProviders: &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: secondary}}}
+	if GetFailoverProvider(cfg, other) != nil {
+		t.Error("expected no failover provider for the service using the secondary provider")
+	}
+
+	// The status is cached until the next check
+	reachable[primary.Endpoint] = true
+	if GetDefaultMinIOProvider(cfg) != secondary {
+		t.Error("expected the cached failover status")
+	}
+	expireCheck()
+	if GetDefaultMinIOProvider(cfg) != primary || GetStorageFailoverStatus(cfg).Active {
+		t.Error("expected the OSCAR's MinIO once it is reachable again")
+	}
+	if GetFailoverProvider(cfg, service) != nil {
+		t.Error("expected no failover provider once the OSCAR's MinIO is reachable")
+	}
+
+	// No failover if the secondary provider is unreachable too
+	reachable[primary.Endpoint] = false
+	reachable[secondary.Endpoint] = false
+	expireCheck()
+	if GetDefaultMinIOProvider(cfg) != primary {
+		t.Error("expected the OSCAR's MinIO if the secondary provider is unreachable")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const gitCloneTimeout = 2 * time.Minute
+
+// FetchGitScript clones (shallowly) the repository defined in the source and returns the content of its script file.
+// The deploy key secret must be labelled with the name of the service (types.ServiceLabel) to be used
+func FetchGitScript(serviceName string, source *types.GitScriptSource, kubeClientset kubernetes.Interface, namespace string) (string, error) {
+	if source == nil || source.URL == "" {
+		return "", fmt.Errorf("the Git repository URL is not defined")
+	}
+	tmpDir, err := os.MkdirTemp("", "oscar-git-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	env := gitEnv()
+
+	// Write the deploy key (if defined) to be used by ssh
+	if source.DeployKeySecret != "" {
+		key, err := getServiceSecretValue(kubeClientset, namespace, serviceName, source.DeployKeySecret, v1.SSHAuthPrivateKey)
+		if err != nil {
+			return "", fmt.Errorf("error getting the deploy key: %v", err)
+		}
+		keyPath := filepath.Join(tmpDir, "deploy_key")
+		if err := os.WriteFile(keyPath, key, 0600); err != nil {
+			return "", err
+		}
+		env = append(env, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null", keyPath))
+	}
+
+	repoDir := filepath.Join(tmpDir, "repo")
+	if err := cloneGitRepository(source.URL, source.Ref, env, repoDir); err != nil {
+		return "", err
+	}
+
+	script, err := readRepoFile(repoDir, source.GetPath())
+	if err != nil {
+		return "", fmt.Errorf("error reading the script \"%s\" from the Git repository \"%s\": %v", source.GetPath(), source.URL, err)
+	}
+
+	return string(script), nil
+}
+
+// cloneGitRepository clones (shallowly) the ref (default branch if empty) of a Git repository into repoDir
+func cloneGitRepository(url string, ref string, env []string, repoDir string) error {
+	// Avoid the URL and the ref being parsed as git options
+	if strings.HasPrefix(url, "-") {
+		return fmt.Errorf("invalid Git repository URL \"%s\"", url)
+	}
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid Git ref \"%s\"", ref)
+	}
+
+	args := []string{"clone", "--depth", "1", "--single-branch"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", url, repoDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitCloneTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error cloning the Git repository \"%s\": %v: %s", url, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// gitEnv returns the environment of the git commands, which only allows remote transports (e.g. blocks "file://"
+// and "ext::") and never prompts for credentials
+func gitEnv() []string {
+	return append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL=https:ssh")
+}
+
+// readRepoFile reads a regular file of the repository, avoiding reading files outside it (e.g. through symlinks)
+func readRepoFile(repoDir string, path string) ([]byte, error) {
+	filePath := filepath.Join(repoDir, filepath.Clean("/"+path))
+
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("the path is not a regular file")
+	}
+
+	// Parent directories can also be symlinks
+	realRepoDir, err := filepath.EvalSymlinks(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	realFilePath, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(realRepoDir, realFilePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("the path is outside the repository")
+	}
+
+	return os.ReadFile(realFilePath)
+}
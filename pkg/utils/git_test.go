@@ -0,0 +1,99 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadRepoFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoDir := filepath.Join(tmpDir, "repo")
+	os.MkdirAll(filepath.Join(repoDir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(repoDir, "script.sh"), []byte("echo test"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "secret"), []byte("secret"), 0644)
+	os.Symlink(filepath.Join(tmpDir, "secret"), filepath.Join(repoDir, "link.sh"))
+	os.Symlink(tmpDir, filepath.Join(repoDir, "outside"))
+
+	scenarios := []struct {
+		name        string
+		path        string
+		returnError bool
+	}{
+		{"regular file", "script.sh", false},
+		{"dot-dot path", "../repo/../../script.sh", false},
+		{"symlink to outside file", "link.sh", true},
+		{"file in symlinked directory", "outside/secret", true},
+		{"directory", "scripts", true},
+		{"not found", "missing.sh", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			content, err := readRepoFile(repoDir, s.path)
+			if s.returnError {
+				if err == nil {
+					t.Errorf("expected error, got content: %s", string(content))
+				}
+			} else {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				} else if string(content) != "echo test" {
+					t.Errorf("unexpected content: %s", string(content))
+				}
+			}
+		})
+	}
+}
+
+func TestFetchGitScriptValidation(t *testing.T) {
+	kubeClientset := testclient.NewSimpleClientset()
+	kubeClientset.CoreV1().Secrets("oscar-svc").Create(context.TODO(), &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "other-key",
+			Labels: map[string]string{types.ServiceLabel: "other"},
+		},
+		Data: map[string][]byte{v1.SSHAuthPrivateKey: []byte("key")},
+	}, metav1.CreateOptions{})
+
+	scenarios := []struct {
+		name   string
+		source *types.GitScriptSource
+	}{
+		{"no URL", &types.GitScriptSource{}},
+		{"URL as option", &types.GitScriptSource{URL: "--upload-pack=touch /tmp/pwned"}},
+		{"ref as option", &types.GitScriptSource{URL: "https://example.com/repo.git", Ref: "--upload-pack=touch /tmp/pwned"}},
+		{"deploy key not found", &types.GitScriptSource{URL: "git@example.com:repo.git", DeployKeySecret: "missing"}},
+		{"deploy key of other service", &types.GitScriptSource{URL: "git@example.com:repo.git", DeployKeySecret: "other-key"}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if _, err := FetchGitScript("test", s.source, kubeClientset, "oscar-svc"); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// idempotencyTTL time that the outcome of the requests sent with an Idempotency-Key is kept
+const idempotencyTTL = 24 * time.Hour
+
+// idempotentRequests requests sent with an Idempotency-Key by scoped key. They are kept in memory, so they are
+// lost if the OSCAR manager restarts
+var idempotentRequests = map[string]*types.IdempotentRequest{}
+var idempotentRequestsMutex sync.Mutex
+
+// BeginIdempotentRequest returns the stored outcome of the request with the key, or reserves the key for the
+// request (returning nil) if it has not been used. A CodedError is returned if the key is being used by a request
+// in progress or has been used by a request with a different fingerprint
+func BeginIdempotentRequest(key string, fingerprint string) (*types.IdempotentRequest, error) {
+	idempotentRequestsMutex.Lock()
+	defer idempotentRequestsMutex.Unlock()
+
+	now := time.Now()
+	// Remove the expired requests
+	for k, req := range idempotentRequests {
+		if now.Sub(req.CreatedAt) > idempotencyTTL {
+			delete(idempotentRequests, k)
+		}
+	}
+
+	req, ok := idempotentRequests[key]
+	if !ok {
+		idempotentRequests[key] = &types.IdempotentRequest{Fingerprint: fingerprint, CreatedAt: now}
+		return nil, nil
+	}
+	if req.Fingerprint != fingerprint {
+		return nil, types.NewCodedError(types.ErrIdempotencyKeyMismatch, fmt.Errorf("the Idempotency-Key has already been used by a different request"))
+	}
+	if !req.Completed {
+		return nil, types.NewCodedError(types.ErrIdempotencyKeyInUse, fmt.Errorf("a request with the same Idempotency-Key is being processed"))
+	}
+	copied := *req
+	return &copied, nil
+}
+
+// FinishIdempotentRequest stores the outcome of the request with the key, or releases the key (so the request can
+// be retried) if outcome is nil
+func FinishIdempotentRequest(key string, outcome *types.IdempotentRequest) {
+	idempotentRequestsMutex.Lock()
+	defer idempotentRequestsMutex.Unlock()
+
+	req, ok := idempotentRequests[key]
+	if !ok {
+		return
+	}
+	if outcome == nil {
+		delete(idempotentRequests, key)
+		return
+	}
+	req.Completed = true
+	req.Status = outcome.Status
+	req.Header = outcome.Header
+	req.Body = outcome.Body
+}
@@ -0,0 +1,193 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+const (
+	imapTimeout = 30 * time.Second
+	// Maximum size of the email bodies passed in the events
+	maxEmailBodySize = 64 * 1024
+)
+
+var imapLiteralRegexp = regexp.MustCompile(`\{(\d+)\}$`)
+
+// imapClient minimal IMAP4rev1 client supporting the commands needed to poll a mailbox
+type imapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tag    int
+}
+
+// dialIMAP connects (TLS) to the IMAP server and logs in
+func dialIMAP(trigger *types.EmailTrigger, password string) (*imapClient, error) {
+	host, _, err := net.SplitHostPort(trigger.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMAP host \"%s\": %v", trigger.Host, err)
+	}
+
+	dialer := &net.Dialer{Timeout: imapTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", trigger.Host, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+
+	client := &imapClient{conn: conn, reader: bufio.NewReader(conn)}
+	// Read the server greeting
+	if _, err := client.readLine(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if _, err := client.cmd(fmt.Sprintf("LOGIN %s %s", imapQuote(trigger.Username), imapQuote(password))); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (client *imapClient) readLine() (string, error) {
+	client.conn.SetReadDeadline(time.Now().Add(imapTimeout))
+	line, err := client.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// cmd sends a command and returns the untagged responses (including literals)
+func (client *imapClient) cmd(command string) ([]string, error) {
+	client.tag++
+	tag := fmt.Sprintf("A%03d", client.tag)
+	client.conn.SetWriteDeadline(time.Now().Add(imapTimeout))
+	if _, err := fmt.Fprintf(client.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, err
+	}
+
+	responses := []string{}
+	for {
+		line, err := client.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(line, tag+" ") {
+			status := strings.TrimPrefix(line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("IMAP command failed: %s", status)
+			}
+			return responses, nil
+		}
+
+		// Read literals
+		if match := imapLiteralRegexp.FindStringSubmatch(line); match != nil {
+			size, _ := strconv.Atoi(match[1])
+			literal := make([]byte, size)
+			if _, err := io.ReadFull(client.reader, literal); err != nil {
+				return nil, err
+			}
+			line = fmt.Sprintf("%s\n%s", line, literal)
+		}
+		responses = append(responses, line)
+	}
+}
+
+// searchUnseen returns the UIDs of the unseen messages of the mailbox matching the trigger's filters
+func (client *imapClient) searchUnseen(trigger *types.EmailTrigger) ([]string, error) {
+	if _, err := client.cmd("SELECT " + imapQuote(trigger.GetMailbox())); err != nil {
+		return nil, err
+	}
+
+	criteria := "UNSEEN"
+	if trigger.From != "" {
+		criteria += " FROM " + imapQuote(trigger.From)
+	}
+	if trigger.Subject != "" {
+		criteria += " SUBJECT " + imapQuote(trigger.Subject)
+	}
+	responses, err := client.cmd("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	uids := []string{}
+	for _, res := range responses {
+		if strings.HasPrefix(res, "* SEARCH") {
+			uids = append(uids, strings.Fields(strings.TrimPrefix(res, "* SEARCH"))...)
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the message with the provided UID and marks it as seen
+func (client *imapClient) fetch(uid string) (*types.EmailMessage, error) {
+	responses, err := client.cmd(fmt.Sprintf("UID FETCH %s BODY[]", uid))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range responses {
+		split := strings.SplitN(res, "\n", 2)
+		if len(split) != 2 || !strings.Contains(split[0], "FETCH") {
+			continue
+		}
+		msg, err := mail.ReadMessage(strings.NewReader(split[1]))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing message %s: %v", uid, err)
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(msg.Body, maxEmailBodySize))
+		email := &types.EmailMessage{
+			MessageID: msg.Header.Get("Message-Id"),
+			From:      msg.Header.Get("From"),
+			Subject:   msg.Header.Get("Subject"),
+			Date:      msg.Header.Get("Date"),
+			Body:      string(body),
+		}
+		if to, err := msg.Header.AddressList("To"); err == nil {
+			for _, addr := range to {
+				email.To = append(email.To, addr.Address)
+			}
+		}
+		return email, nil
+	}
+
+	return nil, fmt.Errorf("message %s not found", uid)
+}
+
+func (client *imapClient) close() {
+	client.cmd("LOGOUT")
+	client.conn.Close()
+}
+
+func imapQuote(s string) string {
+	return strconv.Quote(s)
+}
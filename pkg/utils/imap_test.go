@@ -0,0 +1,151 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// startFakeIMAPServer returns a client connected to a fake server that answers each
+// received command with the provided responses (indexed by command, without tag)
+func startFakeIMAPServer(t *testing.T, responses map[string]string) *imapClient {
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+
+	go func() {
+		reader := bufio.NewReader(serverConn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			split := strings.SplitN(strings.TrimRight(line, "\r\n"), " ", 2)
+			tag, command := split[0], split[1]
+			res, ok := responses[command]
+			if !ok {
+				serverConn.Write([]byte(tag + " BAD unknown command\r\n"))
+				continue
+			}
+			serverConn.Write([]byte(res + tag + " OK done\r\n"))
+		}
+	}()
+
+	return &imapClient{conn: clientConn, reader: bufio.NewReader(clientConn)}
+}
+
+func TestIMAPCmd(t *testing.T) {
+	client := startFakeIMAPServer(t, map[string]string{
+		"NOOP":   "",
+		"SEARCH": "* SEARCH 1 2\r\n",
+		"FETCH":  "* 1 FETCH (BODY[] {12}\r\nhello\r\nworld)\r\n",
+	})
+
+	scenarios := []struct {
+		name        string
+		command     string
+		expected    []string
+		returnError bool
+	}{
+		{"no responses", "NOOP", []string{}, false},
+		{"untagged response", "SEARCH", []string{"* SEARCH 1 2"}, false},
+		{"literal", "FETCH", []string{"* 1 FETCH (BODY[] {12}\nhello\r\nworld", ")"}, false},
+		{"failed command", "UNKNOWN", nil, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			res, err := client.cmd(s.command)
+			if s.returnError {
+				if err == nil {
+					t.Errorf("expected error, got: %v", res)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(res, s.expected) {
+				t.Errorf("expected %q, got %q", s.expected, res)
+			}
+		})
+	}
+}
+
+func TestIMAPSearchAndFetch(t *testing.T) {
+	message := "Message-Id: <1@example.com>\r\n" +
+		"From: Alice <alice@example.com>\r\n" +
+		"To: Bob <bob@example.com>, carol@example.com\r\n" +
+		"Subject: New data\r\n" +
+		"Date: Mon, 02 Jan 2023 15:04:05 +0000\r\n" +
+		"\r\n" +
+		"Process it\r\n"
+	literal := "* 42 FETCH (UID 42 BODY[] {" + strconv.Itoa(len(message)) + "}\r\n" + message + ")\r\n"
+
+	client := startFakeIMAPServer(t, map[string]string{
+		`SELECT "INBOX"`:                         "* 3 EXISTS\r\n",
+		`UID SEARCH UNSEEN SUBJECT "New"`:        "* SEARCH 41 42\r\n",
+		"UID FETCH 42 BODY[]":                    literal,
+		"UID FETCH 43 BODY[]":                    "",
+		`UID SEARCH UNSEEN FROM "alice@example"`: "* SEARCH\r\n",
+	})
+
+	uids, err := client.searchUnseen(&types.EmailTrigger{Subject: "New"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(uids, []string{"41", "42"}) {
+		t.Errorf("unexpected UIDs: %v", uids)
+	}
+
+	uids, err = client.searchUnseen(&types.EmailTrigger{From: "alice@example"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(uids) != 0 {
+		t.Errorf("unexpected UIDs: %v", uids)
+	}
+
+	email, err := client.fetch("42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &types.EmailMessage{
+		MessageID: "<1@example.com>",
+		From:      "Alice <alice@example.com>",
+		To:        []string{"bob@example.com", "carol@example.com"},
+		Subject:   "New data",
+		Date:      "Mon, 02 Jan 2023 15:04:05 +0000",
+		Body:      "Process it\r\n",
+	}
+	if !reflect.DeepEqual(email, expected) {
+		t.Errorf("expected %+v, got %+v", expected, email)
+	}
+
+	if _, err := client.fetch("43"); err == nil {
+		t.Error("expected error fetching a missing message")
+	}
+}
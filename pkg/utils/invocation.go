@@ -0,0 +1,107 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// invocationTTL time that the finished asynchronous invocations are kept
+const invocationTTL = 24 * time.Hour
+
+// invocations asynchronous invocations by ID. They are kept in memory, so they are lost if the OSCAR manager restarts
+var invocations = map[string]*types.AsyncInvocation{}
+var invocationsMutex sync.Mutex
+
+// NewAsyncInvocation registers a new pending asynchronous invocation of a service
+func NewAsyncInvocation(serviceName string) *types.AsyncInvocation {
+	invocationsMutex.Lock()
+	defer invocationsMutex.Unlock()
+
+	now := time.Now().UTC()
+	// Remove the expired invocations
+	for id, inv := range invocations {
+		if inv.FinishedAt != nil && now.Sub(*inv.FinishedAt) > invocationTTL {
+			delete(invocations, id)
+		}
+	}
+
+	inv := &types.AsyncInvocation{
+		ID:        GenerateToken()[:32],
+		Service:   serviceName,
+		Status:    types.InvocationPending,
+		CreatedAt: now,
+	}
+	invocations[inv.ID] = inv
+
+	copied := *inv
+	return &copied
+}
+
+// FinishAsyncInvocation sets the result of an asynchronous invocation. A non-nil err marks it as failed
+func FinishAsyncInvocation(id string, statusCode int, output string, err error) {
+	invocationsMutex.Lock()
+	defer invocationsMutex.Unlock()
+
+	inv, ok := invocations[id]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	inv.FinishedAt = &now
+	inv.StatusCode = statusCode
+	inv.Output = output
+	inv.Status = types.InvocationSucceeded
+	if err != nil {
+		inv.Status = types.InvocationFailed
+		inv.Error = err.Error()
+	}
+}
+
+// GetAsyncInvocation returns an asynchronous invocation
+func GetAsyncInvocation(id string) (*types.AsyncInvocation, bool) {
+	invocationsMutex.Lock()
+	defer invocationsMutex.Unlock()
+
+	inv, ok := invocations[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *inv
+	return &copied, true
+}
+
+// ListAsyncInvocations returns the asynchronous invocations of a service, newest first
+func ListAsyncInvocations(serviceName string) []types.AsyncInvocation {
+	invocationsMutex.Lock()
+	defer invocationsMutex.Unlock()
+
+	list := []types.AsyncInvocation{}
+	for _, inv := range invocations {
+		if inv.Service == serviceName {
+			list = append(list, *inv)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
@@ -0,0 +1,68 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// ListJobOutputs returns the objects stored by a job in the service's MinIO outputs with presigned URLs valid for
+// expiration, i.e. those stored while the job was running (or until now for the routed outputs) whose name starts
+// with the name (without extension) of the input file
+func ListJobOutputs(service *types.Service, job *batchv1.Job, expiration time.Duration) *types.JobOutputs {
+	outputs := &types.JobOutputs{
+		Job:      job.Name,
+		Service:  service.Name,
+		Finished: isJobFinished(job),
+		Expires:  time.Now().Add(expiration).UTC().Truncate(time.Second),
+		Files:    []types.JobOutputFile{},
+	}
+
+	minIOEvent, err := types.ParseMinIOEvent([]byte(GetJobEvent(job)))
+	if err != nil {
+		// The outputs can not be correlated without input file
+		return outputs
+	}
+	outputs.Correlated = true
+
+	// The staged outputs are copied to the outputs once the job finishes
+	until := time.Now()
+	if job.Status.CompletionTime != nil && !service.HasStagedOutputs() {
+		until = job.Status.CompletionTime.Time
+	}
+
+	forEachOutputObject(service, inputNameMatcher(minIOEvent.GetObjectKey()), job.CreationTimestamp.Time, until, func(s3Client *s3.S3, bucket string, obj *s3.Object) {
+		req, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: obj.Key})
+		url, err := req.Presign(expiration)
+		if err != nil {
+			return
+		}
+		outputs.Files = append(outputs.Files, types.JobOutputFile{
+			Bucket:       bucket,
+			Key:          aws.StringValue(obj.Key),
+			Size:         aws.Int64Value(obj.Size),
+			LastModified: aws.TimeValue(obj.LastModified),
+			URL:          url,
+		})
+	})
+	return outputs
+}
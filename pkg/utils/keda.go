@@ -0,0 +1,194 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ScaledJobHashAnnotation annotation of the ScaledJobs with the hash of their spec, to update them only if changed
+const ScaledJobHashAnnotation = "oscar_scaledjob_hash"
+
+// ScaledJobResource resource of the KEDA ScaledJobs
+var ScaledJobResource = schema.GroupVersionResource{Group: "keda.sh", Version: "v1alpha1", Resource: "scaledjobs"}
+
+// Custom logger
+var kedaLogger = log.New(os.Stdout, "[KEDA] ", log.Flags())
+
+// StartScaledJobsReconciler starts the loop to create, update and delete the KEDA ScaledJobs of the services with
+// workers every cfg.KEDAReconcileInterval
+func StartScaledJobsReconciler(cfg *types.Config, back types.ServerlessBackend, dynClient dynamic.Interface) {
+	for {
+		if err := reconcileScaledJobs(cfg, back, dynClient); err != nil {
+			kedaLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.KEDAReconcileInterval) * time.Second)
+	}
+}
+
+// reconcileScaledJobs applies the ScaledJobs of the services with workers and deletes the rest of ScaledJobs of the
+// services' namespaces
+func reconcileScaledJobs(cfg *types.Config, back types.ServerlessBackend, dynClient dynamic.Interface) error {
+	services, err := back.ListServices()
+	if err != nil {
+		return fmt.Errorf("error listing the services: %v", err)
+	}
+
+	namespaces := map[string]bool{cfg.ServicesNamespace: true}
+	desired := map[string]bool{}
+	for _, service := range services {
+		namespace := cfg.GetServiceNamespace(service)
+		namespaces[namespace] = true
+		if !service.HasWorkers() {
+			continue
+		}
+		desired[namespace+"/"+service.Name] = true
+		if err := applyScaledJob(cfg, dynClient, service); err != nil {
+			kedaLogger.Printf("Error applying the ScaledJob of service \"%s\": %v\n", service.Name, err)
+		}
+	}
+
+	// Delete the ScaledJobs of the deleted services or without workers
+	for namespace := range namespaces {
+		list, err := dynClient.Resource(ScaledJobResource).Namespace(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: types.ServiceLabel})
+		if err != nil {
+			return fmt.Errorf("error listing the ScaledJobs of namespace \"%s\": %v", namespace, err)
+		}
+		for _, sj := range list.Items {
+			if desired[namespace+"/"+sj.GetName()] {
+				continue
+			}
+			if err := dynClient.Resource(ScaledJobResource).Namespace(namespace).Delete(context.TODO(), sj.GetName(), metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+				kedaLogger.Printf("Error deleting the ScaledJob \"%s\": %v\n", sj.GetName(), err)
+				continue
+			}
+			kedaLogger.Printf("ScaledJob \"%s\" deleted\n", sj.GetName())
+		}
+	}
+
+	return nil
+}
+
+// applyScaledJob creates the ScaledJob of a service or updates it if its spec has changed
+func applyScaledJob(cfg *types.Config, dynClient dynamic.Interface, service *types.Service) error {
+	sj, err := makeScaledJob(cfg, service)
+	if err != nil {
+		return err
+	}
+
+	client := dynClient.Resource(ScaledJobResource).Namespace(sj.GetNamespace())
+	current, err := client.Get(context.TODO(), sj.GetName(), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = client.Create(context.TODO(), sj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if current.GetAnnotations()[ScaledJobHashAnnotation] == sj.GetAnnotations()[ScaledJobHashAnnotation] {
+		return nil
+	}
+	sj.SetResourceVersion(current.GetResourceVersion())
+	_, err = client.Update(context.TODO(), sj, metav1.UpdateOptions{})
+	return err
+}
+
+// makeScaledJob returns the ScaledJob of a service with workers, scaling its worker jobs by the depth of its queue
+// (polled from OSCAR by the metrics-api scaler)
+func makeScaledJob(cfg *types.Config, service *types.Service) (*unstructured.Unstructured, error) {
+	podSpec, err := service.ToPodSpec(cfg)
+	if err != nil {
+		return nil, err
+	}
+	oscarURL := fmt.Sprintf("http://%s.%s:%d", cfg.Name, cfg.Namespace, cfg.ServicePort)
+	podSpec.RestartPolicy = v1.RestartPolicyNever
+	for i, c := range podSpec.Containers {
+		if c.Name == types.ContainerName {
+			podSpec.Containers[i].Command = []string{"/bin/sh"}
+			podSpec.Containers[i].Args = []string{"-c", service.GetWorkerScript()}
+			podSpec.Containers[i].Env = append(podSpec.Containers[i].Env,
+				v1.EnvVar{Name: types.QueueURLVariable, Value: fmt.Sprintf("%s/queue/%s/next", oscarURL, service.Name)},
+				v1.EnvVar{Name: types.QueueAckURLVariable, Value: fmt.Sprintf("%s/queue/%s/ack", oscarURL, service.Name)},
+				v1.EnvVar{Name: types.QueueTokenVariable, Value: service.GetQueueToken()},
+			)
+		}
+	}
+	types.TerminateSidecars(podSpec)
+	template, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      service.Labels,
+			Annotations: service.Annotations,
+		},
+		Spec: *podSpec,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workers := service.Workers
+	spec := map[string]interface{}{
+		"jobTargetRef": map[string]interface{}{
+			"backoffLimit": int64(0),
+			"template":     template,
+		},
+		"pollingInterval": int64(workers.GetPollingInterval()),
+		"maxReplicaCount": int64(workers.GetMaxReplicas()),
+		"triggers": []interface{}{
+			map[string]interface{}{
+				"type": "metrics-api",
+				"metadata": map[string]interface{}{
+					"url":           fmt.Sprintf("%s/queue/%s?%s=%s", oscarURL, service.Name, types.QueueTokenParam, service.GetQueueToken()),
+					"format":        "json",
+					"valueLocation": "depth",
+					"targetValue":   strconv.Itoa(workers.GetEventsPerWorker()),
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+
+	sj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	sj.SetAPIVersion(ScaledJobResource.GroupVersion().String())
+	sj.SetKind("ScaledJob")
+	sj.SetName(service.Name)
+	sj.SetNamespace(cfg.GetServiceNamespace(service))
+	sj.SetLabels(map[string]string{types.ServiceLabel: service.Name})
+	sj.SetAnnotations(map[string]string{ScaledJobHashAnnotation: hex.EncodeToString(hash[:])})
+	return sj, nil
+}
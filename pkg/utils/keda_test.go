@@ -0,0 +1,90 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestReconcileScaledJobs(t *testing.T) {
+	cfg := &types.Config{Name: "oscar", Namespace: "oscar", ServicePort: 8080, ServicesNamespace: "oscar-svc"}
+	workers := &types.Service{Name: "workers", Image: "busybox", Token: "token", Labels: map[string]string{}, Workers: &types.WorkerPool{MaxReplicas: 5}}
+	back := &fakeServiceBackend{services: map[string]*types.Service{
+		"workers": workers,
+		"jobs":    {Name: "jobs", Image: "busybox"},
+	}}
+
+	// ScaledJob of a service whose workers have been disabled
+	orphan := &unstructured.Unstructured{}
+	orphan.SetAPIVersion("keda.sh/v1alpha1")
+	orphan.SetKind("ScaledJob")
+	orphan.SetName("jobs")
+	orphan.SetNamespace("oscar-svc")
+	orphan.SetLabels(map[string]string{types.ServiceLabel: "jobs"})
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{ScaledJobResource: "ScaledJobList"}, orphan)
+
+	if err := reconcileScaledJobs(cfg, back, dynClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client := dynClient.Resource(ScaledJobResource).Namespace("oscar-svc")
+	if _, err := client.Get(context.TODO(), "jobs", metav1.GetOptions{}); err == nil {
+		t.Error("expecting the ScaledJob of the service without workers deleted")
+	}
+	sj, err := client.Get(context.TODO(), "workers", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expecting the ScaledJob of the service with workers, got error: %v", err)
+	}
+	if maxReplicas, _, _ := unstructured.NestedInt64(sj.Object, "spec", "maxReplicaCount"); maxReplicas != 5 {
+		t.Errorf("expecting maxReplicaCount 5, got %d", maxReplicas)
+	}
+	triggers, _, _ := unstructured.NestedSlice(sj.Object, "spec", "triggers")
+	if len(triggers) != 1 {
+		t.Fatalf("expecting a trigger, got %v", triggers)
+	}
+	url, _, _ := unstructured.NestedString(triggers[0].(map[string]interface{}), "metadata", "url")
+	if expected := "http://oscar.oscar:8080/queue/workers?token=" + workers.GetQueueToken(); url != expected {
+		t.Errorf("expecting url %s, got %s", expected, url)
+	}
+	containers, _, _ := unstructured.NestedSlice(sj.Object, "spec", "jobTargetRef", "template", "spec", "containers")
+	if len(containers) == 0 {
+		t.Fatal("expecting the containers of the workers")
+	}
+	args, _, _ := unstructured.NestedStringSlice(containers[0].(map[string]interface{}), "args")
+	if len(args) != 2 || args[1] != workers.GetWorkerScript() {
+		t.Errorf("expecting the worker script, got %v", args)
+	}
+
+	// Updated when its spec changes
+	workers.Workers.MaxReplicas = 8
+	if err := reconcileScaledJobs(cfg, back, dynClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sj, _ = client.Get(context.TODO(), "workers", metav1.GetOptions{})
+	if maxReplicas, _, _ := unstructured.NestedInt64(sj.Object, "spec", "maxReplicaCount"); maxReplicas != 8 {
+		t.Errorf("expecting maxReplicaCount 8, got %d", maxReplicas)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// lifecycleBufferSize events buffered for each subscriber, the next events are dropped until it reads them
+	lifecycleBufferSize = 64
+	// lifecycleRetryInterval interval to restart the watch of the jobs after an error
+	lifecycleRetryInterval = 5 * time.Second
+)
+
+// Custom logger
+var lifecycleLogger = log.New(os.Stdout, "[EVENTS] ", log.Flags())
+
+var (
+	lifecycleMutex       sync.Mutex
+	lifecycleSubscribers = map[chan types.LifecycleEvent]struct{}{}
+)
+
+// SubscribeLifecycleEvents returns a channel receiving the lifecycle events of the services and a function to cancel
+// the subscription. The events are dropped while the channel is full
+func SubscribeLifecycleEvents() (<-chan types.LifecycleEvent, func()) {
+	ch := make(chan types.LifecycleEvent, lifecycleBufferSize)
+
+	lifecycleMutex.Lock()
+	lifecycleSubscribers[ch] = struct{}{}
+	lifecycleMutex.Unlock()
+
+	return ch, func() {
+		lifecycleMutex.Lock()
+		delete(lifecycleSubscribers, ch)
+		lifecycleMutex.Unlock()
+	}
+}
+
+// PublishLifecycleEvent sends a lifecycle event to all the subscribers
+func PublishLifecycleEvent(event types.LifecycleEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	lifecycleMutex.Lock()
+	defer lifecycleMutex.Unlock()
+	for ch := range lifecycleSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// StartLifecycleEventsWatcher starts watching the jobs of the services to publish their creation and result
+func StartLifecycleEventsWatcher(cfg *types.Config, kubeClientset kubernetes.Interface) {
+	w := &jobWatcher{cfg: cfg, kubeClientset: kubeClientset, states: map[string]string{}}
+	for {
+		if err := w.run(); err != nil {
+			lifecycleLogger.Println(err.Error())
+		}
+
+		time.Sleep(lifecycleRetryInterval)
+	}
+}
+
+// jobWatcher publishes the lifecycle events of the jobs
+type jobWatcher struct {
+	cfg           *types.Config
+	kubeClientset kubernetes.Interface
+	// states last known state of each job (by name)
+	states map[string]string
+	// synced the initial jobs have been listed, so the next new jobs are published
+	synced bool
+}
+
+// run lists the jobs of the services and watches their changes until the watch is closed
+func (w *jobWatcher) run() error {
+	listOpts := metav1.ListOptions{
+		LabelSelector: types.ServiceLabel,
+	}
+	jobs, err := w.kubeClientset.BatchV1().Jobs(w.cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+	// Publish the changes missed while the watch was down
+	for i := range jobs.Items {
+		w.handle(&jobs.Items[i])
+	}
+	w.synced = true
+
+	listOpts.ResourceVersion = jobs.ResourceVersion
+	watcher, err := w.kubeClientset.BatchV1().Jobs(w.cfg.GetJobsNamespace()).Watch(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error watching the jobs: %v", err)
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		job, ok := event.Object.(*batchv1.Job)
+		if !ok {
+			continue
+		}
+		if event.Type == watch.Deleted {
+			delete(w.states, job.Name)
+			continue
+		}
+		w.handle(job)
+	}
+	return nil
+}
+
+// handle publishes the creation of a new job and the result of a finished job
+func (w *jobWatcher) handle(job *batchv1.Job) {
+	state := ""
+	message := ""
+	if isJobComplete(job) {
+		state = types.JobSucceededEvent
+	} else if reason, msg, failed := getJobFailure(job); failed {
+		state = types.JobFailedEvent
+		message = fmt.Sprintf("%s: %s", reason, msg)
+	}
+
+	last, known := w.states[job.Name]
+	w.states[job.Name] = state
+	if !w.synced {
+		return
+	}
+
+	event := types.LifecycleEvent{Service: job.Labels[types.ServiceLabel], Job: job.Name}
+	if !known {
+		event.Type = types.JobCreatedEvent
+		PublishLifecycleEvent(event)
+	}
+	if state != "" && state != last {
+		event.Type = state
+		event.Message = message
+		PublishLifecycleEvent(event)
+	}
+}
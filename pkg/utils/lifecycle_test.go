@@ -0,0 +1,58 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJobWatcher(t *testing.T) {
+	events, unsubscribe := SubscribeLifecycleEvents()
+	defer unsubscribe()
+
+	w := &jobWatcher{cfg: &types.Config{}, states: map[string]string{}}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job", Labels: map[string]string{types.ServiceLabel: "test"}}}
+
+	// The jobs existing before the first list are not published
+	w.handle(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "old", Labels: map[string]string{types.ServiceLabel: "test"}}})
+	w.synced = true
+
+	w.handle(job)
+	w.handle(job)
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"}}
+	w.handle(job)
+	w.handle(job)
+
+	expected := []types.LifecycleEvent{
+		{Type: types.JobCreatedEvent, Service: "test", Job: "job"},
+		{Type: types.JobFailedEvent, Service: "test", Job: "job", Message: "BackoffLimitExceeded: Job has reached the specified backoff limit"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expecting %d events, got %d", len(expected), len(events))
+	}
+	for _, e := range expected {
+		event := <-events
+		if event.Type != e.Type || event.Service != e.Service || event.Job != e.Job || event.Message != e.Message || event.Time.IsZero() {
+			t.Errorf("expecting the event %+v, got %+v", e, event)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var logsArchiveLogger = log.New(os.Stdout, "[LOGS-ARCHIVE] ", log.Flags())
+
+// StartLogsArchiver starts the loop to archive the logs of the finished jobs in the cfg.LogsArchiveBucket
+// every cfg.LogsArchiveInterval
+func StartLogsArchiver(cfg *types.Config, kubeClientset kubernetes.Interface) {
+	for {
+		if err := archiveJobsLogs(cfg, kubeClientset); err != nil {
+			logsArchiveLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.LogsArchiveInterval) * time.Second)
+	}
+}
+
+func archiveJobsLogs(cfg *types.Config, kubeClientset kubernetes.Interface) error {
+	// List the services' jobs not archived yet
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,!%s", types.ServiceLabel, types.LogsArchivedLabelKey),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+
+	bucketCreated := false
+	for _, job := range jobs.Items {
+		if _, _, failed := getJobFailure(&job); !failed && !isJobComplete(&job) {
+			continue
+		}
+
+		if !bucketCreated {
+			if err := createLogsArchiveBucket(cfg); err != nil {
+				return err
+			}
+			bucketCreated = true
+		}
+
+		serviceName := job.Labels[types.ServiceLabel]
+		logs, err := getLastPodLogs(kubeClientset, job.Namespace, job.Name)
+		if err != nil {
+			logsArchiveLogger.Printf("error reading the logs of job \"%s\": %v\n", job.Name, err)
+			continue
+		}
+		if logs != nil {
+			_, err = cfg.MinIOProvider.GetS3Client().PutObject(&s3.PutObjectInput{
+				Bucket:      aws.String(cfg.LogsArchiveBucket),
+				Key:         aws.String(getArchivedLogsKey(serviceName, job.Name)),
+				Body:        bytes.NewReader(logs),
+				ContentType: aws.String("text/plain"),
+			})
+			if err != nil {
+				logsArchiveLogger.Printf("error archiving the logs of job \"%s\": %v\n", job.Name, err)
+				continue
+			}
+		}
+
+		// Mark the job as archived (also if it has no pods left, so it is not listed again)
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{"%s":"true"}}}`, types.LogsArchivedLabelKey))
+		if _, err := kubeClientset.BatchV1().Jobs(job.Namespace).Patch(context.TODO(), job.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			logsArchiveLogger.Printf("error labelling the archived job \"%s\": %v\n", job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// getLastPodLogs returns the logs (with timestamps) of the 'oscar-container' of the newest pod of a job. Nil is
+// returned if the job has no pods
+func getLastPodLogs(kubeClientset kubernetes.Interface, namespace string, jobName string) ([]byte, error) {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", jobName),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, nil
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+
+	podLogOpts := &v1.PodLogOptions{
+		Container:  types.ContainerName,
+		Timestamps: true,
+	}
+	return kubeClientset.CoreV1().Pods(namespace).GetLogs(pods.Items[0].Name, podLogOpts).DoRaw(context.TODO())
+}
+
+// createLogsArchiveBucket creates the cfg.LogsArchiveBucket if it doesn't exist
+func createLogsArchiveBucket(cfg *types.Config) error {
+	_, err := cfg.MinIOProvider.GetS3Client().CreateBucket(&s3.CreateBucketInput{
+		Bucket: aws.String(cfg.LogsArchiveBucket),
+	})
+	if err != nil {
+		// Check if the error is caused because the bucket already exists
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeBucketAlreadyExists || aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou) {
+			return nil
+		}
+		return fmt.Errorf("error creating bucket %s: %v", cfg.LogsArchiveBucket, err)
+	}
+	return nil
+}
+
+// getArchivedLogsKey returns the key of the archived logs of a job
+func getArchivedLogsKey(serviceName string, jobName string) string {
+	return fmt.Sprintf("%s/%s.log", path.Base(serviceName), path.Base(jobName))
+}
+
+// GetArchivedJobLogs returns the archived logs of a job, with the timestamp of each line
+func GetArchivedJobLogs(cfg *types.Config, serviceName string, jobName string) ([]byte, error) {
+	out, err := cfg.MinIOProvider.GetS3Client().GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(cfg.LogsArchiveBucket),
+		Key:    aws.String(getArchivedLogsKey(serviceName, jobName)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == s3.ErrCodeNoSuchBucket) {
+			return nil, types.NewCodedError(types.ErrJobNotFound, fmt.Errorf("the logs of job \"%s\" are not archived", jobName))
+		}
+		return nil, types.NewCodedError(types.ErrStorageConnectionFailed, fmt.Errorf("error reading the archived logs: %v", err))
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
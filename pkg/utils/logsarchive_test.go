@@ -0,0 +1,95 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestArchiveJobsLogs(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	cfg := &types.Config{
+		ServicesNamespace: "oscar-svc",
+		LogsArchiveBucket: "oscar-logs",
+		MinIOProvider: &types.MinIOProvider{
+			Endpoint:  s3Server.URL,
+			Region:    "us-east-1",
+			AccessKey: "minioadmin",
+			SecretKey: "minioadmin",
+			Verify:    true,
+		},
+	}
+
+	newJob := func(name string, condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cfg.ServicesNamespace,
+			Labels:    map[string]string{types.ServiceLabel: "test"},
+		}}
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: v1.ConditionTrue}}
+		}
+		return job
+	}
+	newPod := func(job string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      job + "-pod",
+			Namespace: cfg.ServicesNamespace,
+			Labels:    map[string]string{types.ServiceLabel: "test", "job-name": job},
+		}}
+	}
+	// The fake clientset returns "fake logs" as logs of every pod
+	kubeClientset := testclient.NewSimpleClientset(
+		newJob("completed", batchv1.JobComplete), newPod("completed"),
+		newJob("failed", batchv1.JobFailed), newPod("failed"),
+		newJob("running", ""), newPod("running"),
+		newJob("without-pods", batchv1.JobComplete),
+	)
+
+	if err := archiveJobsLogs(cfg, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, job := range []string{"completed", "failed"} {
+		logs, err := GetArchivedJobLogs(cfg, "test", job)
+		if err != nil || string(logs) != "fake logs" {
+			t.Errorf("unexpected archived logs of job \"%s\": \"%s\" (%v)", job, logs, err)
+		}
+	}
+	for _, job := range []string{"running", "without-pods"} {
+		if _, err := GetArchivedJobLogs(cfg, "test", job); types.GetErrorCode(err, types.ErrInternal) != types.ErrJobNotFound {
+			t.Errorf("expecting the logs of job \"%s\" not to be archived, got %v", job, err)
+		}
+	}
+
+	// The finished jobs are labelled to not archive them again
+	for job, archived := range map[string]bool{"completed": true, "failed": true, "running": false, "without-pods": true} {
+		j, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), job, metav1.GetOptions{})
+		if _, ok := j.Labels[types.LogsArchivedLabelKey]; ok != archived {
+			t.Errorf("expecting the archived label of job \"%s\" to be %t", job, archived)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// maintenanceRefreshInterval time that the maintenance status is cached, so the changes made through other
+	// replicas of the OSCAR manager are applied
+	maintenanceRefreshInterval = 10 * time.Second
+	// maxQueuedInvocations maximum number of invocations queued during a freeze
+	maxQueuedInvocations = 1000
+	// MaxQueuedInvocationBytes maximum size of the body of the queued invocations
+	MaxQueuedInvocationBytes = 1024 * 1024
+)
+
+var (
+	// ErrInvocationQueueFull returned when the queue of invocations is full
+	ErrInvocationQueueFull = errors.New("the queue of invocations is full")
+
+	maintenanceLogger = log.New(os.Stdout, "[MAINTENANCE] ", log.Flags())
+)
+
+// Maintenance read-only mode of the API, stored in the MaintenanceConfigMapName configMap to be shared by the
+// replicas of the OSCAR manager and kept across restarts. The invocations queued during a freeze are kept in the
+// memory of each replica, and replayed when it detects the end of the freeze
+type Maintenance struct {
+	kubeClientset kubernetes.Interface
+	namespace     string
+	mutex         sync.Mutex
+	status        types.MaintenanceStatus
+	refreshedAt   time.Time
+	queue         []*queuedInvocation
+	replay        http.Handler
+}
+
+// queuedInvocation request of an invocation queued during a freeze
+type queuedInvocation struct {
+	method string
+	url    string
+	header http.Header
+	body   []byte
+}
+
+// discardResponseWriter response writer of the replayed invocations, whose responses are discarded
+type discardResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// NewMaintenance creates the maintenance mode of the API, whose queued invocations are replayed to handler
+func NewMaintenance(cfg *types.Config, kubeClientset kubernetes.Interface, handler http.Handler) *Maintenance {
+	return &Maintenance{
+		kubeClientset: kubeClientset,
+		namespace:     cfg.ServicesNamespace,
+		replay:        handler,
+	}
+}
+
+// Status returns the maintenance status, reading it again if the cached status has expired
+func (m *Maintenance) Status() types.MaintenanceStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if time.Since(m.refreshedAt) >= maintenanceRefreshInterval {
+		status, err := m.readStatus()
+		if err != nil {
+			maintenanceLogger.Printf("Error reading the maintenance status, using the cached one: %v\n", err)
+		} else {
+			m.setStatus(status)
+		}
+		m.refreshedAt = time.Now()
+	}
+
+	status := m.status
+	status.QueuedInvocations = len(m.queue)
+	return status
+}
+
+// Freeze enables the read-only mode of the API
+func (m *Maintenance) Freeze(req types.MaintenanceFreezeRequest) (types.MaintenanceStatus, error) {
+	now := time.Now().UTC()
+	status := types.MaintenanceStatus{
+		Frozen:           true,
+		Reason:           req.Reason,
+		Since:            &now,
+		RetryAfter:       req.RetryAfter,
+		QueueInvocations: req.QueueInvocations,
+	}
+	if status.RetryAfter == 0 {
+		status.RetryAfter = types.DefaultMaintenanceRetryAfter
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return status, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cms := m.kubeClientset.CoreV1().ConfigMaps(m.namespace)
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.MaintenanceConfigMapName,
+			Namespace: m.namespace,
+		},
+		Data: map[string]string{
+			types.MaintenanceFileName: string(data),
+		},
+	}
+	if _, err := cms.Update(context.TODO(), cm, metav1.UpdateOptions{}); k8serrors.IsNotFound(err) {
+		_, err = cms.Create(context.TODO(), cm, metav1.CreateOptions{})
+		if err != nil {
+			return status, err
+		}
+	} else if err != nil {
+		return status, err
+	}
+
+	m.setStatus(status)
+	m.refreshedAt = time.Now()
+	status.QueuedInvocations = len(m.queue)
+	return status, nil
+}
+
+// Unfreeze disables the read-only mode of the API and replays the queued invocations
+func (m *Maintenance) Unfreeze() (types.MaintenanceStatus, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	err := m.kubeClientset.CoreV1().ConfigMaps(m.namespace).Delete(context.TODO(), types.MaintenanceConfigMapName, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return m.status, err
+	}
+
+	m.setStatus(types.MaintenanceStatus{})
+	m.refreshedAt = time.Now()
+	return m.status, nil
+}
+
+// Queue stores an invocation to be replayed at the end of the freeze. The body must have been read by the caller
+func (m *Maintenance) Queue(r *http.Request, body []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.queue) >= maxQueuedInvocations {
+		return ErrInvocationQueueFull
+	}
+	m.queue = append(m.queue, &queuedInvocation{
+		method: r.Method,
+		url:    r.URL.RequestURI(),
+		header: r.Header.Clone(),
+		body:   body,
+	})
+	return nil
+}
+
+// setStatus updates the cached status, replaying the queued invocations if the freeze has ended. The mutex must be
+// locked by the caller
+func (m *Maintenance) setStatus(status types.MaintenanceStatus) {
+	if m.status.Frozen && !status.Frozen && len(m.queue) > 0 {
+		go m.replayInvocations(m.queue)
+		m.queue = nil
+	}
+	m.status = status
+}
+
+// replayInvocations sends the queued invocations to the replay handler, in their arrival order
+func (m *Maintenance) replayInvocations(queue []*queuedInvocation) {
+	maintenanceLogger.Printf("Replaying %d invocations queued during the maintenance\n", len(queue))
+	for _, inv := range queue {
+		req, err := http.NewRequest(inv.method, inv.url, bytes.NewReader(inv.body))
+		if err != nil {
+			maintenanceLogger.Printf("Error replaying the invocation \"%s\": %v\n", inv.url, err)
+			continue
+		}
+		req.Header = inv.header
+		w := &discardResponseWriter{header: http.Header{}, status: http.StatusOK}
+		m.replay.ServeHTTP(w, req)
+		if w.status >= http.StatusBadRequest {
+			maintenanceLogger.Printf("The replayed invocation \"%s\" failed with status %d\n", inv.url, w.status)
+		}
+	}
+}
+
+// readStatus reads the maintenance status from its configMap (not frozen if it does not exist)
+func (m *Maintenance) readStatus() (types.MaintenanceStatus, error) {
+	status := types.MaintenanceStatus{}
+	cm, err := m.kubeClientset.CoreV1().ConfigMaps(m.namespace).Get(context.TODO(), types.MaintenanceConfigMapName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	err = json.Unmarshal([]byte(cm.Data[types.MaintenanceFileName]), &status)
+	return status, err
+}
@@ -0,0 +1,305 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+const (
+	// ClamAVScanner type of the scanners sending the objects to a ClamAV daemon (INSTREAM command)
+	ClamAVScanner = "clamav"
+	// ICAPScanner type of the scanners sending the objects to an ICAP server (RESPMOD method)
+	ICAPScanner = "icap"
+
+	// scanChunkSize size of the chunks sent to the scanners
+	scanChunkSize = 64 * 1024
+)
+
+// MalwareScanner scanner of the input objects
+type MalwareScanner interface {
+	// Type returns the type of the scanner
+	Type() string
+	// Scan scans the content read from r, returning the name of the detected threat (empty if clean)
+	Scan(ctx context.Context, r io.Reader) (string, error)
+}
+
+// NewMalwareScanner returns the scanner of the cluster (MalwareScannerURL) or nil if it is not configured
+func NewMalwareScanner(cfg *types.Config) (MalwareScanner, error) {
+	if cfg.MalwareScannerURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(cfg.MalwareScannerURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("the malware scanner URL \"%s\" is not valid", cfg.MalwareScannerURL)
+	}
+	switch u.Scheme {
+	case ClamAVScanner:
+		return &clamAVScanner{address: withDefaultPort(u.Host, "3310")}, nil
+	case ICAPScanner:
+		return &icapScanner{address: withDefaultPort(u.Host, "1344"), service: u.Path}, nil
+	default:
+		return nil, fmt.Errorf("the malware scanner \"%s\" is not supported, the scheme must be \"%s\" or \"%s\"", u.Scheme, ClamAVScanner, ICAPScanner)
+	}
+}
+
+// withDefaultPort adds the default port to address if it has none
+func withDefaultPort(address string, port string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, port)
+	}
+	return address
+}
+
+// dialScanner opens a connection to a scanner whose deadline is the context's deadline
+func dialScanner(ctx context.Context, address string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the malware scanner: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// clamAVScanner scanner using the INSTREAM command of a ClamAV daemon
+type clamAVScanner struct {
+	address string
+}
+
+func (s *clamAVScanner) Type() string {
+	return ClamAVScanner
+}
+
+// Scan sends the content to clamd in chunks prefixed by their length (big endian), ended by a zero-length chunk
+func (s *clamAVScanner) Scan(ctx context.Context, r io.Reader) (string, error) {
+	conn, err := dialScanner(ctx, s.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", fmt.Errorf("error sending the object to clamd: %v", err)
+	}
+	buf := make([]byte, scanChunkSize)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			size := make([]byte, 4)
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(append(size, buf[:n]...)); err != nil {
+				return "", fmt.Errorf("error sending the object to clamd: %v", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return "", fmt.Errorf("error reading the object: %v", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return "", fmt.Errorf("error sending the object to clamd: %v", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("error reading the clamd reply: %v", err)
+	}
+	return parseClamAVReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamAVReply returns the threat found in a clamd reply ("stream: OK", "stream: <THREAT> FOUND" or
+// "<MESSAGE> ERROR")
+func parseClamAVReply(reply string) (string, error) {
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSpace(strings.TrimPrefix(strings.TrimSuffix(reply, " FOUND"), "stream:")), nil
+	case strings.HasSuffix(reply, "OK"):
+		return "", nil
+	default:
+		return "", fmt.Errorf("clamd error: %s", reply)
+	}
+}
+
+// icapScanner scanner using the RESPMOD method of an ICAP server (RFC 3507), which answers 204 if the content is clean
+type icapScanner struct {
+	address string
+	service string
+}
+
+func (s *icapScanner) Type() string {
+	return ICAPScanner
+}
+
+// Threat names in the headers set by the ICAP servers (e.g. "Type=0; Resolution=2; Threat=Eicar-Signature;")
+var icapThreatRegexp = regexp.MustCompile(`Threat=([^;]+)`)
+
+// Scan sends the content as the body of an encapsulated HTTP response
+func (s *icapScanner) Scan(ctx context.Context, r io.Reader) (string, error) {
+	conn, err := dialScanner(ctx, s.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	resHeader := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"
+	icapURL := url.URL{Scheme: ICAPScanner, Host: s.address, Path: s.service}
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\nHost: %s\r\nAllow: 204\r\nEncapsulated: res-hdr=0, res-body=%d\r\n\r\n%s",
+		icapURL.String(), s.address, len(resHeader), resHeader)
+	buf := make([]byte, scanChunkSize)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return "", fmt.Errorf("error reading the object: %v", readErr)
+		}
+	}
+	w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return "", fmt.Errorf("error sending the object to the ICAP server: %v", err)
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	statusLine, err := tp.ReadLine()
+	if err != nil {
+		return "", fmt.Errorf("error reading the ICAP response: %v", err)
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("error reading the ICAP response: %v", err)
+	}
+	return parseICAPResponse(statusLine, header)
+}
+
+// parseICAPResponse returns the threat reported in an ICAP response. The content is infected if it has been
+// modified (200), with the threat name in the X-Infection-Found, X-Violations-Found or X-Virus-ID headers
+func parseICAPResponse(statusLine string, header textproto.MIMEHeader) (string, error) {
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "ICAP/") {
+		return "", fmt.Errorf("invalid ICAP response \"%s\"", statusLine)
+	}
+	switch fields[1] {
+	case "204":
+		return "", nil
+	case "200":
+		for _, name := range []string{"X-Infection-Found", "X-Violations-Found"} {
+			if match := icapThreatRegexp.FindStringSubmatch(header.Get(name)); match != nil {
+				return strings.TrimSpace(match[1]), nil
+			}
+		}
+		if virusID := strings.TrimSpace(header.Get("X-Virus-ID")); virusID != "" {
+			return virusID, nil
+		}
+		return "unknown", nil
+	default:
+		return "", fmt.Errorf("ICAP error: %s", statusLine)
+	}
+}
+
+// ScanObject scans an object of the OSCAR's MinIO, returning the name of the detected threat (empty if clean)
+func ScanObject(cfg *types.Config, scanner MalwareScanner, bucket string, key string) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if cfg.MalwareScanTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), cfg.MalwareScanTimeout)
+	}
+	defer cancel()
+
+	out, err := cfg.MinIOProvider.GetS3Client().GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error reading the object \"%s/%s\": %v", bucket, key, err)
+	}
+	defer out.Body.Close()
+
+	return scanner.Scan(ctx, out.Body)
+}
+
+// QuarantineObject moves an infected object of an input of the service to its quarantine path (keeping its key
+// relative to the input), storing the audit record next to it
+func QuarantineObject(cfg *types.Config, service *types.Service, in types.StorageIOConfig, bucket string, key string, threat string, scannerType string) (*types.QuarantineRecord, error) {
+	_, inFolder := in.SplitPath()
+	relKey := strings.TrimPrefix(strings.TrimPrefix(key, inFolder), "/")
+	qBucket, qFolder := types.StorageIOConfig{Path: service.QuarantinePath}.SplitPath()
+	qKey := path.Join(qFolder, relKey)
+
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	copySource := (&url.URL{Path: fmt.Sprintf("%s/%s", bucket, key)}).EscapedPath()
+	if _, err := s3Client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(qBucket),
+		Key:        aws.String(qKey),
+		CopySource: aws.String(copySource),
+	}); err != nil {
+		return nil, fmt.Errorf("error copying the object \"%s/%s\" to the quarantine path: %v", bucket, key, err)
+	}
+	if _, err := s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return nil, fmt.Errorf("error removing the infected object \"%s/%s\": %v", bucket, key, err)
+	}
+
+	record := &types.QuarantineRecord{
+		Service:       service.Name,
+		Bucket:        bucket,
+		Key:           key,
+		QuarantineKey: qKey,
+		Threat:        threat,
+		Scanner:       scannerType,
+		ScannedAt:     time.Now(),
+	}
+	body, _ := json.Marshal(record)
+	if _, err := s3Client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(qBucket),
+		Key:         aws.String(qKey + types.QuarantineRecordSuffix),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return record, fmt.Errorf("error storing the audit record of the quarantined object \"%s/%s\": %v", bucket, key, err)
+	}
+
+	return record, nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// startFakeScanner accepts connections on a local port, handling each of them with handle
+func startFakeScanner(t *testing.T, handle func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// fakeClamd replies to the INSTREAM commands, detecting the content with "EICAR"
+func fakeClamd(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var content bytes.Buffer
+	for {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(r, size); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+		io.CopyN(&content, r, int64(n))
+	}
+	if strings.Contains(content.String(), "EICAR") {
+		conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+// fakeICAP replies to the RESPMOD requests, detecting the content with "EICAR"
+func fakeICAP(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	tp := textproto.NewReader(r)
+	if line, err := tp.ReadLine(); err != nil || !strings.HasPrefix(line, "RESPMOD icap://") {
+		conn.Write([]byte("ICAP/1.0 400 Bad Request\r\n\r\n"))
+		return
+	}
+	tp.ReadMIMEHeader()
+	// Encapsulated HTTP response with the chunked content
+	res, err := http.ReadResponse(r, nil)
+	if err != nil {
+		conn.Write([]byte("ICAP/1.0 400 Bad Request\r\n\r\n"))
+		return
+	}
+	content, _ := io.ReadAll(res.Body)
+	if strings.Contains(string(content), "EICAR") {
+		conn.Write([]byte("ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Eicar-Test-Signature;\r\nEncapsulated: null-body=0\r\n\r\n"))
+		return
+	}
+	conn.Write([]byte("ICAP/1.0 204 No Content\r\n\r\n"))
+}
+
+func TestMalwareScanners(t *testing.T) {
+	scanners := map[string]string{
+		"clamav://" + startFakeScanner(t, fakeClamd):        "Eicar-Signature",
+		"icap://" + startFakeScanner(t, fakeICAP) + "/scan": "Eicar-Test-Signature",
+	}
+	for scannerURL, expectedThreat := range scanners {
+		scanner, err := NewMalwareScanner(&types.Config{MalwareScannerURL: scannerURL})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", scannerURL, err)
+		}
+
+		// Larger than a chunk
+		clean := strings.Repeat("a", scanChunkSize+10)
+		if threat, err := scanner.Scan(context.Background(), strings.NewReader(clean)); err != nil || threat != "" {
+			t.Errorf("%s: expecting clean content, got \"%s\" (%v)", scannerURL, threat, err)
+		}
+		if threat, err := scanner.Scan(context.Background(), strings.NewReader("X5O!P%@AP-EICAR-TEST")); err != nil || threat != expectedThreat {
+			t.Errorf("%s: expecting threat \"%s\", got \"%s\" (%v)", scannerURL, expectedThreat, threat, err)
+		}
+	}
+}
+
+func TestNewMalwareScanner(t *testing.T) {
+	if scanner, err := NewMalwareScanner(&types.Config{}); scanner != nil || err != nil {
+		t.Errorf("expecting no scanner, got %v (%v)", scanner, err)
+	}
+	for _, invalid := range []string{"http://clamav:3310", "clamav", "::"} {
+		if _, err := NewMalwareScanner(&types.Config{MalwareScannerURL: invalid}); err == nil {
+			t.Errorf("%s: expecting error", invalid)
+		}
+	}
+	scanner, _ := NewMalwareScanner(&types.Config{MalwareScannerURL: "clamav://clamav.oscar"})
+	if s, ok := scanner.(*clamAVScanner); !ok || s.address != "clamav.oscar:3310" {
+		t.Errorf("expecting the ClamAV scanner on the default port, got %v", scanner)
+	}
+}
+
+func TestParseScannerReplies(t *testing.T) {
+	if _, err := parseClamAVReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Error("expecting error for the clamd errors")
+	}
+	header := textproto.MIMEHeader{"X-Virus-Id": {"Win.Test"}}
+	if threat, err := parseICAPResponse("ICAP/1.0 200 OK", header); err != nil || threat != "Win.Test" {
+		t.Errorf("expecting the threat of X-Virus-ID, got \"%s\" (%v)", threat, err)
+	}
+	if threat, _ := parseICAPResponse("ICAP/1.0 200 OK", textproto.MIMEHeader{}); threat != "unknown" {
+		t.Errorf("expecting an unknown threat for the modified responses, got \"%s\"", threat)
+	}
+	if _, err := parseICAPResponse("ICAP/1.0 500 Server Error", textproto.MIMEHeader{}); err == nil {
+		t.Error("expecting error for the ICAP errors")
+	}
+}
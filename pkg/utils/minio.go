@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/grycap/oscar/v2/pkg/types"
@@ -36,10 +37,20 @@ type MinIOAdminClient struct {
 
 // MakeMinIOAdminClient creates a new MinIO Admin client to configure webhook notifications
 func MakeMinIOAdminClient(cfg *types.Config) (*MinIOAdminClient, error) {
+	return MakeProviderMinIOAdminClient(cfg, cfg.MinIOProvider)
+}
+
+// MakeProviderMinIOAdminClient creates a new MinIO Admin client of a MinIO provider (e.g. the secondary one of the
+// failover) to configure webhook notifications. The OSCAR's MinIO is used if provider is nil
+func MakeProviderMinIOAdminClient(cfg *types.Config, provider *types.MinIOProvider) (*MinIOAdminClient, error) {
+	if provider == nil {
+		provider = cfg.MinIOProvider
+	}
+
 	// Parse minIO endpoint
-	endpointURL, err := url.Parse(cfg.MinIOProvider.Endpoint)
+	endpointURL, err := url.Parse(provider.Endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("the provided MinIO endpoint \"%s\" is not valid", cfg.MinIOProvider.Endpoint)
+		return nil, fmt.Errorf("the provided MinIO endpoint \"%s\" is not valid", provider.Endpoint)
 	}
 
 	// Check URL Scheme for using TLS or not
@@ -50,16 +61,16 @@ func MakeMinIOAdminClient(cfg *types.Config) (*MinIOAdminClient, error) {
 	case "https":
 		enableTLS = true
 	default:
-		return nil, fmt.Errorf("invalid MinIO Endpoint: %s. Must start with \"http://\" or \"https://\"", cfg.MinIOProvider.Endpoint)
+		return nil, fmt.Errorf("invalid MinIO Endpoint: %s. Must start with \"http://\" or \"https://\"", provider.Endpoint)
 	}
 
-	adminClient, err := madmin.New(endpointURL.Host, cfg.MinIOProvider.AccessKey, cfg.MinIOProvider.SecretKey, enableTLS)
+	adminClient, err := madmin.New(endpointURL.Host, provider.AccessKey, provider.SecretKey, enableTLS)
 	if err != nil {
 		return nil, err
 	}
 
 	// Disable tls verification in client transport if verify == false
-	if !cfg.MinIOProvider.Verify {
+	if !provider.Verify {
 		tr := &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
@@ -97,6 +108,15 @@ func (minIOAdminClient *MinIOAdminClient) RemoveWebhook(name string) error {
 	return nil
 }
 
+// IsWebhookRegistered checks if the service's webhook is registered in the MinIO configuration
+func (minIOAdminClient *MinIOAdminClient) IsWebhookRegistered(name string) (bool, error) {
+	cfg, err := minIOAdminClient.adminClient.GetConfigKV(context.TODO(), fmt.Sprintf("notify_webhook:%s", name))
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(cfg), fmt.Sprintf("/job/%s", name)), nil
+}
+
 // RestartServer restarts a MinIO server to apply the configuration changes
 func (minIOAdminClient *MinIOAdminClient) RestartServer() error {
 	err := minIOAdminClient.adminClient.ServiceRestart(context.TODO())
@@ -0,0 +1,340 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// ociTitleAnnotation annotation with the file name of the layers pushed as files (e.g. by oras)
+	ociTitleAnnotation = "org.opencontainers.image.title"
+	// ociMaxArtifactSize maximum size of all the layers of a pulled artifact
+	ociMaxArtifactSize = 64 << 20
+	ociPullTimeout     = 2 * time.Minute
+)
+
+// ociManifestTypes media types of the manifests (and indexes) accepted when pulling an artifact
+var ociManifestTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// ociDescriptor descriptor of a manifest or layer
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest image manifest or index
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests,omitempty"`
+	Layers    []ociDescriptor `json:"layers,omitempty"`
+}
+
+// ociRegistry anonymous client of a repository of an OCI distribution registry
+type ociRegistry struct {
+	baseURL    string
+	repository string
+	token      string
+	client     *http.Client
+}
+
+// PullOCIArtifact downloads the layers of an OCI artifact or image ("registry/repository[:tag|@digest]") into dir.
+// The tar layers are extracted (only regular files and directories) and the rest are stored as files named after
+// their title annotation, as pushed by oras. Only anonymous pulls are supported
+func PullOCIArtifact(reference string, insecure bool, dir string) error {
+	host, repository, ref, err := parseOCIReference(reference)
+	if err != nil {
+		return err
+	}
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	reg := &ociRegistry{
+		baseURL:    fmt.Sprintf("%s://%s/v2/%s", scheme, host, repository),
+		repository: repository,
+		client:     &http.Client{Timeout: ociPullTimeout},
+	}
+
+	manifest, err := reg.getManifest(ref)
+	if err != nil {
+		return err
+	}
+	// Use the first manifest of the indexes (artifacts are not platform-specific)
+	if len(manifest.Manifests) > 0 {
+		if manifest, err = reg.getManifest(manifest.Manifests[0].Digest); err != nil {
+			return err
+		}
+	}
+
+	remaining := int64(ociMaxArtifactSize)
+	for _, layer := range manifest.Layers {
+		if layer.Size > remaining {
+			return fmt.Errorf("the artifact \"%s\" exceeds the maximum size (%d bytes)", reference, ociMaxArtifactSize)
+		}
+		remaining -= layer.Size
+		if err := reg.pullLayer(layer, dir); err != nil {
+			return fmt.Errorf("error pulling the layer \"%s\" of \"%s\": %v", layer.Digest, reference, err)
+		}
+	}
+	return nil
+}
+
+// parseOCIReference splits a reference into its registry host, repository and tag or digest ("latest" by default)
+func parseOCIReference(reference string) (host string, repository string, ref string, err error) {
+	host, repository, ok := strings.Cut(reference, "/")
+	if !ok || host == "" || repository == "" {
+		return "", "", "", fmt.Errorf("invalid OCI reference \"%s\", the format must be \"registry/repository[:tag]\"", reference)
+	}
+	ref = "latest"
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, ref = repository[:i], repository[i+1:]
+	} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, ref = repository[:i], repository[i+1:]
+	}
+	if repository == "" || ref == "" {
+		return "", "", "", fmt.Errorf("invalid OCI reference \"%s\"", reference)
+	}
+	return host, repository, ref, nil
+}
+
+// getManifest downloads a manifest (or index) by tag or digest
+func (reg *ociRegistry) getManifest(ref string) (*ociManifest, error) {
+	res, err := reg.get("/manifests/"+url.PathEscape(ref), strings.Join(ociManifestTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	manifest := &ociManifest{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 4<<20)).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("error decoding the manifest \"%s\": %v", ref, err)
+	}
+	return manifest, nil
+}
+
+// pullLayer downloads a layer, checking its digest, and extracts it into dir
+func (reg *ociRegistry) pullLayer(layer ociDescriptor, dir string) error {
+	algorithm, expected, _ := strings.Cut(layer.Digest, ":")
+	if algorithm != "sha256" {
+		return fmt.Errorf("unsupported digest algorithm \"%s\"", algorithm)
+	}
+	res, err := reg.get("/blobs/"+layer.Digest, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Store the blob before extracting it, so it is not used if the digest does not match
+	blob, err := os.CreateTemp("", "oscar-layer-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(blob.Name())
+	defer blob.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(blob, hash), io.LimitReader(res.Body, layer.Size)); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != expected {
+		return fmt.Errorf("the digest does not match")
+	}
+	if _, err := blob.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if strings.Contains(layer.MediaType, "tar") {
+		return extractTar(blob, dir)
+	}
+	title := layer.Annotations[ociTitleAnnotation]
+	if title == "" {
+		return nil
+	}
+	return writeArtifactFile(dir, title, blob)
+}
+
+// get sends a GET request to the repository, requesting an anonymous token if the registry requires it
+func (reg *ociRegistry) get(path string, accept string) (*http.Response, error) {
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, reg.baseURL+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if reg.token != "" {
+			req.Header.Set("Authorization", "Bearer "+reg.token)
+		}
+		return reg.client.Do(req)
+	}
+
+	res, err := do()
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusUnauthorized && reg.token == "" {
+		res.Body.Close()
+		if err := reg.authenticate(res.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+		if res, err = do(); err != nil {
+			return nil, err
+		}
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("unexpected status code from the registry (%s): %s", path, res.Status)
+	}
+	return res, nil
+}
+
+// authenticate requests an anonymous pull token to the realm of the registry's Bearer challenge
+func (reg *ociRegistry) authenticate(challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("the registry requires unsupported authentication: %s", challenge)
+	}
+	values := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok {
+			values[key] = strings.Trim(value, "\"")
+		}
+	}
+	realm, err := url.Parse(values["realm"])
+	if err != nil || values["realm"] == "" {
+		return fmt.Errorf("invalid authentication realm in the challenge: %s", challenge)
+	}
+	query := realm.Query()
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+	scope := values["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", reg.repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	res, err := reg.client.Get(realm.String())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("error requesting the registry token: %s", res.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&token); err != nil {
+		return fmt.Errorf("error decoding the registry token: %v", err)
+	}
+	reg.token = token.Token
+	if reg.token == "" {
+		reg.token = token.AccessToken
+	}
+	return nil
+}
+
+// extractTar extracts the regular files and directories of a (gzipped) tar archive into dir
+func extractTar(r io.Reader, dir string) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			target, err := artifactFilePath(dir, header.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArtifactFile(dir, header.Name, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeArtifactFile writes a file of an artifact, avoiding paths outside dir
+func writeArtifactFile(dir string, name string, r io.Reader) error {
+	target, err := artifactFilePath(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// artifactFilePath returns the path in dir of a file of an artifact, which must be relative and not escape dir
+func artifactFilePath(dir string, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid file path \"%s\" in the artifact", name)
+	}
+	return filepath.Join(dir, clean), nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRegistry starts a registry serving the blobs as the layers of the "catalog:v1" artifact, requiring an
+// anonymous token
+func newTestRegistry(t *testing.T, layers map[string][]byte, mediaTypes map[string]string) *httptest.Server {
+	blobs := map[string][]byte{}
+	manifest := ociManifest{MediaType: "application/vnd.oci.image.manifest.v1+json"}
+	for name, content := range layers {
+		sum := sha256.Sum256(content)
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		blobs[digest] = content
+		manifest.Layers = append(manifest.Layers, ociDescriptor{
+			MediaType:   mediaTypes[name],
+			Digest:      digest,
+			Size:        int64(len(content)),
+			Annotations: map[string]string{ociTitleAnnotation: name},
+		})
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:catalog:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"token": "anonymous"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/catalog/manifests/v1":
+			json.NewEncoder(w).Encode(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/catalog/blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/catalog/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(blob)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPullOCIArtifact(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"grayify/grayify.yaml": "functions: {}", "../outside": "x"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	registry := newTestRegistry(t,
+		map[string][]byte{"templates.tar.gz": archive.Bytes(), "README.md": []byte("# catalog")},
+		map[string]string{"templates.tar.gz": "application/vnd.oci.image.layer.v1.tar+gzip", "README.md": "text/markdown"})
+	host := strings.TrimPrefix(registry.URL, "http://")
+
+	// The archive has a path outside the directory
+	if err := PullOCIArtifact(host+"/catalog:v1", true, t.TempDir()); err == nil {
+		t.Error("expecting error for the path outside the directory")
+	}
+
+	var valid bytes.Buffer
+	tw = tar.NewWriter(&valid)
+	tw.WriteHeader(&tar.Header{Name: "grayify/grayify.yaml", Mode: 0644, Size: 13, Typeflag: tar.TypeReg})
+	tw.Write([]byte("functions: {}"))
+	tw.WriteHeader(&tar.Header{Name: "grayify/link", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink})
+	tw.Close()
+	registry = newTestRegistry(t,
+		map[string][]byte{"templates.tar": valid.Bytes(), "README.md": []byte("# catalog")},
+		map[string]string{"templates.tar": "application/vnd.oci.image.layer.v1.tar", "README.md": "text/markdown"})
+	host = strings.TrimPrefix(registry.URL, "http://")
+
+	dir := t.TempDir()
+	if err := PullOCIArtifact(host+"/catalog:v1", true, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "grayify", "grayify.yaml")); err != nil || string(content) != "functions: {}" {
+		t.Errorf("the tar layer has not been extracted: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "README.md")); err != nil || string(content) != "# catalog" {
+		t.Errorf("the file layer has not been stored: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "grayify", "link")); err == nil {
+		t.Error("the symlinks must not be extracted")
+	}
+
+	if err := PullOCIArtifact(host+"/catalog:v2", true, t.TempDir()); err == nil {
+		t.Error("expecting error for a missing tag")
+	}
+}
+
+func TestParseOCIReference(t *testing.T) {
+	scenarios := []struct {
+		reference   string
+		expected    string
+		returnError bool
+	}{
+		{"ghcr.io/grycap/catalog:v1", "ghcr.io grycap/catalog v1", false},
+		{"localhost:5000/catalog", "localhost:5000 catalog latest", false},
+		{"ghcr.io/catalog@sha256:abc", "ghcr.io catalog sha256:abc", false},
+		{"catalog", "", true},
+		{"ghcr.io/catalog:", "", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.reference, func(t *testing.T) {
+			host, repository, ref, err := parseOCIReference(s.reference)
+			if s.returnError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join([]string{host, repository, ref}, " "); got != s.expected {
+				t.Errorf("expecting \"%s\", got \"%s\"", s.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// operationTTL time that the finished operations are kept
+const operationTTL = 24 * time.Hour
+
+// operations asynchronous operations by ID. They are kept in memory, so they are lost if the OSCAR manager restarts
+var operations = map[string]*types.Operation{}
+var operationsMutex sync.Mutex
+
+// NewOperation registers a new running operation on a service with the provided steps (all pending)
+func NewOperation(opType string, serviceName string, steps []string) *types.Operation {
+	operationsMutex.Lock()
+	defer operationsMutex.Unlock()
+
+	now := time.Now().UTC()
+	// Remove the expired operations
+	for id, op := range operations {
+		if op.Status != types.OperationRunning && now.Sub(op.UpdatedAt) > operationTTL {
+			delete(operations, id)
+		}
+	}
+
+	op := &types.Operation{
+		ID:        GenerateToken()[:32],
+		Type:      opType,
+		Service:   serviceName,
+		Status:    types.OperationRunning,
+		Steps:     []types.OperationStep{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, step := range steps {
+		op.Steps = append(op.Steps, types.OperationStep{Name: step, Status: types.OperationPending})
+	}
+	if len(op.Steps) > 0 {
+		op.Steps[0].Status = types.OperationRunning
+	}
+	operations[op.ID] = op
+
+	return copyOperation(op)
+}
+
+// UpdateOperationStep sets the result of a step of an operation, starting the next one (or skipping the
+// rest if the step failed)
+func UpdateOperationStep(id string, step string, err error) {
+	operationsMutex.Lock()
+	defer operationsMutex.Unlock()
+
+	op, ok := operations[id]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	op.UpdatedAt = now
+	for i := range op.Steps {
+		if op.Steps[i].Name != step {
+			continue
+		}
+		op.Steps[i].FinishedAt = &now
+		if err != nil {
+			op.Steps[i].Status = types.OperationFailed
+			op.Steps[i].Message = err.Error()
+			for j := i + 1; j < len(op.Steps); j++ {
+				op.Steps[j].Status = types.OperationSkipped
+			}
+			return
+		}
+		op.Steps[i].Status = types.OperationSucceeded
+		if i+1 < len(op.Steps) {
+			op.Steps[i+1].Status = types.OperationRunning
+		}
+		return
+	}
+}
+
+// FinishOperation sets the final result of an operation. The steps not reported are skipped
+func FinishOperation(id string, err error, defaultCode types.ErrorCode) {
+	operationsMutex.Lock()
+	defer operationsMutex.Unlock()
+
+	op, ok := operations[id]
+	if !ok {
+		return
+	}
+	op.UpdatedAt = time.Now().UTC()
+	op.Status = types.OperationSucceeded
+	if err != nil {
+		op.Status = types.OperationFailed
+		apiErr := types.NewAPIError(types.GetErrorCode(err, defaultCode), err.Error())
+		op.Error = &apiErr
+	}
+	for i := range op.Steps {
+		if op.Steps[i].Status == types.OperationPending || op.Steps[i].Status == types.OperationRunning {
+			op.Steps[i].Status = types.OperationSkipped
+		}
+	}
+}
+
+// GetOperation returns the progress of an operation
+func GetOperation(id string) (*types.Operation, bool) {
+	operationsMutex.Lock()
+	defer operationsMutex.Unlock()
+
+	op, ok := operations[id]
+	if !ok {
+		return nil, false
+	}
+	return copyOperation(op), true
+}
+
+// copyOperation returns a copy of the operation to be read without holding the mutex
+func copyOperation(op *types.Operation) *types.Operation {
+	copied := *op
+	copied.Steps = append([]types.OperationStep{}, op.Steps...)
+	return &copied
+}
@@ -0,0 +1,64 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// serviceUnlockTTL time that the unlock of a protected service is valid
+const serviceUnlockTTL = 5 * time.Minute
+
+// serviceUnlocks unlocks of the protected services by service name. They are kept in memory, so they are lost if
+// the OSCAR manager restarts (and are only valid in the replica that issued them)
+var serviceUnlocks = map[string]types.ServiceUnlock{}
+var serviceUnlocksMutex sync.Mutex
+
+// UnlockService unlocks a protected service for a single update or deletion within the next serviceUnlockTTL,
+// replacing its previous unlock
+func UnlockService(serviceName string, user string) types.ServiceUnlock {
+	serviceUnlocksMutex.Lock()
+	defer serviceUnlocksMutex.Unlock()
+
+	// Remove the expired unlocks
+	now := time.Now()
+	for name, unlock := range serviceUnlocks {
+		if now.After(unlock.ExpiresAt) {
+			delete(serviceUnlocks, name)
+		}
+	}
+
+	unlock := types.ServiceUnlock{Service: serviceName, UnlockedBy: user, ExpiresAt: now.Add(serviceUnlockTTL)}
+	serviceUnlocks[serviceName] = unlock
+	return unlock
+}
+
+// ConsumeServiceUnlock returns true (removing it) if the protected service has a valid unlock
+func ConsumeServiceUnlock(serviceName string) bool {
+	serviceUnlocksMutex.Lock()
+	defer serviceUnlocksMutex.Unlock()
+
+	unlock, ok := serviceUnlocks[serviceName]
+	if !ok {
+		return false
+	}
+	delete(serviceUnlocks, serviceName)
+	return time.Now().Before(unlock.ExpiresAt)
+}
@@ -0,0 +1,239 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var queueLogger = log.New(os.Stdout, "[QUEUE] ", log.Flags())
+
+// QueueJob suspends the job if the service has already max_concurrent_jobs running or if the input of the job's
+// event has reached its max_in_flight or max_events_per_minute with the "queue" policy (it is released by the queued
+// jobs releaser). Returns true if the job has been queued
+func QueueJob(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, job *batchv1.Job) bool {
+	var in *types.StorageIOConfig
+	input := getJobInput(service, job)
+	if input >= 0 && service.Input[input].HasLimits() {
+		in = &service.Input[input]
+	}
+	if service.MaxConcurrentJobs <= 0 && in == nil {
+		return false
+	}
+
+	// The job's maps are shared with the service definition
+	job.Labels = copyStringMap(job.Labels)
+	job.Annotations = copyStringMap(job.Annotations)
+
+	// Label the jobs of the inputs with limits to count them
+	if in != nil {
+		job.Labels[types.InputLabelKey] = strconv.Itoa(input)
+	}
+
+	usage, err := getJobUsage(cfg, kubeClientset, service.Name)
+	if err != nil {
+		queueLogger.Printf("Unable to check the concurrent jobs of service \"%s\": %v\n", service.Name, err)
+		return false
+	}
+	queue := service.MaxConcurrentJobs > 0 && usage.running >= service.MaxConcurrentJobs
+	queueInput := in != nil && in.GetLimitPolicy() == types.LimitPolicyQueue
+	if queueInput && usage.inputLimitExceeded(strconv.Itoa(input), in.MaxInFlight, in.MaxEventsPerMinute) != "" {
+		queue = true
+	}
+	if !queue {
+		return false
+	}
+
+	suspend := true
+	job.Spec.Suspend = &suspend
+	job.Labels[types.QueuedLabelKey] = "true"
+	job.Annotations[types.MaxConcurrentJobsAnnotation] = strconv.Itoa(service.MaxConcurrentJobs)
+	if queueInput {
+		job.Annotations[types.InputMaxInFlightAnnotation] = strconv.Itoa(in.MaxInFlight)
+		job.Annotations[types.InputMaxEventsPerMinuteAnnotation] = strconv.Itoa(in.MaxEventsPerMinute)
+	}
+	return true
+}
+
+// CheckInputLimits returns the description of the max_in_flight or max_events_per_minute limit reached by an input
+// of the service (empty if none is reached)
+func CheckInputLimits(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service, input int) (string, error) {
+	in := service.Input[input]
+	if !in.HasLimits() {
+		return "", nil
+	}
+	usage, err := getJobUsage(cfg, kubeClientset, service.Name)
+	if err != nil {
+		return "", err
+	}
+	return usage.inputLimitExceeded(strconv.Itoa(input), in.MaxInFlight, in.MaxEventsPerMinute), nil
+}
+
+// StartQueuedJobsReleaser starts the loop to release the queued jobs of the services with free slots
+// every cfg.QueuedJobsInterval
+func StartQueuedJobsReleaser(cfg *types.Config, kubeClientset kubernetes.Interface) {
+	for {
+		if err := releaseQueuedJobs(cfg, kubeClientset); err != nil {
+			queueLogger.Println(err.Error())
+		}
+
+		time.Sleep(time.Duration(cfg.QueuedJobsInterval) * time.Second)
+	}
+}
+
+// releaseQueuedJobs resumes the oldest queued jobs of each service while it has less than max_concurrent_jobs running
+func releaseQueuedJobs(cfg *types.Config, kubeClientset kubernetes.Interface) error {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,%s", types.ServiceLabel, types.QueuedLabelKey),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+
+	// Group the queued jobs by service, oldest first
+	queues := map[string][]batchv1.Job{}
+	for _, job := range jobs.Items {
+		serviceName := job.Labels[types.ServiceLabel]
+		queues[serviceName] = append(queues[serviceName], job)
+	}
+
+	for serviceName, queue := range queues {
+		sort.SliceStable(queue, func(i, j int) bool {
+			return queue[i].CreationTimestamp.Before(&queue[j].CreationTimestamp)
+		})
+
+		usage, err := getJobUsage(cfg, kubeClientset, serviceName)
+		if err != nil {
+			queueLogger.Println(err.Error())
+			continue
+		}
+
+		for i := range queue {
+			job := &queue[i]
+			// The limits when the job was queued are used, so it is released if the limits are removed
+			if limit, err := strconv.Atoi(job.Annotations[types.MaxConcurrentJobsAnnotation]); err == nil && limit > 0 && usage.running >= limit {
+				break
+			}
+			input, hasInput := job.Labels[types.InputLabelKey]
+			if hasInput {
+				maxInFlight, _ := strconv.Atoi(job.Annotations[types.InputMaxInFlightAnnotation])
+				maxEventsPerMinute, _ := strconv.Atoi(job.Annotations[types.InputMaxEventsPerMinuteAnnotation])
+				// The jobs of other inputs can be released
+				if usage.inputLimitExceeded(input, maxInFlight, maxEventsPerMinute) != "" {
+					continue
+				}
+			}
+
+			suspend := false
+			job.Spec.Suspend = &suspend
+			delete(job.Labels, types.QueuedLabelKey)
+			if _, err := kubeClientset.BatchV1().Jobs(job.Namespace).Update(context.TODO(), job, metav1.UpdateOptions{}); err != nil {
+				queueLogger.Printf("Error releasing queued job \"%s\": %v\n", job.Name, err)
+				continue
+			}
+			usage.running++
+			if hasInput {
+				usage.inFlight[input]++
+				usage.recent[input]++
+			}
+			queueLogger.Printf("Queued job \"%s\" released\n", job.Name)
+		}
+	}
+
+	return nil
+}
+
+// jobUsage usage of the job slots of a service and of its inputs
+type jobUsage struct {
+	// running unfinished and not suspended (queued or deferred) jobs of the service
+	running int
+	// inFlight unfinished and not suspended jobs of each input (by index)
+	inFlight map[string]int
+	// recent jobs of each input (by index) started in the last minute
+	recent map[string]int
+}
+
+// getJobUsage returns the usage of the job slots of a service and of its inputs
+func getJobUsage(cfg *types.Config, kubeClientset kubernetes.Interface, serviceName string) (*jobUsage, error) {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", types.ServiceLabel, serviceName),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the jobs of service \"%s\": %v", serviceName, err)
+	}
+
+	usage := &jobUsage{inFlight: map[string]int{}, recent: map[string]int{}}
+	minuteAgo := time.Now().Add(-time.Minute)
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Spec.Suspend != nil && *job.Spec.Suspend {
+			continue
+		}
+		input, hasInput := job.Labels[types.InputLabelKey]
+		// The released jobs are started when resumed
+		start := job.CreationTimestamp.Time
+		if job.Status.StartTime != nil {
+			start = job.Status.StartTime.Time
+		}
+		if hasInput && start.After(minuteAgo) {
+			usage.recent[input]++
+		}
+		if _, _, failed := getJobFailure(job); failed || isJobComplete(job) {
+			continue
+		}
+		usage.running++
+		if hasInput {
+			usage.inFlight[input]++
+		}
+	}
+	return usage, nil
+}
+
+// inputLimitExceeded returns the description of the max_in_flight or max_events_per_minute limit reached by an input
+// (empty if none is reached)
+func (usage *jobUsage) inputLimitExceeded(input string, maxInFlight int, maxEventsPerMinute int) string {
+	if maxInFlight > 0 && usage.inFlight[input] >= maxInFlight {
+		return fmt.Sprintf("the input has %d jobs in flight (max_in_flight: %d)", usage.inFlight[input], maxInFlight)
+	}
+	if maxEventsPerMinute > 0 && usage.recent[input] >= maxEventsPerMinute {
+		return fmt.Sprintf("the input has started %d jobs in the last minute (max_events_per_minute: %d)", usage.recent[input], maxEventsPerMinute)
+	}
+	return ""
+}
+
+// getJobInput returns the index of the service's input whose event (MinIO notification) created the job, or -1
+func getJobInput(service *types.Service, job *batchv1.Job) int {
+	minIOEvent, err := types.ParseMinIOEvent([]byte(GetJobEvent(job)))
+	if err != nil {
+		return -1
+	}
+	return service.GetInputIndexForObject(minIOEvent.GetBucket(), minIOEvent.GetObjectKey())
+}
@@ -0,0 +1,150 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestQueueJobs(t *testing.T) {
+	cfg := &types.Config{ServicesNamespace: "oscar-svc"}
+	kubeClientset := testclient.NewSimpleClientset()
+	service := &types.Service{Name: "test", MaxConcurrentJobs: 2, Labels: map[string]string{types.ServiceLabel: "test"}}
+
+	// Create 4 jobs, the last two are queued
+	now := time.Now()
+	for i, name := range []string{"job1", "job2", "job3", "job4"} {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         cfg.ServicesNamespace,
+				Labels:            service.Labels,
+				CreationTimestamp: metav1.NewTime(now.Add(time.Duration(i) * time.Second)),
+			},
+		}
+		queued := QueueJob(cfg, kubeClientset, service, job)
+		if queued != (i >= 2) {
+			t.Errorf("expecting job \"%s\" queued %v, got %v", name, i >= 2, queued)
+		}
+		if _, err := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Create(context.TODO(), job, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, ok := service.Labels[types.QueuedLabelKey]; ok {
+		t.Error("the labels of the service have been modified")
+	}
+
+	// No slot is free yet
+	if err := releaseQueuedJobs(cfg, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkQueued := func(expected map[string]bool) {
+		for name, queued := range expected {
+			job, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+			if _, ok := job.Labels[types.QueuedLabelKey]; ok != queued || (job.Spec.Suspend != nil && *job.Spec.Suspend) != queued {
+				t.Errorf("expecting job \"%s\" queued %v", name, queued)
+			}
+		}
+	}
+	checkQueued(map[string]bool{"job3": true, "job4": true})
+
+	// Finish a job to release the oldest queued one
+	job, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), "job1", metav1.GetOptions{})
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Update(context.TODO(), job, metav1.UpdateOptions{})
+
+	if err := releaseQueuedJobs(cfg, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkQueued(map[string]bool{"job3": false, "job4": true})
+}
+
+func TestQueueInputJobs(t *testing.T) {
+	cfg := &types.Config{ServicesNamespace: "oscar-svc"}
+	kubeClientset := testclient.NewSimpleClientset()
+	service := &types.Service{
+		Name:   "test",
+		Labels: map[string]string{types.ServiceLabel: "test"},
+		Input: []types.StorageIOConfig{
+			{Provider: "minio.default", Path: "in", MaxEventsPerMinute: 2, LimitPolicy: types.LimitPolicyQueue},
+			{Provider: "minio.default", Path: "other"},
+		},
+	}
+	newJob := func(name string, bucket string) *batchv1.Job {
+		event, _ := json.Marshal(types.NewMinIOEvent(bucket, types.MinIOEventObject{Key: name}, time.Now()))
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         cfg.ServicesNamespace,
+				Labels:            service.Labels,
+				CreationTimestamp: metav1.Now(),
+			},
+			Spec: batchv1.JobSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{Name: types.ContainerName, Env: []v1.EnvVar{{Name: types.EventVariable, Value: string(event)}}}},
+					},
+				},
+			},
+		}
+	}
+
+	// The third job of the input in the same minute is queued, the jobs of other inputs are not limited
+	for i, name := range []string{"job1", "job2", "job3"} {
+		job := newJob(name, "in")
+		if queued := QueueJob(cfg, kubeClientset, service, job); queued != (i == 2) {
+			t.Errorf("expecting job \"%s\" queued %v, got %v", name, i == 2, queued)
+		}
+		if job.Labels[types.InputLabelKey] != "0" {
+			t.Errorf("expecting job \"%s\" labeled with its input", name)
+		}
+		kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Create(context.TODO(), job, metav1.CreateOptions{})
+	}
+	if QueueJob(cfg, kubeClientset, service, newJob("job4", "other")) {
+		t.Error("expecting job \"job4\" not queued")
+	}
+
+	// The queued job is released once the previous jobs started more than a minute ago
+	if err := releaseQueuedJobs(cfg, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), "job3", metav1.GetOptions{})
+	if _, ok := job.Labels[types.QueuedLabelKey]; !ok {
+		t.Fatal("expecting job \"job3\" queued")
+	}
+	for _, name := range []string{"job1", "job2"} {
+		job, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+		job.Status.StartTime = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+		kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Update(context.TODO(), job, metav1.UpdateOptions{})
+	}
+	if err := releaseQueuedJobs(cfg, kubeClientset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job, _ = kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), "job3", metav1.GetOptions{})
+	if _, ok := job.Labels[types.QueuedLabelKey]; ok || *job.Spec.Suspend {
+		t.Error("expecting job \"job3\" released")
+	}
+}
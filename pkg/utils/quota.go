@@ -0,0 +1,279 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// voLabel label of the services' jobs with their VO
+const voLabel = "vo"
+
+// Custom logger
+var quotasLogger = log.New(os.Stdout, "[QUOTAS] ", log.Flags())
+
+// storageUsage storage usage of the VOs and users, refreshed by the storage usage tracker to check the quotas
+// without listing the buckets on each job creation
+var storageUsage = struct {
+	mutex sync.Mutex
+	usage map[string]map[string]int64
+}{usage: map[string]map[string]int64{types.QuotaScopeVO: {}, types.QuotaScopeUser: {}}}
+
+// StartStorageUsageTracker starts the loop to refresh the storage usage of the VOs and users every
+// cfg.QuotasInterval
+func StartStorageUsageTracker(cfg *types.Config, back types.ServerlessBackend) {
+	for {
+		services, err := back.ListServices()
+		if err != nil {
+			quotasLogger.Printf("error listing services: %v\n", err)
+		} else if usage, err := getAccountsStorageUsage(services); err != nil {
+			quotasLogger.Println(err.Error())
+		} else {
+			setStorageUsage(usage)
+		}
+
+		time.Sleep(time.Duration(cfg.QuotasInterval) * time.Second)
+	}
+}
+
+// GetUsageSummary computes the resources consumed by each VO and user (owner of the services) in the quotas period,
+// along with their quotas. If vo or user are not empty only their usage is returned
+func GetUsageSummary(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface, vo string, user string) (*types.UsageSummary, error) {
+	services, err := back.ListServices()
+	if err != nil {
+		return nil, fmt.Errorf("error listing services: %v", err)
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-cfg.QuotasPeriod)
+	usage, err := getAccountsJobUsage(cfg, kubeClientset, services, from, to)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := getAccountsStorageUsage(services)
+	if err != nil {
+		return nil, err
+	}
+	setStorageUsage(storage)
+	for scope, accounts := range storage {
+		for name, size := range accounts {
+			getAccountUsage(usage, scope, name).StorageBytes = size
+		}
+	}
+
+	quotas := cfg.GetQuotas()
+	filters := map[string]string{types.QuotaScopeVO: vo, types.QuotaScopeUser: user}
+	summary := &types.UsageSummary{From: from, To: to, VOs: []types.AccountUsage{}, Users: []types.AccountUsage{}}
+	for _, scope := range []string{types.QuotaScopeVO, types.QuotaScopeUser} {
+		// The accounts with quotas are listed even without usage
+		for name := range quotas[scope] {
+			getAccountUsage(usage, scope, name)
+		}
+
+		accounts := []types.AccountUsage{}
+		for name, resources := range usage[scope] {
+			if filters[scope] != "" && filters[scope] != name {
+				continue
+			}
+			account := types.AccountUsage{Name: name, ResourceUsage: *resources, Quota: quotas[scope][name]}
+			if exceeded := account.Quota.Exceeded(account.ResourceUsage); len(exceeded) > 0 {
+				account.Exceeded = exceeded
+			}
+			accounts = append(accounts, account)
+		}
+		sort.Slice(accounts, func(i, j int) bool {
+			return accounts[i].Name < accounts[j].Name
+		})
+
+		if scope == types.QuotaScopeVO {
+			summary.VOs = accounts
+		} else {
+			summary.Users = accounts
+		}
+	}
+	// Only the usage of the filtered account is returned
+	if vo != "" && user == "" {
+		summary.Users = []types.AccountUsage{}
+	}
+	if user != "" && vo == "" {
+		summary.VOs = []types.AccountUsage{}
+	}
+
+	return summary, nil
+}
+
+// CheckQuotas returns an ErrQuotaExceeded coded error if the VO or the owner of the service have reached any of
+// their quotas
+func CheckQuotas(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service) error {
+	quotas := cfg.GetQuotas()
+	accounts := map[string]string{types.QuotaScopeVO: service.VO, types.QuotaScopeUser: service.Owner}
+	checked := false
+	for scope, name := range accounts {
+		if name != "" && quotas[scope][name] != nil {
+			checked = true
+		}
+	}
+	if !checked {
+		return nil
+	}
+
+	to := time.Now().UTC()
+	usage, err := getAccountsJobUsage(cfg, kubeClientset, nil, to.Add(-cfg.QuotasPeriod), to)
+	if err != nil {
+		return fmt.Errorf("error checking the quotas of service \"%s\": %v", service.Name, err)
+	}
+
+	storageUsage.mutex.Lock()
+	defer storageUsage.mutex.Unlock()
+	for _, scope := range []string{types.QuotaScopeVO, types.QuotaScopeUser} {
+		name := accounts[scope]
+		quota := quotas[scope][name]
+		if name == "" || quota == nil {
+			continue
+		}
+		resources := *getAccountUsage(usage, scope, name)
+		resources.StorageBytes = storageUsage.usage[scope][name]
+		if exceeded := quota.Exceeded(resources); len(exceeded) > 0 {
+			return types.NewCodedError(types.ErrQuotaExceeded, fmt.Errorf("the %s \"%s\" has reached its quota of %s", getScopeDescription(scope), name, strings.Join(exceeded, ", ")))
+		}
+	}
+
+	return nil
+}
+
+// getAccountsJobUsage returns the jobs, CPU-seconds and memory-seconds consumed by each VO and user in the provided
+// period, indexed by scope and name. The jobs created before their service had an owner are accounted to the
+// current owner of the service (if services are provided)
+func getAccountsJobUsage(cfg *types.Config, kubeClientset kubernetes.Interface, services []*types.Service, from time.Time, to time.Time) (map[string]map[string]*types.ResourceUsage, error) {
+	owners := map[string]string{}
+	for _, service := range services {
+		owners[service.Name] = service.Owner
+	}
+
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), metav1.ListOptions{LabelSelector: types.ServiceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("error getting job list: %v", err)
+	}
+	pods, err := kubeClientset.CoreV1().Pods(cfg.GetJobsNamespace()).List(context.TODO(), metav1.ListOptions{LabelSelector: fmt.Sprintf("%s,job-name", types.ServiceLabel)})
+	if err != nil {
+		return nil, fmt.Errorf("error getting pod list: %v", err)
+	}
+
+	usage := map[string]map[string]*types.ResourceUsage{types.QuotaScopeVO: {}, types.QuotaScopeUser: {}}
+	jobsByName := map[string]*batchv1.Job{}
+	for i, job := range jobs.Items {
+		jobsByName[job.Name] = &jobs.Items[i]
+		if created := job.CreationTimestamp.Time; created.Before(from) || !created.Before(to) {
+			continue
+		}
+		for scope, name := range getJobAccounts(&job, owners) {
+			getAccountUsage(usage, scope, name).Jobs++
+		}
+	}
+
+	for _, pod := range pods.Items {
+		job, ok := jobsByName[pod.Labels["job-name"]]
+		if !ok {
+			continue
+		}
+		running := getPodRunningTime(&pod, from, to).Seconds()
+		if running == 0 {
+			continue
+		}
+		cpuSeconds := getContainerRequest(&pod, v1.ResourceCPU) * running
+		memorySeconds := getContainerRequest(&pod, v1.ResourceMemory) / (1 << 30) * running
+		for scope, name := range getJobAccounts(job, owners) {
+			getAccountUsage(usage, scope, name).Add(types.ResourceUsage{CPUSeconds: cpuSeconds, MemorySeconds: memorySeconds})
+		}
+	}
+
+	return usage, nil
+}
+
+// getJobAccounts returns the VO and the user accounting the usage of a job, indexed by scope
+func getJobAccounts(job *batchv1.Job, owners map[string]string) map[string]string {
+	accounts := map[string]string{}
+	if vo := job.Labels[voLabel]; vo != "" {
+		accounts[types.QuotaScopeVO] = vo
+	}
+	owner := job.Annotations[types.OwnerAnnotation]
+	if owner == "" {
+		owner = owners[job.Labels[types.ServiceLabel]]
+	}
+	if owner != "" {
+		accounts[types.QuotaScopeUser] = owner
+	}
+	return accounts
+}
+
+// getAccountsStorageUsage returns the size of the objects stored in the MinIO inputs and outputs of the services
+// of each VO and user, indexed by scope and name
+func getAccountsStorageUsage(services []*types.Service) (map[string]map[string]int64, error) {
+	usage := map[string]map[string]int64{types.QuotaScopeVO: {}, types.QuotaScopeUser: {}}
+	for _, service := range services {
+		if service.VO == "" && service.Owner == "" {
+			continue
+		}
+		size, err := getStorageUsage(service)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the storage usage of service \"%s\": %v", service.Name, err)
+		}
+		if service.VO != "" {
+			usage[types.QuotaScopeVO][service.VO] += size
+		}
+		if service.Owner != "" {
+			usage[types.QuotaScopeUser][service.Owner] += size
+		}
+	}
+	return usage, nil
+}
+
+// setStorageUsage stores the storage usage of the VOs and users to check their quotas
+func setStorageUsage(usage map[string]map[string]int64) {
+	storageUsage.mutex.Lock()
+	defer storageUsage.mutex.Unlock()
+	storageUsage.usage = usage
+}
+
+// getAccountUsage returns the usage of an account, adding it if not present
+func getAccountUsage(usage map[string]map[string]*types.ResourceUsage, scope string, name string) *types.ResourceUsage {
+	if usage[scope][name] == nil {
+		usage[scope][name] = &types.ResourceUsage{}
+	}
+	return usage[scope][name]
+}
+
+// getScopeDescription returns the description of the accounts of a quota scope
+func getScopeDescription(scope string) string {
+	if scope == types.QuotaScopeVO {
+		return "VO"
+	}
+	return scope
+}
@@ -0,0 +1,110 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckQuotas(t *testing.T) {
+	cfg := &types.Config{ServicesNamespace: "oscar-svc", QuotasPeriod: 24 * time.Hour}
+	kubeClientset := fake.NewSimpleClientset()
+
+	// Two jobs of the VO "vo.example.eu" owned by "alice", running 1 CPU and 2GiB for an hour
+	for _, name := range []string{"job-1", "job-2"} {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         cfg.ServicesNamespace,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+				Labels:            map[string]string{types.ServiceLabel: "test", voLabel: "vo.example.eu"},
+				Annotations:       map[string]string{types.OwnerAnnotation: "alice"},
+			},
+		}
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-pod",
+				Namespace: cfg.ServicesNamespace,
+				Labels:    map[string]string{types.ServiceLabel: "test", "job-name": name},
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: types.ContainerName,
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("2Gi")},
+					},
+				}},
+			},
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name: types.ContainerName,
+					State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+						StartedAt:  metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+						FinishedAt: metav1.NewTime(time.Now().Add(-time.Hour)),
+					}},
+				}},
+			},
+		}
+		kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Create(context.TODO(), job, metav1.CreateOptions{})
+		kubeClientset.CoreV1().Pods(cfg.ServicesNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	}
+	setStorageUsage(map[string]map[string]int64{types.QuotaScopeVO: {}, types.QuotaScopeUser: {"alice": 2048}})
+	defer setStorageUsage(map[string]map[string]int64{types.QuotaScopeVO: {}, types.QuotaScopeUser: {}})
+
+	service := &types.Service{Name: "test", VO: "vo.example.eu", Owner: "alice"}
+
+	scenarios := []struct {
+		name        string
+		quotas      []string
+		expectError bool
+	}{
+		{"no quotas", []string{""}, false},
+		{"other VO", []string{"vo:other.eu:jobs=1"}, false},
+		{"jobs not reached", []string{"vo:vo.example.eu:jobs=3"}, false},
+		{"jobs reached", []string{"vo:vo.example.eu:jobs=2"}, true},
+		{"cpu reached", []string{"user:alice:cpu_seconds=7200"}, true},
+		{"memory not reached", []string{"user:alice:memory_seconds=14401"}, false},
+		{"memory reached", []string{"user:alice:memory_seconds=14000"}, true},
+		{"storage reached", []string{"user:alice:storage_bytes=1024"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			cfg.Quotas = s.quotas
+			err := CheckQuotas(cfg, kubeClientset, service)
+			if s.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if code := types.GetErrorCode(err, types.ErrInternal); code != types.ErrQuotaExceeded {
+					t.Errorf("expected error code %s, got %s", types.ErrQuotaExceeded.Code, code.Code)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"log"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var reportsLogger = log.New(os.Stdout, "[REPORTS] ", log.Flags())
+
+var reportTemplate = template.Must(template.New("report").Parse(`<html>
+<body>
+<h2>OSCAR usage report</h2>
+<p>Period: {{ .From.Format "2006-01-02 15:04" }} - {{ .To.Format "2006-01-02 15:04" }} (UTC)</p>
+{{ range .VOs }}
+<h3>VO: {{ if .VO }}{{ .VO }}{{ else }}(none){{ end }}</h3>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Service</th><th>Executions</th><th>CPU-hours</th><th>Storage (bytes)</th><th>Avoided emissions (gCO2eq)</th></tr>
+{{ range .Services }}<tr><td>{{ .Name }}</td><td>{{ .Executions }}</td><td>{{ printf "%.2f" .CPUHours }}</td><td>{{ .StorageBytes }}</td><td>{{ printf "%.2f" .AvoidedEmissions }}</td></tr>
+{{ end }}<tr><th>Total</th><th>{{ .Executions }}</th><th>{{ printf "%.2f" .CPUHours }}</th><th>{{ .StorageBytes }}</th><th>{{ printf "%.2f" .AvoidedEmissions }}</th></tr>
+</table>
+{{ end }}
+</body>
+</html>
+`))
+
+// StartReportScheduler starts the loop to email the usage report of each VO to its managers every cfg.ReportsInterval
+func StartReportScheduler(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface) {
+	for {
+		time.Sleep(cfg.ReportsInterval)
+
+		to := time.Now().UTC()
+		report, err := GenerateUsageReport(cfg, back, kubeClientset, to.Add(-cfg.ReportsInterval), to)
+		if err != nil {
+			reportsLogger.Println(err.Error())
+			continue
+		}
+
+		for vo, recipients := range cfg.GetReportsRecipients() {
+			usage := report.GetVO(vo)
+			if usage == nil {
+				usage = &types.VOUsage{VO: vo, Services: []types.ServiceUsage{}}
+			}
+			voReport := &types.UsageReport{From: report.From, To: report.To, VOs: []types.VOUsage{*usage}}
+			if err := sendUsageReport(cfg, voReport, recipients); err != nil {
+				reportsLogger.Printf("error sending the usage report of VO \"%s\": %v\n", vo, err)
+			} else {
+				reportsLogger.Printf("Usage report of VO \"%s\" sent to %v\n", vo, recipients)
+			}
+		}
+	}
+}
+
+// GenerateUsageReport compiles the usage of the services per VO in the provided period
+func GenerateUsageReport(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface, from time.Time, to time.Time) (*types.UsageReport, error) {
+	services, err := back.ListServices()
+	if err != nil {
+		return nil, fmt.Errorf("error listing services: %v", err)
+	}
+
+	// List the pods of all the services' jobs
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,job-name", types.ServiceLabel),
+	}
+	pods, err := kubeClientset.CoreV1().Pods(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pod list: %v", err)
+	}
+
+	// Annotations of the jobs, to compute the emissions avoided by the deferred ones
+	jobList, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), metav1.ListOptions{LabelSelector: types.ServiceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("error getting job list: %v", err)
+	}
+	jobAnnotations := map[string]map[string]string{}
+	for _, job := range jobList.Items {
+		jobAnnotations[job.Name] = job.Annotations
+	}
+
+	usages := map[string]*types.ServiceUsage{}
+	for _, service := range services {
+		storage, err := getStorageUsage(service)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the storage usage of service \"%s\": %v", service.Name, err)
+		}
+		usages[service.Name] = &types.ServiceUsage{Name: service.Name, StorageBytes: storage}
+	}
+
+	jobs := map[string]bool{}
+	for _, pod := range pods.Items {
+		usage, ok := usages[pod.Labels[types.ServiceLabel]]
+		if !ok {
+			continue
+		}
+		if created := pod.CreationTimestamp.Time; !created.Before(from) && created.Before(to) && !jobs[pod.Labels["job-name"]] {
+			jobs[pod.Labels["job-name"]] = true
+			usage.Executions++
+		}
+		cpuHours := getPodCPUHours(&pod, from, to)
+		usage.CPUHours += cpuHours
+		usage.AvoidedEmissions += getAvoidedEmissions(cfg, jobAnnotations[pod.Labels["job-name"]], cpuHours)
+	}
+
+	report := &types.UsageReport{From: from, To: to, VOs: []types.VOUsage{}}
+	for _, service := range services {
+		usage := report.GetVO(service.VO)
+		if usage == nil {
+			report.VOs = append(report.VOs, types.VOUsage{VO: service.VO, Services: []types.ServiceUsage{}})
+			usage = &report.VOs[len(report.VOs)-1]
+		}
+		usage.Add(*usages[service.Name])
+	}
+	sort.Slice(report.VOs, func(i, j int) bool {
+		return report.VOs[i].VO < report.VOs[j].VO
+	})
+
+	return report, nil
+}
+
+// getPodCPUHours returns the CPU-hours consumed by the service container of a pod in the provided period
+func getPodCPUHours(pod *v1.Pod, from time.Time, to time.Time) float64 {
+	return getContainerRequest(pod, v1.ResourceCPU) * getPodRunningTime(pod, from, to).Hours()
+}
+
+// getPodRunningTime returns the time that the service container of a pod has been running in the provided period
+func getPodRunningTime(pod *v1.Pod, from time.Time, to time.Time) time.Duration {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != types.ContainerName {
+			continue
+		}
+
+		var start, end time.Time
+		if status.State.Running != nil {
+			start, end = status.State.Running.StartedAt.Time, to
+		} else if status.State.Terminated != nil {
+			start, end = status.State.Terminated.StartedAt.Time, status.State.Terminated.FinishedAt.Time
+		} else {
+			return 0
+		}
+		// Only count the time inside the period
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			return 0
+		}
+		return end.Sub(start)
+	}
+	return 0
+}
+
+// getContainerRequest returns the amount of a resource requested (or limited) by the service container of a pod,
+// in cores for the CPU and in bytes for the memory
+func getContainerRequest(pod *v1.Pod, resource v1.ResourceName) float64 {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != types.ContainerName {
+			continue
+		}
+		quantity, ok := c.Resources.Requests[resource]
+		if !ok {
+			if quantity, ok = c.Resources.Limits[resource]; !ok {
+				return 0
+			}
+		}
+		if resource == v1.ResourceCPU {
+			return float64(quantity.MilliValue()) / 1000
+		}
+		return float64(quantity.Value())
+	}
+	return 0
+}
+
+// getStorageUsage returns the size of the objects stored in the service's MinIO inputs and outputs
+func getStorageUsage(service *types.Service) (int64, error) {
+	var size int64
+	counted := map[string]bool{}
+	for _, storageIO := range append(append([]types.StorageIOConfig{}, service.Input...), service.Output...) {
+		provName, provID := storageIO.GetProvider()
+		if provName != types.MinIOName || service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+			continue
+		}
+		bucket, folder := storageIO.SplitPath()
+		if counted[provID+"/"+bucket+"/"+folder] {
+			continue
+		}
+		counted[provID+"/"+bucket+"/"+folder] = true
+
+		prefix := ""
+		if folder != "" {
+			prefix = folder + "/"
+		}
+		err := service.StorageProviders.MinIO[provID].GetS3Client().ListObjectsV2Pages(&s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, obj := range page.Contents {
+				size += aws.Int64Value(obj.Size)
+			}
+			return true
+		})
+		if err != nil {
+			return 0, fmt.Errorf("error listing the objects of \"%s\": %v", storageIO.Path, err)
+		}
+	}
+	return size, nil
+}
+
+// UsageReportToCSV renders the usage report as CSV (one row per service)
+func UsageReportToCSV(report *types.UsageReport) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Write([]string{"vo", "service", "executions", "cpu_hours", "storage_bytes", "avoided_emissions"})
+	for _, vo := range report.VOs {
+		for _, svc := range vo.Services {
+			w.Write([]string{vo.VO, svc.Name, strconv.Itoa(svc.Executions), strconv.FormatFloat(svc.CPUHours, 'f', 4, 64), strconv.FormatInt(svc.StorageBytes, 10), strconv.FormatFloat(svc.AvoidedEmissions, 'f', 2, 64)})
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// UsageReportToHTML renders the usage report as HTML
+func UsageReportToHTML(report *types.UsageReport) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := reportTemplate.Execute(buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sendUsageReport emails the usage report (HTML with the CSV attached) using the cluster's SMTP server
+func sendUsageReport(cfg *types.Config, report *types.UsageReport, recipients []string) error {
+	if cfg.SMTPHost == "" {
+		return fmt.Errorf("the SMTP server is not configured")
+	}
+
+	html, err := UsageReportToHTML(report)
+	if err != nil {
+		return err
+	}
+	csvReport, err := UsageReportToCSV(report)
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	htmlPart, _ := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	htmlPart.Write(html)
+	csvPart, _ := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/csv; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {"attachment; filename=\"oscar-usage-report.csv\""},
+	})
+	csvPart.Write([]byte(base64.StdEncoding.EncodeToString(csvReport)))
+	w.Close()
+
+	notification := &types.EmailNotification{
+		Host:     cfg.SMTPHost,
+		Username: cfg.SMTPUsername,
+		From:     cfg.SMTPFrom,
+		To:       recipients,
+	}
+	subject := fmt.Sprintf("[OSCAR] Usage report %s - %s", report.From.Format("2006-01-02"), report.To.Format("2006-01-02"))
+
+	return sendMail(notification, cfg.SMTPPassword, subject, fmt.Sprintf("multipart/mixed; boundary=%s", w.Boundary()), body.Bytes())
+}
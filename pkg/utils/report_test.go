@@ -0,0 +1,89 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPodCPUHours(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	makePod := func(cpu string, state v1.ContainerState) *v1.Pod {
+		return &v1.Pod{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Name: types.ContainerName,
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+						},
+					},
+				},
+			},
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{{Name: types.ContainerName, State: state}},
+			},
+		}
+	}
+	terminated := func(start, end time.Time) v1.ContainerState {
+		return v1.ContainerState{Terminated: &v1.ContainerStateTerminated{StartedAt: metav1.NewTime(start), FinishedAt: metav1.NewTime(end)}}
+	}
+
+	scenarios := []struct {
+		name     string
+		pod      *v1.Pod
+		expected float64
+	}{
+		{"terminated inside the period", makePod("2", terminated(from.Add(time.Hour), from.Add(3*time.Hour))), 4},
+		{"started before the period", makePod("500m", terminated(from.Add(-time.Hour), from.Add(2*time.Hour))), 1},
+		{"running", makePod("1", v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: metav1.NewTime(to.Add(-6 * time.Hour))}}), 6},
+		{"outside the period", makePod("1", terminated(to.Add(time.Hour), to.Add(2*time.Hour))), 0},
+		{"waiting", makePod("1", v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}), 0},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if res := getPodCPUHours(s.pod, from, to); math.Abs(res-s.expected) > 1e-9 {
+				t.Errorf("expecting %f CPU-hours, got %f", s.expected, res)
+			}
+		})
+	}
+}
+
+func TestUsageReportToCSV(t *testing.T) {
+	report := &types.UsageReport{VOs: []types.VOUsage{{VO: "vo.example.eu"}}}
+	report.VOs[0].Add(types.ServiceUsage{Name: "svc", Executions: 3, CPUHours: 1.5, StorageBytes: 1024, AvoidedEmissions: 12.5})
+
+	res, err := UsageReportToCSV(report)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "vo,service,executions,cpu_hours,storage_bytes,avoided_emissions\nvo.example.eu,svc,3,1.5000,1024,12.50\n"
+	if string(res) != expected {
+		t.Errorf("expecting %q, got %q", expected, string(res))
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/goccy/go-yaml"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NextServiceRevision returns the number of the next revision of a service definition
+func NextServiceRevision(kubeClientset kubernetes.Interface, namespace string, serviceName string) (int, error) {
+	cms, err := listRevisionConfigMaps(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return 0, err
+	}
+	next := 1
+	for _, cm := range cms {
+		if rev := getConfigMapRevision(&cm); rev >= next {
+			next = rev + 1
+		}
+	}
+	return next, nil
+}
+
+// SaveServiceRevision stores the service definition as its revision service.Revision, removing the oldest revisions
+// exceeding cfg.ServiceRevisionsLimit
+func SaveServiceRevision(cfg *types.Config, kubeClientset kubernetes.Interface, service *types.Service) error {
+	// The script is stored apart, as in the service's configMap
+	svc := *service
+	svc.Script = ""
+	fdl, err := svc.ToYAML()
+	if err != nil {
+		return err
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.RevisionConfigMapName(service.Name, service.Revision),
+			Namespace: cfg.ServicesNamespace,
+			Labels: map[string]string{
+				types.ServiceLabel:  service.Name,
+				types.RevisionLabel: strconv.Itoa(service.Revision),
+			},
+		},
+		Data: map[string]string{
+			types.ScriptFileName: service.Script,
+			types.FDLFileName:    fdl,
+		},
+	}
+	if _, err := kubeClientset.CoreV1().ConfigMaps(cfg.ServicesNamespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("error storing the revision %d of service \"%s\": %v", service.Revision, service.Name, err)
+	}
+
+	// Remove the oldest revisions
+	cms, err := listRevisionConfigMaps(kubeClientset, cfg.ServicesNamespace, service.Name)
+	if err != nil {
+		return err
+	}
+	for i := cfg.ServiceRevisionsLimit; cfg.ServiceRevisionsLimit > 0 && i < len(cms); i++ {
+		if err := kubeClientset.CoreV1().ConfigMaps(cfg.ServicesNamespace).Delete(context.TODO(), cms[i].Name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("error removing the revision \"%s\": %v", cms[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ListServiceRevisions returns the stored revisions of a service definition, newest first
+func ListServiceRevisions(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]types.ServiceRevision, error) {
+	cms, err := listRevisionConfigMaps(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []types.ServiceRevision{}
+	for _, cm := range cms {
+		service, err := getRevisionService(&cm)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, types.ServiceRevision{
+			Revision:  service.Revision,
+			CreatedAt: cm.CreationTimestamp.Time,
+			Service:   service,
+		})
+	}
+	return revisions, nil
+}
+
+// GetServiceRevision returns the service definition of a revision. The Kubernetes NotFound error is returned
+// if the revision does not exist
+func GetServiceRevision(kubeClientset kubernetes.Interface, namespace string, serviceName string, revision int) (*types.Service, error) {
+	cm, err := kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), types.RevisionConfigMapName(serviceName, revision), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return getRevisionService(cm)
+}
+
+// DeleteServiceRevisions removes all the stored revisions of a service definition
+func DeleteServiceRevisions(kubeClientset kubernetes.Interface, namespace string, serviceName string) error {
+	cms, err := listRevisionConfigMaps(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return err
+	}
+	for _, cm := range cms {
+		if err := kubeClientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), cm.Name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("error removing the revision \"%s\": %v", cm.Name, err)
+		}
+	}
+	return nil
+}
+
+// listRevisionConfigMaps returns the configMaps storing the revisions of a service definition, newest first
+func listRevisionConfigMaps(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]v1.ConfigMap, error) {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s", types.ServiceLabel, serviceName, types.RevisionLabel),
+	}
+	cms, err := kubeClientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the revisions of service \"%s\": %v", serviceName, err)
+	}
+
+	sort.Slice(cms.Items, func(i, j int) bool {
+		return getConfigMapRevision(&cms.Items[i]) > getConfigMapRevision(&cms.Items[j])
+	})
+	return cms.Items, nil
+}
+
+// getConfigMapRevision returns the revision number of a revision's configMap
+func getConfigMapRevision(cm *v1.ConfigMap) int {
+	rev, _ := strconv.Atoi(cm.Labels[types.RevisionLabel])
+	return rev
+}
+
+// getRevisionService returns the service definition stored in a revision's configMap
+func getRevisionService(cm *v1.ConfigMap) (*types.Service, error) {
+	service := &types.Service{}
+	if err := yaml.Unmarshal([]byte(cm.Data[types.FDLFileName]), service); err != nil {
+		return nil, fmt.Errorf("the revision \"%s\" cannot be read: %v", cm.Name, err)
+	}
+	service.Script = cm.Data[types.ScriptFileName]
+	service.Revision = getConfigMapRevision(cm)
+	return service, nil
+}
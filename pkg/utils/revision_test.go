@@ -0,0 +1,76 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	"k8s.io/apimachinery/pkg/api/errors"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceRevisions(t *testing.T) {
+	kubeClientset := testclient.NewSimpleClientset()
+	cfg := &types.Config{ServicesNamespace: "oscar-svc", ServiceRevisionsLimit: 2}
+
+	for i, image := range []string{"image:1", "image:2", "image:3"} {
+		rev, err := NextServiceRevision(kubeClientset, cfg.ServicesNamespace, "test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rev != i+1 {
+			t.Fatalf("expecting revision %d, got %d", i+1, rev)
+		}
+		service := &types.Service{Name: "test", Image: image, Script: "echo " + image, Revision: rev}
+		if err := SaveServiceRevision(cfg, kubeClientset, service); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Only the newest revisions are kept
+	revisions, err := ListServiceRevisions(kubeClientset, cfg.ServicesNamespace, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Revision != 3 || revisions[1].Revision != 2 {
+		t.Fatalf("expecting revisions 3 and 2, got %v", revisions)
+	}
+
+	service, err := GetServiceRevision(kubeClientset, cfg.ServicesNamespace, "test", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service.Image != "image:2" || service.Script != "echo image:2" || service.Revision != 2 {
+		t.Errorf("unexpected revision: %v", service)
+	}
+
+	if _, err := GetServiceRevision(kubeClientset, cfg.ServicesNamespace, "test", 1); !errors.IsNotFound(err) {
+		t.Errorf("expecting NotFound error for a pruned revision, got %v", err)
+	}
+
+	if rev, _ := NextServiceRevision(kubeClientset, cfg.ServicesNamespace, "other"); rev != 1 {
+		t.Errorf("expecting revision 1 for a new service, got %d", rev)
+	}
+
+	if err := DeleteServiceRevisions(kubeClientset, cfg.ServicesNamespace, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revisions, _ := ListServiceRevisions(kubeClientset, cfg.ServicesNamespace, "test"); len(revisions) != 0 {
+		t.Errorf("expecting no revisions, got %d", len(revisions))
+	}
+}
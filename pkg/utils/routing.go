@@ -0,0 +1,371 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Custom logger
+var routingLogger = log.New(os.Stdout, "[OUTPUT-ROUTING] ", log.Flags())
+
+// StageJobOutputs returns a copy of the service whose outputs are staged in the upload staging bucket, to be routed
+// by the tags of the files once the job finishes
+func StageJobOutputs(cfg *types.Config, service *types.Service, jobName string) (*types.Service, error) {
+	if err := createStagingBucket(cfg); err != nil {
+		return nil, err
+	}
+	return service.WithOutputStaging(cfg.UploadStagingBucket, jobName), nil
+}
+
+// InlineDelivery delivery of the inline outputs of the jobs (services' inline_outputs) to their chained services
+type InlineDelivery struct {
+	// Services services that can be chained to the jobs' services
+	Services []*types.Service
+	// Run creates a job of a chained service to process the event
+	Run func(service *types.Service, event string) error
+}
+
+// StartOutputRouter starts the loop to route the staged outputs of the finished jobs to the outputs of their services
+// (or to the jobs of their chained services with run) every cfg.OutputRoutingInterval, or as soon as a job finishes
+func StartOutputRouter(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface, run func(service *types.Service, event string) error) {
+	events, cancel := SubscribeLifecycleEvents()
+	defer cancel()
+	ticker := time.NewTicker(time.Duration(cfg.OutputRoutingInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if err := routeFinishedJobs(cfg, back, kubeClientset, run); err != nil {
+			routingLogger.Println(err.Error())
+		}
+
+		// Wait for the next interval or for a finished job
+		for waiting := true; waiting; {
+			select {
+			case <-ticker.C:
+				waiting = false
+			case event := <-events:
+				waiting = event.Type != types.JobSucceededEvent && event.Type != types.JobFailedEvent
+			}
+		}
+	}
+}
+
+// routeFinishedJobs routes the staged outputs of the finished jobs, labelling them as routed
+func routeFinishedJobs(cfg *types.Config, back types.ServerlessBackend, kubeClientset kubernetes.Interface, run func(service *types.Service, event string) error) error {
+	listOpts := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,%s=%s", types.ServiceLabel, types.OutputRoutingLabel, types.OutputRoutingPending),
+	}
+	jobs, err := kubeClientset.BatchV1().Jobs(cfg.GetJobsNamespace()).List(context.TODO(), listOpts)
+	if err != nil {
+		return fmt.Errorf("error getting job list: %v", err)
+	}
+
+	// Map to store services' pointers
+	svcPtrs := map[string]*types.Service{}
+	// Delivery of the inline outputs (the services are listed once, if needed)
+	var inline *InlineDelivery
+
+	for _, job := range jobs.Items {
+		if !isJobFinished(&job) {
+			continue
+		}
+
+		serviceName := job.Labels[types.ServiceLabel]
+		if _, ok := svcPtrs[serviceName]; !ok {
+			svcPtrs[serviceName], err = back.ReadService(serviceName)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				routingLogger.Printf("error getting service \"%s\": %v\n", serviceName, err)
+				delete(svcPtrs, serviceName)
+				continue
+			}
+		}
+
+		// The staged outputs of the removed services are discarded
+		if service := svcPtrs[serviceName]; service != nil {
+			if service.InlineOutputs != nil && inline == nil && run != nil {
+				services, err := back.ListServices()
+				if err != nil {
+					routingLogger.Printf("error listing the chained services: %v\n", err)
+					continue
+				}
+				inline = &InlineDelivery{Services: services, Run: run}
+			}
+			if err := RouteJobOutputs(cfg, service, job.Name, inline); err != nil {
+				routingLogger.Printf("error routing the outputs of job \"%s\": %v\n", job.Name, err)
+				continue
+			}
+		} else if err := deleteStagedOutputs(cfg, serviceName, job.Name); err != nil {
+			routingLogger.Println(err.Error())
+			continue
+		}
+
+		// Mark the job as routed
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{"%s":"%s"}}}`, types.OutputRoutingLabel, types.OutputRoutingDone))
+		if _, err := kubeClientset.BatchV1().Jobs(job.Namespace).Patch(context.TODO(), job.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			routingLogger.Printf("error labelling the routed job \"%s\": %v\n", job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// isJobFinished checks if the job has succeeded or (permanently) failed
+func isJobFinished(job *batchv1.Job) bool {
+	_, _, failed := getJobFailure(job)
+	return failed || job.Status.Succeeded > 0
+}
+
+// RouteJobOutputs copies the staged outputs of a job to the outputs of the service matching their tags (see
+// Service.GetRoutedOutputs), removing them from the staging path. With the service's inline_outputs, the files that
+// would trigger the jobs of the chained services are passed to them (inline) instead
+func RouteJobOutputs(cfg *types.Config, service *types.Service, jobName string, inline *InlineDelivery) error {
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	prefix := getStagedOutputsPrefix(service.Name, jobName)
+
+	objects := []*s3.Object{}
+	err := s3Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(cfg.UploadStagingBucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		objects = append(objects, page.Contents...)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("error listing the staged outputs: %v", err)
+	}
+
+	// The files not in the manifest (or without manifest) have no tags
+	tags := map[string][]string{}
+	manifest, err := getOutputManifest(s3Client, cfg.UploadStagingBucket, prefix+types.OutputManifestName)
+	if err != nil {
+		routingLogger.Printf("Invalid output manifest of job \"%s\": %v\n", jobName, err)
+	} else if manifest != nil {
+		tags = manifest.GetTags()
+	}
+
+	for _, obj := range objects {
+		key := aws.StringValue(obj.Key)
+		name := strings.TrimPrefix(key, prefix)
+		// The files passed by reference are kept in the staging bucket (removed by its lifecycle rule)
+		referenced := false
+		if name != types.OutputManifestName {
+			for _, out := range service.GetRoutedOutputs(name, tags[name]) {
+				delivered, err := deliverInlineOutput(cfg, service, inline, out, obj, name)
+				if err != nil {
+					routingLogger.Printf("error passing the output \"%s\" of job \"%s\" inline: %v\n", name, jobName, err)
+				}
+				if delivered {
+					referenced = referenced || service.InlineOutputs.GetMode() == types.InlineOutputsReference
+					continue
+				}
+				if err := copyStagedOutput(cfg, service, key, out, name); err != nil {
+					return err
+				}
+			}
+		}
+		if referenced {
+			continue
+		}
+		if _, err := s3Client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(cfg.UploadStagingBucket), Key: aws.String(key)}); err != nil {
+			return fmt.Errorf("error removing the staged output \"%s\": %v", key, err)
+		}
+	}
+
+	routingLogger.Printf("Outputs of job \"%s\" of service \"%s\" routed\n", jobName, service.Name)
+	return nil
+}
+
+// deliverInlineOutput passes a staged output file to the jobs of the chained services that would be triggered by
+// storing it in the output, in base64 or by reference (the services' inline_outputs). Returns false if the file has
+// to be stored in the output (no inline outputs, no chained services or a file too large)
+func deliverInlineOutput(cfg *types.Config, service *types.Service, inline *InlineDelivery, out types.StorageIOConfig, obj *s3.Object, name string) (bool, error) {
+	if inline == nil || service.InlineOutputs == nil {
+		return false, nil
+	}
+	if provName, provID := out.GetProvider(); provName != types.MinIOName || provID != types.DefaultProvider {
+		return false, nil
+	}
+	mode := service.InlineOutputs.GetMode()
+	size := aws.Int64Value(obj.Size)
+	if mode == types.InlineOutputsBase64 && size > service.InlineOutputs.GetMaxSize(cfg) {
+		return false, nil
+	}
+
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	head, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(cfg.UploadStagingBucket), Key: obj.Key})
+	if err != nil {
+		return false, fmt.Errorf("error reading the staged output \"%s\": %v", aws.StringValue(obj.Key), err)
+	}
+	contentType := aws.StringValue(head.ContentType)
+
+	bucket, folder := out.SplitPath()
+	destKey := path.Join(folder, name)
+	chained := []*types.Service{}
+	for _, svc := range inline.Services {
+		if svc.AcceptsInlineOutput(bucket, destKey, size, contentType) {
+			chained = append(chained, svc)
+		}
+	}
+	if len(chained) == 0 {
+		return false, nil
+	}
+
+	var event string
+	if mode == types.InlineOutputsBase64 {
+		res, err := s3Client.GetObject(&s3.GetObjectInput{Bucket: aws.String(cfg.UploadStagingBucket), Key: obj.Key})
+		if err != nil {
+			return false, fmt.Errorf("error reading the staged output \"%s\": %v", aws.StringValue(obj.Key), err)
+		}
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			return false, fmt.Errorf("error reading the staged output \"%s\": %v", aws.StringValue(obj.Key), err)
+		}
+		event = base64.StdEncoding.EncodeToString(data)
+	} else {
+		object := types.MinIOEventObject{Key: aws.StringValue(obj.Key), Size: size, ETag: aws.StringValue(obj.ETag), ContentType: contentType}
+		data, _ := json.Marshal(types.NewMinIOEvent(cfg.UploadStagingBucket, object, time.Now()))
+		event = string(data)
+	}
+
+	// The file is not stored in the output, so a failed job is not retried by a MinIO notification
+	for _, svc := range chained {
+		if err := inline.Run(svc, event); err != nil {
+			routingLogger.Printf("error creating the job of the chained service \"%s\": %v\n", svc.Name, err)
+			continue
+		}
+		routingLogger.Printf("Output \"%s\" of service \"%s\" passed inline to service \"%s\"\n", name, service.Name, svc.Name)
+	}
+	return true, nil
+}
+
+// getStagedOutputsPrefix returns the key prefix of the staged outputs of a job in the upload staging bucket
+func getStagedOutputsPrefix(serviceName string, jobName string) string {
+	return path.Join(types.OutputRoutingPrefix, serviceName, jobName) + "/"
+}
+
+// getOutputManifest reads the output manifest of a job (nil if the script has not written it)
+func getOutputManifest(s3Client *s3.S3, bucket string, key string) (*types.OutputManifest, error) {
+	out, err := s3Client.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	manifest := &types.OutputManifest{}
+	if err := json.NewDecoder(out.Body).Decode(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// copyStagedOutput copies a staged output file to an output of the service, keeping its path relative to the
+// output folder
+func copyStagedOutput(cfg *types.Config, service *types.Service, key string, out types.StorageIOConfig, name string) error {
+	provName, provID := out.GetProvider()
+	bucket, folder := out.SplitPath()
+	destKey := path.Join(folder, name)
+
+	// Objects staged in the same MinIO are copied by the server
+	if provName == types.MinIOName && provID == types.DefaultProvider {
+		_, err := cfg.MinIOProvider.GetS3Client().CopyObject(&s3.CopyObjectInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(destKey),
+			CopySource: aws.String(path.Join(cfg.UploadStagingBucket, key)),
+		})
+		if err != nil {
+			return fmt.Errorf("error copying the output \"%s\" to \"%s\": %v", name, out.Path, err)
+		}
+		return nil
+	}
+
+	var destClient *s3.S3
+	if service.StorageProviders != nil && provName == types.MinIOName && service.StorageProviders.MinIO[provID] != nil {
+		destClient = service.StorageProviders.MinIO[provID].GetS3Client()
+	} else if service.StorageProviders != nil && provName == types.S3Name && service.StorageProviders.S3[provID] != nil {
+		destClient = service.StorageProviders.S3[provID].GetS3Client()
+	} else {
+		return fmt.Errorf("the storage provider \"%s\" of the output \"%s\" is not defined", out.Provider, out.Path)
+	}
+
+	obj, err := cfg.MinIOProvider.GetS3Client().GetObject(&s3.GetObjectInput{Bucket: aws.String(cfg.UploadStagingBucket), Key: aws.String(key)})
+	if err != nil {
+		return fmt.Errorf("error reading the staged output \"%s\": %v", key, err)
+	}
+	defer obj.Body.Close()
+
+	_, err = s3manager.NewUploaderWithClient(destClient).Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(destKey),
+		Body:        obj.Body,
+		ContentType: obj.ContentType,
+	})
+	if err != nil {
+		return fmt.Errorf("error copying the output \"%s\" to \"%s\": %v", name, out.Path, err)
+	}
+	return nil
+}
+
+// deleteStagedOutputs removes the staged outputs of a job
+func deleteStagedOutputs(cfg *types.Config, serviceName string, jobName string) error {
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	prefix := getStagedOutputsPrefix(serviceName, jobName)
+	var deleteErr error
+	err := s3Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(cfg.UploadStagingBucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			if _, err := s3Client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(cfg.UploadStagingBucket), Key: obj.Key}); err != nil {
+				deleteErr = err
+				return false
+			}
+		}
+		return true
+	})
+	if err == nil {
+		err = deleteErr
+	}
+	if err != nil {
+		return fmt.Errorf("error removing the staged outputs of job \"%s\": %v", jobName, err)
+	}
+	return nil
+}
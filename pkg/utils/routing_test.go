@@ -0,0 +1,225 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/chaos"
+	"github.com/grycap/oscar/v2/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRouteFinishedJobs(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	minIOProvider := &types.MinIOProvider{
+		Endpoint:  s3Server.URL,
+		Region:    "us-east-1",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Verify:    true,
+	}
+	cfg := &types.Config{
+		ServicesNamespace:   "oscar-svc",
+		UploadStagingBucket: "oscar-uploads",
+		MinIOProvider:       minIOProvider,
+	}
+	for _, bucket := range []string{"oscar-uploads", "reports", "previews", "others"} {
+		s3Server.CreateBucket(bucket)
+	}
+
+	service := &types.Service{
+		Name: "test",
+		Output: []types.StorageIOConfig{
+			{Provider: "minio.default", Path: "reports/out"},
+			{Provider: "minio.public", Path: "previews/out", Suffix: []string{".png"}},
+			{Provider: "minio.misc", Path: "others/out"},
+		},
+		OutputRoutes: []types.OutputRoute{
+			{Tag: "report", Output: "minio.default"},
+			{Tag: "preview", Output: "minio.public"},
+		},
+		StorageProviders: &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{
+			types.DefaultProvider: minIOProvider,
+			"public":              minIOProvider,
+			"misc":                minIOProvider,
+		}},
+	}
+	back := &fakeServiceBackend{services: map[string]*types.Service{"test": service}}
+
+	// Staged outputs of the finished job
+	manifest := `{"files": [{"name": "report.pdf", "tags": ["report"]}, {"name": "dir/thumb.png", "tags": ["preview", "report"]}, {"name": "thumb.jpg", "tags": ["preview"]}]}`
+	for key, data := range map[string]string{
+		types.OutputManifestName: manifest,
+		"report.pdf":             "report",
+		"dir/thumb.png":          "thumb",
+		"thumb.jpg":              "thumb",
+		"log.txt":                "log",
+	} {
+		s3Server.PutObject("oscar-uploads", "routing/test/finished/"+key, chaos.S3Object{Data: []byte(data)})
+	}
+	s3Server.PutObject("oscar-uploads", "routing/test/running/log.txt", chaos.S3Object{Data: []byte("log")})
+
+	newJob := func(name string, succeeded int32) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cfg.ServicesNamespace,
+				Labels:    map[string]string{types.ServiceLabel: "test", types.OutputRoutingLabel: types.OutputRoutingPending},
+			},
+			Status: batchv1.JobStatus{Succeeded: succeeded},
+		}
+	}
+	kubeClientset := testclient.NewSimpleClientset(newJob("finished", 1), newJob("running", 0))
+
+	if err := routeFinishedJobs(cfg, back, kubeClientset, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]bool{
+		"reports/out/report.pdf":     true,
+		"reports/out/dir/thumb.png":  true,
+		"previews/out/dir/thumb.png": true,
+		// The suffix filter of the output is applied
+		"previews/out/thumb.jpg": false,
+		// Untagged files are routed to the outputs without routes
+		"others/out/log.txt":    true,
+		"reports/out/log.txt":   false,
+		"others/out/report.pdf": false,
+		"others/out/thumb.jpg":  false,
+	}
+	for object, exists := range expected {
+		bucket, key := types.StorageIOConfig{Path: object}.SplitPath()
+		if _, ok := s3Server.GetObject(bucket, key); ok != exists {
+			t.Errorf("expected object \"%s\" to exist: %v", object, exists)
+		}
+	}
+	if _, ok := s3Server.GetObject("oscar-uploads", "routing/test/finished/report.pdf"); ok {
+		t.Error("expected the staged outputs to be removed")
+	}
+	if _, ok := s3Server.GetObject("oscar-uploads", "routing/test/running/log.txt"); !ok {
+		t.Error("expected the outputs of the running job to be kept")
+	}
+
+	for job, label := range map[string]string{"finished": types.OutputRoutingDone, "running": types.OutputRoutingPending} {
+		res, _ := kubeClientset.BatchV1().Jobs(cfg.ServicesNamespace).Get(context.TODO(), job, metav1.GetOptions{})
+		if res.Labels[types.OutputRoutingLabel] != label {
+			t.Errorf("expected the job \"%s\" to be labelled as %s, got %s", job, label, res.Labels[types.OutputRoutingLabel])
+		}
+	}
+}
+
+func TestRouteJobOutputsInline(t *testing.T) {
+	s3Server := chaos.NewS3Server()
+	defer s3Server.Close()
+	minIOProvider := &types.MinIOProvider{
+		Endpoint:  s3Server.URL,
+		Region:    "us-east-1",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Verify:    true,
+	}
+	cfg := &types.Config{
+		ServicesNamespace:    "oscar-svc",
+		UploadStagingBucket:  "oscar-uploads",
+		MinIOProvider:        minIOProvider,
+		InlineOutputsMaxSize: 1024,
+	}
+	s3Server.CreateBucket("oscar-uploads")
+	s3Server.CreateBucket("chain")
+
+	service := &types.Service{
+		Name: "producer",
+		Output: []types.StorageIOConfig{
+			{Provider: "minio.default", Path: "chain/in"},
+		},
+		InlineOutputs:    &types.InlineOutputs{MaxSize: 10},
+		StorageProviders: &types.StorageProviders{MinIO: map[string]*types.MinIOProvider{types.DefaultProvider: minIOProvider}},
+	}
+	consumer := &types.Service{
+		Name:  "consumer",
+		Input: []types.StorageIOConfig{{Provider: "minio.default", Path: "chain/in", Suffix: []string{".txt"}}},
+	}
+	events := map[string][]string{}
+	inline := &InlineDelivery{
+		Services: []*types.Service{service, consumer},
+		Run: func(service *types.Service, event string) error {
+			events[service.Name] = append(events[service.Name], event)
+			return nil
+		},
+	}
+
+	stage := func(job string) {
+		for key, data := range map[string]string{
+			"small.txt": "small",
+			"large.txt": "larger than the maximum size",
+			"image.png": "png",
+		} {
+			s3Server.PutObject("oscar-uploads", "routing/producer/"+job+"/"+key, chaos.S3Object{Data: []byte(data)})
+		}
+	}
+
+	// base64 mode: only the small files accepted by the consumer are passed inline
+	stage("job-base64")
+	if err := RouteJobOutputs(cfg, service, "job-base64", inline); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events["consumer"]) != 1 || events["consumer"][0] != base64.StdEncoding.EncodeToString([]byte("small")) {
+		t.Errorf("unexpected events of the consumer: %v", events["consumer"])
+	}
+	if len(events["producer"]) != 0 {
+		t.Errorf("unexpected events of the producer: %v", events["producer"])
+	}
+	for key, exists := range map[string]bool{"in/small.txt": false, "in/large.txt": true, "in/image.png": true} {
+		if _, ok := s3Server.GetObject("chain", key); ok != exists {
+			t.Errorf("expected object \"%s\" to exist: %v", key, exists)
+		}
+	}
+	if _, ok := s3Server.GetObject("oscar-uploads", "routing/producer/job-base64/small.txt"); ok {
+		t.Error("expected the staged outputs to be removed")
+	}
+
+	// reference mode: the staged files are passed by their MinIO events and kept
+	events = map[string][]string{}
+	service.InlineOutputs = &types.InlineOutputs{Mode: types.InlineOutputsReference}
+	stage("job-reference")
+	if err := RouteJobOutputs(cfg, service, "job-reference", inline); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events["consumer"]) != 2 {
+		t.Fatalf("expected 2 events of the consumer, got %d", len(events["consumer"]))
+	}
+	for _, event := range events["consumer"] {
+		minioEvent := &types.MinIOEvent{}
+		if err := json.Unmarshal([]byte(event), minioEvent); err != nil {
+			t.Fatalf("unexpected error decoding the event: %v", err)
+		}
+		key := minioEvent.GetObjectKey()
+		if _, ok := s3Server.GetObject("oscar-uploads", key); !ok {
+			t.Errorf("expected the referenced object \"%s\" to be kept", key)
+		}
+	}
+	if _, ok := s3Server.GetObject("oscar-uploads", "routing/producer/job-reference/image.png"); ok {
+		t.Error("expected the staged outputs not passed inline to be removed")
+	}
+}
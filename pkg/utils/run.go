@@ -0,0 +1,162 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+const (
+	// runInputsPrefix prefix of the staging bucket where the files uploaded in the sync invocations are staged
+	runInputsPrefix = "run"
+	// runIDLength length of the random ID prefixing the names of the staged files (and of their outputs)
+	runIDLength = 32
+	// runIDSeparator separator of the random ID and the name of the staged files
+	runIDSeparator = "-"
+)
+
+// StageRunInput stores a file uploaded in a sync invocation of the service in a temporary prefix of the staging bucket
+// ("run/<SERVICE>/<ID>/<ID>-<NAME>"), returning the event object to invoke the service with. The random ID prefixes
+// the name of the file, so that the outputs stored from it (named after the input) can be told apart from the ones
+// of other invocations
+func StageRunInput(cfg *types.Config, serviceName string, name string, size int64, contentType string, body io.ReadSeeker) (types.MinIOEventObject, error) {
+	if err := createStagingBucket(cfg); err != nil {
+		return types.MinIOEventObject{}, err
+	}
+
+	id := GenerateToken()[:runIDLength]
+	object := types.MinIOEventObject{
+		Key:         path.Join(runInputsPrefix, serviceName, id, id+runIDSeparator+path.Base(name)),
+		Size:        size,
+		ContentType: contentType,
+	}
+	_, err := cfg.MinIOProvider.GetS3Client().PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(cfg.UploadStagingBucket),
+		Key:         aws.String(object.Key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return types.MinIOEventObject{}, fmt.Errorf("error staging the file \"%s\": %v", name, err)
+	}
+	return object, nil
+}
+
+// DeleteRunInput removes a file staged by StageRunInput
+func DeleteRunInput(cfg *types.Config, key string) error {
+	_, err := cfg.MinIOProvider.GetS3Client().DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(cfg.UploadStagingBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("error removing the staged file \"%s\": %v", key, err)
+	}
+	return nil
+}
+
+// GetRunOutput returns the latest object stored in the service's MinIO outputs since the invocation with the input
+// file inputKey (see forEachOutputObject) and its key. A nil object is returned if no output has been found
+func GetRunOutput(service *types.Service, inputKey string, since time.Time) (*s3.GetObjectOutput, string, error) {
+	client, bucket, latest := findRunOutput(service, inputKey, since)
+	if latest == nil {
+		return nil, "", nil
+	}
+
+	out, err := client.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: latest.Key})
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting the output \"%s/%s\": %v", bucket, aws.StringValue(latest.Key), err)
+	}
+	return out, aws.StringValue(latest.Key), nil
+}
+
+// CopyRunOutput copies the latest output of the sync invocation with the input file inputKey (see GetRunOutput) to
+// the destination of an output_override, which must be in the same MinIO server. Returns the key of the copy (empty
+// if no output has been found)
+func CopyRunOutput(service *types.Service, dest types.StorageIOConfig, inputKey string, since time.Time) (string, error) {
+	_, bucket, latest := findRunOutput(service, inputKey, since)
+	if latest == nil {
+		return "", nil
+	}
+	_, provID := dest.GetProvider()
+	if service.StorageProviders == nil || service.StorageProviders.MinIO[provID] == nil {
+		return "", fmt.Errorf("the StorageProvider \"%s\" is not defined", dest.Provider)
+	}
+
+	key := aws.StringValue(latest.Key)
+	destBucket, destFolder := dest.SplitPath()
+	destKey := path.Join(destFolder, path.Base(key))
+	_, err := service.StorageProviders.MinIO[provID].GetS3Client().CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(destBucket),
+		Key:        aws.String(destKey),
+		CopySource: aws.String((&url.URL{Path: path.Join(bucket, key)}).EscapedPath()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error copying the output \"%s/%s\" to \"%s\": %v", bucket, key, dest.Path, err)
+	}
+	return destKey, nil
+}
+
+// RunOutputName returns the name of an output of a sync invocation without the random ID of its input file
+func RunOutputName(key string) string {
+	name := path.Base(key)
+	if id := runInputID(key); id != "" {
+		return strings.TrimPrefix(name, id+runIDSeparator)
+	}
+	return name
+}
+
+// runInputID returns the random ID prefixing the name of a file staged by StageRunInput (or of its outputs), empty
+// if the name has no ID
+func runInputID(key string) string {
+	id, _, found := strings.Cut(path.Base(key), runIDSeparator)
+	if !found || len(id) != runIDLength {
+		return ""
+	}
+	return id
+}
+
+// findRunOutput returns the latest object stored in the service's MinIO outputs since the invocation with the input
+// file inputKey, along with its bucket and the client of its provider. Only the objects whose name starts with the
+// random ID of the input file are considered
+func findRunOutput(service *types.Service, inputKey string, since time.Time) (*s3.S3, string, *s3.Object) {
+	var client *s3.S3
+	var bucket string
+	var latest *s3.Object
+	id := runInputID(inputKey)
+	if id == "" {
+		return client, bucket, latest
+	}
+	match := func(name string) bool {
+		return runInputID(name) == id
+	}
+	// The modification times of the objects are truncated to seconds
+	forEachOutputObject(service, match, since.Truncate(time.Second), time.Now(), func(s3Client *s3.S3, b string, obj *s3.Object) {
+		if latest == nil || aws.TimeValue(obj.LastModified).After(aws.TimeValue(latest.LastModified)) {
+			client, bucket, latest = s3Client, b, obj
+		}
+	})
+	return client, bucket, latest
+}
@@ -0,0 +1,55 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunOutputName(t *testing.T) {
+	id := strings.Repeat("a", runIDLength)
+	scenarios := []struct {
+		key          string
+		expectedID   string
+		expectedName string
+	}{
+		{"results/" + id + "-image.png", id, "image.png"},
+		{"results/" + id + "-", id, ""},
+		{"results/image-" + id + ".png", "", "image-" + id + ".png"},
+		{"results/image.png", "", "image.png"},
+	}
+
+	for _, s := range scenarios {
+		if id := runInputID(s.key); id != s.expectedID {
+			t.Errorf("expecting ID \"%s\" for \"%s\", got \"%s\"", s.expectedID, s.key, id)
+		}
+		if name := RunOutputName(s.key); name != s.expectedName {
+			t.Errorf("expecting name \"%s\" for \"%s\", got \"%s\"", s.expectedName, s.key, name)
+		}
+	}
+}
+
+func TestInputNameMatcher(t *testing.T) {
+	match := inputNameMatcher("uploads/image.jpg")
+	if !match("image.png") || !match("image_small.png") || match("other.png") {
+		t.Error("expecting the outputs to be matched by the name of the input")
+	}
+	if match := inputNameMatcher("uploads/.jpg"); match("image.png") || match(".png") {
+		t.Error("expecting no output to be matched by an input without name")
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// getServiceSecretValue returns the value of a field of a secret referenced by a service.
+// The secret must be labelled with the name of the service (types.ServiceLabel) to avoid using other services' secrets
+func getServiceSecretValue(kubeClientset kubernetes.Interface, namespace string, serviceName string, secretName string, key string) ([]byte, error) {
+	secret, err := kubeClientset.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting the secret \"%s\": %v", secretName, err)
+	}
+	if secret.Labels[types.ServiceLabel] != serviceName {
+		return nil, fmt.Errorf("the secret \"%s\" must have the label \"%s=%s\" to be used by the service", secretName, types.ServiceLabel, serviceName)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("the secret \"%s\" does not contain the field \"%s\"", secretName, key)
+	}
+	return value, nil
+}
+
+// ResolveSecretReferences replaces the credentials of the service referencing a secret field ("secret://<name>/<key>")
+// with the value of the field. The secrets must be labelled with the name of the service
+func ResolveSecretReferences(kubeClientset kubernetes.Interface, namespace string, service *types.Service) error {
+	return service.RewriteCredentials(func(key string, value string) (string, error) {
+		secretName, secretKey, ok := types.ParseSecretReference(value)
+		if !ok {
+			return value, nil
+		}
+		resolved, err := getServiceSecretValue(kubeClientset, namespace, service.Name, secretName, secretKey)
+		if err != nil {
+			return "", fmt.Errorf("error resolving the credential \"%s\": %v", key, err)
+		}
+		return string(resolved), nil
+	})
+}
+
+// SetServiceSecrets replaces the secrets of a service, stored in its Kubernetes secret (removed if there are no secrets)
+func SetServiceSecrets(kubeClientset kubernetes.Interface, namespace string, serviceName string, secrets map[string]string) error {
+	if len(secrets) == 0 {
+		return DeleteServiceSecrets(kubeClientset, namespace, serviceName)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.ServiceSecretsName(serviceName),
+			Namespace: namespace,
+			Labels: map[string]string{
+				types.ServiceLabel: serviceName,
+			},
+		},
+		Data: map[string][]byte{},
+	}
+	for name, value := range secrets {
+		secret.Data[name] = []byte(value)
+	}
+
+	_, err := kubeClientset.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = kubeClientset.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error storing the secrets of service \"%s\": %v", serviceName, err)
+	}
+	return nil
+}
+
+// GetServiceSecretNames returns the sorted names of the secrets of a service
+func GetServiceSecretNames(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]string, error) {
+	secret, err := kubeClientset.CoreV1().Secrets(namespace).Get(context.TODO(), types.ServiceSecretsName(serviceName), metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("error getting the secrets of service \"%s\": %v", serviceName, err)
+	}
+
+	secrets := types.ServiceSecrets{Secrets: map[string]string{}}
+	for name := range secret.Data {
+		secrets.Secrets[name] = ""
+	}
+	return secrets.GetNames(), nil
+}
+
+// DeleteServiceSecrets removes the Kubernetes secret storing the secrets of a service (if any)
+func DeleteServiceSecrets(kubeClientset kubernetes.Interface, namespace string, serviceName string) error {
+	err := kubeClientset.CoreV1().Secrets(namespace).Delete(context.TODO(), types.ServiceSecretsName(serviceName), metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("error removing the secrets of service \"%s\": %v", serviceName, err)
+	}
+	return nil
+}
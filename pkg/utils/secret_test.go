@@ -0,0 +1,67 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveSecretReferences(t *testing.T) {
+	kubeClientset := testclient.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-credentials", Namespace: "oscar-svc", Labels: map[string]string{types.ServiceLabel: "test"}},
+			Data:       map[string][]byte{"s3.aws.secret_key": []byte("s3-secret")},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-credentials", Namespace: "oscar-svc", Labels: map[string]string{types.ServiceLabel: "other"}},
+			Data:       map[string][]byte{"s3.aws.secret_key": []byte("other-secret")},
+		},
+	)
+
+	makeService := func(secretKey string) *types.Service {
+		return &types.Service{
+			Name: "test",
+			StorageProviders: &types.StorageProviders{
+				S3: map[string]*types.S3Provider{"aws": {AccessKey: "access", SecretKey: secretKey}},
+			},
+		}
+	}
+
+	service := makeService("secret://test-credentials/s3.aws.secret_key")
+	if err := ResolveSecretReferences(kubeClientset, "oscar-svc", service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service.StorageProviders.S3["aws"].SecretKey != "s3-secret" {
+		t.Errorf("expecting the resolved secret, got %s", service.StorageProviders.S3["aws"].SecretKey)
+	}
+
+	service = makeService("inline-secret")
+	if err := ResolveSecretReferences(kubeClientset, "oscar-svc", service); err != nil || service.StorageProviders.S3["aws"].SecretKey != "inline-secret" {
+		t.Errorf("expecting the inline secret to be kept, got %s (%v)", service.StorageProviders.S3["aws"].SecretKey, err)
+	}
+
+	for _, ref := range []string{"secret://missing/key", "secret://test-credentials/missing", "secret://other-credentials/s3.aws.secret_key"} {
+		if err := ResolveSecretReferences(kubeClientset, "oscar-svc", makeService(ref)); err == nil {
+			t.Errorf("%s: expecting error", ref)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+const (
+	// minIOMetricsPath path of the cluster metrics (Prometheus format) of MinIO
+	minIOMetricsPath = "/minio/v2/metrics/cluster"
+	// minIOMetricsTimeout timeout of the requests to the metrics of MinIO
+	minIOMetricsTimeout = 10 * time.Second
+	// minIONotifyTargetPrefix prefix of the metrics of the MinIO notification targets
+	minIONotifyTargetPrefix = "minio_notify_target_"
+)
+
+// minIONotifyMetrics metrics of the notification targets (named after the service of the webhook) by their
+// meaning, including the names of the different MinIO releases
+var minIONotifyMetrics = map[string]string{
+	"total_events":             "total",
+	"total_requests":           "total",
+	"failed_events":            "failed",
+	"failed_requests":          "failed",
+	"queue_length":             "pending",
+	"current_send_in_progress": "pending",
+}
+
+// GetTriggerDeliveryStats scrapes the metrics of the OSCAR's MinIO and returns the delivery stats of the webhooks
+// of the services by their name (the ID of the notification target)
+func GetTriggerDeliveryStats(cfg *types.Config) (map[string]*types.TriggerDeliveryStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), minIOMetricsTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cfg.MinIOProvider.Endpoint, "/")+minIOMetricsPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MinIO endpoint \"%s\": %v", cfg.MinIOProvider.Endpoint, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+makeMinIOMetricsToken(cfg.MinIOProvider, time.Now()))
+
+	client := &http.Client{}
+	if !cfg.MinIOProvider.Verify {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the MinIO metrics: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting the MinIO metrics: status %d", res.StatusCode)
+	}
+
+	return parseTriggerDeliveryStats(res.Body)
+}
+
+// parseTriggerDeliveryStats reads the metrics of the webhook notification targets, adding the values reported by
+// each MinIO server
+func parseTriggerDeliveryStats(metrics io.Reader) (map[string]*types.TriggerDeliveryStats, error) {
+	stats := map[string]*types.TriggerDeliveryStats{}
+	totals := map[string]float64{}
+	scanner := bufio.NewScanner(metrics)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, minIONotifyTargetPrefix) {
+			continue
+		}
+		name, labels, value, ok := parseMetricLine(line)
+		kind := minIONotifyMetrics[strings.TrimPrefix(name, minIONotifyTargetPrefix)]
+		if !ok || kind == "" || labels["target_name"] != "webhook" || labels["target_id"] == "" {
+			continue
+		}
+
+		service := labels["target_id"]
+		if stats[service] == nil {
+			stats[service] = &types.TriggerDeliveryStats{Service: service}
+		}
+		switch kind {
+		case "total":
+			totals[service] += value
+		case "failed":
+			stats[service].Failed += value
+		case "pending":
+			stats[service].Pending += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the MinIO metrics: %v", err)
+	}
+
+	for service, s := range stats {
+		if delivered := totals[service] - s.Failed; delivered > 0 {
+			s.Delivered = delivered
+		}
+	}
+	return stats, nil
+}
+
+// parseMetricLine parses a sample of the Prometheus text format (name{label="value",...} value [timestamp])
+func parseMetricLine(line string) (string, map[string]string, float64, bool) {
+	labels := map[string]string{}
+	name := line
+	rest := ""
+	if i := strings.IndexAny(line, "{ "); i >= 0 {
+		name, rest = line[:i], line[i:]
+	}
+
+	if strings.HasPrefix(rest, "{") {
+		end := strings.LastIndex(rest, "}")
+		if end < 0 {
+			return "", nil, 0, false
+		}
+		for _, pair := range splitMetricLabels(rest[1:end]) {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			value, err := strconv.Unquote(strings.TrimSpace(kv[1]))
+			if err != nil {
+				return "", nil, 0, false
+			}
+			labels[strings.TrimSpace(kv[0])] = value
+		}
+		rest = rest[end+1:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, false
+	}
+	return name, labels, value, true
+}
+
+// splitMetricLabels splits the label pairs of a sample, ignoring the commas of the quoted values
+func splitMetricLabels(labels string) []string {
+	pairs := []string{}
+	quoted, escaped, start := false, false, 0
+	for i, r := range labels {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			pairs = append(pairs, labels[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(labels[start:]) != "" {
+		pairs = append(pairs, labels[start:])
+	}
+	return pairs
+}
+
+// makeMinIOMetricsToken returns the bearer token to read the metrics of MinIO, as generated by
+// "mc admin prometheus generate" (a JWT signed with the secret key)
+func makeMinIOMetricsToken(provider *types.MinIOProvider, now time.Time) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS512", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"exp": now.Add(time.Hour).Unix(),
+		"sub": provider.AccessKey,
+		"iss": "prometheus",
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha512.New, []byte(provider.SecretKey))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,72 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+const testMinIOMetrics = `# HELP minio_notify_target_total_events Total number of events sent (or) queued to the target
+# TYPE minio_notify_target_total_events counter
+minio_notify_target_total_events{server="minio-0:9000",target_id="grayify",target_name="webhook"} 10
+minio_notify_target_total_events{server="minio-1:9000",target_id="grayify",target_name="webhook"} 5
+minio_notify_target_total_events{server="minio-0:9000",target_id="other",target_name="kafka"} 7
+# TYPE minio_notify_target_failed_events counter
+minio_notify_target_failed_events{server="minio-0:9000",target_id="grayify",target_name="webhook"} 3
+# TYPE minio_notify_target_queue_length gauge
+minio_notify_target_queue_length{server="minio-0:9000",target_id="grayify",target_name="webhook"} 2
+minio_notify_target_total_requests{target_id="plants, \"v2\"",target_name="webhook"} 4
+minio_node_process_uptime_seconds{server="minio-0:9000"} 100
+`
+
+func TestGetTriggerDeliveryStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != minIOMetricsPath || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(testMinIOMetrics))
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{MinIOProvider: &types.MinIOProvider{Endpoint: server.URL, AccessKey: "minioadmin", SecretKey: "minioadmin"}}
+	stats, err := GetTriggerDeliveryStats(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("expected the stats of 2 services, got %d", len(stats))
+	}
+	expected := types.TriggerDeliveryStats{Service: "grayify", Delivered: 12, Failed: 3, Pending: 2}
+	if s := stats["grayify"]; s == nil || *s != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, s)
+	}
+	if s := stats["plants, \"v2\""]; s == nil || s.Delivered != 4 {
+		t.Errorf("unexpected stats of the quoted target: %+v", s)
+	}
+
+	cfg.MinIOProvider.Endpoint = server.URL + "/forbidden"
+	if _, err := GetTriggerDeliveryStats(cfg); err == nil {
+		t.Error("expected error reading the metrics")
+	}
+}
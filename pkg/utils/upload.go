@@ -0,0 +1,462 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// Custom logger
+var uploadLogger = log.New(os.Stdout, "[UPLOAD] ", log.Flags())
+
+const (
+	uploadSessionFile = "session.json"
+	// Maximum size of the objects copied with a single CopyObject request
+	uploadMaxCopySize = 5 << 30
+	// Size of the parts of the multipart copies
+	uploadCopyPartSize = 1 << 30
+)
+
+// Mutex to serialise the updates of the session records
+var uploadMutex sync.Mutex
+
+// uploadSessionRecord upload session stored in the staging bucket
+type uploadSessionRecord struct {
+	types.UploadSession
+	// Definition of the service to activate (it includes credentials, so it is never returned)
+	Definition types.Service `json:"definition"`
+	// Multipart uploads of the assets
+	Uploads map[string]*assetUpload `json:"uploads"`
+}
+
+// assetUpload state of the S3 multipart upload of an asset
+type assetUpload struct {
+	UploadID string              `json:"upload_id"`
+	Parts    []*s3.CompletedPart `json:"parts"`
+}
+
+// getSessionKey returns the key of the session record in the staging bucket
+func getSessionKey(id string) string {
+	return path.Join(path.Base(id), uploadSessionFile)
+}
+
+// getAssetKey returns the key of a staged asset of the session
+func getAssetKey(id string, name string) string {
+	return path.Join(path.Base(id), "assets", name)
+}
+
+// CreateUploadSession stores a new upload session in the staging bucket for the service's script (if it is
+// not defined in the definition) and assets. The service definition must be already validated
+func CreateUploadSession(cfg *types.Config, service *types.Service) (*types.UploadSession, error) {
+	now := time.Now().UTC()
+	record := &uploadSessionRecord{
+		UploadSession: types.UploadSession{
+			ID:        GenerateToken()[:32],
+			Service:   service.Name,
+			Assets:    []types.UploadAsset{},
+			CreatedAt: now,
+			ExpiresAt: now.Add(cfg.UploadSessionTTL),
+		},
+		Definition: *service,
+		Uploads:    map[string]*assetUpload{},
+	}
+	if service.Script == "" && service.ScriptGit == nil {
+		record.Assets = append(record.Assets, types.UploadAsset{Name: types.ScriptAssetName})
+	}
+	for _, asset := range service.Assets {
+		record.Assets = append(record.Assets, types.UploadAsset{Name: asset.Name, Path: asset.Path})
+	}
+
+	if err := createStagingBucket(cfg); err != nil {
+		return nil, err
+	}
+	if err := putUploadSession(cfg, record); err != nil {
+		return nil, err
+	}
+
+	return &record.UploadSession, nil
+}
+
+// createStagingBucket creates the staging bucket (if it doesn't exist) with a lifecycle rule to remove
+// the abandoned sessions
+func createStagingBucket(cfg *types.Config) error {
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	_, err := s3Client.CreateBucket(&s3.CreateBucketInput{
+		Bucket: aws.String(cfg.UploadStagingBucket),
+	})
+	if err != nil {
+		// Check if the error is caused because the bucket already exists
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeBucketAlreadyExists || aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou) {
+			return nil
+		}
+		return fmt.Errorf("error creating the staging bucket %s: %v", cfg.UploadStagingBucket, err)
+	}
+
+	// Expired sessions are also discarded when accessed, the rule only frees the space of the abandoned ones
+	days := aws.Int64(int64(math.Ceil(cfg.UploadSessionTTL.Hours()/24)) + 1)
+	_, err = s3Client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(cfg.UploadStagingBucket),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{
+				{
+					ID:                             aws.String("oscar-upload-sessions"),
+					Status:                         aws.String(s3.ExpirationStatusEnabled),
+					Filter:                         &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+					Expiration:                     &s3.LifecycleExpiration{Days: days},
+					AbortIncompleteMultipartUpload: &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: days},
+				},
+			},
+		},
+	})
+	if err != nil {
+		uploadLogger.Printf("error setting the lifecycle of the staging bucket %s: %v\n", cfg.UploadStagingBucket, err)
+	}
+
+	return nil
+}
+
+func putUploadSession(cfg *types.Config, record *uploadSessionRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.MinIOProvider.GetS3Client().PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(cfg.UploadStagingBucket),
+		Key:         aws.String(getSessionKey(record.ID)),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return types.NewCodedError(types.ErrUploadFailed, fmt.Errorf("error storing the upload session: %v", err))
+	}
+	return nil
+}
+
+// getUploadSession reads a session record from the staging bucket, discarding it if it has expired
+func getUploadSession(cfg *types.Config, id string) (*uploadSessionRecord, error) {
+	out, err := cfg.MinIOProvider.GetS3Client().GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(cfg.UploadStagingBucket),
+		Key:    aws.String(getSessionKey(id)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == s3.ErrCodeNoSuchBucket) {
+			return nil, types.NewCodedError(types.ErrUploadNotFound, fmt.Errorf("the upload session \"%s\" does not exist", id))
+		}
+		return nil, types.NewCodedError(types.ErrStorageConnectionFailed, fmt.Errorf("error reading the upload session: %v", err))
+	}
+	defer out.Body.Close()
+
+	record := &uploadSessionRecord{}
+	if err := json.NewDecoder(out.Body).Decode(record); err != nil {
+		return nil, fmt.Errorf("error decoding the upload session: %v", err)
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		if err := deleteUploadSession(cfg, record); err != nil {
+			uploadLogger.Printf("error deleting the expired upload session \"%s\": %v\n", id, err)
+		}
+		return nil, types.NewCodedError(types.ErrUploadNotFound, fmt.Errorf("the upload session \"%s\" has expired", id))
+	}
+
+	return record, nil
+}
+
+// GetUploadSession returns the status of an upload session
+func GetUploadSession(cfg *types.Config, id string) (*types.UploadSession, error) {
+	record, err := getUploadSession(cfg, id)
+	if err != nil {
+		return nil, err
+	}
+	return &record.UploadSession, nil
+}
+
+// UploadAssetChunk stores a chunk of an asset (starting at offset) as a part of its multipart upload in the
+// staging bucket. The chunk must start at the current offset of the asset and, except for the last one,
+// have at least types.UploadMinChunkSize bytes. When the last chunk is received the asset is completed
+func UploadAssetChunk(cfg *types.Config, id string, name string, offset int64, size int64, total int64, body io.Reader) (*types.UploadAsset, error) {
+	uploadMutex.Lock()
+	defer uploadMutex.Unlock()
+
+	record, err := getUploadSession(cfg, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var asset *types.UploadAsset
+	for i := range record.Assets {
+		if record.Assets[i].Name == name {
+			asset = &record.Assets[i]
+		}
+	}
+	if asset == nil {
+		return nil, types.NewCodedError(types.ErrUploadNotFound, fmt.Errorf("the asset \"%s\" is not part of the upload session", name))
+	}
+
+	// Check the chunk
+	if asset.Complete || offset != asset.Offset {
+		return nil, types.NewCodedError(types.ErrUploadOffsetMismatch, fmt.Errorf("the chunk must start at the offset %d of the asset \"%s\"", asset.Offset, name))
+	}
+	if asset.Size != 0 && total != asset.Size {
+		return nil, types.NewCodedError(types.ErrBadRequest, fmt.Errorf("the total size of the asset \"%s\" is %d bytes", name, asset.Size))
+	}
+	last := offset+size == total
+	if offset+size > total || (size == 0 && total != 0) {
+		return nil, types.NewCodedError(types.ErrBadRequest, errors.New("the chunk range is not valid"))
+	}
+	if !last && size < types.UploadMinChunkSize {
+		return nil, types.NewCodedError(types.ErrBadRequest, fmt.Errorf("all the chunks except the last one must have at least %d bytes", types.UploadMinChunkSize))
+	}
+	if name == types.ScriptAssetName && total > types.UploadMaxScriptSize {
+		return nil, types.NewCodedError(types.ErrBadRequest, fmt.Errorf("the script can not exceed %d bytes", types.UploadMaxScriptSize))
+	}
+
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	key := aws.String(getAssetKey(id, name))
+	upload := record.Uploads[name]
+	if upload == nil {
+		out, err := s3Client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+			Bucket: aws.String(cfg.UploadStagingBucket),
+			Key:    key,
+		})
+		if err != nil {
+			return nil, types.NewCodedError(types.ErrUploadFailed, fmt.Errorf("error starting the upload of the asset \"%s\": %v", name, err))
+		}
+		upload = &assetUpload{UploadID: aws.StringValue(out.UploadId), Parts: []*s3.CompletedPart{}}
+		record.Uploads[name] = upload
+	}
+
+	// The body is buffered to be sent with a known length (and to be retried by the S3 client)
+	data, err := io.ReadAll(io.LimitReader(body, size+1))
+	if err != nil {
+		return nil, types.NewCodedError(types.ErrBadRequest, fmt.Errorf("error reading the chunk: %v", err))
+	}
+	if int64(len(data)) != size {
+		return nil, types.NewCodedError(types.ErrBadRequest, fmt.Errorf("the chunk has %d bytes instead of %d", len(data), size))
+	}
+
+	partNumber := aws.Int64(int64(len(upload.Parts) + 1))
+	part, err := s3Client.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String(cfg.UploadStagingBucket),
+		Key:        key,
+		UploadId:   aws.String(upload.UploadID),
+		PartNumber: partNumber,
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return nil, types.NewCodedError(types.ErrUploadFailed, fmt.Errorf("error uploading the chunk of the asset \"%s\": %v", name, err))
+	}
+	upload.Parts = append(upload.Parts, &s3.CompletedPart{ETag: part.ETag, PartNumber: partNumber})
+	asset.Size = total
+	asset.Offset += size
+
+	if last {
+		_, err := s3Client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(cfg.UploadStagingBucket),
+			Key:             key,
+			UploadId:        aws.String(upload.UploadID),
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: upload.Parts},
+		})
+		if err != nil {
+			return nil, types.NewCodedError(types.ErrUploadFailed, fmt.Errorf("error completing the upload of the asset \"%s\": %v", name, err))
+		}
+		asset.Complete = true
+		delete(record.Uploads, name)
+	}
+
+	if err := putUploadSession(cfg, record); err != nil {
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+// ReadUploadedService returns the definition of the service of a completed upload session, with the uploaded script
+func ReadUploadedService(cfg *types.Config, id string) (*types.Service, error) {
+	record, err := getCompletedUploadSession(cfg, id)
+	if err != nil {
+		return nil, err
+	}
+
+	service := record.Definition
+	for _, asset := range record.Assets {
+		if asset.Name != types.ScriptAssetName {
+			continue
+		}
+		out, err := cfg.MinIOProvider.GetS3Client().GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(cfg.UploadStagingBucket),
+			Key:    aws.String(getAssetKey(id, asset.Name)),
+		})
+		if err != nil {
+			return nil, types.NewCodedError(types.ErrStorageConnectionFailed, fmt.Errorf("error reading the script: %v", err))
+		}
+		script, err := io.ReadAll(out.Body)
+		out.Body.Close()
+		if err != nil {
+			return nil, types.NewCodedError(types.ErrStorageConnectionFailed, fmt.Errorf("error reading the script: %v", err))
+		}
+		service.Script = string(script)
+	}
+
+	return &service, nil
+}
+
+// CopyUploadedAssets copies the assets of a completed upload session to their paths
+func CopyUploadedAssets(cfg *types.Config, id string) error {
+	record, err := getCompletedUploadSession(cfg, id)
+	if err != nil {
+		return err
+	}
+
+	for _, asset := range record.Assets {
+		if asset.Name == types.ScriptAssetName {
+			continue
+		}
+		if err := copyAsset(cfg, id, asset); err != nil {
+			return types.NewCodedError(types.ErrUploadFailed, fmt.Errorf("error copying the asset \"%s\" to \"%s\": %v", asset.Name, asset.Path, err))
+		}
+	}
+
+	return nil
+}
+
+func getCompletedUploadSession(cfg *types.Config, id string) (*uploadSessionRecord, error) {
+	record, err := getUploadSession(cfg, id)
+	if err != nil {
+		return nil, err
+	}
+	if !record.IsComplete() {
+		return nil, types.NewCodedError(types.ErrUploadIncomplete, fmt.Errorf("the upload session \"%s\" has pending assets", id))
+	}
+	return record, nil
+}
+
+// copyAsset copies a staged asset to its path, creating the bucket if it doesn't exist
+func copyAsset(cfg *types.Config, id string, asset types.UploadAsset) error {
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	bucket, key := types.StorageIOConfig{Path: asset.Path}.SplitPath()
+	source := aws.String(path.Join(cfg.UploadStagingBucket, getAssetKey(id, asset.Name)))
+
+	_, err := s3Client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)})
+	if aerr, ok := err.(awserr.Error); err != nil && !(ok && (aerr.Code() == s3.ErrCodeBucketAlreadyExists || aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou)) {
+		return err
+	}
+
+	if asset.Size <= uploadMaxCopySize {
+		_, err := s3Client.CopyObject(&s3.CopyObjectInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(key),
+			CopySource: source,
+		})
+		return err
+	}
+
+	// Objects larger than 5GiB must be copied by parts
+	out, err := s3Client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	parts := []*s3.CompletedPart{}
+	for start := int64(0); start < asset.Size; start += uploadCopyPartSize {
+		end := start + uploadCopyPartSize - 1
+		if end >= asset.Size {
+			end = asset.Size - 1
+		}
+		partNumber := aws.Int64(int64(len(parts) + 1))
+		part, err := s3Client.UploadPartCopy(&s3.UploadPartCopyInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(key),
+			UploadId:        out.UploadId,
+			PartNumber:      partNumber,
+			CopySource:      source,
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		})
+		if err != nil {
+			s3Client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{Bucket: aws.String(bucket), Key: aws.String(key), UploadId: out.UploadId})
+			return err
+		}
+		parts = append(parts, &s3.CompletedPart{ETag: part.CopyPartResult.ETag, PartNumber: partNumber})
+	}
+	_, err = s3Client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        out.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+// DeleteUploadSession aborts the pending uploads of a session and removes its staged assets and record
+func DeleteUploadSession(cfg *types.Config, id string) error {
+	uploadMutex.Lock()
+	defer uploadMutex.Unlock()
+
+	record, err := getUploadSession(cfg, id)
+	if err != nil {
+		return err
+	}
+	return deleteUploadSession(cfg, record)
+}
+
+func deleteUploadSession(cfg *types.Config, record *uploadSessionRecord) error {
+	s3Client := cfg.MinIOProvider.GetS3Client()
+	bucket := aws.String(cfg.UploadStagingBucket)
+
+	for name, upload := range record.Uploads {
+		_, err := s3Client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket:   bucket,
+			Key:      aws.String(getAssetKey(record.ID, name)),
+			UploadId: aws.String(upload.UploadID),
+		})
+		if err != nil {
+			uploadLogger.Printf("error aborting the upload of the asset \"%s\" of the session \"%s\": %v\n", name, record.ID, err)
+		}
+	}
+	for _, asset := range record.Assets {
+		if asset.Complete {
+			s3Client.DeleteObject(&s3.DeleteObjectInput{Bucket: bucket, Key: aws.String(getAssetKey(record.ID, asset.Name))})
+		}
+	}
+
+	// The record is deleted last to be able to retry the deletion
+	_, err := s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: bucket,
+		Key:    aws.String(getSessionKey(record.ID)),
+	})
+	if err != nil {
+		return types.NewCodedError(types.ErrUploadFailed, fmt.Errorf("error deleting the upload session: %v", err))
+	}
+	return nil
+}
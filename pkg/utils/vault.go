@@ -0,0 +1,200 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+// vaultTokenRenewMargin time before the expiration of the Vault token to log in again
+const vaultTokenRenewMargin = 30 * time.Second
+
+// vaultServiceAccountTokenPath path of the service account token of OSCAR, used to log in Vault
+var vaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+var (
+	vaultToken        string
+	vaultTokenExpires time.Time
+	vaultMutex        sync.Mutex
+)
+
+// vaultResponse response of the Vault API reading a secret or logging in
+type vaultResponse struct {
+	Data          map[string]interface{} `json:"data"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// ResolveVaultCredentials returns a copy of the service whose credentials referencing a Vault secret
+// ("vault://<path>#<field>") are replaced by the value of the field. Each secret is read once, so the fields of the
+// dynamic secrets (e.g. the access and secret keys of the AWS secrets engine) belong to the same lease. Only the paths
+// of the service's VO under the allowed prefixes (VAULT_PATH_PREFIX) are read. The service is returned as is if it
+// has no references
+func ResolveVaultCredentials(cfg *types.Config, service *types.Service) (*types.Service, error) {
+	if !service.HasVaultReferences() {
+		return service, nil
+	}
+	if cfg.VaultAddress == "" {
+		return nil, types.NewCodedError(types.ErrVaultCredentialsFailed, errors.New("the credentials of the service reference Vault secrets, but VAULT_ADDR is not configured"))
+	}
+
+	// Deep copy the storage providers to not modify the service
+	svc := *service
+	providers := &types.StorageProviders{}
+	spBytes, err := json.Marshal(service.StorageProviders)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(spBytes, providers); err != nil {
+		return nil, err
+	}
+	svc.StorageProviders = providers
+
+	secrets := map[string]map[string]interface{}{}
+	err = svc.RewriteStorageCredentials(func(key string, value string) (string, error) {
+		path, field, ok := types.ParseVaultReference(value)
+		if !ok {
+			return value, nil
+		}
+		// The paths are checked again, as OSCAR reads them with its own identity
+		if err := svc.CheckVaultPath(key, path, cfg.GetVaultPathPrefixes()); err != nil {
+			return "", err
+		}
+		if _, read := secrets[path]; !read {
+			data, err := readVaultSecret(cfg, path)
+			if err != nil {
+				return "", err
+			}
+			secrets[path] = data
+		}
+		fieldValue, found := secrets[path][field]
+		if !found {
+			return "", fmt.Errorf("the Vault secret \"%s\" does not contain the field \"%s\" (credential \"%s\")", path, field, key)
+		}
+		return fmt.Sprint(fieldValue), nil
+	})
+	if err != nil {
+		return nil, types.NewCodedError(types.ErrVaultCredentialsFailed, err)
+	}
+
+	svc.VaultCredentials = true
+	return &svc, nil
+}
+
+// readVaultSecret reads the data of a Vault secret. The data of the KV version 2 secrets is unwrapped
+func readVaultSecret(cfg *types.Config, path string) (map[string]interface{}, error) {
+	token, err := getVaultToken(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(cfg.VaultAddress, "/"), path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	res, err := doVaultRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the Vault secret \"%s\": %v", path, err)
+	}
+
+	data := res.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, kv2 := data["metadata"]; kv2 {
+			data = inner
+		}
+	}
+	return data, nil
+}
+
+// getVaultToken returns the configured Vault token or the (cached) one obtained logging in with the service account
+// token of OSCAR through the Kubernetes auth method
+func getVaultToken(cfg *types.Config) (string, error) {
+	if cfg.VaultToken != "" {
+		return cfg.VaultToken, nil
+	}
+
+	vaultMutex.Lock()
+	defer vaultMutex.Unlock()
+
+	if vaultToken != "" && time.Now().Before(vaultTokenExpires) {
+		return vaultToken, nil
+	}
+
+	jwt, err := os.ReadFile(vaultServiceAccountTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("error reading the service account token to log in Vault: %v", err)
+	}
+	body, err := json.Marshal(map[string]string{"role": cfg.VaultRole, "jwt": strings.TrimSpace(string(jwt))})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v1/auth/%s/login", strings.TrimSuffix(cfg.VaultAddress, "/"), strings.Trim(cfg.VaultAuthPath, "/")), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := doVaultRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("error logging in Vault: %v", err)
+	}
+	if res.Auth == nil || res.Auth.ClientToken == "" {
+		return "", errors.New("error logging in Vault: the response does not contain a token")
+	}
+
+	vaultToken = res.Auth.ClientToken
+	vaultTokenExpires = time.Now().Add(time.Duration(res.Auth.LeaseDuration)*time.Second - vaultTokenRenewMargin)
+	return vaultToken, nil
+}
+
+// doVaultRequest sends a request to the Vault API and decodes its response
+func doVaultRequest(req *http.Request) (*vaultResponse, error) {
+	client := &http.Client{Timeout: time.Second * 10}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	vaultRes := &vaultResponse{}
+	if err := json.NewDecoder(res.Body).Decode(vaultRes); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("error decoding the response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if len(vaultRes.Errors) > 0 {
+			return nil, fmt.Errorf("status code %d: %s", res.StatusCode, strings.Join(vaultRes.Errors, "; "))
+		}
+		return nil, fmt.Errorf("status code %d", res.StatusCode)
+	}
+	return vaultRes, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+)
+
+func testVaultServer(reads *int) *httptest.Server {
+	// Clear the cached token
+	vaultToken = ""
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			body := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["role"] != "oscar" || body["jwt"] != "sa-token" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			w.Write([]byte(`{"auth":{"client_token":"vault-token","lease_duration":3600}}`))
+		case "/v1/secret/data/oscar/webdav":
+			if r.Header.Get("X-Vault-Token") != "vault-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			*reads++
+			w.Write([]byte(`{"data":{"data":{"login":"user","password":"pass"},"metadata":{"version":1}}}`))
+		case "/v1/aws/creds/oscar":
+			*reads++
+			w.Write([]byte(`{"data":{"access_key":"AKIA","secret_key":"secret"},"lease_duration":900}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func TestResolveVaultCredentials(t *testing.T) {
+	reads := 0
+	server := testVaultServer(&reads)
+	defer server.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenPath, []byte("sa-token\n"), 0600)
+	vaultServiceAccountTokenPath = tokenPath
+
+	cfg := &types.Config{VaultAddress: server.URL, VaultRole: "oscar", VaultAuthPath: "kubernetes", VaultPathPrefix: []string{"aws/creds", "secret/data"}}
+	service := &types.Service{Name: "test", VO: "oscar", StorageProviders: &types.StorageProviders{
+		S3:     map[string]*types.S3Provider{"aws": {AccessKey: "vault://aws/creds/oscar#access_key", SecretKey: "vault://aws/creds/oscar#secret_key", Region: "us-east-1"}},
+		WebDav: map[string]*types.WebDavProvider{"dcache": {Hostname: "dcache.example.org", Login: "vault://secret/data/oscar/webdav#login", Password: "vault://secret/data/oscar/webdav#password"}},
+	}}
+
+	resolved, err := ResolveVaultCredentials(cfg, service)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s3 := resolved.StorageProviders.S3["aws"]; s3.AccessKey != "AKIA" || s3.SecretKey != "secret" || s3.Region != "us-east-1" {
+		t.Errorf("unexpected S3 credentials: %+v", s3)
+	}
+	if webdav := resolved.StorageProviders.WebDav["dcache"]; webdav.Login != "user" || webdav.Password != "pass" {
+		t.Errorf("unexpected WebDav credentials: %+v", webdav)
+	}
+	if !resolved.VaultCredentials {
+		t.Error("expecting the resolved service to be marked")
+	}
+	if reads != 2 {
+		t.Errorf("expecting each secret to be read once, got %d reads", reads)
+	}
+	if service.StorageProviders.S3["aws"].AccessKey != "vault://aws/creds/oscar#access_key" {
+		t.Error("the service must not be modified")
+	}
+
+	// Missing field
+	service.StorageProviders.S3["aws"].SecretKey = "vault://aws/creds/oscar#session_token"
+	if _, err := ResolveVaultCredentials(cfg, service); err == nil || types.GetErrorCode(err, types.ErrInternal) != types.ErrVaultCredentialsFailed {
+		t.Errorf("expecting a Vault credentials error, got %v", err)
+	}
+
+	// Paths outside the prefixes of the service's VO are not read
+	service.StorageProviders.S3["aws"].SecretKey = "vault://secret/data/admin/s3#secret_key"
+	if _, err := ResolveVaultCredentials(cfg, service); err == nil || types.GetErrorCode(err, types.ErrInternal) != types.ErrVaultCredentialsFailed {
+		t.Errorf("expecting a Vault credentials error, got %v", err)
+	}
+
+	// Services without references are returned as is
+	plain := &types.Service{Name: "plain"}
+	if res, err := ResolveVaultCredentials(&types.Config{}, plain); err != nil || res != plain {
+		t.Errorf("expecting the service to be returned as is, got %v", err)
+	}
+}
+
+func TestResolveVaultCredentialsLoginFailed(t *testing.T) {
+	reads := 0
+	server := testVaultServer(&reads)
+	defer server.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenPath, []byte("wrong"), 0600)
+	vaultServiceAccountTokenPath = tokenPath
+
+	cfg := &types.Config{VaultAddress: server.URL, VaultRole: "oscar", VaultAuthPath: "kubernetes", VaultPathPrefix: []string{"aws/creds", "secret/data"}}
+	service := &types.Service{Name: "test", VO: "oscar", StorageProviders: &types.StorageProviders{
+		S3: map[string]*types.S3Provider{"aws": {AccessKey: "vault://aws/creds/oscar#access_key", SecretKey: "secret"}},
+	}}
+	if _, err := ResolveVaultCredentials(cfg, service); err == nil {
+		t.Error("expecting an error logging in Vault")
+	}
+}
@@ -0,0 +1,320 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/uuid"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// approvedVOsRefreshInterval time between the reads of the approved VOs, so the VOs approved through other
+// replicas of the OSCAR manager are enabled
+const approvedVOsRefreshInterval = 30 * time.Second
+
+var voRequestsLogger = log.New(os.Stdout, "[VO-REQUESTS] ", log.Flags())
+
+// CreateVORequest stores a new pending request to enable a VO. The VO must not be enabled in the cluster nor
+// have another pending request
+func CreateVORequest(cfg *types.Config, kubeClientset kubernetes.Interface, req *types.VORequest) error {
+	if cfg.IsAllowedGroup(req.VO) {
+		return types.NewCodedError(types.ErrBadRequest, fmt.Errorf("the VO \"%s\" is already enabled in the cluster", req.VO))
+	}
+	pending, err := ListVORequests(kubeClientset, cfg.ServicesNamespace, "")
+	if err != nil {
+		return err
+	}
+	for _, other := range pending {
+		if other.VO == req.VO && other.Status == types.VORequestPending {
+			return types.NewCodedError(types.ErrBadRequest, fmt.Errorf("there is already a pending request (\"%s\") for the VO \"%s\"", other.ID, req.VO))
+		}
+	}
+
+	req.ID = strings.Split(uuid.New().String(), "-")[0]
+	req.Status = types.VORequestPending
+	req.CreatedAt = time.Now().UTC()
+	req.DecidedAt = nil
+	req.DecidedBy = ""
+	req.Message = ""
+
+	cm, err := getVORequestConfigMap(cfg.ServicesNamespace, req)
+	if err != nil {
+		return err
+	}
+	if _, err := kubeClientset.CoreV1().ConfigMaps(cfg.ServicesNamespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("error storing the request for the VO \"%s\": %v", req.VO, err)
+	}
+	return nil
+}
+
+// ListVORequests returns the VO requests made by requester (all of them if empty), newest first
+func ListVORequests(kubeClientset kubernetes.Interface, namespace string, requester string) ([]types.VORequest, error) {
+	cms, err := kubeClientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: types.VORequestLabel})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the VO requests: %v", err)
+	}
+
+	requests := []types.VORequest{}
+	for _, cm := range cms.Items {
+		req, err := readVORequest(&cm)
+		if err != nil {
+			voRequestsLogger.Println(err.Error())
+			continue
+		}
+		if requester == "" || req.Requester == requester {
+			requests = append(requests, *req)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.After(requests[j].CreatedAt)
+	})
+	return requests, nil
+}
+
+// GetVORequest returns a VO request. The error coded types.ErrVORequestNotFound is returned if it does not exist
+func GetVORequest(kubeClientset kubernetes.Interface, namespace string, id string) (*types.VORequest, error) {
+	cm, err := kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), types.VORequestConfigMapName(id), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) || (err == nil && cm.Labels[types.VORequestLabel] == "") {
+		return nil, types.NewCodedError(types.ErrVORequestNotFound, fmt.Errorf("the VO request \"%s\" does not exist", id))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting the VO request \"%s\": %v", id, err)
+	}
+	return readVORequest(cm)
+}
+
+// ApproveVORequest enables the VO of a pending request in the cluster with the quota of the decision (or the
+// requested one), provisioning its namespace and its bucket if requested
+func ApproveVORequest(cfg *types.Config, kubeClientset kubernetes.Interface, id string, admin string, decision *types.VORequestDecision) (*types.VORequest, error) {
+	req, err := getPendingVORequest(kubeClientset, cfg.ServicesNamespace, id)
+	if err != nil {
+		return nil, err
+	}
+	if decision.Quota != nil {
+		req.Quota = decision.Quota
+	}
+	if decision.Namespace != nil {
+		req.Namespace = *decision.Namespace
+	}
+	if decision.Storage != nil {
+		req.Storage = *decision.Storage
+	}
+
+	approved := types.ApprovedVO{
+		VO:         req.VO,
+		RequestID:  req.ID,
+		Quota:      req.Quota,
+		ApprovedAt: time.Now().UTC(),
+	}
+	if req.Namespace {
+		if approved.Namespace, err = provisionVONamespace(kubeClientset, req.VO); err != nil {
+			return nil, err
+		}
+	}
+	if req.Storage {
+		if approved.Bucket, err = provisionVOBucket(cfg, req.VO); err != nil {
+			return nil, err
+		}
+	}
+	if err := storeApprovedVO(kubeClientset, cfg.ServicesNamespace, approved); err != nil {
+		return nil, err
+	}
+	if err := LoadApprovedVOs(kubeClientset, cfg.ServicesNamespace); err != nil {
+		voRequestsLogger.Println(err.Error())
+	}
+
+	return req, decideVORequest(kubeClientset, cfg.ServicesNamespace, req, types.VORequestApproved, admin, decision.Message)
+}
+
+// RejectVORequest rejects a pending VO request
+func RejectVORequest(cfg *types.Config, kubeClientset kubernetes.Interface, id string, admin string, decision *types.VORequestDecision) (*types.VORequest, error) {
+	req, err := getPendingVORequest(kubeClientset, cfg.ServicesNamespace, id)
+	if err != nil {
+		return nil, err
+	}
+	return req, decideVORequest(kubeClientset, cfg.ServicesNamespace, req, types.VORequestRejected, admin, decision.Message)
+}
+
+// LoadApprovedVOs reads the approved VOs, enabling them in the OSCAR manager (types.SetApprovedVOs)
+func LoadApprovedVOs(kubeClientset kubernetes.Interface, namespace string) error {
+	cm, err := kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), types.ApprovedVOsConfigMapName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		types.SetApprovedVOs(map[string]types.ApprovedVO{})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading the approved VOs: %v", err)
+	}
+
+	vos := map[string]types.ApprovedVO{}
+	for name, data := range cm.Data {
+		vo := types.ApprovedVO{}
+		if err := json.Unmarshal([]byte(data), &vo); err != nil {
+			voRequestsLogger.Printf("The approved VO \"%s\" cannot be read: %v\n", name, err)
+			continue
+		}
+		vos[vo.VO] = vo
+	}
+	types.SetApprovedVOs(vos)
+	return nil
+}
+
+// StartApprovedVOsRefresher starts the loop to read the approved VOs every approvedVOsRefreshInterval
+func StartApprovedVOsRefresher(cfg *types.Config, kubeClientset kubernetes.Interface) {
+	for {
+		time.Sleep(approvedVOsRefreshInterval)
+
+		if err := LoadApprovedVOs(kubeClientset, cfg.ServicesNamespace); err != nil {
+			voRequestsLogger.Println(err.Error())
+		}
+	}
+}
+
+// getPendingVORequest returns a VO request, or the error coded types.ErrVORequestDecided if it is not pending
+func getPendingVORequest(kubeClientset kubernetes.Interface, namespace string, id string) (*types.VORequest, error) {
+	req, err := GetVORequest(kubeClientset, namespace, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != types.VORequestPending {
+		return nil, types.NewCodedError(types.ErrVORequestDecided, fmt.Errorf("the VO request \"%s\" has already been %s", id, req.Status))
+	}
+	return req, nil
+}
+
+// decideVORequest stores the decision of an admin on a VO request
+func decideVORequest(kubeClientset kubernetes.Interface, namespace string, req *types.VORequest, status string, admin string, message string) error {
+	now := time.Now().UTC()
+	req.Status = status
+	req.DecidedAt = &now
+	req.DecidedBy = admin
+	req.Message = message
+
+	cm, err := getVORequestConfigMap(namespace, req)
+	if err != nil {
+		return err
+	}
+	if _, err := kubeClientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("error storing the decision on the VO request \"%s\": %v", req.ID, err)
+	}
+	return nil
+}
+
+// storeApprovedVO adds an approved VO to the ApprovedVOsConfigMapName configMap
+func storeApprovedVO(kubeClientset kubernetes.Interface, namespace string, vo types.ApprovedVO) error {
+	data, err := json.Marshal(vo)
+	if err != nil {
+		return err
+	}
+
+	cms := kubeClientset.CoreV1().ConfigMaps(namespace)
+	cm, err := cms.Get(context.TODO(), types.ApprovedVOsConfigMapName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      types.ApprovedVOsConfigMapName,
+				Namespace: namespace,
+			},
+			Data: map[string]string{vo.VO: string(data)},
+		}
+		_, err = cms.Create(context.TODO(), cm, metav1.CreateOptions{})
+	} else if err == nil {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[vo.VO] = string(data)
+		_, err = cms.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error storing the approved VO \"%s\": %v", vo.VO, err)
+	}
+	return nil
+}
+
+// provisionVONamespace creates the namespace of a VO (if it does not exist), returning its name
+func provisionVONamespace(kubeClientset kubernetes.Interface, vo string) (string, error) {
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: types.VONamespaceName(vo),
+			Labels: map[string]string{
+				voLabel: vo,
+			},
+		},
+	}
+	if _, err := kubeClientset.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("error creating the namespace of the VO \"%s\": %v", vo, err)
+	}
+	return ns.Name, nil
+}
+
+// provisionVOBucket creates the bucket of a VO in the MinIO of the cluster (if it does not exist), returning its name
+func provisionVOBucket(cfg *types.Config, vo string) (string, error) {
+	bucket := types.VOBucketName(vo)
+	_, err := cfg.MinIOProvider.GetS3Client().CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		var aerr awserr.Error
+		if !errors.As(err, &aerr) || (aerr.Code() != s3.ErrCodeBucketAlreadyExists && aerr.Code() != s3.ErrCodeBucketAlreadyOwnedByYou) {
+			return "", fmt.Errorf("error creating the bucket of the VO \"%s\": %v", vo, err)
+		}
+	}
+	return bucket, nil
+}
+
+// getVORequestConfigMap returns the configMap storing a VO request, labelled with its status
+func getVORequestConfigMap(namespace string, req *types.VORequest) (*v1.ConfigMap, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.VORequestConfigMapName(req.ID),
+			Namespace: namespace,
+			Labels: map[string]string{
+				types.VORequestLabel: req.Status,
+			},
+		},
+		Data: map[string]string{
+			types.VORequestFileName: string(data),
+		},
+	}, nil
+}
+
+// readVORequest returns the VO request stored in a configMap
+func readVORequest(cm *v1.ConfigMap) (*types.VORequest, error) {
+	req := &types.VORequest{}
+	if err := json.Unmarshal([]byte(cm.Data[types.VORequestFileName]), req); err != nil {
+		return nil, fmt.Errorf("the VO request \"%s\" cannot be read: %v", cm.Name, err)
+	}
+	return req, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// VOResourceQuotaName name of the ResourceQuota of the VO namespaces
+	VOResourceQuotaName = "oscar-vo-quota"
+	// VOLimitRangeName name of the LimitRange of the VO namespaces
+	VOLimitRangeName = "oscar-vo-limits"
+)
+
+// EnsureVONamespace creates (if it does not exist) the namespace of a VO and sets its ResourceQuota and
+// LimitRange from cfg.VONamespaceQuota and cfg.VONamespaceLimits
+func EnsureVONamespace(cfg *types.Config, kubeClientset kubernetes.Interface, name string, vo string) error {
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				types.VONamespaceLabel: "true",
+			},
+			Annotations: map[string]string{
+				types.VOAnnotation: vo,
+			},
+		},
+	}
+	if _, err := kubeClientset.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("error creating the namespace of the VO \"%s\": %v", vo, err)
+	}
+
+	if cfg.VONamespaceQuota != "" {
+		hard, err := types.ParseResourceList(cfg.VONamespaceQuota)
+		if err != nil {
+			return err
+		}
+		quota := &v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      VOResourceQuotaName,
+				Namespace: name,
+			},
+			Spec: v1.ResourceQuotaSpec{
+				Hard: hard,
+			},
+		}
+		if err := applyResourceQuota(kubeClientset, quota); err != nil {
+			return fmt.Errorf("error setting the quota of the VO \"%s\": %v", vo, err)
+		}
+	}
+
+	if cfg.VONamespaceLimits != "" {
+		limits, err := types.ParseResourceList(cfg.VONamespaceLimits)
+		if err != nil {
+			return err
+		}
+		limitRange := &v1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      VOLimitRangeName,
+				Namespace: name,
+			},
+			Spec: v1.LimitRangeSpec{
+				Limits: []v1.LimitRangeItem{
+					{
+						Type:           v1.LimitTypeContainer,
+						Default:        limits,
+						DefaultRequest: limits,
+					},
+				},
+			},
+		}
+		if err := applyLimitRange(kubeClientset, limitRange); err != nil {
+			return fmt.Errorf("error setting the limits of the VO \"%s\": %v", vo, err)
+		}
+	}
+
+	return nil
+}
+
+// ListVONamespaces returns the names of the namespaces created for the VOs
+func ListVONamespaces(kubeClientset kubernetes.Interface) ([]string, error) {
+	namespaces, err := kubeClientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{LabelSelector: types.VONamespaceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the namespaces of the VOs: %v", err)
+	}
+	names := []string{}
+	for _, ns := range namespaces.Items {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+// applyResourceQuota creates the ResourceQuota or updates its limits if it already exists
+func applyResourceQuota(kubeClientset kubernetes.Interface, quota *v1.ResourceQuota) error {
+	current, err := kubeClientset.CoreV1().ResourceQuotas(quota.Namespace).Get(context.TODO(), quota.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = kubeClientset.CoreV1().ResourceQuotas(quota.Namespace).Create(context.TODO(), quota, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	current.Spec.Hard = quota.Spec.Hard
+	_, err = kubeClientset.CoreV1().ResourceQuotas(quota.Namespace).Update(context.TODO(), current, metav1.UpdateOptions{})
+	return err
+}
+
+// applyLimitRange creates the LimitRange or updates its limits if it already exists
+func applyLimitRange(kubeClientset kubernetes.Interface, limitRange *v1.LimitRange) error {
+	current, err := kubeClientset.CoreV1().LimitRanges(limitRange.Namespace).Get(context.TODO(), limitRange.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = kubeClientset.CoreV1().LimitRanges(limitRange.Namespace).Create(context.TODO(), limitRange, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	current.Spec.Limits = limitRange.Spec.Limits
+	_, err = kubeClientset.CoreV1().LimitRanges(limitRange.Namespace).Update(context.TODO(), current, metav1.UpdateOptions{})
+	return err
+}
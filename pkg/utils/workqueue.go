@@ -0,0 +1,206 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+var workersQueueLogger = log.New(os.Stdout, "[WORKERS-QUEUE] ", log.Flags())
+
+// PushQueuedEvent appends an event to the queue of a service with workers. The error coded types.ErrQueueFull is
+// returned if the queue has reached the max_queued_events of the service or the maximum size of the queues
+func PushQueuedEvent(kubeClientset kubernetes.Interface, namespace string, service *types.Service, event string) error {
+	return updateEventQueue(kubeClientset, namespace, service.Name, func(events []types.QueuedEvent) ([]types.QueuedEvent, error) {
+		events = append(events, types.QueuedEvent{Event: event})
+		data, err := json.Marshal(events)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > service.Workers.GetMaxQueuedEvents() || len(data) > types.MaxEventQueueSize {
+			return nil, types.NewCodedError(types.ErrQueueFull, fmt.Errorf("the queue of service \"%s\" is full (%d events)", service.Name, len(events)-1))
+		}
+		return events, nil
+	})
+}
+
+// LeaseQueuedEvent leases the oldest available event of the queue of a service to a worker (with the lease ID chosen
+// by it) during the visibility timeout of the service, returning false if there is none. The events whose lease has
+// expired are available again, unless they have been leased max_attempts times (they are discarded). Leasing again
+// with the same lease ID (e.g. a retried request) returns the same event
+func LeaseQueuedEvent(kubeClientset kubernetes.Interface, namespace string, service *types.Service, lease string) (string, bool, error) {
+	var event string
+	var found bool
+	err := updateEventQueue(kubeClientset, namespace, service.Name, func(events []types.QueuedEvent) ([]types.QueuedEvent, error) {
+		event, found = "", false
+		now := time.Now().Unix()
+		available := []types.QueuedEvent{}
+		for _, e := range events {
+			if e.Lease == lease {
+				event, found = e.Event, true
+				return events, nil
+			}
+			if e.Lease != "" && e.LeasedUntil <= now && e.Attempts >= service.Workers.GetMaxAttempts() {
+				workersQueueLogger.Printf("Discarding an event of service \"%s\" not acknowledged after %d attempts\n", service.Name, e.Attempts)
+				continue
+			}
+			available = append(available, e)
+		}
+		for i, e := range available {
+			if e.Lease == "" || e.LeasedUntil <= now {
+				available[i].Lease = lease
+				available[i].LeasedUntil = now + int64(service.Workers.GetVisibilityTimeout())
+				available[i].Attempts++
+				event, found = e.Event, true
+				break
+			}
+		}
+		return available, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return event, found, nil
+}
+
+// AckQueuedEvent removes the event leased by a worker from the queue of a service once processed or, if requeue,
+// returns it to the queue (in its position) to be leased again (unless it has been leased max_attempts times).
+// The error coded types.ErrQueueLeaseNotFound is returned if no event has the lease ID
+func AckQueuedEvent(kubeClientset kubernetes.Interface, namespace string, service *types.Service, lease string, requeue bool) error {
+	return updateEventQueue(kubeClientset, namespace, service.Name, func(events []types.QueuedEvent) ([]types.QueuedEvent, error) {
+		for i, e := range events {
+			if e.Lease != lease {
+				continue
+			}
+			if requeue && e.Attempts < service.Workers.GetMaxAttempts() {
+				events[i].Lease = ""
+				events[i].LeasedUntil = 0
+				return events, nil
+			}
+			if requeue {
+				workersQueueLogger.Printf("Discarding an event of service \"%s\" failed after %d attempts\n", service.Name, e.Attempts)
+			}
+			return append(events[:i], events[i+1:]...), nil
+		}
+		return nil, types.NewCodedError(types.ErrQueueLeaseNotFound, fmt.Errorf("the queue of service \"%s\" does not have an event with the lease \"%s\"", service.Name, lease))
+	})
+}
+
+// GetQueueDepth returns the number of events of the queue of a service not leased by a worker (or whose lease has
+// expired)
+func GetQueueDepth(kubeClientset kubernetes.Interface, namespace string, serviceName string) (int, error) {
+	events, _, err := readEventQueue(kubeClientset, namespace, serviceName)
+	if err != nil {
+		return 0, err
+	}
+	depth := 0
+	now := time.Now().Unix()
+	for _, e := range events {
+		if e.Lease == "" || e.LeasedUntil <= now {
+			depth++
+		}
+	}
+	return depth, nil
+}
+
+// DeleteEventQueue removes the queue of a service along with its events (if any)
+func DeleteEventQueue(kubeClientset kubernetes.Interface, namespace string, serviceName string) error {
+	err := kubeClientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), types.EventQueueConfigMapName(serviceName), metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("error removing the queue of service \"%s\": %v", serviceName, err)
+	}
+	return nil
+}
+
+// readEventQueue returns the events of the queue of a service (empty if it does not exist) and its configMap (nil if
+// it does not exist)
+func readEventQueue(kubeClientset kubernetes.Interface, namespace string, serviceName string) ([]types.QueuedEvent, *v1.ConfigMap, error) {
+	events := []types.QueuedEvent{}
+	cm, err := kubeClientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), types.EventQueueConfigMapName(serviceName), metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return events, nil, nil
+		}
+		return nil, nil, fmt.Errorf("error reading the queue of service \"%s\": %v", serviceName, err)
+	}
+	if data := cm.Data[types.EventQueueKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &events); err != nil {
+			return nil, nil, fmt.Errorf("error parsing the queue of service \"%s\": %v", serviceName, err)
+		}
+	}
+	return events, cm, nil
+}
+
+// updateEventQueue stores the queue of a service after applying fn to its events. The configMap is updated with its
+// resource version, retrying on the conflicts with the concurrent updates (of other requests or OSCAR replicas)
+func updateEventQueue(kubeClientset kubernetes.Interface, namespace string, serviceName string, fn func(events []types.QueuedEvent) ([]types.QueuedEvent, error)) error {
+	cms := kubeClientset.CoreV1().ConfigMaps(namespace)
+	isConflict := func(err error) bool {
+		return k8serrors.IsConflict(err) || k8serrors.IsAlreadyExists(err)
+	}
+	return retry.OnError(retry.DefaultRetry, isConflict, func() error {
+		events, cm, err := readEventQueue(kubeClientset, namespace, serviceName)
+		if err != nil {
+			return err
+		}
+		events, err = fn(events)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(events)
+		if err != nil {
+			return fmt.Errorf("error storing the queue of service \"%s\": %v", serviceName, err)
+		}
+
+		if cm == nil {
+			cm = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      types.EventQueueConfigMapName(serviceName),
+					Namespace: namespace,
+					Labels: map[string]string{
+						types.ServiceLabel:    serviceName,
+						types.EventQueueLabel: "true",
+					},
+				},
+				Data: map[string]string{types.EventQueueKey: string(data)},
+			}
+			_, err = cms.Create(context.TODO(), cm, metav1.CreateOptions{})
+		} else {
+			if cm.Data == nil {
+				cm.Data = map[string]string{}
+			}
+			cm.Data[types.EventQueueKey] = string(data)
+			_, err = cms.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		}
+		if err != nil && !isConflict(err) {
+			return fmt.Errorf("error storing the queue of service \"%s\": %v", serviceName, err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,116 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grycap/oscar/v2/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// expireQueueLeases expires the leases of the queued events of a service, as if their visibility timeout had passed
+func expireQueueLeases(t *testing.T, kubeClientset kubernetes.Interface, serviceName string) {
+	events, cm, err := readEventQueue(kubeClientset, "oscar-svc", serviceName)
+	if err != nil || cm == nil {
+		t.Fatalf("expecting the queue, got %v", err)
+	}
+	for i := range events {
+		if events[i].Lease != "" {
+			events[i].LeasedUntil = 1
+		}
+	}
+	data, _ := json.Marshal(events)
+	cm.Data[types.EventQueueKey] = string(data)
+	if _, err := kubeClientset.CoreV1().ConfigMaps("oscar-svc").Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEventQueue(t *testing.T) {
+	kubeClientset := testclient.NewSimpleClientset()
+	service := &types.Service{Name: "test", Workers: &types.WorkerPool{MaxQueuedEvents: 2, MaxAttempts: 2}}
+
+	for _, event := range []string{"a", "b"} {
+		if err := PushQueuedEvent(kubeClientset, "oscar-svc", service, event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err := PushQueuedEvent(kubeClientset, "oscar-svc", service, "c")
+	if code := types.GetErrorCode(err, types.ErrInternal); code != types.ErrQueueFull {
+		t.Errorf("expecting the queue full, got %v", err)
+	}
+	if depth, _ := GetQueueDepth(kubeClientset, "oscar-svc", service.Name); depth != 2 {
+		t.Errorf("expecting depth 2, got %d", depth)
+	}
+
+	// The events are leased oldest first, and the same lease ID returns the same event
+	if event, ok, err := LeaseQueuedEvent(kubeClientset, "oscar-svc", service, "w1"); err != nil || !ok || event != "a" {
+		t.Errorf("expecting event a, got %s (%v, %v)", event, ok, err)
+	}
+	if event, ok, _ := LeaseQueuedEvent(kubeClientset, "oscar-svc", service, "w1"); !ok || event != "a" {
+		t.Errorf("expecting event a leased again, got %s", event)
+	}
+	if event, ok, _ := LeaseQueuedEvent(kubeClientset, "oscar-svc", service, "w2"); !ok || event != "b" {
+		t.Errorf("expecting event b, got %s", event)
+	}
+	if _, ok, _ := LeaseQueuedEvent(kubeClientset, "oscar-svc", service, "w3"); ok {
+		t.Error("expecting no available events")
+	}
+	if depth, _ := GetQueueDepth(kubeClientset, "oscar-svc", service.Name); depth != 0 {
+		t.Errorf("expecting depth 0 with the events leased, got %d", depth)
+	}
+
+	// Acknowledged events are removed, and the failed ones returned to the queue
+	if err := AckQueuedEvent(kubeClientset, "oscar-svc", service, "w2", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AckQueuedEvent(kubeClientset, "oscar-svc", service, "w1", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = AckQueuedEvent(kubeClientset, "oscar-svc", service, "w1", false)
+	if code := types.GetErrorCode(err, types.ErrInternal); code != types.ErrQueueLeaseNotFound {
+		t.Errorf("expecting the lease not found, got %v", err)
+	}
+
+	// The events not acknowledged in time are leased again, until their max attempts
+	if event, ok, _ := LeaseQueuedEvent(kubeClientset, "oscar-svc", service, "w4"); !ok || event != "a" {
+		t.Errorf("expecting event a requeued, got %s", event)
+	}
+	expireQueueLeases(t, kubeClientset, service.Name)
+	if depth, _ := GetQueueDepth(kubeClientset, "oscar-svc", service.Name); depth != 1 {
+		t.Errorf("expecting depth 1 with the lease expired, got %d", depth)
+	}
+	if _, ok, _ := LeaseQueuedEvent(kubeClientset, "oscar-svc", service, "w5"); ok {
+		t.Error("expecting the event discarded after its max attempts")
+	}
+
+	// The queue is removed along with its events
+	if err := PushQueuedEvent(kubeClientset, "oscar-svc", service, "d"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := DeleteEventQueue(kubeClientset, "oscar-svc", service.Name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if depth, _ := GetQueueDepth(kubeClientset, "oscar-svc", service.Name); depth != 0 {
+		t.Errorf("expecting the queue removed, got depth %d", depth)
+	}
+}
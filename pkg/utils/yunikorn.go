@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/apache/yunikorn-core/pkg/common/configs"
 	"github.com/goccy/go-yaml"
@@ -70,16 +71,25 @@ func updateYunikornConfig(cfg *types.Config, kubeClientset kubernetes.Interface,
 	return nil
 }
 
-// AddYunikornQueue Add (or update) a service's queue to Yunikorn's config
+// AddYunikornQueue Add (or update) a service's queue to Yunikorn's config, in the parent queue of the service's
+// YuniKorn settings. No queue is added if the service's jobs are placed by the YuniKorn's placement rules
 func AddYunikornQueue(cfg *types.Config, kubeClientset kubernetes.Interface, svc *types.Service) error {
+	settings := svc.GetYunikornSettings(cfg)
+	if settings.Placement == types.YunikornPlacementRules {
+		return nil
+	}
+
 	// Read the config
 	yConfig, err := readYunikornConfig(cfg, kubeClientset)
 	if err != nil {
 		return err
 	}
 
-	// Get the pointer of the Oscar queue
-	oQueue := getOscarQueue(yConfig)
+	// Get the pointer of the parent queue (creating it if doesn't exists)
+	pQueue := getYunikornQueue(yConfig, getYunikornPartition(cfg), settings.Queue, true)
+	if pQueue == nil {
+		return fmt.Errorf("the YuniKorn partition \"%s\" does not exist", getYunikornPartition(cfg))
+	}
 
 	// Create the Resources struct
 	maxResources := make(map[string]string)
@@ -96,9 +106,9 @@ func AddYunikornQueue(cfg *types.Config, kubeClientset kubernetes.Interface, svc
 
 	// Update the service's queue if already exists
 	found := false
-	for i, q := range oQueue.Queues {
+	for i, q := range pQueue.Queues {
 		if q.Name == svc.Name {
-			oQueue.Queues[i].Resources = resources
+			pQueue.Queues[i].Resources = resources
 			found = true
 			break
 		}
@@ -106,7 +116,7 @@ func AddYunikornQueue(cfg *types.Config, kubeClientset kubernetes.Interface, svc
 
 	// Create the service's queue if doesn't exists
 	if !found {
-		oQueue.Queues = append(oQueue.Queues, configs.QueueConfig{
+		pQueue.Queues = append(pQueue.Queues, configs.QueueConfig{
 			Name:      svc.Name,
 			Resources: resources,
 		})
@@ -116,21 +126,30 @@ func AddYunikornQueue(cfg *types.Config, kubeClientset kubernetes.Interface, svc
 	return updateYunikornConfig(cfg, kubeClientset, yConfig)
 }
 
-// DeleteYunikornQueue delete a service's queue in Yunikorn's config
+// DeleteYunikornQueue delete a service's queue (set in its YunikornQueueLabel) in Yunikorn's config
 func DeleteYunikornQueue(cfg *types.Config, kubeClientset kubernetes.Interface, svc *types.Service) error {
+	queue := svc.Labels[types.YunikornQueueLabel]
+	if queue == "" {
+		return nil
+	}
+
 	// Read the config
 	yConfig, err := readYunikornConfig(cfg, kubeClientset)
 	if err != nil {
 		return err
 	}
 
-	// Get the pointer of the Oscar queue
-	oQueue := getOscarQueue(yConfig)
+	// Get the pointer of the parent queue
+	parent, name := types.SplitYunikornQueue(queue)
+	pQueue := getYunikornQueue(yConfig, getYunikornPartition(cfg), parent, false)
+	if pQueue == nil {
+		return nil
+	}
 
 	// Search the service's queue
 	index := -1
-	for i, q := range oQueue.Queues {
-		if q.Name == svc.Name {
+	for i, q := range pQueue.Queues {
+		if q.Name == name {
 			index = i
 			break
 		}
@@ -138,7 +157,7 @@ func DeleteYunikornQueue(cfg *types.Config, kubeClientset kubernetes.Interface,
 
 	// Remove the service's queue
 	if index != -1 {
-		oQueue.Queues = append(oQueue.Queues[:index], oQueue.Queues[index+1:]...)
+		pQueue.Queues = append(pQueue.Queues[:index], pQueue.Queues[index+1:]...)
 	}
 
 	// Update the configMap
@@ -149,31 +168,49 @@ func DeleteYunikornQueue(cfg *types.Config, kubeClientset kubernetes.Interface,
 	return nil
 }
 
-// getOscarQueue returns a pointer to the OSCAR's Yunikorn queue (configs.QueueConfig)
-// If the Queue doesn't exists, create a new one in the SchedulerConfig
-// (the existance of the default partition and the root queue is assumed)
-func getOscarQueue(schedulerConfig *configs.SchedulerConfig) *configs.QueueConfig {
-	// First get a pointer to the root queue
-	root := &configs.QueueConfig{}
-	for i, partition := range schedulerConfig.Partitions {
-		if partition.Name == types.YunikornDefaultPartition {
-			for j, queue := range partition.Queues {
-				if queue.Name == types.YunikornRootQueue {
-					root = &schedulerConfig.Partitions[i].Queues[j]
-					break
-				}
-			}
+// getYunikornPartition returns the YuniKorn partition where the services' queues are created
+func getYunikornPartition(cfg *types.Config) string {
+	if cfg.YunikornPartition == "" {
+		return types.YunikornDefaultPartition
+	}
+	return cfg.YunikornPartition
+}
+
+// getYunikornQueue returns a pointer to a Yunikorn queue (configs.QueueConfig) by its full name (e.g.
+// "root.oscar-queue"). If create is true, the missing queues of the path are created as parent queues in the
+// SchedulerConfig. Returns nil if the partition or (if create is false) the queue don't exist
+func getYunikornQueue(schedulerConfig *configs.SchedulerConfig, partition string, fullName string, create bool) *configs.QueueConfig {
+	var queues *[]configs.QueueConfig
+	for i := range schedulerConfig.Partitions {
+		if schedulerConfig.Partitions[i].Name == partition {
+			queues = &schedulerConfig.Partitions[i].Queues
+			break
 		}
 	}
+	if queues == nil {
+		return nil
+	}
 
-	// Now search the OSCAR queue and return it if exists create it if doesn't exists
-	for i, queue := range root.Queues {
-		if queue.Name == types.YunikornOscarQueue {
-			return &root.Queues[i]
+	var queue *configs.QueueConfig
+	for _, name := range strings.Split(fullName, ".") {
+		queue = nil
+		for i := range *queues {
+			if (*queues)[i].Name == name {
+				queue = &(*queues)[i]
+				break
+			}
+		}
+
+		// Create it if doesn't exists
+		if queue == nil {
+			if !create {
+				return nil
+			}
+			*queues = append(*queues, configs.QueueConfig{Name: name, Parent: true})
+			queue = &(*queues)[len(*queues)-1]
 		}
+		queues = &queue.Queues
 	}
 
-	// Create it if doesn't exists and return it
-	root.Queues = append(root.Queues, configs.QueueConfig{Name: types.YunikornOscarQueue})
-	return &root.Queues[len(root.Queues)-1]
+	return queue
 }
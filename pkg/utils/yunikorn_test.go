@@ -0,0 +1,100 @@
+/*
+Copyright (C) GRyCAP - I3M - UPV
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/yunikorn-core/pkg/common/configs"
+	"github.com/goccy/go-yaml"
+	"github.com/grycap/oscar/v2/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+const testYunikornConfig = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: hpc
+            parent: true
+`
+
+func TestYunikornQueues(t *testing.T) {
+	cfg := &types.Config{
+		YunikornNamespace:      "yunikorn",
+		YunikornConfigMap:      "yunikorn-configs",
+		YunikornConfigFileName: "queues.yaml",
+	}
+	kubeClientset := testclient.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cfg.YunikornConfigMap, Namespace: cfg.YunikornNamespace},
+		Data:       map[string]string{cfg.YunikornConfigFileName: testYunikornConfig},
+	})
+
+	getQueue := func(fullName string) *configs.QueueConfig {
+		yConfig, err := readYunikornConfig(cfg, kubeClientset)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return getYunikornQueue(yConfig, types.YunikornDefaultPartition, fullName, false)
+	}
+
+	// The service's queue and the missing parent queues are created
+	service := &types.Service{Name: "test", TotalCPU: "2", Yunikorn: &types.YunikornSettings{Queue: "root.hpc.bio"}}
+	service.Labels = map[string]string{types.YunikornQueueLabel: service.GetYunikornSettings(cfg).GetQueue(service)}
+	if err := AddYunikornQueue(cfg, kubeClientset, service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q := getQueue("root.hpc.bio"); q == nil || !q.Parent {
+		t.Fatalf("expecting the parent queue \"root.hpc.bio\", got %+v", q)
+	}
+	if q := getQueue("root.hpc.bio.test"); q == nil || q.Resources.Max["vcore"] != "2" {
+		t.Fatalf("expecting the service's queue, got %+v", q)
+	}
+
+	// The queue is deleted from its parent queue
+	if err := DeleteYunikornQueue(cfg, kubeClientset, service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q := getQueue("root.hpc.bio.test"); q != nil {
+		t.Errorf("expecting the service's queue to be removed, got %+v", q)
+	}
+
+	// No queue is created for the services placed by the YuniKorn's placement rules
+	service.Yunikorn.Placement = types.YunikornPlacementRules
+	if err := AddYunikornQueue(cfg, kubeClientset, service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q := getQueue("root.hpc.bio.test"); q != nil {
+		t.Errorf("unexpected queue %+v", q)
+	}
+
+	// The other queues are kept
+	cm, _ := kubeClientset.CoreV1().ConfigMaps(cfg.YunikornNamespace).Get(context.TODO(), cfg.YunikornConfigMap, metav1.GetOptions{})
+	yConfig := &configs.SchedulerConfig{}
+	if err := yaml.Unmarshal([]byte(cm.Data[cfg.YunikornConfigFileName]), yConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if root := yConfig.Partitions[0].Queues[0]; root.SubmitACL != "*" || len(root.Queues) != 1 {
+		t.Errorf("unexpected root queue: %+v", root)
+	}
+}
@@ -4,6 +4,8 @@ ARCH=$(uname -m)
 FAAS_SUPERVISOR_NAME=supervisor
 FAAS_SUPERVISOR_ALPINE_NAME=supervisor-alpine
 WATCHDOG_NAME=fwatchdog-amd64
+OF_WATCHDOG_NAME=of-watchdog
+OF_WATCHDOG_VERSION=${OF_WATCHDOG_VERSION:-0.9.10}
 
 echo "Downloading binaries for $ARCH..."
 
@@ -11,6 +13,7 @@ if [[ $ARCH == "aarch64" ]] || [[ $ARCH == "arm64" ]]; then
     FAAS_SUPERVISOR_NAME=$FAAS_SUPERVISOR_NAME-arm64
     FAAS_SUPERVISOR_ALPINE_NAME=$FAAS_SUPERVISOR_ALPINE_NAME-arm64
     WATCHDOG_NAME=fwatchdog-arm64
+    OF_WATCHDOG_NAME=of-watchdog-arm64
 fi
 
 # Download FaaS Supervisor and unzip
@@ -28,3 +31,7 @@ cp -r /tmp/alpine/supervisor/* /data/alpine
 # Download OpenFaaS watchdog and set execution permissions
 wget "https://github.com/openfaas/classic-watchdog/releases/download/$WATCHDOG_VERSION/$WATCHDOG_NAME" -O /data/fwatchdog
 chmod +x /data/fwatchdog
+
+# Download OpenFaaS of-watchdog (used by the streaming services) and set execution permissions
+wget "https://github.com/openfaas/of-watchdog/releases/download/$OF_WATCHDOG_VERSION/$OF_WATCHDOG_NAME" -O /data/of-watchdog
+chmod +x /data/of-watchdog